@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/adopt"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+)
+
+// adoptCommand is the subcommand which generates the Machines of the pre-existing VirtualMachines of the cluster
+const adoptCommand = "adopt"
+
+// runAdopt runs the adopt subcommand with its arguments, the flags of the command line like --kubeconfig
+// are shared with the controller
+func runAdopt(args []string) {
+	machineNamespace := flag.String(
+		"namespace",
+		"openshift-machine-api",
+		"The namespace of the generated Machines.",
+	)
+	output := flag.String(
+		"output",
+		"-",
+		"Path of the YAML manifests of the generated Machines to write, - for the standard output.",
+	)
+	execCredentialPlugins := flag.String(
+		"infra-exec-credential-plugins",
+		"",
+		execCredentialPluginsUsage,
+	)
+	credentialsSecretName := flag.String(
+		"infra-credentials-secret-name",
+		"",
+		credentialsSecretNameUsage,
+	)
+	credentialsSecretNamespace := flag.String(
+		"infra-credentials-secret-namespace",
+		"",
+		credentialsSecretNamespaceUsage,
+	)
+	klog.InitFlags(nil)
+	flag.Set("logtostderr", "true")
+	flag.CommandLine.Parse(args)
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		klog.Fatalf("Error getting configuration: %v", err)
+	}
+	tenantClusterClient, err := tenantcluster.NewFromConfig(cfg)
+	if err != nil {
+		klog.Fatalf("failed to create tenantcluster client from configuration, with error: %v", err)
+	}
+	infraClusterClient, err := infracluster.New(context.Background(), tenantClusterClient, infracluster.ResilienceOptions{},
+		infraAuthOptions(*execCredentialPlugins, *credentialsSecretName, *credentialsSecretNamespace), infracluster.DataVolumeOptions{})
+	if err != nil {
+		klog.Fatalf("failed to create infracluster client from configuration, with error: %v", err)
+	}
+
+	out := os.Stdout
+	if *output != "-" {
+		if out, err = os.Create(*output); err != nil {
+			klog.Fatalf("failed to create %s, with error: %v", *output, err)
+		}
+		defer out.Close()
+	}
+	if err := adopt.New(infraClusterClient, tenantClusterClient).Adopt(context.Background(), *machineNamespace, out); err != nil {
+		klog.Fatalf("failed to generate the Machines of the VirtualMachines, with error: %v", err)
+	}
+}
@@ -0,0 +1,30 @@
+//go:build faultinjection
+// +build faultinjection
+
+package main
+
+import (
+	"flag"
+
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster"
+)
+
+// The fault injection is only built into the test binaries, with the faultinjection tag, so that the production
+// controller can never fail its requests on purpose.
+func init() {
+	infraFaultInjection := flag.String(
+		"infra-fault-injection",
+		"",
+		"The faults injected into the requests to the infra-cluster API, for testing the resilience of the controller, separated by semicolons, e.g. \"create/virtualmachines status=503 count=2; get/* latency=500ms error=connection\".",
+	)
+	infraFaults = func() (*infracluster.FaultInjector, error) {
+		if *infraFaultInjection == "" {
+			return nil, nil
+		}
+		faults, err := infracluster.ParseFaults(*infraFaultInjection)
+		if err != nil {
+			return nil, err
+		}
+		return infracluster.NewFaultInjector(faults), nil
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/gather"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+)
+
+// gatherCommand is the subcommand which collects the objects of a Machine into a tarball for support bundles
+const gatherCommand = "gather"
+
+// runGather runs the gather subcommand with its arguments, the flags of the command line like --kubeconfig
+// are shared with the controller
+func runGather(args []string) {
+	machineName := flag.String(
+		"machine",
+		"",
+		"The name of the Machine to gather.",
+	)
+	machineNamespace := flag.String(
+		"namespace",
+		"openshift-machine-api",
+		"The namespace of the Machine to gather.",
+	)
+	output := flag.String(
+		"output",
+		"",
+		"Path of the gzipped tarball to write. Defaults to <machine>-gather.tar.gz in the working directory.",
+	)
+	execCredentialPlugins := flag.String(
+		"infra-exec-credential-plugins",
+		"",
+		execCredentialPluginsUsage,
+	)
+	credentialsSecretName := flag.String(
+		"infra-credentials-secret-name",
+		"",
+		credentialsSecretNameUsage,
+	)
+	credentialsSecretNamespace := flag.String(
+		"infra-credentials-secret-namespace",
+		"",
+		credentialsSecretNamespaceUsage,
+	)
+	klog.InitFlags(nil)
+	flag.Set("logtostderr", "true")
+	flag.CommandLine.Parse(args)
+
+	if *machineName == "" {
+		klog.Fatalf("--machine is required")
+	}
+	if *output == "" {
+		*output = fmt.Sprintf("%s-gather.tar.gz", *machineName)
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		klog.Fatalf("Error getting configuration: %v", err)
+	}
+	tenantClusterClient, err := tenantcluster.NewFromConfig(cfg)
+	if err != nil {
+		klog.Fatalf("failed to create tenantcluster client from configuration, with error: %v", err)
+	}
+	infraClusterClient, err := infracluster.New(context.Background(), tenantClusterClient, infracluster.ResilienceOptions{},
+		infraAuthOptions(*execCredentialPlugins, *credentialsSecretName, *credentialsSecretNamespace), infracluster.DataVolumeOptions{})
+	if err != nil {
+		klog.Fatalf("failed to create infracluster client from configuration, with error: %v", err)
+	}
+
+	out, err := os.Create(*output)
+	if err != nil {
+		klog.Fatalf("failed to create %s, with error: %v", *output, err)
+	}
+	defer out.Close()
+	if err := gather.New(infraClusterClient, tenantClusterClient).Gather(context.Background(), *machineName, *machineNamespace, out); err != nil {
+		klog.Fatalf("failed to gather Machine %s, with error: %v", *machineName, err)
+	}
+	klog.Infof("gathered Machine %s into %s", *machineName, *output)
+}
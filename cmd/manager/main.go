@@ -15,18 +15,35 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
 	"flag"
+	"fmt"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/openshift/cluster-api-provider-kubevirt/pkg/actuator"
 	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster"
 	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/componentconfig"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/controller/bootimage"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/controller/capacity"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/controller/footprint"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/controller/infradrain"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/controller/infranamespace"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/controller/networkcheckup"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/controller/nodestatus"
 	"github.com/openshift/cluster-api-provider-kubevirt/pkg/controller/nodeupdate"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/controller/sharding"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/controller/stuckmachine"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/controller/utilization"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/controller/vmpool"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/controller/warmpool"
 	"github.com/openshift/cluster-api-provider-kubevirt/pkg/kubevirt"
 	"github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/metrics"
 	mapiv1beta1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
 	"github.com/openshift/machine-api-operator/pkg/controller/machine"
-	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	"k8s.io/klog"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
@@ -42,7 +59,56 @@ var (
 	syncPeriod    = 10 * time.Minute
 )
 
+// The default interval for polling the infra-cluster nodes for disruptions.
+var infraDrainPollInterval = 30 * time.Second
+
+// The default interval for reporting the remaining capacity of the MachineSets.
+var capacityPollInterval = time.Minute
+
+// The default interval for scaling the VirtualMachinePools backing MachineSets.
+var vmPoolPollInterval = 30 * time.Second
+
+// The default time given to the in-flight reconciles, like the creation of a machine, to complete on shutdown.
+var gracefulShutdownTimeout = 2 * time.Minute
+
+// The delay before the first retry of a request to the infra-cluster API which failed with a transient error.
+var infraRequestRetryBackoff = 500 * time.Millisecond
+
+// The interval for checking the configuration file for changes.
+var configReloadInterval = 30 * time.Second
+
+// The interval for labeling the new Machines with their shard.
+var shardLabelPollInterval = 10 * time.Second
+
+// The interval for reporting the Machines stuck in a phase.
+var stuckMachinePollInterval = time.Minute
+
+// infraFaults returns the faults injected into the requests to the infra-cluster API, set by the builds with the
+// faultinjection tag only.
+var infraFaults = func() (*infracluster.FaultInjector, error) { return nil, nil }
+
+// The default delays before re-checking operations which are still in progress.
+var (
+	requeueAfter           = 20 * time.Second
+	vmNotReadyRequeueAfter = 60 * time.Second
+)
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == gatherCommand {
+		runGather(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == adoptCommand {
+		runAdopt(os.Args[2:])
+		return
+	}
+
+	configFile := flag.String(
+		"config",
+		"",
+		"Path of a ControllerConfiguration YAML file, which sets the flags of the requeue durations, feature gates, concurrency, infra credentials secret and metrics not set on the command line. The controller restarts once the file changes.",
+	)
+
 	watchNamespace := flag.String(
 		"namespace",
 		"",
@@ -55,6 +121,24 @@ func main() {
 		"The address the metric endpoint binds to.",
 	)
 
+	metricsCertFile := flag.String(
+		"metrics-tls-cert-file",
+		"",
+		"Path of the serving certificate of the metrics endpoint, which is served with TLS when set, together with --metrics-tls-key-file. The certificate is reloaded once rotated.",
+	)
+
+	metricsKeyFile := flag.String(
+		"metrics-tls-key-file",
+		"",
+		"Path of the key of the serving certificate of the metrics endpoint.",
+	)
+
+	metricsAuthnAuthz := flag.Bool(
+		"metrics-authn-authz",
+		false,
+		"Require the scrapers of the metrics endpoint to send a bearer token, authenticated with a TokenReview and allowed to get /metrics with a SubjectAccessReview.",
+	)
+
 	healthAddr := flag.String(
 		"health-addr",
 		":9440",
@@ -79,12 +163,262 @@ func main() {
 		"The duration that non-leader candidates will wait after observing a leadership renewal until attempting to acquire leadership of a led but unrenewed leader slot. This is effectively the maximum duration that a leader can be stopped before it is replaced by another candidate. This is only applicable if leader election is enabled.",
 	)
 
+	gracefulShutdownTimeoutDuration := flag.Duration(
+		"graceful-shutdown-timeout",
+		gracefulShutdownTimeout,
+		"The time given to the in-flight reconciles to complete on shutdown. A creation interrupted by the shutdown is resumed once the controller is back.",
+	)
+
+	infraDrainInterval := flag.Duration(
+		"infra-drain-poll-interval",
+		infraDrainPollInterval,
+		"The interval for checking the infra-cluster nodes for disruptions (cordoned or NotReady). Tenant nodes whose VMs run on a disrupted infra node are cordoned and drained.",
+	)
+
+	capacityInterval := flag.Duration(
+		"capacity-poll-interval",
+		capacityPollInterval,
+		"The interval for reporting the number of machines each MachineSet can still create within the ResourceQuotas of the infra namespace.",
+	)
+
+	utilizationInterval := flag.Duration(
+		"utilization-poll-interval",
+		0,
+		"The interval for collecting the CPU and memory usage of the machines from the metrics API of the infra-cluster. The collector is disabled when zero.",
+	)
+
+	footprintInterval := flag.Duration(
+		"footprint-poll-interval",
+		0,
+		"The interval for publishing the vCPUs, memory and storage every machine consumes in the infra-cluster, as annotations of the machine and the kubevirt_machine_footprint metric, for the chargeback of the tenants. The footprint is disabled when zero.",
+	)
+
+	vmPoolInterval := flag.Duration(
+		"vm-pool-poll-interval",
+		vmPoolPollInterval,
+		"The interval for scaling the VirtualMachinePools backing MachineSets to the replicas of their MachineSet.",
+	)
+
+	warmPoolInterval := flag.Duration(
+		"warm-pool-poll-interval",
+		0,
+		"The interval for cloning the boot volumes of the warm pool of the MachineSets with a warm pool size annotation. The warm pool is disabled when zero.",
+	)
+
+	bootImageInterval := flag.Duration(
+		"boot-image-poll-interval",
+		0,
+		"The interval for checking the source PVCs of the MachineSets for a new golden image, marking the machines cloned from an outdated image and replacing them for the MachineSets with a boot image rollout annotation. The check is disabled when zero.",
+	)
+
+	networkCheckupInterval := flag.Duration(
+		"network-checkup-poll-interval",
+		0,
+		"The interval for checking the network of the MachineSets with a network checkup annotation, whose machines aren't created until their network is checked. The checkup is disabled when zero.",
+	)
+
+	networkCheckupImage := flag.String(
+		"network-checkup-image",
+		networkcheckup.DefaultCheckupImage,
+		"The image of the kubevirt-vm-latency checkup run against the network of the MachineSets.",
+	)
+
+	machineStuckThreshold := flag.Duration(
+		"machine-stuck-threshold",
+		30*time.Minute,
+		"The time after which a machine still Provisioning, Provisioned or Deleting is reported as stuck in its phase, by the kubevirt_machine_stuck_in_phase_seconds metric. The report is disabled when zero.",
+	)
+
+	requeueAfterDuration := flag.Duration(
+		"requeue-after",
+		requeueAfter,
+		"The delay before re-checking a machine whose operation is still in progress in the infra-cluster: its ignition sources being checked or its ignition being uploaded, its VirtualMachine not visible yet after its creation, still shutting down, or not yet available to adopt from its VirtualMachinePool, and the sync of a machine skipped as stale. Increase it to reduce the load on slow infra-clusters.",
+	)
+
+	vmNotReadyRequeueAfterDuration := flag.Duration(
+		"vm-not-ready-requeue-after",
+		vmNotReadyRequeueAfter,
+		"The delay before re-checking a node whose VirtualMachine isn't ready yet, for setting its providerID.",
+	)
+
+	createInfraNamespace := flag.Bool(
+		"create-infra-namespace",
+		false,
+		"Create the infra namespace in the infra-cluster if it doesn't exist, labeled as owned by the tenant-cluster.",
+	)
+
+	infraNamespaceResourceQuota := flag.String(
+		"infra-namespace-resource-quota",
+		"",
+		"Path of a ResourceQuota YAML template, created in the infra namespace when --create-infra-namespace is set.",
+	)
+
+	infraNamespaceNetworkPolicy := flag.String(
+		"infra-namespace-network-policy",
+		"",
+		"Path of a NetworkPolicy YAML template, created in the infra namespace when --create-infra-namespace is set.",
+	)
+
+	dataVolumeStorageAPI := flag.Bool(
+		"datavolume-storage-api",
+		true,
+		"Request the DataVolumes with the spec.storage API of CDI, whose access modes and volume mode are filled from the StorageProfile of their storage class, when the infra-cluster serves the StorageProfiles. Disable it to keep the legacy spec.pvc API.",
+	)
+
+	maxConcurrentVMCreations := flag.Int(
+		"max-concurrent-vm-creations",
+		0,
+		"The number of VirtualMachines being provisioned at once in the infra-clusters, until their VirtualMachineInstance is created and their DataVolumes are populated, above which the creations are retried later. Unlimited when zero.",
+	)
+
+	maxConcurrentVMCreationsPerNamespace := flag.Int(
+		"max-concurrent-vm-creations-per-namespace",
+		0,
+		"The number of VirtualMachines being provisioned at once in an infra namespace, above which the creations in the namespace are retried later. Unlimited when zero.",
+	)
+
+	maxPendingClones := flag.Int(
+		"max-pending-clones",
+		0,
+		"The number of VirtualMachines of an infra namespace waiting for their boot volume to be cloned by CDI, above which the creations are retried later. Unlimited when zero.",
+	)
+
+	vmMetadataAllowedPrefixes := flag.String(
+		"vm-metadata-allowed-prefixes",
+		"",
+		"Comma-separated prefixes of the keys of the labels and annotations of the Machines copied onto their VirtualMachine. All the keys are copied when empty.",
+	)
+
+	vmMetadataDeniedPrefixes := flag.String(
+		"vm-metadata-denied-prefixes",
+		"",
+		"Comma-separated prefixes of the keys of the labels and annotations of the Machines never copied onto their VirtualMachine, even when allowed.",
+	)
+
+	vmMetadataMaxValueLength := flag.Int(
+		"vm-metadata-max-value-length",
+		0,
+		"The length above which the labels and annotations of the Machines aren't copied onto their VirtualMachine. Unlimited when zero.",
+	)
+
+	vmMetadataMaxSize := flag.Int(
+		"vm-metadata-max-size",
+		0,
+		"The size of the keys and values of the labels, and of the annotations, of a Machine copied onto its VirtualMachine, in the order of the keys, the keys exceeding it are dropped. Unlimited when zero.",
+	)
+
+	infraRequestTimeout := flag.Duration(
+		"infra-request-timeout",
+		time.Minute,
+		"The timeout of every attempt of a request to the infra-cluster API, but the watches. Disabled when zero.",
+	)
+
+	infraRequestRetries := flag.Int(
+		"infra-request-retries",
+		3,
+		"The number of times an idempotent request to the infra-cluster API failing with a transient error is retried, with a jittered exponential backoff.",
+	)
+
+	infraCircuitBreakerThreshold := flag.Int(
+		"infra-circuit-breaker-threshold",
+		10,
+		"The number of consecutive transient failures of the infra-cluster API after which its requests fail fast until the cooldown is over. Disabled when zero.",
+	)
+
+	infraCircuitBreakerCooldown := flag.Duration(
+		"infra-circuit-breaker-cooldown",
+		30*time.Second,
+		"The time the requests to the infra-cluster API fail fast once the circuit breaker opened, before a single request probes the infra-cluster API again.",
+	)
+
+	infraExecCredentialPlugins := flag.String(
+		"infra-exec-credential-plugins",
+		"",
+		execCredentialPluginsUsage,
+	)
+
+	infraCredentialsSecretName := flag.String(
+		"infra-credentials-secret-name",
+		"",
+		credentialsSecretNameUsage,
+	)
+
+	infraCredentialsSecretNamespace := flag.String(
+		"infra-credentials-secret-namespace",
+		"",
+		credentialsSecretNamespaceUsage,
+	)
+
+	infraAuditLog := flag.String(
+		"infra-audit-log",
+		"",
+		"Path of the file the mutating requests to the infra-cluster API are appended to as JSON lines, with the Machine they are made for and the fields changed by the updates, or - for the standard output. The audit is disabled when empty.",
+	)
+
+	infraMaintenance := flag.Bool(
+		"infra-maintenance",
+		false,
+		"Put the infra-cluster in maintenance: the creations and deletions of machines are postponed until the maintenance ends, while their status is still synced. The maintenance can also be started without restarting the controller, by setting infraMaintenance to \"true\" in the cloud provider configMap.",
+	)
+
+	nodeLifecycleOnly := flag.Bool(
+		"node-lifecycle-only",
+		false,
+		"Run only the node controllers, as an external cloud provider, without the machine actuator: the providerID and the addresses of the nodes are set from their VirtualMachines, and the nodes are deleted with them. For the clusters whose KubeVirt VirtualMachines are created by other means than Machines.",
+	)
+
+	machineOnly := flag.Bool(
+		"machine-only",
+		false,
+		"Run only the machine controllers, without the node controllers setting the providerID of the nodes and deleting the nodes of the deleted VirtualMachines. For the clusters whose node lifecycle is handled by the KubeVirt cloud-controller-manager.",
+	)
+
+	cloudControllerManagerLease := flag.String(
+		"cloud-controller-manager-lease",
+		"kube-system/cloud-controller-manager",
+		"The namespace/name of the leader election lease of the KubeVirt cloud-controller-manager. While the lease is held, or cloudControllerManager is \"true\" in the cloud provider configMap, the providerID, the topology labels, the addresses and the deletion of the nodes are left to the cloud-controller-manager. An empty value disables the detection by the lease.",
+	)
+
+	shardCount := flag.Int(
+		"shard-count",
+		1,
+		"The number of shards the Machines are split into, by the hash of their namespace and MachineSet, every shard being reconciled by its own leader elected replica. The replica of the shard 0 also labels the Machines with their shard, and runs the node controllers and the cluster-wide runnables. The sharding is disabled with a single shard.",
+	)
+
+	shardIndex := flag.Int(
+		"shard-index",
+		0,
+		"The shard of the Machines reconciled by the replica, between 0 and --shard-count minus 1.",
+	)
+
 	klog.InitFlags(nil)
 	flag.Set("logtostderr", "true")
 	flag.Parse()
 
 	klog.Info("start kubevirt machine controller")
 
+	var configDigest [sha256.Size]byte
+	if *configFile != "" {
+		var err error
+		if configDigest, err = applyConfigFile(*configFile); err != nil {
+			klog.Fatalf("failed to apply the configuration file, with error: %v", err)
+		}
+	}
+	if *machineOnly && *nodeLifecycleOnly {
+		klog.Fatalf("--machine-only and --node-lifecycle-only are mutually exclusive")
+	}
+	shardOptions := sharding.Options{Count: *shardCount, Index: *shardIndex}
+	if err := shardOptions.Validate(); err != nil {
+		klog.Fatalf("invalid sharding flags, with error: %v", err)
+	}
+	if shardOptions.Enabled() && *nodeLifecycleOnly {
+		klog.Fatalf("--shard-count and --node-lifecycle-only are mutually exclusive")
+	}
+	ccmLease, err := nodeupdate.ParseLease(*cloudControllerManagerLease)
+	if err != nil {
+		klog.Fatalf("invalid --cloud-controller-manager-lease, with error: %v", err)
+	}
+
 	// Get a config to talk to the apiserver
 	cfg, err := config.GetConfig()
 	if err != nil {
@@ -95,12 +429,24 @@ func main() {
 	opts := manager.Options{
 		LeaderElection:          *leaderElect,
 		LeaderElectionNamespace: *leaderElectResourceNamespace,
-		LeaderElectionID:        "cluster-api-provider-kubevirt-leader",
+		LeaderElectionID:        shardOptions.LeaderElectionID("cluster-api-provider-kubevirt-leader"),
 		LeaseDuration:           leaderElectLeaseDuration,
 		MetricsBindAddress:      *metricsAddr,
 		HealthProbeBindAddress:  *healthAddr,
 		RetryPeriod:             &retryPeriod,
 		RenewDeadline:           &renewDeadline,
+		GracefulShutdownTimeout: gracefulShutdownTimeoutDuration,
+	}
+
+	// The metrics endpoint with TLS or authorization is served by the provider instead of the manager
+	metricsServerOptions := metrics.ServerOptions{
+		BindAddress: *metricsAddr,
+		CertFile:    *metricsCertFile,
+		KeyFile:     *metricsKeyFile,
+		AuthnAuthz:  *metricsAuthnAuthz,
+	}
+	if metricsServerOptions.Enabled() {
+		opts.MetricsBindAddress = "0"
 	}
 
 	if *watchNamespace != "" {
@@ -108,62 +454,267 @@ func main() {
 		klog.Infof("Watching machine-api objects only in namespace %q for reconciliation.", opts.Namespace)
 	}
 
+	// Every shard only caches the Machines of its shard
+	if shardOptions.Enabled() {
+		opts.NewCache = shardOptions.NewCache()
+		klog.Infof("Reconciling the machine-api objects of shard %d of %d.", shardOptions.Index, shardOptions.Count)
+	}
+
 	mgr, err := manager.New(cfg, opts)
 	if err != nil {
 		klog.Fatalf("failed to set up overall controller manager, with error: %v", err)
 	}
 
+	if metricsServerOptions.Enabled() {
+		if err := metrics.AddServer(mgr, metricsServerOptions); err != nil {
+			klog.Fatalf("failed to add the metrics endpoint, with error: %v", err)
+		}
+	}
+
 	// Setup Scheme for all resources
 	if err := mapiv1beta1.AddToScheme(mgr.GetScheme()); err != nil {
 		klog.Fatalf("failed to set up scheme, with error: %v", err)
 	}
 
+	// The node controllers and the cluster-wide runnables of the shard 0 read all the Machines
+	clusterWideMgr := manager.Manager(mgr)
+	if shardOptions.Enabled() && shardOptions.RunsClusterWide() {
+		if clusterWideMgr, err = sharding.FullView(mgr, cfg, opts.Namespace); err != nil {
+			klog.Fatalf("failed to set up the view of all the machines, with error: %v", err)
+		}
+	}
+
 	// Initialize tenant-cluster clients
-	tenantClusterClient, err := tenantcluster.New(mgr)
+	tenantClusterClient, err := tenantcluster.New(clusterWideMgr)
 	if err != nil {
 		klog.Fatalf("failed to create tenantcluster client from configuration, with error: %v", err)
 	}
 
 	// Initialize infra-cluster clients
-	infraClusterClient, err := infracluster.New(context.Background(), tenantClusterClient)
+	infraResilience := infracluster.ResilienceOptions{
+		Timeout:                 *infraRequestTimeout,
+		MaxRetries:              *infraRequestRetries,
+		RetryBackoff:            infraRequestRetryBackoff,
+		CircuitBreakerThreshold: *infraCircuitBreakerThreshold,
+		CircuitBreakerCooldown:  *infraCircuitBreakerCooldown,
+	}
+	if infraResilience.Faults, err = infraFaults(); err != nil {
+		klog.Fatalf("failed to parse the faults injected into the infra-cluster requests, with error: %v", err)
+	}
+	infraAuth := infraAuthOptions(*infraExecCredentialPlugins, *infraCredentialsSecretName, *infraCredentialsSecretNamespace)
+	infraCreationLimiter := infracluster.NewCreationLimiter(infracluster.CreationLimits{
+		MaxConcurrentCreations:             *maxConcurrentVMCreations,
+		MaxConcurrentCreationsPerNamespace: *maxConcurrentVMCreationsPerNamespace,
+		MaxPendingClones:                   *maxPendingClones,
+	})
+	infraDataVolumes := infracluster.DataVolumeOptions{StorageAPI: *dataVolumeStorageAPI}
+	infraAudit, err := infracluster.OpenAuditLog(*infraAuditLog)
+	if err != nil {
+		klog.Fatalf("failed to open the infra-cluster audit log, with error: %v", err)
+	}
+	infraClusterClient, err := infracluster.New(context.Background(), tenantClusterClient, infraResilience, infraAuth, infraDataVolumes)
 	if err != nil {
 		klog.Fatalf("failed to create infracluster client from configuration, with error: %v", err)
 	}
+	infraClusterClient = infracluster.WithCreationLimits(infracluster.WithAudit(infraClusterClient, infraAudit, ""), infraCreationLimiter, "")
+	// The Machines naming a credentials secret in their provider spec use the InfraCluster of their secret
+	credentialsClients := infracluster.NewCredentialsClients(tenantClusterClient, infraClusterClient, infraResilience, infraAuth, infraDataVolumes, infraCreationLimiter, infraAudit)
+	// Register the providerID controller, unless the node lifecycle is handled by the KubeVirt cloud-controller-manager
+	if *machineOnly {
+		klog.Infof("running the machine controllers only, without the providerID controller")
+	} else if !shardOptions.RunsClusterWide() {
+		klog.Infof("running the machine controllers of the shard only, the node controllers run in the shard 0")
+	} else if err := nodeupdate.Add(clusterWideMgr, infraClusterClient, credentialsClients.ForMachine, tenantClusterClient, *vmNotReadyRequeueAfterDuration, *nodeLifecycleOnly, ccmLease); err != nil {
+		klog.Fatalf("failed to add providerID reconciler, with error: %v", err)
+	}
 
-	// Initialize machineScope creator
-	machineScopeCreator := machinescope.New()
+	// The Machines are reconciled unless the controller only runs the node lifecycle
+	if *nodeLifecycleOnly {
+		klog.Infof("running the node lifecycle only, without the machine actuator")
+	} else {
+		// Initialize machineScope creator
+		machineScopeCreator := machinescope.New(machinescope.MetadataFilter{
+			AllowedPrefixes: commaSeparated(*vmMetadataAllowedPrefixes),
+			DeniedPrefixes:  commaSeparated(*vmMetadataDeniedPrefixes),
+			MaxValueLength:  *vmMetadataMaxValueLength,
+			MaxTotalSize:    *vmMetadataMaxSize,
+		}, tenantClusterClient)
 
-	// Initialize provider vm manager (infraClusterClientBuilder would be the function infracluster.New)
-	kubevirtVM := kubevirt.New(infraClusterClient)
+		// Initialize provider vm manager (infraClusterClientBuilder would be the function infracluster.New)
+		kubevirtVM := kubevirt.New(infraClusterClient, *requeueAfterDuration)
 
-	// Initialize machine actuator.
-	machineActuator, err := actuator.New(kubevirtVM, mgr.GetEventRecorderFor("kubevirtcontroller"),
-		machineScopeCreator, tenantClusterClient)
-	if err != nil {
-		klog.Fatalf("failed to create actuator, with error: %v", err)
-	}
+		// Initialize machine actuator.
+		machineActuator, err := actuator.New(kubevirtVM, mgr.GetEventRecorderFor("kubevirtcontroller"),
+			machineScopeCreator, tenantClusterClient, *infraMaintenance,
+			kubevirt.NewCredentialsKubevirtVMs(credentialsClients, *requeueAfterDuration))
+		if err != nil {
+			klog.Fatalf("failed to create actuator, with error: %v", err)
+		}
 
-	// Register Actuator on machine-controller
-	if err := machine.AddWithActuator(mgr, machineActuator); err != nil {
-		klog.Fatalf("failed to add actuator, with error: %v", err)
+		// Register Actuator on machine-controller
+		if err := machine.AddWithActuator(mgr, machineActuator); err != nil {
+			klog.Fatalf("failed to add actuator, with error: %v", err)
+		}
+
+		// Register the shard labeler
+		if shardOptions.Enabled() && shardOptions.RunsClusterWide() {
+			if err := sharding.Add(mgr, tenantClusterClient, shardOptions, shardLabelPollInterval); err != nil {
+				klog.Fatalf("failed to add shard labeler, with error: %v", err)
+			}
+		}
 	}
 
-	// Register the providerID controller
-	if err := nodeupdate.Add(mgr, infraClusterClient, tenantClusterClient); err != nil {
-		klog.Fatalf("failed to add providerID reconciler, with error: %v", err)
+	// The node controllers and the cluster-wide runnables only run in the shard 0
+	if !*nodeLifecycleOnly && shardOptions.RunsClusterWide() {
+		// Register the node status controller
+		if err := nodestatus.Add(clusterWideMgr); err != nil {
+			klog.Fatalf("failed to add node status reconciler, with error: %v", err)
+		}
+
+		// Register the infra namespace runnable
+		if *createInfraNamespace {
+			templates, err := infranamespace.LoadTemplates(*infraNamespaceResourceQuota, *infraNamespaceNetworkPolicy)
+			if err != nil {
+				klog.Fatalf("failed to load the infra namespace templates, with error: %v", err)
+			}
+			if err := infranamespace.Add(mgr, infraClusterClient, tenantClusterClient, templates); err != nil {
+				klog.Fatalf("failed to add infra namespace runnable, with error: %v", err)
+			}
+		}
+
+		// Register the infra drain runnable
+		if err := infradrain.Add(mgr, infraClusterClient, credentialsClients.ForMachine, tenantClusterClient, *infraDrainInterval); err != nil {
+			klog.Fatalf("failed to add infra drain runnable, with error: %v", err)
+		}
+
+		// Register the capacity runnable
+		if err := capacity.Add(mgr, infraClusterClient, tenantClusterClient, *capacityInterval); err != nil {
+			klog.Fatalf("failed to add capacity runnable, with error: %v", err)
+		}
+
+		// Register the utilization runnable
+		if *utilizationInterval > 0 {
+			if err := utilization.Add(mgr, infraClusterClient, tenantClusterClient, *utilizationInterval); err != nil {
+				klog.Fatalf("failed to add utilization runnable, with error: %v", err)
+			}
+		}
+
+		// Register the footprint runnable
+		if *footprintInterval > 0 {
+			if err := footprint.Add(mgr, tenantClusterClient, *footprintInterval); err != nil {
+				klog.Fatalf("failed to add footprint runnable, with error: %v", err)
+			}
+		}
 
+		// Register the vm pool runnable
+		if err := vmpool.Add(mgr, infraClusterClient, tenantClusterClient, *vmPoolInterval); err != nil {
+			klog.Fatalf("failed to add vm pool runnable, with error: %v", err)
+		}
+
+		// Register the warm pool runnable
+		if *warmPoolInterval > 0 {
+			if err := warmpool.Add(mgr, infraClusterClient, tenantClusterClient, *warmPoolInterval); err != nil {
+				klog.Fatalf("failed to add warm pool runnable, with error: %v", err)
+			}
+		}
+
+		// Register the boot image runnable
+		if *bootImageInterval > 0 {
+			if err := bootimage.Add(mgr, infraClusterClient, tenantClusterClient, *bootImageInterval); err != nil {
+				klog.Fatalf("failed to add boot image runnable, with error: %v", err)
+			}
+		}
+
+		// Register the stuck machine runnable
+		if *machineStuckThreshold > 0 {
+			if err := stuckmachine.Add(mgr, tenantClusterClient, *machineStuckThreshold, stuckMachinePollInterval); err != nil {
+				klog.Fatalf("failed to add stuck machine runnable, with error: %v", err)
+			}
+		}
+
+		// Register the network checkup runnable
+		if *networkCheckupInterval > 0 {
+			if err := networkcheckup.Add(mgr, infraClusterClient, tenantClusterClient, *networkCheckupImage, *networkCheckupInterval); err != nil {
+				klog.Fatalf("failed to add network checkup runnable, with error: %v", err)
+			}
+		}
 	}
 
 	if err := mgr.AddReadyzCheck("ping", healthz.Ping); err != nil {
 		klog.Fatalf("failed to add ReadyzCheck, with error: %v", err)
 	}
 
+	// Register the configuration reloader
+	if *configFile != "" {
+		if err := componentconfig.AddReloader(mgr, *configFile, configDigest, configReloadInterval); err != nil {
+			klog.Fatalf("failed to add configuration reloader, with error: %v", err)
+		}
+	}
+
 	if err := mgr.AddHealthzCheck("ping", healthz.Ping); err != nil {
 		klog.Fatalf("failed to add HealthzCheck, with error: %v", err)
 	}
 
 	// Start the Cmd
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+		if componentconfig.IsChanged(err) {
+			klog.Info(err)
+			os.Exit(0)
+		}
 		klog.Fatalf("failed to start manager, with error: %v", err)
 	}
 }
+
+// applyConfigFile sets the flags of the configuration file which aren't set on the command line, and returns
+// the digest of the file
+func applyConfigFile(path string) ([sha256.Size]byte, error) {
+	controllerConfig, digest, err := componentconfig.Load(path)
+	if err != nil {
+		return digest, err
+	}
+	setOnCommandLine := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) {
+		setOnCommandLine[f.Name] = true
+	})
+	for name, value := range controllerConfig.Flags() {
+		if setOnCommandLine[name] {
+			klog.Infof("flag --%s is set on the command line, ignoring its value in the configuration file", name)
+			continue
+		}
+		if err := flag.Set(name, value); err != nil {
+			return digest, fmt.Errorf("failed to set --%s from the configuration file, with error: %v", name, err)
+		}
+	}
+	return digest, nil
+}
+
+// execCredentialPluginsUsage is the usage of the flag allowing the exec credential plugins of the infra-cluster kubeconfig
+const execCredentialPluginsUsage = "Comma-separated commands of the exec credential plugins the infra-cluster kubeconfig may run, which must be in the image of the controller. The kubeconfigs with an exec credential plugin are rejected when empty."
+
+// credentialsSecretNameUsage and credentialsSecretNamespaceUsage are the usages of the flags of the credentials secret
+const (
+	credentialsSecretNameUsage      = "The name of the secret of the tenant-cluster holding the infra-cluster kubeconfig. Defaults to kubevirt-credentials."
+	credentialsSecretNamespaceUsage = "The namespace of the secret of the tenant-cluster holding the infra-cluster kubeconfig. Defaults to openshift-machine-api."
+)
+
+// infraAuthOptions returns the auth options of the infra-cluster client, from the comma-separated exec credential
+// plugins and the credentials secret
+func infraAuthOptions(execCredentialPlugins string, credentialsSecretName string, credentialsSecretNamespace string) infracluster.AuthOptions {
+	options := infracluster.AuthOptions{
+		CredentialsSecretName:      credentialsSecretName,
+		CredentialsSecretNamespace: credentialsSecretNamespace,
+	}
+	options.ExecCommands = commaSeparated(execCredentialPlugins)
+	return options
+}
+
+// commaSeparated returns the non-empty values of the comma-separated list
+func commaSeparated(list string) []string {
+	var values []string
+	for _, value := range strings.Split(list, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -19,6 +19,7 @@ package actuator
 import (
 	"context"
 	"fmt"
+	"time"
 
 	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
 	"github.com/pkg/errors"
@@ -26,6 +27,8 @@ import (
 	"k8s.io/client-go/tools/record"
 	"k8s.io/klog"
 
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster"
 	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster"
 	"github.com/openshift/cluster-api-provider-kubevirt/pkg/kubevirt"
 	"github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
@@ -40,9 +43,29 @@ const (
 	updateEventAction eventAction = "update machine"
 	deleteEventAction eventAction = "delete machine"
 	existsEventAction eventAction = "check machine exists"
+	// accessModeEventAction reports the access mode selected for the boot volume of a created machine
+	accessModeEventAction eventAction = "select volume access mode"
+	// userDataEventAction reports the user-data secret a machine which doesn't set IgnitionSecretName falls back to
+	userDataEventAction eventAction = "select user-data secret"
+	// sourceImageEventAction reports a machine refused since its source image isn't the pinned one
+	sourceImageEventAction eventAction = "verify source image"
+	// ignitionSourceEventAction reports a machine whose ignition sources are unreachable from its network
+	ignitionSourceEventAction eventAction = "check ignition source"
+	// infraMaintenanceEventAction reports a machine operation postponed during the maintenance of the infra-cluster
+	infraMaintenanceEventAction eventAction = "wait for infra maintenance"
 
 	userDataKey = "userData"
 
+	// nodeDrainRequeueAfter is the delay before re-checking whether the node of a deleted machine is drained
+	nodeDrainRequeueAfter = 20 * time.Second
+	// creationThrottleRequeueAfter is the delay before retrying a creation throttled by the concurrent creations
+	// or the pending clones of the infra namespace
+	creationThrottleRequeueAfter = 30 * time.Second
+
+	// createInProgressAnnotation marks a machine whose resources are being created in the infra-cluster, its value
+	// is the start time of the creation. A creation interrupted by a restart of the controller is resumed by Create.
+	createInProgressAnnotation = "kubevirt.machine.openshift.io/create-in-progress"
+
 	configMapNamespace             = "openshift-config"
 	configMapName                  = "cloud-provider-config"
 	configMapDataKeyName           = "config"
@@ -58,13 +81,19 @@ type actuator struct {
 	tenantClusterClient tenantcluster.Client
 	infraID             string
 	infraNamespace      string
+	infraMaintenance    bool
+	// credentialsKubevirtVMs returns the KubevirtVM of the Machines naming a credentials secret in their provider
+	// spec; all the Machines use kubevirtVM when nil
+	credentialsKubevirtVMs kubevirt.CredentialsKubevirtVMs
 }
 
 // New returns an actuator.
 func New(kubevirtVM kubevirt.KubevirtVM,
 	eventRecorder record.EventRecorder,
 	machineScopeCreator machinescope.MachineScopeCreator,
-	tenantClusterClient tenantcluster.Client) (machinecontroller.Actuator, error) {
+	tenantClusterClient tenantcluster.Client,
+	infraMaintenance bool,
+	credentialsKubevirtVMs kubevirt.CredentialsKubevirtVMs) (machinecontroller.Actuator, error) {
 
 	cMap, err := tenantClusterClient.GetConfigMapValue(context.Background(), configMapName, configMapNamespace, configMapDataKeyName)
 	if err != nil {
@@ -81,12 +110,14 @@ func New(kubevirtVM kubevirt.KubevirtVM,
 			configMapNamespace, configMapName, configMapDataKeyName, configMapInfraNamespaceKeyName)
 	}
 	return &actuator{
-		kubevirtVM:          kubevirtVM,
-		eventRecorder:       eventRecorder,
-		machineScopeCreator: machineScopeCreator,
-		tenantClusterClient: tenantClusterClient,
-		infraID:             infraID,
-		infraNamespace:      infraNamespace,
+		kubevirtVM:             kubevirtVM,
+		eventRecorder:          eventRecorder,
+		machineScopeCreator:    machineScopeCreator,
+		tenantClusterClient:    tenantClusterClient,
+		infraID:                infraID,
+		infraNamespace:         infraNamespace,
+		infraMaintenance:       infraMaintenance,
+		credentialsKubevirtVMs: credentialsKubevirtVMs,
 	}, nil
 }
 
@@ -94,15 +125,28 @@ func (a *actuator) createMachineScope(machine *machinev1.Machine) (machinescope.
 	return a.machineScopeCreator.CreateMachineScope(machine, a.infraNamespace, a.infraID)
 }
 
-// Set corresponding event based on error. It also returns the original error
-// for convenience, so callers can do "return handleMachineError(...)".
+// kubevirtVMOf returns the KubevirtVM of the InfraCluster of the Machine: the one of the credentials secret named
+// by its provider spec, in the namespace of the Machine, and the one of the controller otherwise
+func (a *actuator) kubevirtVMOf(ctx context.Context, machineScope machinescope.MachineScope) (kubevirt.KubevirtVM, error) {
+	if a.credentialsKubevirtVMs == nil {
+		return a.kubevirtVM, nil
+	}
+	secretName := machineScope.GetCredentialsSecretName()
+	if secretName == "" {
+		return a.kubevirtVM, nil
+	}
+	return a.credentialsKubevirtVMs(ctx, machineScope.GetMachineNamespace(), secretName)
+}
+
+// Set corresponding event based on error. It also returns the original error, wrapped
+// so its cause can still be inspected, for convenience, so callers can do "return handleMachineError(...)".
 func (a *actuator) handleMachineError(machine *machinev1.Machine, action *eventAction, err error) error {
-	errMsg := fmt.Sprintf("%s: kubevirt wrapper failed to %s: %v", machine.GetName(), *action, err)
-	klog.Errorf(errMsg)
+	wrappedErr := errors.Wrapf(err, "%s: kubevirt wrapper failed to %s", machine.GetName(), *action)
+	klog.Errorf(wrappedErr.Error())
 	if action != nil {
-		a.eventRecorder.Eventf(machine, corev1.EventTypeWarning, fmt.Sprintf("%s failed", *action), errMsg)
+		a.eventRecorder.Eventf(machine, corev1.EventTypeWarning, fmt.Sprintf("%s failed", *action), wrappedErr.Error())
 	}
-	return fmt.Errorf(errMsg)
+	return wrappedErr
 }
 
 func (a *actuator) eventActionPointer(action eventAction) *eventAction {
@@ -111,29 +155,130 @@ func (a *actuator) eventActionPointer(action eventAction) *eventAction {
 
 // Create creates a machine and is invoked by the machine controller.
 func (a *actuator) Create(ctx context.Context, machine *machinev1.Machine) error {
-	originMachineCopy := machine.DeepCopy()
-
 	machineScope, err := a.createMachineScope(machine)
 	if err != nil {
 		return a.handleMachineError(machine, a.eventActionPointer(createEventAction), err)
 	}
+	kubevirtVM, err := a.kubevirtVMOf(ctx, machineScope)
+	if err != nil {
+		return a.handleMachineError(machine, a.eventActionPointer(createEventAction), err)
+	}
 
 	klog.Infof("%s: actuator creating machine", machineScope.GetMachineName())
 
+	machineSet, err := a.machineSetOfMachine(ctx, machine)
+	if err != nil {
+		return a.handleMachineError(machine, a.eventActionPointer(createEventAction), err)
+	}
+	if reconcilePaused(machine, machineSet) {
+		klog.Infof("%s: actuator skipping the creation of the paused machine", machineScope.GetMachineName())
+		return &machinecontroller.RequeueAfterError{RequeueAfter: pausedRequeueAfter}
+	}
+	maintenance, err := a.inInfraMaintenance(ctx)
+	if err != nil {
+		return a.handleMachineError(machine, a.eventActionPointer(createEventAction), err)
+	}
+	if maintenance {
+		return a.postponeForInfraMaintenance(machine, createEventAction)
+	}
+	if machineSet != nil {
+		backoff, err := a.quotaBackoff(kubevirtVM, machineScope, machineSet)
+		if err != nil {
+			return a.handleMachineError(machine, a.eventActionPointer(createEventAction), err)
+		}
+		if backoff {
+			klog.Infof("%s: actuator backing off, the quotas of the infra namespace are exhausted by MachineSet %s",
+				machineScope.GetMachineName(), machineSet.Name)
+			return &machinecontroller.RequeueAfterError{RequeueAfter: quotaBackoffRequeueAfter}
+		}
+		pending, err := networkCheckupPending(machineSet)
+		if err != nil {
+			return a.handleMachineError(machine, a.eventActionPointer(networkCheckupEventAction), err)
+		}
+		if pending {
+			klog.Infof("%s: actuator waiting for the checkup of the network of MachineSet %s",
+				machineScope.GetMachineName(), machineSet.Name)
+			return &machinecontroller.RequeueAfterError{RequeueAfter: networkCheckupRequeueAfter}
+		}
+	}
+
+	if zones := machineScope.GetZoneChoices(); len(zones) > 0 {
+		zone, err := a.selectZone(ctx, machine, machineSet, zones)
+		if err != nil {
+			return a.handleMachineError(machine, a.eventActionPointer(createEventAction), err)
+		}
+		klog.Infof("%s: actuator assigning the machine to zone %s", machineScope.GetMachineName(), zone)
+		machineScope.SetZone(zone)
+	}
+
+	if pools := machineScope.GetAddressesFromPools(); len(pools) > 0 {
+		addresses, allocated, err := a.claimAddresses(ctx, machine, pools)
+		if err != nil {
+			return a.handleMachineError(machine, a.eventActionPointer(createEventAction), err)
+		}
+		if !allocated {
+			klog.Infof("%s: actuator waiting for the addresses of the machine to be allocated", machineScope.GetMachineName())
+			return &machinecontroller.RequeueAfterError{RequeueAfter: addressClaimRequeueAfter}
+		}
+		machineScope.SetStaticAddresses(addresses)
+	}
+
+	if machineScope.ClusterProxyPropagationRequired() {
+		proxy, err := a.getClusterProxy(ctx)
+		if err != nil {
+			return a.handleMachineError(machine, a.eventActionPointer(createEventAction), err)
+		}
+		machineScope.SetClusterProxy(proxy)
+	}
+
 	userData, err := a.getUserData(machineScope)
 	if err != nil {
 		return a.handleMachineError(machine, a.eventActionPointer(createEventAction), err)
 	}
 
-	ready, err := a.kubevirtVM.Create(machineScope, userData)
-	patchErr := a.patchMachine(machineScope.GetMachine(), originMachineCopy)
+	if err := a.setCreateInProgress(machineScope); err != nil {
+		return a.handleMachineError(machine, a.eventActionPointer(createEventAction), err)
+	}
+
+	ready, err := kubevirtVM.Create(machineScope, userData)
+	if infracluster.IsCreationThrottled(err) {
+		klog.Infof("%s: actuator throttling the creation of the machine: %v", machineScope.GetMachineName(), err)
+		return &machinecontroller.RequeueAfterError{RequeueAfter: creationThrottleRequeueAfter}
+	}
+	var requeueErr *machinecontroller.RequeueAfterError
+	if errors.As(err, &requeueErr) {
+		klog.Infof("%s: actuator waiting for the creation of the machine to progress", machineScope.GetMachineName())
+		return err
+	}
+	if err == nil {
+		delete(machine.Annotations, createInProgressAnnotation)
+		delete(machine.Annotations, machinescope.CreateStepsAnnotation)
+	}
+	// The mark of the MachineSet is kept until the machine is ready, since the quotas may still reject its pod
+	// or its PVCs
+	if machineSet != nil && kubevirt.IsQuotaExceeded(err) {
+		a.markQuotaExhausted(kubevirtVM, machineSet, machine, err)
+	}
+	if conditionErr := machineScope.SetMachineCreationCondition(err); conditionErr != nil {
+		klog.Errorf("%s: failed to set the machine creation condition, with error: %v", machineScope.GetMachineName(), conditionErr)
+	}
+	patchErr := machineScope.PatchMachine()
 	if patchErr != nil {
 		err = patchErr
 	}
 	if err != nil {
+		if kubevirt.IsImageDigestMismatch(err) {
+			return a.handleMachineError(machine, a.eventActionPointer(sourceImageEventAction), err)
+		}
+		if kubevirt.IsIgnitionSourceUnreachable(err) {
+			return a.handleMachineError(machine, a.eventActionPointer(ignitionSourceEventAction), err)
+		}
 		return a.handleMachineError(machine, a.eventActionPointer(createEventAction), err)
 	}
 
+	if decision := machineScope.GetPersistentVolumeAccessModeDecision(); decision != "" {
+		a.eventRecorder.Eventf(machine, corev1.EventTypeNormal, string(accessModeEventAction), "Machine %v: %s", machineScope.GetMachineName(), decision)
+	}
 	a.eventRecorder.Eventf(machine, corev1.EventTypeNormal, string(createEventAction), "Created Machine %v", machineScope.GetMachineName())
 
 	if !ready {
@@ -143,9 +288,40 @@ func (a *actuator) Create(ctx context.Context, machine *machinev1.Machine) error
 	return nil
 }
 
+// setCreateInProgress persists the create in progress annotation on the machine, before any of its resources
+// is created in the infra-cluster
+func (a *actuator) setCreateInProgress(machineScope machinescope.MachineScope) error {
+	machine := machineScope.GetMachine()
+	if _, ok := machine.Annotations[createInProgressAnnotation]; ok {
+		return nil
+	}
+	if machine.Annotations == nil {
+		machine.Annotations = map[string]string{}
+	}
+	machine.Annotations[createInProgressAnnotation] = time.Now().UTC().Format(time.RFC3339)
+	return machineScope.PatchMachineMetadata()
+}
+
+// createInterrupted returns true if the creation of the machine started but didn't complete, and the machine
+// wasn't provisioned yet, so the machine controller calls Create again instead of taking the machine as failed
+func createInterrupted(machine *machinev1.Machine) bool {
+	if _, ok := machine.Annotations[createInProgressAnnotation]; !ok || machine.DeletionTimestamp != nil {
+		return false
+	}
+	return machine.Spec.ProviderID == nil && len(machine.Status.Addresses) == 0
+}
+
 func (a *actuator) getUserData(machineScope machinescope.MachineScope) ([]byte, error) {
+	if machineScope.GetInfraIgnitionSecretName() != "" {
+		// The ignition is distributed to the infra-cluster by another channel
+		return nil, nil
+	}
 	secretName := machineScope.GetIgnitionSecretName()
 	machineNamespace := machineScope.GetMachineNamespace()
+	if machineScope.IgnitionSecretNameDefaulted() {
+		a.eventRecorder.Eventf(machineScope.GetMachine(), corev1.EventTypeNormal, string(userDataEventAction),
+			"IgnitionSecretName isn't set, using the user-data of secret %s/%s", machineNamespace, secretName)
+	}
 	userDataSecret, err := a.tenantClusterClient.GetSecret(context.Background(), secretName, machineNamespace)
 	if err != nil {
 		if apimachineryerrors.IsNotFound(err) {
@@ -160,31 +336,122 @@ func (a *actuator) getUserData(machineScope machinescope.MachineScope) ([]byte,
 	return userData, nil
 }
 
+// getClusterProxy returns the cluster-wide proxy of the tenant-cluster, or nil when it has none
+func (a *actuator) getClusterProxy(ctx context.Context) (*machinescope.ClusterProxy, error) {
+	proxy, err := a.tenantClusterClient.GetClusterProxy(ctx)
+	if err != nil || proxy == nil {
+		return nil, err
+	}
+	return &machinescope.ClusterProxy{
+		HTTPProxy:   proxy.HTTPProxy,
+		HTTPSProxy:  proxy.HTTPSProxy,
+		NoProxy:     proxy.NoProxy,
+		TrustBundle: proxy.TrustBundle,
+	}, nil
+}
+
 // Exists determines if the given machine currently exists.
 // A machine which is not terminated is considered as existing.
 func (a *actuator) Exists(ctx context.Context, machine *machinev1.Machine) (bool, error) {
 	klog.Infof("%s: actuator checking if machine exists", machine.GetName())
 
-	return a.kubevirtVM.Exists(machine.GetName(), a.infraNamespace)
+	machineScope, err := a.createMachineScope(machine)
+	if err != nil {
+		return false, err
+	}
+	virtualMachineName, err := machineScope.GetVirtualMachineName()
+	if err != nil {
+		return false, err
+	}
+	kubevirtVM, err := a.kubevirtVMOf(ctx, machineScope)
+	if err != nil {
+		return false, err
+	}
+	if createInterrupted(machine) {
+		klog.Infof("%s: creation of the machine was interrupted - resume it", machine.GetName())
+		return false, nil
+	}
+
+	return kubevirtVM.Exists(virtualMachineName, a.infraNamespace)
 }
 
 // Update attempts to sync machine state with an existing instance.
 func (a *actuator) Update(ctx context.Context, machine *machinev1.Machine) error {
-	originMachineCopy := machine.DeepCopy()
-
 	machineScope, err := a.createMachineScope(machine)
 	if err != nil {
 		return a.handleMachineError(machine, a.eventActionPointer(updateEventAction), err)
 	}
+	kubevirtVM, err := a.kubevirtVMOf(ctx, machineScope)
+	if err != nil {
+		return a.handleMachineError(machine, a.eventActionPointer(updateEventAction), err)
+	}
 
 	klog.Infof("%s: actuator updating machine", machineScope.GetMachineName())
 
-	wasUpdated, ready, err := a.kubevirtVM.Update(machineScope)
-	patchErr := a.patchMachine(machineScope.GetMachine(), originMachineCopy)
+	machineSet, err := a.machineSetOfMachine(ctx, machine)
+	if err != nil {
+		return a.handleMachineError(machine, a.eventActionPointer(updateEventAction), err)
+	}
+	paused := reconcilePaused(machine, machineSet)
+	if !paused {
+		if paused, err = a.inInfraMaintenance(ctx); err != nil {
+			return a.handleMachineError(machine, a.eventActionPointer(updateEventAction), err)
+		}
+	}
+	if paused {
+		klog.Infof("%s: actuator only syncing the status of the paused machine", machineScope.GetMachineName())
+		ready, err := kubevirtVM.SyncStatus(machineScope)
+		a.reportMigration(machineScope.GetMachine(), machineScope.GetOriginalMachine())
+		if patchErr := machineScope.PatchMachine(); patchErr != nil {
+			err = patchErr
+		}
+		if err != nil {
+			return a.handleMachineError(machine, a.eventActionPointer(updateEventAction), err)
+		}
+		if !ready {
+			return fmt.Errorf("Error since VirtualMachine is not ready - requeue")
+		}
+		return nil
+	}
+
+	mode, err := machineScope.GetReconciliationMode()
+	if err != nil {
+		return a.handleMachineError(machine, a.eventActionPointer(updateEventAction), err)
+	}
+	// The resources of the VirtualMachine are only changed, and therefore restarted for, in full reconciliation
+	stopping := false
+	if mode == kubevirtproviderv1alpha1.ReconciliationModeFull {
+		stopping, err = a.reconcileResize(ctx, kubevirtVM, machineScope)
+	}
+	if stopping || err != nil {
+		if patchErr := machineScope.PatchMachine(); patchErr != nil && err == nil {
+			err = patchErr
+		}
+		if err != nil {
+			return a.handleMachineError(machine, a.eventActionPointer(resizeEventAction), err)
+		}
+		klog.Infof("%s: actuator waiting for the VirtualMachine to stop before restarting it", machineScope.GetMachineName())
+		return &machinecontroller.RequeueAfterError{RequeueAfter: resizeRequeueAfter}
+	}
+
+	wasUpdated, ready, err := kubevirtVM.Update(machineScope)
+	if err == nil && ready {
+		err = a.completeResize(ctx, machineScope.GetMachine())
+	}
+	if err == nil && machineSet != nil {
+		a.reconcileQuotaRejection(kubevirtVM, machineScope, machineSet, ready)
+	}
+	a.reportMigration(machineScope.GetMachine(), machineScope.GetOriginalMachine())
+	patchErr := machineScope.PatchMachine()
 	if patchErr != nil {
 		err = patchErr
 	}
 	if err != nil {
+		var requeueErr *machinecontroller.RequeueAfterError
+		if errors.As(err, &requeueErr) {
+			klog.Infof("%s: actuator waiting for the VirtualMachine to be found", machineScope.GetMachineName())
+			return err
+		}
 		return a.handleMachineError(machine, a.eventActionPointer(updateEventAction), err)
 	}
 
@@ -206,33 +473,64 @@ func (a *actuator) Delete(ctx context.Context, machine *machinev1.Machine) error
 	if err != nil {
 		return a.handleMachineError(machine, a.eventActionPointer(deleteEventAction), err)
 	}
+	kubevirtVM, err := a.kubevirtVMOf(ctx, machineScope)
+	if err != nil {
+		return a.handleMachineError(machine, a.eventActionPointer(deleteEventAction), err)
+	}
 
 	klog.Infof("%s: actuator deleting machine", machineScope.GetMachineName())
 
-	if err := a.kubevirtVM.Delete(machineScope); err != nil {
+	machineSet, err := a.machineSetOfMachine(ctx, machine)
+	if err != nil {
 		return a.handleMachineError(machine, a.eventActionPointer(deleteEventAction), err)
 	}
-
-	a.eventRecorder.Eventf(machine, corev1.EventTypeNormal, string(deleteEventAction), "Deleted machine %v", machineScope.GetMachineName())
-	return nil
-}
-
-// Patch patches the machine spec and machine status after reconciling.
-func (a *actuator) patchMachine(machine *machinev1.Machine, originMachineCopy *machinev1.Machine) error {
-	klog.V(3).Infof("%v: patching machine", machine.GetName())
-
-	// patch machine
-	statusCopy := *machine.Status.DeepCopy()
-	if err := a.tenantClusterClient.PatchMachine(machine, originMachineCopy); err != nil {
-		return errors.Wrap(err, "failed to patch machine")
+	if reconcilePaused(machine, machineSet) {
+		klog.Infof("%s: actuator postponing the deletion of the paused machine", machineScope.GetMachineName())
+		return &machinecontroller.RequeueAfterError{RequeueAfter: pausedRequeueAfter}
+	}
+	maintenance, err := a.inInfraMaintenance(ctx)
+	if err != nil {
+		return a.handleMachineError(machine, a.eventActionPointer(deleteEventAction), err)
+	}
+	if maintenance {
+		return a.postponeForInfraMaintenance(machine, deleteEventAction)
+	}
+	if machineScope.NodeDrainRequiredBeforeDelete() && machine.Status.NodeRef != nil {
+		nodeName := machine.Status.NodeRef.Name
+		drained, err := a.tenantClusterClient.IsNodeDrained(ctx, nodeName)
+		if err != nil {
+			return a.handleMachineError(machine, a.eventActionPointer(deleteEventAction), err)
+		}
+		if !drained {
+			klog.Infof("%s: actuator waiting for node %s to be drained", machineScope.GetMachineName(), nodeName)
+			a.eventRecorder.Eventf(machine, corev1.EventTypeWarning, string(deleteEventAction),
+				"Node %v still runs workloads, the VirtualMachine is deleted once the node is drained or tainted out-of-service", nodeName)
+			return &machinecontroller.RequeueAfterError{RequeueAfter: nodeDrainRequeueAfter}
+		}
+	}
+	// The VirtualMachine isn't deleted until the lifecycle hooks of the termination are removed. The preDrain hooks
+	// are honored by the machine controller, which drains the node before it calls Delete.
+	hooks, err := a.tenantClusterClient.GetMachineLifecycleHooks(ctx, machine.Name, machine.Namespace)
+	if err != nil {
+		return a.handleMachineError(machine, a.eventActionPointer(deleteEventAction), err)
+	}
+	if err := a.waitForLifecycleHooks(machine, "preTerminate", hooks.PreTerminate); err != nil {
+		return err
 	}
 
-	machine.Status = statusCopy
+	if err := kubevirtVM.Delete(machineScope); err != nil {
+		var requeueErr *machinecontroller.RequeueAfterError
+		if errors.As(err, &requeueErr) {
+			klog.Infof("%s: actuator waiting for the VirtualMachine to shut down", machineScope.GetMachineName())
+			return err
+		}
+		return a.handleMachineError(machine, a.eventActionPointer(deleteEventAction), err)
+	}
 
-	// patch status
-	if err := a.tenantClusterClient.StatusPatchMachine(machine, originMachineCopy); err != nil {
-		return errors.Wrap(err, "failed to patch machine status")
+	if err := a.releaseAddresses(ctx, machine, machineScope.GetAddressesFromPools()); err != nil {
+		return a.handleMachineError(machine, a.eventActionPointer(deleteEventAction), err)
 	}
 
+	a.eventRecorder.Eventf(machine, corev1.EventTypeNormal, string(deleteEventAction), "Deleted machine %v", machineScope.GetMachineName())
 	return nil
 }
@@ -1,10 +1,29 @@
 package actuator
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
 
+	"github.com/golang/mock/gomock"
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+	mockTenantClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster/mock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/kubevirt"
+	mockKubevirt "github.com/openshift/cluster-api-provider-kubevirt/pkg/kubevirt/mock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/testutils"
 	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	apimachineryerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
 )
 
 func init() {
@@ -20,3 +39,411 @@ func TestHandleMachineErrors(t *testing.T) {
 	// TODO implement
 
 }
+
+func TestCreatePatchesMachine(t *testing.T) {
+	cMap := map[string]string{
+		configMapInfraNamespaceKeyName: testutils.InfraNamespace,
+		configMapInfraIDKeyName:        testutils.InfraID,
+	}
+	providerID := fmt.Sprintf("kubevirt://%s/%s", testutils.InfraNamespace, testutils.MachineName)
+
+	cases := []struct {
+		name              string
+		createErr         error
+		expectedErr       string
+		expectedCondition kubevirtproviderv1alpha1.KubevirtMachineProviderConditionReason
+	}{
+		{
+			name:              "Success",
+			expectedCondition: kubevirtproviderv1alpha1.MachineCreationSucceeded,
+		},
+		{
+			name:              "Failure create virtual machine",
+			createErr:         fmt.Errorf("test error"),
+			expectedErr:       "test-machine-name: kubevirt wrapper failed to create machine: test error",
+			expectedCondition: kubevirtproviderv1alpha1.MachineCreationFailed,
+		},
+		{
+			name:              "Failure source image mismatch",
+			createErr:         &kubevirt.ImageDigestMismatchError{PVC: "rhcos", ExpectedDigest: "sha256:1234", Digest: "sha256:5678"},
+			expectedErr:       "test-machine-name: kubevirt wrapper failed to verify source image: the source PVC rhcos has image digest \"sha256:5678\", while the Machine is pinned to sha256:1234",
+			expectedCondition: kubevirtproviderv1alpha1.MachineCreationFailed,
+		},
+		{
+			name:              "Failure ignition source unreachable",
+			createErr:         &kubevirt.IgnitionSourceUnreachableError{Sources: []string{"https://192.168.123.15:22623/config/worker"}, Message: "curl: (28) Connection timed out"},
+			expectedErr:       "test-machine-name: kubevirt wrapper failed to check ignition source: the ignition sources https://192.168.123.15:22623/config/worker are unreachable from the network of the Machine: curl: (28) Connection timed out",
+			expectedCondition: kubevirtproviderv1alpha1.MachineCreationFailed,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			tenantClient := mockTenantClusterClient.NewMockClient(mockCtrl)
+			kubevirtVM := mockKubevirt.NewMockKubevirtVM(mockCtrl)
+
+			machine, err := testutils.StubMachine()
+			assert.NilError(t, err)
+			vm := testutils.StubVirtualMachine(nil, nil, testutils.StringPointer("test-vm-id"))
+			vm.Status.Created = true
+			vm.Status.Ready = true
+			vmi := testutils.StubVirtualMachineInstance()
+
+			tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(2)
+			tenantClient.EXPECT().GetSecret(gomock.Any(), testutils.IgnitionSecretName, machine.Namespace).Return(&corev1.Secret{
+				Data: map[string][]byte{userDataKey: []byte(testutils.SrcUserData)},
+			}, nil).Times(1)
+			kubevirtVM.EXPECT().Create(gomock.Any(), []byte(testutils.SrcUserData)).DoAndReturn(
+				func(machineScope machinescope.MachineScope, userData []byte) (bool, error) {
+					if tc.createErr != nil {
+						return false, tc.createErr
+					}
+					return true, machineScope.SyncMachine(*vm, vmi, providerID)
+				}).Times(1)
+
+			var patchedMachines []*machinev1.Machine
+			var statusPatchedMachine *machinev1.Machine
+			tenantClient.EXPECT().PatchMachine(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(machine *machinev1.Machine, originMachineCopy *machinev1.Machine) error {
+					assert.Assert(t, originMachineCopy.Status.ProviderStatus == nil)
+					patchedMachines = append(patchedMachines, machine.DeepCopy())
+					return nil
+				}).Times(2)
+			tenantClient.EXPECT().StatusPatchMachine(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(machine *machinev1.Machine, originMachineCopy *machinev1.Machine) error {
+					statusPatchedMachine = machine.DeepCopy()
+					return nil
+				}).Times(1)
+
+			a, err := New(kubevirtVM, record.NewFakeRecorder(10), machinescope.New(machinescope.MetadataFilter{}, tenantClient), tenantClient, false, nil)
+			assert.NilError(t, err)
+			err = a.Create(context.Background(), machine)
+			// The creation is marked in progress before it starts, and the mark is cleared once it succeeds
+			_, inProgress := patchedMachines[0].Annotations[createInProgressAnnotation]
+			assert.Assert(t, inProgress)
+			patchedMachine := patchedMachines[1]
+			_, inProgress = patchedMachine.Annotations[createInProgressAnnotation]
+			assert.Equal(t, inProgress, tc.createErr != nil)
+			if tc.expectedErr != "" {
+				assert.Error(t, err, tc.expectedErr)
+			} else {
+				assert.NilError(t, err)
+				assert.Equal(t, providerID, *patchedMachine.Spec.ProviderID)
+				assert.Equal(t, "test-vm-id", patchedMachine.Annotations[machinescope.KubevirtIdAnnotationKey])
+				assert.Equal(t, vmi.Name, statusPatchedMachine.Status.Addresses[0].Address)
+			}
+
+			providerStatus, err := kubevirtproviderv1alpha1.ProviderStatusFromRawExtension(statusPatchedMachine.Status.ProviderStatus)
+			assert.NilError(t, err)
+			assert.Equal(t, tc.createErr == nil, providerStatus.Ready)
+			assert.Equal(t, len(providerStatus.ProviderConditions), 1)
+			assert.Equal(t, tc.expectedCondition, providerStatus.ProviderConditions[0].Reason)
+		})
+	}
+}
+
+func TestCreateInterrupted(t *testing.T) {
+	machine, err := testutils.StubMachine()
+	assert.NilError(t, err)
+	machine.Spec.ProviderID = nil
+	assert.Assert(t, !createInterrupted(machine))
+
+	machine.Annotations = map[string]string{createInProgressAnnotation: "2021-05-12T13:18:17Z"}
+	assert.Assert(t, createInterrupted(machine))
+
+	// A provisioned machine whose instance is gone is failed by the machine controller as usual
+	machine.Spec.ProviderID = pointer.StringPtr("kubevirt://test-namespace/test-machine-name")
+	assert.Assert(t, !createInterrupted(machine))
+
+	machine.Spec.ProviderID = nil
+	machine.DeletionTimestamp = &metav1.Time{}
+	assert.Assert(t, !createInterrupted(machine))
+}
+
+func TestGetUserDataFallback(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	tenantClient := mockTenantClusterClient.NewMockClient(mockCtrl)
+
+	machine, err := testutils.StubMachine()
+	assert.NilError(t, err)
+	machine.Labels["machine.openshift.io/cluster-api-machine-role"] = "worker"
+	providerSpec := testutils.ProviderSpec
+	providerSpec.IgnitionSecretName = ""
+	machine.Spec.ProviderSpec.Value, err = kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&providerSpec)
+	assert.NilError(t, err)
+	machineScope, err := machinescope.New(machinescope.MetadataFilter{}, tenantClient).CreateMachineScope(machine, testutils.InfraNamespace, testutils.InfraID)
+	assert.NilError(t, err)
+
+	tenantClient.EXPECT().GetSecret(gomock.Any(), "worker-user-data", machine.Namespace).Return(&corev1.Secret{
+		Data: map[string][]byte{userDataKey: []byte(testutils.SrcUserData)},
+	}, nil).Times(1)
+
+	eventRecorder := record.NewFakeRecorder(10)
+	a := &actuator{tenantClusterClient: tenantClient, eventRecorder: eventRecorder}
+	userData, err := a.getUserData(machineScope)
+	assert.NilError(t, err)
+	assert.Equal(t, string(userData), testutils.SrcUserData)
+	assert.Equal(t, <-eventRecorder.Events,
+		fmt.Sprintf("Normal select user-data secret IgnitionSecretName isn't set, using the user-data of secret %s/worker-user-data", machine.Namespace))
+}
+
+func TestCreateQuotaBackoff(t *testing.T) {
+	cMap := map[string]string{
+		configMapInfraNamespaceKeyName: testutils.InfraNamespace,
+		configMapInfraIDKeyName:        testutils.InfraID,
+	}
+	quotaErr := apimachineryerrors.NewForbidden(schema.GroupResource{Group: "kubevirt.io", Resource: "virtualmachines"}, testutils.MachineName,
+		fmt.Errorf("exceeded quota: tenant-quota"))
+
+	cases := []struct {
+		name               string
+		annotation         *string
+		quotaVersion       string
+		exceededQuotas     []string
+		createErr          error
+		expectCreate       bool
+		expectedAnnotation *string
+		expectedRequeue    bool
+	}{
+		{
+			name:               "Backoff while the quotas didn't change",
+			annotation:         testutils.StringPointer("tenant-quota/pods=5/5"),
+			quotaVersion:       "tenant-quota/pods=5/5",
+			expectedAnnotation: testutils.StringPointer("tenant-quota/pods=5/5"),
+			expectedRequeue:    true,
+		},
+		{
+			name:               "Backoff while the quotas don't have room for the machine",
+			annotation:         testutils.StringPointer("tenant-quota/pods=5/5"),
+			quotaVersion:       "tenant-quota/pods=5/6,tenant-quota/requests.memory=6Gi/6Gi",
+			exceededQuotas:     []string{"tenant-quota/requests.memory"},
+			expectedAnnotation: testutils.StringPointer("tenant-quota/pods=5/5"),
+			expectedRequeue:    true,
+		},
+		{
+			name:               "Mark the machine set when the quota is exceeded",
+			quotaVersion:       "tenant-quota/pods=5/5",
+			createErr:          quotaErr,
+			expectCreate:       true,
+			expectedAnnotation: testutils.StringPointer("tenant-quota/pods=5/5"),
+		},
+		{
+			name:               "Retry once the quotas have room for the machine",
+			annotation:         testutils.StringPointer("tenant-quota/pods=5/5"),
+			quotaVersion:       "tenant-quota/pods=5/6",
+			createErr:          quotaErr,
+			expectCreate:       true,
+			expectedAnnotation: testutils.StringPointer("tenant-quota/pods=5/6"),
+		},
+		{
+			name:               "Keep the mark until the machine is ready",
+			annotation:         testutils.StringPointer("tenant-quota/pods=5/5"),
+			quotaVersion:       "tenant-quota/pods=5/6",
+			expectCreate:       true,
+			expectedAnnotation: testutils.StringPointer("tenant-quota/pods=5/5"),
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			tenantClient := mockTenantClusterClient.NewMockClient(mockCtrl)
+			kubevirtVM := mockKubevirt.NewMockKubevirtVM(mockCtrl)
+
+			machine, err := testutils.StubMachine()
+			assert.NilError(t, err)
+			machine.OwnerReferences = []metav1.OwnerReference{{Kind: "MachineSet", Name: "test-machine-set", Controller: pointer.BoolPtr(true)}}
+			machineSet := &machinev1.MachineSet{ObjectMeta: metav1.ObjectMeta{Name: "test-machine-set", Namespace: machine.Namespace}}
+			if tc.annotation != nil {
+				machineSet.Annotations = map[string]string{quotaExhaustedAnnotation: *tc.annotation}
+			}
+
+			tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(2)
+			tenantClient.EXPECT().GetMachineSet(gomock.Any(), "test-machine-set", machine.Namespace).Return(machineSet, nil).Times(1)
+			kubevirtVM.EXPECT().QuotaVersion(testutils.InfraNamespace).Return(tc.quotaVersion, nil).AnyTimes()
+			kubevirtVM.EXPECT().ExceededQuotas(gomock.Any()).Return(tc.exceededQuotas, nil).AnyTimes()
+			if tc.expectCreate {
+				tenantClient.EXPECT().GetSecret(gomock.Any(), testutils.IgnitionSecretName, machine.Namespace).Return(&corev1.Secret{
+					Data: map[string][]byte{userDataKey: []byte(testutils.SrcUserData)},
+				}, nil).Times(1)
+				kubevirtVM.EXPECT().Create(gomock.Any(), gomock.Any()).Return(false, tc.createErr).Times(1)
+				tenantClient.EXPECT().PatchMachine(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+				tenantClient.EXPECT().StatusPatchMachine(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+			}
+			var patchedMachineSet *machinev1.MachineSet
+			tenantClient.EXPECT().PatchMachineSet(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(machineSet *machinev1.MachineSet, originMachineSetCopy *machinev1.MachineSet) error {
+					patchedMachineSet = machineSet.DeepCopy()
+					return nil
+				}).MaxTimes(1)
+
+			a, err := New(kubevirtVM, record.NewFakeRecorder(10), machinescope.New(machinescope.MetadataFilter{}, tenantClient), tenantClient, false, nil)
+			assert.NilError(t, err)
+			err = a.Create(context.Background(), machine)
+
+			var requeueErr *machinecontroller.RequeueAfterError
+			assert.Equal(t, errors.As(err, &requeueErr), tc.expectedRequeue)
+			if patchedMachineSet != nil {
+				machineSet = patchedMachineSet
+			}
+			annotation, ok := machineSet.Annotations[quotaExhaustedAnnotation]
+			if tc.expectedAnnotation == nil {
+				assert.Assert(t, !ok)
+			} else {
+				assert.Equal(t, annotation, *tc.expectedAnnotation)
+			}
+		})
+	}
+}
+
+func TestUpdateQuotaRejection(t *testing.T) {
+	cMap := map[string]string{
+		configMapInfraNamespaceKeyName: testutils.InfraNamespace,
+		configMapInfraIDKeyName:        testutils.InfraID,
+	}
+	rejection := `Error creating pod: pods "virt-launcher-test-machine-name-x2v4k" is forbidden: exceeded quota: tenant-quota`
+
+	cases := []struct {
+		name               string
+		annotation         *string
+		ready              bool
+		rejection          string
+		expectedAnnotation *string
+		expectedEvents     int
+	}{
+		{
+			name:               "Mark the machine set when the pod of the machine is rejected",
+			rejection:          rejection,
+			expectedAnnotation: testutils.StringPointer("tenant-quota/pods=5/5"),
+			expectedEvents:     1,
+		},
+		{
+			name:               "Report the rejection once per version of the quotas",
+			annotation:         testutils.StringPointer("tenant-quota/pods=5/5"),
+			rejection:          rejection,
+			expectedAnnotation: testutils.StringPointer("tenant-quota/pods=5/5"),
+		},
+		{
+			name: "Leave the machine set unmarked while the machine is provisioned",
+		},
+		{
+			name:       "Clear the mark once the machine is ready",
+			annotation: testutils.StringPointer("tenant-quota/pods=5/5"),
+			ready:      true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			tenantClient := mockTenantClusterClient.NewMockClient(mockCtrl)
+			kubevirtVM := mockKubevirt.NewMockKubevirtVM(mockCtrl)
+
+			machine, err := testutils.StubMachine()
+			assert.NilError(t, err)
+			machine.OwnerReferences = []metav1.OwnerReference{{Kind: "MachineSet", Name: "test-machine-set", Controller: pointer.BoolPtr(true)}}
+			machineSet := &machinev1.MachineSet{ObjectMeta: metav1.ObjectMeta{Name: "test-machine-set", Namespace: machine.Namespace}}
+			if tc.annotation != nil {
+				machineSet.Annotations = map[string]string{quotaExhaustedAnnotation: *tc.annotation}
+			}
+
+			tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).AnyTimes()
+			tenantClient.EXPECT().GetMachineSet(gomock.Any(), "test-machine-set", machine.Namespace).Return(machineSet, nil).Times(1)
+			tenantClient.EXPECT().PatchMachine(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			tenantClient.EXPECT().StatusPatchMachine(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			kubevirtVM.EXPECT().Update(gomock.Any()).Return(false, tc.ready, nil).Times(1)
+			kubevirtVM.EXPECT().QuotaRejection(gomock.Any()).Return(tc.rejection, nil).AnyTimes()
+			kubevirtVM.EXPECT().QuotaVersion(testutils.InfraNamespace).Return("tenant-quota/pods=5/5", nil).AnyTimes()
+			var patchedMachineSet *machinev1.MachineSet
+			tenantClient.EXPECT().PatchMachineSet(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(machineSet *machinev1.MachineSet, originMachineSetCopy *machinev1.MachineSet) error {
+					patchedMachineSet = machineSet.DeepCopy()
+					return nil
+				}).MaxTimes(1)
+
+			eventRecorder := record.NewFakeRecorder(10)
+			a, err := New(kubevirtVM, eventRecorder, machinescope.New(machinescope.MetadataFilter{}, tenantClient), tenantClient, false, nil)
+			assert.NilError(t, err)
+			err = a.Update(context.Background(), machine)
+			if tc.ready {
+				assert.NilError(t, err)
+			}
+
+			if patchedMachineSet != nil {
+				machineSet = patchedMachineSet
+			}
+			annotation, ok := machineSet.Annotations[quotaExhaustedAnnotation]
+			if tc.expectedAnnotation == nil {
+				assert.Assert(t, !ok)
+			} else {
+				assert.Equal(t, annotation, *tc.expectedAnnotation)
+			}
+			close(eventRecorder.Events)
+			quotaEvents := 0
+			for event := range eventRecorder.Events {
+				if strings.Contains(event, string(quotaEventAction)) {
+					quotaEvents++
+				}
+			}
+			assert.Equal(t, quotaEvents, tc.expectedEvents)
+		})
+	}
+}
+
+func TestKubevirtVMOf(t *testing.T) {
+	cases := []struct {
+		name                  string
+		credentialsSecretName string
+		credentialsVMs        bool
+		expectCredentialsVM   bool
+	}{
+		{
+			name:                  "machine naming a credentials secret",
+			credentialsSecretName: "tenant-credentials",
+			credentialsVMs:        true,
+			expectCredentialsVM:   true,
+		},
+		{
+			name:           "machine without a credentials secret",
+			credentialsVMs: true,
+		},
+		{
+			name:                  "credentials secrets not supported",
+			credentialsSecretName: "tenant-credentials",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			kubevirtVM := mockKubevirt.NewMockKubevirtVM(mockCtrl)
+			credentialsVM := mockKubevirt.NewMockKubevirtVM(mockCtrl)
+			machine, err := testutils.StubMachine()
+			assert.NilError(t, err)
+			providerSpec, err := kubevirtproviderv1alpha1.ProviderSpecFromRawExtension(machine.Spec.ProviderSpec.Value)
+			assert.NilError(t, err)
+			providerSpec.CredentialsSecretName = tc.credentialsSecretName
+			machine.Spec.ProviderSpec.Value, err = kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(providerSpec)
+			assert.NilError(t, err)
+			machineScope, err := machinescope.New(machinescope.MetadataFilter{}, nil).CreateMachineScope(machine, "test-infra-namespace", "test-infra-id")
+			assert.NilError(t, err)
+
+			a := &actuator{kubevirtVM: kubevirtVM}
+			if tc.credentialsVMs {
+				a.credentialsKubevirtVMs = func(_ context.Context, secretNamespace string, secretName string) (kubevirt.KubevirtVM, error) {
+					assert.Equal(t, secretNamespace, machine.Namespace)
+					assert.Equal(t, secretName, tc.credentialsSecretName)
+					return credentialsVM, nil
+				}
+			}
+			selected, err := a.kubevirtVMOf(context.Background(), machineScope)
+			assert.NilError(t, err)
+			if tc.expectCredentialsVM {
+				assert.Assert(t, selected == kubevirt.KubevirtVM(credentialsVM))
+			} else {
+				assert.Assert(t, selected == kubevirt.KubevirtVM(kubevirtVM))
+			}
+		})
+	}
+}
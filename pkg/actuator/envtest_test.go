@@ -0,0 +1,104 @@
+package actuator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster"
+	mockKubevirt "github.com/openshift/cluster-api-provider-kubevirt/pkg/kubevirt/mock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/testutils"
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+)
+
+// TestCreatePatchesMachineThroughAPIServer runs the actuator against a real API server, with the Machine CRD of
+// the machine-api-operator installed, so the patches of the Machine spec and status go through the API machinery.
+// It requires the etcd and kube-apiserver binaries of envtest, fetched by hack/fetch-ext-bins.sh.
+func TestCreatePatchesMachineThroughAPIServer(t *testing.T) {
+	if os.Getenv("TEST_ASSET_KUBE_APISERVER") == "" && os.Getenv("KUBEBUILDER_ASSETS") == "" {
+		t.Skip("neither TEST_ASSET_KUBE_APISERVER nor KUBEBUILDER_ASSETS is set, the envtest binaries are required")
+	}
+	testEnv := &envtest.Environment{
+		CRDDirectoryPaths:     []string{filepath.Join("testdata", "crds")},
+		ErrorIfCRDPathMissing: true,
+	}
+	cfg, err := testEnv.Start()
+	assert.NilError(t, err)
+	defer func() {
+		assert.NilError(t, testEnv.Stop())
+	}()
+
+	ctx := context.Background()
+	runtimeClient, err := client.New(cfg, client.Options{Scheme: scheme.Scheme})
+	assert.NilError(t, err)
+	tenantClient, err := tenantcluster.NewFromConfig(cfg)
+	assert.NilError(t, err)
+
+	machine, err := testutils.StubMachine()
+	assert.NilError(t, err)
+	machine.Spec.ProviderID = nil
+	config, err := json.Marshal(map[string]string{
+		configMapInfraNamespaceKeyName: testutils.InfraNamespace,
+		configMapInfraIDKeyName:        testutils.InfraID,
+	})
+	assert.NilError(t, err)
+	for _, object := range []client.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: configMapNamespace}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: machine.Namespace}},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: configMapName, Namespace: configMapNamespace},
+			Data:       map[string]string{configMapDataKeyName: string(config)},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: testutils.IgnitionSecretName, Namespace: machine.Namespace},
+			Data:       map[string][]byte{userDataKey: []byte(testutils.SrcUserData)},
+		},
+		machine,
+	} {
+		assert.NilError(t, runtimeClient.Create(ctx, object))
+	}
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	kubevirtVM := mockKubevirt.NewMockKubevirtVM(mockCtrl)
+	providerID := fmt.Sprintf("kubevirt://%s/%s", testutils.InfraNamespace, testutils.MachineName)
+	vm := testutils.StubVirtualMachine(nil, nil, testutils.StringPointer("test-vm-id"))
+	vm.Status.Created = true
+	vm.Status.Ready = true
+	vmi := testutils.StubVirtualMachineInstance()
+	kubevirtVM.EXPECT().Create(gomock.Any(), []byte(testutils.SrcUserData)).DoAndReturn(
+		func(machineScope machinescope.MachineScope, userData []byte) (bool, error) {
+			return true, machineScope.SyncMachine(*vm, vmi, providerID)
+		}).Times(1)
+
+	a, err := New(kubevirtVM, record.NewFakeRecorder(10), machinescope.New(machinescope.MetadataFilter{}, tenantClient), tenantClient, false, nil)
+	assert.NilError(t, err)
+	assert.NilError(t, a.Create(ctx, machine))
+
+	result := &machinev1.Machine{}
+	assert.NilError(t, runtimeClient.Get(ctx, client.ObjectKey{Namespace: machine.Namespace, Name: machine.Name}, result))
+	assert.Equal(t, providerID, *result.Spec.ProviderID)
+	assert.Equal(t, "test-vm-id", result.Annotations[machinescope.KubevirtIdAnnotationKey])
+	_, inProgress := result.Annotations[createInProgressAnnotation]
+	assert.Assert(t, !inProgress)
+	assert.Assert(t, len(result.Status.Addresses) > 0)
+	assert.Equal(t, vmi.Name, result.Status.Addresses[0].Address)
+	providerStatus, err := kubevirtproviderv1alpha1.ProviderStatusFromRawExtension(result.Status.ProviderStatus)
+	assert.NilError(t, err)
+	assert.Assert(t, providerStatus.Ready)
+	assert.Equal(t, len(providerStatus.ProviderConditions), 1)
+	assert.Equal(t, kubevirtproviderv1alpha1.MachineCreationSucceeded, providerStatus.ProviderConditions[0].Reason)
+}
@@ -0,0 +1,61 @@
+package actuator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apimachineryerrors "k8s.io/apimachinery/pkg/api/errors"
+
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
+)
+
+// addressClaimRequeueAfter is the delay before re-checking whether the address claims of a machine are allocated
+const addressClaimRequeueAfter = 20 * time.Second
+
+func buildAddressClaimName(machineName string, poolIndex int) string {
+	return fmt.Sprintf("%s-claim-%d", machineName, poolIndex)
+}
+
+// claimAddresses ensures an IPAddressClaim of the machine for every pool, and returns the allocated addresses,
+// or false when any of the claims isn't allocated yet
+func (a *actuator) claimAddresses(ctx context.Context, machine *machinev1.Machine, pools []kubevirtproviderv1alpha1.AddressesFromPool) ([]machinescope.StaticAddress, bool, error) {
+	addresses := []machinescope.StaticAddress{}
+	allocated := true
+	for i, pool := range pools {
+		claimName := buildAddressClaimName(machine.Name, i)
+		address, err := a.tenantClusterClient.GetClaimedIPAddress(ctx, claimName, machine.Namespace)
+		if apimachineryerrors.IsNotFound(err) {
+			group := pool.Group
+			poolRef := corev1.TypedLocalObjectReference{APIGroup: &group, Kind: pool.Resource, Name: pool.Name}
+			if err := a.tenantClusterClient.CreateIPAddressClaim(ctx, claimName, machine, poolRef); err != nil && !apimachineryerrors.IsAlreadyExists(err) {
+				return nil, false, fmt.Errorf("failed to create IPAddressClaim %s, with error: %v", claimName, err)
+			}
+			allocated = false
+			continue
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to get the address of IPAddressClaim %s, with error: %v", claimName, err)
+		}
+		if address == nil {
+			allocated = false
+			continue
+		}
+		addresses = append(addresses, machinescope.StaticAddress{Address: address.Address, Prefix: address.Prefix, Gateway: address.Gateway})
+	}
+	return addresses, allocated, nil
+}
+
+// releaseAddresses deletes the IPAddressClaims of the machine
+func (a *actuator) releaseAddresses(ctx context.Context, machine *machinev1.Machine, pools []kubevirtproviderv1alpha1.AddressesFromPool) error {
+	for i := range pools {
+		claimName := buildAddressClaimName(machine.Name, i)
+		if err := a.tenantClusterClient.DeleteIPAddressClaim(ctx, claimName, machine.Namespace); err != nil && !apimachineryerrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete IPAddressClaim %s, with error: %v", claimName, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package actuator
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster"
+	mockTenantClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster/mock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/testutils"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	apimachineryerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestClaimAddresses(t *testing.T) {
+	pools := []kubevirtproviderv1alpha1.AddressesFromPool{
+		{Group: "ipamcontroller.openshift.io", Resource: "IPPool", Name: "pool-a"},
+		{Group: "ipamcontroller.openshift.io", Resource: "IPPool", Name: "pool-b"},
+	}
+	notFound := apimachineryerrors.NewNotFound(schema.GroupResource{Group: "ipam.cluster.x-k8s.io", Resource: "ipaddressclaims"}, "claim")
+	addressA := &tenantcluster.IPAddress{Address: "10.0.0.5", Prefix: 24, Gateway: "10.0.0.1"}
+	addressB := &tenantcluster.IPAddress{Address: "fd00::5", Prefix: 64}
+
+	cases := []struct {
+		name              string
+		expect            func(tenantClient *mockTenantClusterClient.MockClient)
+		expectedAddresses []machinescope.StaticAddress
+		expectedAllocated bool
+		expectedErr       string
+	}{
+		{
+			name: "Success create the missing claims",
+			expect: func(tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().GetClaimedIPAddress(gomock.Any(), "test-machine-name-claim-0", gomock.Any()).Return(nil, notFound).Times(1)
+				tenantClient.EXPECT().GetClaimedIPAddress(gomock.Any(), "test-machine-name-claim-1", gomock.Any()).Return(nil, notFound).Times(1)
+				tenantClient.EXPECT().CreateIPAddressClaim(gomock.Any(), "test-machine-name-claim-0", gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, _ string, _ interface{}, poolRef corev1.TypedLocalObjectReference) error {
+						assert.Equal(t, *poolRef.APIGroup, "ipamcontroller.openshift.io")
+						assert.Equal(t, poolRef.Kind, "IPPool")
+						assert.Equal(t, poolRef.Name, "pool-a")
+						return nil
+					}).Times(1)
+				tenantClient.EXPECT().CreateIPAddressClaim(gomock.Any(), "test-machine-name-claim-1", gomock.Any(), gomock.Any()).Return(nil).Times(1)
+			},
+			expectedAddresses: []machinescope.StaticAddress{},
+		},
+		{
+			name: "Success claim not allocated yet",
+			expect: func(tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().GetClaimedIPAddress(gomock.Any(), "test-machine-name-claim-0", gomock.Any()).Return(addressA, nil).Times(1)
+				tenantClient.EXPECT().GetClaimedIPAddress(gomock.Any(), "test-machine-name-claim-1", gomock.Any()).Return(nil, nil).Times(1)
+			},
+			expectedAddresses: []machinescope.StaticAddress{{Address: "10.0.0.5", Prefix: 24, Gateway: "10.0.0.1"}},
+		},
+		{
+			name: "Success all claims allocated",
+			expect: func(tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().GetClaimedIPAddress(gomock.Any(), "test-machine-name-claim-0", gomock.Any()).Return(addressA, nil).Times(1)
+				tenantClient.EXPECT().GetClaimedIPAddress(gomock.Any(), "test-machine-name-claim-1", gomock.Any()).Return(addressB, nil).Times(1)
+			},
+			expectedAddresses: []machinescope.StaticAddress{
+				{Address: "10.0.0.5", Prefix: 24, Gateway: "10.0.0.1"},
+				{Address: "fd00::5", Prefix: 64},
+			},
+			expectedAllocated: true,
+		},
+		{
+			name: "Failure create claim",
+			expect: func(tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().GetClaimedIPAddress(gomock.Any(), "test-machine-name-claim-0", gomock.Any()).Return(nil, notFound).Times(1)
+				tenantClient.EXPECT().CreateIPAddressClaim(gomock.Any(), "test-machine-name-claim-0", gomock.Any(), gomock.Any()).Return(fmt.Errorf("test error")).Times(1)
+			},
+			expectedErr: "failed to create IPAddressClaim test-machine-name-claim-0, with error: test error",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			tenantClient := mockTenantClusterClient.NewMockClient(mockCtrl)
+			tc.expect(tenantClient)
+
+			machine, err := testutils.StubMachine()
+			assert.NilError(t, err)
+			a := &actuator{tenantClusterClient: tenantClient}
+			addresses, allocated, err := a.claimAddresses(context.Background(), machine, pools)
+			if tc.expectedErr != "" {
+				assert.Error(t, err, tc.expectedErr)
+				return
+			}
+			assert.NilError(t, err)
+			assert.DeepEqual(t, addresses, tc.expectedAddresses)
+			assert.Equal(t, allocated, tc.expectedAllocated)
+		})
+	}
+}
+
+func TestReleaseAddresses(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	tenantClient := mockTenantClusterClient.NewMockClient(mockCtrl)
+
+	machine, err := testutils.StubMachine()
+	assert.NilError(t, err)
+	notFound := apimachineryerrors.NewNotFound(schema.GroupResource{Group: "ipam.cluster.x-k8s.io", Resource: "ipaddressclaims"}, "claim")
+	tenantClient.EXPECT().DeleteIPAddressClaim(gomock.Any(), "test-machine-name-claim-0", machine.Namespace).Return(nil).Times(1)
+	tenantClient.EXPECT().DeleteIPAddressClaim(gomock.Any(), "test-machine-name-claim-1", machine.Namespace).Return(notFound).Times(1)
+
+	a := &actuator{tenantClusterClient: tenantClient}
+	assert.NilError(t, a.releaseAddresses(context.Background(), machine, make([]kubevirtproviderv1alpha1.AddressesFromPool, 2)))
+}
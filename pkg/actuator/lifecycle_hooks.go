@@ -0,0 +1,35 @@
+package actuator
+
+import (
+	"strings"
+	"time"
+
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster"
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog"
+)
+
+const (
+	// lifecycleHookEventAction is reported while a lifecycle hook of a Machine blocks its deletion
+	lifecycleHookEventAction eventAction = "wait for lifecycle hook"
+	// lifecycleHookRequeueAfter is the delay before re-checking whether the lifecycle hooks of a deleted Machine were removed
+	lifecycleHookRequeueAfter = 20 * time.Second
+)
+
+// waitForLifecycleHooks reports the lifecycle hooks of the step of the deletion of the machine, and returns the error
+// requeuing the deletion until their owners remove them. It returns nil when the step has no hook.
+func (a *actuator) waitForLifecycleHooks(machine *machinev1.Machine, step string, hooks []tenantcluster.LifecycleHook) error {
+	if len(hooks) == 0 {
+		return nil
+	}
+	var names []string
+	for _, hook := range hooks {
+		names = append(names, hook.String())
+	}
+	klog.Infof("%s: actuator waiting for the %s lifecycle hooks %s", machine.GetName(), step, strings.Join(names, ", "))
+	a.eventRecorder.Eventf(machine, corev1.EventTypeNormal, string(lifecycleHookEventAction),
+		"The %s lifecycle hooks %s block the deletion of the VirtualMachine", step, strings.Join(names, ", "))
+	return &machinecontroller.RequeueAfterError{RequeueAfter: lifecycleHookRequeueAfter}
+}
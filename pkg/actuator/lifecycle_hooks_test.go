@@ -0,0 +1,81 @@
+package actuator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster"
+	mockTenantClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster/mock"
+	mockKubevirt "github.com/openshift/cluster-api-provider-kubevirt/pkg/kubevirt/mock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/testutils"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"gotest.tools/assert"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestDeleteLifecycleHooks(t *testing.T) {
+	backupHook := tenantcluster.LifecycleHook{Name: "backup", Owner: "backup-operator"}
+	cases := []struct {
+		name          string
+		hooks         tenantcluster.LifecycleHooks
+		expectDelete  bool
+		expectedEvent string
+	}{
+		{
+			name:         "no lifecycle hooks",
+			expectDelete: true,
+		},
+		{
+			// The machine controller waits for the preDrain hooks before it calls Delete
+			name:         "preDrain hook",
+			hooks:        tenantcluster.LifecycleHooks{PreDrain: []tenantcluster.LifecycleHook{backupHook}},
+			expectDelete: true,
+		},
+		{
+			name:          "preTerminate hook",
+			hooks:         tenantcluster.LifecycleHooks{PreTerminate: []tenantcluster.LifecycleHook{backupHook}},
+			expectedEvent: "The preTerminate lifecycle hooks backup (owned by backup-operator) block the deletion of the VirtualMachine",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cMap := map[string]string{
+				configMapInfraNamespaceKeyName: testutils.InfraNamespace,
+				configMapInfraIDKeyName:        testutils.InfraID,
+			}
+
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			tenantClient := mockTenantClusterClient.NewMockClient(mockCtrl)
+			kubevirtVM := mockKubevirt.NewMockKubevirtVM(mockCtrl)
+
+			machine, err := testutils.StubMachine()
+			assert.NilError(t, err)
+
+			tenantClient.EXPECT().GetMachineLifecycleHooks(gomock.Any(), machine.Name, machine.Namespace).Return(&tc.hooks, nil).Times(1)
+			if tc.expectDelete {
+				kubevirtVM.EXPECT().Delete(gomock.Any()).Return(nil).Times(1)
+			}
+			tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).AnyTimes()
+
+			eventRecorder := record.NewFakeRecorder(10)
+			a, err := New(kubevirtVM, eventRecorder, machinescope.New(machinescope.MetadataFilter{}, tenantClient), tenantClient, false, nil)
+			assert.NilError(t, err)
+
+			err = a.Delete(context.Background(), machine)
+			if tc.expectDelete {
+				assert.NilError(t, err)
+				return
+			}
+			var requeueErr *machinecontroller.RequeueAfterError
+			assert.Assert(t, errors.As(err, &requeueErr))
+			assert.Equal(t, requeueErr.RequeueAfter, lifecycleHookRequeueAfter)
+			event := <-eventRecorder.Events
+			assert.Assert(t, strings.Contains(event, tc.expectedEvent), event)
+		})
+	}
+}
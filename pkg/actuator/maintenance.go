@@ -0,0 +1,42 @@
+package actuator
+
+import (
+	"context"
+	"time"
+
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog"
+)
+
+const (
+	// configMapInfraMaintenanceKeyName switches the infra-cluster maintenance mode on, when "true"
+	configMapInfraMaintenanceKeyName = "infraMaintenance"
+	// infraMaintenanceRequeueAfter is the delay before re-checking whether the maintenance of the infra-cluster ended
+	infraMaintenanceRequeueAfter = time.Minute
+)
+
+// inInfraMaintenance returns true while the infra-cluster is in maintenance, e.g. during its upgrade: the machines
+// are neither created nor deleted, but their status is still synced. The maintenance mode is set by the
+// infra-maintenance flag, or by the infraMaintenance key of the cloud provider configMap, which is read on every
+// call for the maintenance to be started and ended without restarting the controller.
+func (a *actuator) inInfraMaintenance(ctx context.Context) (bool, error) {
+	if a.infraMaintenance {
+		return true, nil
+	}
+	cMap, err := a.tenantClusterClient.GetConfigMapValue(ctx, configMapName, configMapNamespace, configMapDataKeyName)
+	if err != nil {
+		return false, err
+	}
+	return (*cMap)[configMapInfraMaintenanceKeyName] == "true", nil
+}
+
+// postponeForInfraMaintenance reports the operation postponed until the end of the maintenance of the
+// infra-cluster, and returns the error requeuing it
+func (a *actuator) postponeForInfraMaintenance(machine *machinev1.Machine, action eventAction) error {
+	klog.Infof("%s: actuator postponing the %s during the maintenance of the infra-cluster", machine.GetName(), action)
+	a.eventRecorder.Eventf(machine, corev1.EventTypeNormal, string(infraMaintenanceEventAction),
+		"The infra-cluster is in maintenance, %s is postponed", action)
+	return &machinecontroller.RequeueAfterError{RequeueAfter: infraMaintenanceRequeueAfter}
+}
@@ -0,0 +1,80 @@
+package actuator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	mockTenantClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster/mock"
+	mockKubevirt "github.com/openshift/cluster-api-provider-kubevirt/pkg/kubevirt/mock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/testutils"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"gotest.tools/assert"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestInfraMaintenance(t *testing.T) {
+	cases := []struct {
+		name             string
+		infraMaintenance bool
+		configMapValue   string
+	}{
+		{
+			name:             "maintenance set by the flag",
+			infraMaintenance: true,
+		},
+		{
+			name:           "maintenance set in the configMap",
+			configMapValue: "true",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cMap := map[string]string{
+				configMapInfraNamespaceKeyName: testutils.InfraNamespace,
+				configMapInfraIDKeyName:        testutils.InfraID,
+			}
+			if tc.configMapValue != "" {
+				cMap[configMapInfraMaintenanceKeyName] = tc.configMapValue
+			}
+
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			tenantClient := mockTenantClusterClient.NewMockClient(mockCtrl)
+			kubevirtVM := mockKubevirt.NewMockKubevirtVM(mockCtrl)
+
+			machine, err := testutils.StubMachine()
+			assert.NilError(t, err)
+
+			// Only the status of the machine is synced, nothing is created, updated or deleted in the infra-cluster
+			kubevirtVM.EXPECT().SyncStatus(gomock.Any()).Return(true, nil).Times(1)
+			tenantClient.EXPECT().PatchMachine(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+			tenantClient.EXPECT().StatusPatchMachine(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+			tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).MinTimes(1)
+
+			eventRecorder := record.NewFakeRecorder(10)
+			a, err := New(kubevirtVM, eventRecorder, machinescope.New(machinescope.MetadataFilter{}, tenantClient), tenantClient, tc.infraMaintenance, nil)
+			assert.NilError(t, err)
+
+			var requeueErr *machinecontroller.RequeueAfterError
+			err = a.Create(context.Background(), machine)
+			assert.Assert(t, errors.As(err, &requeueErr))
+			assert.NilError(t, a.Update(context.Background(), machine))
+			err = a.Delete(context.Background(), machine)
+			assert.Assert(t, errors.As(err, &requeueErr))
+
+			close(eventRecorder.Events)
+			var events []string
+			for event := range eventRecorder.Events {
+				events = append(events, event)
+			}
+			assert.Equal(t, len(events), 2)
+			for _, event := range events {
+				assert.Assert(t, strings.Contains(event, string(infraMaintenanceEventAction)), event)
+			}
+		})
+	}
+}
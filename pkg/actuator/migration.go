@@ -0,0 +1,41 @@
+package actuator
+
+import (
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+)
+
+// migrationEventAction reports the live migrations of the VirtualMachine of a Machine between the infra nodes,
+// e.g. by the descheduler of the infra-cluster
+const migrationEventAction eventAction = "migrate machine"
+
+// reportMigration records an event on the Machine for the live migration of its VirtualMachine which ended since
+// the previous sync, from the migrations in the provider status of the synced Machine and of its copy before the sync
+func (a *actuator) reportMigration(machine *machinev1.Machine, originMachineCopy *machinev1.Machine) {
+	migration := migrationOf(machine)
+	if migration == nil || !migration.Completed && !migration.Failed {
+		return
+	}
+	if previous := migrationOf(originMachineCopy); previous != nil && previous.UID == migration.UID &&
+		previous.Completed == migration.Completed && previous.Failed == migration.Failed {
+		return
+	}
+	if migration.Failed {
+		a.eventRecorder.Eventf(machine, corev1.EventTypeWarning, string(migrationEventAction),
+			"Live migration of the VirtualMachine of Machine %v from infra node %s to %s failed", machine.Name, migration.SourceNode, migration.TargetNode)
+		return
+	}
+	a.eventRecorder.Eventf(machine, corev1.EventTypeNormal, string(migrationEventAction),
+		"Live migrated the VirtualMachine of Machine %v from infra node %s to %s", machine.Name, migration.SourceNode, migration.TargetNode)
+}
+
+// migrationOf returns the last live migration of the VirtualMachine of the Machine, or nil when it never migrated
+func migrationOf(machine *machinev1.Machine) *kubevirtproviderv1alpha1.VirtualMachineMigration {
+	providerStatus, err := kubevirtproviderv1alpha1.ProviderStatusFromRawExtension(machine.Status.ProviderStatus)
+	if err != nil || providerStatus.VirtualMachine == nil {
+		return nil
+	}
+	return providerStatus.VirtualMachine.Migration
+}
@@ -0,0 +1,84 @@
+package actuator
+
+import (
+	"testing"
+
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/testutils"
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"gotest.tools/assert"
+	"k8s.io/client-go/tools/record"
+)
+
+func machineWithMigration(t *testing.T, migration *kubevirtproviderv1alpha1.VirtualMachineMigration) *machinev1.Machine {
+	machine, err := testutils.StubMachine()
+	assert.NilError(t, err)
+	machine.Status.ProviderStatus, err = kubevirtproviderv1alpha1.RawExtensionFromProviderStatus(&kubevirtproviderv1alpha1.KubevirtMachineProviderStatus{
+		VirtualMachine: &kubevirtproviderv1alpha1.VirtualMachineSummary{Migration: migration},
+	})
+	assert.NilError(t, err)
+	return machine
+}
+
+func TestReportMigration(t *testing.T) {
+	running := &kubevirtproviderv1alpha1.VirtualMachineMigration{UID: "migration-1", SourceNode: "infra-a", TargetNode: "infra-b"}
+	completed := &kubevirtproviderv1alpha1.VirtualMachineMigration{UID: "migration-1", SourceNode: "infra-a", TargetNode: "infra-b", Completed: true}
+	failed := &kubevirtproviderv1alpha1.VirtualMachineMigration{UID: "migration-2", SourceNode: "infra-b", TargetNode: "infra-c", Failed: true}
+
+	cases := []struct {
+		name           string
+		previous       *kubevirtproviderv1alpha1.VirtualMachineMigration
+		current        *kubevirtproviderv1alpha1.VirtualMachineMigration
+		expectedEvents []string
+	}{
+		{
+			name:    "report a completed migration",
+			current: completed,
+			expectedEvents: []string{
+				"Normal migrate machine Live migrated the VirtualMachine of Machine test-machine-name from infra node infra-a to infra-b",
+			},
+		},
+		{
+			name:     "report a running migration once it completed",
+			previous: running,
+			current:  completed,
+			expectedEvents: []string{
+				"Normal migrate machine Live migrated the VirtualMachine of Machine test-machine-name from infra node infra-a to infra-b",
+			},
+		},
+		{
+			name:     "report a new failed migration",
+			previous: completed,
+			current:  failed,
+			expectedEvents: []string{
+				"Warning migrate machine Live migration of the VirtualMachine of Machine test-machine-name from infra node infra-b to infra-c failed",
+			},
+		},
+		{
+			name:     "don't report a reported migration again",
+			previous: completed,
+			current:  completed,
+		},
+		{
+			name:    "don't report a running migration",
+			current: running,
+		},
+		{
+			name: "don't report a machine which never migrated",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			eventRecorder := record.NewFakeRecorder(10)
+			a := &actuator{eventRecorder: eventRecorder}
+			a.reportMigration(machineWithMigration(t, tc.current), machineWithMigration(t, tc.previous))
+
+			close(eventRecorder.Events)
+			var events []string
+			for event := range eventRecorder.Events {
+				events = append(events, event)
+			}
+			assert.DeepEqual(t, events, tc.expectedEvents)
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package actuator
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/controller/networkcheckup"
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// networkCheckupEventAction reports a machine refused since the checkup of the network of its MachineSet failed
+	networkCheckupEventAction eventAction = "check network"
+	// networkCheckupRequeueAfter is the delay before re-checking whether the network of a MachineSet was checked
+	networkCheckupRequeueAfter = 30 * time.Second
+)
+
+// networkCheckupPending returns true while the network of a MachineSet opting in to the network checkup isn't
+// checked yet, and an error when the checkup failed: the Machines aren't created on a broken network
+func networkCheckupPending(machineSet *machinev1.MachineSet) (bool, error) {
+	if machineSet.Annotations[networkcheckup.NetworkCheckupAnnotation] != "true" {
+		return false, nil
+	}
+	condition, err := networkcheckup.GetNetworkReadyCondition(machineSet)
+	if err != nil {
+		return true, nil
+	}
+	switch {
+	case condition == nil || condition.Status == corev1.ConditionUnknown:
+		return true, nil
+	case condition.Status == corev1.ConditionFalse:
+		return false, fmt.Errorf("MachineSet %s: %s", machineSet.Name, condition.Message)
+	}
+	return false, nil
+}
@@ -0,0 +1,63 @@
+package actuator
+
+import (
+	"testing"
+
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/controller/networkcheckup"
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"gotest.tools/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNetworkCheckupPending(t *testing.T) {
+	cases := []struct {
+		name            string
+		annotations     map[string]string
+		expectedPending bool
+		expectedErr     string
+	}{
+		{
+			name: "not opting in",
+		},
+		{
+			name:            "checkup not started",
+			annotations:     map[string]string{networkcheckup.NetworkCheckupAnnotation: "true"},
+			expectedPending: true,
+		},
+		{
+			name: "checkup running",
+			annotations: map[string]string{
+				networkcheckup.NetworkCheckupAnnotation:        "true",
+				networkcheckup.NetworkReadyConditionAnnotation: `{"type":"NetworkReady","status":"Unknown"}`,
+			},
+			expectedPending: true,
+		},
+		{
+			name: "checkup succeeded",
+			annotations: map[string]string{
+				networkcheckup.NetworkCheckupAnnotation:        "true",
+				networkcheckup.NetworkReadyConditionAnnotation: `{"type":"NetworkReady","status":"True"}`,
+			},
+		},
+		{
+			name: "checkup failed",
+			annotations: map[string]string{
+				networkcheckup.NetworkCheckupAnnotation:        "true",
+				networkcheckup.NetworkReadyConditionAnnotation: `{"type":"NetworkReady","status":"False","message":"test error"}`,
+			},
+			expectedErr: "MachineSet workers: test error",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			machineSet := &machinev1.MachineSet{ObjectMeta: metav1.ObjectMeta{Name: "workers", Annotations: tc.annotations}}
+			pending, err := networkCheckupPending(machineSet)
+			if tc.expectedErr != "" {
+				assert.Error(t, err, tc.expectedErr)
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, pending, tc.expectedPending)
+		})
+	}
+}
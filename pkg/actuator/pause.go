@@ -0,0 +1,36 @@
+package actuator
+
+import (
+	"time"
+
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// pausedAnnotation pauses the reconciliation of a Machine, or of the Machines of a MachineSet, when "true"
+	pausedAnnotation = "machine.openshift.io/paused"
+	// clusterAPIPausedAnnotation pauses the reconciliation of a Machine, or of the Machines of a MachineSet,
+	// whatever its value, as in cluster-api
+	clusterAPIPausedAnnotation = "cluster.x-k8s.io/paused"
+	// pausedRequeueAfter is the delay before re-checking whether a paused Machine was resumed
+	pausedRequeueAfter = time.Minute
+)
+
+// reconcilePaused returns true when the Machine, or its MachineSet, is paused: nothing is changed in the
+// infra-cluster for the Machine, e.g. during a maintenance window of the infra-cluster, but its status is
+// still synced
+func reconcilePaused(machine *machinev1.Machine, machineSet *machinev1.MachineSet) bool {
+	if isPaused(machine) {
+		return true
+	}
+	return machineSet != nil && isPaused(machineSet)
+}
+
+func isPaused(obj metav1.Object) bool {
+	annotations := obj.GetAnnotations()
+	if _, ok := annotations[clusterAPIPausedAnnotation]; ok {
+		return true
+	}
+	return annotations[pausedAnnotation] == "true"
+}
@@ -0,0 +1,97 @@
+package actuator
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	mockTenantClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster/mock"
+	mockKubevirt "github.com/openshift/cluster-api-provider-kubevirt/pkg/kubevirt/mock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/testutils"
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"gotest.tools/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+)
+
+func TestReconcilePaused(t *testing.T) {
+	cases := []struct {
+		name                  string
+		machineAnnotations    map[string]string
+		machineSetAnnotations map[string]string
+		expectedPaused        bool
+	}{
+		{
+			name: "not paused",
+		},
+		{
+			name:               "machine paused",
+			machineAnnotations: map[string]string{pausedAnnotation: "true"},
+			expectedPaused:     true,
+		},
+		{
+			name:               "machine resumed",
+			machineAnnotations: map[string]string{pausedAnnotation: "false"},
+		},
+		{
+			name:               "machine paused cluster-api style",
+			machineAnnotations: map[string]string{clusterAPIPausedAnnotation: ""},
+			expectedPaused:     true,
+		},
+		{
+			name:                  "machine set paused",
+			machineSetAnnotations: map[string]string{pausedAnnotation: "true"},
+			expectedPaused:        true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			machine := &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Annotations: tc.machineAnnotations}}
+			machineSet := &machinev1.MachineSet{ObjectMeta: metav1.ObjectMeta{Annotations: tc.machineSetAnnotations}}
+			assert.Equal(t, reconcilePaused(machine, machineSet), tc.expectedPaused)
+		})
+	}
+	assert.Assert(t, !reconcilePaused(&machinev1.Machine{}, nil))
+}
+
+func TestPausedMachine(t *testing.T) {
+	cMap := map[string]string{
+		configMapInfraNamespaceKeyName: testutils.InfraNamespace,
+		configMapInfraIDKeyName:        testutils.InfraID,
+	}
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	tenantClient := mockTenantClusterClient.NewMockClient(mockCtrl)
+	kubevirtVM := mockKubevirt.NewMockKubevirtVM(mockCtrl)
+
+	machine, err := testutils.StubMachine()
+	assert.NilError(t, err)
+	machine.OwnerReferences = []metav1.OwnerReference{{Kind: "MachineSet", Name: "test-machine-set", Controller: pointer.BoolPtr(true)}}
+	machineSet := &machinev1.MachineSet{ObjectMeta: metav1.ObjectMeta{
+		Name:        "test-machine-set",
+		Namespace:   machine.Namespace,
+		Annotations: map[string]string{pausedAnnotation: "true"},
+	}}
+
+	tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+	tenantClient.EXPECT().GetMachineSet(gomock.Any(), "test-machine-set", machine.Namespace).Return(machineSet, nil).Times(3)
+	// Only the status of the paused machine is synced, nothing is created, updated or deleted in the infra-cluster
+	kubevirtVM.EXPECT().SyncStatus(gomock.Any()).Return(true, nil).Times(1)
+	tenantClient.EXPECT().PatchMachine(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+	tenantClient.EXPECT().StatusPatchMachine(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+	a, err := New(kubevirtVM, record.NewFakeRecorder(10), machinescope.New(machinescope.MetadataFilter{}, tenantClient), tenantClient, false, nil)
+	assert.NilError(t, err)
+
+	var requeueErr *machinecontroller.RequeueAfterError
+	err = a.Create(context.Background(), machine)
+	assert.Assert(t, errors.As(err, &requeueErr))
+	assert.NilError(t, a.Update(context.Background(), machine))
+	err = a.Delete(context.Background(), machine)
+	assert.Assert(t, errors.As(err, &requeueErr))
+}
@@ -0,0 +1,123 @@
+package actuator
+
+import (
+	"context"
+	goerrors "errors"
+	"fmt"
+	"strings"
+	"time"
+
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apimachineryerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/kubevirt"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
+)
+
+const (
+	// quotaExhaustedAnnotation marks a MachineSet whose Machines can't be created in the infra namespace, its value
+	// is the version of the hard limits and the usage of the ResourceQuotas which rejected the last Machine
+	quotaExhaustedAnnotation = "kubevirt.machine.openshift.io/quota-exhausted"
+	// quotaEventAction reports a MachineSet whose Machines exceed the quotas of the infra namespace
+	quotaEventAction eventAction = "quota exhausted"
+	// quotaBackoffRequeueAfter is the delay before re-checking whether the quotas of a backed off MachineSet changed
+	quotaBackoffRequeueAfter = time.Minute
+)
+
+// machineSetOfMachine returns the MachineSet which controls the machine, or nil if there is no such MachineSet
+func (a *actuator) machineSetOfMachine(ctx context.Context, machine *machinev1.Machine) (*machinev1.MachineSet, error) {
+	owner := metav1.GetControllerOf(machine)
+	if owner == nil || owner.Kind != "MachineSet" {
+		return nil, nil
+	}
+	machineSet, err := a.tenantClusterClient.GetMachineSet(ctx, owner.Name, machine.Namespace)
+	if err != nil {
+		if apimachineryerrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get the MachineSet %s of the machine, with error: %v", owner.Name, err)
+	}
+	return machineSet, nil
+}
+
+// quotaBackoff returns true while the quotas which rejected the last creation of a Machine of the MachineSet
+// didn't change, or still don't have room for the machine, so creating it would fail as well
+func (a *actuator) quotaBackoff(kubevirtVM kubevirt.KubevirtVM, machineScope machinescope.MachineScope, machineSet *machinev1.MachineSet) (bool, error) {
+	exhaustedVersion, ok := machineSet.Annotations[quotaExhaustedAnnotation]
+	if !ok {
+		return false, nil
+	}
+	quotaVersion, err := kubevirtVM.QuotaVersion(a.infraNamespace)
+	if err != nil {
+		return false, fmt.Errorf("failed to get the ResourceQuotas of the infra namespace, with error: %v", err)
+	}
+	if quotaVersion == exhaustedVersion {
+		return true, nil
+	}
+	exceeded, err := kubevirtVM.ExceededQuotas(machineScope)
+	if err != nil {
+		return false, fmt.Errorf("failed to check the ResourceQuotas of the infra namespace, with error: %v", err)
+	}
+	if len(exceeded) > 0 {
+		klog.Infof("%s: the quotas %s of the infra namespace don't have room for the machine", machineScope.GetMachineName(), strings.Join(exceeded, ", "))
+		return true, nil
+	}
+	return false, nil
+}
+
+// reconcileQuotaRejection marks the MachineSet once the provisioning of the VirtualMachine of the machine, its
+// pod or the PVCs of its DataVolumes, is rejected by a quota after its creation, and clears the mark once the
+// machine is ready
+func (a *actuator) reconcileQuotaRejection(kubevirtVM kubevirt.KubevirtVM, machineScope machinescope.MachineScope, machineSet *machinev1.MachineSet, ready bool) {
+	if ready {
+		a.setQuotaExhaustedAnnotation(machineSet, nil)
+		return
+	}
+	rejection, err := kubevirtVM.QuotaRejection(machineScope)
+	if err != nil {
+		klog.Errorf("%s: failed to check whether a quota rejected the VirtualMachine, with error: %v", machineScope.GetMachineName(), err)
+		return
+	}
+	if rejection != "" {
+		a.markQuotaExhausted(kubevirtVM, machineSet, machineScope.GetMachine(), goerrors.New(rejection))
+	}
+}
+
+// markQuotaExhausted annotates the MachineSet with the version of the quotas which rejected the machine,
+// and reports it on the MachineSet, unless it was marked for this version already
+func (a *actuator) markQuotaExhausted(kubevirtVM kubevirt.KubevirtVM, machineSet *machinev1.MachineSet, machine *machinev1.Machine, createErr error) {
+	quotaVersion, err := kubevirtVM.QuotaVersion(a.infraNamespace)
+	if err != nil {
+		klog.Errorf("%s: failed to get the ResourceQuotas of the infra namespace, with error: %v", machine.Name, err)
+		return
+	}
+	if exhaustedVersion, ok := machineSet.Annotations[quotaExhaustedAnnotation]; ok && exhaustedVersion == quotaVersion {
+		return
+	}
+	a.eventRecorder.Eventf(machineSet, corev1.EventTypeWarning, string(quotaEventAction),
+		"Machine %v exceeds the quotas of the infra namespace, creating machines is backed off until the quotas change: %v", machine.Name, createErr)
+	a.setQuotaExhaustedAnnotation(machineSet, &quotaVersion)
+}
+
+// setQuotaExhaustedAnnotation sets the quota exhausted annotation of the MachineSet to the quota version,
+// or removes it when the quota version is nil
+func (a *actuator) setQuotaExhaustedAnnotation(machineSet *machinev1.MachineSet, quotaVersion *string) {
+	originMachineSetCopy := machineSet.DeepCopy()
+	if quotaVersion == nil {
+		if _, ok := machineSet.Annotations[quotaExhaustedAnnotation]; !ok {
+			return
+		}
+		delete(machineSet.Annotations, quotaExhaustedAnnotation)
+	} else {
+		if machineSet.Annotations == nil {
+			machineSet.Annotations = map[string]string{}
+		}
+		machineSet.Annotations[quotaExhaustedAnnotation] = *quotaVersion
+	}
+	if err := a.tenantClusterClient.PatchMachineSet(machineSet, originMachineSetCopy); err != nil {
+		klog.Errorf("%s: failed to patch the %s annotation of the MachineSet, with error: %v", machineSet.Name, quotaExhaustedAnnotation, err)
+	}
+}
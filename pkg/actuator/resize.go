@@ -0,0 +1,93 @@
+package actuator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog"
+
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/kubevirt"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
+)
+
+const (
+	// resizeStrategyAnnotation opts a Machine in restarting its VirtualMachine to apply CPU and memory changes,
+	// without it the changes are applied to the VirtualMachine and take effect on its next restart
+	resizeStrategyAnnotation = "kubevirt.machine.openshift.io/resize-strategy"
+	// resizeStrategyRestart is the value of the resize strategy annotation which opts the Machine in
+	resizeStrategyRestart = "Restart"
+	// resizePhaseAnnotation records the progress of the restart of a resized Machine
+	resizePhaseAnnotation = "kubevirt.machine.openshift.io/resize-phase"
+	// resizePhaseStopping is set once the Node is drained, while the VirtualMachine is being stopped
+	resizePhaseStopping = "Stopping"
+	// resizePhaseStarting is set once the VirtualMachine is stopped, until it runs again with the new resources
+	resizePhaseStarting = "Starting"
+	// resizeEventAction reports the restart of a resized Machine
+	resizeEventAction eventAction = "resize machine"
+	// resizeRequeueAfter is the delay before re-checking a VirtualMachine which is being stopped
+	resizeRequeueAfter = 20 * time.Second
+)
+
+// reconcileResize restarts the VirtualMachine of an opted-in Machine whose CPU or memory changed: the Node is
+// cordoned and drained, then the VirtualMachine is stopped, and the update starts it with the new resources.
+// It returns true while the VirtualMachine is being stopped, the VirtualMachine must not be updated until then.
+func (a *actuator) reconcileResize(ctx context.Context, kubevirtVM kubevirt.KubevirtVM, machineScope machinescope.MachineScope) (bool, error) {
+	machine := machineScope.GetMachine()
+	switch machine.Annotations[resizePhaseAnnotation] {
+	case "":
+		if machine.Annotations[resizeStrategyAnnotation] != resizeStrategyRestart {
+			return false, nil
+		}
+		restartRequired, err := kubevirtVM.RestartRequired(machineScope)
+		if err != nil || !restartRequired {
+			return false, err
+		}
+		if machine.Status.NodeRef != nil {
+			klog.Infof("%s: cordon and drain node %s before restarting the VirtualMachine", machine.Name, machine.Status.NodeRef.Name)
+			if err := a.tenantClusterClient.CordonAndDrainNode(ctx, machine.Status.NodeRef.Name); err != nil {
+				return false, fmt.Errorf("failed to drain node %s before restarting the VirtualMachine, with error: %v", machine.Status.NodeRef.Name, err)
+			}
+		}
+		a.eventRecorder.Eventf(machine, corev1.EventTypeNormal, string(resizeEventAction),
+			"Restarting the VirtualMachine of Machine %v to apply the CPU and memory changes", machine.Name)
+		setResizePhase(machine, resizePhaseStopping)
+		fallthrough
+	case resizePhaseStopping:
+		stopped, err := kubevirtVM.Stop(machineScope)
+		if err != nil {
+			return true, err
+		}
+		if !stopped {
+			return true, nil
+		}
+		setResizePhase(machine, resizePhaseStarting)
+	}
+	return false, nil
+}
+
+// completeResize uncordons the Node of a resized Machine once its VirtualMachine is ready again
+func (a *actuator) completeResize(ctx context.Context, machine *machinev1.Machine) error {
+	if machine.Annotations[resizePhaseAnnotation] != resizePhaseStarting {
+		return nil
+	}
+	if machine.Status.NodeRef != nil {
+		if err := a.tenantClusterClient.UncordonNode(ctx, machine.Status.NodeRef.Name); err != nil {
+			return fmt.Errorf("failed to uncordon node %s after restarting the VirtualMachine, with error: %v", machine.Status.NodeRef.Name, err)
+		}
+	}
+	a.eventRecorder.Eventf(machine, corev1.EventTypeNormal, string(resizeEventAction),
+		"Restarted the VirtualMachine of Machine %v with the new CPU and memory", machine.Name)
+	delete(machine.Annotations, resizePhaseAnnotation)
+	return nil
+}
+
+// setResizePhase sets the resize phase annotation of the machine, it is persisted by the patch of the machine
+func setResizePhase(machine *machinev1.Machine, phase string) {
+	if machine.Annotations == nil {
+		machine.Annotations = map[string]string{}
+	}
+	machine.Annotations[resizePhaseAnnotation] = phase
+}
@@ -0,0 +1,119 @@
+package actuator
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	mockTenantClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster/mock"
+	mockKubevirt "github.com/openshift/cluster-api-provider-kubevirt/pkg/kubevirt/mock"
+	mockMachineScope "github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope/mock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/testutils"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestReconcileResize(t *testing.T) {
+	cases := []struct {
+		name             string
+		annotations      map[string]string
+		expect           func(kubevirtVM *mockKubevirt.MockKubevirtVM, tenantClient *mockTenantClusterClient.MockClient)
+		expectedStopping bool
+		expectedPhase    string
+		expectedErr      string
+	}{
+		{
+			name:   "Success machine not opted in",
+			expect: func(kubevirtVM *mockKubevirt.MockKubevirtVM, tenantClient *mockTenantClusterClient.MockClient) {},
+		},
+		{
+			name:        "Success restart not required",
+			annotations: map[string]string{resizeStrategyAnnotation: resizeStrategyRestart},
+			expect: func(kubevirtVM *mockKubevirt.MockKubevirtVM, tenantClient *mockTenantClusterClient.MockClient) {
+				kubevirtVM.EXPECT().RestartRequired(gomock.Any()).Return(false, nil).Times(1)
+			},
+		},
+		{
+			name:        "Success drain the node and stop the VirtualMachine",
+			annotations: map[string]string{resizeStrategyAnnotation: resizeStrategyRestart},
+			expect: func(kubevirtVM *mockKubevirt.MockKubevirtVM, tenantClient *mockTenantClusterClient.MockClient) {
+				kubevirtVM.EXPECT().RestartRequired(gomock.Any()).Return(true, nil).Times(1)
+				tenantClient.EXPECT().CordonAndDrainNode(gomock.Any(), "test-node").Return(nil).Times(1)
+				kubevirtVM.EXPECT().Stop(gomock.Any()).Return(false, nil).Times(1)
+			},
+			expectedStopping: true,
+			expectedPhase:    resizePhaseStopping,
+		},
+		{
+			name:        "Success VirtualMachine stopped",
+			annotations: map[string]string{resizeStrategyAnnotation: resizeStrategyRestart, resizePhaseAnnotation: resizePhaseStopping},
+			expect: func(kubevirtVM *mockKubevirt.MockKubevirtVM, tenantClient *mockTenantClusterClient.MockClient) {
+				kubevirtVM.EXPECT().Stop(gomock.Any()).Return(true, nil).Times(1)
+			},
+			expectedPhase: resizePhaseStarting,
+		},
+		{
+			name:          "Success VirtualMachine starting",
+			annotations:   map[string]string{resizeStrategyAnnotation: resizeStrategyRestart, resizePhaseAnnotation: resizePhaseStarting},
+			expect:        func(kubevirtVM *mockKubevirt.MockKubevirtVM, tenantClient *mockTenantClusterClient.MockClient) {},
+			expectedPhase: resizePhaseStarting,
+		},
+		{
+			name:        "Failure drain the node",
+			annotations: map[string]string{resizeStrategyAnnotation: resizeStrategyRestart},
+			expect: func(kubevirtVM *mockKubevirt.MockKubevirtVM, tenantClient *mockTenantClusterClient.MockClient) {
+				kubevirtVM.EXPECT().RestartRequired(gomock.Any()).Return(true, nil).Times(1)
+				tenantClient.EXPECT().CordonAndDrainNode(gomock.Any(), "test-node").Return(fmt.Errorf("test error")).Times(1)
+			},
+			expectedErr: "failed to drain node test-node before restarting the VirtualMachine, with error: test error",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			kubevirtVM := mockKubevirt.NewMockKubevirtVM(mockCtrl)
+			tenantClient := mockTenantClusterClient.NewMockClient(mockCtrl)
+			machineScope := mockMachineScope.NewMockMachineScope(mockCtrl)
+			tc.expect(kubevirtVM, tenantClient)
+
+			machine, err := testutils.StubMachine()
+			assert.NilError(t, err)
+			machine.Annotations = tc.annotations
+			machine.Status.NodeRef = &corev1.ObjectReference{Name: "test-node"}
+			machineScope.EXPECT().GetMachine().Return(machine).AnyTimes()
+
+			a := &actuator{kubevirtVM: kubevirtVM, tenantClusterClient: tenantClient, eventRecorder: record.NewFakeRecorder(10)}
+			stopping, err := a.reconcileResize(context.Background(), kubevirtVM, machineScope)
+			if tc.expectedErr != "" {
+				assert.Error(t, err, tc.expectedErr)
+				assert.Equal(t, machine.Annotations[resizePhaseAnnotation], "")
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, stopping, tc.expectedStopping)
+			assert.Equal(t, machine.Annotations[resizePhaseAnnotation], tc.expectedPhase)
+		})
+	}
+}
+
+func TestCompleteResize(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	tenantClient := mockTenantClusterClient.NewMockClient(mockCtrl)
+	tenantClient.EXPECT().UncordonNode(gomock.Any(), "test-node").Return(nil).Times(1)
+
+	machine, err := testutils.StubMachine()
+	assert.NilError(t, err)
+	machine.Annotations = map[string]string{resizeStrategyAnnotation: resizeStrategyRestart, resizePhaseAnnotation: resizePhaseStarting}
+	machine.Status.NodeRef = &corev1.ObjectReference{Name: "test-node"}
+
+	a := &actuator{tenantClusterClient: tenantClient, eventRecorder: record.NewFakeRecorder(10)}
+	assert.NilError(t, a.completeResize(context.Background(), machine))
+	_, ok := machine.Annotations[resizePhaseAnnotation]
+	assert.Assert(t, !ok)
+	// A machine which isn't being resized is left alone
+	assert.NilError(t, a.completeResize(context.Background(), machine))
+}
@@ -0,0 +1,38 @@
+package actuator
+
+import (
+	"context"
+	"fmt"
+
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// selectZone returns the zone with the fewest Machines of the MachineSet of the machine, the first one of the
+// zones on a tie, so the Machines of a MachineSet are spread over the zones
+func (a *actuator) selectZone(ctx context.Context, machine *machinev1.Machine, machineSet *machinev1.MachineSet, zones []string) (string, error) {
+	if machineSet == nil {
+		return zones[0], nil
+	}
+	machines, err := a.tenantClusterClient.ListMachines(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to list Machines, with error: %v", err)
+	}
+	machinesOfZone := map[string]int{}
+	for i := range machines {
+		owner := metav1.GetControllerOf(&machines[i])
+		if owner == nil || owner.Kind != "MachineSet" || owner.Name != machineSet.Name ||
+			machines[i].Namespace != machineSet.Namespace || machines[i].Name == machine.Name {
+			continue
+		}
+		machinesOfZone[machines[i].Labels[machinecontroller.MachineAZLabelName]]++
+	}
+	selected := zones[0]
+	for _, zone := range zones[1:] {
+		if machinesOfZone[zone] < machinesOfZone[selected] {
+			selected = zone
+		}
+	}
+	return selected, nil
+}
@@ -0,0 +1,68 @@
+package actuator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	mockTenantClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster/mock"
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"gotest.tools/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+func stubZoneMachine(name string, machineSetName string, zone string) machinev1.Machine {
+	machine := machinev1.Machine{ObjectMeta: metav1.ObjectMeta{
+		Name:            name,
+		Namespace:       "openshift-machine-api",
+		OwnerReferences: []metav1.OwnerReference{{Kind: "MachineSet", Name: machineSetName, Controller: pointer.BoolPtr(true)}},
+	}}
+	if zone != "" {
+		machine.Labels = map[string]string{machinecontroller.MachineAZLabelName: zone}
+	}
+	return machine
+}
+
+func TestSelectZone(t *testing.T) {
+	zones := []string{"zone-a", "zone-b", "zone-c"}
+	cases := []struct {
+		name         string
+		machines     []machinev1.Machine
+		expectedZone string
+	}{
+		{
+			name:         "first zone without machines",
+			expectedZone: "zone-a",
+		},
+		{
+			name: "zone with the fewest machines of the machine set",
+			machines: []machinev1.Machine{
+				stubZoneMachine("workers-0", "workers", "zone-a"),
+				stubZoneMachine("workers-1", "workers", "zone-b"),
+				stubZoneMachine("workers-4", "workers", "zone-c"),
+				stubZoneMachine("workers-5", "workers", "zone-b"),
+				stubZoneMachine("workers-2", "workers", "zone-a"),
+				stubZoneMachine("other-0", "other", "zone-c"),
+				stubZoneMachine("workers-3", "workers", ""),
+			},
+			expectedZone: "zone-c",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			tenantClient := mockTenantClusterClient.NewMockClient(mockCtrl)
+			machine := stubZoneMachine("workers-3", "workers", "")
+			machineSet := &machinev1.MachineSet{ObjectMeta: metav1.ObjectMeta{Name: "workers", Namespace: machine.Namespace}}
+			tenantClient.EXPECT().ListMachines(gomock.Any()).Return(tc.machines, nil).Times(1)
+
+			a := &actuator{tenantClusterClient: tenantClient}
+			zone, err := a.selectZone(context.Background(), &machine, machineSet, zones)
+			assert.NilError(t, err)
+			assert.Equal(t, zone, tc.expectedZone)
+		})
+	}
+}
@@ -0,0 +1,265 @@
+// adopt package generates the Machines of the pre-existing VirtualMachines of the cluster, to bring the workers
+// created by other means than Machines under the management of the machine-api:
+// - Find the VirtualMachines of the infra namespace labeled for the cluster, which no Machine, VirtualMachinePool
+// or pre-staged adoption manages yet
+// - Reconstruct the KubevirtMachineProviderSpec of every VirtualMachine from its spec, on a best-effort basis
+// - Generate a Machine named as the VirtualMachine, with its providerID and VmId annotation, in the status-only
+// reconciliation mode, so the reconstructed spec never rewrites the VirtualMachine
+// The Machines are written as YAML manifests, to be reviewed before they are applied to the tenant-cluster.
+package adopt
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog"
+	kubevirtapiv1 "kubevirt.io/client-go/api/v1"
+	"sigs.k8s.io/yaml"
+
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/kubevirt"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/utils"
+)
+
+const (
+	configMapNamespace             = "openshift-config"
+	configMapName                  = "cloud-provider-config"
+	configMapDataKeyName           = "config"
+	configMapInfraNamespaceKeyName = "namespace"
+	configMapInfraIDKeyName        = "infraID"
+
+	machineRoleLabel = "machine.openshift.io/cluster-api-machine-role"
+	machineTypeLabel = "machine.openshift.io/cluster-api-machine-type"
+	// defaultRole is the role of the adopted Machines whose VirtualMachine isn't labeled with one
+	defaultRole = "worker"
+	// machineLabelPrefix is the prefix of the labels of the Machines, which the provider copies to their
+	// VirtualMachine, and which are copied back to the adopted Machines
+	machineLabelPrefix = "machine.openshift.io/"
+
+	virtualMachinePoolKind = "VirtualMachinePool"
+)
+
+// Adopter generates the Machines of the pre-existing VirtualMachines
+type Adopter struct {
+	infraClusterClient  infracluster.Client
+	tenantClusterClient tenantcluster.Client
+}
+
+// New creates an Adopter reading from the infra-cluster and the tenant-cluster
+func New(infraClusterClient infracluster.Client, tenantClusterClient tenantcluster.Client) *Adopter {
+	return &Adopter{
+		infraClusterClient:  infraClusterClient,
+		tenantClusterClient: tenantClusterClient,
+	}
+}
+
+// Adopt writes the YAML manifests of the Machines of the VirtualMachines of the cluster which aren't managed yet to
+// out, the Machines are generated in the machine namespace
+func (a *Adopter) Adopt(ctx context.Context, machineNamespace string, out io.Writer) error {
+	cMap, err := a.tenantClusterClient.GetConfigMapValue(ctx, configMapName, configMapNamespace, configMapDataKeyName)
+	if err != nil {
+		return fmt.Errorf("failed to get configMap %s/%s, with error: %v", configMapNamespace, configMapName, err)
+	}
+	infraNamespace, infraID := (*cMap)[configMapInfraNamespaceKeyName], (*cMap)[configMapInfraIDKeyName]
+	if infraNamespace == "" || infraID == "" {
+		return fmt.Errorf("configMap %s/%s doesn't contain the keys %s and %s", configMapNamespace, configMapName,
+			configMapInfraNamespaceKeyName, configMapInfraIDKeyName)
+	}
+
+	vms, err := a.infraClusterClient.ListVirtualMachine(ctx, infraNamespace, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(utils.BuildLabels(infraID)).String(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list the VirtualMachines of namespace %s, with error: %v", infraNamespace, err)
+	}
+	machines, err := a.tenantClusterClient.ListMachines(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list Machines, with error: %v", err)
+	}
+	managed := managedVirtualMachines(machines)
+
+	adopted := 0
+	for i := range vms.Items {
+		vm := &vms.Items[i]
+		if reason := unadoptableReason(vm, managed); reason != "" {
+			klog.Infof("%s: skipping VirtualMachine, %s", vm.Name, reason)
+			continue
+		}
+		machine, err := MachineFromVirtualMachine(vm, machineNamespace, infraID)
+		if err != nil {
+			klog.Warningf("%s: skipping VirtualMachine, failed to generate its Machine, with error: %v", vm.Name, err)
+			continue
+		}
+		content, err := yaml.Marshal(machine)
+		if err != nil {
+			return fmt.Errorf("%s: failed to marshal the Machine, with error: %v", vm.Name, err)
+		}
+		if _, err := fmt.Fprintf(out, "---\n%s", content); err != nil {
+			return err
+		}
+		adopted++
+	}
+	klog.Infof("generated the Machines of %d of the %d VirtualMachines of namespace %s", adopted, len(vms.Items), infraNamespace)
+	return nil
+}
+
+// managedVirtualMachines returns the names of the VirtualMachines of the Machines: by their providerID, or by their
+// name until the providerID is set
+func managedVirtualMachines(machines []machinev1.Machine) map[string]bool {
+	managed := map[string]bool{}
+	for _, machine := range machines {
+		managed[machine.Name] = true
+		if machine.Spec.ProviderID != nil && *machine.Spec.ProviderID != "" {
+			if _, vmName, err := kubevirt.ParseProviderID(*machine.Spec.ProviderID); err == nil {
+				managed[vmName] = true
+			}
+		}
+	}
+	return managed
+}
+
+// unadoptableReason returns why the VirtualMachine can't be adopted, or an empty string when it can
+func unadoptableReason(vm *kubevirtapiv1.VirtualMachine, managed map[string]bool) string {
+	if managed[vm.Name] {
+		return "it already has a Machine"
+	}
+	if adoptedBy := vm.Labels[machinescope.AdoptedByLabel]; adoptedBy != "" {
+		return fmt.Sprintf("it is adopted by Machine %s", adoptedBy)
+	}
+	for _, ownerReference := range vm.OwnerReferences {
+		if ownerReference.Kind == virtualMachinePoolKind {
+			return fmt.Sprintf("it belongs to VirtualMachinePool %s", ownerReference.Name)
+		}
+	}
+	if vm.DeletionTimestamp != nil {
+		return "it is being deleted"
+	}
+	if vm.Spec.Template == nil {
+		return "it has no template"
+	}
+	return ""
+}
+
+// MachineFromVirtualMachine generates the Machine adopting the VirtualMachine, with the provider spec reconstructed
+// from the VirtualMachine
+func MachineFromVirtualMachine(vm *kubevirtapiv1.VirtualMachine, machineNamespace string, infraID string) (*machinev1.Machine, error) {
+	providerSpec := ProviderSpecFromVirtualMachine(vm)
+	providerSpecValue, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(providerSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	machineLabels := map[string]string{}
+	for key, value := range vm.Labels {
+		if strings.HasPrefix(key, machineLabelPrefix) {
+			machineLabels[key] = value
+		}
+	}
+	if machineLabels[machinev1.MachineClusterIDLabel] == "" {
+		machineLabels[machinev1.MachineClusterIDLabel] = infraID
+	}
+	if machineLabels[machineRoleLabel] == "" {
+		machineLabels[machineRoleLabel] = defaultRole
+	}
+	if machineLabels[machineTypeLabel] == "" {
+		machineLabels[machineTypeLabel] = machineLabels[machineRoleLabel]
+	}
+
+	providerID := kubevirt.FormatProviderID(vm.Namespace, vm.Name)
+	return &machinev1.Machine{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: machinev1.SchemeGroupVersion.String(),
+			Kind:       "Machine",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      vm.Name,
+			Namespace: machineNamespace,
+			Labels:    machineLabels,
+			Annotations: map[string]string{
+				machinescope.KubevirtIdAnnotationKey: string(vm.UID),
+			},
+		},
+		Spec: machinev1.MachineSpec{
+			ProviderSpec: machinev1.ProviderSpec{Value: providerSpecValue},
+			ProviderID:   &providerID,
+		},
+	}, nil
+}
+
+// ProviderSpecFromVirtualMachine reconstructs the provider spec of a VirtualMachine, from its boot volume, its
+// resources, its network and its config drive. The fields which can't be told from the VirtualMachine, like the
+// ignition secret of the tenant-cluster, are left to their defaults, and the reconciliation mode is status-only.
+func ProviderSpecFromVirtualMachine(vm *kubevirtapiv1.VirtualMachine) *kubevirtproviderv1alpha1.KubevirtMachineProviderSpec {
+	providerSpec := &kubevirtproviderv1alpha1.KubevirtMachineProviderSpec{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: kubevirtproviderv1alpha1.SchemeGroupVersion.String(),
+			Kind:       "KubevirtMachineProviderSpec",
+		},
+		ReconciliationMode: kubevirtproviderv1alpha1.ReconciliationModeStatusOnly,
+	}
+
+	if len(vm.Spec.DataVolumeTemplates) > 0 {
+		spec := vm.Spec.DataVolumeTemplates[0].Spec
+		if spec.Source.PVC != nil {
+			providerSpec.SourcePvcName = spec.Source.PVC.Name
+		}
+		if spec.PVC != nil {
+			if storage, ok := spec.PVC.Resources.Requests[corev1.ResourceStorage]; ok {
+				providerSpec.RequestedStorage = storage.String()
+			}
+			if spec.PVC.StorageClassName != nil {
+				providerSpec.StorageClassName = *spec.PVC.StorageClassName
+			}
+			if len(spec.PVC.AccessModes) > 0 {
+				providerSpec.PersistentVolumeAccessMode = string(spec.PVC.AccessModes[0])
+			}
+		}
+	}
+
+	templateSpec := vm.Spec.Template.Spec
+	if memory, ok := templateSpec.Domain.Resources.Requests[corev1.ResourceMemory]; ok {
+		providerSpec.RequestedMemory = memory.String()
+	}
+	if cpu, ok := templateSpec.Domain.Resources.Requests[corev1.ResourceCPU]; ok {
+		providerSpec.RequestedCPU = uint32(cpu.Value())
+	} else if templateSpec.Domain.CPU != nil && templateSpec.Domain.CPU.Cores != 0 {
+		providerSpec.RequestedCPU = templateSpec.Domain.CPU.Cores
+	}
+	providerSpec.Zone = templateSpec.NodeSelector[machinescope.InfraZoneLabel]
+	providerSpec.DNSPolicy = templateSpec.DNSPolicy
+	if templateSpec.DNSConfig != nil {
+		providerSpec.DNSConfig = templateSpec.DNSConfig.DeepCopy()
+	}
+
+	// The network of the VirtualMachine is the multus network of its first interface
+	if len(templateSpec.Domain.Devices.Interfaces) > 0 {
+		iface := templateSpec.Domain.Devices.Interfaces[0]
+		providerSpec.InterfaceModel = iface.Model
+		providerSpec.MacAddress = iface.MacAddress
+		if iface.SRIOV != nil {
+			providerSpec.InterfaceBindingMethod = "SRIOV"
+		}
+		for _, network := range templateSpec.Networks {
+			if network.Name == iface.Name && network.Multus != nil {
+				providerSpec.NetworkName = network.Multus.NetworkName
+			}
+		}
+	}
+
+	// The VirtualMachine keeps booting from the ignition of its config drive
+	for _, volume := range templateSpec.Volumes {
+		if volume.CloudInitConfigDrive != nil && volume.CloudInitConfigDrive.UserDataSecretRef != nil {
+			providerSpec.InfraIgnitionSecretName = volume.CloudInitConfigDrive.UserDataSecretRef.Name
+		}
+	}
+	return providerSpec
+}
@@ -0,0 +1,125 @@
+package adopt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+	mockInfraClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster/mock"
+	mockTenantClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster/mock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/testutils"
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"gotest.tools/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtapiv1 "kubevirt.io/client-go/api/v1"
+	"sigs.k8s.io/yaml"
+)
+
+func TestProviderSpecFromVirtualMachine(t *testing.T) {
+	vm := testutils.StubVirtualMachine(nil, nil, nil)
+	vm.Spec.Template.Spec.NodeSelector = map[string]string{machinescope.InfraZoneLabel: "zone-a"}
+	vm.Spec.Template.Spec.Domain.Devices.Interfaces[0].MacAddress = "02:00:00:00:00:01"
+
+	assert.DeepEqual(t, ProviderSpecFromVirtualMachine(vm), &kubevirtproviderv1alpha1.KubevirtMachineProviderSpec{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "kubevirtproviderconfig.openshift.io/v1alpha1",
+			Kind:       "KubevirtMachineProviderSpec",
+		},
+		SourcePvcName:              "test-source-pvc-name",
+		RequestedMemory:            "123456M",
+		RequestedCPU:               77,
+		RequestedStorage:           "666Gi",
+		StorageClassName:           "test-storage-class",
+		PersistentVolumeAccessMode: "ReadWriteOnce",
+		NetworkName:                "test-network-name",
+		MacAddress:                 "02:00:00:00:00:01",
+		InfraIgnitionSecretName:    fmt.Sprintf("%s-ignition", testutils.MachineName),
+		Zone:                       "zone-a",
+		ReconciliationMode:         kubevirtproviderv1alpha1.ReconciliationModeStatusOnly,
+	})
+}
+
+func TestAdopt(t *testing.T) {
+	cMap := map[string]string{
+		configMapInfraNamespaceKeyName: testutils.InfraNamespace,
+		configMapInfraIDKeyName:        testutils.InfraID,
+	}
+	stubVM := func(name string, modify func(vm *kubevirtapiv1.VirtualMachine)) kubevirtapiv1.VirtualMachine {
+		vm := testutils.StubVirtualMachine(&name, nil, testutils.StringPointer(name+"-uid"))
+		if modify != nil {
+			modify(vm)
+		}
+		return *vm
+	}
+	managedMachine := func(name string, vmName string) machinev1.Machine {
+		providerID := fmt.Sprintf("kubevirt://%s/%s", testutils.InfraNamespace, vmName)
+		return machinev1.Machine{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       machinev1.MachineSpec{ProviderID: &providerID},
+		}
+	}
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	infraClient := mockInfraClusterClient.NewMockClient(mockCtrl)
+	tenantClient := mockTenantClusterClient.NewMockClient(mockCtrl)
+	tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+	infraClient.EXPECT().ListVirtualMachine(gomock.Any(), testutils.InfraNamespace, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("tenantcluster-%s-machine.openshift.io=owned", testutils.InfraID),
+	}).Return(&kubevirtapiv1.VirtualMachineList{Items: []kubevirtapiv1.VirtualMachine{
+		stubVM("manual-worker", func(vm *kubevirtapiv1.VirtualMachine) {
+			vm.Labels["machine.openshift.io/cluster-api-machine-role"] = "infra"
+			vm.Labels["unrelated"] = "label"
+		}),
+		stubVM("named-machine", nil),
+		stubVM("renamed-vm", nil),
+		stubVM("pre-staged", func(vm *kubevirtapiv1.VirtualMachine) {
+			vm.Labels[machinescope.AdoptedByLabel] = "other-machine"
+		}),
+		stubVM("pool-vm", func(vm *kubevirtapiv1.VirtualMachine) {
+			vm.OwnerReferences = []metav1.OwnerReference{{Kind: "VirtualMachinePool", Name: "pool"}}
+		}),
+	}}, nil).Times(1)
+	tenantClient.EXPECT().ListMachines(gomock.Any()).Return([]machinev1.Machine{
+		managedMachine("named-machine", "named-machine"),
+		managedMachine("renaming-machine", "renamed-vm"),
+	}, nil).Times(1)
+
+	out := &bytes.Buffer{}
+	assert.NilError(t, New(infraClient, tenantClient).Adopt(context.Background(), "openshift-machine-api", out))
+
+	documents := strings.Split(strings.TrimPrefix(out.String(), "---\n"), "---\n")
+	assert.Equal(t, len(documents), 1)
+	machine := &machinev1.Machine{}
+	assert.NilError(t, yaml.Unmarshal([]byte(documents[0]), machine))
+	assert.Equal(t, machine.Name, "manual-worker")
+	assert.Equal(t, machine.Namespace, "openshift-machine-api")
+	assert.Equal(t, *machine.Spec.ProviderID, fmt.Sprintf("kubevirt://%s/manual-worker", testutils.InfraNamespace))
+	assert.DeepEqual(t, machine.Annotations, map[string]string{machinescope.KubevirtIdAnnotationKey: "manual-worker-uid"})
+	assert.DeepEqual(t, machine.Labels, map[string]string{
+		machinev1.MachineClusterIDLabel:                 "test-cluster-id",
+		"machine.openshift.io/cluster-api-machine-role": "infra",
+		"machine.openshift.io/cluster-api-machine-type": "infra",
+	})
+	providerSpec, err := kubevirtproviderv1alpha1.ProviderSpecFromRawExtension(machine.Spec.ProviderSpec.Value)
+	assert.NilError(t, err)
+	assert.Equal(t, providerSpec.SourcePvcName, "test-source-pvc-name")
+	assert.Equal(t, providerSpec.ReconciliationMode, kubevirtproviderv1alpha1.ReconciliationModeStatusOnly)
+}
+
+func TestAdoptConfigMapWithoutInfraID(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	infraClient := mockInfraClusterClient.NewMockClient(mockCtrl)
+	tenantClient := mockTenantClusterClient.NewMockClient(mockCtrl)
+	tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(
+		&map[string]string{configMapInfraNamespaceKeyName: testutils.InfraNamespace}, nil).Times(1)
+
+	err := New(infraClient, tenantClient).Adopt(context.Background(), "openshift-machine-api", &bytes.Buffer{})
+	assert.Error(t, err, "configMap openshift-config/cloud-provider-config doesn't contain the keys namespace and infraID")
+}
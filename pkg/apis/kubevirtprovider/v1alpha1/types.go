@@ -17,6 +17,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	kubevirtapiv1 "kubevirt.io/client-go/api/v1"
 )
@@ -26,17 +27,240 @@ import (
 // +genclient
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 type KubevirtMachineProviderSpec struct {
-	metav1.TypeMeta            `json:",inline"`
-	SourcePvcName              string `json:"sourcePvcName,omitempty"`
-	CredentialsSecretName      string `json:"credentialsSecretName,omitempty"`
-	RequestedMemory            string `json:"requestedMemory,omitempty"`
-	RequestedCPU               uint32 `json:"requestedCPU,omitempty"`
-	RequestedStorage           string `json:"requestedStorage,omitempty"`
-	StorageClassName           string `json:"storageClassName,omitempty"`
-	IgnitionSecretName         string `json:"ignitionSecretName,omitempty"`
-	NetworkName                string `json:"networkName,omitempty"`
-	InterfaceBindingMethod     string `json:"interfaceBindingMethod,omitempty"`
+	metav1.TypeMeta        `json:",inline"`
+	SourcePvcName          string `json:"sourcePvcName,omitempty"`
+	CredentialsSecretName  string `json:"credentialsSecretName,omitempty"`
+	RequestedMemory        string `json:"requestedMemory,omitempty"`
+	RequestedCPU           uint32 `json:"requestedCPU,omitempty"`
+	RequestedStorage       string `json:"requestedStorage,omitempty"`
+	StorageClassName       string `json:"storageClassName,omitempty"`
+	IgnitionSecretName     string `json:"ignitionSecretName,omitempty"`
+	NetworkName            string `json:"networkName,omitempty"`
+	InterfaceBindingMethod string `json:"interfaceBindingMethod,omitempty"`
+	// PersistentVolumeAccessMode of the boot volume. When empty, the access mode is selected by the CDI StorageProfile
+	// of the storage class, preferring ReadWriteMany over ReadWriteOnce, and defaults to ReadWriteMany when unknown.
 	PersistentVolumeAccessMode string `json:"persistentVolumeAccessMode,omitempty"`
+	// InterfaceModel is the emulated network device model (virtio, e1000, e1000e, ne2k_pci, pcnet, rtl8139),
+	// KubeVirt defaults to virtio. The MTU of the interface follows the NetworkAttachmentDefinition.
+	InterfaceModel string `json:"interfaceModel,omitempty"`
+	// MacAddress of the main interface. When empty, the MAC assigned to the VirtualMachine in the infra-cluster
+	// (e.g. by kubemacpool) is recorded on the Machine and reused if the VirtualMachine is recreated.
+	// It can't be set on the Machines of a MachineSet, which would all share the same MAC address.
+	MacAddress string `json:"macAddress,omitempty"`
+	// PropagateIgnitionViaSecret defaults to true. When set to false, no ignition secret is created in the
+	// infra-cluster, the ignition is uploaded as a config drive image to a DataVolume through the CDI upload
+	// proxy of the infra-cluster instead, and the VirtualMachine mounts the DataVolume as its config drive.
+	PropagateIgnitionViaSecret *bool `json:"propagateIgnitionViaSecret,omitempty"`
+	// InstanceMetadata holds custom key/values written, together with the instance-id, to
+	// /etc/kubevirt/instance-metadata.json in the guest
+	InstanceMetadata map[string]string `json:"instanceMetadata,omitempty"`
+	// DisableHostnameInjection skips writing /etc/hostname through the ignition, for images which
+	// manage their hostname via DHCP/systemd
+	DisableHostnameInjection bool `json:"disableHostnameInjection,omitempty"`
+	// HostnameOverride is a go template (e.g. "worker-{{.MachineName}}") for the hostname of the guest,
+	// and therefore the name of its Node. Defaults to the Machine name.
+	HostnameOverride string `json:"hostnameOverride,omitempty"`
+	// NameTemplate is a go template (e.g. "{{.InfraID}}-{{.MachineName}}") for the name of the VirtualMachine
+	// in the infra-cluster, which prefixes the names of its boot DataVolume and ignition secret.
+	// The templates can reference .MachineName, .MachineNamespace, .InfraID, .InfraNamespace, .Zone and .Index,
+	// the last dash-separated segment of the Machine name. Defaults to the Machine name.
+	// SourcePvcName and NetworkName may also be go templates, e.g. "rhcos-{{.Zone}}" to clone the golden image
+	// of the zone of every Machine, or "{{.Index}}" suffixed names to differentiate the replicas of a MachineSet.
+	NameTemplate string `json:"nameTemplate,omitempty"`
+	// WaitForGuestAgent delays reporting the Machine as ready, and setting the providerID of its Node,
+	// until the qemu-guest-agent of the VirtualMachineInstance is connected
+	WaitForGuestAgent bool `json:"waitForGuestAgent,omitempty"`
+	// DNSPolicy and DNSConfig are set on the VirtualMachineInstance, so workers on isolated networks
+	// can be pointed to name servers which resolve the tenant-cluster endpoints
+	DNSPolicy corev1.DNSPolicy     `json:"dnsPolicy,omitempty"`
+	DNSConfig *corev1.PodDNSConfig `json:"dnsConfig,omitempty"`
+	// NodeLabels and NodeTaints are applied to the Node of the Machine by the nodeupdate controller,
+	// whenever the Node is linked to its Machine, so the labels and taints added later are applied as well
+	NodeLabels map[string]string `json:"nodeLabels,omitempty"`
+	NodeTaints []corev1.Taint    `json:"nodeTaints,omitempty"`
+	// RequireNodeDrainedBeforeDelete blocks the deletion of the VirtualMachine until the Node of the Machine
+	// runs no pods other than DaemonSet and mirror pods, or carries the out-of-service taint
+	RequireNodeDrainedBeforeDelete bool `json:"requireNodeDrainedBeforeDelete,omitempty"`
+	// NodeSmokeCheck, when set, has the kubelet register the Node of the Machine with an uninitialized taint,
+	// which is removed once the Node passes the check, so broken workers never receive workloads. The kubelet
+	// unit of the image has to pass the KUBELET_EXTRA_ARGS of its environment to the kubelet.
+	NodeSmokeCheck *NodeSmokeCheck `json:"nodeSmokeCheck,omitempty"`
+	// IsolateTenantNetwork maintains a NetworkPolicy in the infra namespace, which only lets the virt-launcher
+	// pods of this tenant-cluster, and the pods of the TenantNetworkAllowedNamespaces, reach the VirtualMachines
+	// of the tenant-cluster
+	IsolateTenantNetwork bool `json:"isolateTenantNetwork,omitempty"`
+	// TenantNetworkAllowedNamespaces are the infra-cluster namespaces whose pods may reach the VirtualMachines
+	// isolated by IsolateTenantNetwork, e.g. the namespaces of the ingress routers and of the monitoring
+	TenantNetworkAllowedNamespaces []string `json:"tenantNetworkAllowedNamespaces,omitempty"`
+	// IgnitionMergeSource, when set, keeps the ignition content and its bootstrap credentials out of the infra-cluster:
+	// the userdata written to the infra-cluster only tells the guest to merge the ignition from this source
+	IgnitionMergeSource *IgnitionMergeSource `json:"ignitionMergeSource,omitempty"`
+	// IgnitionSourceHost rewrites the host of the remote ignitions the userData merges, as host or host:port, when
+	// the guest reaches the machine config server through another address than the one of the userData, e.g. on a
+	// Multus network instead of the internal API address. The certificate of the source must be valid for the host.
+	IgnitionSourceHost string `json:"ignitionSourceHost,omitempty"`
+	// IgnitionSourceCheck, when set, probes that the remote ignitions merged by the userData, e.g. the machine config
+	// server, are reachable from the network of the Machine before its VirtualMachine is created, so the creation
+	// fails with a clear event instead of a guest hanging at the fetch of its ignition
+	IgnitionSourceCheck *IgnitionSourceCheck `json:"ignitionSourceCheck,omitempty"`
+	// InfraIgnitionSecretName references a pre-provisioned secret in the infra namespace, holding the ignition
+	// under the "userdata" key, which is mounted as is: no ignition secret is created, IgnitionSecretName isn't
+	// required, and the hostname and instance metadata aren't injected into the ignition
+	InfraIgnitionSecretName string `json:"infraIgnitionSecretName,omitempty"`
+	// AfterburnMetadata, when set, writes the attributes afterburn reports for the kubevirt platform to the guest,
+	// and has the kubelet register the Node with the providerID and the instance type label of the attributes,
+	// before the nodeupdate controller sets them
+	AfterburnMetadata *AfterburnMetadata `json:"afterburnMetadata,omitempty"`
+	// AddressesFromPools claims a static address of the main interface from each pool, through IPAddressClaims
+	// of the ipam.cluster.x-k8s.io API in the namespace of the Machine. The VirtualMachine is created once the
+	// addresses are allocated, with the addresses in the network data of its config drive, and the claims are
+	// released when the Machine is deleted.
+	AddressesFromPools []AddressesFromPool `json:"addressesFromPools,omitempty"`
+	// Nameservers are written to the network data of the config drive, together with the claimed addresses
+	Nameservers []string `json:"nameservers,omitempty"`
+	// ReconciliationMode of the Machine once it is created, defaults to full
+	ReconciliationMode ReconciliationMode `json:"reconciliationMode,omitempty"`
+	// VirtualMachinePoolName is the name of a KubeVirt VirtualMachinePool in the infra namespace which backs the
+	// Machines of the MachineSet: the replicas of the pool follow the MachineSet, and every Machine adopts a
+	// VirtualMachine of the pool instead of creating its own. The spec of the adopted VirtualMachines is left to
+	// the pool, so the reconciliation mode defaults to status-only.
+	VirtualMachinePoolName string `json:"virtualMachinePoolName,omitempty"`
+	// ExistingVMName is the name of a halted VirtualMachine pre-staged in the infra namespace, which the Machine
+	// adopts instead of creating its own: the ignition of the Machine is injected in its config drive, it is
+	// started, and deleted with the Machine. Its spec is otherwise left as staged, so the reconciliation mode
+	// defaults to status-only.
+	ExistingVMName string `json:"existingVMName,omitempty"`
+	// BootVolumeDeletePolicy selects whether the boot DataVolume, and its PVC, are deleted with the Machine
+	// or retained in the infra namespace, e.g. for forensics or reuse. Defaults to Delete.
+	BootVolumeDeletePolicy BootVolumeDeletePolicy `json:"bootVolumeDeletePolicy,omitempty"`
+	// SourceImageDigest pins the golden image the boot volume is cloned from, as sha256:<hex>. The Machine is only
+	// created while the kubevirt.machine.openshift.io/image-digest annotation of the source PVC matches it, so
+	// a mutated golden image isn't silently picked up by the MachineSet.
+	SourceImageDigest string `json:"sourceImageDigest,omitempty"`
+	// DataSourceName is the CDI DataSource the boot volume is cloned from, instead of SourcePvcName. The DataSource
+	// is resolved when the Machine is created, so the Machines follow the golden images a DataImportCron keeps
+	// updating instead of a fixed PVC name that goes stale.
+	DataSourceName string `json:"dataSourceName,omitempty"`
+	// DataSourceNamespace is the namespace of the DataSource, defaults to the infra namespace
+	DataSourceNamespace string `json:"dataSourceNamespace,omitempty"`
+	// PropagateClusterProxy merges the cluster-wide proxy settings and additional trust bundle of the tenant-cluster
+	// into the ignition of the Machine, as the machine config server serves them, for the guest to fetch its
+	// ignition through the proxy, e.g. when its network reaches the machine config server only through the proxy
+	PropagateClusterProxy bool `json:"propagateClusterProxy,omitempty"`
+	// Zone is the infra zone the VirtualMachine is scheduled in, through a node selector on the
+	// topology.kubernetes.io/zone label of the infra nodes. The Machine is labeled with its zone.
+	Zone string `json:"zone,omitempty"`
+	// ZoneNetworkNames are the NetworkAttachmentDefinitions of the infra zones, which use different ones, keyed by
+	// zone. The network of the zone of the Machine overrides NetworkName. Without Zone, every Machine is assigned
+	// the zone with the fewest Machines of its MachineSet, so a single MachineSet spans the zones. The networks
+	// may be go templates, as NetworkName.
+	ZoneNetworkNames map[string]string `json:"zoneNetworkNames,omitempty"`
+	// OmitVirtualMachineStatus omits the raw VirtualMachineStatus, whose fields change with the KubeVirt versions,
+	// from the provider status of the Machine, which then only has the summary of the VirtualMachine
+	OmitVirtualMachineStatus bool `json:"omitVirtualMachineStatus,omitempty"`
+	// InstanceStateValues selects the values of the machine.openshift.io/instance-state annotation of the Machine.
+	// The KubeVirt specific state is always set in the kubevirt.machine.openshift.io/vm-state annotation.
+	// Defaults to Standard.
+	InstanceStateValues InstanceStateValues `json:"instanceStateValues,omitempty"`
+	// VersionedIgnitionSecret names the ignition secret of the VirtualMachine after the hash of its ignition,
+	// <name>-ignition-<hash>, so a recreated VirtualMachine with a new ignition gets a new version of the secret
+	// instead of the one in use. The versions no longer referenced by the VirtualMachine or its
+	// VirtualMachineInstance are deleted.
+	VersionedIgnitionSecret bool `json:"versionedIgnitionSecret,omitempty"`
+	// DeschedulerEviction marks the virt-launcher pod of the VirtualMachine for the descheduler of the infra-cluster:
+	// Evictable lets the descheduler rebalance the VirtualMachine with a live migration, NotEvictable asks it to
+	// leave the VirtualMachine in place. By default the descheduler applies its own policy.
+	DeschedulerEviction DeschedulerEviction `json:"deschedulerEviction,omitempty"`
+}
+
+// DeschedulerEviction selects whether the descheduler of the infra-cluster may evict the VirtualMachine
+type DeschedulerEviction string
+
+const (
+	// DeschedulerEvictionEvictable annotates the virt-launcher pod as evictable, and live migrates the
+	// VirtualMachine when its pod is evicted, which requires a ReadWriteMany boot volume
+	DeschedulerEvictionEvictable DeschedulerEviction = "Evictable"
+	// DeschedulerEvictionNotEvictable annotates the virt-launcher pod as preferring not to be evicted
+	DeschedulerEvictionNotEvictable DeschedulerEviction = "NotEvictable"
+)
+
+// InstanceStateValues selects the values of the instance-state annotation of the Machines
+type InstanceStateValues string
+
+const (
+	// InstanceStateValuesStandard are the values the other providers set: pending, running, stopped and terminated
+	InstanceStateValuesStandard InstanceStateValues = "Standard"
+	// InstanceStateValuesKubeVirt are the KubeVirt specific values: vmNotCreated, vmWasCreatedButNotReady and
+	// vmWasCreatedAndReady
+	InstanceStateValuesKubeVirt InstanceStateValues = "KubeVirt"
+)
+
+// BootVolumeDeletePolicy selects what happens to the boot volume of a deleted Machine
+type BootVolumeDeletePolicy string
+
+const (
+	// BootVolumeDeletePolicyDelete deletes the boot volume together with the VirtualMachine
+	BootVolumeDeletePolicyDelete BootVolumeDeletePolicy = "Delete"
+	// BootVolumeDeletePolicyRetain releases the boot volume from the VirtualMachine before it is deleted,
+	// so the garbage collector keeps it
+	BootVolumeDeletePolicyRetain BootVolumeDeletePolicy = "Retain"
+)
+
+// ReconciliationMode selects what the update of a created Machine reconciles
+type ReconciliationMode string
+
+const (
+	// ReconciliationModeNone leaves the Machine and its VirtualMachine as they were created
+	ReconciliationModeNone ReconciliationMode = "none"
+	// ReconciliationModeStatusOnly syncs the status and the providerID of the Machine from its VirtualMachine,
+	// but never rewrites the spec of the VirtualMachine
+	ReconciliationModeStatusOnly ReconciliationMode = "status-only"
+	// ReconciliationModeFull also rewrites the spec of the VirtualMachine from the Machine
+	ReconciliationModeFull ReconciliationMode = "full"
+)
+
+// AddressesFromPool references an address pool of the ipam.cluster.x-k8s.io API
+type AddressesFromPool struct {
+	// Group of the pool, e.g. ipamcontroller.openshift.io
+	Group string `json:"group"`
+	// Resource is the kind of the pool, e.g. IPPool
+	Resource string `json:"resource"`
+	// Name of the pool
+	Name string `json:"name"`
+}
+
+// AfterburnMetadata are the provider hints written to /etc/kubevirt/afterburn.env in the guest, as
+// AFTERBURN_KUBEVIRT_INSTANCE_ID, AFTERBURN_KUBEVIRT_PROVIDER_ID, AFTERBURN_KUBEVIRT_HOSTNAME and
+// AFTERBURN_KUBEVIRT_INSTANCE_TYPE environment variables. The file is the environment file of a drop-in of the
+// kubelet unit, which reads the providerID from its KUBELET_PROVIDERID variable; the kubelet unit of the image
+// has to pass the KUBELET_PROVIDERID and KUBELET_EXTRA_ARGS of its environment to the kubelet.
+type AfterburnMetadata struct {
+	// InstanceType is reported as the instance type of the guest, e.g. for the node.kubernetes.io/instance-type label
+	InstanceType string `json:"instanceType,omitempty"`
+}
+
+// IgnitionMergeSource is a TLS-protected endpoint the guest fetches its ignition from, e.g. the machine config server
+type IgnitionMergeSource struct {
+	// URL of the ignition, must use https
+	URL string `json:"url"`
+	// CABundle is the PEM encoded bundle of the certificate authorities the guest trusts for the URL
+	CABundle string `json:"caBundle,omitempty"`
+}
+
+// IgnitionSourceCheck probes the remote ignitions of the userData with a short-lived checkup pod in the infra
+// namespace, attached to the network of the Machine
+type IgnitionSourceCheck struct {
+	// Image of the checkup pod, which must provide sh and curl, defaults to the UBI minimal image
+	Image string `json:"image,omitempty"`
+}
+
+// NodeSmokeCheck is a lightweight check of a Node which joined the tenant-cluster
+type NodeSmokeCheck struct {
+	// ReadySeconds is the time the Node has to be Ready before the check passes
+	ReadySeconds int32 `json:"readySeconds,omitempty"`
+	// RequiredAnnotations are annotations the Node has to carry before the check passes,
+	// e.g. the annotation the CNI sets once the network of the Node is functional
+	RequiredAnnotations []string `json:"requiredAnnotations,omitempty"`
 }
 
 // KubevirtMachineProviderStatus is the type that will be embedded in a Machine.Status.ProviderStatus field.
@@ -45,7 +269,139 @@ type KubevirtMachineProviderSpec struct {
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 type KubevirtMachineProviderStatus struct {
 	metav1.TypeMeta `json:",inline"`
+	// VirtualMachineStatus is the raw status of the VirtualMachine, unless omitted by OmitVirtualMachineStatus.
+	// Its fields change with the KubeVirt versions, the consumers should read the VirtualMachine summary instead.
 	kubevirtapiv1.VirtualMachineStatus
+	// VirtualMachine summarizes the VirtualMachine and its VirtualMachineInstance, in fields which are stable
+	// across the KubeVirt versions
+	VirtualMachine *VirtualMachineSummary `json:"virtualMachine,omitempty"`
+	// NodeReadyCondition is the Ready condition of the Node of the Machine, without its heartbeat time
+	NodeReadyCondition *corev1.NodeCondition `json:"nodeReadyCondition,omitempty"`
+	// KubeletVersion is the kubelet version reported by the Node of the Machine
+	KubeletVersion string `json:"kubeletVersion,omitempty"`
+	// ProviderConditions is a set of conditions associated with the Machine, named so it doesn't
+	// shadow the conditions of the embedded VirtualMachineStatus
+	ProviderConditions []KubevirtMachineProviderCondition `json:"providerConditions,omitempty"`
+	// ProvisioningTimeline records when each provisioning step of the Machine was first observed
+	ProvisioningTimeline *ProvisioningTimeline `json:"provisioningTimeline,omitempty"`
+	// ObservedGeneration is the generation of the Machine the provider status was last synced with
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// VirtualMachineObservedGeneration is the generation of the VirtualMachine the provider status was last synced
+	// with. A VirtualMachine of an older generation is stale, and isn't synced.
+	VirtualMachineObservedGeneration int64 `json:"virtualMachineObservedGeneration,omitempty"`
+	// VirtualMachineUID is the UID of the VirtualMachine the Machine was first synced with. A VirtualMachine of
+	// another UID was recreated, and is never adopted: the identity of the node, its certificates and CSRs, no
+	// longer matches.
+	VirtualMachineUID string `json:"virtualMachineUID,omitempty"`
+}
+
+// VirtualMachineSummary is the status of the VirtualMachine of a Machine, independent of the KubeVirt versions
+type VirtualMachineSummary struct {
+	// Created is true when the VirtualMachineInstance of the VirtualMachine exists
+	Created bool `json:"created"`
+	// Ready is true when the VirtualMachineInstance of the VirtualMachine is ready
+	Ready bool `json:"ready"`
+	// Phase is the phase of the VirtualMachineInstance, e.g. Scheduling or Running
+	Phase string `json:"phase,omitempty"`
+	// PrintedStatus is the human readable status of the VirtualMachine, e.g. Starting, Running or Stopped
+	PrintedStatus string `json:"printedStatus,omitempty"`
+	// NodeName is the infra node the VirtualMachineInstance runs on
+	NodeName string `json:"nodeName,omitempty"`
+	// Conditions are the conditions of the VirtualMachine
+	Conditions []VirtualMachineSummaryCondition `json:"conditions,omitempty"`
+	// Migration is the last live migration of the VirtualMachineInstance between the infra nodes
+	Migration *VirtualMachineMigration `json:"migration,omitempty"`
+}
+
+// VirtualMachineMigration is a live migration of the VirtualMachineInstance of a Machine
+type VirtualMachineMigration struct {
+	// UID of the VirtualMachineInstanceMigration
+	UID string `json:"uid,omitempty"`
+	// SourceNode is the infra node the VirtualMachineInstance migrates from
+	SourceNode string `json:"sourceNode,omitempty"`
+	// TargetNode is the infra node the VirtualMachineInstance migrates to
+	TargetNode string `json:"targetNode,omitempty"`
+	// StartTime is the time the migration started
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// EndTime is the time the migration ended
+	EndTime *metav1.Time `json:"endTime,omitempty"`
+	// Completed is true once the migration succeeded
+	Completed bool `json:"completed,omitempty"`
+	// Failed is true once the migration failed
+	Failed bool `json:"failed,omitempty"`
+}
+
+// VirtualMachineSummaryCondition is a condition of the VirtualMachine of a Machine
+type VirtualMachineSummaryCondition struct {
+	// Type is the type of the condition, e.g. Ready or Failure
+	Type string `json:"type"`
+	// Status is the status of the condition
+	Status corev1.ConditionStatus `json:"status"`
+	// LastTransitionTime is the last time the condition transitioned from one status to another
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+	// Reason is a one-word CamelCase reason for the last transition of the condition
+	Reason string `json:"reason,omitempty"`
+	// Message is a human readable message about the last transition of the condition
+	Message string `json:"message,omitempty"`
+}
+
+// ProvisioningTimeline holds the timestamps of the provisioning steps of a Machine, showing where the
+// time to scale up goes: cloning the boot volume, booting the guest and joining the tenant-cluster
+type ProvisioningTimeline struct {
+	// VMCreated is the creation time of the VirtualMachine
+	VMCreated *metav1.Time `json:"vmCreated,omitempty"`
+	// DataVolumeReady is the creation time of the VirtualMachineInstance, which KubeVirt creates
+	// once the DataVolumes of the VirtualMachine are ready
+	DataVolumeReady *metav1.Time `json:"dataVolumeReady,omitempty"`
+	// VMIRunning is the time the VirtualMachineInstance became ready
+	VMIRunning *metav1.Time `json:"vmiRunning,omitempty"`
+	// NodeJoined is the creation time of the Node of the Machine
+	NodeJoined *metav1.Time `json:"nodeJoined,omitempty"`
+}
+
+// KubevirtMachineProviderConditionType is a valid value for KubevirtMachineProviderCondition.Type
+type KubevirtMachineProviderConditionType string
+
+// Valid conditions for a kubevirt machine instance
+const (
+	// MachineCreation indicates whether the machine has been created or not. If not,
+	// it should include a reason and message for the failure.
+	MachineCreation KubevirtMachineProviderConditionType = "MachineCreation"
+	// MachineRequiresReplacement indicates that the VirtualMachine of the machine was recreated in the
+	// infra-cluster, and that the machine has to be deleted and replaced to get a node again
+	MachineRequiresReplacement KubevirtMachineProviderConditionType = "MachineRequiresReplacement"
+)
+
+// KubevirtMachineProviderConditionReason is the reason of a KubevirtMachineProviderCondition
+type KubevirtMachineProviderConditionReason string
+
+const (
+	// MachineCreationSucceeded indicates machine creation success
+	MachineCreationSucceeded KubevirtMachineProviderConditionReason = "MachineCreationSucceeded"
+	// MachineCreationFailed indicates machine creation failure
+	MachineCreationFailed KubevirtMachineProviderConditionReason = "MachineCreationFailed"
+	// VirtualMachineRecreated indicates the VirtualMachine of the machine isn't the one it was first synced with
+	VirtualMachineRecreated KubevirtMachineProviderConditionReason = "VirtualMachineRecreated"
+)
+
+// KubevirtMachineProviderCondition is a condition in a KubevirtMachineProviderStatus
+type KubevirtMachineProviderCondition struct {
+	// Type is the type of the condition.
+	Type KubevirtMachineProviderConditionType `json:"type"`
+	// Status is the status of the condition.
+	Status corev1.ConditionStatus `json:"status"`
+	// LastProbeTime is the last time we probed the condition.
+	// +optional
+	LastProbeTime metav1.Time `json:"lastProbeTime,omitempty"`
+	// LastTransitionTime is the last time the condition transitioned from one status to another.
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+	// Reason is a unique, one-word, CamelCase reason for the condition's last transition.
+	// +optional
+	Reason KubevirtMachineProviderConditionReason `json:"reason,omitempty"`
+	// Message is a human-readable message indicating details about last transition.
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
 func init() {
@@ -21,13 +21,164 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AddressesFromPool) DeepCopyInto(out *AddressesFromPool) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AddressesFromPool.
+func (in *AddressesFromPool) DeepCopy() *AddressesFromPool {
+	if in == nil {
+		return nil
+	}
+	out := new(AddressesFromPool)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AfterburnMetadata) DeepCopyInto(out *AfterburnMetadata) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AfterburnMetadata.
+func (in *AfterburnMetadata) DeepCopy() *AfterburnMetadata {
+	if in == nil {
+		return nil
+	}
+	out := new(AfterburnMetadata)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IgnitionMergeSource) DeepCopyInto(out *IgnitionMergeSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IgnitionMergeSource.
+func (in *IgnitionMergeSource) DeepCopy() *IgnitionMergeSource {
+	if in == nil {
+		return nil
+	}
+	out := new(IgnitionMergeSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IgnitionSourceCheck) DeepCopyInto(out *IgnitionSourceCheck) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IgnitionSourceCheck.
+func (in *IgnitionSourceCheck) DeepCopy() *IgnitionSourceCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(IgnitionSourceCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubevirtMachineProviderCondition) DeepCopyInto(out *KubevirtMachineProviderCondition) {
+	*out = *in
+	in.LastProbeTime.DeepCopyInto(&out.LastProbeTime)
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubevirtMachineProviderCondition.
+func (in *KubevirtMachineProviderCondition) DeepCopy() *KubevirtMachineProviderCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(KubevirtMachineProviderCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KubevirtMachineProviderSpec) DeepCopyInto(out *KubevirtMachineProviderSpec) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
+	if in.PropagateIgnitionViaSecret != nil {
+		in, out := &in.PropagateIgnitionViaSecret, &out.PropagateIgnitionViaSecret
+		*out = new(bool)
+		**out = **in
+	}
+	if in.InstanceMetadata != nil {
+		in, out := &in.InstanceMetadata, &out.InstanceMetadata
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.DNSConfig != nil {
+		in, out := &in.DNSConfig, &out.DNSConfig
+		*out = new(v1.PodDNSConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NodeLabels != nil {
+		in, out := &in.NodeLabels, &out.NodeLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.NodeTaints != nil {
+		in, out := &in.NodeTaints, &out.NodeTaints
+		*out = make([]v1.Taint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.NodeSmokeCheck != nil {
+		in, out := &in.NodeSmokeCheck, &out.NodeSmokeCheck
+		*out = new(NodeSmokeCheck)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TenantNetworkAllowedNamespaces != nil {
+		in, out := &in.TenantNetworkAllowedNamespaces, &out.TenantNetworkAllowedNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IgnitionMergeSource != nil {
+		in, out := &in.IgnitionMergeSource, &out.IgnitionMergeSource
+		*out = new(IgnitionMergeSource)
+		**out = **in
+	}
+	if in.IgnitionSourceCheck != nil {
+		in, out := &in.IgnitionSourceCheck, &out.IgnitionSourceCheck
+		*out = new(IgnitionSourceCheck)
+		**out = **in
+	}
+	if in.AfterburnMetadata != nil {
+		in, out := &in.AfterburnMetadata, &out.AfterburnMetadata
+		*out = new(AfterburnMetadata)
+		**out = **in
+	}
+	if in.AddressesFromPools != nil {
+		in, out := &in.AddressesFromPools, &out.AddressesFromPools
+		*out = make([]AddressesFromPool, len(*in))
+		copy(*out, *in)
+	}
+	if in.Nameservers != nil {
+		in, out := &in.Nameservers, &out.Nameservers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ZoneNetworkNames != nil {
+		in, out := &in.ZoneNetworkNames, &out.ZoneNetworkNames
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubevirtMachineProviderSpec.
@@ -53,6 +204,28 @@ func (in *KubevirtMachineProviderStatus) DeepCopyInto(out *KubevirtMachineProvid
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.VirtualMachineStatus.DeepCopyInto(&out.VirtualMachineStatus)
+	if in.VirtualMachine != nil {
+		in, out := &in.VirtualMachine, &out.VirtualMachine
+		*out = new(VirtualMachineSummary)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NodeReadyCondition != nil {
+		in, out := &in.NodeReadyCondition, &out.NodeReadyCondition
+		*out = new(v1.NodeCondition)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ProviderConditions != nil {
+		in, out := &in.ProviderConditions, &out.ProviderConditions
+		*out = make([]KubevirtMachineProviderCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ProvisioningTimeline != nil {
+		in, out := &in.ProvisioningTimeline, &out.ProvisioningTimeline
+		*out = new(ProvisioningTimeline)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubevirtMachineProviderStatus.
@@ -72,3 +245,120 @@ func (in *KubevirtMachineProviderStatus) DeepCopyObject() runtime.Object {
 	}
 	return nil
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeSmokeCheck) DeepCopyInto(out *NodeSmokeCheck) {
+	*out = *in
+	if in.RequiredAnnotations != nil {
+		in, out := &in.RequiredAnnotations, &out.RequiredAnnotations
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeSmokeCheck.
+func (in *NodeSmokeCheck) DeepCopy() *NodeSmokeCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeSmokeCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProvisioningTimeline) DeepCopyInto(out *ProvisioningTimeline) {
+	*out = *in
+	if in.VMCreated != nil {
+		in, out := &in.VMCreated, &out.VMCreated
+		*out = (*in).DeepCopy()
+	}
+	if in.DataVolumeReady != nil {
+		in, out := &in.DataVolumeReady, &out.DataVolumeReady
+		*out = (*in).DeepCopy()
+	}
+	if in.VMIRunning != nil {
+		in, out := &in.VMIRunning, &out.VMIRunning
+		*out = (*in).DeepCopy()
+	}
+	if in.NodeJoined != nil {
+		in, out := &in.NodeJoined, &out.NodeJoined
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisioningTimeline.
+func (in *ProvisioningTimeline) DeepCopy() *ProvisioningTimeline {
+	if in == nil {
+		return nil
+	}
+	out := new(ProvisioningTimeline)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualMachineMigration) DeepCopyInto(out *VirtualMachineMigration) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.EndTime != nil {
+		in, out := &in.EndTime, &out.EndTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachineMigration.
+func (in *VirtualMachineMigration) DeepCopy() *VirtualMachineMigration {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualMachineMigration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualMachineSummary) DeepCopyInto(out *VirtualMachineSummary) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]VirtualMachineSummaryCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Migration != nil {
+		in, out := &in.Migration, &out.Migration
+		*out = new(VirtualMachineMigration)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachineSummary.
+func (in *VirtualMachineSummary) DeepCopy() *VirtualMachineSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualMachineSummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualMachineSummaryCondition) DeepCopyInto(out *VirtualMachineSummaryCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachineSummaryCondition.
+func (in *VirtualMachineSummaryCondition) DeepCopy() *VirtualMachineSummaryCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualMachineSummaryCondition)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,333 @@
+package infracluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog"
+	kubevirtapiv1 "kubevirt.io/client-go/api/v1"
+	cdiv1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
+)
+
+// maxAuditChangeDepth is the depth of the paths of the changed fields of an update, e.g. spec.template.spec.domain
+const maxAuditChangeDepth = 4
+
+// AuditRecord is a mutating request to the infra-cluster API
+type AuditRecord struct {
+	Time time.Time `json:"time"`
+	// CredentialsSecret is the credentials secret of the infra-cluster, unset for the infra-cluster of the controller
+	CredentialsSecret string `json:"credentialsSecret,omitempty"`
+	// Machine is the Machine the request was made for, unset for the requests of the cluster-wide controllers
+	Machine   string `json:"machine,omitempty"`
+	Verb      string `json:"verb"`
+	Resource  string `json:"resource"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	// Changes are the paths of the fields changed by an update, the values are never recorded
+	Changes []string `json:"changes,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// AuditLog writes the audit records as JSON lines
+type AuditLog struct {
+	lock    sync.Mutex
+	encoder *json.Encoder
+}
+
+// NewAuditLog returns the AuditLog writing to the writer
+func NewAuditLog(w io.Writer) *AuditLog {
+	return &AuditLog{encoder: json.NewEncoder(w)}
+}
+
+// OpenAuditLog returns the AuditLog appending to the file, or writing to the standard output for "-". The audit
+// is disabled, with a nil AuditLog, for an empty path.
+func OpenAuditLog(path string) (*AuditLog, error) {
+	switch path {
+	case "":
+		return nil, nil
+	case "-":
+		return NewAuditLog(os.Stdout), nil
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the audit log %s, with error: %v", path, err)
+	}
+	return NewAuditLog(file), nil
+}
+
+// Record writes the audit record
+func (l *AuditLog) Record(record AuditRecord) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if err := l.encoder.Encode(record); err != nil {
+		klog.Errorf("failed to write the audit record of %s %s %s/%s, with error: %v",
+			record.Verb, record.Resource, record.Namespace, record.Name, err)
+	}
+}
+
+type requesterKey struct{}
+
+// WithRequester returns the context of the requests made for the Machine, which the audit records
+func WithRequester(ctx context.Context, machineName string) context.Context {
+	return context.WithValue(ctx, requesterKey{}, machineName)
+}
+
+// requesterOf returns the Machine the requests of the context are made for
+func requesterOf(ctx context.Context) string {
+	machineName, _ := ctx.Value(requesterKey{}).(string)
+	return machineName
+}
+
+// auditedClient records the mutating requests of the wrapped Client
+type auditedClient struct {
+	Client
+	log               *AuditLog
+	credentialsSecret string
+	now               func() time.Time
+}
+
+// WithAudit returns a Client which records its mutating requests in the audit log, with the Machine they are made
+// for and the fields changed by the updates, for the accountability of the changes made to a shared infra-cluster.
+// The updates get their object first, to compare it. The Client is returned as is when the audit log is nil.
+func WithAudit(client Client, log *AuditLog, credentialsSecret string) Client {
+	if log == nil {
+		return client
+	}
+	return &auditedClient{
+		Client:            client,
+		log:               log,
+		credentialsSecret: credentialsSecret,
+		now:               time.Now,
+	}
+}
+
+// record records the request, with the changes of an update
+func (c *auditedClient) record(ctx context.Context, verb string, resource string, namespace string, name string, changes []string, err error) {
+	record := AuditRecord{
+		Time:              c.now(),
+		CredentialsSecret: c.credentialsSecret,
+		Machine:           requesterOf(ctx),
+		Verb:              verb,
+		Resource:          resource,
+		Namespace:         namespace,
+		Name:              name,
+		Changes:           changes,
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	c.log.Record(record)
+}
+
+func (c *auditedClient) CreateVirtualMachine(ctx context.Context, namespace string, newVM *kubevirtapiv1.VirtualMachine) (*kubevirtapiv1.VirtualMachine, error) {
+	vm, err := c.Client.CreateVirtualMachine(ctx, namespace, newVM)
+	c.record(ctx, "create", "virtualmachines", namespace, newVM.Name, nil, err)
+	return vm, err
+}
+
+func (c *auditedClient) DeleteVirtualMachine(ctx context.Context, namespace string, name string, options *metav1.DeleteOptions) error {
+	err := c.Client.DeleteVirtualMachine(ctx, namespace, name, options)
+	c.record(ctx, "delete", "virtualmachines", namespace, name, nil, err)
+	return err
+}
+
+func (c *auditedClient) UpdateVirtualMachine(ctx context.Context, namespace string, vm *kubevirtapiv1.VirtualMachine) (*kubevirtapiv1.VirtualMachine, error) {
+	var changes []string
+	if existingVM, err := c.Client.GetVirtualMachine(ctx, namespace, vm.Name, &metav1.GetOptions{}); err == nil {
+		changes = changedFields(existingVM, vm)
+	}
+	updatedVM, err := c.Client.UpdateVirtualMachine(ctx, namespace, vm)
+	c.record(ctx, "update", "virtualmachines", namespace, vm.Name, changes, err)
+	return updatedVM, err
+}
+
+func (c *auditedClient) CreateSecret(ctx context.Context, namespace string, newSecret *corev1.Secret) (*corev1.Secret, error) {
+	secret, err := c.Client.CreateSecret(ctx, namespace, newSecret)
+	c.record(ctx, "create", "secrets", namespace, newSecret.Name, nil, err)
+	return secret, err
+}
+
+func (c *auditedClient) UpdateSecret(ctx context.Context, namespace string, secret *corev1.Secret) (*corev1.Secret, error) {
+	var changes []string
+	if existingSecret, err := c.Client.GetSecret(ctx, namespace, secret.Name); err == nil {
+		changes = changedFields(existingSecret, secret)
+	}
+	updatedSecret, err := c.Client.UpdateSecret(ctx, namespace, secret)
+	c.record(ctx, "update", "secrets", namespace, secret.Name, changes, err)
+	return updatedSecret, err
+}
+
+func (c *auditedClient) DeleteSecret(ctx context.Context, namespace string, name string) error {
+	err := c.Client.DeleteSecret(ctx, namespace, name)
+	c.record(ctx, "delete", "secrets", namespace, name, nil, err)
+	return err
+}
+
+func (c *auditedClient) CreateNamespace(ctx context.Context, newNamespace *corev1.Namespace) (*corev1.Namespace, error) {
+	namespace, err := c.Client.CreateNamespace(ctx, newNamespace)
+	c.record(ctx, "create", "namespaces", "", newNamespace.Name, nil, err)
+	return namespace, err
+}
+
+func (c *auditedClient) CreateResourceQuota(ctx context.Context, namespace string, newResourceQuota *corev1.ResourceQuota) (*corev1.ResourceQuota, error) {
+	resourceQuota, err := c.Client.CreateResourceQuota(ctx, namespace, newResourceQuota)
+	c.record(ctx, "create", "resourcequotas", namespace, newResourceQuota.Name, nil, err)
+	return resourceQuota, err
+}
+
+func (c *auditedClient) CreateNetworkPolicy(ctx context.Context, namespace string, newNetworkPolicy *networkingv1.NetworkPolicy) (*networkingv1.NetworkPolicy, error) {
+	networkPolicy, err := c.Client.CreateNetworkPolicy(ctx, namespace, newNetworkPolicy)
+	c.record(ctx, "create", "networkpolicies", namespace, newNetworkPolicy.Name, nil, err)
+	return networkPolicy, err
+}
+
+func (c *auditedClient) UpdateNetworkPolicy(ctx context.Context, namespace string, networkPolicy *networkingv1.NetworkPolicy) (*networkingv1.NetworkPolicy, error) {
+	var changes []string
+	if existingNetworkPolicy, err := c.Client.GetNetworkPolicy(ctx, namespace, networkPolicy.Name); err == nil {
+		changes = changedFields(existingNetworkPolicy, networkPolicy)
+	}
+	updatedNetworkPolicy, err := c.Client.UpdateNetworkPolicy(ctx, namespace, networkPolicy)
+	c.record(ctx, "update", "networkpolicies", namespace, networkPolicy.Name, changes, err)
+	return updatedNetworkPolicy, err
+}
+
+func (c *auditedClient) CreatePod(ctx context.Context, namespace string, newPod *corev1.Pod) (*corev1.Pod, error) {
+	pod, err := c.Client.CreatePod(ctx, namespace, newPod)
+	c.record(ctx, "create", "pods", namespace, newPod.Name, nil, err)
+	return pod, err
+}
+
+func (c *auditedClient) DeletePod(ctx context.Context, namespace string, name string) error {
+	err := c.Client.DeletePod(ctx, namespace, name)
+	c.record(ctx, "delete", "pods", namespace, name, nil, err)
+	return err
+}
+
+func (c *auditedClient) CreateConfigMap(ctx context.Context, namespace string, newConfigMap *corev1.ConfigMap) (*corev1.ConfigMap, error) {
+	configMap, err := c.Client.CreateConfigMap(ctx, namespace, newConfigMap)
+	c.record(ctx, "create", "configmaps", namespace, newConfigMap.Name, nil, err)
+	return configMap, err
+}
+
+func (c *auditedClient) DeleteConfigMap(ctx context.Context, namespace string, name string) error {
+	err := c.Client.DeleteConfigMap(ctx, namespace, name)
+	c.record(ctx, "delete", "configmaps", namespace, name, nil, err)
+	return err
+}
+
+func (c *auditedClient) CreateJob(ctx context.Context, namespace string, newJob *batchv1.Job) (*batchv1.Job, error) {
+	job, err := c.Client.CreateJob(ctx, namespace, newJob)
+	c.record(ctx, "create", "jobs", namespace, newJob.Name, nil, err)
+	return job, err
+}
+
+func (c *auditedClient) DeleteJob(ctx context.Context, namespace string, name string) error {
+	err := c.Client.DeleteJob(ctx, namespace, name)
+	c.record(ctx, "delete", "jobs", namespace, name, nil, err)
+	return err
+}
+
+func (c *auditedClient) CreateDataVolume(ctx context.Context, namespace string, newDataVolume *cdiv1.DataVolume) (*cdiv1.DataVolume, error) {
+	dataVolume, err := c.Client.CreateDataVolume(ctx, namespace, newDataVolume)
+	c.record(ctx, "create", "datavolumes", namespace, newDataVolume.Name, nil, err)
+	return dataVolume, err
+}
+
+func (c *auditedClient) UpdateDataVolume(ctx context.Context, namespace string, dataVolume *cdiv1.DataVolume) (*cdiv1.DataVolume, error) {
+	var changes []string
+	if existingDataVolume, err := c.Client.GetDataVolume(ctx, namespace, dataVolume.Name); err == nil {
+		changes = changedFields(existingDataVolume, dataVolume)
+	}
+	updatedDataVolume, err := c.Client.UpdateDataVolume(ctx, namespace, dataVolume)
+	c.record(ctx, "update", "datavolumes", namespace, dataVolume.Name, changes, err)
+	return updatedDataVolume, err
+}
+
+func (c *auditedClient) DeleteDataVolume(ctx context.Context, namespace string, name string) error {
+	err := c.Client.DeleteDataVolume(ctx, namespace, name)
+	c.record(ctx, "delete", "datavolumes", namespace, name, nil, err)
+	return err
+}
+
+func (c *auditedClient) UploadDataVolume(ctx context.Context, namespace string, name string, image []byte) error {
+	err := c.Client.UploadDataVolume(ctx, namespace, name, image)
+	c.record(ctx, "upload", "datavolumes", namespace, name, nil, err)
+	return err
+}
+
+func (c *auditedClient) UpdateVirtualMachinePool(ctx context.Context, namespace string, pool *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	var changes []string
+	if existingPool, err := c.Client.GetVirtualMachinePool(ctx, namespace, pool.GetName()); err == nil {
+		changes = changedFields(existingPool, pool)
+	}
+	updatedPool, err := c.Client.UpdateVirtualMachinePool(ctx, namespace, pool)
+	c.record(ctx, "update", "virtualmachinepools", namespace, pool.GetName(), changes, err)
+	return updatedPool, err
+}
+
+// changedFields returns the sorted paths of the labels, annotations, owners, spec and data fields which differ
+// between the objects
+func changedFields(existing interface{}, updated interface{}) []string {
+	existingFields, err := toUnstructured(existing)
+	if err != nil {
+		return nil
+	}
+	updatedFields, err := toUnstructured(updated)
+	if err != nil {
+		return nil
+	}
+	var changes []string
+	existingMetadata, _ := existingFields["metadata"].(map[string]interface{})
+	updatedMetadata, _ := updatedFields["metadata"].(map[string]interface{})
+	for _, field := range []string{"labels", "annotations", "ownerReferences"} {
+		diffField("metadata."+field, existingMetadata[field], updatedMetadata[field], 2, &changes)
+	}
+	for _, field := range []string{"spec", "data", "stringData"} {
+		diffField(field, existingFields[field], updatedFields[field], 1, &changes)
+	}
+	sort.Strings(changes)
+	return changes
+}
+
+// diffField appends the path of the field when it differs, or the paths of its differing fields until the
+// maximal depth
+func diffField(path string, existing interface{}, updated interface{}, depth int, changes *[]string) {
+	if reflect.DeepEqual(existing, updated) {
+		return
+	}
+	existingMap, existingIsMap := existing.(map[string]interface{})
+	updatedMap, updatedIsMap := updated.(map[string]interface{})
+	if !existingIsMap || !updatedIsMap || depth >= maxAuditChangeDepth {
+		*changes = append(*changes, path)
+		return
+	}
+	for key, value := range existingMap {
+		diffField(path+"."+key, value, updatedMap[key], depth+1, changes)
+	}
+	for key := range updatedMap {
+		if _, ok := existingMap[key]; !ok {
+			*changes = append(*changes, path+"."+key)
+		}
+	}
+}
+
+// toUnstructured returns the fields of the object
+func toUnstructured(obj interface{}) (map[string]interface{}, error) {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		return u.Object, nil
+	}
+	return runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+}
@@ -0,0 +1,124 @@
+package infracluster_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster"
+	mockInfraClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster/mock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/testutils"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtapiv1 "kubevirt.io/client-go/api/v1"
+)
+
+// auditRecords returns the records of the audit log, without their time
+func auditRecords(t *testing.T, log *bytes.Buffer) []infracluster.AuditRecord {
+	var records []infracluster.AuditRecord
+	for _, line := range strings.Split(strings.TrimSpace(log.String()), "\n") {
+		var record infracluster.AuditRecord
+		assert.NilError(t, json.Unmarshal([]byte(line), &record))
+		assert.Assert(t, !record.Time.IsZero())
+		record.Time = time.Time{}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestWithAudit(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	client := mockInfraClusterClient.NewMockClient(mockCtrl)
+	var log bytes.Buffer
+	audited := infracluster.WithAudit(client, infracluster.NewAuditLog(&log), "test-namespace/infra-credentials")
+	ctx := infracluster.WithRequester(context.Background(), testutils.MachineName)
+
+	newVM := testutils.StubVirtualMachine(nil, nil, nil)
+	client.EXPECT().CreateVirtualMachine(gomock.Any(), testutils.InfraNamespace, newVM).Return(newVM, nil).Times(1)
+	_, err := audited.CreateVirtualMachine(ctx, testutils.InfraNamespace, newVM)
+	assert.NilError(t, err)
+
+	// The update records the paths of the changed fields, never their values
+	existingVM := testutils.StubVirtualMachine(nil, nil, nil)
+	updatedVM := existingVM.DeepCopy()
+	halted := kubevirtapiv1.RunStrategyHalted
+	updatedVM.Spec.RunStrategy = &halted
+	updatedVM.Labels["kubevirt.machine.openshift.io/adopted-by"] = testutils.MachineName
+	client.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, existingVM.Name, gomock.Any()).Return(existingVM, nil).Times(1)
+	client.EXPECT().UpdateVirtualMachine(gomock.Any(), testutils.InfraNamespace, updatedVM).Return(updatedVM, nil).Times(1)
+	_, err = audited.UpdateVirtualMachine(ctx, testutils.InfraNamespace, updatedVM)
+	assert.NilError(t, err)
+
+	existingSecret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "ignition"}, Data: map[string][]byte{"userdata": []byte("old")}}
+	updatedSecret := existingSecret.DeepCopy()
+	updatedSecret.Data["userdata"] = []byte("new")
+	client.EXPECT().GetSecret(gomock.Any(), testutils.InfraNamespace, "ignition").Return(existingSecret, nil).Times(1)
+	client.EXPECT().UpdateSecret(gomock.Any(), testutils.InfraNamespace, updatedSecret).Return(updatedSecret, nil).Times(1)
+	_, err = audited.UpdateSecret(ctx, testutils.InfraNamespace, updatedSecret)
+	assert.NilError(t, err)
+
+	// The requests of the cluster-wide controllers have no Machine
+	client.EXPECT().DeleteSecret(gomock.Any(), testutils.InfraNamespace, "ignition").Return(fmt.Errorf("test error")).Times(1)
+	assert.Error(t, audited.DeleteSecret(context.Background(), testutils.InfraNamespace, "ignition"), "test error")
+
+	// The reads aren't recorded
+	client.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, existingVM.Name, gomock.Any()).Return(existingVM, nil).Times(1)
+	_, err = audited.GetVirtualMachine(ctx, testutils.InfraNamespace, existingVM.Name, &metav1.GetOptions{})
+	assert.NilError(t, err)
+
+	assert.DeepEqual(t, auditRecords(t, &log), []infracluster.AuditRecord{
+		{
+			CredentialsSecret: "test-namespace/infra-credentials",
+			Machine:           testutils.MachineName,
+			Verb:              "create",
+			Resource:          "virtualmachines",
+			Namespace:         testutils.InfraNamespace,
+			Name:              newVM.Name,
+		},
+		{
+			CredentialsSecret: "test-namespace/infra-credentials",
+			Machine:           testutils.MachineName,
+			Verb:              "update",
+			Resource:          "virtualmachines",
+			Namespace:         testutils.InfraNamespace,
+			Name:              existingVM.Name,
+			Changes:           []string{"metadata.labels.kubevirt.machine.openshift.io/adopted-by", "spec.runStrategy"},
+		},
+		{
+			CredentialsSecret: "test-namespace/infra-credentials",
+			Machine:           testutils.MachineName,
+			Verb:              "update",
+			Resource:          "secrets",
+			Namespace:         testutils.InfraNamespace,
+			Name:              "ignition",
+			Changes:           []string{"data.userdata"},
+		},
+		{
+			CredentialsSecret: "test-namespace/infra-credentials",
+			Verb:              "delete",
+			Resource:          "secrets",
+			Namespace:         testutils.InfraNamespace,
+			Name:              "ignition",
+			Error:             "test error",
+		},
+	})
+	assert.Assert(t, !strings.Contains(log.String(), "bmV3"), "the values of the secrets are never recorded")
+}
+
+func TestWithAuditDisabled(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	client := mockInfraClusterClient.NewMockClient(mockCtrl)
+	assert.Equal(t, infracluster.WithAudit(client, nil, ""), infracluster.Client(client))
+
+	log, err := infracluster.OpenAuditLog("")
+	assert.NilError(t, err)
+	assert.Assert(t, log == nil)
+}
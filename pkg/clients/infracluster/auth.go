@@ -0,0 +1,50 @@
+package infracluster
+
+import (
+	machineapiapierrors "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"k8s.io/client-go/rest"
+
+	// Registers the OIDC auth provider, which the infra-cluster kubeconfig can reference by name. Other auth
+	// providers are registered with rest.RegisterAuthProviderPlugin before building the Client.
+	_ "k8s.io/client-go/plugin/pkg/client/auth/oidc"
+)
+
+// AuthOptions configure how the Client authenticates to the infra-cluster, on top of the credentials of its kubeconfig
+type AuthOptions struct {
+	// ExecCommands are the commands of the exec credential plugins the infra-cluster kubeconfig may run, e.g. the
+	// CLI issuing the tokens of an external managed KubeVirt cluster. The commands must be in the image of the
+	// controller, and match the command of the kubeconfig as is. The kubeconfigs with an exec credential plugin
+	// are rejected when empty.
+	ExecCommands []string
+	// CredentialsSecretName and CredentialsSecretNamespace are the secret of the tenant-cluster holding the
+	// infra-cluster kubeconfig, kubevirt-credentials in openshift-machine-api when empty
+	CredentialsSecretName      string
+	CredentialsSecretNamespace string
+}
+
+// credentialsSecret returns the namespace and the name of the credentials secret of the options
+func (o AuthOptions) credentialsSecret() (string, string) {
+	namespace, name := o.CredentialsSecretNamespace, o.CredentialsSecretName
+	if namespace == "" {
+		namespace = defaultCredentialsSecretSecretNamespace
+	}
+	if name == "" {
+		name = defaultCredentialsSecretSecretName
+	}
+	return namespace, name
+}
+
+// verifyAuth returns an InvalidMachineConfiguration error when the infra-cluster kubeconfig runs an exec
+// credential plugin which isn't allowed by the options
+func verifyAuth(config *rest.Config, options AuthOptions) error {
+	if config.ExecProvider == nil {
+		return nil
+	}
+	for _, command := range options.ExecCommands {
+		if config.ExecProvider.Command == command {
+			return nil
+		}
+	}
+	return machineapiapierrors.InvalidMachineConfiguration("Infra-cluster kubeconfig runs the credential plugin %q, which isn't an allowed exec command",
+		config.ExecProvider.Command)
+}
@@ -0,0 +1,52 @@
+package infracluster
+
+import (
+	"errors"
+	"testing"
+
+	machineapiapierrors "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"gotest.tools/assert"
+	"k8s.io/client-go/rest"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestVerifyAuth(t *testing.T) {
+	cases := []struct {
+		name         string
+		execProvider *clientcmdapi.ExecConfig
+		execCommands []string
+		expectedErr  string
+	}{
+		{
+			name: "no exec credential plugin",
+		},
+		{
+			name:         "allowed exec credential plugin",
+			execProvider: &clientcmdapi.ExecConfig{Command: "kubelogin"},
+			execCommands: []string{"oc", "kubelogin"},
+		},
+		{
+			name:         "exec credential plugin not allowed",
+			execProvider: &clientcmdapi.ExecConfig{Command: "/tmp/kubelogin"},
+			execCommands: []string{"kubelogin"},
+			expectedErr:  `Infra-cluster kubeconfig runs the credential plugin "/tmp/kubelogin", which isn't an allowed exec command`,
+		},
+		{
+			name:         "exec credential plugins disabled",
+			execProvider: &clientcmdapi.ExecConfig{Command: "kubelogin"},
+			expectedErr:  `Infra-cluster kubeconfig runs the credential plugin "kubelogin", which isn't an allowed exec command`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := verifyAuth(&rest.Config{ExecProvider: tc.execProvider}, AuthOptions{ExecCommands: tc.execCommands})
+			if tc.expectedErr == "" {
+				assert.NilError(t, err)
+				return
+			}
+			assert.Error(t, err, tc.expectedErr)
+			var machineErr *machineapiapierrors.MachineError
+			assert.Assert(t, errors.As(err, &machineErr))
+		})
+	}
+}
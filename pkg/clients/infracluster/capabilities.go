@@ -0,0 +1,177 @@
+package infracluster
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog"
+	kubevirtapiv1 "kubevirt.io/client-go/api/v1"
+)
+
+const (
+	cdiGroupName           = "cdi.kubevirt.io"
+	instancetypeGroupName  = "instancetype.kubevirt.io"
+	snapshotGroupName      = "snapshot.kubevirt.io"
+	poolGroupName          = "pool.kubevirt.io"
+	hotplugVolumeResource  = "virtualmachines/addvolume"
+	storageProfilesVersion = "v1beta1"
+	dataSourcesVersion     = "v1beta1"
+	// liveUpdateRolloutStrategy is the VirtualMachine rollout strategy of the KubeVirt configuration
+	// which applies CPU and memory changes to the running VirtualMachineInstances
+	liveUpdateRolloutStrategy = "LiveUpdate"
+)
+
+var kubevirtResource = schema.GroupVersionResource{
+	Group:    kubevirtapiv1.GroupVersion.Group,
+	Version:  kubevirtapiv1.GroupVersion.Version,
+	Resource: "kubevirts",
+}
+
+// Capabilities are the kubevirt and CDI API versions and the optional features the infra-cluster serves,
+// discovered when the Client is built
+type Capabilities struct {
+	// KubevirtVersions are the served versions of the kubevirt.io API group
+	KubevirtVersions []string
+	// CDIVersions are the served versions of the cdi.kubevirt.io API group
+	CDIVersions []string
+	// Hotplug is whether volumes can be hot-plugged to running VirtualMachines
+	Hotplug bool
+	// Instancetypes is whether the VirtualMachine instancetypes and preferences are served
+	Instancetypes bool
+	// Snapshots is whether the VirtualMachine snapshots are served
+	Snapshots bool
+	// PoolVersions are the served versions of the pool.kubevirt.io API group
+	PoolVersions []string
+	// LiveUpdate is whether CPU sockets and guest memory are hot-plugged to running VirtualMachines
+	LiveUpdate bool
+}
+
+// StorageProfiles returns whether the infra-cluster serves the CDI StorageProfiles
+func (c Capabilities) StorageProfiles() bool {
+	return containsString(c.CDIVersions, storageProfilesVersion)
+}
+
+// DataSources returns whether the infra-cluster serves the CDI DataSources
+func (c Capabilities) DataSources() bool {
+	return containsString(c.CDIVersions, dataSourcesVersion)
+}
+
+// VirtualMachineResource returns the VirtualMachine resource of the kubevirt version the VirtualMachines are
+// managed with
+func (c Capabilities) VirtualMachineResource() schema.GroupVersionResource {
+	return vmResource.GroupResource().WithVersion(c.kubevirtVersion())
+}
+
+// VirtualMachineInstanceResource returns the VirtualMachineInstance resource of the kubevirt version the
+// VirtualMachines are managed with
+func (c Capabilities) VirtualMachineInstanceResource() schema.GroupVersionResource {
+	return vmiResource.GroupResource().WithVersion(c.kubevirtVersion())
+}
+
+// kubevirtVersion returns the version the VirtualMachines are managed with: the version the provider is built with
+// while the infra-cluster serves it, and the preferred version of the infra-cluster otherwise, so the infra-clusters
+// of newer KubeVirt releases which dropped it are managed without a rebuild
+func (c Capabilities) kubevirtVersion() string {
+	if len(c.KubevirtVersions) == 0 || containsString(c.KubevirtVersions, kubevirtapiv1.GroupVersion.Version) {
+		return kubevirtapiv1.GroupVersion.Version
+	}
+	return c.KubevirtVersions[0]
+}
+
+func (c Capabilities) String() string {
+	return fmt.Sprintf("kubevirt versions: %v, cdi versions: %v, pool versions: %v, hotplug: %t, instancetypes: %t, snapshots: %t, live update: %t",
+		c.KubevirtVersions, c.CDIVersions, c.PoolVersions, c.Hotplug, c.Instancetypes, c.Snapshots, c.LiveUpdate)
+}
+
+// discoverCapabilities reads the Capabilities of the infra-cluster from its discovery API, and whether live updates
+// are enabled from the KubeVirt configuration
+func discoverCapabilities(discoveryClient discovery.DiscoveryInterface, dynamicClient dynamic.Interface) (Capabilities, error) {
+	groups, err := discoveryClient.ServerGroups()
+	if err != nil {
+		return Capabilities{}, errors.Wrap(err, "failed to discover the API groups of the infra-cluster")
+	}
+	var subresources *metav1.APIResourceList
+	for _, group := range groups.Groups {
+		if group.Name != kubevirtapiv1.SubresourceGroupName {
+			continue
+		}
+		if subresources, err = discoveryClient.ServerResourcesForGroupVersion(group.PreferredVersion.GroupVersion); err != nil {
+			return Capabilities{}, errors.Wrap(err, "failed to discover the kubevirt subresources of the infra-cluster")
+		}
+	}
+	capabilities := capabilitiesFromDiscovery(groups, subresources)
+	if len(capabilities.KubevirtVersions) > 0 {
+		kubevirts, err := dynamicClient.Resource(kubevirtResource.GroupResource().WithVersion(capabilities.kubevirtVersion())).
+			List(context.Background(), metav1.ListOptions{})
+		if err != nil {
+			// The KubeVirt configuration lives in the namespace of KubeVirt, which the credentials may not read
+			klog.Warningf("failed to read the KubeVirt configuration of the infra-cluster, live updates are disabled: %v", err)
+		} else {
+			capabilities.LiveUpdate = liveUpdateEnabled(kubevirts.Items)
+		}
+	}
+	return capabilities, nil
+}
+
+// liveUpdateEnabled returns whether a KubeVirt configuration of the infra-cluster rolls out the changes of the
+// VirtualMachines live
+func liveUpdateEnabled(kubevirts []unstructured.Unstructured) bool {
+	for _, kubevirt := range kubevirts {
+		strategy, _, _ := unstructured.NestedString(kubevirt.Object, "spec", "configuration", "vmRolloutStrategy")
+		if strategy == liveUpdateRolloutStrategy {
+			return true
+		}
+	}
+	return false
+}
+
+// capabilitiesFromDiscovery builds the Capabilities from the API groups of the infra-cluster, and the resources
+// of the kubevirt subresources group, which is nil if the group isn't served
+func capabilitiesFromDiscovery(groups *metav1.APIGroupList, subresources *metav1.APIResourceList) Capabilities {
+	capabilities := Capabilities{}
+	for _, group := range groups.Groups {
+		switch group.Name {
+		case kubevirtapiv1.GroupName:
+			capabilities.KubevirtVersions = groupVersions(group)
+		case cdiGroupName:
+			capabilities.CDIVersions = groupVersions(group)
+		case instancetypeGroupName:
+			capabilities.Instancetypes = true
+		case snapshotGroupName:
+			capabilities.Snapshots = true
+		case poolGroupName:
+			capabilities.PoolVersions = groupVersions(group)
+		}
+	}
+	if subresources != nil {
+		for _, resource := range subresources.APIResources {
+			if resource.Name == hotplugVolumeResource {
+				capabilities.Hotplug = true
+			}
+		}
+	}
+	return capabilities
+}
+
+func groupVersions(group metav1.APIGroup) []string {
+	versions := make([]string, 0, len(group.Versions))
+	for _, version := range group.Versions {
+		versions = append(versions, version.Version)
+	}
+	return versions
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
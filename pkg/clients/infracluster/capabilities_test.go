@@ -0,0 +1,122 @@
+package infracluster
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestCapabilitiesFromDiscovery(t *testing.T) {
+	group := func(name string, versions ...string) metav1.APIGroup {
+		apiGroup := metav1.APIGroup{Name: name}
+		for _, version := range versions {
+			apiGroup.Versions = append(apiGroup.Versions, metav1.GroupVersionForDiscovery{GroupVersion: name + "/" + version, Version: version})
+		}
+		return apiGroup
+	}
+
+	cases := []struct {
+		name                    string
+		groups                  []metav1.APIGroup
+		subresources            *metav1.APIResourceList
+		expected                Capabilities
+		expectedStorageProfiles bool
+		expectedDataSources     bool
+	}{
+		{
+			name:   "no kubevirt",
+			groups: []metav1.APIGroup{group("apps", "v1")},
+		},
+		{
+			name:   "old kubevirt and cdi",
+			groups: []metav1.APIGroup{group("kubevirt.io", "v1alpha3"), group("cdi.kubevirt.io", "v1alpha1")},
+			subresources: &metav1.APIResourceList{APIResources: []metav1.APIResource{
+				{Name: "virtualmachineinstances/console"},
+			}},
+			expected: Capabilities{KubevirtVersions: []string{"v1alpha3"}, CDIVersions: []string{"v1alpha1"}},
+		},
+		{
+			name: "all the capabilities",
+			groups: []metav1.APIGroup{
+				group("kubevirt.io", "v1", "v1alpha3"),
+				group("cdi.kubevirt.io", "v1beta1", "v1alpha1"),
+				group("instancetype.kubevirt.io", "v1beta1"),
+				group("snapshot.kubevirt.io", "v1alpha1"),
+				group("pool.kubevirt.io", "v1alpha1"),
+			},
+			subresources: &metav1.APIResourceList{APIResources: []metav1.APIResource{
+				{Name: "virtualmachineinstances/console"},
+				{Name: "virtualmachines/addvolume"},
+			}},
+			expected: Capabilities{
+				KubevirtVersions: []string{"v1", "v1alpha3"},
+				CDIVersions:      []string{"v1beta1", "v1alpha1"},
+				Hotplug:          true,
+				Instancetypes:    true,
+				Snapshots:        true,
+				PoolVersions:     []string{"v1alpha1"},
+			},
+			expectedStorageProfiles: true,
+			expectedDataSources:     true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			capabilities := capabilitiesFromDiscovery(&metav1.APIGroupList{Groups: tc.groups}, tc.subresources)
+			assert.DeepEqual(t, capabilities, tc.expected)
+			assert.Equal(t, capabilities.StorageProfiles(), tc.expectedStorageProfiles)
+			assert.Equal(t, capabilities.DataSources(), tc.expectedDataSources)
+		})
+	}
+}
+
+func TestVirtualMachineResource(t *testing.T) {
+	cases := []struct {
+		name             string
+		kubevirtVersions []string
+		expectedVersion  string
+	}{
+		{
+			name:            "kubevirt not discovered",
+			expectedVersion: "v1alpha3",
+		},
+		{
+			name:             "built version served",
+			kubevirtVersions: []string{"v1", "v1alpha3"},
+			expectedVersion:  "v1alpha3",
+		},
+		{
+			name:             "built version dropped",
+			kubevirtVersions: []string{"v2", "v1"},
+			expectedVersion:  "v2",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			capabilities := Capabilities{KubevirtVersions: tc.kubevirtVersions}
+			assert.Equal(t, capabilities.VirtualMachineResource(), schema.GroupVersionResource{
+				Group: "kubevirt.io", Version: tc.expectedVersion, Resource: "virtualmachines",
+			})
+			assert.Equal(t, capabilities.VirtualMachineInstanceResource(), schema.GroupVersionResource{
+				Group: "kubevirt.io", Version: tc.expectedVersion, Resource: "virtualmachineinstances",
+			})
+		})
+	}
+}
+
+func TestLiveUpdateEnabled(t *testing.T) {
+	kubevirt := func(configuration map[string]interface{}) unstructured.Unstructured {
+		return unstructured.Unstructured{Object: map[string]interface{}{
+			"spec": map[string]interface{}{"configuration": configuration},
+		}}
+	}
+
+	assert.Assert(t, !liveUpdateEnabled(nil))
+	assert.Assert(t, !liveUpdateEnabled([]unstructured.Unstructured{kubevirt(map[string]interface{}{"vmRolloutStrategy": "Stage"})}))
+	assert.Assert(t, liveUpdateEnabled([]unstructured.Unstructured{kubevirt(map[string]interface{}{"vmRolloutStrategy": "LiveUpdate"})}))
+}
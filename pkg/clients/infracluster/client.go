@@ -14,24 +14,37 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
+// infracluster package wraps the clients of the infra-cluster, in which the VirtualMachines are created.
+// External consumers can build the Client with NewFromKubeconfig or NewFromConfig, without the
+// credentials secret which New reads from the tenant-cluster.
 package infracluster
 
 import (
 	"context"
+	"net/http"
+	"time"
 
 	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster"
 	machineapiapierrors "github.com/openshift/machine-api-operator/pkg/controller/machine"
 	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	apimachineryerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog"
 	kubevirtapiv1 "kubevirt.io/client-go/api/v1"
+	cdiv1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
 )
 
 //go:generate mockgen -source=./client.go -destination=./mock/client_generated.go -package=mock
@@ -41,6 +54,8 @@ const (
 	platformCredentialsKey                  = "kubeconfig"
 	defaultCredentialsSecretSecretName      = "kubevirt-credentials"
 	defaultCredentialsSecretSecretNamespace = "openshift-machine-api"
+	// defaultStorageClassAnnotation marks the storage class used by PVCs which don't name one
+	defaultStorageClassAnnotation = "storageclass.kubernetes.io/is-default-class"
 )
 
 // Client is a wrapper object for actual infra-cluster clients: kubernetes and the kubevirt
@@ -50,10 +65,56 @@ type Client interface {
 	GetVirtualMachine(ctx context.Context, namespace string, name string, options *metav1.GetOptions) (*kubevirtapiv1.VirtualMachine, error)
 	GetVirtualMachineInstance(ctx context.Context, namespace string, name string, options *metav1.GetOptions) (*kubevirtapiv1.VirtualMachineInstance, error)
 	ListVirtualMachine(ctx context.Context, namespace string, options metav1.ListOptions) (*kubevirtapiv1.VirtualMachineList, error)
+	// Watch watches a resource of the infra-cluster, with unstructured objects
+	Watch(ctx context.Context, resource schema.GroupVersionResource, namespace string, options metav1.ListOptions) (watch.Interface, error)
+	// NewInformer returns an informer of a resource of the infra-cluster filtered by namespace and labels, to be started
+	NewInformer(resource schema.GroupVersionResource, namespace string, selector labels.Selector, resyncPeriod time.Duration) cache.SharedIndexInformer
+	ListVirtualMachineInstance(ctx context.Context, namespace string, options metav1.ListOptions) (*kubevirtapiv1.VirtualMachineInstanceList, error)
 	UpdateVirtualMachine(ctx context.Context, namespace string, vm *kubevirtapiv1.VirtualMachine) (*kubevirtapiv1.VirtualMachine, error)
 	CreateSecret(ctx context.Context, namespace string, newSecret *corev1.Secret) (*corev1.Secret, error)
+	GetSecret(ctx context.Context, namespace string, name string) (*corev1.Secret, error)
+	// ListSecrets lists the secrets of the namespace, e.g. with the label selector of the secrets of the tenant-cluster
+	ListSecrets(ctx context.Context, namespace string, options metav1.ListOptions) (*corev1.SecretList, error)
+	UpdateSecret(ctx context.Context, namespace string, secret *corev1.Secret) (*corev1.Secret, error)
+	DeleteSecret(ctx context.Context, namespace string, name string) error
+	ListNodes(ctx context.Context, options metav1.ListOptions) (*corev1.NodeList, error)
+	GetStorageClassAccessModes(ctx context.Context, storageClassName string) (string, []corev1.PersistentVolumeAccessMode, error)
+	GetCapabilities() Capabilities
+	CreateNamespace(ctx context.Context, newNamespace *corev1.Namespace) (*corev1.Namespace, error)
+	CreateResourceQuota(ctx context.Context, namespace string, newResourceQuota *corev1.ResourceQuota) (*corev1.ResourceQuota, error)
+	CreateNetworkPolicy(ctx context.Context, namespace string, newNetworkPolicy *networkingv1.NetworkPolicy) (*networkingv1.NetworkPolicy, error)
+	GetNetworkPolicy(ctx context.Context, namespace string, name string) (*networkingv1.NetworkPolicy, error)
+	UpdateNetworkPolicy(ctx context.Context, namespace string, networkPolicy *networkingv1.NetworkPolicy) (*networkingv1.NetworkPolicy, error)
+	ListResourceQuotas(ctx context.Context, namespace string) (*corev1.ResourceQuotaList, error)
+	GetPersistentVolumeClaim(ctx context.Context, namespace string, name string) (*corev1.PersistentVolumeClaim, error)
+	// GetDataSourcePVC returns the source PVC the CDI DataSource currently points to
+	GetDataSourcePVC(ctx context.Context, namespace string, name string) (*cdiv1.DataVolumeSourcePVC, error)
+	ListPodMetrics(ctx context.Context, namespace string, options metav1.ListOptions) ([]PodMetrics, error)
+	ListPods(ctx context.Context, namespace string, options metav1.ListOptions) (*corev1.PodList, error)
+	GetPod(ctx context.Context, namespace string, name string) (*corev1.Pod, error)
+	CreatePod(ctx context.Context, namespace string, newPod *corev1.Pod) (*corev1.Pod, error)
+	DeletePod(ctx context.Context, namespace string, name string) error
+	GetConfigMap(ctx context.Context, namespace string, name string) (*corev1.ConfigMap, error)
+	CreateConfigMap(ctx context.Context, namespace string, newConfigMap *corev1.ConfigMap) (*corev1.ConfigMap, error)
+	DeleteConfigMap(ctx context.Context, namespace string, name string) error
+	CreateJob(ctx context.Context, namespace string, newJob *batchv1.Job) (*batchv1.Job, error)
+	// DeleteJob deletes the Job together with its pods
+	DeleteJob(ctx context.Context, namespace string, name string) error
+	ListEvents(ctx context.Context, namespace string, options metav1.ListOptions) (*corev1.EventList, error)
+	GetDataVolume(ctx context.Context, namespace string, name string) (*unstructured.Unstructured, error)
+	ListDataVolumes(ctx context.Context, namespace string, options metav1.ListOptions) ([]cdiv1.DataVolume, error)
+	CreateDataVolume(ctx context.Context, namespace string, newDataVolume *cdiv1.DataVolume) (*cdiv1.DataVolume, error)
+	UpdateDataVolume(ctx context.Context, namespace string, dataVolume *cdiv1.DataVolume) (*cdiv1.DataVolume, error)
+	DeleteDataVolume(ctx context.Context, namespace string, name string) error
+	// UploadDataVolume uploads the image to the DataVolume with an upload source, once it is UploadReady, through
+	// the CDI upload proxy of the infra-cluster
+	UploadDataVolume(ctx context.Context, namespace string, name string, image []byte) error
+	GetVirtualMachinePool(ctx context.Context, namespace string, name string) (*unstructured.Unstructured, error)
+	UpdateVirtualMachinePool(ctx context.Context, namespace string, pool *unstructured.Unstructured) (*unstructured.Unstructured, error)
 }
 
+// The VirtualMachine resources of the version the provider is built with, the client manages the VirtualMachines
+// with the resources of the version discovered in the infra-cluster instead, see Capabilities.VirtualMachineResource
 var (
 	vmResource = schema.GroupVersionResource{
 		Group:    kubevirtapiv1.GroupVersion.Group,
@@ -65,36 +126,93 @@ var (
 		Version:  kubevirtapiv1.GroupVersion.Version,
 		Resource: "virtualmachineinstances",
 	}
+	storageProfileResource = schema.GroupVersionResource{
+		Group:    cdiGroupName,
+		Version:  storageProfilesVersion,
+		Resource: "storageprofiles",
+	}
+	dataSourceResource = schema.GroupVersionResource{
+		Group:    cdiGroupName,
+		Version:  dataSourcesVersion,
+		Resource: "datasources",
+	}
 )
 
 type client struct {
-	kubernetesClient *kubernetes.Clientset
+	kubernetesClient kubernetes.Interface
 	dynamicClient    dynamic.Interface
+	capabilities     Capabilities
+	uploadClient     *http.Client
+	dataVolumes      DataVolumeOptions
 }
 
-// New creates our client wrapper object for the actual kubeVirt and kubernetes clients we use.
-func New(ctx context.Context, tenantClusterKubernetesClient tenantcluster.Client) (Client, error) {
-	returnedSecret, err := tenantClusterKubernetesClient.GetSecret(ctx, defaultCredentialsSecretSecretName, defaultCredentialsSecretSecretNamespace)
+// New creates our client wrapper object for the actual kubeVirt and kubernetes clients we use,
+// from the infra-cluster kubeconfig saved in the credentials secret of the tenant-cluster. Its requests are
+// wrapped with the resilience options, and the credential plugins of the kubeconfig verified against the auth options.
+func New(ctx context.Context, tenantClusterKubernetesClient tenantcluster.Client, resilience ResilienceOptions, auth AuthOptions,
+	dataVolumes DataVolumeOptions) (Client, error) {
+	secretNamespace, secretName := auth.credentialsSecret()
+	returnedSecret, err := getCredentialsSecret(ctx, tenantClusterKubernetesClient, secretNamespace, secretName)
+	if err != nil {
+		return nil, err
+	}
+	return newFromCredentialsSecret(returnedSecret, resilience, auth, dataVolumes)
+}
+
+// getCredentialsSecret returns the credentials secret from the tenant-cluster, or an InvalidMachineConfiguration
+// error when it doesn't exist
+func getCredentialsSecret(ctx context.Context, tenantClusterKubernetesClient tenantcluster.Client, secretNamespace string, secretName string) (*corev1.Secret, error) {
+	returnedSecret, err := tenantClusterKubernetesClient.GetSecret(ctx, secretName, secretNamespace)
 	if err != nil {
 		if apimachineryerrors.IsNotFound(err) {
-			return nil, machineapiapierrors.InvalidMachineConfiguration("Infra-cluster credentials secret %s/%s: %v not found", defaultCredentialsSecretSecretNamespace, defaultCredentialsSecretSecretName, err)
+			return nil, machineapiapierrors.InvalidMachineConfiguration("Infra-cluster credentials secret %s/%s: %v not found", secretNamespace, secretName, err)
 		}
 		return nil, err
 	}
-	platformCredentials, ok := returnedSecret.Data[platformCredentialsKey]
+	return returnedSecret, nil
+}
+
+// newFromCredentialsSecret creates the client wrapper object from the kubeconfig of a credentials secret
+func newFromCredentialsSecret(secret *corev1.Secret, resilience ResilienceOptions, auth AuthOptions, dataVolumes DataVolumeOptions) (Client, error) {
+	platformCredentials, ok := secret.Data[platformCredentialsKey]
 	if !ok {
 		return nil, machineapiapierrors.InvalidMachineConfiguration("Infra-cluster credentials secret %v did not contain key %v",
-			defaultCredentialsSecretSecretName, platformCredentials)
+			secret.Name, platformCredentialsKey)
 	}
 
-	clientConfig, err := clientcmd.NewClientConfigFromBytes(platformCredentials)
+	restClientConfig, err := restConfigFromKubeconfig(platformCredentials)
 	if err != nil {
 		return nil, err
 	}
-	restClientConfig, err := clientConfig.ClientConfig()
+	if err := verifyAuth(restClientConfig, auth); err != nil {
+		return nil, err
+	}
+	return newFromConfig(WithResilience(restClientConfig, resilience), dataVolumes)
+}
+
+// NewFromKubeconfig creates the client wrapper object from the content of an infra-cluster kubeconfig
+func NewFromKubeconfig(kubeconfig []byte) (Client, error) {
+	restClientConfig, err := restConfigFromKubeconfig(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	return NewFromConfig(restClientConfig)
+}
+
+func restConfigFromKubeconfig(kubeconfig []byte) (*rest.Config, error) {
+	clientConfig, err := clientcmd.NewClientConfigFromBytes(kubeconfig)
 	if err != nil {
 		return nil, err
 	}
+	return clientConfig.ClientConfig()
+}
+
+// NewFromConfig creates the client wrapper object from an infra-cluster rest config
+func NewFromConfig(restClientConfig *rest.Config) (Client, error) {
+	return newFromConfig(restClientConfig, DataVolumeOptions{})
+}
+
+func newFromConfig(restClientConfig *rest.Config, dataVolumes DataVolumeOptions) (Client, error) {
 	kubernetesClient, err := kubernetes.NewForConfig(restClientConfig)
 	if err != nil {
 		return nil, err
@@ -103,55 +221,85 @@ func New(ctx context.Context, tenantClusterKubernetesClient tenantcluster.Client
 	if err != nil {
 		return nil, err
 	}
+	capabilities, err := discoverCapabilities(kubernetesClient.Discovery(), dynamicClient)
+	if err != nil {
+		return nil, err
+	}
+	uploadClient, err := newUploadClient(restClientConfig)
+	if err != nil {
+		return nil, err
+	}
+	klog.Infof("infra-cluster capabilities: %v", capabilities)
+	if len(capabilities.KubevirtVersions) == 0 {
+		klog.Warningf("infra-cluster doesn't serve %s, which the VirtualMachines are managed with", kubevirtapiv1.GroupName)
+	} else if resource := capabilities.VirtualMachineResource(); resource != vmResource {
+		klog.Warningf("infra-cluster doesn't serve %s, the VirtualMachines are managed with %s", vmResource.GroupVersion(), resource.GroupVersion())
+	}
 	return &client{
 		kubernetesClient: kubernetesClient,
 		dynamicClient:    dynamicClient,
+		capabilities:     capabilities,
+		uploadClient:     uploadClient,
+		dataVolumes:      dataVolumes,
 	}, nil
 }
 
+// GetCapabilities returns the Capabilities of the infra-cluster, discovered when the Client was built
+func (c *client) GetCapabilities() Capabilities {
+	return c.capabilities
+}
+
 func (c *client) CreateVirtualMachine(ctx context.Context, namespace string, newVM *kubevirtapiv1.VirtualMachine) (*kubevirtapiv1.VirtualMachine, error) {
-	if err := c.createResource(ctx, newVM, namespace, vmResource); err != nil {
+	resource := c.capabilities.VirtualMachineResource()
+	newVM.APIVersion = resource.GroupVersion().String()
+	if err := c.createResource(ctx, newVM, namespace, resource); err != nil {
 		return nil, err
 	}
 	return newVM, nil
 }
 
 func (c *client) DeleteVirtualMachine(ctx context.Context, namespace string, name string, options *metav1.DeleteOptions) error {
-	return c.deleteResource(ctx, namespace, name, vmResource, options)
+	return c.deleteResource(ctx, namespace, name, c.capabilities.VirtualMachineResource(), options)
 }
 
 func (c *client) GetVirtualMachine(ctx context.Context, namespace string, name string, options *metav1.GetOptions) (*kubevirtapiv1.VirtualMachine, error) {
-	resp, err := c.getResource(ctx, namespace, name, vmResource, options)
+	resp, err := c.getResource(ctx, namespace, name, c.capabilities.VirtualMachineResource(), options)
 	if err != nil {
 		if apimachineryerrors.IsNotFound(err) {
 			return nil, err
 		}
 		return nil, errors.Wrap(err, "failed to get VirtualMachine")
 	}
+	fromStorageAPI("virtualmachines", resp.Object)
 	var vm kubevirtapiv1.VirtualMachine
 	err = c.fromUnstructedToInterface(*resp, &vm, "VirtualMachine")
 	return &vm, err
 }
 
 func (c *client) ListVirtualMachine(ctx context.Context, namespace string, options metav1.ListOptions) (*kubevirtapiv1.VirtualMachineList, error) {
-	resp, err := c.listResource(ctx, namespace, vmResource, options)
+	resp, err := c.listResource(ctx, namespace, c.capabilities.VirtualMachineResource(), options)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to list VirtualMachine")
 	}
+	for i := range resp.Items {
+		fromStorageAPI("virtualmachines", resp.Items[i].Object)
+	}
 	var vmList kubevirtapiv1.VirtualMachineList
 	err = c.fromUnstructedListToInterface(*resp, &vmList, "VirtualMachineList")
 	return &vmList, err
 }
 
 func (c *client) UpdateVirtualMachine(ctx context.Context, namespace string, vm *kubevirtapiv1.VirtualMachine) (*kubevirtapiv1.VirtualMachine, error) {
-	if err := c.updateResource(ctx, namespace, vm.Name, vmResource, vm); err != nil {
+	resource := c.capabilities.VirtualMachineResource()
+	vm.APIVersion = resource.GroupVersion().String()
+	if err := c.updateResource(ctx, namespace, vm.Name, resource, vm); err != nil {
 		return nil, err
 	}
 	return vm, nil
 }
 
 func (c *client) GetVirtualMachineInstance(ctx context.Context, namespace string, name string, options *metav1.GetOptions) (*kubevirtapiv1.VirtualMachineInstance, error) {
-	resp, err := c.getResource(ctx, namespace, name, vmiResource, options)
+	resp, err := c.getResource(ctx, namespace, name, c.capabilities.VirtualMachineInstanceResource(), options)
 	if err != nil {
 		if apimachineryerrors.IsNotFound(err) {
 			return nil, err
@@ -163,15 +311,302 @@ func (c *client) GetVirtualMachineInstance(ctx context.Context, namespace string
 	return &vmi, err
 }
 
+func (c *client) ListVirtualMachineInstance(ctx context.Context, namespace string, options metav1.ListOptions) (*kubevirtapiv1.VirtualMachineInstanceList, error) {
+	resp, err := c.listResource(ctx, namespace, c.capabilities.VirtualMachineInstanceResource(), options)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list VirtualMachineInstance")
+	}
+	var vmiList kubevirtapiv1.VirtualMachineInstanceList
+	err = c.fromUnstructedListToInterface(*resp, &vmiList, "VirtualMachineInstanceList")
+	return &vmiList, err
+}
+
 func (c *client) CreateSecret(ctx context.Context, namespace string, newSecret *corev1.Secret) (*corev1.Secret, error) {
 	return c.kubernetesClient.CoreV1().Secrets(namespace).Create(ctx, newSecret, metav1.CreateOptions{})
 }
 
+func (c *client) GetSecret(ctx context.Context, namespace string, name string) (*corev1.Secret, error) {
+	return c.kubernetesClient.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
+func (c *client) ListSecrets(ctx context.Context, namespace string, options metav1.ListOptions) (*corev1.SecretList, error) {
+	return c.kubernetesClient.CoreV1().Secrets(namespace).List(ctx, options)
+}
+
+func (c *client) UpdateSecret(ctx context.Context, namespace string, secret *corev1.Secret) (*corev1.Secret, error) {
+	return c.kubernetesClient.CoreV1().Secrets(namespace).Update(ctx, secret, metav1.UpdateOptions{})
+}
+
+func (c *client) DeleteSecret(ctx context.Context, namespace string, name string) error {
+	return c.kubernetesClient.CoreV1().Secrets(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+}
+
+func (c *client) CreateNamespace(ctx context.Context, newNamespace *corev1.Namespace) (*corev1.Namespace, error) {
+	return c.kubernetesClient.CoreV1().Namespaces().Create(ctx, newNamespace, metav1.CreateOptions{})
+}
+
+func (c *client) CreateResourceQuota(ctx context.Context, namespace string, newResourceQuota *corev1.ResourceQuota) (*corev1.ResourceQuota, error) {
+	return c.kubernetesClient.CoreV1().ResourceQuotas(namespace).Create(ctx, newResourceQuota, metav1.CreateOptions{})
+}
+
+func (c *client) CreateNetworkPolicy(ctx context.Context, namespace string, newNetworkPolicy *networkingv1.NetworkPolicy) (*networkingv1.NetworkPolicy, error) {
+	return c.kubernetesClient.NetworkingV1().NetworkPolicies(namespace).Create(ctx, newNetworkPolicy, metav1.CreateOptions{})
+}
+
+func (c *client) GetNetworkPolicy(ctx context.Context, namespace string, name string) (*networkingv1.NetworkPolicy, error) {
+	return c.kubernetesClient.NetworkingV1().NetworkPolicies(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
+func (c *client) UpdateNetworkPolicy(ctx context.Context, namespace string, networkPolicy *networkingv1.NetworkPolicy) (*networkingv1.NetworkPolicy, error) {
+	return c.kubernetesClient.NetworkingV1().NetworkPolicies(namespace).Update(ctx, networkPolicy, metav1.UpdateOptions{})
+}
+
+func (c *client) ListResourceQuotas(ctx context.Context, namespace string) (*corev1.ResourceQuotaList, error) {
+	return c.kubernetesClient.CoreV1().ResourceQuotas(namespace).List(ctx, metav1.ListOptions{})
+}
+
+func (c *client) GetPersistentVolumeClaim(ctx context.Context, namespace string, name string) (*corev1.PersistentVolumeClaim, error) {
+	return c.kubernetesClient.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
+func (c *client) ListPods(ctx context.Context, namespace string, options metav1.ListOptions) (*corev1.PodList, error) {
+	return c.kubernetesClient.CoreV1().Pods(namespace).List(ctx, options)
+}
+
+func (c *client) GetPod(ctx context.Context, namespace string, name string) (*corev1.Pod, error) {
+	return c.kubernetesClient.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
+func (c *client) CreatePod(ctx context.Context, namespace string, newPod *corev1.Pod) (*corev1.Pod, error) {
+	return c.kubernetesClient.CoreV1().Pods(namespace).Create(ctx, newPod, metav1.CreateOptions{})
+}
+
+func (c *client) DeletePod(ctx context.Context, namespace string, name string) error {
+	return c.kubernetesClient.CoreV1().Pods(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+}
+
+func (c *client) GetConfigMap(ctx context.Context, namespace string, name string) (*corev1.ConfigMap, error) {
+	return c.kubernetesClient.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
+func (c *client) CreateConfigMap(ctx context.Context, namespace string, newConfigMap *corev1.ConfigMap) (*corev1.ConfigMap, error) {
+	return c.kubernetesClient.CoreV1().ConfigMaps(namespace).Create(ctx, newConfigMap, metav1.CreateOptions{})
+}
+
+func (c *client) DeleteConfigMap(ctx context.Context, namespace string, name string) error {
+	return c.kubernetesClient.CoreV1().ConfigMaps(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+}
+
+func (c *client) CreateJob(ctx context.Context, namespace string, newJob *batchv1.Job) (*batchv1.Job, error) {
+	return c.kubernetesClient.BatchV1().Jobs(namespace).Create(ctx, newJob, metav1.CreateOptions{})
+}
+
+func (c *client) DeleteJob(ctx context.Context, namespace string, name string) error {
+	propagationPolicy := metav1.DeletePropagationBackground
+	return c.kubernetesClient.BatchV1().Jobs(namespace).Delete(ctx, name, metav1.DeleteOptions{PropagationPolicy: &propagationPolicy})
+}
+
+func (c *client) ListEvents(ctx context.Context, namespace string, options metav1.ListOptions) (*corev1.EventList, error) {
+	return c.kubernetesClient.CoreV1().Events(namespace).List(ctx, options)
+}
+
+// GetDataVolume returns the DataVolume as served by the preferred CDI version of the infra-cluster, with its
+// spec.storage moved to spec.pvc like the DataVolumes the provider builds
+func (c *client) GetDataVolume(ctx context.Context, namespace string, name string) (*unstructured.Unstructured, error) {
+	dataVolumeResource, err := c.dataVolumeResource()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.getResource(ctx, namespace, name, dataVolumeResource, &metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	fromStorageAPI("datavolumes", resp.Object)
+	return resp, nil
+}
+
+func (c *client) ListDataVolumes(ctx context.Context, namespace string, options metav1.ListOptions) ([]cdiv1.DataVolume, error) {
+	dataVolumeResource, err := c.dataVolumeResource()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.listResource(ctx, namespace, dataVolumeResource, options)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list DataVolumes")
+	}
+	for i := range resp.Items {
+		fromStorageAPI("datavolumes", resp.Items[i].Object)
+	}
+	var dataVolumeList cdiv1.DataVolumeList
+	err = c.fromUnstructedListToInterface(*resp, &dataVolumeList, "DataVolumeList")
+	return dataVolumeList.Items, err
+}
+
+func (c *client) CreateDataVolume(ctx context.Context, namespace string, newDataVolume *cdiv1.DataVolume) (*cdiv1.DataVolume, error) {
+	dataVolumeResource, err := c.dataVolumeResource()
+	if err != nil {
+		return nil, err
+	}
+	newDataVolume.APIVersion = dataVolumeResource.GroupVersion().String()
+	if err := c.createResource(ctx, newDataVolume, namespace, dataVolumeResource); err != nil {
+		return nil, err
+	}
+	return newDataVolume, nil
+}
+
+func (c *client) UpdateDataVolume(ctx context.Context, namespace string, dataVolume *cdiv1.DataVolume) (*cdiv1.DataVolume, error) {
+	dataVolumeResource, err := c.dataVolumeResource()
+	if err != nil {
+		return nil, err
+	}
+	dataVolume.APIVersion = dataVolumeResource.GroupVersion().String()
+	if err := c.updateResource(ctx, namespace, dataVolume.Name, dataVolumeResource, dataVolume); err != nil {
+		return nil, err
+	}
+	return dataVolume, nil
+}
+
+func (c *client) DeleteDataVolume(ctx context.Context, namespace string, name string) error {
+	dataVolumeResource, err := c.dataVolumeResource()
+	if err != nil {
+		return err
+	}
+	return c.deleteResource(ctx, namespace, name, dataVolumeResource, &metav1.DeleteOptions{})
+}
+
+// dataVolumeResource returns the DataVolume resource of the preferred version of CDI served by the infra-cluster
+func (c *client) dataVolumeResource() (schema.GroupVersionResource, error) {
+	if len(c.capabilities.CDIVersions) == 0 {
+		return schema.GroupVersionResource{}, errors.Errorf("infra-cluster doesn't serve %s", cdiGroupName)
+	}
+	return schema.GroupVersionResource{
+		Group:    cdiGroupName,
+		Version:  c.capabilities.CDIVersions[0],
+		Resource: "datavolumes",
+	}, nil
+}
+
+// GetVirtualMachinePool returns the VirtualMachinePool as served by the preferred pool version of the infra-cluster
+func (c *client) GetVirtualMachinePool(ctx context.Context, namespace string, name string) (*unstructured.Unstructured, error) {
+	poolResource, err := c.virtualMachinePoolResource()
+	if err != nil {
+		return nil, err
+	}
+	return c.getResource(ctx, namespace, name, poolResource, &metav1.GetOptions{})
+}
+
+func (c *client) UpdateVirtualMachinePool(ctx context.Context, namespace string, pool *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	poolResource, err := c.virtualMachinePoolResource()
+	if err != nil {
+		return nil, err
+	}
+	return c.dynamicClient.Resource(poolResource).Namespace(namespace).Update(ctx, pool, metav1.UpdateOptions{})
+}
+
+// virtualMachinePoolResource returns the VirtualMachinePool resource of the preferred pool version served by the infra-cluster
+func (c *client) GetDataSourcePVC(ctx context.Context, namespace string, name string) (*cdiv1.DataVolumeSourcePVC, error) {
+	if !c.capabilities.DataSources() {
+		return nil, errors.Errorf("infra-cluster doesn't serve %s/%s DataSources", cdiGroupName, dataSourcesVersion)
+	}
+	dataSource, err := c.getResource(ctx, namespace, name, dataSourceResource, &metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	pvcName, _, err := unstructured.NestedString(dataSource.Object, "spec", "source", "pvc", "name")
+	if err != nil {
+		return nil, err
+	}
+	if pvcName == "" {
+		return nil, errors.Errorf("DataSource %s/%s doesn't point to a PVC", namespace, name)
+	}
+	pvcNamespace, _, err := unstructured.NestedString(dataSource.Object, "spec", "source", "pvc", "namespace")
+	if err != nil {
+		return nil, err
+	}
+	if pvcNamespace == "" {
+		pvcNamespace = namespace
+	}
+	return &cdiv1.DataVolumeSourcePVC{Name: pvcName, Namespace: pvcNamespace}, nil
+}
+
+func (c *client) virtualMachinePoolResource() (schema.GroupVersionResource, error) {
+	if len(c.capabilities.PoolVersions) == 0 {
+		return schema.GroupVersionResource{}, errors.Errorf("infra-cluster doesn't serve %s", poolGroupName)
+	}
+	return schema.GroupVersionResource{
+		Group:    poolGroupName,
+		Version:  c.capabilities.PoolVersions[0],
+		Resource: "virtualmachinepools",
+	}, nil
+}
+
+func (c *client) ListNodes(ctx context.Context, options metav1.ListOptions) (*corev1.NodeList, error) {
+	return c.kubernetesClient.CoreV1().Nodes().List(ctx, options)
+}
+
+// GetStorageClassAccessModes returns the access modes the CDI StorageProfile of the storage class recommends,
+// together with the name of the storage class, which is the default storage class when storageClassName is empty.
+// No access modes are returned when the storage class or its StorageProfile can't be found.
+func (c *client) GetStorageClassAccessModes(ctx context.Context, storageClassName string) (string, []corev1.PersistentVolumeAccessMode, error) {
+	if storageClassName == "" {
+		storageClasses, err := c.kubernetesClient.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return "", nil, errors.Wrap(err, "failed to list StorageClass")
+		}
+		for _, storageClass := range storageClasses.Items {
+			if storageClass.Annotations[defaultStorageClassAnnotation] == "true" {
+				storageClassName = storageClass.Name
+				break
+			}
+		}
+		if storageClassName == "" {
+			return "", nil, nil
+		}
+	}
+
+	if !c.capabilities.StorageProfiles() {
+		klog.Infof("infra-cluster doesn't serve %s/%s StorageProfiles, the access modes of storage class %s are unknown",
+			cdiGroupName, storageProfilesVersion, storageClassName)
+		return storageClassName, nil, nil
+	}
+
+	// The StorageProfile is a cluster scoped resource, named as its storage class
+	resp, err := c.dynamicClient.Resource(storageProfileResource).Get(ctx, storageClassName, metav1.GetOptions{})
+	if err != nil {
+		if apimachineryerrors.IsNotFound(err) {
+			return storageClassName, nil, nil
+		}
+		return storageClassName, nil, errors.Wrap(err, "failed to get StorageProfile")
+	}
+	claimPropertySets, _, err := unstructured.NestedSlice(resp.Object, "status", "claimPropertySets")
+	if err != nil {
+		return storageClassName, nil, errors.Wrap(err, "failed to read the claimPropertySets of StorageProfile")
+	}
+	var accessModes []corev1.PersistentVolumeAccessMode
+	for _, claimPropertySet := range claimPropertySets {
+		propertySet, ok := claimPropertySet.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		modes, _, err := unstructured.NestedStringSlice(propertySet, "accessModes")
+		if err != nil {
+			return storageClassName, nil, errors.Wrap(err, "failed to read the accessModes of StorageProfile")
+		}
+		for _, mode := range modes {
+			accessModes = append(accessModes, corev1.PersistentVolumeAccessMode(mode))
+		}
+	}
+	return storageClassName, accessModes, nil
+}
+
 func (c *client) createResource(ctx context.Context, obj interface{}, namespace string, resource schema.GroupVersionResource) error {
 	resultMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
 	if err != nil {
 		return errors.Wrapf(err, "failed to translate %s to Unstructed (for create operation)", resource.Resource)
 	}
+	if c.storageAPI() {
+		toStorageAPI(resource.Resource, resultMap)
+	}
 	input := unstructured.Unstructured{}
 	input.SetUnstructuredContent(resultMap)
 	resp, err := c.dynamicClient.Resource(resource).Namespace(namespace).Create(ctx, &input, metav1.CreateOptions{})
@@ -179,6 +614,7 @@ func (c *client) createResource(ctx context.Context, obj interface{}, namespace
 		return errors.Wrapf(err, "failed to create %s", resource.Resource)
 	}
 	unstructured := resp.UnstructuredContent()
+	fromStorageAPI(resource.Resource, unstructured)
 	return runtime.DefaultUnstructuredConverter.FromUnstructured(unstructured, obj)
 }
 
@@ -199,6 +635,9 @@ func (c *client) updateResource(ctx context.Context, namespace string, name stri
 	if err != nil {
 		return errors.Wrapf(err, "failed to translate %s to Unstructed (for create operation)", resource.Resource)
 	}
+	if c.storageAPI() {
+		toStorageAPI(resource.Resource, resultMap)
+	}
 	input := unstructured.Unstructured{}
 	input.SetUnstructuredContent(resultMap)
 	resp, err := c.dynamicClient.Resource(resource).Namespace(namespace).Update(ctx, &input, metav1.UpdateOptions{})
@@ -206,6 +645,7 @@ func (c *client) updateResource(ctx context.Context, namespace string, name stri
 		return err
 	}
 	unstructured := resp.UnstructuredContent()
+	fromStorageAPI(resource.Resource, unstructured)
 	return runtime.DefaultUnstructuredConverter.FromUnstructured(unstructured, obj)
 }
 
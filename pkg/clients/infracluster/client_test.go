@@ -0,0 +1,159 @@
+package infracluster
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/testutils"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	apimachineryerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	fakecorev1 "k8s.io/client-go/kubernetes/typed/core/v1/fake"
+	clienttesting "k8s.io/client-go/testing"
+	kubevirtapiv1 "kubevirt.io/client-go/api/v1"
+)
+
+const testNamespace = "test-namespace"
+
+// fakeClientset serves the core API of the kubernetes clientset from an object tracker
+type fakeClientset struct {
+	kubernetes.Interface
+	coreV1 *fakecorev1.FakeCoreV1
+}
+
+func (f *fakeClientset) CoreV1() typedcorev1.CoreV1Interface {
+	return f.coreV1
+}
+
+func newFakeClient(t *testing.T, objects ...runtime.Object) *client {
+	tracker := clienttesting.NewObjectTracker(scheme.Scheme, scheme.Codecs.UniversalDecoder())
+	for _, obj := range objects {
+		assert.NilError(t, tracker.Add(obj))
+	}
+	fake := &clienttesting.Fake{}
+	fake.AddReactor("*", "*", clienttesting.ObjectReaction(tracker))
+	return &client{kubernetesClient: &fakeClientset{coreV1: &fakecorev1.FakeCoreV1{Fake: fake}}}
+}
+
+func stubSecret(name string, labels map[string]string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace, Labels: labels},
+		Data:       map[string][]byte{"userdata": []byte("ignition")},
+	}
+}
+
+func TestSecrets(t *testing.T) {
+	ctx := context.Background()
+	c := newFakeClient(t,
+		stubSecret("tenant-a-ignition", map[string]string{"tenant": "a"}),
+		stubSecret("tenant-b-ignition", map[string]string{"tenant": "b"}),
+	)
+
+	_, err := c.CreateSecret(ctx, testNamespace, stubSecret("tenant-a-other", map[string]string{"tenant": "a"}))
+	assert.NilError(t, err)
+
+	secrets, err := c.ListSecrets(ctx, testNamespace, metav1.ListOptions{LabelSelector: "tenant=a"})
+	assert.NilError(t, err)
+	var names []string
+	for _, secret := range secrets.Items {
+		names = append(names, secret.Name)
+	}
+	assert.DeepEqual(t, names, []string{"tenant-a-ignition", "tenant-a-other"})
+
+	secret, err := c.GetSecret(ctx, testNamespace, "tenant-a-ignition")
+	assert.NilError(t, err)
+	assert.Equal(t, string(secret.Data["userdata"]), "ignition")
+
+	secret.Data["userdata"] = []byte("refreshed")
+	_, err = c.UpdateSecret(ctx, testNamespace, secret)
+	assert.NilError(t, err)
+	secret, err = c.GetSecret(ctx, testNamespace, "tenant-a-ignition")
+	assert.NilError(t, err)
+	assert.Equal(t, string(secret.Data["userdata"]), "refreshed")
+
+	assert.NilError(t, c.DeleteSecret(ctx, testNamespace, "tenant-a-ignition"))
+	_, err = c.GetSecret(ctx, testNamespace, "tenant-a-ignition")
+	assert.Assert(t, apimachineryerrors.IsNotFound(err))
+	err = c.DeleteSecret(ctx, testNamespace, "tenant-a-ignition")
+	assert.Assert(t, apimachineryerrors.IsNotFound(err))
+}
+
+// The numbers of allocations the unstructured round trips of the requests may take
+const (
+	vmRoundTripAllocsBudget     = 400
+	vmListRoundTripAllocsBudget = 16000
+)
+
+// vmListSize is the number of VirtualMachines of the listed namespace
+const vmListSize = 100
+
+// vmRoundTrip translates the VirtualMachine to unstructured and back, like every create and update
+func vmRoundTrip(vm *kubevirtapiv1.VirtualMachine) error {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(vm)
+	if err != nil {
+		return err
+	}
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(content, vm)
+}
+
+// unstructuredVMList returns the unstructured list of VirtualMachines the infra-cluster API responds to a list with
+func unstructuredVMList(tb testing.TB) unstructured.UnstructuredList {
+	var list unstructured.UnstructuredList
+	for i := 0; i < vmListSize; i++ {
+		vm := testutils.StubVirtualMachine(nil, nil, nil)
+		vm.Name = fmt.Sprintf("%s-%d", vm.Name, i)
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(vm)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		list.Items = append(list.Items, unstructured.Unstructured{Object: content})
+	}
+	return list
+}
+
+func BenchmarkVirtualMachineRoundTrip(b *testing.B) {
+	vm := testutils.StubVirtualMachine(nil, nil, nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := vmRoundTrip(vm); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkVirtualMachineListFromUnstructured(b *testing.B) {
+	c := &client{}
+	list := unstructuredVMList(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var vmList kubevirtapiv1.VirtualMachineList
+		if err := c.fromUnstructedListToInterface(list, &vmList, "VirtualMachineList"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestUnstructuredRoundTripAllocations(t *testing.T) {
+	vm := testutils.StubVirtualMachine(nil, nil, nil)
+	allocs := testing.AllocsPerRun(100, func() {
+		assert.NilError(t, vmRoundTrip(vm))
+	})
+	assert.Assert(t, allocs <= vmRoundTripAllocsBudget, "the VirtualMachine round trip took %v allocations, over the budget of %d", allocs, vmRoundTripAllocsBudget)
+
+	c := &client{}
+	list := unstructuredVMList(t)
+	allocs = testing.AllocsPerRun(10, func() {
+		var vmList kubevirtapiv1.VirtualMachineList
+		assert.NilError(t, c.fromUnstructedListToInterface(list, &vmList, "VirtualMachineList"))
+	})
+	assert.Assert(t, allocs <= vmListRoundTripAllocsBudget, "the list of %d VirtualMachines took %v allocations, over the budget of %d", vmListSize, allocs, vmListRoundTripAllocsBudget)
+}
@@ -0,0 +1,295 @@
+package infracluster
+
+import (
+	"context"
+	goerrors "errors"
+	"fmt"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+	kubevirtapiv1 "kubevirt.io/client-go/api/v1"
+	cdiv1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
+)
+
+// CreationLimits bound the creations of VirtualMachines, so a large scale-up doesn't overwhelm the infra-cluster,
+// and CDI which clones the boot volume of every created VirtualMachine. A zero limit is unlimited.
+type CreationLimits struct {
+	// MaxConcurrentCreations is the number of VirtualMachines being provisioned at once in the infra-clusters, above
+	// which the creations are throttled
+	MaxConcurrentCreations int
+	// MaxConcurrentCreationsPerNamespace is the number of VirtualMachines being provisioned at once in a namespace,
+	// above which the creations in the namespace are throttled
+	MaxConcurrentCreationsPerNamespace int
+	// MaxPendingClones is the number of VirtualMachines of a namespace waiting for their boot volume to be cloned,
+	// above which the creations in the namespace are throttled
+	MaxPendingClones int
+}
+
+// CreationThrottledError is returned when a VirtualMachine isn't created, since too many VirtualMachines
+// of its namespace, or of all the infra-clusters, are being provisioned, or are still waiting for their boot volume
+// to be cloned
+type CreationThrottledError struct {
+	// Namespace is empty when the creation is throttled by the VirtualMachines of all the infra-clusters
+	Namespace     string
+	PendingClones int
+	// ConcurrentCreations is set when the creation is throttled by the VirtualMachines being provisioned
+	ConcurrentCreations int
+}
+
+func (e *CreationThrottledError) Error() string {
+	if e.Namespace == "" {
+		return fmt.Sprintf("creation of VirtualMachines throttled, %d VirtualMachines are being provisioned in the infra-clusters",
+			e.ConcurrentCreations)
+	}
+	if e.ConcurrentCreations > 0 {
+		return fmt.Sprintf("creation of VirtualMachines throttled, %d VirtualMachines of namespace %s are being provisioned",
+			e.ConcurrentCreations, e.Namespace)
+	}
+	return fmt.Sprintf("creation of VirtualMachines throttled, %d VirtualMachines of namespace %s are waiting for their boot volume to be cloned",
+		e.PendingClones, e.Namespace)
+}
+
+// IsCreationThrottled returns true when the VirtualMachine wasn't created since the creations of its
+// namespace are throttled
+func IsCreationThrottled(err error) bool {
+	var throttledErr *CreationThrottledError
+	return goerrors.As(err, &throttledErr)
+}
+
+// CreationLimiter enforces the CreationLimits over the creations of all the Clients sharing it, the Client of the
+// controller and the Clients of the credentials secrets. The VirtualMachines being provisioned are counted from the
+// infra-clusters, so a VirtualMachine counts until KubeVirt created its VirtualMachineInstance and CDI populated
+// its DataVolumes, not only while its creation request runs.
+type CreationLimiter struct {
+	limits CreationLimits
+	// lock guards the fields below. It is only held to read or update them, never across the requests to the
+	// infra-clusters, so the creations in a namespace don't wait for the creations in the other namespaces.
+	lock sync.Mutex
+	// namespaces are the infra namespaces VirtualMachines are created in, or which still have VirtualMachines being
+	// provisioned, by the key of their Client and their name
+	namespaces map[string]*limitedNamespace
+	// creating is the number of creations allowed by MaxConcurrentCreations whose request still runs, the
+	// infra-clusters may not list their VirtualMachines yet
+	creating int
+}
+
+// limitedNamespace is an infra namespace, with the Client of its infra-cluster
+type limitedNamespace struct {
+	client    Client
+	namespace string
+	// lock serializes the creations in the namespace, so a creation counts the VirtualMachines created before it
+	lock sync.Mutex
+	// creations is the number of creations holding or waiting for the lock
+	creations int
+	// provisioning is the number of VirtualMachines of the namespace being provisioned, as last counted
+	provisioning int
+}
+
+// NewCreationLimiter returns the CreationLimiter of the limits, nil when they are unlimited
+func NewCreationLimiter(limits CreationLimits) *CreationLimiter {
+	if limits == (CreationLimits{}) {
+		return nil
+	}
+	return &CreationLimiter{
+		limits:     limits,
+		namespaces: map[string]*limitedNamespace{},
+	}
+}
+
+// limitedClient bounds the creations of VirtualMachines of the wrapped Client
+type limitedClient struct {
+	Client
+	limiter *CreationLimiter
+	// key identifies the Client, and its infra-cluster, among the Clients sharing the limiter
+	key string
+}
+
+// WithCreationLimits returns a Client which creates the VirtualMachines of the client within the limits of the
+// limiter, the key identifies the client among the Clients sharing the limiter. The creations over the limits are
+// throttled, for the machine controller to retry them later instead of holding its workers during a large
+// scale-up.
+func WithCreationLimits(client Client, limiter *CreationLimiter, key string) Client {
+	if limiter == nil {
+		return client
+	}
+	return &limitedClient{Client: client, limiter: limiter, key: key}
+}
+
+func (c *limitedClient) CreateVirtualMachine(ctx context.Context, namespace string, newVM *kubevirtapiv1.VirtualMachine) (*kubevirtapiv1.VirtualMachine, error) {
+	limited := c.limiter.lockNamespace(c.Client, c.key+"/"+namespace, namespace)
+	defer c.limiter.unlockNamespace(limited)
+	if c.limiter.limits.MaxConcurrentCreationsPerNamespace > 0 {
+		provisioning, err := provisioningVirtualMachines(ctx, c.Client, namespace)
+		if err != nil {
+			return nil, err
+		}
+		if provisioning >= c.limiter.limits.MaxConcurrentCreationsPerNamespace {
+			klog.Infof("%s: %d VirtualMachines of namespace %s are being provisioned - throttle the creation", newVM.Name, provisioning, namespace)
+			return nil, &CreationThrottledError{Namespace: namespace, ConcurrentCreations: provisioning}
+		}
+	}
+	if c.limiter.limits.MaxPendingClones > 0 {
+		vms, err := c.ListVirtualMachine(ctx, namespace, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		if pending := pendingClones(vms.Items); pending >= c.limiter.limits.MaxPendingClones {
+			klog.Infof("%s: %d VirtualMachines of namespace %s are waiting for their boot volume to be cloned - throttle the creation",
+				newVM.Name, pending, namespace)
+			return nil, &CreationThrottledError{Namespace: namespace, PendingClones: pending}
+		}
+	}
+	if c.limiter.limits.MaxConcurrentCreations == 0 {
+		return c.Client.CreateVirtualMachine(ctx, namespace, newVM)
+	}
+
+	provisioning, reserved, err := c.limiter.reserveCreation(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !reserved {
+		klog.Infof("%s: %d VirtualMachines are being provisioned in the infra-clusters - throttle the creation", newVM.Name, provisioning)
+		return nil, &CreationThrottledError{ConcurrentCreations: provisioning}
+	}
+	createdVM, err := c.Client.CreateVirtualMachine(ctx, namespace, newVM)
+	c.limiter.releaseCreation(limited, err == nil)
+	return createdVM, err
+}
+
+// lockNamespace locks the namespace for a creation, and registers it when it is new
+func (l *CreationLimiter) lockNamespace(client Client, key string, namespace string) *limitedNamespace {
+	l.lock.Lock()
+	limited, ok := l.namespaces[key]
+	if !ok {
+		limited = &limitedNamespace{client: client, namespace: namespace}
+		l.namespaces[key] = limited
+	}
+	limited.creations++
+	l.lock.Unlock()
+
+	limited.lock.Lock()
+	return limited
+}
+
+// unlockNamespace unlocks the namespace after a creation
+func (l *CreationLimiter) unlockNamespace(limited *limitedNamespace) {
+	limited.lock.Unlock()
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	limited.creations--
+	l.prune()
+}
+
+// prune forgets the namespaces without creations nor VirtualMachines being provisioned, which don't count towards
+// MaxConcurrentCreations. The lock must be held.
+func (l *CreationLimiter) prune() {
+	for key, limited := range l.namespaces {
+		if limited.creations == 0 && limited.provisioning == 0 {
+			delete(l.namespaces, key)
+		}
+	}
+}
+
+// reserveCreation counts the VirtualMachines being provisioned in the namespaces, and the creations whose request
+// still runs. It reserves a creation when they are below MaxConcurrentCreations, and returns whether it did.
+func (l *CreationLimiter) reserveCreation(ctx context.Context) (int, bool, error) {
+	l.lock.Lock()
+	namespaces := make([]*limitedNamespace, 0, len(l.namespaces))
+	for _, limited := range l.namespaces {
+		namespaces = append(namespaces, limited)
+	}
+	l.lock.Unlock()
+
+	counts := make([]int, len(namespaces))
+	for i, limited := range namespaces {
+		count, err := provisioningVirtualMachines(ctx, limited.client, limited.namespace)
+		if err != nil {
+			return 0, false, err
+		}
+		counts[i] = count
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	for i, limited := range namespaces {
+		limited.provisioning = counts[i]
+	}
+	l.prune()
+	// The namespaces registered while counting are included with their last count
+	provisioning := l.creating
+	for _, limited := range l.namespaces {
+		provisioning += limited.provisioning
+	}
+	if provisioning >= l.limits.MaxConcurrentCreations {
+		return provisioning, false, nil
+	}
+	l.creating++
+	return provisioning, true, nil
+}
+
+// releaseCreation releases a creation reserved in the namespace once its request completed. A created VirtualMachine
+// counts in its namespace until the namespace is counted again.
+func (l *CreationLimiter) releaseCreation(limited *limitedNamespace, created bool) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.creating--
+	if created {
+		limited.provisioning++
+	}
+}
+
+// provisioningVirtualMachines returns the number of VirtualMachines of the namespace being provisioned: the
+// VirtualMachines which should run but whose VirtualMachineInstance wasn't created yet, and the VirtualMachines
+// whose DataVolumes weren't populated yet. A DataVolume which isn't owned by a VirtualMachine, like an ignition
+// uploaded before the VirtualMachine is created, counts on its own.
+func provisioningVirtualMachines(ctx context.Context, client Client, namespace string) (int, error) {
+	vms, err := client.ListVirtualMachine(ctx, namespace, metav1.ListOptions{})
+	if err != nil {
+		return 0, err
+	}
+	dataVolumes, err := client.ListDataVolumes(ctx, namespace, metav1.ListOptions{})
+	if err != nil {
+		return 0, err
+	}
+	provisioning := map[string]bool{}
+	for _, vm := range vms.Items {
+		if vm.DeletionTimestamp == nil && !vm.Status.Created && shouldRun(vm) {
+			provisioning[vm.Name] = true
+		}
+	}
+	for _, dataVolume := range dataVolumes {
+		if dataVolume.DeletionTimestamp != nil || dataVolume.Status.Phase == cdiv1.Succeeded {
+			continue
+		}
+		name := "DataVolume/" + dataVolume.Name
+		for _, owner := range dataVolume.OwnerReferences {
+			if owner.Kind == "VirtualMachine" {
+				name = owner.Name
+			}
+		}
+		provisioning[name] = true
+	}
+	return len(provisioning), nil
+}
+
+// pendingClones returns the number of VirtualMachines which should run but whose VirtualMachineInstance
+// wasn't created yet, KubeVirt creates it once the DataVolumes of the VirtualMachine are cloned
+func pendingClones(vms []kubevirtapiv1.VirtualMachine) int {
+	pending := 0
+	for _, vm := range vms {
+		if vm.DeletionTimestamp != nil || vm.Status.Created || !shouldRun(vm) {
+			continue
+		}
+		pending++
+	}
+	return pending
+}
+
+func shouldRun(vm kubevirtapiv1.VirtualMachine) bool {
+	if vm.Spec.RunStrategy != nil {
+		return *vm.Spec.RunStrategy != kubevirtapiv1.RunStrategyHalted && *vm.Spec.RunStrategy != kubevirtapiv1.RunStrategyManual
+	}
+	return vm.Spec.Running != nil && *vm.Spec.Running
+}
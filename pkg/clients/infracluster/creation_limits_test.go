@@ -0,0 +1,176 @@
+package infracluster_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster"
+	mockInfraClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster/mock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/testutils"
+	"gotest.tools/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	kubevirtapiv1 "kubevirt.io/client-go/api/v1"
+	cdiv1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
+)
+
+func TestWithCreationLimitsPendingClones(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	client := mockInfraClusterClient.NewMockClient(mockCtrl)
+
+	cloning := *testutils.StubVirtualMachine(nil, nil, nil)
+	cloning.Spec.RunStrategy = nil
+	cloning.Spec.Running = pointer.BoolPtr(true)
+	running := *cloning.DeepCopy()
+	running.Status.Created = true
+	halted := *cloning.DeepCopy()
+	halted.Spec.Running = pointer.BoolPtr(false)
+	vms := &kubevirtapiv1.VirtualMachineList{Items: []kubevirtapiv1.VirtualMachine{cloning, running, halted}}
+	newVM := testutils.StubVirtualMachine(nil, nil, nil)
+
+	limited := infracluster.WithCreationLimits(client, infracluster.NewCreationLimiter(infracluster.CreationLimits{MaxPendingClones: 2}), "")
+	client.EXPECT().ListVirtualMachine(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(vms, nil).Times(1)
+	client.EXPECT().CreateVirtualMachine(gomock.Any(), testutils.InfraNamespace, newVM).Return(newVM, nil).Times(1)
+	_, err := limited.CreateVirtualMachine(context.Background(), testutils.InfraNamespace, newVM)
+	assert.NilError(t, err)
+
+	limited = infracluster.WithCreationLimits(client, infracluster.NewCreationLimiter(infracluster.CreationLimits{MaxPendingClones: 1}), "")
+	client.EXPECT().ListVirtualMachine(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(vms, nil).Times(1)
+	_, err = limited.CreateVirtualMachine(context.Background(), testutils.InfraNamespace, newVM)
+	assert.Assert(t, infracluster.IsCreationThrottled(err))
+	assert.Error(t, err, "creation of VirtualMachines throttled, 1 VirtualMachines of namespace test-infra-namespace are waiting for their boot volume to be cloned")
+}
+
+func TestWithCreationLimitsConcurrency(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	client := mockInfraClusterClient.NewMockClient(mockCtrl)
+	newVM := testutils.StubVirtualMachine(nil, nil, nil)
+
+	starting := *testutils.StubVirtualMachine(nil, nil, nil)
+	started := *starting.DeepCopy()
+	started.Status.Created = true
+	vms := &kubevirtapiv1.VirtualMachineList{Items: []kubevirtapiv1.VirtualMachine{starting}}
+	limited := infracluster.WithCreationLimits(client, infracluster.NewCreationLimiter(infracluster.CreationLimits{MaxConcurrentCreationsPerNamespace: 1}), "")
+	client.EXPECT().ListVirtualMachine(gomock.Any(), testutils.InfraNamespace, gomock.Any()).DoAndReturn(
+		func(ctx context.Context, namespace string, options metav1.ListOptions) (*kubevirtapiv1.VirtualMachineList, error) {
+			return vms, nil
+		}).AnyTimes()
+	client.EXPECT().ListDataVolumes(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(nil, nil).AnyTimes()
+
+	// The creation in the namespace is throttled while a VirtualMachine of the namespace is being provisioned
+	_, err := limited.CreateVirtualMachine(context.Background(), testutils.InfraNamespace, newVM)
+	assert.Assert(t, infracluster.IsCreationThrottled(err))
+	assert.Error(t, err, "creation of VirtualMachines throttled, 1 VirtualMachines of namespace test-infra-namespace are being provisioned")
+
+	// Once KubeVirt created its VirtualMachineInstance, a creation proceeds
+	vms = &kubevirtapiv1.VirtualMachineList{Items: []kubevirtapiv1.VirtualMachine{started}}
+	client.EXPECT().CreateVirtualMachine(gomock.Any(), testutils.InfraNamespace, newVM).Return(newVM, nil).Times(1)
+	_, err = limited.CreateVirtualMachine(context.Background(), testutils.InfraNamespace, newVM)
+	assert.NilError(t, err)
+}
+
+func TestWithCreationLimitsGlobalConcurrency(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	client := mockInfraClusterClient.NewMockClient(mockCtrl)
+	credentialsClient := mockInfraClusterClient.NewMockClient(mockCtrl)
+	newVM := testutils.StubVirtualMachine(nil, nil, nil)
+
+	created := *testutils.StubVirtualMachine(nil, nil, nil)
+	created.Status.Created = true
+	starting := *created.DeepCopy()
+	starting.Name = "starting"
+	starting.Status.Created = false
+	populated := cdiv1.DataVolume{ObjectMeta: metav1.ObjectMeta{Name: "populated"}, Status: cdiv1.DataVolumeStatus{Phase: cdiv1.Succeeded}}
+	// The DataVolume of the VirtualMachine which is starting doesn't count twice
+	cloning := cdiv1.DataVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "cloning", OwnerReferences: []metav1.OwnerReference{{Kind: "VirtualMachine", Name: "starting"}}},
+		Status:     cdiv1.DataVolumeStatus{Phase: cdiv1.CloneInProgress},
+	}
+	uploading := cdiv1.DataVolume{ObjectMeta: metav1.ObjectMeta{Name: "uploading"}, Status: cdiv1.DataVolumeStatus{Phase: cdiv1.UploadReady}}
+
+	// The limiter is shared by the Client of the controller and the Client of a credentials secret
+	limiter := infracluster.NewCreationLimiter(infracluster.CreationLimits{MaxConcurrentCreations: 3})
+	limited := infracluster.WithCreationLimits(client, limiter, "")
+	limitedCredentials := infracluster.WithCreationLimits(credentialsClient, limiter, "test-namespace/credentials")
+
+	client.EXPECT().ListVirtualMachine(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(
+		&kubevirtapiv1.VirtualMachineList{Items: []kubevirtapiv1.VirtualMachine{created, starting}}, nil).AnyTimes()
+	client.EXPECT().ListDataVolumes(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(
+		[]cdiv1.DataVolume{populated, cloning}, nil).AnyTimes()
+	client.EXPECT().CreateVirtualMachine(gomock.Any(), testutils.InfraNamespace, newVM).Return(newVM, nil).Times(1)
+	_, err := limited.CreateVirtualMachine(context.Background(), testutils.InfraNamespace, newVM)
+	assert.NilError(t, err)
+
+	credentialsClient.EXPECT().ListVirtualMachine(gomock.Any(), "other-namespace", gomock.Any()).Return(
+		&kubevirtapiv1.VirtualMachineList{Items: []kubevirtapiv1.VirtualMachine{starting}}, nil).AnyTimes()
+	credentialsClient.EXPECT().ListDataVolumes(gomock.Any(), "other-namespace", gomock.Any()).Return(
+		[]cdiv1.DataVolume{uploading}, nil).AnyTimes()
+	_, err = limitedCredentials.CreateVirtualMachine(context.Background(), "other-namespace", newVM)
+	assert.Assert(t, infracluster.IsCreationThrottled(err))
+	assert.Error(t, err, "creation of VirtualMachines throttled, 3 VirtualMachines are being provisioned in the infra-clusters")
+}
+
+func TestWithCreationLimitsNamespacesProvisioned(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	client := mockInfraClusterClient.NewMockClient(mockCtrl)
+	newVM := testutils.StubVirtualMachine(nil, nil, nil)
+	limited := infracluster.WithCreationLimits(client, infracluster.NewCreationLimiter(infracluster.CreationLimits{MaxConcurrentCreations: 3}), "")
+
+	// The VirtualMachines of the namespace were all provisioned when it is counted again for the next creation, the
+	// namespace is then no longer counted
+	client.EXPECT().ListVirtualMachine(gomock.Any(), "provisioned-namespace", gomock.Any()).Return(&kubevirtapiv1.VirtualMachineList{}, nil).Times(2)
+	client.EXPECT().ListDataVolumes(gomock.Any(), "provisioned-namespace", gomock.Any()).Return(nil, nil).Times(2)
+	client.EXPECT().CreateVirtualMachine(gomock.Any(), "provisioned-namespace", newVM).Return(newVM, nil).Times(1)
+	_, err := limited.CreateVirtualMachine(context.Background(), "provisioned-namespace", newVM)
+	assert.NilError(t, err)
+
+	client.EXPECT().ListVirtualMachine(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(&kubevirtapiv1.VirtualMachineList{}, nil).Times(2)
+	client.EXPECT().ListDataVolumes(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(nil, nil).Times(2)
+	client.EXPECT().CreateVirtualMachine(gomock.Any(), testutils.InfraNamespace, newVM).Return(newVM, nil).Times(2)
+	for i := 0; i < 2; i++ {
+		_, err = limited.CreateVirtualMachine(context.Background(), testutils.InfraNamespace, newVM)
+		assert.NilError(t, err)
+	}
+}
+
+func TestWithCreationLimitsConcurrentNamespaces(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	client := mockInfraClusterClient.NewMockClient(mockCtrl)
+	newVM := testutils.StubVirtualMachine(nil, nil, nil)
+	limited := infracluster.WithCreationLimits(client, infracluster.NewCreationLimiter(infracluster.CreationLimits{
+		MaxConcurrentCreations:             1,
+		MaxConcurrentCreationsPerNamespace: 1,
+	}), "")
+
+	client.EXPECT().ListVirtualMachine(gomock.Any(), gomock.Any(), gomock.Any()).Return(&kubevirtapiv1.VirtualMachineList{}, nil).AnyTimes()
+	client.EXPECT().ListDataVolumes(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	creating := make(chan struct{})
+	created := make(chan struct{})
+	client.EXPECT().CreateVirtualMachine(gomock.Any(), "slow-namespace", newVM).DoAndReturn(
+		func(ctx context.Context, namespace string, vm *kubevirtapiv1.VirtualMachine) (*kubevirtapiv1.VirtualMachine, error) {
+			close(creating)
+			<-created
+			return vm, nil
+		}).Times(1)
+	slowErr := make(chan error)
+	go func() {
+		_, err := limited.CreateVirtualMachine(context.Background(), "slow-namespace", newVM)
+		slowErr <- err
+	}()
+	<-creating
+
+	// The creation in another namespace doesn't wait for the creation still running, whose VirtualMachine isn't
+	// listed yet but counts
+	_, err := limited.CreateVirtualMachine(context.Background(), testutils.InfraNamespace, newVM)
+	assert.Assert(t, infracluster.IsCreationThrottled(err))
+	assert.Error(t, err, "creation of VirtualMachines throttled, 1 VirtualMachines are being provisioned in the infra-clusters")
+
+	close(created)
+	assert.NilError(t, <-slowErr)
+}
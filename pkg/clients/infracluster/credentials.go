@@ -0,0 +1,98 @@
+package infracluster
+
+import (
+	"context"
+	"sync"
+
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog"
+
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster"
+)
+
+// credentialsClient is a Client built from a credentials secret, at a version of the secret
+type credentialsClient struct {
+	client          Client
+	resourceVersion string
+}
+
+// CredentialsClients builds the Clients of the infra-clusters of the credentials secrets which the provider specs
+// of the Machines name, in the namespace of the Machine. The Clients are cached, and built again once their secret
+// changes, e.g. when its credentials are rotated.
+type CredentialsClients struct {
+	tenantClusterClient tenantcluster.Client
+	// controllerSecret and controllerClient are the credentials secret and the Client of the controller, which the
+	// Machines naming the same secret share
+	controllerSecret string
+	controllerClient Client
+	// build creates the Client from the credentials secret
+	build func(secret *corev1.Secret) (Client, error)
+
+	lock    sync.Mutex
+	clients map[string]credentialsClient
+}
+
+// NewCredentialsClients returns the CredentialsClients reading the credentials secrets from the tenant-cluster.
+// The Clients have the resilience, authentication, DataVolume and audit options of the Client of the controller, and
+// share its creation limiter.
+func NewCredentialsClients(tenantClusterClient tenantcluster.Client, controllerClient Client, resilience ResilienceOptions, auth AuthOptions,
+	dataVolumes DataVolumeOptions, limiter *CreationLimiter, audit *AuditLog) *CredentialsClients {
+	secretNamespace, secretName := auth.credentialsSecret()
+	return &CredentialsClients{
+		tenantClusterClient: tenantClusterClient,
+		controllerSecret:    secretNamespace + "/" + secretName,
+		controllerClient:    controllerClient,
+		build: func(secret *corev1.Secret) (Client, error) {
+			client, err := newFromCredentialsSecret(secret, resilience, auth, dataVolumes)
+			if err != nil {
+				return nil, err
+			}
+			key := secret.Namespace + "/" + secret.Name
+			return WithCreationLimits(WithAudit(client, audit, key), limiter, key), nil
+		},
+		clients: map[string]credentialsClient{},
+	}
+}
+
+// Get returns the Client of the infra-cluster of the credentials secret
+func (c *CredentialsClients) Get(ctx context.Context, secretNamespace string, secretName string) (Client, error) {
+	key := secretNamespace + "/" + secretName
+	if key == c.controllerSecret {
+		return c.controllerClient, nil
+	}
+	secret, err := getCredentialsSecret(ctx, c.tenantClusterClient, secretNamespace, secretName)
+	if err != nil {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if cached, ok := c.clients[key]; ok && cached.resourceVersion == secret.ResourceVersion {
+		return cached.client, nil
+	}
+	client, err := c.build(secret)
+	if err != nil {
+		return nil, err
+	}
+	klog.Infof("built the infra-cluster client of credentials secret %s at version %s", key, secret.ResourceVersion)
+	c.clients[key] = credentialsClient{client: client, resourceVersion: secret.ResourceVersion}
+	return client, nil
+}
+
+// MachineClients returns the Client of the infra-cluster of a Machine, which keeps its VirtualMachine
+type MachineClients func(ctx context.Context, machine *machinev1.Machine) (Client, error)
+
+// ForMachine returns the Client of the infra-cluster of the Machine: the one of the credentials secret named by its
+// provider spec, in the namespace of the Machine, and the Client of the controller otherwise
+func (c *CredentialsClients) ForMachine(ctx context.Context, machine *machinev1.Machine) (Client, error) {
+	providerSpec, err := kubevirtproviderv1alpha1.ProviderSpecFromRawExtension(machine.Spec.ProviderSpec.Value)
+	if err != nil {
+		return nil, err
+	}
+	if providerSpec.CredentialsSecretName == "" {
+		return c.controllerClient, nil
+	}
+	return c.Get(ctx, machine.Namespace, providerSpec.CredentialsSecretName)
+}
@@ -0,0 +1,80 @@
+package infracluster
+
+import (
+	"context"
+	"testing"
+
+	gomock "github.com/golang/mock/gomock"
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	apimachineryerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+	mockTenantClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster/mock"
+)
+
+func TestCredentialsClients(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	tenantClient := mockTenantClusterClient.NewMockClient(mockCtrl)
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "infra-credentials", Namespace: testNamespace, ResourceVersion: "1"}}
+	tenantClient.EXPECT().GetSecret(gomock.Any(), "infra-credentials", testNamespace).DoAndReturn(
+		func(context.Context, string, string) (*corev1.Secret, error) {
+			return secret.DeepCopy(), nil
+		}).AnyTimes()
+	tenantClient.EXPECT().GetSecret(gomock.Any(), "missing-credentials", testNamespace).Return(nil,
+		apimachineryerrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, "missing-credentials")).AnyTimes()
+
+	builds := 0
+	controllerClient := &client{}
+	clients := &CredentialsClients{
+		tenantClusterClient: tenantClient,
+		controllerSecret:    defaultCredentialsSecretSecretNamespace + "/" + defaultCredentialsSecretSecretName,
+		controllerClient:    controllerClient,
+		build: func(*corev1.Secret) (Client, error) {
+			builds++
+			return &client{}, nil
+		},
+		clients: map[string]credentialsClient{},
+	}
+
+	first, err := clients.Get(context.Background(), testNamespace, "infra-credentials")
+	assert.NilError(t, err)
+	again, err := clients.Get(context.Background(), testNamespace, "infra-credentials")
+	assert.NilError(t, err)
+	assert.Assert(t, first == again)
+	assert.Equal(t, builds, 1)
+
+	// A rotated secret builds the Client again
+	secret.ResourceVersion = "2"
+	rotated, err := clients.Get(context.Background(), testNamespace, "infra-credentials")
+	assert.NilError(t, err)
+	assert.Assert(t, rotated != first)
+	assert.Equal(t, builds, 2)
+
+	_, err = clients.Get(context.Background(), testNamespace, "missing-credentials")
+	assert.ErrorContains(t, err, "Infra-cluster credentials secret test-namespace/missing-credentials")
+	assert.Equal(t, builds, 2)
+
+	// The Machines naming the credentials secret of the controller share its Client
+	shared, err := clients.Get(context.Background(), defaultCredentialsSecretSecretNamespace, defaultCredentialsSecretSecretName)
+	assert.NilError(t, err)
+	assert.Assert(t, shared == Client(controllerClient))
+	assert.Equal(t, builds, 2)
+
+	// The Machines are served by the Client of the credentials secret of their provider spec, in their namespace
+	machine := &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "test-machine", Namespace: testNamespace}}
+	machine.Spec.ProviderSpec.Value, err = kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(
+		&kubevirtproviderv1alpha1.KubevirtMachineProviderSpec{CredentialsSecretName: "infra-credentials"})
+	assert.NilError(t, err)
+	ofMachine, err := clients.ForMachine(context.Background(), machine)
+	assert.NilError(t, err)
+	assert.Assert(t, ofMachine == rotated)
+	machine.Spec.ProviderSpec.Value, err = kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&kubevirtproviderv1alpha1.KubevirtMachineProviderSpec{})
+	assert.NilError(t, err)
+	ofMachine, err = clients.ForMachine(context.Background(), machine)
+	assert.NilError(t, err)
+	assert.Assert(t, ofMachine == Client(controllerClient))
+}
@@ -0,0 +1,209 @@
+package infracluster
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"k8s.io/klog"
+)
+
+// anyValue matches every verb or resource of the requests
+const anyValue = "*"
+
+// Fault is a failure injected into the requests to the infra-cluster API matching its verb and resource, for
+// testing the resilience of the controller: the requests are failed with a status or a connection error, after a
+// latency. A fault with only a latency delays the requests without failing them.
+type Fault struct {
+	// Verb is the verb of the requests, e.g. get, list, watch, create, update, patch or delete, or * for any verb
+	Verb string
+	// Resource is the resource of the requests, e.g. virtualmachines, or * for any resource
+	Resource string
+	// StatusCode is the status of the failed requests
+	StatusCode int
+	// ConnectionError fails the requests with a connection reset instead of a status
+	ConnectionError bool
+	// Latency delays the requests
+	Latency time.Duration
+	// Count is the number of matching requests which are injected the fault, every request when zero
+	Count int
+}
+
+// FaultInjector injects the faults into the requests to the infra-cluster API, under the retries and the circuit
+// breaker of the resilience options, which they exercise
+type FaultInjector struct {
+	faults []Fault
+
+	lock sync.Mutex
+	// injected is the number of requests each fault was injected into
+	injected []int
+}
+
+// NewFaultInjector returns the FaultInjector of the faults, the first fault matching a request is injected
+func NewFaultInjector(faults []Fault) *FaultInjector {
+	return &FaultInjector{faults: faults, injected: make([]int, len(faults))}
+}
+
+// ParseFaults parses the faults separated by semicolons, each of the form <verb>/<resource> followed by the
+// key=value settings status=<code>, error=connection, latency=<duration> and count=<n>, e.g.
+// "create/virtualmachines status=503 count=2; get/* latency=500ms"
+func ParseFaults(spec string) ([]Fault, error) {
+	var faults []Fault
+	for _, rule := range strings.Split(spec, ";") {
+		fields := strings.Fields(rule)
+		if len(fields) == 0 {
+			continue
+		}
+		target := strings.Split(fields[0], "/")
+		if len(target) != 2 || target[0] == "" || target[1] == "" {
+			return nil, fmt.Errorf("invalid fault %q, expected <verb>/<resource>", rule)
+		}
+		fault := Fault{Verb: target[0], Resource: target[1]}
+		for _, setting := range fields[1:] {
+			parts := strings.SplitN(setting, "=", 2)
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("invalid setting %q of fault %q, expected key=value", setting, rule)
+			}
+			var err error
+			switch parts[0] {
+			case "status":
+				fault.StatusCode, err = strconv.Atoi(parts[1])
+			case "error":
+				if parts[1] != "connection" {
+					err = fmt.Errorf("unknown error %q", parts[1])
+				}
+				fault.ConnectionError = true
+			case "latency":
+				fault.Latency, err = time.ParseDuration(parts[1])
+			case "count":
+				fault.Count, err = strconv.Atoi(parts[1])
+			default:
+				err = fmt.Errorf("unknown setting")
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid setting %q of fault %q: %v", setting, rule, err)
+			}
+		}
+		faults = append(faults, fault)
+	}
+	return faults, nil
+}
+
+// wrap returns the transport injecting the faults into the requests of the transport
+func (f *FaultInjector) wrap(rt http.RoundTripper) http.RoundTripper {
+	return &faultTransport{next: rt, injector: f}
+}
+
+// inject returns the fault to inject into the request, or false when none matches
+func (f *FaultInjector) inject(verb string, resource string) (Fault, bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for i, fault := range f.faults {
+		if (fault.Verb != anyValue && fault.Verb != verb) || (fault.Resource != anyValue && fault.Resource != resource) {
+			continue
+		}
+		if fault.Count > 0 && f.injected[i] >= fault.Count {
+			continue
+		}
+		f.injected[i]++
+		return fault, true
+	}
+	return Fault{}, false
+}
+
+// faultTransport injects the faults into the requests of the wrapped transport
+type faultTransport struct {
+	next     http.RoundTripper
+	injector *FaultInjector
+}
+
+func (t *faultTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	verb, resource := requestVerbAndResource(req)
+	fault, ok := t.injector.inject(verb, resource)
+	if !ok {
+		return t.next.RoundTrip(req)
+	}
+	klog.V(3).Infof("injecting fault into %s %s of the infra-cluster", verb, req.URL.Path)
+	if fault.Latency > 0 {
+		select {
+		case <-time.After(fault.Latency):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+	switch {
+	case fault.ConnectionError:
+		return nil, &faultConnectionError{op: req.Method + " " + req.URL.Path}
+	case fault.StatusCode != 0:
+		return &http.Response{
+			Status:     fmt.Sprintf("%d %s", fault.StatusCode, http.StatusText(fault.StatusCode)),
+			StatusCode: fault.StatusCode,
+			Header:     http.Header{"Content-Type": []string{"text/plain"}},
+			Body:       ioutil.NopCloser(bytes.NewBufferString("injected fault")),
+			Request:    req,
+		}, nil
+	default:
+		return t.next.RoundTrip(req)
+	}
+}
+
+// faultConnectionError is an injected connection reset, a transient network error
+type faultConnectionError struct {
+	op string
+}
+
+func (e *faultConnectionError) Error() string {
+	return fmt.Sprintf("%s: injected fault: %v", e.op, syscall.ECONNRESET)
+}
+
+func (e *faultConnectionError) Timeout() bool   { return false }
+func (e *faultConnectionError) Temporary() bool { return true }
+
+// requestVerbAndResource returns the verb and the resource of a request to the API server, from its method and
+// its path: /api/<version>/... or /apis/<group>/<version>/..., optionally followed by namespaces/<namespace>
+func requestVerbAndResource(req *http.Request) (string, string) {
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	switch {
+	case len(parts) >= 2 && parts[0] == "api":
+		parts = parts[2:]
+	case len(parts) >= 3 && parts[0] == "apis":
+		parts = parts[3:]
+	default:
+		return strings.ToLower(req.Method), ""
+	}
+	if len(parts) >= 3 && parts[0] == "namespaces" {
+		parts = parts[2:]
+	}
+	resource := ""
+	if len(parts) > 0 {
+		resource = parts[0]
+	}
+	named := len(parts) > 1
+
+	switch req.Method {
+	case http.MethodGet:
+		if req.URL.Query().Get("watch") == "true" {
+			return "watch", resource
+		}
+		if named {
+			return "get", resource
+		}
+		return "list", resource
+	case http.MethodPost:
+		return "create", resource
+	case http.MethodPut:
+		return "update", resource
+	case http.MethodPatch:
+		return "patch", resource
+	case http.MethodDelete:
+		return "delete", resource
+	default:
+		return strings.ToLower(req.Method), resource
+	}
+}
@@ -0,0 +1,185 @@
+package infracluster
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestParseFaults(t *testing.T) {
+	cases := []struct {
+		name           string
+		spec           string
+		expectedFaults []Fault
+		expectedErr    string
+	}{
+		{
+			name: "Success parse the faults",
+			spec: "create/virtualmachines status=503 count=2; get/* latency=500ms error=connection;",
+			expectedFaults: []Fault{
+				{Verb: "create", Resource: "virtualmachines", StatusCode: 503, Count: 2},
+				{Verb: "get", Resource: "*", Latency: 500 * time.Millisecond, ConnectionError: true},
+			},
+		},
+		{
+			name:        "Failure fault without a resource",
+			spec:        "create status=503",
+			expectedErr: `invalid fault "create status=503", expected <verb>/<resource>`,
+		},
+		{
+			name:        "Failure unknown setting",
+			spec:        "create/secrets code=503",
+			expectedErr: `invalid setting "code=503" of fault "create/secrets code=503": unknown setting`,
+		},
+		{
+			name:        "Failure unknown error",
+			spec:        "create/secrets error=dns",
+			expectedErr: `invalid setting "error=dns" of fault "create/secrets error=dns": unknown error "dns"`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			faults, err := ParseFaults(tc.spec)
+			if tc.expectedErr != "" {
+				assert.Error(t, err, tc.expectedErr)
+				return
+			}
+			assert.NilError(t, err)
+			assert.DeepEqual(t, faults, tc.expectedFaults)
+		})
+	}
+}
+
+func TestRequestVerbAndResource(t *testing.T) {
+	cases := []struct {
+		method           string
+		url              string
+		expectedVerb     string
+		expectedResource string
+	}{
+		{http.MethodGet, "/apis/kubevirt.io/v1/namespaces/ns/virtualmachines/vm", "get", "virtualmachines"},
+		{http.MethodGet, "/apis/kubevirt.io/v1/namespaces/ns/virtualmachines", "list", "virtualmachines"},
+		{http.MethodGet, "/api/v1/namespaces/ns/secrets?watch=true", "watch", "secrets"},
+		{http.MethodGet, "/api/v1/nodes", "list", "nodes"},
+		{http.MethodPost, "/apis/cdi.kubevirt.io/v1beta1/namespaces/ns/datavolumes", "create", "datavolumes"},
+		{http.MethodPut, "/api/v1/namespaces/ns/secrets/ignition", "update", "secrets"},
+		{http.MethodPatch, "/api/v1/namespaces/ns/services/svc", "patch", "services"},
+		{http.MethodDelete, "/api/v1/namespaces/ns", "delete", "namespaces"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.method+" "+tc.url, func(t *testing.T) {
+			req, err := http.NewRequest(tc.method, "https://infra"+tc.url, nil)
+			assert.NilError(t, err)
+			verb, resource := requestVerbAndResource(req)
+			assert.Equal(t, verb, tc.expectedVerb)
+			assert.Equal(t, resource, tc.expectedResource)
+		})
+	}
+}
+
+func TestFaultInjectionResilience(t *testing.T) {
+	cases := []struct {
+		name             string
+		faults           string
+		options          ResilienceOptions
+		method           string
+		path             string
+		expectedRequests int32
+		expectedStatus   int
+		expectedErr      bool
+		expectedOpen     bool
+	}{
+		{
+			name:             "retry the injected transient statuses",
+			faults:           "get/virtualmachines status=503 count=2",
+			options:          ResilienceOptions{MaxRetries: 3, RetryBackoff: time.Millisecond},
+			method:           http.MethodGet,
+			path:             "/apis/kubevirt.io/v1/namespaces/ns/virtualmachines/vm",
+			expectedRequests: 1,
+			expectedStatus:   http.StatusOK,
+		},
+		{
+			name:             "retry the injected connection errors",
+			faults:           "delete/* error=connection count=1",
+			options:          ResilienceOptions{MaxRetries: 1, RetryBackoff: time.Millisecond},
+			method:           http.MethodDelete,
+			path:             "/api/v1/namespaces/ns/secrets/ignition",
+			expectedRequests: 1,
+			expectedStatus:   http.StatusOK,
+		},
+		{
+			name:           "never retry the creations",
+			faults:         "create/virtualmachines status=503",
+			options:        ResilienceOptions{MaxRetries: 3, RetryBackoff: time.Millisecond},
+			method:         http.MethodPost,
+			path:           "/apis/kubevirt.io/v1/namespaces/ns/virtualmachines",
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:        "time out the injected latencies",
+			faults:      "get/* latency=1s",
+			options:     ResilienceOptions{Timeout: 20 * time.Millisecond},
+			method:      http.MethodGet,
+			path:        "/api/v1/namespaces/ns/secrets/ignition",
+			expectedErr: true,
+		},
+		{
+			name:         "open the circuit on the injected failures",
+			faults:       "*/* status=503",
+			options:      ResilienceOptions{CircuitBreakerThreshold: 1, CircuitBreakerCooldown: time.Minute},
+			method:       http.MethodGet,
+			path:         "/api/v1/nodes",
+			expectedErr:  true,
+			expectedOpen: true,
+		},
+		{
+			name:             "leave the requests of the other resources alone",
+			faults:           "get/virtualmachines status=503",
+			method:           http.MethodGet,
+			path:             "/api/v1/namespaces/ns/secrets/ignition",
+			expectedRequests: 1,
+			expectedStatus:   http.StatusOK,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var requests int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&requests, 1)
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+			faults, err := ParseFaults(tc.faults)
+			assert.NilError(t, err)
+			transport := newResilientTransport(tc.options)
+			transport.next = NewFaultInjector(faults).wrap(http.DefaultTransport)
+
+			do := func() (*http.Response, error) {
+				req, err := http.NewRequest(tc.method, server.URL+tc.path, strings.NewReader("{}"))
+				assert.NilError(t, err)
+				return transport.RoundTrip(req)
+			}
+			resp, err := do()
+			if tc.expectedOpen {
+				// The first failure opens the circuit, the following requests fail fast
+				assert.NilError(t, err)
+				resp.Body.Close()
+				_, err = do()
+			}
+			if tc.expectedErr {
+				assert.Assert(t, err != nil)
+				assert.Equal(t, IsCircuitOpen(err), tc.expectedOpen)
+			} else {
+				assert.NilError(t, err)
+				assert.Equal(t, resp.StatusCode, tc.expectedStatus)
+				resp.Body.Close()
+			}
+			assert.Equal(t, atomic.LoadInt32(&requests), tc.expectedRequests)
+		})
+	}
+}
@@ -7,10 +7,20 @@ package mock
 import (
 	context "context"
 	gomock "github.com/golang/mock/gomock"
-	v1 "k8s.io/api/core/v1"
-	v10 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	v11 "kubevirt.io/client-go/api/v1"
+	infracluster "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster"
+	v1 "k8s.io/api/batch/v1"
+	v10 "k8s.io/api/core/v1"
+	v11 "k8s.io/api/networking/v1"
+	v12 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	unstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	watch "k8s.io/apimachinery/pkg/watch"
+	cache "k8s.io/client-go/tools/cache"
+	v13 "kubevirt.io/client-go/api/v1"
+	v1alpha1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
 	reflect "reflect"
+	time "time"
 )
 
 // MockClient is a mock of Client interface
@@ -37,10 +47,10 @@ func (m *MockClient) EXPECT() *MockClientMockRecorder {
 }
 
 // CreateVirtualMachine mocks base method
-func (m *MockClient) CreateVirtualMachine(ctx context.Context, namespace string, newVM *v11.VirtualMachine) (*v11.VirtualMachine, error) {
+func (m *MockClient) CreateVirtualMachine(ctx context.Context, namespace string, newVM *v13.VirtualMachine) (*v13.VirtualMachine, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "CreateVirtualMachine", ctx, namespace, newVM)
-	ret0, _ := ret[0].(*v11.VirtualMachine)
+	ret0, _ := ret[0].(*v13.VirtualMachine)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -52,7 +62,7 @@ func (mr *MockClientMockRecorder) CreateVirtualMachine(ctx, namespace, newVM int
 }
 
 // DeleteVirtualMachine mocks base method
-func (m *MockClient) DeleteVirtualMachine(ctx context.Context, namespace, name string, options *v10.DeleteOptions) error {
+func (m *MockClient) DeleteVirtualMachine(ctx context.Context, namespace, name string, options *v12.DeleteOptions) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "DeleteVirtualMachine", ctx, namespace, name, options)
 	ret0, _ := ret[0].(error)
@@ -66,10 +76,10 @@ func (mr *MockClientMockRecorder) DeleteVirtualMachine(ctx, namespace, name, opt
 }
 
 // GetVirtualMachine mocks base method
-func (m *MockClient) GetVirtualMachine(ctx context.Context, namespace, name string, options *v10.GetOptions) (*v11.VirtualMachine, error) {
+func (m *MockClient) GetVirtualMachine(ctx context.Context, namespace, name string, options *v12.GetOptions) (*v13.VirtualMachine, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetVirtualMachine", ctx, namespace, name, options)
-	ret0, _ := ret[0].(*v11.VirtualMachine)
+	ret0, _ := ret[0].(*v13.VirtualMachine)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -81,10 +91,10 @@ func (mr *MockClientMockRecorder) GetVirtualMachine(ctx, namespace, name, option
 }
 
 // GetVirtualMachineInstance mocks base method
-func (m *MockClient) GetVirtualMachineInstance(ctx context.Context, namespace, name string, options *v10.GetOptions) (*v11.VirtualMachineInstance, error) {
+func (m *MockClient) GetVirtualMachineInstance(ctx context.Context, namespace, name string, options *v12.GetOptions) (*v13.VirtualMachineInstance, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetVirtualMachineInstance", ctx, namespace, name, options)
-	ret0, _ := ret[0].(*v11.VirtualMachineInstance)
+	ret0, _ := ret[0].(*v13.VirtualMachineInstance)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -96,10 +106,10 @@ func (mr *MockClientMockRecorder) GetVirtualMachineInstance(ctx, namespace, name
 }
 
 // ListVirtualMachine mocks base method
-func (m *MockClient) ListVirtualMachine(ctx context.Context, namespace string, options v10.ListOptions) (*v11.VirtualMachineList, error) {
+func (m *MockClient) ListVirtualMachine(ctx context.Context, namespace string, options v12.ListOptions) (*v13.VirtualMachineList, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "ListVirtualMachine", ctx, namespace, options)
-	ret0, _ := ret[0].(*v11.VirtualMachineList)
+	ret0, _ := ret[0].(*v13.VirtualMachineList)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -110,11 +120,55 @@ func (mr *MockClientMockRecorder) ListVirtualMachine(ctx, namespace, options int
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListVirtualMachine", reflect.TypeOf((*MockClient)(nil).ListVirtualMachine), ctx, namespace, options)
 }
 
+// Watch mocks base method
+func (m *MockClient) Watch(ctx context.Context, resource schema.GroupVersionResource, namespace string, options v12.ListOptions) (watch.Interface, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Watch", ctx, resource, namespace, options)
+	ret0, _ := ret[0].(watch.Interface)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Watch indicates an expected call of Watch
+func (mr *MockClientMockRecorder) Watch(ctx, resource, namespace, options interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Watch", reflect.TypeOf((*MockClient)(nil).Watch), ctx, resource, namespace, options)
+}
+
+// NewInformer mocks base method
+func (m *MockClient) NewInformer(resource schema.GroupVersionResource, namespace string, selector labels.Selector, resyncPeriod time.Duration) cache.SharedIndexInformer {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NewInformer", resource, namespace, selector, resyncPeriod)
+	ret0, _ := ret[0].(cache.SharedIndexInformer)
+	return ret0
+}
+
+// NewInformer indicates an expected call of NewInformer
+func (mr *MockClientMockRecorder) NewInformer(resource, namespace, selector, resyncPeriod interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewInformer", reflect.TypeOf((*MockClient)(nil).NewInformer), resource, namespace, selector, resyncPeriod)
+}
+
+// ListVirtualMachineInstance mocks base method
+func (m *MockClient) ListVirtualMachineInstance(ctx context.Context, namespace string, options v12.ListOptions) (*v13.VirtualMachineInstanceList, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListVirtualMachineInstance", ctx, namespace, options)
+	ret0, _ := ret[0].(*v13.VirtualMachineInstanceList)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListVirtualMachineInstance indicates an expected call of ListVirtualMachineInstance
+func (mr *MockClientMockRecorder) ListVirtualMachineInstance(ctx, namespace, options interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListVirtualMachineInstance", reflect.TypeOf((*MockClient)(nil).ListVirtualMachineInstance), ctx, namespace, options)
+}
+
 // UpdateVirtualMachine mocks base method
-func (m *MockClient) UpdateVirtualMachine(ctx context.Context, namespace string, vm *v11.VirtualMachine) (*v11.VirtualMachine, error) {
+func (m *MockClient) UpdateVirtualMachine(ctx context.Context, namespace string, vm *v13.VirtualMachine) (*v13.VirtualMachine, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "UpdateVirtualMachine", ctx, namespace, vm)
-	ret0, _ := ret[0].(*v11.VirtualMachine)
+	ret0, _ := ret[0].(*v13.VirtualMachine)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -126,10 +180,10 @@ func (mr *MockClientMockRecorder) UpdateVirtualMachine(ctx, namespace, vm interf
 }
 
 // CreateSecret mocks base method
-func (m *MockClient) CreateSecret(ctx context.Context, namespace string, newSecret *v1.Secret) (*v1.Secret, error) {
+func (m *MockClient) CreateSecret(ctx context.Context, namespace string, newSecret *v10.Secret) (*v10.Secret, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "CreateSecret", ctx, namespace, newSecret)
-	ret0, _ := ret[0].(*v1.Secret)
+	ret0, _ := ret[0].(*v10.Secret)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -139,3 +193,507 @@ func (mr *MockClientMockRecorder) CreateSecret(ctx, namespace, newSecret interfa
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSecret", reflect.TypeOf((*MockClient)(nil).CreateSecret), ctx, namespace, newSecret)
 }
+
+// GetSecret mocks base method
+func (m *MockClient) GetSecret(ctx context.Context, namespace, name string) (*v10.Secret, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSecret", ctx, namespace, name)
+	ret0, _ := ret[0].(*v10.Secret)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSecret indicates an expected call of GetSecret
+func (mr *MockClientMockRecorder) GetSecret(ctx, namespace, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSecret", reflect.TypeOf((*MockClient)(nil).GetSecret), ctx, namespace, name)
+}
+
+// ListSecrets mocks base method
+func (m *MockClient) ListSecrets(ctx context.Context, namespace string, options v12.ListOptions) (*v10.SecretList, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSecrets", ctx, namespace, options)
+	ret0, _ := ret[0].(*v10.SecretList)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSecrets indicates an expected call of ListSecrets
+func (mr *MockClientMockRecorder) ListSecrets(ctx, namespace, options interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSecrets", reflect.TypeOf((*MockClient)(nil).ListSecrets), ctx, namespace, options)
+}
+
+// UpdateSecret mocks base method
+func (m *MockClient) UpdateSecret(ctx context.Context, namespace string, secret *v10.Secret) (*v10.Secret, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSecret", ctx, namespace, secret)
+	ret0, _ := ret[0].(*v10.Secret)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateSecret indicates an expected call of UpdateSecret
+func (mr *MockClientMockRecorder) UpdateSecret(ctx, namespace, secret interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSecret", reflect.TypeOf((*MockClient)(nil).UpdateSecret), ctx, namespace, secret)
+}
+
+// DeleteSecret mocks base method
+func (m *MockClient) DeleteSecret(ctx context.Context, namespace, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSecret", ctx, namespace, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteSecret indicates an expected call of DeleteSecret
+func (mr *MockClientMockRecorder) DeleteSecret(ctx, namespace, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSecret", reflect.TypeOf((*MockClient)(nil).DeleteSecret), ctx, namespace, name)
+}
+
+// ListNodes mocks base method
+func (m *MockClient) ListNodes(ctx context.Context, options v12.ListOptions) (*v10.NodeList, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListNodes", ctx, options)
+	ret0, _ := ret[0].(*v10.NodeList)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListNodes indicates an expected call of ListNodes
+func (mr *MockClientMockRecorder) ListNodes(ctx, options interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListNodes", reflect.TypeOf((*MockClient)(nil).ListNodes), ctx, options)
+}
+
+// GetStorageClassAccessModes mocks base method
+func (m *MockClient) GetStorageClassAccessModes(ctx context.Context, storageClassName string) (string, []v10.PersistentVolumeAccessMode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStorageClassAccessModes", ctx, storageClassName)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].([]v10.PersistentVolumeAccessMode)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetStorageClassAccessModes indicates an expected call of GetStorageClassAccessModes
+func (mr *MockClientMockRecorder) GetStorageClassAccessModes(ctx, storageClassName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStorageClassAccessModes", reflect.TypeOf((*MockClient)(nil).GetStorageClassAccessModes), ctx, storageClassName)
+}
+
+// GetCapabilities mocks base method
+func (m *MockClient) GetCapabilities() infracluster.Capabilities {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCapabilities")
+	ret0, _ := ret[0].(infracluster.Capabilities)
+	return ret0
+}
+
+// GetCapabilities indicates an expected call of GetCapabilities
+func (mr *MockClientMockRecorder) GetCapabilities() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCapabilities", reflect.TypeOf((*MockClient)(nil).GetCapabilities))
+}
+
+// CreateNamespace mocks base method
+func (m *MockClient) CreateNamespace(ctx context.Context, newNamespace *v10.Namespace) (*v10.Namespace, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateNamespace", ctx, newNamespace)
+	ret0, _ := ret[0].(*v10.Namespace)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateNamespace indicates an expected call of CreateNamespace
+func (mr *MockClientMockRecorder) CreateNamespace(ctx, newNamespace interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateNamespace", reflect.TypeOf((*MockClient)(nil).CreateNamespace), ctx, newNamespace)
+}
+
+// CreateResourceQuota mocks base method
+func (m *MockClient) CreateResourceQuota(ctx context.Context, namespace string, newResourceQuota *v10.ResourceQuota) (*v10.ResourceQuota, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateResourceQuota", ctx, namespace, newResourceQuota)
+	ret0, _ := ret[0].(*v10.ResourceQuota)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateResourceQuota indicates an expected call of CreateResourceQuota
+func (mr *MockClientMockRecorder) CreateResourceQuota(ctx, namespace, newResourceQuota interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateResourceQuota", reflect.TypeOf((*MockClient)(nil).CreateResourceQuota), ctx, namespace, newResourceQuota)
+}
+
+// CreateNetworkPolicy mocks base method
+func (m *MockClient) CreateNetworkPolicy(ctx context.Context, namespace string, newNetworkPolicy *v11.NetworkPolicy) (*v11.NetworkPolicy, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateNetworkPolicy", ctx, namespace, newNetworkPolicy)
+	ret0, _ := ret[0].(*v11.NetworkPolicy)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateNetworkPolicy indicates an expected call of CreateNetworkPolicy
+func (mr *MockClientMockRecorder) CreateNetworkPolicy(ctx, namespace, newNetworkPolicy interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateNetworkPolicy", reflect.TypeOf((*MockClient)(nil).CreateNetworkPolicy), ctx, namespace, newNetworkPolicy)
+}
+
+// GetNetworkPolicy mocks base method
+func (m *MockClient) GetNetworkPolicy(ctx context.Context, namespace, name string) (*v11.NetworkPolicy, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetNetworkPolicy", ctx, namespace, name)
+	ret0, _ := ret[0].(*v11.NetworkPolicy)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetNetworkPolicy indicates an expected call of GetNetworkPolicy
+func (mr *MockClientMockRecorder) GetNetworkPolicy(ctx, namespace, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNetworkPolicy", reflect.TypeOf((*MockClient)(nil).GetNetworkPolicy), ctx, namespace, name)
+}
+
+// UpdateNetworkPolicy mocks base method
+func (m *MockClient) UpdateNetworkPolicy(ctx context.Context, namespace string, networkPolicy *v11.NetworkPolicy) (*v11.NetworkPolicy, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateNetworkPolicy", ctx, namespace, networkPolicy)
+	ret0, _ := ret[0].(*v11.NetworkPolicy)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateNetworkPolicy indicates an expected call of UpdateNetworkPolicy
+func (mr *MockClientMockRecorder) UpdateNetworkPolicy(ctx, namespace, networkPolicy interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateNetworkPolicy", reflect.TypeOf((*MockClient)(nil).UpdateNetworkPolicy), ctx, namespace, networkPolicy)
+}
+
+// ListResourceQuotas mocks base method
+func (m *MockClient) ListResourceQuotas(ctx context.Context, namespace string) (*v10.ResourceQuotaList, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListResourceQuotas", ctx, namespace)
+	ret0, _ := ret[0].(*v10.ResourceQuotaList)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListResourceQuotas indicates an expected call of ListResourceQuotas
+func (mr *MockClientMockRecorder) ListResourceQuotas(ctx, namespace interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListResourceQuotas", reflect.TypeOf((*MockClient)(nil).ListResourceQuotas), ctx, namespace)
+}
+
+// GetPersistentVolumeClaim mocks base method
+func (m *MockClient) GetPersistentVolumeClaim(ctx context.Context, namespace, name string) (*v10.PersistentVolumeClaim, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPersistentVolumeClaim", ctx, namespace, name)
+	ret0, _ := ret[0].(*v10.PersistentVolumeClaim)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPersistentVolumeClaim indicates an expected call of GetPersistentVolumeClaim
+func (mr *MockClientMockRecorder) GetPersistentVolumeClaim(ctx, namespace, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPersistentVolumeClaim", reflect.TypeOf((*MockClient)(nil).GetPersistentVolumeClaim), ctx, namespace, name)
+}
+
+// GetDataSourcePVC mocks base method
+func (m *MockClient) GetDataSourcePVC(ctx context.Context, namespace, name string) (*v1alpha1.DataVolumeSourcePVC, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDataSourcePVC", ctx, namespace, name)
+	ret0, _ := ret[0].(*v1alpha1.DataVolumeSourcePVC)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDataSourcePVC indicates an expected call of GetDataSourcePVC
+func (mr *MockClientMockRecorder) GetDataSourcePVC(ctx, namespace, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDataSourcePVC", reflect.TypeOf((*MockClient)(nil).GetDataSourcePVC), ctx, namespace, name)
+}
+
+// ListPodMetrics mocks base method
+func (m *MockClient) ListPodMetrics(ctx context.Context, namespace string, options v12.ListOptions) ([]infracluster.PodMetrics, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPodMetrics", ctx, namespace, options)
+	ret0, _ := ret[0].([]infracluster.PodMetrics)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPodMetrics indicates an expected call of ListPodMetrics
+func (mr *MockClientMockRecorder) ListPodMetrics(ctx, namespace, options interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPodMetrics", reflect.TypeOf((*MockClient)(nil).ListPodMetrics), ctx, namespace, options)
+}
+
+// ListPods mocks base method
+func (m *MockClient) ListPods(ctx context.Context, namespace string, options v12.ListOptions) (*v10.PodList, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPods", ctx, namespace, options)
+	ret0, _ := ret[0].(*v10.PodList)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPods indicates an expected call of ListPods
+func (mr *MockClientMockRecorder) ListPods(ctx, namespace, options interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPods", reflect.TypeOf((*MockClient)(nil).ListPods), ctx, namespace, options)
+}
+
+// GetPod mocks base method
+func (m *MockClient) GetPod(ctx context.Context, namespace, name string) (*v10.Pod, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPod", ctx, namespace, name)
+	ret0, _ := ret[0].(*v10.Pod)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPod indicates an expected call of GetPod
+func (mr *MockClientMockRecorder) GetPod(ctx, namespace, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPod", reflect.TypeOf((*MockClient)(nil).GetPod), ctx, namespace, name)
+}
+
+// CreatePod mocks base method
+func (m *MockClient) CreatePod(ctx context.Context, namespace string, newPod *v10.Pod) (*v10.Pod, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePod", ctx, namespace, newPod)
+	ret0, _ := ret[0].(*v10.Pod)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePod indicates an expected call of CreatePod
+func (mr *MockClientMockRecorder) CreatePod(ctx, namespace, newPod interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePod", reflect.TypeOf((*MockClient)(nil).CreatePod), ctx, namespace, newPod)
+}
+
+// DeletePod mocks base method
+func (m *MockClient) DeletePod(ctx context.Context, namespace, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeletePod", ctx, namespace, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeletePod indicates an expected call of DeletePod
+func (mr *MockClientMockRecorder) DeletePod(ctx, namespace, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePod", reflect.TypeOf((*MockClient)(nil).DeletePod), ctx, namespace, name)
+}
+
+// GetConfigMap mocks base method
+func (m *MockClient) GetConfigMap(ctx context.Context, namespace, name string) (*v10.ConfigMap, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetConfigMap", ctx, namespace, name)
+	ret0, _ := ret[0].(*v10.ConfigMap)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetConfigMap indicates an expected call of GetConfigMap
+func (mr *MockClientMockRecorder) GetConfigMap(ctx, namespace, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConfigMap", reflect.TypeOf((*MockClient)(nil).GetConfigMap), ctx, namespace, name)
+}
+
+// CreateConfigMap mocks base method
+func (m *MockClient) CreateConfigMap(ctx context.Context, namespace string, newConfigMap *v10.ConfigMap) (*v10.ConfigMap, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateConfigMap", ctx, namespace, newConfigMap)
+	ret0, _ := ret[0].(*v10.ConfigMap)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateConfigMap indicates an expected call of CreateConfigMap
+func (mr *MockClientMockRecorder) CreateConfigMap(ctx, namespace, newConfigMap interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateConfigMap", reflect.TypeOf((*MockClient)(nil).CreateConfigMap), ctx, namespace, newConfigMap)
+}
+
+// DeleteConfigMap mocks base method
+func (m *MockClient) DeleteConfigMap(ctx context.Context, namespace, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteConfigMap", ctx, namespace, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteConfigMap indicates an expected call of DeleteConfigMap
+func (mr *MockClientMockRecorder) DeleteConfigMap(ctx, namespace, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteConfigMap", reflect.TypeOf((*MockClient)(nil).DeleteConfigMap), ctx, namespace, name)
+}
+
+// CreateJob mocks base method
+func (m *MockClient) CreateJob(ctx context.Context, namespace string, newJob *v1.Job) (*v1.Job, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateJob", ctx, namespace, newJob)
+	ret0, _ := ret[0].(*v1.Job)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateJob indicates an expected call of CreateJob
+func (mr *MockClientMockRecorder) CreateJob(ctx, namespace, newJob interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateJob", reflect.TypeOf((*MockClient)(nil).CreateJob), ctx, namespace, newJob)
+}
+
+// DeleteJob mocks base method
+func (m *MockClient) DeleteJob(ctx context.Context, namespace, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteJob", ctx, namespace, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteJob indicates an expected call of DeleteJob
+func (mr *MockClientMockRecorder) DeleteJob(ctx, namespace, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteJob", reflect.TypeOf((*MockClient)(nil).DeleteJob), ctx, namespace, name)
+}
+
+// ListEvents mocks base method
+func (m *MockClient) ListEvents(ctx context.Context, namespace string, options v12.ListOptions) (*v10.EventList, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListEvents", ctx, namespace, options)
+	ret0, _ := ret[0].(*v10.EventList)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListEvents indicates an expected call of ListEvents
+func (mr *MockClientMockRecorder) ListEvents(ctx, namespace, options interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListEvents", reflect.TypeOf((*MockClient)(nil).ListEvents), ctx, namespace, options)
+}
+
+// GetDataVolume mocks base method
+func (m *MockClient) GetDataVolume(ctx context.Context, namespace, name string) (*unstructured.Unstructured, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDataVolume", ctx, namespace, name)
+	ret0, _ := ret[0].(*unstructured.Unstructured)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDataVolume indicates an expected call of GetDataVolume
+func (mr *MockClientMockRecorder) GetDataVolume(ctx, namespace, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDataVolume", reflect.TypeOf((*MockClient)(nil).GetDataVolume), ctx, namespace, name)
+}
+
+// ListDataVolumes mocks base method
+func (m *MockClient) ListDataVolumes(ctx context.Context, namespace string, options v12.ListOptions) ([]v1alpha1.DataVolume, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDataVolumes", ctx, namespace, options)
+	ret0, _ := ret[0].([]v1alpha1.DataVolume)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDataVolumes indicates an expected call of ListDataVolumes
+func (mr *MockClientMockRecorder) ListDataVolumes(ctx, namespace, options interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDataVolumes", reflect.TypeOf((*MockClient)(nil).ListDataVolumes), ctx, namespace, options)
+}
+
+// CreateDataVolume mocks base method
+func (m *MockClient) CreateDataVolume(ctx context.Context, namespace string, newDataVolume *v1alpha1.DataVolume) (*v1alpha1.DataVolume, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateDataVolume", ctx, namespace, newDataVolume)
+	ret0, _ := ret[0].(*v1alpha1.DataVolume)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateDataVolume indicates an expected call of CreateDataVolume
+func (mr *MockClientMockRecorder) CreateDataVolume(ctx, namespace, newDataVolume interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateDataVolume", reflect.TypeOf((*MockClient)(nil).CreateDataVolume), ctx, namespace, newDataVolume)
+}
+
+// UpdateDataVolume mocks base method
+func (m *MockClient) UpdateDataVolume(ctx context.Context, namespace string, dataVolume *v1alpha1.DataVolume) (*v1alpha1.DataVolume, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateDataVolume", ctx, namespace, dataVolume)
+	ret0, _ := ret[0].(*v1alpha1.DataVolume)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateDataVolume indicates an expected call of UpdateDataVolume
+func (mr *MockClientMockRecorder) UpdateDataVolume(ctx, namespace, dataVolume interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateDataVolume", reflect.TypeOf((*MockClient)(nil).UpdateDataVolume), ctx, namespace, dataVolume)
+}
+
+// DeleteDataVolume mocks base method
+func (m *MockClient) DeleteDataVolume(ctx context.Context, namespace, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteDataVolume", ctx, namespace, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteDataVolume indicates an expected call of DeleteDataVolume
+func (mr *MockClientMockRecorder) DeleteDataVolume(ctx, namespace, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteDataVolume", reflect.TypeOf((*MockClient)(nil).DeleteDataVolume), ctx, namespace, name)
+}
+
+// UploadDataVolume mocks base method
+func (m *MockClient) UploadDataVolume(ctx context.Context, namespace, name string, image []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UploadDataVolume", ctx, namespace, name, image)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UploadDataVolume indicates an expected call of UploadDataVolume
+func (mr *MockClientMockRecorder) UploadDataVolume(ctx, namespace, name, image interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UploadDataVolume", reflect.TypeOf((*MockClient)(nil).UploadDataVolume), ctx, namespace, name, image)
+}
+
+// GetVirtualMachinePool mocks base method
+func (m *MockClient) GetVirtualMachinePool(ctx context.Context, namespace, name string) (*unstructured.Unstructured, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVirtualMachinePool", ctx, namespace, name)
+	ret0, _ := ret[0].(*unstructured.Unstructured)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVirtualMachinePool indicates an expected call of GetVirtualMachinePool
+func (mr *MockClientMockRecorder) GetVirtualMachinePool(ctx, namespace, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVirtualMachinePool", reflect.TypeOf((*MockClient)(nil).GetVirtualMachinePool), ctx, namespace, name)
+}
+
+// UpdateVirtualMachinePool mocks base method
+func (m *MockClient) UpdateVirtualMachinePool(ctx context.Context, namespace string, pool *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateVirtualMachinePool", ctx, namespace, pool)
+	ret0, _ := ret[0].(*unstructured.Unstructured)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateVirtualMachinePool indicates an expected call of UpdateVirtualMachinePool
+func (mr *MockClientMockRecorder) UpdateVirtualMachinePool(ctx, namespace, pool interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateVirtualMachinePool", reflect.TypeOf((*MockClient)(nil).UpdateVirtualMachinePool), ctx, namespace, pool)
+}
@@ -0,0 +1,66 @@
+package infracluster
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var podMetricsResource = schema.GroupVersionResource{
+	Group:    "metrics.k8s.io",
+	Version:  "v1beta1",
+	Resource: "pods",
+}
+
+// PodMetrics is the resource usage of a pod, as reported by the metrics API of the infra-cluster
+type PodMetrics struct {
+	Name   string
+	Labels map[string]string
+	// Usage is the sum of the usage of the containers of the pod
+	Usage corev1.ResourceList
+}
+
+func (c *client) ListPodMetrics(ctx context.Context, namespace string, options metav1.ListOptions) ([]PodMetrics, error) {
+	resp, err := c.dynamicClient.Resource(podMetricsResource).Namespace(namespace).List(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]PodMetrics, 0, len(resp.Items))
+	for _, item := range resp.Items {
+		podMetrics, err := podMetricsFromUnstructured(item)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, podMetrics)
+	}
+	return result, nil
+}
+
+func podMetricsFromUnstructured(item unstructured.Unstructured) (PodMetrics, error) {
+	podMetrics := PodMetrics{Name: item.GetName(), Labels: item.GetLabels(), Usage: corev1.ResourceList{}}
+	containers, _, err := unstructured.NestedSlice(item.Object, "containers")
+	if err != nil {
+		return PodMetrics{}, fmt.Errorf("PodMetrics %s: invalid containers, with error: %v", item.GetName(), err)
+	}
+	for _, container := range containers {
+		usage, _, err := unstructured.NestedStringMap(container.(map[string]interface{}), "usage")
+		if err != nil {
+			return PodMetrics{}, fmt.Errorf("PodMetrics %s: invalid usage, with error: %v", item.GetName(), err)
+		}
+		for name, value := range usage {
+			quantity, err := apiresource.ParseQuantity(value)
+			if err != nil {
+				return PodMetrics{}, fmt.Errorf("PodMetrics %s: invalid %s usage %q, with error: %v", item.GetName(), name, value, err)
+			}
+			total := podMetrics.Usage[corev1.ResourceName(name)]
+			total.Add(quantity)
+			podMetrics.Usage[corev1.ResourceName(name)] = total
+		}
+	}
+	return podMetrics, nil
+}
@@ -0,0 +1,37 @@
+package infracluster
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestPodMetricsFromUnstructured(t *testing.T) {
+	item := unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name":   "virt-launcher-vm-a-xyz",
+			"labels": map[string]interface{}{"kubevirt.io/vm": "vm-a"},
+		},
+		"containers": []interface{}{
+			map[string]interface{}{"name": "compute", "usage": map[string]interface{}{"cpu": "1200m", "memory": "2Gi"}},
+			map[string]interface{}{"name": "volumecontainerdisk", "usage": map[string]interface{}{"cpu": "300m", "memory": "10Mi"}},
+		},
+	}}
+
+	podMetrics, err := podMetricsFromUnstructured(item)
+	assert.NilError(t, err)
+	assert.Equal(t, podMetrics.Name, "virt-launcher-vm-a-xyz")
+	assert.Equal(t, podMetrics.Labels["kubevirt.io/vm"], "vm-a")
+	cpu := podMetrics.Usage[corev1.ResourceCPU]
+	assert.Equal(t, cpu.MilliValue(), int64(1500))
+	memory := podMetrics.Usage[corev1.ResourceMemory]
+	assert.Equal(t, memory.Value(), int64(2*1024*1024*1024+10*1024*1024))
+
+	item.Object["containers"] = []interface{}{
+		map[string]interface{}{"name": "compute", "usage": map[string]interface{}{"cpu": "lots"}},
+	}
+	_, err = podMetricsFromUnstructured(item)
+	assert.ErrorContains(t, err, "invalid cpu usage")
+}
@@ -0,0 +1,246 @@
+package infracluster
+
+import (
+	"context"
+	goerrors "errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog"
+
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/metrics"
+)
+
+// ResilienceOptions configure how the requests to the infra-cluster API weather its transient failures.
+// They apply to every operation of the Client, at the transport of its requests. A zero option is disabled.
+type ResilienceOptions struct {
+	// Timeout bounds every attempt of a request, but the watches which are long running
+	Timeout time.Duration
+	// MaxRetries is the number of times an idempotent request failing with a transient error is retried
+	MaxRetries int
+	// RetryBackoff is the delay before the first retry, doubled for every following retry, with jitter
+	RetryBackoff time.Duration
+	// CircuitBreakerThreshold is the number of consecutive transient failures which open the circuit: the
+	// requests then fail fast, without reaching the infra-cluster API, until the cooldown is over
+	CircuitBreakerThreshold int
+	// CircuitBreakerCooldown is the time the circuit stays open, before a single request probes the infra-cluster API
+	CircuitBreakerCooldown time.Duration
+	// Faults injects failures into the requests, under the retries and the circuit breaker, for testing only
+	Faults *FaultInjector
+}
+
+// retryJitterFactor is the maximal fraction of the retry backoff added to it
+const retryJitterFactor = 0.5
+
+// CircuitOpenError is returned for the requests failed fast while the infra-cluster API is down
+type CircuitOpenError struct {
+	Until time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("infra-cluster API unavailable, requests are rejected until %s", e.Until.Format(time.RFC3339))
+}
+
+// IsCircuitOpen returns true when the request was rejected since the infra-cluster API is down
+func IsCircuitOpen(err error) bool {
+	var circuitErr *CircuitOpenError
+	return goerrors.As(err, &circuitErr)
+}
+
+// WithResilience returns a copy of the rest config of the infra-cluster, whose requests are wrapped with the
+// timeouts, retries and circuit breaking of the options
+func WithResilience(config *rest.Config, options ResilienceOptions) *rest.Config {
+	if options == (ResilienceOptions{}) {
+		return config
+	}
+	config = rest.CopyConfig(config)
+	breaker := &circuitBreaker{threshold: options.CircuitBreakerThreshold, cooldown: options.CircuitBreakerCooldown}
+	if options.Faults != nil {
+		// Wrapped first, the faults are injected into every attempt of the requests
+		config.Wrap(options.Faults.wrap)
+	}
+	config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return &resilientTransport{next: rt, options: options, breaker: breaker}
+	})
+	return config
+}
+
+// resilientTransport applies the resilience options to the requests of the wrapped transport
+type resilientTransport struct {
+	next    http.RoundTripper
+	options ResilienceOptions
+	breaker *circuitBreaker
+}
+
+func (t *resilientTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.breaker.allow(); err != nil {
+		metrics.ObserveInfraRequest(metrics.InfraRequestRejected)
+		return nil, err
+	}
+	// The watches are re-established by their consumers, and only bounded by their own timeout
+	if req.URL.Query().Get("watch") == "true" {
+		resp, err := t.next.RoundTrip(req)
+		t.breaker.record(isTransient(resp, err))
+		return resp, err
+	}
+
+	retries := 0
+	if isIdempotent(req) {
+		retries = t.options.MaxRetries
+	}
+	for attempt := 0; ; attempt++ {
+		resp, err := t.attempt(req)
+		transient := isTransient(resp, err)
+		t.breaker.record(transient)
+		if !transient {
+			metrics.ObserveInfraRequest(metrics.InfraRequestSucceeded)
+			return resp, err
+		}
+		if goerrors.Is(err, context.DeadlineExceeded) && req.Context().Err() == nil {
+			metrics.ObserveInfraRequest(metrics.InfraRequestTimedOut)
+		}
+		if attempt >= retries || req.Context().Err() != nil || t.breaker.allow() != nil {
+			metrics.ObserveInfraRequest(metrics.InfraRequestFailed)
+			return resp, err
+		}
+		metrics.ObserveInfraRequest(metrics.InfraRequestRetried)
+		klog.V(3).Infof("retrying %s %s to the infra-cluster after a transient failure", req.Method, req.URL.Path)
+		if resp != nil {
+			resp.Body.Close()
+		}
+		if req, err = rewind(req); err != nil {
+			return nil, err
+		}
+		select {
+		case <-time.After(wait.Jitter(t.options.RetryBackoff<<attempt, retryJitterFactor)):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// attempt sends the request once, within the timeout. The timeout covers reading the body of the response.
+func (t *resilientTransport) attempt(req *http.Request) (*http.Response, error) {
+	if t.options.Timeout == 0 {
+		return t.next.RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), t.options.Timeout)
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelOnClose releases the timeout of a request once its response is read
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
+
+// isIdempotent returns true for the requests which can be sent again without changing their outcome
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return req.Body == nil || req.GetBody != nil
+	default:
+		return false
+	}
+}
+
+// isTransient returns true when the request failed since the infra-cluster API is unreachable or overloaded
+func isTransient(resp *http.Response, err error) bool {
+	if err != nil {
+		var netErr net.Error
+		return goerrors.As(err, &netErr) || goerrors.Is(err, io.EOF) || goerrors.Is(err, io.ErrUnexpectedEOF) ||
+			goerrors.Is(err, context.DeadlineExceeded)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// rewind returns a copy of the request with a fresh body, to send it again
+func rewind(req *http.Request) (*http.Request, error) {
+	if req.Body == nil || req.GetBody == nil {
+		return req, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Body = body
+	return req, nil
+}
+
+// circuitBreaker rejects the requests for a cooldown once the infra-cluster API failed too many times in a row.
+// Once the cooldown is over, a single request probes the infra-cluster API: the circuit closes if it succeeds,
+// and opens again otherwise.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	lock     sync.Mutex
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// allow returns a CircuitOpenError while the circuit is open
+func (b *circuitBreaker) allow() error {
+	if b.threshold == 0 {
+		return nil
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.failures < b.threshold {
+		return nil
+	}
+	until := b.openedAt.Add(b.cooldown)
+	if time.Now().Before(until) || b.probing {
+		return &CircuitOpenError{Until: until}
+	}
+	b.probing = true
+	return nil
+}
+
+// record records the outcome of a request
+func (b *circuitBreaker) record(transient bool) {
+	if b.threshold == 0 {
+		return
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.probing = false
+	if !transient {
+		if b.failures >= b.threshold {
+			klog.Infof("infra-cluster API available again, closing the circuit")
+		}
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		if b.failures == b.threshold {
+			klog.Warningf("infra-cluster API failed %d times in a row, opening the circuit for %v", b.failures, b.cooldown)
+		}
+		b.openedAt = time.Now()
+	}
+}
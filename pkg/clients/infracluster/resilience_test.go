@@ -0,0 +1,136 @@
+package infracluster
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func newResilientTransport(options ResilienceOptions) *resilientTransport {
+	return &resilientTransport{
+		next:    http.DefaultTransport,
+		options: options,
+		breaker: &circuitBreaker{threshold: options.CircuitBreakerThreshold, cooldown: options.CircuitBreakerCooldown},
+	}
+}
+
+func TestResilientTransport(t *testing.T) {
+	cases := []struct {
+		name             string
+		options          ResilienceOptions
+		method           string
+		failures         int32
+		delay            time.Duration
+		expectedAttempts int32
+		expectedStatus   int
+		expectedErr      bool
+	}{
+		{
+			name:             "retry a transient failure",
+			options:          ResilienceOptions{MaxRetries: 3, RetryBackoff: time.Millisecond},
+			method:           http.MethodGet,
+			failures:         2,
+			expectedAttempts: 3,
+			expectedStatus:   http.StatusOK,
+		},
+		{
+			name:             "give up once the retries are exhausted",
+			options:          ResilienceOptions{MaxRetries: 1, RetryBackoff: time.Millisecond},
+			method:           http.MethodGet,
+			failures:         5,
+			expectedAttempts: 2,
+			expectedStatus:   http.StatusServiceUnavailable,
+		},
+		{
+			name:             "never retry a creation",
+			options:          ResilienceOptions{MaxRetries: 3, RetryBackoff: time.Millisecond},
+			method:           http.MethodPost,
+			failures:         1,
+			expectedAttempts: 1,
+			expectedStatus:   http.StatusServiceUnavailable,
+		},
+		{
+			name:             "retry a timed out attempt",
+			options:          ResilienceOptions{Timeout: 50 * time.Millisecond, MaxRetries: 1, RetryBackoff: time.Millisecond},
+			method:           http.MethodGet,
+			delay:            300 * time.Millisecond,
+			expectedAttempts: 2,
+			expectedErr:      true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var attempts int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempt := atomic.AddInt32(&attempts, 1)
+				if tc.delay > 0 {
+					select {
+					case <-time.After(tc.delay):
+					case <-r.Context().Done():
+						return
+					}
+				}
+				if attempt <= tc.failures {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+
+			req, err := http.NewRequest(tc.method, server.URL, strings.NewReader("{}"))
+			assert.NilError(t, err)
+			resp, err := newResilientTransport(tc.options).RoundTrip(req)
+			if tc.expectedErr {
+				assert.Assert(t, err != nil)
+			} else {
+				assert.NilError(t, err)
+				assert.Equal(t, resp.StatusCode, tc.expectedStatus)
+				resp.Body.Close()
+			}
+			assert.Equal(t, atomic.LoadInt32(&attempts), tc.expectedAttempts)
+		})
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	var down int32 = 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&down) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	transport := newResilientTransport(ResilienceOptions{CircuitBreakerThreshold: 2, CircuitBreakerCooldown: 100 * time.Millisecond})
+
+	get := func() (*http.Response, error) {
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		assert.NilError(t, err)
+		return transport.RoundTrip(req)
+	}
+	for i := 0; i < 2; i++ {
+		resp, err := get()
+		assert.NilError(t, err)
+		resp.Body.Close()
+	}
+	_, err := get()
+	assert.Assert(t, IsCircuitOpen(err))
+
+	// The probe after the cooldown closes the circuit once the infra-cluster API is back
+	atomic.StoreInt32(&down, 0)
+	time.Sleep(150 * time.Millisecond)
+	resp, err := get()
+	assert.NilError(t, err)
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+	resp.Body.Close()
+	resp, err = get()
+	assert.NilError(t, err)
+	resp.Body.Close()
+}
@@ -0,0 +1,68 @@
+package infracluster
+
+// DataVolumeOptions configure how the DataVolumes, and the DataVolume templates of the VirtualMachines, are requested
+// from CDI
+type DataVolumeOptions struct {
+	// StorageAPI requests the DataVolumes with the spec.storage API of CDI instead of the legacy spec.pvc, when the
+	// infra-cluster serves the StorageProfiles. Their access modes and volume mode are then left to CDI, which fills
+	// them from the StorageProfile of their storage class.
+	StorageAPI bool
+}
+
+// storageAPI returns whether the DataVolumes are requested with the spec.storage API
+func (c *client) storageAPI() bool {
+	return c.dataVolumes.StorageAPI && c.capabilities.StorageProfiles()
+}
+
+// toStorageAPI moves the spec.pvc of the DataVolumes of the unstructured DataVolume or VirtualMachine to
+// spec.storage, without their access modes and volume mode. The DataVolume types the provider is built with only
+// have spec.pvc, so the DataVolumes are rewritten once unstructured, before they are sent.
+func toStorageAPI(resource string, obj map[string]interface{}) {
+	for _, spec := range dataVolumeSpecs(resource, obj) {
+		pvc, ok := spec["pvc"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		delete(pvc, "accessModes")
+		delete(pvc, "volumeMode")
+		spec["storage"] = pvc
+		delete(spec, "pvc")
+	}
+}
+
+// fromStorageAPI moves the spec.storage of the DataVolumes of the unstructured DataVolume or VirtualMachine back to
+// spec.pvc, which the DataVolume types of the provider keep, so the DataVolumes read are updated unchanged
+func fromStorageAPI(resource string, obj map[string]interface{}) {
+	for _, spec := range dataVolumeSpecs(resource, obj) {
+		storage, ok := spec["storage"].(map[string]interface{})
+		if !ok || spec["pvc"] != nil {
+			continue
+		}
+		spec["pvc"] = storage
+		delete(spec, "storage")
+	}
+}
+
+// dataVolumeSpecs returns the specs of the unstructured DataVolume, or of the DataVolume templates of the
+// unstructured VirtualMachine, to be edited in place
+func dataVolumeSpecs(resource string, obj map[string]interface{}) []map[string]interface{} {
+	spec, _ := obj["spec"].(map[string]interface{})
+	if spec == nil {
+		return nil
+	}
+	switch resource {
+	case "datavolumes":
+		return []map[string]interface{}{spec}
+	case "virtualmachines":
+		templates, _ := spec["dataVolumeTemplates"].([]interface{})
+		var specs []map[string]interface{}
+		for _, template := range templates {
+			template, _ := template.(map[string]interface{})
+			if templateSpec, ok := template["spec"].(map[string]interface{}); ok {
+				specs = append(specs, templateSpec)
+			}
+		}
+		return specs
+	}
+	return nil
+}
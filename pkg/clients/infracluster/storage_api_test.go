@@ -0,0 +1,116 @@
+package infracluster
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/testutils"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+	cdiv1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
+)
+
+func stubDataVolume() *cdiv1.DataVolume {
+	return &cdiv1.DataVolume{
+		TypeMeta:   metav1.TypeMeta{Kind: "DataVolume"},
+		ObjectMeta: metav1.ObjectMeta{Name: "vm-ignition", Namespace: testNamespace},
+		Spec: cdiv1.DataVolumeSpec{
+			Source: cdiv1.DataVolumeSource{Upload: &cdiv1.DataVolumeSourceUpload{}},
+			PVC: &corev1.PersistentVolumeClaimSpec{
+				AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: apiresource.MustParse("1Mi")},
+				},
+			},
+		},
+	}
+}
+
+func TestStorageAPI(t *testing.T) {
+	cases := []struct {
+		name            string
+		storageAPI      bool
+		cdiVersions     []string
+		expectedStorage bool
+	}{
+		{
+			name:            "storage API",
+			storageAPI:      true,
+			cdiVersions:     []string{"v1beta1", "v1alpha1"},
+			expectedStorage: true,
+		},
+		{
+			name:        "storage API disabled",
+			cdiVersions: []string{"v1beta1", "v1alpha1"},
+		},
+		{
+			name:        "StorageProfiles not served",
+			storageAPI:  true,
+			cdiVersions: []string{"v1alpha1"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			created := map[string]*unstructured.Unstructured{}
+			dynamicClient := fakedynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+				vmResource: "VirtualMachineList",
+				{Group: cdiGroupName, Version: tc.cdiVersions[0], Resource: "datavolumes"}: "DataVolumeList",
+			})
+			dynamicClient.PrependReactor("create", "*", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				created[action.GetResource().Resource] = action.(clienttesting.CreateAction).GetObject().(*unstructured.Unstructured).DeepCopy()
+				return false, nil, nil
+			})
+			c := &client{
+				dynamicClient: dynamicClient,
+				capabilities:  Capabilities{CDIVersions: tc.cdiVersions},
+				dataVolumes:   DataVolumeOptions{StorageAPI: tc.storageAPI},
+			}
+
+			vm, err := c.CreateVirtualMachine(ctx, testutils.InfraNamespace, testutils.StubVirtualMachine(nil, nil, nil))
+			assert.NilError(t, err)
+			dataVolume, err := c.CreateDataVolume(ctx, testNamespace, stubDataVolume())
+			assert.NilError(t, err)
+
+			templates, _, err := unstructured.NestedSlice(created["virtualmachines"].Object, "spec", "dataVolumeTemplates")
+			assert.NilError(t, err)
+			assert.Equal(t, len(templates), 1)
+			for _, spec := range []map[string]interface{}{
+				templates[0].(map[string]interface{})["spec"].(map[string]interface{}),
+				created["datavolumes"].Object["spec"].(map[string]interface{}),
+			} {
+				_, hasPVC := spec["pvc"]
+				storage, hasStorage := spec["storage"].(map[string]interface{})
+				assert.Equal(t, hasPVC, !tc.expectedStorage)
+				assert.Equal(t, hasStorage, tc.expectedStorage)
+				if tc.expectedStorage {
+					_, hasAccessModes := storage["accessModes"]
+					_, hasVolumeMode := storage["volumeMode"]
+					assert.Assert(t, !hasAccessModes && !hasVolumeMode)
+					_, hasRequests := storage["resources"].(map[string]interface{})["requests"]
+					assert.Assert(t, hasRequests)
+				}
+			}
+
+			// The DataVolumes created and read back have their spec.storage as spec.pvc
+			assert.Assert(t, vm.Spec.DataVolumeTemplates[0].Spec.PVC != nil)
+			assert.Assert(t, dataVolume.Spec.PVC != nil)
+			readVM, err := c.GetVirtualMachine(ctx, testutils.InfraNamespace, testutils.MachineName, &metav1.GetOptions{})
+			assert.NilError(t, err)
+			assert.Assert(t, readVM.Spec.DataVolumeTemplates[0].Spec.PVC != nil)
+			assert.Equal(t, readVM.Spec.DataVolumeTemplates[0].Spec.PVC.Resources.Requests.Storage().String(), "666Gi")
+			dataVolumes, err := c.ListDataVolumes(ctx, testNamespace, metav1.ListOptions{})
+			assert.NilError(t, err)
+			assert.Equal(t, len(dataVolumes), 1)
+			assert.Assert(t, dataVolumes[0].Spec.PVC != nil)
+			assert.Equal(t, len(dataVolumes[0].Spec.PVC.AccessModes) == 0, tc.expectedStorage)
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package infracluster
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	uploadGroupName = "upload.cdi.kubevirt.io"
+	// cdiConfigName is the name of the cluster scoped CDIConfig, which publishes the URL of the upload proxy
+	cdiConfigName = "config"
+	// uploadTimeout bounds an upload to the upload proxy, the images uploaded by the provider are small
+	uploadTimeout = 2 * time.Minute
+)
+
+// UploadDataVolume requests an upload token for the PVC of the DataVolume, and uploads the image through the CDI
+// upload proxy of the infra-cluster. The DataVolume has an upload source, and is UploadReady.
+func (c *client) UploadDataVolume(ctx context.Context, namespace string, name string, image []byte) error {
+	if len(c.capabilities.CDIVersions) == 0 {
+		return errors.Errorf("infra-cluster doesn't serve %s", cdiGroupName)
+	}
+	version := c.capabilities.CDIVersions[0]
+	uploadProxyURL, err := c.uploadProxyURL(ctx, version)
+	if err != nil {
+		return err
+	}
+	token, err := c.requestUploadToken(ctx, version, namespace, name)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/%s/upload", uploadProxyURL, version), bytes.NewReader(image))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Bearer "+token)
+	request.Header.Set("Content-Type", "application/octet-stream")
+	response, err := c.uploadClient.Do(request)
+	if err != nil {
+		return errors.Wrapf(err, "failed to upload to DataVolume %s/%s", namespace, name)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(response.Body)
+		return errors.Errorf("failed to upload to DataVolume %s/%s: %s: %s", namespace, name, response.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// uploadProxyURL returns the URL of the upload proxy published by the CDIConfig, with the https scheme
+func (c *client) uploadProxyURL(ctx context.Context, version string) (string, error) {
+	cdiConfigResource := schema.GroupVersionResource{Group: cdiGroupName, Version: version, Resource: "cdiconfigs"}
+	cdiConfig, err := c.dynamicClient.Resource(cdiConfigResource).Get(ctx, cdiConfigName, metav1.GetOptions{})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get CDIConfig")
+	}
+	uploadProxyURL, _, err := unstructured.NestedString(cdiConfig.Object, "status", "uploadProxyURL")
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read the uploadProxyURL of CDIConfig")
+	}
+	if uploadProxyURL == "" {
+		return "", errors.New("CDIConfig doesn't publish the uploadProxyURL of the infra-cluster")
+	}
+	if !strings.Contains(uploadProxyURL, "://") {
+		uploadProxyURL = "https://" + uploadProxyURL
+	}
+	return strings.TrimSuffix(uploadProxyURL, "/"), nil
+}
+
+// requestUploadToken creates an UploadTokenRequest for the PVC, and returns the token the upload proxy authorizes
+// the upload with
+func (c *client) requestUploadToken(ctx context.Context, version string, namespace string, pvcName string) (string, error) {
+	uploadTokenRequestResource := schema.GroupVersionResource{Group: uploadGroupName, Version: version, Resource: "uploadtokenrequests"}
+	tokenRequest := &unstructured.Unstructured{}
+	tokenRequest.SetAPIVersion(uploadTokenRequestResource.GroupVersion().String())
+	tokenRequest.SetKind("UploadTokenRequest")
+	tokenRequest.SetNamespace(namespace)
+	tokenRequest.SetName(pvcName)
+	if err := unstructured.SetNestedField(tokenRequest.Object, pvcName, "spec", "pvcName"); err != nil {
+		return "", err
+	}
+	resp, err := c.dynamicClient.Resource(uploadTokenRequestResource).Namespace(namespace).Create(ctx, tokenRequest, metav1.CreateOptions{})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create UploadTokenRequest")
+	}
+	token, _, err := unstructured.NestedString(resp.Object, "status", "token")
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read the token of UploadTokenRequest")
+	}
+	if token == "" {
+		return "", errors.Errorf("UploadTokenRequest of PVC %s/%s returned no token", namespace, pvcName)
+	}
+	return token, nil
+}
+
+// newUploadClient returns the HTTP client of the upload proxy, which trusts the system roots and the certificate
+// authority of the infra-cluster kubeconfig. The credentials of the kubeconfig aren't sent, the upload is
+// authorized by its token.
+func newUploadClient(restClientConfig *rest.Config) (*http.Client, error) {
+	rootCAs, err := x509.SystemCertPool()
+	if err != nil {
+		rootCAs = x509.NewCertPool()
+	}
+	caData := restClientConfig.CAData
+	if len(caData) == 0 && restClientConfig.CAFile != "" {
+		if caData, err = ioutil.ReadFile(restClientConfig.CAFile); err != nil {
+			return nil, errors.Wrap(err, "failed to read the certificate authority of the infra-cluster")
+		}
+	}
+	rootCAs.AppendCertsFromPEM(caData)
+	return &http.Client{
+		Timeout: uploadTimeout,
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{
+				RootCAs:            rootCAs,
+				InsecureSkipVerify: restClientConfig.Insecure,
+			},
+		},
+	}, nil
+}
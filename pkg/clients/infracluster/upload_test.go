@@ -0,0 +1,100 @@
+package infracluster
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func stubCDIConfig(uploadProxyURL string) *unstructured.Unstructured {
+	cdiConfig := &unstructured.Unstructured{}
+	cdiConfig.SetAPIVersion(cdiGroupName + "/v1beta1")
+	cdiConfig.SetKind("CDIConfig")
+	cdiConfig.SetName(cdiConfigName)
+	if uploadProxyURL != "" {
+		_ = unstructured.SetNestedField(cdiConfig.Object, uploadProxyURL, "status", "uploadProxyURL")
+	}
+	return cdiConfig
+}
+
+func TestUploadDataVolume(t *testing.T) {
+	var uploaded []string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1beta1/upload" || r.Header.Get("Authorization") != "Bearer test-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		uploaded = append(uploaded, string(body))
+	}))
+	defer server.Close()
+
+	cases := []struct {
+		name             string
+		uploadProxyURL   string
+		token            string
+		expectedError    string
+		expectedUploaded []string
+	}{
+		{
+			name:             "upload through the proxy",
+			uploadProxyURL:   strings.TrimPrefix(server.URL, "https://"),
+			token:            "test-token",
+			expectedUploaded: []string{"image"},
+		},
+		{
+			name:          "upload proxy not published",
+			token:         "test-token",
+			expectedError: "CDIConfig doesn't publish the uploadProxyURL of the infra-cluster",
+		},
+		{
+			name:           "no token",
+			uploadProxyURL: server.URL,
+			expectedError:  "UploadTokenRequest of PVC test-namespace/vm-ignition returned no token",
+		},
+		{
+			name:           "upload rejected",
+			uploadProxyURL: server.URL,
+			token:          "expired-token",
+			expectedError:  "failed to upload to DataVolume test-namespace/vm-ignition: 401 Unauthorized: ",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			uploaded = nil
+			dynamicClient := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme(), stubCDIConfig(tc.uploadProxyURL))
+			dynamicClient.PrependReactor("create", "uploadtokenrequests", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				tokenRequest := action.(clienttesting.CreateAction).GetObject().(*unstructured.Unstructured).DeepCopy()
+				assert.Equal(t, tokenRequest.GetAPIVersion(), uploadGroupName+"/v1beta1")
+				pvcName, _, _ := unstructured.NestedString(tokenRequest.Object, "spec", "pvcName")
+				assert.Equal(t, pvcName, "vm-ignition")
+				if tc.token != "" {
+					_ = unstructured.SetNestedField(tokenRequest.Object, tc.token, "status", "token")
+				}
+				return true, tokenRequest, nil
+			})
+			c := &client{
+				dynamicClient: dynamicClient,
+				capabilities:  Capabilities{CDIVersions: []string{"v1beta1"}},
+				uploadClient:  server.Client(),
+			}
+
+			err := c.UploadDataVolume(context.Background(), testNamespace, "vm-ignition", []byte("image"))
+			if tc.expectedError != "" {
+				assert.Error(t, err, tc.expectedError)
+			} else {
+				assert.NilError(t, err)
+			}
+			assert.DeepEqual(t, uploaded, tc.expectedUploaded)
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package infracluster
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+// The resources of the infra-cluster the controllers watch, for Watch and NewInformer. The VirtualMachine resources
+// are of the version the provider is built with, the ones of the version the infra-cluster serves are returned by
+// the Capabilities of the Client.
+var (
+	VirtualMachineResource         = vmResource
+	VirtualMachineInstanceResource = vmiResource
+	SecretResource                 = corev1.SchemeGroupVersion.WithResource("secrets")
+	PodResource                    = corev1.SchemeGroupVersion.WithResource("pods")
+)
+
+// Watch watches the resources of the namespace, all the namespaces when empty, the objects of the returned
+// events are unstructured, or a Status in case of an error event
+func (c *client) Watch(ctx context.Context, resource schema.GroupVersionResource, namespace string, options metav1.ListOptions) (watch.Interface, error) {
+	w, err := c.dynamicClient.Resource(resource).Namespace(namespace).Watch(ctx, options)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to watch %s", resource.Resource)
+	}
+	return w, nil
+}
+
+// NewInformer returns an informer of the resources of the namespace, all the namespaces when empty, which match
+// the label selector. Its objects are unstructured, indexed by namespace. The informer is started by the caller.
+func (c *client) NewInformer(resource schema.GroupVersionResource, namespace string, selector labels.Selector, resyncPeriod time.Duration) cache.SharedIndexInformer {
+	withSelector := func(options *metav1.ListOptions) {
+		if selector != nil {
+			options.LabelSelector = selector.String()
+		}
+	}
+	listWatch := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			withSelector(&options)
+			return c.dynamicClient.Resource(resource).Namespace(namespace).List(context.Background(), options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			withSelector(&options)
+			return c.dynamicClient.Resource(resource).Namespace(namespace).Watch(context.Background(), options)
+		},
+	}
+	return cache.NewSharedIndexInformer(listWatch, &unstructured.Unstructured{}, resyncPeriod,
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+}
@@ -0,0 +1,95 @@
+package infracluster
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/tools/cache"
+	kubevirtapiv1 "kubevirt.io/client-go/api/v1"
+)
+
+func stubUnstructuredVM(namespace string, name string, labels map[string]string) *unstructured.Unstructured {
+	vm := &unstructured.Unstructured{}
+	vm.SetAPIVersion(vmResource.GroupVersion().String())
+	vm.SetKind("VirtualMachine")
+	vm.SetNamespace(namespace)
+	vm.SetName(name)
+	vm.SetLabels(labels)
+	return vm
+}
+
+func newFakeDynamicClient(objects ...runtime.Object) *client {
+	dynamicClient := fakedynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{vmResource: "VirtualMachineList"}, objects...)
+	return &client{dynamicClient: dynamicClient}
+}
+
+func TestNewInformer(t *testing.T) {
+	tenantLabels := map[string]string{"tenant": "a"}
+	c := newFakeDynamicClient(
+		stubUnstructuredVM(testNamespace, "vm-a", tenantLabels),
+		stubUnstructuredVM(testNamespace, "vm-b", map[string]string{"tenant": "b"}),
+		stubUnstructuredVM("other-namespace", "vm-c", tenantLabels),
+	)
+
+	informer := c.NewInformer(VirtualMachineResource, testNamespace, labels.SelectorFromSet(tenantLabels), 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go informer.Run(ctx.Done())
+	assert.Assert(t, cache.WaitForCacheSync(ctx.Done(), informer.HasSynced))
+
+	objs, err := informer.GetIndexer().ByIndex(cache.NamespaceIndex, testNamespace)
+	assert.NilError(t, err)
+	var names []string
+	for _, obj := range objs {
+		names = append(names, obj.(*unstructured.Unstructured).GetName())
+	}
+	sort.Strings(names)
+	assert.DeepEqual(t, names, []string{"vm-a"})
+}
+
+func TestWatch(t *testing.T) {
+	c := newFakeDynamicClient()
+	w, err := c.Watch(context.Background(), VirtualMachineResource, testNamespace, metav1.ListOptions{})
+	assert.NilError(t, err)
+	defer w.Stop()
+
+	_, err = c.dynamicClient.Resource(vmResource).Namespace(testNamespace).Create(context.Background(),
+		stubUnstructuredVM(testNamespace, "vm-a", nil), metav1.CreateOptions{})
+	assert.NilError(t, err)
+	select {
+	case e := <-w.ResultChan():
+		assert.Equal(t, e.Type, watch.Added)
+		assert.Equal(t, e.Object.(*unstructured.Unstructured).GetName(), "vm-a")
+	case <-time.After(10 * time.Second):
+		t.Fatal("no watch event")
+	}
+}
+
+func TestVirtualMachineOfDiscoveredVersion(t *testing.T) {
+	v1Resource := vmResource.GroupResource().WithVersion("v1")
+	dynamicClient := fakedynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{v1Resource: "VirtualMachineList"})
+	c := &client{dynamicClient: dynamicClient, capabilities: Capabilities{KubevirtVersions: []string{"v1"}}}
+
+	vm := &kubevirtapiv1.VirtualMachine{ObjectMeta: metav1.ObjectMeta{Name: "vm-a", Namespace: testNamespace}}
+	vm.Kind = "VirtualMachine"
+	_, err := c.CreateVirtualMachine(context.Background(), testNamespace, vm)
+	assert.NilError(t, err)
+	assert.Equal(t, vm.APIVersion, "kubevirt.io/v1")
+
+	vms, err := c.ListVirtualMachine(context.Background(), testNamespace, metav1.ListOptions{})
+	assert.NilError(t, err)
+	assert.Equal(t, len(vms.Items), 1)
+	assert.Equal(t, vms.Items[0].Name, "vm-a")
+}
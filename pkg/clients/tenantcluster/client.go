@@ -19,13 +19,21 @@ package tenantcluster
 import (
 	"context"
 	"encoding/json"
+	"time"
 
 	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
 
 	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	coordinationv1 "k8s.io/api/coordination/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog"
+	"k8s.io/kubectl/pkg/drain"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 )
@@ -37,8 +45,38 @@ type Client interface {
 	StatusPatchMachine(machine *machinev1.Machine, originMachineCopy *machinev1.Machine) error
 	GetSecret(ctx context.Context, secretName string, namespace string) (*corev1.Secret, error)
 	GetConfigMapValue(ctx context.Context, configMapName, configMapNamespace, configMapDataKeyName string) (*map[string]string, error)
+	CordonAndDrainNode(ctx context.Context, nodeName string) error
+	IsNodeDrained(ctx context.Context, nodeName string) (bool, error)
+	// IsNodeUnschedulable returns true when the node is cordoned, false when it doesn't exist
+	IsNodeUnschedulable(ctx context.Context, nodeName string) (bool, error)
+	UncordonNode(ctx context.Context, nodeName string) error
+	GetMachine(ctx context.Context, name string, namespace string) (*machinev1.Machine, error)
+	ListMachines(ctx context.Context) ([]machinev1.Machine, error)
+	DeleteMachine(ctx context.Context, machine *machinev1.Machine) error
+	GetMachineSet(ctx context.Context, name string, namespace string) (*machinev1.MachineSet, error)
+	ListMachineSets(ctx context.Context) ([]machinev1.MachineSet, error)
+	PatchMachineSet(machineSet *machinev1.MachineSet, originMachineSetCopy *machinev1.MachineSet) error
+	// GetMachineLifecycleHooks returns the lifecycle hooks of the Machine, which block the steps of its deletion
+	GetMachineLifecycleHooks(ctx context.Context, name string, namespace string) (*LifecycleHooks, error)
+	// CreateIPAddressClaim claims an address from the pool for the Machine, the claim is owned by the Machine
+	CreateIPAddressClaim(ctx context.Context, name string, machine *machinev1.Machine, poolRef corev1.TypedLocalObjectReference) error
+	// GetClaimedIPAddress returns the address allocated to the claim, or nil if it isn't allocated yet
+	GetClaimedIPAddress(ctx context.Context, claimName string, namespace string) (*IPAddress, error)
+	DeleteIPAddressClaim(ctx context.Context, claimName string, namespace string) error
+	// GetClusterProxy returns the cluster-wide proxy of the tenant-cluster, or nil when it has none
+	GetClusterProxy(ctx context.Context) (*ClusterProxy, error)
+	// GetLease returns the lease, read from the API server rather than the cache of the manager, which doesn't
+	// watch the leases
+	GetLease(ctx context.Context, name string, namespace string) (*coordinationv1.Lease, error)
 }
 
+const (
+	// drainTimeout bounds the time spent evicting the pods of a single node
+	drainTimeout = 2 * time.Minute
+	// outOfServiceTaintKey marks a node whose workloads may be moved away without a drain
+	outOfServiceTaintKey = "node.kubernetes.io/out-of-service"
+)
+
 type kubeClient struct {
 	kubernetesClient *kubernetes.Clientset
 	runtimeClient    client.Client
@@ -57,10 +95,71 @@ func New(mgr manager.Manager) (Client, error) {
 	}, nil
 }
 
+// NewFromConfig creates the client wrapper object from a tenant-cluster rest config, without the cache of a
+// manager, for the commands which read the tenant-cluster once
+func NewFromConfig(restClientConfig *rest.Config) (Client, error) {
+	kubernetesClient, err := kubernetes.NewForConfig(restClientConfig)
+	if err != nil {
+		return nil, err
+	}
+	if err := machinev1.AddToScheme(scheme.Scheme); err != nil {
+		return nil, err
+	}
+	runtimeClient, err := client.New(restClientConfig, client.Options{Scheme: scheme.Scheme})
+	if err != nil {
+		return nil, err
+	}
+
+	return &kubeClient{
+		kubernetesClient: kubernetesClient,
+		runtimeClient:    runtimeClient,
+	}, nil
+}
+
 func (c *kubeClient) PatchMachine(machine *machinev1.Machine, originMachineCopy *machinev1.Machine) error {
 	return c.runtimeClient.Patch(context.Background(), machine, client.MergeFrom(originMachineCopy))
 }
 
+func (c *kubeClient) GetMachine(ctx context.Context, name string, namespace string) (*machinev1.Machine, error) {
+	machine := machinev1.Machine{}
+	if err := c.runtimeClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, &machine); err != nil {
+		return nil, err
+	}
+	return &machine, nil
+}
+
+func (c *kubeClient) ListMachines(ctx context.Context) ([]machinev1.Machine, error) {
+	machines := machinev1.MachineList{}
+	if err := c.runtimeClient.List(ctx, &machines); err != nil {
+		return nil, err
+	}
+	return machines.Items, nil
+}
+
+func (c *kubeClient) DeleteMachine(ctx context.Context, machine *machinev1.Machine) error {
+	return c.runtimeClient.Delete(ctx, machine)
+}
+
+func (c *kubeClient) GetMachineSet(ctx context.Context, name string, namespace string) (*machinev1.MachineSet, error) {
+	machineSet := machinev1.MachineSet{}
+	if err := c.runtimeClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, &machineSet); err != nil {
+		return nil, err
+	}
+	return &machineSet, nil
+}
+
+func (c *kubeClient) ListMachineSets(ctx context.Context) ([]machinev1.MachineSet, error) {
+	machineSets := machinev1.MachineSetList{}
+	if err := c.runtimeClient.List(ctx, &machineSets); err != nil {
+		return nil, err
+	}
+	return machineSets.Items, nil
+}
+
+func (c *kubeClient) PatchMachineSet(machineSet *machinev1.MachineSet, originMachineSetCopy *machinev1.MachineSet) error {
+	return c.runtimeClient.Patch(context.Background(), machineSet, client.MergeFrom(originMachineSetCopy))
+}
+
 func (c *kubeClient) StatusPatchMachine(machine *machinev1.Machine, originMachineCopy *machinev1.Machine) error {
 	return c.runtimeClient.Status().Patch(context.Background(), machine, client.MergeFrom(originMachineCopy))
 }
@@ -69,6 +168,10 @@ func (c *kubeClient) GetSecret(ctx context.Context, secretName string, namespace
 	return c.kubernetesClient.CoreV1().Secrets(namespace).Get(ctx, secretName, k8smetav1.GetOptions{})
 }
 
+func (c *kubeClient) GetLease(ctx context.Context, name string, namespace string) (*coordinationv1.Lease, error) {
+	return c.kubernetesClient.CoordinationV1().Leases(namespace).Get(ctx, name, k8smetav1.GetOptions{})
+}
+
 func (c *kubeClient) GetConfigMapValue(ctx context.Context,
 	configMapName, configMapNamespace, configMapDataKeyName string) (*map[string]string, error) {
 	configMap, err := c.kubernetesClient.CoreV1().ConfigMaps(configMapNamespace).Get(ctx, configMapName, k8smetav1.GetOptions{})
@@ -87,3 +190,106 @@ func (c *kubeClient) GetConfigMapValue(ctx context.Context,
 	}
 	return &cMap, nil
 }
+
+func (c *kubeClient) CordonAndDrainNode(ctx context.Context, nodeName string) error {
+	node, err := c.kubernetesClient.CoreV1().Nodes().Get(ctx, nodeName, k8smetav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	drainer := &drain.Helper{
+		Ctx:                 ctx,
+		Client:              c.kubernetesClient,
+		Force:               true,
+		IgnoreAllDaemonSets: true,
+		DeleteEmptyDirData:  true,
+		GracePeriodSeconds:  -1,
+		Timeout:             drainTimeout,
+		Out:                 klogWriter{klog.Info},
+		ErrOut:              klogWriter{klog.Warning},
+	}
+	if err := drain.RunCordonOrUncordon(drainer, node, true); err != nil {
+		return err
+	}
+	return drain.RunNodeDrain(drainer, nodeName)
+}
+
+// UncordonNode marks the node schedulable again, it does nothing when the node doesn't exist
+func (c *kubeClient) UncordonNode(ctx context.Context, nodeName string) error {
+	node, err := c.kubernetesClient.CoreV1().Nodes().Get(ctx, nodeName, k8smetav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	drainer := &drain.Helper{
+		Ctx:    ctx,
+		Client: c.kubernetesClient,
+		Out:    klogWriter{klog.Info},
+		ErrOut: klogWriter{klog.Warning},
+	}
+	return drain.RunCordonOrUncordon(drainer, node, false)
+}
+
+func (c *kubeClient) IsNodeUnschedulable(ctx context.Context, nodeName string) (bool, error) {
+	node, err := c.kubernetesClient.CoreV1().Nodes().Get(ctx, nodeName, k8smetav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return node.Spec.Unschedulable, nil
+}
+
+// IsNodeDrained returns true when the node doesn't exist, carries the out-of-service taint,
+// or runs no pods other than DaemonSet and mirror pods
+func (c *kubeClient) IsNodeDrained(ctx context.Context, nodeName string) (bool, error) {
+	node, err := c.kubernetesClient.CoreV1().Nodes().Get(ctx, nodeName, k8smetav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}
+	pods, err := c.kubernetesClient.CoreV1().Pods(corev1.NamespaceAll).List(ctx, k8smetav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", nodeName).String(),
+	})
+	if err != nil {
+		return false, err
+	}
+	return isNodeDrained(node, pods.Items), nil
+}
+
+// isNodeDrained returns true when the node carries the out-of-service taint, or when all the running pods
+// of the node are DaemonSet or mirror pods
+func isNodeDrained(node *corev1.Node, pods []corev1.Pod) bool {
+	for _, taint := range node.Spec.Taints {
+		if taint.Key == outOfServiceTaintKey {
+			return true
+		}
+	}
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		if _, isMirrorPod := pod.Annotations[corev1.MirrorPodAnnotationKey]; isMirrorPod {
+			continue
+		}
+		if controllerRef := k8smetav1.GetControllerOf(&pod); controllerRef != nil && controllerRef.Kind == "DaemonSet" {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// klogWriter forwards the output of the drain helper to klog
+type klogWriter struct {
+	logFunc func(args ...interface{})
+}
+
+func (w klogWriter) Write(p []byte) (int, error) {
+	w.logFunc(string(p))
+	return len(p), nil
+}
@@ -0,0 +1,63 @@
+package tenantcluster
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func stubPod(name string, phase corev1.PodPhase, ownerKind string, annotations map[string]string) corev1.Pod {
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: annotations},
+		Status:     corev1.PodStatus{Phase: phase},
+	}
+	if ownerKind != "" {
+		controller := true
+		pod.OwnerReferences = []metav1.OwnerReference{{Kind: ownerKind, Name: name, Controller: &controller}}
+	}
+	return pod
+}
+
+func TestIsNodeDrained(t *testing.T) {
+	systemPods := []corev1.Pod{
+		stubPod("daemonset-pod", corev1.PodRunning, "DaemonSet", nil),
+		stubPod("mirror-pod", corev1.PodRunning, "", map[string]string{corev1.MirrorPodAnnotationKey: "mirror"}),
+		stubPod("completed-pod", corev1.PodSucceeded, "ReplicaSet", nil),
+	}
+	workloadPod := stubPod("workload-pod", corev1.PodRunning, "ReplicaSet", nil)
+
+	cases := []struct {
+		name           string
+		taints         []corev1.Taint
+		pods           []corev1.Pod
+		expectedResult bool
+	}{
+		{
+			name:           "node runs only system pods",
+			pods:           systemPods,
+			expectedResult: true,
+		},
+		{
+			name:           "node runs a workload",
+			pods:           append(systemPods, workloadPod),
+			expectedResult: false,
+		},
+		{
+			name:           "node with the out-of-service taint runs a workload",
+			taints:         []corev1.Taint{{Key: outOfServiceTaintKey, Value: "nodeshutdown", Effect: corev1.TaintEffectNoExecute}},
+			pods:           append(systemPods, workloadPod),
+			expectedResult: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			node := &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-node"},
+				Spec:       corev1.NodeSpec{Taints: tc.taints},
+			}
+			assert.Equal(t, tc.expectedResult, isNodeDrained(node, tc.pods))
+		})
+	}
+}
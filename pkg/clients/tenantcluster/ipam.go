@@ -0,0 +1,86 @@
+package tenantcluster
+
+import (
+	"context"
+	"fmt"
+
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	ipAddressClaimGVK = schema.GroupVersionKind{Group: "ipam.cluster.x-k8s.io", Version: "v1beta1", Kind: "IPAddressClaim"}
+	ipAddressGVK      = schema.GroupVersionKind{Group: "ipam.cluster.x-k8s.io", Version: "v1beta1", Kind: "IPAddress"}
+)
+
+// IPAddress is an address allocated from a pool of the ipam.cluster.x-k8s.io API
+type IPAddress struct {
+	Address string
+	Prefix  int64
+	Gateway string
+}
+
+func (c *kubeClient) CreateIPAddressClaim(ctx context.Context, name string, machine *machinev1.Machine, poolRef corev1.TypedLocalObjectReference) error {
+	claim := &unstructured.Unstructured{}
+	claim.SetGroupVersionKind(ipAddressClaimGVK)
+	claim.SetName(name)
+	claim.SetNamespace(machine.Namespace)
+	// The claim is released by the garbage collector as well, if the Machine is removed without being deleted by the provider
+	claim.SetOwnerReferences([]k8smetav1.OwnerReference{*k8smetav1.NewControllerRef(machine, machinev1.SchemeGroupVersion.WithKind("Machine"))})
+	apiGroup := ""
+	if poolRef.APIGroup != nil {
+		apiGroup = *poolRef.APIGroup
+	}
+	if err := unstructured.SetNestedMap(claim.Object, map[string]interface{}{
+		"apiGroup": apiGroup,
+		"kind":     poolRef.Kind,
+		"name":     poolRef.Name,
+	}, "spec", "poolRef"); err != nil {
+		return err
+	}
+	return c.runtimeClient.Create(ctx, claim)
+}
+
+func (c *kubeClient) GetClaimedIPAddress(ctx context.Context, claimName string, namespace string) (*IPAddress, error) {
+	claim := &unstructured.Unstructured{}
+	claim.SetGroupVersionKind(ipAddressClaimGVK)
+	if err := c.runtimeClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: claimName}, claim); err != nil {
+		return nil, err
+	}
+	addressName, _, err := unstructured.NestedString(claim.Object, "status", "addressRef", "name")
+	if err != nil {
+		return nil, fmt.Errorf("IPAddressClaim %s/%s: invalid addressRef, with error: %v", namespace, claimName, err)
+	}
+	if addressName == "" {
+		return nil, nil
+	}
+
+	address := &unstructured.Unstructured{}
+	address.SetGroupVersionKind(ipAddressGVK)
+	if err := c.runtimeClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: addressName}, address); err != nil {
+		return nil, err
+	}
+	result := &IPAddress{}
+	if result.Address, _, err = unstructured.NestedString(address.Object, "spec", "address"); err != nil {
+		return nil, fmt.Errorf("IPAddress %s/%s: invalid address, with error: %v", namespace, addressName, err)
+	}
+	if result.Prefix, _, err = unstructured.NestedInt64(address.Object, "spec", "prefix"); err != nil {
+		return nil, fmt.Errorf("IPAddress %s/%s: invalid prefix, with error: %v", namespace, addressName, err)
+	}
+	if result.Gateway, _, err = unstructured.NestedString(address.Object, "spec", "gateway"); err != nil {
+		return nil, fmt.Errorf("IPAddress %s/%s: invalid gateway, with error: %v", namespace, addressName, err)
+	}
+	return result, nil
+}
+
+func (c *kubeClient) DeleteIPAddressClaim(ctx context.Context, claimName string, namespace string) error {
+	claim := &unstructured.Unstructured{}
+	claim.SetGroupVersionKind(ipAddressClaimGVK)
+	claim.SetName(claimName)
+	claim.SetNamespace(namespace)
+	return c.runtimeClient.Delete(ctx, claim)
+}
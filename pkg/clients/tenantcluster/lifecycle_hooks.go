@@ -0,0 +1,53 @@
+package tenantcluster
+
+import (
+	"context"
+	"fmt"
+
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// LifecycleHook is a hook of the spec.lifecycleHooks of a Machine, added by another controller to block a step of
+// the deletion of the Machine until it removes the hook
+type LifecycleHook struct {
+	Name  string `json:"name"`
+	Owner string `json:"owner"`
+}
+
+// String returns the name of the hook, together with its owner
+func (h LifecycleHook) String() string {
+	return fmt.Sprintf("%s (owned by %s)", h.Name, h.Owner)
+}
+
+// LifecycleHooks are the lifecycle hooks of a Machine: the preDrain hooks block the drain of its Node, and the
+// preTerminate hooks block the deletion of its instance. They aren't part of the vendored Machine type, so they are
+// read from the Machine as is.
+type LifecycleHooks struct {
+	PreDrain     []LifecycleHook `json:"preDrain,omitempty"`
+	PreTerminate []LifecycleHook `json:"preTerminate,omitempty"`
+}
+
+func (c *kubeClient) GetMachineLifecycleHooks(ctx context.Context, name string, namespace string) (*LifecycleHooks, error) {
+	machine := &unstructured.Unstructured{}
+	machine.SetGroupVersionKind(machinev1.SchemeGroupVersion.WithKind("Machine"))
+	if err := c.runtimeClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, machine); err != nil {
+		return nil, err
+	}
+	return lifecycleHooksOfMachine(machine)
+}
+
+// lifecycleHooksOfMachine returns the lifecycle hooks of the spec of the Machine
+func lifecycleHooksOfMachine(machine *unstructured.Unstructured) (*LifecycleHooks, error) {
+	hooks := &LifecycleHooks{}
+	rawHooks, found, err := unstructured.NestedMap(machine.Object, "spec", "lifecycleHooks")
+	if err != nil || !found {
+		return hooks, err
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(rawHooks, hooks); err != nil {
+		return nil, fmt.Errorf("Machine %s: invalid lifecycleHooks, with error: %v", machine.GetName(), err)
+	}
+	return hooks, nil
+}
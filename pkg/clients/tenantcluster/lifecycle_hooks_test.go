@@ -0,0 +1,56 @@
+package tenantcluster
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestLifecycleHooksOfMachine(t *testing.T) {
+	cases := []struct {
+		name          string
+		spec          map[string]interface{}
+		expectedHooks LifecycleHooks
+		expectedErr   bool
+	}{
+		{
+			name: "no lifecycle hooks",
+			spec: map[string]interface{}{},
+		},
+		{
+			name: "preDrain and preTerminate hooks",
+			spec: map[string]interface{}{
+				"lifecycleHooks": map[string]interface{}{
+					"preDrain": []interface{}{
+						map[string]interface{}{"name": "migrate-storage", "owner": "storage-operator"},
+					},
+					"preTerminate": []interface{}{
+						map[string]interface{}{"name": "backup", "owner": "backup-operator"},
+					},
+				},
+			},
+			expectedHooks: LifecycleHooks{
+				PreDrain:     []LifecycleHook{{Name: "migrate-storage", Owner: "storage-operator"}},
+				PreTerminate: []LifecycleHook{{Name: "backup", Owner: "backup-operator"}},
+			},
+		},
+		{
+			name:        "invalid lifecycle hooks",
+			spec:        map[string]interface{}{"lifecycleHooks": "backup"},
+			expectedErr: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			machine := &unstructured.Unstructured{Object: map[string]interface{}{"spec": tc.spec}}
+			hooks, err := lifecycleHooksOfMachine(machine)
+			if tc.expectedErr {
+				assert.Assert(t, err != nil)
+				return
+			}
+			assert.NilError(t, err)
+			assert.DeepEqual(t, *hooks, tc.expectedHooks)
+		})
+	}
+}
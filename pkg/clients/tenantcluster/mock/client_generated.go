@@ -7,8 +7,10 @@ package mock
 import (
 	context "context"
 	gomock "github.com/golang/mock/gomock"
+	tenantcluster "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster"
 	v1beta1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
-	v1 "k8s.io/api/core/v1"
+	v1 "k8s.io/api/coordination/v1"
+	v10 "k8s.io/api/core/v1"
 	reflect "reflect"
 )
 
@@ -64,10 +66,10 @@ func (mr *MockClientMockRecorder) StatusPatchMachine(machine, originMachineCopy
 }
 
 // GetSecret mocks base method
-func (m *MockClient) GetSecret(ctx context.Context, secretName, namespace string) (*v1.Secret, error) {
+func (m *MockClient) GetSecret(ctx context.Context, secretName, namespace string) (*v10.Secret, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetSecret", ctx, secretName, namespace)
-	ret0, _ := ret[0].(*v1.Secret)
+	ret0, _ := ret[0].(*v10.Secret)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -92,3 +94,237 @@ func (mr *MockClientMockRecorder) GetConfigMapValue(ctx, configMapName, configMa
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConfigMapValue", reflect.TypeOf((*MockClient)(nil).GetConfigMapValue), ctx, configMapName, configMapNamespace, configMapDataKeyName)
 }
+
+// CordonAndDrainNode mocks base method
+func (m *MockClient) CordonAndDrainNode(ctx context.Context, nodeName string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CordonAndDrainNode", ctx, nodeName)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CordonAndDrainNode indicates an expected call of CordonAndDrainNode
+func (mr *MockClientMockRecorder) CordonAndDrainNode(ctx, nodeName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CordonAndDrainNode", reflect.TypeOf((*MockClient)(nil).CordonAndDrainNode), ctx, nodeName)
+}
+
+// IsNodeDrained mocks base method
+func (m *MockClient) IsNodeDrained(ctx context.Context, nodeName string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsNodeDrained", ctx, nodeName)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsNodeDrained indicates an expected call of IsNodeDrained
+func (mr *MockClientMockRecorder) IsNodeDrained(ctx, nodeName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsNodeDrained", reflect.TypeOf((*MockClient)(nil).IsNodeDrained), ctx, nodeName)
+}
+
+// IsNodeUnschedulable mocks base method
+func (m *MockClient) IsNodeUnschedulable(ctx context.Context, nodeName string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsNodeUnschedulable", ctx, nodeName)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsNodeUnschedulable indicates an expected call of IsNodeUnschedulable
+func (mr *MockClientMockRecorder) IsNodeUnschedulable(ctx, nodeName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsNodeUnschedulable", reflect.TypeOf((*MockClient)(nil).IsNodeUnschedulable), ctx, nodeName)
+}
+
+// UncordonNode mocks base method
+func (m *MockClient) UncordonNode(ctx context.Context, nodeName string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UncordonNode", ctx, nodeName)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UncordonNode indicates an expected call of UncordonNode
+func (mr *MockClientMockRecorder) UncordonNode(ctx, nodeName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UncordonNode", reflect.TypeOf((*MockClient)(nil).UncordonNode), ctx, nodeName)
+}
+
+// GetMachine mocks base method
+func (m *MockClient) GetMachine(ctx context.Context, name, namespace string) (*v1beta1.Machine, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMachine", ctx, name, namespace)
+	ret0, _ := ret[0].(*v1beta1.Machine)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMachine indicates an expected call of GetMachine
+func (mr *MockClientMockRecorder) GetMachine(ctx, name, namespace interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMachine", reflect.TypeOf((*MockClient)(nil).GetMachine), ctx, name, namespace)
+}
+
+// ListMachines mocks base method
+func (m *MockClient) ListMachines(ctx context.Context) ([]v1beta1.Machine, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListMachines", ctx)
+	ret0, _ := ret[0].([]v1beta1.Machine)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListMachines indicates an expected call of ListMachines
+func (mr *MockClientMockRecorder) ListMachines(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListMachines", reflect.TypeOf((*MockClient)(nil).ListMachines), ctx)
+}
+
+// DeleteMachine mocks base method
+func (m *MockClient) DeleteMachine(ctx context.Context, machine *v1beta1.Machine) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteMachine", ctx, machine)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteMachine indicates an expected call of DeleteMachine
+func (mr *MockClientMockRecorder) DeleteMachine(ctx, machine interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteMachine", reflect.TypeOf((*MockClient)(nil).DeleteMachine), ctx, machine)
+}
+
+// GetMachineSet mocks base method
+func (m *MockClient) GetMachineSet(ctx context.Context, name, namespace string) (*v1beta1.MachineSet, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMachineSet", ctx, name, namespace)
+	ret0, _ := ret[0].(*v1beta1.MachineSet)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMachineSet indicates an expected call of GetMachineSet
+func (mr *MockClientMockRecorder) GetMachineSet(ctx, name, namespace interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMachineSet", reflect.TypeOf((*MockClient)(nil).GetMachineSet), ctx, name, namespace)
+}
+
+// ListMachineSets mocks base method
+func (m *MockClient) ListMachineSets(ctx context.Context) ([]v1beta1.MachineSet, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListMachineSets", ctx)
+	ret0, _ := ret[0].([]v1beta1.MachineSet)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListMachineSets indicates an expected call of ListMachineSets
+func (mr *MockClientMockRecorder) ListMachineSets(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListMachineSets", reflect.TypeOf((*MockClient)(nil).ListMachineSets), ctx)
+}
+
+// PatchMachineSet mocks base method
+func (m *MockClient) PatchMachineSet(machineSet, originMachineSetCopy *v1beta1.MachineSet) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PatchMachineSet", machineSet, originMachineSetCopy)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PatchMachineSet indicates an expected call of PatchMachineSet
+func (mr *MockClientMockRecorder) PatchMachineSet(machineSet, originMachineSetCopy interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PatchMachineSet", reflect.TypeOf((*MockClient)(nil).PatchMachineSet), machineSet, originMachineSetCopy)
+}
+
+// GetMachineLifecycleHooks mocks base method
+func (m *MockClient) GetMachineLifecycleHooks(ctx context.Context, name, namespace string) (*tenantcluster.LifecycleHooks, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMachineLifecycleHooks", ctx, name, namespace)
+	ret0, _ := ret[0].(*tenantcluster.LifecycleHooks)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMachineLifecycleHooks indicates an expected call of GetMachineLifecycleHooks
+func (mr *MockClientMockRecorder) GetMachineLifecycleHooks(ctx, name, namespace interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMachineLifecycleHooks", reflect.TypeOf((*MockClient)(nil).GetMachineLifecycleHooks), ctx, name, namespace)
+}
+
+// CreateIPAddressClaim mocks base method
+func (m *MockClient) CreateIPAddressClaim(ctx context.Context, name string, machine *v1beta1.Machine, poolRef v10.TypedLocalObjectReference) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateIPAddressClaim", ctx, name, machine, poolRef)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateIPAddressClaim indicates an expected call of CreateIPAddressClaim
+func (mr *MockClientMockRecorder) CreateIPAddressClaim(ctx, name, machine, poolRef interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateIPAddressClaim", reflect.TypeOf((*MockClient)(nil).CreateIPAddressClaim), ctx, name, machine, poolRef)
+}
+
+// GetClaimedIPAddress mocks base method
+func (m *MockClient) GetClaimedIPAddress(ctx context.Context, claimName, namespace string) (*tenantcluster.IPAddress, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetClaimedIPAddress", ctx, claimName, namespace)
+	ret0, _ := ret[0].(*tenantcluster.IPAddress)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetClaimedIPAddress indicates an expected call of GetClaimedIPAddress
+func (mr *MockClientMockRecorder) GetClaimedIPAddress(ctx, claimName, namespace interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetClaimedIPAddress", reflect.TypeOf((*MockClient)(nil).GetClaimedIPAddress), ctx, claimName, namespace)
+}
+
+// DeleteIPAddressClaim mocks base method
+func (m *MockClient) DeleteIPAddressClaim(ctx context.Context, claimName, namespace string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteIPAddressClaim", ctx, claimName, namespace)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteIPAddressClaim indicates an expected call of DeleteIPAddressClaim
+func (mr *MockClientMockRecorder) DeleteIPAddressClaim(ctx, claimName, namespace interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteIPAddressClaim", reflect.TypeOf((*MockClient)(nil).DeleteIPAddressClaim), ctx, claimName, namespace)
+}
+
+// GetClusterProxy mocks base method
+func (m *MockClient) GetClusterProxy(ctx context.Context) (*tenantcluster.ClusterProxy, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetClusterProxy", ctx)
+	ret0, _ := ret[0].(*tenantcluster.ClusterProxy)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetClusterProxy indicates an expected call of GetClusterProxy
+func (mr *MockClientMockRecorder) GetClusterProxy(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetClusterProxy", reflect.TypeOf((*MockClient)(nil).GetClusterProxy), ctx)
+}
+
+// GetLease mocks base method
+func (m *MockClient) GetLease(ctx context.Context, name, namespace string) (*v1.Lease, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLease", ctx, name, namespace)
+	ret0, _ := ret[0].(*v1.Lease)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLease indicates an expected call of GetLease
+func (mr *MockClientMockRecorder) GetLease(ctx, name, namespace interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLease", reflect.TypeOf((*MockClient)(nil).GetLease), ctx, name, namespace)
+}
@@ -0,0 +1,66 @@
+package tenantcluster
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// clusterProxyName is the name of the cluster-wide proxy of an OpenShift cluster
+	clusterProxyName = "cluster"
+	// trustedCANamespace is the namespace of the configMap holding the additional trust bundle of the proxy
+	trustedCANamespace = "openshift-config"
+	// trustedCABundleKey is the key of the additional trust bundle in its configMap
+	trustedCABundleKey = "ca-bundle.crt"
+)
+
+var proxyGVK = schema.GroupVersionKind{Group: "config.openshift.io", Version: "v1", Kind: "Proxy"}
+
+// ClusterProxy is the cluster-wide proxy of the tenant-cluster, as observed by its status, together with the
+// additional trust bundle its spec references
+type ClusterProxy struct {
+	HTTPProxy   string
+	HTTPSProxy  string
+	NoProxy     string
+	TrustBundle string
+}
+
+func (c *kubeClient) GetClusterProxy(ctx context.Context) (*ClusterProxy, error) {
+	proxy := &unstructured.Unstructured{}
+	proxy.SetGroupVersionKind(proxyGVK)
+	if err := c.runtimeClient.Get(ctx, client.ObjectKey{Name: clusterProxyName}, proxy); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	result := &ClusterProxy{}
+	var err error
+	if result.HTTPProxy, _, err = unstructured.NestedString(proxy.Object, "status", "httpProxy"); err != nil {
+		return nil, fmt.Errorf("Proxy %s: invalid httpProxy, with error: %v", clusterProxyName, err)
+	}
+	if result.HTTPSProxy, _, err = unstructured.NestedString(proxy.Object, "status", "httpsProxy"); err != nil {
+		return nil, fmt.Errorf("Proxy %s: invalid httpsProxy, with error: %v", clusterProxyName, err)
+	}
+	if result.NoProxy, _, err = unstructured.NestedString(proxy.Object, "status", "noProxy"); err != nil {
+		return nil, fmt.Errorf("Proxy %s: invalid noProxy, with error: %v", clusterProxyName, err)
+	}
+	trustedCAName, _, err := unstructured.NestedString(proxy.Object, "spec", "trustedCA", "name")
+	if err != nil {
+		return nil, fmt.Errorf("Proxy %s: invalid trustedCA, with error: %v", clusterProxyName, err)
+	}
+	if trustedCAName != "" {
+		configMap, err := c.kubernetesClient.CoreV1().ConfigMaps(trustedCANamespace).Get(ctx, trustedCAName, k8smetav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		result.TrustBundle = configMap.Data[trustedCABundleKey]
+	}
+	return result, nil
+}
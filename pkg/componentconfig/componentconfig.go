@@ -0,0 +1,174 @@
+// componentconfig package loads the configuration file of the kubevirt machine controller, which consolidates
+// the flags of its behavior in a single YAML document:
+//
+//	apiVersion: kubevirt.machine.openshift.io/v1alpha1
+//	kind: ControllerConfiguration
+//	requeueAfter: 20s
+//	featureGates:
+//	  CreateInfraNamespace: true
+//
+// The configuration sets the flags which aren't set on the command line, so the flags keep precedence.
+package componentconfig
+
+import (
+	"crypto/sha256"
+	goerrors "errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// APIVersion and Kind identify the configuration file
+	APIVersion = "kubevirt.machine.openshift.io/v1alpha1"
+	Kind       = "ControllerConfiguration"
+)
+
+// featureGateFlags are the boolean flags which the feature gates of the configuration set, by feature
+var featureGateFlags = map[string]string{
+	"CreateInfraNamespace": "create-infra-namespace",
+	"InfraMaintenance":     "infra-maintenance",
+}
+
+// ControllerConfiguration is the configuration file of the controller. A zero field leaves its flag as is.
+type ControllerConfiguration struct {
+	metav1.TypeMeta `json:",inline"`
+	// RequeueAfter is the delay before re-checking an operation which is still in progress in the infra-cluster
+	RequeueAfter metav1.Duration `json:"requeueAfter,omitempty"`
+	// VMNotReadyRequeueAfter is the delay before re-checking a node whose VirtualMachine isn't ready yet
+	VMNotReadyRequeueAfter metav1.Duration `json:"vmNotReadyRequeueAfter,omitempty"`
+	// FeatureGates enable or disable the optional features of the controller, by name
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+	// Concurrency limits the creations of VirtualMachines in the infra-cluster
+	Concurrency ConcurrencyConfiguration `json:"concurrency,omitempty"`
+	// InfraCredentialsSecret is the secret of the tenant-cluster holding the kubeconfig of the infra-cluster
+	InfraCredentialsSecret SecretReference `json:"infraCredentialsSecret,omitempty"`
+	// Metrics configure the metrics endpoint
+	Metrics MetricsConfiguration `json:"metrics,omitempty"`
+	// VMMetadata filters the labels and annotations of the Machines copied onto their VirtualMachine
+	VMMetadata VMMetadataConfiguration `json:"vmMetadata,omitempty"`
+}
+
+// ConcurrencyConfiguration limits the creations of VirtualMachines, zero is unlimited
+type ConcurrencyConfiguration struct {
+	MaxConcurrentVMCreations             int `json:"maxConcurrentVMCreations,omitempty"`
+	MaxConcurrentVMCreationsPerNamespace int `json:"maxConcurrentVMCreationsPerNamespace,omitempty"`
+	MaxPendingClones                     int `json:"maxPendingClones,omitempty"`
+}
+
+// SecretReference references a secret by name and namespace
+type SecretReference struct {
+	Name      string `json:"name,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// MetricsConfiguration configure the metrics endpoint, as the metrics flags
+type MetricsConfiguration struct {
+	BindAddress string `json:"bindAddress,omitempty"`
+	TLSCertFile string `json:"tlsCertFile,omitempty"`
+	TLSKeyFile  string `json:"tlsKeyFile,omitempty"`
+	AuthnAuthz  *bool  `json:"authnAuthz,omitempty"`
+}
+
+// VMMetadataConfiguration filters the labels and annotations of the Machines copied onto their VirtualMachine, as the
+// vm-metadata flags, zero is unlimited
+type VMMetadataConfiguration struct {
+	AllowedPrefixes []string `json:"allowedPrefixes,omitempty"`
+	DeniedPrefixes  []string `json:"deniedPrefixes,omitempty"`
+	MaxValueLength  int      `json:"maxValueLength,omitempty"`
+	MaxSize         int      `json:"maxSize,omitempty"`
+}
+
+// Load reads and validates the configuration file. It also returns the digest of the file, to detect its changes.
+func Load(path string) (*ControllerConfiguration, [sha256.Size]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, [sha256.Size]byte{}, fmt.Errorf("failed to read the configuration file %s, with error: %v", path, err)
+	}
+	config := &ControllerConfiguration{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, [sha256.Size]byte{}, fmt.Errorf("failed to parse the configuration file %s, with error: %v", path, err)
+	}
+	if err := config.validate(); err != nil {
+		return nil, [sha256.Size]byte{}, fmt.Errorf("invalid configuration file %s: %v", path, err)
+	}
+	return config, sha256.Sum256(data), nil
+}
+
+func (c *ControllerConfiguration) validate() error {
+	if c.APIVersion != APIVersion || c.Kind != Kind {
+		return fmt.Errorf("expected %s %s, got %s %s", APIVersion, Kind, c.APIVersion, c.Kind)
+	}
+	if c.RequeueAfter.Duration < 0 || c.VMNotReadyRequeueAfter.Duration < 0 {
+		return goerrors.New("the requeue durations can't be negative")
+	}
+	if c.Concurrency.MaxConcurrentVMCreations < 0 || c.Concurrency.MaxConcurrentVMCreationsPerNamespace < 0 ||
+		c.Concurrency.MaxPendingClones < 0 {
+		return goerrors.New("the concurrency limits can't be negative")
+	}
+	if c.VMMetadata.MaxValueLength < 0 || c.VMMetadata.MaxSize < 0 {
+		return goerrors.New("the vm metadata limits can't be negative")
+	}
+	for gate := range c.FeatureGates {
+		if _, ok := featureGateFlags[gate]; !ok {
+			return fmt.Errorf("unknown feature gate %q, expected one of %s", gate, strings.Join(knownFeatureGates(), ", "))
+		}
+	}
+	return nil
+}
+
+func knownFeatureGates() []string {
+	gates := make([]string, 0, len(featureGateFlags))
+	for gate := range featureGateFlags {
+		gates = append(gates, gate)
+	}
+	sort.Strings(gates)
+	return gates
+}
+
+// Flags returns the values of the flags set by the configuration, by flag name
+func (c *ControllerConfiguration) Flags() map[string]string {
+	flags := map[string]string{}
+	setDuration := func(name string, d metav1.Duration) {
+		if d.Duration != 0 {
+			flags[name] = d.Duration.String()
+		}
+	}
+	setInt := func(name string, value int) {
+		if value != 0 {
+			flags[name] = strconv.Itoa(value)
+		}
+	}
+	setString := func(name string, value string) {
+		if value != "" {
+			flags[name] = value
+		}
+	}
+
+	setDuration("requeue-after", c.RequeueAfter)
+	setDuration("vm-not-ready-requeue-after", c.VMNotReadyRequeueAfter)
+	for gate, enabled := range c.FeatureGates {
+		flags[featureGateFlags[gate]] = strconv.FormatBool(enabled)
+	}
+	setInt("max-concurrent-vm-creations", c.Concurrency.MaxConcurrentVMCreations)
+	setInt("max-concurrent-vm-creations-per-namespace", c.Concurrency.MaxConcurrentVMCreationsPerNamespace)
+	setInt("max-pending-clones", c.Concurrency.MaxPendingClones)
+	setString("infra-credentials-secret-name", c.InfraCredentialsSecret.Name)
+	setString("infra-credentials-secret-namespace", c.InfraCredentialsSecret.Namespace)
+	setString("metrics-addr", c.Metrics.BindAddress)
+	setString("metrics-tls-cert-file", c.Metrics.TLSCertFile)
+	setString("metrics-tls-key-file", c.Metrics.TLSKeyFile)
+	setString("vm-metadata-allowed-prefixes", strings.Join(c.VMMetadata.AllowedPrefixes, ","))
+	setString("vm-metadata-denied-prefixes", strings.Join(c.VMMetadata.DeniedPrefixes, ","))
+	setInt("vm-metadata-max-value-length", c.VMMetadata.MaxValueLength)
+	setInt("vm-metadata-max-size", c.VMMetadata.MaxSize)
+	if c.Metrics.AuthnAuthz != nil {
+		flags["metrics-authn-authz"] = strconv.FormatBool(*c.Metrics.AuthnAuthz)
+	}
+	return flags
+}
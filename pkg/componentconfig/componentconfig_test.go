@@ -0,0 +1,152 @@
+package componentconfig
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func writeConfigFile(t *testing.T, path string, content string) {
+	assert.NilError(t, ioutil.WriteFile(path, []byte(content), 0600))
+}
+
+func TestLoad(t *testing.T) {
+	cases := []struct {
+		name          string
+		content       string
+		expectedFlags map[string]string
+		expectedErr   string
+	}{
+		{
+			name: "every option",
+			content: `apiVersion: kubevirt.machine.openshift.io/v1alpha1
+kind: ControllerConfiguration
+requeueAfter: 30s
+vmNotReadyRequeueAfter: 2m
+featureGates:
+  CreateInfraNamespace: true
+  InfraMaintenance: false
+concurrency:
+  maxConcurrentVMCreations: 10
+  maxPendingClones: 5
+infraCredentialsSecret:
+  name: infra-kubeconfig
+  namespace: kubevirt-machines
+metrics:
+  bindAddress: ":8443"
+  tlsCertFile: /etc/tls/tls.crt
+  tlsKeyFile: /etc/tls/tls.key
+  authnAuthz: true
+vmMetadata:
+  allowedPrefixes:
+  - machine.openshift.io/
+  - example.com/
+  deniedPrefixes:
+  - kubectl.kubernetes.io/
+  maxValueLength: 256
+  maxSize: 4096
+`,
+			expectedFlags: map[string]string{
+				"requeue-after":                      "30s",
+				"vm-not-ready-requeue-after":         "2m0s",
+				"create-infra-namespace":             "true",
+				"infra-maintenance":                  "false",
+				"max-concurrent-vm-creations":        "10",
+				"max-pending-clones":                 "5",
+				"infra-credentials-secret-name":      "infra-kubeconfig",
+				"infra-credentials-secret-namespace": "kubevirt-machines",
+				"metrics-addr":                       ":8443",
+				"metrics-tls-cert-file":              "/etc/tls/tls.crt",
+				"metrics-tls-key-file":               "/etc/tls/tls.key",
+				"metrics-authn-authz":                "true",
+				"vm-metadata-allowed-prefixes":       "machine.openshift.io/,example.com/",
+				"vm-metadata-denied-prefixes":        "kubectl.kubernetes.io/",
+				"vm-metadata-max-value-length":       "256",
+				"vm-metadata-max-size":               "4096",
+			},
+		},
+		{
+			name: "no option",
+			content: `apiVersion: kubevirt.machine.openshift.io/v1alpha1
+kind: ControllerConfiguration
+`,
+			expectedFlags: map[string]string{},
+		},
+		{
+			name: "unknown kind",
+			content: `apiVersion: kubevirt.machine.openshift.io/v1alpha1
+kind: KubeletConfiguration
+`,
+			expectedErr: "expected kubevirt.machine.openshift.io/v1alpha1 ControllerConfiguration, got kubevirt.machine.openshift.io/v1alpha1 KubeletConfiguration",
+		},
+		{
+			name: "unknown field",
+			content: `apiVersion: kubevirt.machine.openshift.io/v1alpha1
+kind: ControllerConfiguration
+requeue: 30s
+`,
+			expectedErr: `unknown field "requeue"`,
+		},
+		{
+			name: "unknown feature gate",
+			content: `apiVersion: kubevirt.machine.openshift.io/v1alpha1
+kind: ControllerConfiguration
+featureGates:
+  WarmPool: true
+`,
+			expectedErr: `unknown feature gate "WarmPool", expected one of CreateInfraNamespace, InfraMaintenance`,
+		},
+		{
+			name: "negative concurrency",
+			content: `apiVersion: kubevirt.machine.openshift.io/v1alpha1
+kind: ControllerConfiguration
+concurrency:
+  maxPendingClones: -1
+`,
+			expectedErr: "the concurrency limits can't be negative",
+		},
+		{
+			name: "negative vm metadata limit",
+			content: `apiVersion: kubevirt.machine.openshift.io/v1alpha1
+kind: ControllerConfiguration
+vmMetadata:
+  maxSize: -1
+`,
+			expectedErr: "the vm metadata limits can't be negative",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			writeConfigFile(t, path, tc.content)
+
+			config, _, err := Load(path)
+			if tc.expectedErr != "" {
+				assert.ErrorContains(t, err, tc.expectedErr)
+				return
+			}
+			assert.NilError(t, err)
+			assert.DeepEqual(t, config.Flags(), tc.expectedFlags)
+		})
+	}
+}
+
+func TestReloader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfigFile(t, path, "apiVersion: kubevirt.machine.openshift.io/v1alpha1\nkind: ControllerConfiguration\n")
+	_, digest, err := Load(path)
+	assert.NilError(t, err)
+
+	r := &reloader{path: path, digest: digest, interval: 10 * time.Millisecond}
+	assert.Assert(t, !r.changed())
+
+	writeConfigFile(t, path, "apiVersion: kubevirt.machine.openshift.io/v1alpha1\nkind: ControllerConfiguration\nrequeueAfter: 1m\n")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = r.Start(ctx)
+	assert.Assert(t, IsChanged(err))
+}
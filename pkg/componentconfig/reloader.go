@@ -0,0 +1,71 @@
+package componentconfig
+
+import (
+	"context"
+	"crypto/sha256"
+	goerrors "errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// ChangedError is returned by the manager once the configuration file changed: the controller exits to be
+// restarted with the new configuration, as most of the configuration is only read when the controller starts
+type ChangedError struct {
+	Path string
+}
+
+func (e *ChangedError) Error() string {
+	return fmt.Sprintf("configuration file %s changed, restarting to apply it", e.Path)
+}
+
+// IsChanged returns true when the manager stopped since the configuration file changed
+func IsChanged(err error) bool {
+	var changedErr *ChangedError
+	return goerrors.As(err, &changedErr)
+}
+
+// reloader checks the configuration file for changes, e.g. once its configMap is updated
+type reloader struct {
+	path     string
+	digest   [sha256.Size]byte
+	interval time.Duration
+}
+
+// AddReloader adds the runnable which stops the manager with a ChangedError once the configuration file,
+// loaded with the digest, changes. A file which can't be read is only logged, as during the update of a configMap.
+func AddReloader(mgr manager.Manager, path string, digest [sha256.Size]byte, interval time.Duration) error {
+	return mgr.Add(&reloader{path: path, digest: digest, interval: interval})
+}
+
+func (r *reloader) Start(ctx context.Context) error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if r.changed() {
+				return &ChangedError{Path: r.path}
+			}
+		}
+	}
+}
+
+// NeedLeaderElection returns false, every replica applies the new configuration
+func (r *reloader) NeedLeaderElection() bool {
+	return false
+}
+
+func (r *reloader) changed() bool {
+	data, err := ioutil.ReadFile(r.path)
+	if err != nil {
+		klog.Errorf("failed to read the configuration file %s, with error: %v", r.path, err)
+		return false
+	}
+	return sha256.Sum256(data) != r.digest
+}
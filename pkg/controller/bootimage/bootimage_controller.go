@@ -0,0 +1,242 @@
+// bootimage package implements a controller to coordinate the rollout of a new golden image to the Machines:
+// - Read the image of the source PVC of every kubevirt MachineSet, from its image digest annotation or its UID
+// - Record the image on the MachineSet, and on its Machines the image they were cloned from
+// - Mark the Machines cloned from another image, and their MachineSet, as boot image outdated
+// - Replace the outdated Machines one at a time, for the MachineSets which opt in with the rollout annotation
+// A golden image refreshed by a DataImportCron lands as a new source PVC, or as a new digest of the same PVC.
+// The source PVC of a MachineSet cloning a CDI DataSource is the PVC the DataSource currently points to.
+package bootimage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
+)
+
+const (
+	configMapNamespace             = "openshift-config"
+	configMapName                  = "cloud-provider-config"
+	configMapDataKeyName           = "config"
+	configMapInfraNamespaceKeyName = "namespace"
+
+	// BootImageAnnotation is the image of the source PVC, on a MachineSet the latest one and on a Machine
+	// the one its boot volume was cloned from
+	BootImageAnnotation = "kubevirt.machine.openshift.io/boot-image"
+	// BootImageOutdatedAnnotation marks the Machines cloned from an image older than the latest one of their
+	// MachineSet, and the MachineSets which have such Machines
+	BootImageOutdatedAnnotation = "kubevirt.machine.openshift.io/boot-image-outdated"
+	// BootImageRolloutAnnotation opts a MachineSet in to the replacement of its outdated Machines, when "true"
+	BootImageRolloutAnnotation = "kubevirt.machine.openshift.io/boot-image-rollout"
+
+	machineRunningPhase = "Running"
+)
+
+var _ manager.Runnable = &bootImageReconciler{}
+
+type bootImageReconciler struct {
+	infraClusterClient  infracluster.Client
+	tenantClusterClient tenantcluster.Client
+	pollInterval        time.Duration
+}
+
+// Start coordinates the rollout of the golden images until the context is done
+func (r *bootImageReconciler) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := r.Reconcile(ctx); err != nil {
+			klog.Errorf("boot image: %v", err)
+		}
+	}, r.pollInterval)
+	return nil
+}
+
+// Reconcile marks the Machines cloned from an outdated image of every kubevirt MachineSet, and replaces one
+// of them for the MachineSets which opt in to the rollout
+func (r *bootImageReconciler) Reconcile(ctx context.Context) error {
+	cMap, err := r.tenantClusterClient.GetConfigMapValue(ctx, configMapName, configMapNamespace, configMapDataKeyName)
+	if err != nil {
+		return err
+	}
+	infraNamespace, ok := (*cMap)[configMapInfraNamespaceKeyName]
+	if !ok {
+		return fmt.Errorf("configMap %s/%s: The map extracted with key %s doesn't contain key %s",
+			configMapNamespace, configMapName, configMapDataKeyName, configMapInfraNamespaceKeyName)
+	}
+
+	machineSets, err := r.tenantClusterClient.ListMachineSets(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list MachineSets, with error: %v", err)
+	}
+	machines, err := r.tenantClusterClient.ListMachines(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list Machines, with error: %v", err)
+	}
+	machinesOfMachineSet := map[string][]*machinev1.Machine{}
+	for i := range machines {
+		owner := metav1.GetControllerOf(&machines[i])
+		if owner == nil || owner.Kind != "MachineSet" {
+			continue
+		}
+		key := machines[i].Namespace + "/" + owner.Name
+		machinesOfMachineSet[key] = append(machinesOfMachineSet[key], &machines[i])
+	}
+
+	images := map[string]string{}
+	for i := range machineSets {
+		machineSet := &machineSets[i]
+		providerSpec, err := kubevirtproviderv1alpha1.ProviderSpecFromRawExtension(machineSet.Spec.Template.Spec.ProviderSpec.Value)
+		if err != nil {
+			klog.Errorf("%s: failed to get the provider spec of the MachineSet, with error: %v", machineSet.Name, err)
+			continue
+		}
+		// The VirtualMachines of a pool or pre-staged by name aren't cloned by the Machines
+		if providerSpec.VirtualMachinePoolName != "" || providerSpec.ExistingVMName != "" {
+			continue
+		}
+		sourcePvcNamespace, sourcePvcName := infraNamespace, providerSpec.SourcePvcName
+		if providerSpec.DataSourceName != "" {
+			dataSourceNamespace := providerSpec.DataSourceNamespace
+			if dataSourceNamespace == "" {
+				dataSourceNamespace = infraNamespace
+			}
+			sourcePvc, err := r.infraClusterClient.GetDataSourcePVC(ctx, dataSourceNamespace, providerSpec.DataSourceName)
+			if err != nil {
+				klog.Errorf("%s: failed to get the DataSource %s of the MachineSet, with error: %v", machineSet.Name, providerSpec.DataSourceName, err)
+				continue
+			}
+			sourcePvcNamespace, sourcePvcName = sourcePvc.Namespace, sourcePvc.Name
+		}
+		if sourcePvcName == "" || machinescope.IsTemplate(sourcePvcName) {
+			continue
+		}
+		key := sourcePvcNamespace + "/" + sourcePvcName
+		image, ok := images[key]
+		if !ok {
+			if image, err = r.sourceImage(ctx, sourcePvcNamespace, sourcePvcName); err != nil {
+				klog.Errorf("%s: failed to get the source PVC %s of the MachineSet, with error: %v", machineSet.Name, key, err)
+				continue
+			}
+			images[key] = image
+		}
+		r.reconcileMachineSet(ctx, machineSet, machinesOfMachineSet[machineSet.Namespace+"/"+machineSet.Name], image)
+	}
+	return nil
+}
+
+// sourceImage returns the image of the source PVC: its image digest when annotated, its UID otherwise
+func (r *bootImageReconciler) sourceImage(ctx context.Context, namespace string, sourcePvcName string) (string, error) {
+	sourcePvc, err := r.infraClusterClient.GetPersistentVolumeClaim(ctx, namespace, sourcePvcName)
+	if err != nil {
+		return "", err
+	}
+	if digest := sourcePvc.Annotations[machinescope.SourceImageDigestAnnotation]; digest != "" {
+		return digest, nil
+	}
+	return string(sourcePvc.UID), nil
+}
+
+// reconcileMachineSet records the latest image on the MachineSet and marks its outdated Machines
+func (r *bootImageReconciler) reconcileMachineSet(ctx context.Context, machineSet *machinev1.MachineSet, machines []*machinev1.Machine, image string) {
+	// A Machine seen for the first time was cloned from the image recorded on its MachineSet by the previous
+	// reconcile. A Machine created since an image change is thus replaced once more, but never left outdated.
+	recordedImage := machineSet.Annotations[BootImageAnnotation]
+	if recordedImage == "" {
+		recordedImage = image
+	}
+	var outdated []*machinev1.Machine
+	for _, machine := range machines {
+		originMachineCopy := machine.DeepCopy()
+		if machine.Annotations == nil {
+			machine.Annotations = map[string]string{}
+		}
+		if _, ok := machine.Annotations[BootImageAnnotation]; !ok {
+			machine.Annotations[BootImageAnnotation] = recordedImage
+		}
+		if machine.Annotations[BootImageAnnotation] != image {
+			machine.Annotations[BootImageOutdatedAnnotation] = "true"
+			outdated = append(outdated, machine)
+		} else {
+			delete(machine.Annotations, BootImageOutdatedAnnotation)
+		}
+		if machine.Annotations[BootImageAnnotation] != originMachineCopy.Annotations[BootImageAnnotation] ||
+			machine.Annotations[BootImageOutdatedAnnotation] != originMachineCopy.Annotations[BootImageOutdatedAnnotation] {
+			if err := r.tenantClusterClient.PatchMachine(machine, originMachineCopy); err != nil {
+				klog.Errorf("%s: failed to annotate the boot image of the Machine, with error: %v", machine.Name, err)
+			}
+		}
+	}
+
+	originMachineSetCopy := machineSet.DeepCopy()
+	if machineSet.Annotations == nil {
+		machineSet.Annotations = map[string]string{}
+	}
+	machineSet.Annotations[BootImageAnnotation] = image
+	if len(outdated) > 0 {
+		machineSet.Annotations[BootImageOutdatedAnnotation] = "true"
+	} else {
+		delete(machineSet.Annotations, BootImageOutdatedAnnotation)
+	}
+	if machineSet.Annotations[BootImageAnnotation] != originMachineSetCopy.Annotations[BootImageAnnotation] ||
+		machineSet.Annotations[BootImageOutdatedAnnotation] != originMachineSetCopy.Annotations[BootImageOutdatedAnnotation] {
+		if recordedImage != image {
+			klog.Infof("%s: the boot image of the MachineSet changed from %s to %s, %d Machines are outdated", machineSet.Name, recordedImage, image, len(outdated))
+		}
+		if err := r.tenantClusterClient.PatchMachineSet(machineSet, originMachineSetCopy); err != nil {
+			klog.Errorf("%s: failed to annotate the boot image of the MachineSet, with error: %v", machineSet.Name, err)
+		}
+	}
+
+	if len(outdated) > 0 && machineSet.Annotations[BootImageRolloutAnnotation] == "true" {
+		r.replaceOutdatedMachine(ctx, machineSet, machines, outdated)
+	}
+}
+
+// replaceOutdatedMachine deletes the oldest outdated Machine, for the MachineSet to replace it with a Machine
+// cloned from the latest image. A single Machine is replaced at a time: none is deleted until all the Machines
+// of the MachineSet are running.
+func (r *bootImageReconciler) replaceOutdatedMachine(ctx context.Context, machineSet *machinev1.MachineSet, machines []*machinev1.Machine, outdated []*machinev1.Machine) {
+	if len(machines) < machineSetReplicas(machineSet) {
+		return
+	}
+	for _, machine := range machines {
+		if machine.DeletionTimestamp != nil || machine.Status.Phase == nil || *machine.Status.Phase != machineRunningPhase {
+			return
+		}
+	}
+	sort.Slice(outdated, func(i, j int) bool {
+		return outdated[i].CreationTimestamp.Before(&outdated[j].CreationTimestamp)
+	})
+	machine := outdated[0]
+	klog.Infof("%s: replace Machine %s cloned from the outdated boot image %s", machineSet.Name, machine.Name, machine.Annotations[BootImageAnnotation])
+	if err := r.tenantClusterClient.DeleteMachine(ctx, machine); err != nil {
+		klog.Errorf("%s: failed to delete the outdated Machine, with error: %v", machine.Name, err)
+	}
+}
+
+// machineSetReplicas returns the replicas of the MachineSet, which default to 1
+func machineSetReplicas(machineSet *machinev1.MachineSet) int {
+	if machineSet.Spec.Replicas == nil {
+		return 1
+	}
+	return int(*machineSet.Spec.Replicas)
+}
+
+// Add registers the boot image runnable with the controller manager
+func Add(mgr manager.Manager, infraClusterClient infracluster.Client, tenantClusterClient tenantcluster.Client, pollInterval time.Duration) error {
+	return mgr.Add(&bootImageReconciler{
+		infraClusterClient:  infraClusterClient,
+		tenantClusterClient: tenantClusterClient,
+		pollInterval:        pollInterval,
+	})
+}
@@ -0,0 +1,198 @@
+package bootimage
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+	mockInfraClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster/mock"
+	mockTenantClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster/mock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/testutils"
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	cdiv1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
+)
+
+const (
+	oldImage = "sha256:old"
+	newImage = "sha256:new"
+)
+
+func stubMachineSet(t *testing.T, annotations map[string]string, dataSourceName string) machinev1.MachineSet {
+	providerSpec := kubevirtproviderv1alpha1.KubevirtMachineProviderSpec{SourcePvcName: "rhcos"}
+	if dataSourceName != "" {
+		providerSpec = kubevirtproviderv1alpha1.KubevirtMachineProviderSpec{DataSourceName: dataSourceName}
+	}
+	machineSet, err := testutils.StubMachineSet("workers", annotations, providerSpec)
+	assert.NilError(t, err)
+	machineSet.Spec.Replicas = pointer.Int32Ptr(2)
+	return machineSet
+}
+
+func stubMachine(name string, image string, age time.Duration) machinev1.Machine {
+	machine := testutils.StubMachineOfMachineSet(name, "workers")
+	machine.CreationTimestamp = metav1.NewTime(time.Now().Add(-age))
+	if image != "" {
+		machine.Annotations = map[string]string{BootImageAnnotation: image}
+	}
+	machine.Status.Phase = pointer.StringPtr(machineRunningPhase)
+	return machine
+}
+
+func stubSourcePvc(name string, digest string) *corev1.PersistentVolumeClaim {
+	return &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+		Name:        name,
+		Namespace:   testutils.InfraNamespace,
+		UID:         "rhcos-uid",
+		Annotations: map[string]string{machinescope.SourceImageDigestAnnotation: digest},
+	}}
+}
+
+func TestReconcile(t *testing.T) {
+	cMap := map[string]string{configMapInfraNamespaceKeyName: testutils.InfraNamespace}
+
+	cases := []struct {
+		name                     string
+		machineSetAnnotations    map[string]string
+		dataSourceName           string
+		machines                 []machinev1.Machine
+		sourcePvc                *corev1.PersistentVolumeClaim
+		expect                   func(tenantClient *mockTenantClusterClient.MockClient)
+		expectedMachineSetImage  string
+		expectedOutdatedMachines []string
+	}{
+		{
+			name:      "Success record the image of new machines",
+			machines:  []machinev1.Machine{stubMachine("workers-0", "", time.Hour)},
+			sourcePvc: stubSourcePvc("rhcos", newImage),
+			expect: func(tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().PatchMachine(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(machine *machinev1.Machine, originMachineCopy *machinev1.Machine) error {
+						assert.Equal(t, machine.Annotations[BootImageAnnotation], newImage)
+						return nil
+					}).Times(1)
+			},
+			expectedMachineSetImage: newImage,
+		},
+		{
+			name:                  "Success record the image of the source pvc without digest",
+			machineSetAnnotations: map[string]string{BootImageAnnotation: "rhcos-uid"},
+			machines:              []machinev1.Machine{stubMachine("workers-0", "rhcos-uid", time.Hour)},
+			sourcePvc:             stubSourcePvc("rhcos", ""),
+			expect:                func(tenantClient *mockTenantClusterClient.MockClient) {},
+		},
+		{
+			name:                  "Success mark the machines of the outdated image",
+			machineSetAnnotations: map[string]string{BootImageAnnotation: oldImage},
+			machines: []machinev1.Machine{
+				stubMachine("workers-0", oldImage, time.Hour),
+				stubMachine("workers-1", "", time.Minute),
+			},
+			sourcePvc:                stubSourcePvc("rhcos", newImage),
+			expect:                   func(tenantClient *mockTenantClusterClient.MockClient) {},
+			expectedMachineSetImage:  newImage,
+			expectedOutdatedMachines: []string{"workers-0", "workers-1"},
+		},
+		{
+			name:                  "Success mark the machines of the previous pvc of the data source",
+			machineSetAnnotations: map[string]string{BootImageAnnotation: "rhcos-uid"},
+			dataSourceName:        "rhcos",
+			machines:              []machinev1.Machine{stubMachine("workers-0", "rhcos-uid", time.Hour)},
+			sourcePvc: func() *corev1.PersistentVolumeClaim {
+				sourcePvc := stubSourcePvc("rhcos-v2", "")
+				sourcePvc.UID = "rhcos-v2-uid"
+				return sourcePvc
+			}(),
+			expect:                   func(tenantClient *mockTenantClusterClient.MockClient) {},
+			expectedMachineSetImage:  "rhcos-v2-uid",
+			expectedOutdatedMachines: []string{"workers-0"},
+		},
+		{
+			name:                  "Success replace the oldest outdated machine",
+			machineSetAnnotations: map[string]string{BootImageAnnotation: newImage, BootImageOutdatedAnnotation: "true", BootImageRolloutAnnotation: "true"},
+			machines: []machinev1.Machine{
+				stubMachine("workers-0", oldImage, time.Minute),
+				stubMachine("workers-1", oldImage, time.Hour),
+			},
+			sourcePvc: stubSourcePvc("rhcos", newImage),
+			expect: func(tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().DeleteMachine(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, machine *machinev1.Machine) error {
+						assert.Equal(t, machine.Name, "workers-1")
+						return nil
+					}).Times(1)
+			},
+			expectedOutdatedMachines: []string{"workers-0", "workers-1"},
+		},
+		{
+			name:                  "Success wait for the replaced machine to run",
+			machineSetAnnotations: map[string]string{BootImageAnnotation: newImage, BootImageOutdatedAnnotation: "true", BootImageRolloutAnnotation: "true"},
+			machines: func() []machinev1.Machine {
+				provisioning := stubMachine("workers-2", newImage, time.Second)
+				provisioning.Status.Phase = pointer.StringPtr("Provisioning")
+				return []machinev1.Machine{stubMachine("workers-0", oldImage, time.Minute), provisioning}
+			}(),
+			sourcePvc:                stubSourcePvc("rhcos", newImage),
+			expect:                   func(tenantClient *mockTenantClusterClient.MockClient) {},
+			expectedOutdatedMachines: []string{"workers-0"},
+		},
+		{
+			name:     "Failure get the source pvc",
+			machines: []machinev1.Machine{stubMachine("workers-0", oldImage, time.Hour)},
+			expect:   func(tenantClient *mockTenantClusterClient.MockClient) {},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			infraClient := mockInfraClusterClient.NewMockClient(mockCtrl)
+			tenantClient := mockTenantClusterClient.NewMockClient(mockCtrl)
+			tc.expect(tenantClient)
+			tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+			tenantClient.EXPECT().ListMachineSets(gomock.Any()).Return([]machinev1.MachineSet{stubMachineSet(t, tc.machineSetAnnotations, tc.dataSourceName)}, nil).Times(1)
+			tenantClient.EXPECT().ListMachines(gomock.Any()).Return(tc.machines, nil).Times(1)
+			if tc.sourcePvc != nil {
+				if tc.dataSourceName != "" {
+					infraClient.EXPECT().GetDataSourcePVC(gomock.Any(), testutils.InfraNamespace, tc.dataSourceName).Return(
+						&cdiv1.DataVolumeSourcePVC{Name: tc.sourcePvc.Name, Namespace: testutils.InfraNamespace}, nil).Times(1)
+				}
+				infraClient.EXPECT().GetPersistentVolumeClaim(gomock.Any(), testutils.InfraNamespace, tc.sourcePvc.Name).Return(tc.sourcePvc, nil).Times(1)
+			} else {
+				infraClient.EXPECT().GetPersistentVolumeClaim(gomock.Any(), testutils.InfraNamespace, "rhcos").Return(nil, fmt.Errorf("test error")).Times(1)
+			}
+			var patchedMachineSet *machinev1.MachineSet
+			tenantClient.EXPECT().PatchMachineSet(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(machineSet *machinev1.MachineSet, originMachineSetCopy *machinev1.MachineSet) error {
+					patchedMachineSet = machineSet
+					return nil
+				}).AnyTimes()
+			var outdatedMachines []string
+			tenantClient.EXPECT().PatchMachine(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(machine *machinev1.Machine, originMachineCopy *machinev1.Machine) error {
+					outdatedMachines = append(outdatedMachines, machine.Name)
+					assert.Equal(t, machine.Annotations[BootImageOutdatedAnnotation], "true")
+					return nil
+				}).AnyTimes()
+
+			r := &bootImageReconciler{infraClusterClient: infraClient, tenantClusterClient: tenantClient}
+			assert.NilError(t, r.Reconcile(context.Background()))
+			if tc.expectedMachineSetImage != "" {
+				assert.Equal(t, patchedMachineSet.Annotations[BootImageAnnotation], tc.expectedMachineSetImage)
+				_, outdated := patchedMachineSet.Annotations[BootImageOutdatedAnnotation]
+				assert.Equal(t, outdated, len(tc.expectedOutdatedMachines) > 0)
+			} else {
+				assert.Assert(t, patchedMachineSet == nil)
+			}
+			assert.DeepEqual(t, outdatedMachines, tc.expectedOutdatedMachines)
+		})
+	}
+}
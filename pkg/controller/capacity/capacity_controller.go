@@ -0,0 +1,203 @@
+// capacity package implements a controller to report how many more Machines fit in the infra namespace:
+// - Read the ResourceQuotas of the infra namespace, and the remaining amount of each quoted resource
+// - For every MachineSet, compute the resources a Machine of its shape consumes from the quotas
+// - Publish the number of Machines which still fit as an annotation of the MachineSet, and as a metric
+// MachineSets which no quota limits have no capacity annotation.
+// The overhead of the virt-launcher pods isn't known to the provider, so the capacity is an upper bound.
+package capacity
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
+)
+
+const (
+	configMapNamespace             = "openshift-config"
+	configMapName                  = "cloud-provider-config"
+	configMapDataKeyName           = "config"
+	configMapInfraNamespaceKeyName = "namespace"
+
+	// CapacityAnnotation is the number of Machines of the MachineSet which still fit in the quotas of the infra namespace
+	CapacityAnnotation = "kubevirt.machine.openshift.io/remaining-capacity"
+)
+
+var remainingCapacity = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "kubevirt_machineset_remaining_capacity",
+		Help: "Number of Machines of the MachineSet which still fit in the ResourceQuotas of the infra namespace",
+	},
+	[]string{"namespace", "name"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(remainingCapacity)
+}
+
+var _ manager.Runnable = &capacityReconciler{}
+
+type capacityReconciler struct {
+	infraClusterClient  infracluster.Client
+	tenantClusterClient tenantcluster.Client
+	pollInterval        time.Duration
+}
+
+// Start reports the capacity of the MachineSets until the context is done
+func (r *capacityReconciler) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := r.Reconcile(ctx); err != nil {
+			klog.Errorf("capacity: %v", err)
+		}
+	}, r.pollInterval)
+	return nil
+}
+
+// Reconcile sets the capacity annotation of every kubevirt MachineSet, by the remaining quotas of the infra namespace
+func (r *capacityReconciler) Reconcile(ctx context.Context) error {
+	cMap, err := r.tenantClusterClient.GetConfigMapValue(ctx, configMapName, configMapNamespace, configMapDataKeyName)
+	if err != nil {
+		return err
+	}
+	infraNamespace, ok := (*cMap)[configMapInfraNamespaceKeyName]
+	if !ok {
+		return fmt.Errorf("configMap %s/%s: The map extracted with key %s doesn't contain key %s",
+			configMapNamespace, configMapName, configMapDataKeyName, configMapInfraNamespaceKeyName)
+	}
+
+	quotas, err := r.infraClusterClient.ListResourceQuotas(ctx, infraNamespace)
+	if err != nil {
+		return fmt.Errorf("failed to list the ResourceQuotas of the infra namespace, with error: %v", err)
+	}
+	remaining := remainingQuota(quotas.Items)
+
+	machineSets, err := r.tenantClusterClient.ListMachineSets(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list MachineSets, with error: %v", err)
+	}
+	for i := range machineSets {
+		machineSet := &machineSets[i]
+		providerSpec, err := kubevirtproviderv1alpha1.ProviderSpecFromRawExtension(machineSet.Spec.Template.Spec.ProviderSpec.Value)
+		if err != nil {
+			klog.Errorf("%s: failed to get the provider spec of the MachineSet, with error: %v", machineSet.Name, err)
+			continue
+		}
+		usage, err := machineUsage(providerSpec)
+		if err != nil {
+			klog.Errorf("%s: failed to get the resources of the MachineSet, with error: %v", machineSet.Name, err)
+			continue
+		}
+		capacity, limited := remainingMachines(remaining, usage)
+		if err := r.setCapacity(machineSet, capacity, limited); err != nil {
+			klog.Errorf("%s: failed to set the capacity of the MachineSet, with error: %v", machineSet.Name, err)
+		}
+	}
+	return nil
+}
+
+// setCapacity publishes the capacity of the MachineSet, or removes it when the MachineSet isn't limited
+func (r *capacityReconciler) setCapacity(machineSet *machinev1.MachineSet, capacity int64, limited bool) error {
+	originMachineSetCopy := machineSet.DeepCopy()
+	if limited {
+		remainingCapacity.WithLabelValues(machineSet.Namespace, machineSet.Name).Set(float64(capacity))
+		if machineSet.Annotations == nil {
+			machineSet.Annotations = map[string]string{}
+		}
+		machineSet.Annotations[CapacityAnnotation] = strconv.FormatInt(capacity, 10)
+	} else {
+		remainingCapacity.DeleteLabelValues(machineSet.Namespace, machineSet.Name)
+		delete(machineSet.Annotations, CapacityAnnotation)
+	}
+	if originMachineSetCopy.Annotations[CapacityAnnotation] == machineSet.Annotations[CapacityAnnotation] {
+		return nil
+	}
+	return r.tenantClusterClient.PatchMachineSet(machineSet, originMachineSetCopy)
+}
+
+// remainingQuota returns the remaining amount of every resource limited by the quotas, the most restrictive quota wins
+func remainingQuota(quotas []corev1.ResourceQuota) corev1.ResourceList {
+	remaining := corev1.ResourceList{}
+	for _, quota := range quotas {
+		for name, hard := range quota.Status.Hard {
+			left := hard.DeepCopy()
+			if used, ok := quota.Status.Used[name]; ok {
+				left.Sub(used)
+			}
+			if current, ok := remaining[name]; !ok || left.Cmp(current) < 0 {
+				remaining[name] = left
+			}
+		}
+	}
+	return remaining
+}
+
+// machineUsage returns the quoted resources a Machine with the provider spec consumes in the infra namespace:
+// its VirtualMachine runs in a single virt-launcher pod, and its boot volume is a single PVC
+func machineUsage(providerSpec *kubevirtproviderv1alpha1.KubevirtMachineProviderSpec) (corev1.ResourceList, error) {
+	requests, err := machinescope.ResourceRequests(providerSpec)
+	if err != nil {
+		return nil, err
+	}
+	usage := corev1.ResourceList{
+		corev1.ResourcePods:                   *apiresource.NewQuantity(1, apiresource.DecimalSI),
+		corev1.ResourcePersistentVolumeClaims: *apiresource.NewQuantity(1, apiresource.DecimalSI),
+		corev1.ResourceMemory:                 requests[corev1.ResourceMemory],
+		corev1.ResourceRequestsMemory:         requests[corev1.ResourceMemory],
+		corev1.ResourceRequestsStorage:        requests[corev1.ResourceStorage],
+	}
+	if cpu, ok := requests[corev1.ResourceCPU]; ok {
+		usage[corev1.ResourceCPU] = cpu
+		usage[corev1.ResourceRequestsCPU] = cpu
+	}
+	if providerSpec.StorageClassName != "" {
+		usage[corev1.ResourceName(providerSpec.StorageClassName+".storageclass.storage.k8s.io/requests.storage")] = requests[corev1.ResourceStorage]
+		usage[corev1.ResourceName(providerSpec.StorageClassName+".storageclass.storage.k8s.io/persistentvolumeclaims")] =
+			*apiresource.NewQuantity(1, apiresource.DecimalSI)
+	}
+	return usage, nil
+}
+
+// remainingMachines returns how many Machines with the usage fit in the remaining quotas,
+// and false when no quota limits the resources of the Machines
+func remainingMachines(remaining corev1.ResourceList, usage corev1.ResourceList) (int64, bool) {
+	var capacity int64
+	limited := false
+	for name, perMachine := range usage {
+		left, ok := remaining[name]
+		if !ok || perMachine.IsZero() {
+			continue
+		}
+		fit := left.MilliValue() / perMachine.MilliValue()
+		if fit < 0 {
+			fit = 0
+		}
+		if !limited || fit < capacity {
+			capacity = fit
+			limited = true
+		}
+	}
+	return capacity, limited
+}
+
+// Add registers the capacity runnable with the controller manager
+func Add(mgr manager.Manager, infraClusterClient infracluster.Client, tenantClusterClient tenantcluster.Client, pollInterval time.Duration) error {
+	return mgr.Add(&capacityReconciler{
+		infraClusterClient:  infraClusterClient,
+		tenantClusterClient: tenantClusterClient,
+		pollInterval:        pollInterval,
+	})
+}
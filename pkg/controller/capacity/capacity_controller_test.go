@@ -0,0 +1,189 @@
+package capacity
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+	mockInfraClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster/mock"
+	mockTenantClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster/mock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/testutils"
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
+)
+
+func stubQuota(hard, used corev1.ResourceList) corev1.ResourceQuota {
+	return corev1.ResourceQuota{Status: corev1.ResourceQuotaStatus{Hard: hard, Used: used}}
+}
+
+func stubMachineSet(t *testing.T, name string, annotations map[string]string, providerSpec kubevirtproviderv1alpha1.KubevirtMachineProviderSpec) machinev1.MachineSet {
+	machineSet, err := testutils.StubMachineSet(name, annotations, providerSpec)
+	assert.NilError(t, err)
+	return machineSet
+}
+
+func TestRemainingQuota(t *testing.T) {
+	remaining := remainingQuota([]corev1.ResourceQuota{
+		stubQuota(corev1.ResourceList{
+			corev1.ResourceRequestsCPU:    apiresource.MustParse("16"),
+			corev1.ResourceRequestsMemory: apiresource.MustParse("64Gi"),
+		}, corev1.ResourceList{
+			corev1.ResourceRequestsCPU: apiresource.MustParse("4"),
+		}),
+		stubQuota(corev1.ResourceList{
+			corev1.ResourceRequestsCPU: apiresource.MustParse("10"),
+		}, corev1.ResourceList{
+			corev1.ResourceRequestsCPU: apiresource.MustParse("6"),
+		}),
+	})
+	assert.Equal(t, len(remaining), 2)
+	cpu := remaining[corev1.ResourceRequestsCPU]
+	assert.Equal(t, cpu.String(), "4")
+	memory := remaining[corev1.ResourceRequestsMemory]
+	assert.Equal(t, memory.String(), "64Gi")
+}
+
+func TestRemainingMachines(t *testing.T) {
+	usage, err := machineUsage(&kubevirtproviderv1alpha1.KubevirtMachineProviderSpec{
+		RequestedCPU:     2,
+		RequestedMemory:  "8Gi",
+		RequestedStorage: "50Gi",
+		StorageClassName: "fast",
+	})
+	assert.NilError(t, err)
+
+	cases := []struct {
+		name             string
+		remaining        corev1.ResourceList
+		expectedCapacity int64
+		expectedLimited  bool
+	}{
+		{
+			name:      "no quota",
+			remaining: corev1.ResourceList{},
+		},
+		{
+			name:      "unrelated quota",
+			remaining: corev1.ResourceList{corev1.ResourceServices: apiresource.MustParse("5")},
+		},
+		{
+			name: "cpu limits",
+			remaining: corev1.ResourceList{
+				corev1.ResourceRequestsCPU:    apiresource.MustParse("5"),
+				corev1.ResourceRequestsMemory: apiresource.MustParse("64Gi"),
+			},
+			expectedCapacity: 2,
+			expectedLimited:  true,
+		},
+		{
+			name: "pods limit",
+			remaining: corev1.ResourceList{
+				corev1.ResourceRequestsCPU: apiresource.MustParse("500m"),
+				corev1.ResourcePods:        apiresource.MustParse("0"),
+			},
+			expectedCapacity: 0,
+			expectedLimited:  true,
+		},
+		{
+			name: "storage class limits",
+			remaining: corev1.ResourceList{
+				"fast.storageclass.storage.k8s.io/requests.storage": apiresource.MustParse("120Gi"),
+			},
+			expectedCapacity: 2,
+			expectedLimited:  true,
+		},
+		{
+			name: "over quota",
+			remaining: corev1.ResourceList{
+				corev1.ResourceRequestsMemory: apiresource.MustParse("-1Gi"),
+			},
+			expectedCapacity: 0,
+			expectedLimited:  true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			capacity, limited := remainingMachines(tc.remaining, usage)
+			assert.Equal(t, capacity, tc.expectedCapacity)
+			assert.Equal(t, limited, tc.expectedLimited)
+		})
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	cMap := map[string]string{configMapInfraNamespaceKeyName: testutils.InfraNamespace}
+	quotas := &corev1.ResourceQuotaList{Items: []corev1.ResourceQuota{
+		stubQuota(corev1.ResourceList{corev1.ResourceRequestsMemory: apiresource.MustParse("20Gi")}, nil),
+	}}
+	smallSpec := kubevirtproviderv1alpha1.KubevirtMachineProviderSpec{RequestedMemory: "4Gi"}
+	largeSpec := kubevirtproviderv1alpha1.KubevirtMachineProviderSpec{RequestedMemory: "8Gi"}
+
+	cases := []struct {
+		name        string
+		expectedErr string
+		expect      func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient)
+	}{
+		{
+			name: "Success annotate the machine sets",
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				small := stubMachineSet(t, "small", nil, smallSpec)
+				large := stubMachineSet(t, "large", map[string]string{CapacityAnnotation: "2"}, largeSpec)
+				tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+				infraClient.EXPECT().ListResourceQuotas(gomock.Any(), testutils.InfraNamespace).Return(quotas, nil).Times(1)
+				tenantClient.EXPECT().ListMachineSets(gomock.Any()).Return([]machinev1.MachineSet{small, large}, nil).Times(1)
+				tenantClient.EXPECT().PatchMachineSet(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(machineSet *machinev1.MachineSet, originMachineSetCopy *machinev1.MachineSet) error {
+						assert.Equal(t, machineSet.Name, "small")
+						assert.Equal(t, machineSet.Annotations[CapacityAnnotation], "5")
+						return nil
+					}).Times(1)
+			},
+		},
+		{
+			name: "Success remove the annotation without quotas",
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				large := stubMachineSet(t, "large", map[string]string{CapacityAnnotation: "2"}, largeSpec)
+				tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+				infraClient.EXPECT().ListResourceQuotas(gomock.Any(), testutils.InfraNamespace).Return(&corev1.ResourceQuotaList{}, nil).Times(1)
+				tenantClient.EXPECT().ListMachineSets(gomock.Any()).Return([]machinev1.MachineSet{large}, nil).Times(1)
+				tenantClient.EXPECT().PatchMachineSet(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(machineSet *machinev1.MachineSet, originMachineSetCopy *machinev1.MachineSet) error {
+						_, ok := machineSet.Annotations[CapacityAnnotation]
+						assert.Assert(t, !ok)
+						return nil
+					}).Times(1)
+			},
+		},
+		{
+			name: "Failure list resource quotas",
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+				infraClient.EXPECT().ListResourceQuotas(gomock.Any(), testutils.InfraNamespace).Return(nil, fmt.Errorf("test error")).Times(1)
+			},
+			expectedErr: "failed to list the ResourceQuotas of the infra namespace, with error: test error",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			infraClient := mockInfraClusterClient.NewMockClient(mockCtrl)
+			tenantClient := mockTenantClusterClient.NewMockClient(mockCtrl)
+			tc.expect(infraClient, tenantClient)
+
+			r := &capacityReconciler{infraClusterClient: infraClient, tenantClusterClient: tenantClient}
+			err := r.Reconcile(context.Background())
+			if tc.expectedErr != "" {
+				assert.Error(t, err, tc.expectedErr)
+			} else {
+				assert.NilError(t, err)
+			}
+		})
+	}
+}
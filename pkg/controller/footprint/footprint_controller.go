@@ -0,0 +1,187 @@
+// footprint package implements an optional controller publishing the resource footprint of the Machines, for
+// charging the tenants back for their consumption of the infra-cluster:
+// - Compute the vCPUs, memory and storage every Machine consumes, from its provider spec and the defaults of the
+// provider
+// - Normalize them to vCPUs and GiB, rounded to two decimals
+// - Publish them as annotations of the Machine, and as metrics labeled with the MachineSet of the Machine
+// The footprint is what the Machines request, not their usage, which is reported by the utilization collector.
+package footprint
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
+)
+
+const (
+	// VCPUsAnnotation is the number of vCPUs of the Machine
+	VCPUsAnnotation = "kubevirt.machine.openshift.io/footprint-vcpus"
+	// MemoryAnnotation is the memory of the Machine, in GiB
+	MemoryAnnotation = "kubevirt.machine.openshift.io/footprint-memory-gib"
+	// StorageAnnotation is the size of the boot volume of the Machine, in GiB
+	StorageAnnotation = "kubevirt.machine.openshift.io/footprint-storage-gib"
+
+	// defaultVCPUs is the number of vCPUs KubeVirt gives the VirtualMachines without a CPU request
+	defaultVCPUs = 1
+
+	gib = 1 << 30
+)
+
+// The resources of the footprint metric
+const (
+	resourceVCPUs   = "vcpus"
+	resourceMemory  = "memory_gib"
+	resourceStorage = "storage_gib"
+)
+
+var machineFootprint = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "kubevirt_machine_footprint",
+		Help: "Resources the Machine consumes in the infra-cluster, in vCPUs for the vcpus resource and GiB for the memory_gib and storage_gib resources",
+	},
+	[]string{"namespace", "name", "machineset", "resource"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(machineFootprint)
+}
+
+// resources are the normalized resources a Machine consumes in the infra-cluster
+type resources struct {
+	vcpus      float64
+	memoryGiB  float64
+	storageGiB float64
+}
+
+// machineResources returns the resources of a Machine with the provider spec
+func machineResources(providerSpec *kubevirtproviderv1alpha1.KubevirtMachineProviderSpec) (resources, error) {
+	requests, err := machinescope.ResourceRequests(providerSpec)
+	if err != nil {
+		return resources{}, err
+	}
+	memory, storage := requests[corev1.ResourceMemory], requests[corev1.ResourceStorage]
+	footprint := resources{
+		vcpus:      defaultVCPUs,
+		memoryGiB:  toGiB(memory.Value()),
+		storageGiB: toGiB(storage.Value()),
+	}
+	if cpu, ok := requests[corev1.ResourceCPU]; ok {
+		footprint.vcpus = float64(cpu.Value())
+	}
+	return footprint, nil
+}
+
+// toGiB returns the bytes in GiB, rounded to two decimals
+func toGiB(bytes int64) float64 {
+	return math.Round(float64(bytes)/gib*100) / 100
+}
+
+var _ manager.Runnable = &footprintReconciler{}
+
+type footprintReconciler struct {
+	tenantClusterClient tenantcluster.Client
+	pollInterval        time.Duration
+}
+
+// Start publishes the footprint of the Machines until the context is done
+func (r *footprintReconciler) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := r.Reconcile(ctx); err != nil {
+			klog.Errorf("footprint: %v", err)
+		}
+	}, r.pollInterval)
+	return nil
+}
+
+// Reconcile publishes the footprint of every Machine
+func (r *footprintReconciler) Reconcile(ctx context.Context) error {
+	machines, err := r.tenantClusterClient.ListMachines(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list Machines, with error: %v", err)
+	}
+	// The deleted Machines don't consume anymore
+	machineFootprint.Reset()
+	for i := range machines {
+		machine := &machines[i]
+		providerSpec, err := kubevirtproviderv1alpha1.ProviderSpecFromRawExtension(machine.Spec.ProviderSpec.Value)
+		if err != nil {
+			klog.Errorf("%s: failed to get the provider spec of the Machine, with error: %v", machine.Name, err)
+			continue
+		}
+		footprint, err := machineResources(providerSpec)
+		if err != nil {
+			klog.Errorf("%s: failed to get the resources of the Machine, with error: %v", machine.Name, err)
+			continue
+		}
+		machineSet := machineSetOf(machine)
+		machineFootprint.WithLabelValues(machine.Namespace, machine.Name, machineSet, resourceVCPUs).Set(footprint.vcpus)
+		machineFootprint.WithLabelValues(machine.Namespace, machine.Name, machineSet, resourceMemory).Set(footprint.memoryGiB)
+		machineFootprint.WithLabelValues(machine.Namespace, machine.Name, machineSet, resourceStorage).Set(footprint.storageGiB)
+		if err := r.setAnnotations(machine, footprint); err != nil {
+			klog.Errorf("%s: failed to set the footprint of the Machine, with error: %v", machine.Name, err)
+		}
+	}
+	return nil
+}
+
+// setAnnotations sets the footprint annotations of the Machine, which is only patched when they change
+func (r *footprintReconciler) setAnnotations(machine *machinev1.Machine, footprint resources) error {
+	annotations := map[string]string{
+		VCPUsAnnotation:   formatFloat(footprint.vcpus),
+		MemoryAnnotation:  formatFloat(footprint.memoryGiB),
+		StorageAnnotation: formatFloat(footprint.storageGiB),
+	}
+	changed := false
+	for key, value := range annotations {
+		if machine.Annotations[key] != value {
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	originMachineCopy := machine.DeepCopy()
+	if machine.Annotations == nil {
+		machine.Annotations = map[string]string{}
+	}
+	for key, value := range annotations {
+		machine.Annotations[key] = value
+	}
+	return r.tenantClusterClient.PatchMachine(machine, originMachineCopy)
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// machineSetOf returns the name of the MachineSet owning the Machine, or an empty name for a standalone Machine
+func machineSetOf(machine *machinev1.Machine) string {
+	for _, owner := range machine.OwnerReferences {
+		if owner.Kind == "MachineSet" {
+			return owner.Name
+		}
+	}
+	return ""
+}
+
+// Add registers the runnable publishing the footprint of the Machines
+func Add(mgr manager.Manager, tenantClusterClient tenantcluster.Client, pollInterval time.Duration) error {
+	return mgr.Add(&footprintReconciler{
+		tenantClusterClient: tenantClusterClient,
+		pollInterval:        pollInterval,
+	})
+}
@@ -0,0 +1,118 @@
+package footprint
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+	mockTenantClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster/mock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/testutils"
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"gotest.tools/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func stubMachine(t *testing.T, name string, modifyProviderSpec func(*kubevirtproviderv1alpha1.KubevirtMachineProviderSpec)) machinev1.Machine {
+	machine, err := testutils.StubMachine()
+	assert.NilError(t, err)
+	machine.Name = name
+	machine.OwnerReferences = []metav1.OwnerReference{{Kind: "MachineSet", Name: "workers"}}
+	if modifyProviderSpec != nil {
+		providerSpec := testutils.ProviderSpec
+		modifyProviderSpec(&providerSpec)
+		machine.Spec.ProviderSpec.Value, err = kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&providerSpec)
+		assert.NilError(t, err)
+	}
+	return *machine
+}
+
+func TestReconcile(t *testing.T) {
+	annotated := stubMachine(t, "annotated", nil)
+	annotated.Annotations = map[string]string{
+		VCPUsAnnotation:   "77",
+		MemoryAnnotation:  "114.98",
+		StorageAnnotation: "666",
+	}
+	invalid := stubMachine(t, "invalid", func(providerSpec *kubevirtproviderv1alpha1.KubevirtMachineProviderSpec) {
+		providerSpec.RequestedMemory = "lots"
+	})
+
+	cases := []struct {
+		name                string
+		machines            []machinev1.Machine
+		expectedAnnotations map[string]map[string]string
+		expected            map[string]float64
+	}{
+		{
+			name:     "Success annotate the machines with their footprint",
+			machines: []machinev1.Machine{stubMachine(t, "sized", nil)},
+			expectedAnnotations: map[string]map[string]string{
+				"sized": {VCPUsAnnotation: "77", MemoryAnnotation: "114.98", StorageAnnotation: "666"},
+			},
+			expected: map[string]float64{
+				"sized/workers/vcpus":       77,
+				"sized/workers/memory_gib":  114.98,
+				"sized/workers/storage_gib": 666,
+			},
+		},
+		{
+			name: "Success the defaults of the provider are the footprint of the unset resources",
+			machines: []machinev1.Machine{stubMachine(t, "defaulted", func(providerSpec *kubevirtproviderv1alpha1.KubevirtMachineProviderSpec) {
+				providerSpec.RequestedCPU = 0
+				providerSpec.RequestedMemory = ""
+				providerSpec.RequestedStorage = ""
+			})},
+			expectedAnnotations: map[string]map[string]string{
+				"defaulted": {VCPUsAnnotation: "1", MemoryAnnotation: "1.91", StorageAnnotation: "35"},
+			},
+			expected: map[string]float64{
+				"defaulted/workers/vcpus":       1,
+				"defaulted/workers/memory_gib":  1.91,
+				"defaulted/workers/storage_gib": 35,
+			},
+		},
+		{
+			name:                "Success machines already annotated and invalid machines aren't patched",
+			machines:            []machinev1.Machine{annotated, invalid},
+			expectedAnnotations: map[string]map[string]string{},
+			expected: map[string]float64{
+				"annotated/workers/vcpus":       77,
+				"annotated/workers/memory_gib":  114.98,
+				"annotated/workers/storage_gib": 666,
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			tenantClient := mockTenantClusterClient.NewMockClient(mockCtrl)
+			tenantClient.EXPECT().ListMachines(gomock.Any()).Return(tc.machines, nil).Times(1)
+			patched := map[string]map[string]string{}
+			tenantClient.EXPECT().PatchMachine(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(machine *machinev1.Machine, _ *machinev1.Machine) error {
+					patched[machine.Name] = machine.Annotations
+					return nil
+				}).AnyTimes()
+
+			r := &footprintReconciler{tenantClusterClient: tenantClient}
+			assert.NilError(t, r.Reconcile(context.Background()))
+			assert.DeepEqual(t, patched, tc.expectedAnnotations)
+			values, err := testutils.GaugeValues(machineFootprint, "name", "machineset", "resource")
+			assert.NilError(t, err)
+			assert.DeepEqual(t, values, tc.expected)
+		})
+	}
+}
+
+func TestReconcileListError(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	tenantClient := mockTenantClusterClient.NewMockClient(mockCtrl)
+	tenantClient.EXPECT().ListMachines(gomock.Any()).Return(nil, fmt.Errorf("test error")).Times(1)
+
+	r := &footprintReconciler{tenantClusterClient: tenantClient}
+	assert.Error(t, r.Reconcile(context.Background()), "failed to list Machines, with error: test error")
+}
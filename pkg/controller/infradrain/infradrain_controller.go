@@ -0,0 +1,289 @@
+// infradrain package implements a controller to react on disruptions of the infra-cluster nodes:
+// - Detect infra-cluster Nodes which are cordoned (drained for maintenance) or NotReady
+// - Find the VirtualMachineInstances of the Machines of this tenant-cluster running on these Nodes, by their providerID
+// - The Machines naming a credentials secret in their provider spec are checked against the infra-cluster of the secret
+// - Find the tenant-cluster Node of a Machine by its nodeRef or InternalDNS address, the hostname may be overridden
+// - Cordon and drain these tenant-cluster Nodes in the background, before their VMs are killed
+// - Record the cordon on the Machine, the drain isn't repeated once it succeeded
+// - Uncordon the recorded Nodes once their VirtualMachineInstance runs on a healthy infra-cluster Node again
+// - The Nodes which were already cordoned before their drain, e.g. by an admin, are left cordoned
+// It runs inside the machine controller, so no DaemonSet is needed in the infra-cluster.
+package infradrain
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/kubevirt"
+)
+
+const (
+	configMapNamespace             = "openshift-config"
+	configMapName                  = "cloud-provider-config"
+	configMapDataKeyName           = "config"
+	configMapInfraNamespaceKeyName = "namespace"
+
+	// InfraDrainedAnnotation records on the Machine the disrupted infra-cluster node for which its node was cordoned,
+	// so the node is uncordoned once the infra-cluster node recovers, also after a restart of the controller
+	InfraDrainedAnnotation = "kubevirt.machine.openshift.io/infra-drained"
+	// NodeCordonedAnnotation records on the Machine that its node was already cordoned when the infra-cluster node
+	// was disrupted, so the node is left cordoned once the infra-cluster node recovers
+	NodeCordonedAnnotation = "kubevirt.machine.openshift.io/node-cordoned"
+)
+
+var _ manager.Runnable = &infraDrainReconciler{}
+
+type infraDrainReconciler struct {
+	infraClusterClient infracluster.Client
+	// machineInfraClients returns the Client of the infra-cluster of a Machine
+	machineInfraClients infracluster.MachineClients
+	tenantClusterClient tenantcluster.Client
+	pollInterval        time.Duration
+
+	// lock guards draining and drained
+	lock sync.Mutex
+	// draining are the nodes whose drain runs in the background
+	draining map[string]bool
+	// drained are the nodes which were drained since they were cordoned, their drain isn't repeated
+	drained map[string]bool
+	// drains tracks the background drains, so the tests wait for them
+	drains sync.WaitGroup
+}
+
+// Start polls the infra-cluster nodes until the context is done
+func (r *infraDrainReconciler) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := r.Reconcile(ctx); err != nil {
+			klog.Errorf("infra drain: %v", err)
+		}
+	}, r.pollInterval)
+	return nil
+}
+
+// Reconcile cordons and drains every tenant-cluster Node whose VirtualMachineInstance runs on a disrupted
+// infra-cluster Node, and uncordons the Nodes it cordoned once their VirtualMachineInstance runs on a healthy one.
+func (r *infraDrainReconciler) Reconcile(ctx context.Context) error {
+	cMap, err := r.tenantClusterClient.GetConfigMapValue(ctx, configMapName, configMapNamespace, configMapDataKeyName)
+	if err != nil {
+		return err
+	}
+	infraNamespace, ok := (*cMap)[configMapInfraNamespaceKeyName]
+	if !ok {
+		return fmt.Errorf("configMap %s/%s: The map extracted with key %s doesn't contain key %s",
+			configMapNamespace, configMapName, configMapDataKeyName, configMapInfraNamespaceKeyName)
+	}
+
+	machines, err := r.tenantClusterClient.ListMachines(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list Machines, with error: %v", err)
+	}
+	// The Machines are grouped by their infra-cluster, and indexed by the name of their VirtualMachine,
+	// from their providerID
+	machinesOfInfraClusters := map[infracluster.Client]map[string]*machinev1.Machine{}
+	for i := range machines {
+		machine := &machines[i]
+		if machine.Spec.ProviderID == nil || nodeNameOf(machine) == "" {
+			continue
+		}
+		namespace, vmName, err := kubevirt.ParseProviderID(*machine.Spec.ProviderID)
+		if err != nil || namespace != infraNamespace {
+			continue
+		}
+		infraClusterClient, err := r.infraClusterClientOf(ctx, machine)
+		if err != nil {
+			klog.Errorf("%s: failed to get the infra-cluster client of the Machine, with error: %v", machine.Name, err)
+			continue
+		}
+		if machinesOfInfraClusters[infraClusterClient] == nil {
+			machinesOfInfraClusters[infraClusterClient] = map[string]*machinev1.Machine{}
+		}
+		machinesOfInfraClusters[infraClusterClient][vmName] = machine
+	}
+
+	var errs []error
+	for infraClusterClient, machinesOfVMs := range machinesOfInfraClusters {
+		if err := r.reconcileInfraCluster(ctx, infraClusterClient, infraNamespace, machinesOfVMs); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// infraClusterClientOf returns the Client of the infra-cluster of the Machine
+func (r *infraDrainReconciler) infraClusterClientOf(ctx context.Context, machine *machinev1.Machine) (infracluster.Client, error) {
+	if r.machineInfraClients == nil {
+		return r.infraClusterClient, nil
+	}
+	return r.machineInfraClients(ctx, machine)
+}
+
+// reconcileInfraCluster drains and uncordons the nodes of the Machines of an infra-cluster, indexed by the name of
+// their VirtualMachine
+func (r *infraDrainReconciler) reconcileInfraCluster(ctx context.Context, infraClusterClient infracluster.Client, infraNamespace string,
+	machinesOfVMs map[string]*machinev1.Machine) error {
+	infraNodes, err := infraClusterClient.ListNodes(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list infra-cluster nodes, with error: %v", err)
+	}
+	disruptedNodes := map[string]bool{}
+	for _, node := range infraNodes.Items {
+		if isNodeDisrupted(node) {
+			disruptedNodes[node.Name] = true
+		}
+	}
+
+	vmis, err := infraClusterClient.ListVirtualMachineInstance(ctx, infraNamespace, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list Virtual Machine Instances, with error: %v", err)
+	}
+	for _, vmi := range vmis.Items {
+		machine, ok := machinesOfVMs[vmi.Name]
+		if !ok || vmi.Status.NodeName == "" {
+			continue
+		}
+		if disruptedNodes[vmi.Status.NodeName] {
+			r.cordonAndDrain(ctx, machine, vmi.Status.NodeName)
+		} else if _, cordoned := machine.Annotations[InfraDrainedAnnotation]; cordoned {
+			r.uncordon(ctx, machine, vmi.Status.NodeName)
+		}
+	}
+	return nil
+}
+
+// cordonAndDrain records the disrupted infra-cluster node on the Machine, and drains its node in the background,
+// unless it is drained already
+func (r *infraDrainReconciler) cordonAndDrain(ctx context.Context, machine *machinev1.Machine, infraNodeName string) {
+	nodeName := nodeNameOf(machine)
+	if _, cordoned := machine.Annotations[InfraDrainedAnnotation]; !cordoned {
+		klog.Infof("%s: infra-cluster node %s is disrupted - cordon and drain the node %s", machine.Name, infraNodeName, nodeName)
+		unschedulable, err := r.tenantClusterClient.IsNodeUnschedulable(ctx, nodeName)
+		if err != nil {
+			klog.Errorf("%s: failed to check whether the node is cordoned, with error: %v", machine.Name, err)
+			return
+		}
+		originMachineCopy := machine.DeepCopy()
+		if machine.Annotations == nil {
+			machine.Annotations = map[string]string{}
+		}
+		machine.Annotations[InfraDrainedAnnotation] = infraNodeName
+		if unschedulable {
+			machine.Annotations[NodeCordonedAnnotation] = "true"
+		}
+		if err := r.tenantClusterClient.PatchMachine(machine, originMachineCopy); err != nil {
+			klog.Errorf("%s: failed to record the drain of the node on the Machine, with error: %v", machine.Name, err)
+			return
+		}
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.draining[nodeName] || r.drained[nodeName] {
+		return
+	}
+	r.draining[nodeName] = true
+	r.drains.Add(1)
+	go func() {
+		defer r.drains.Done()
+		err := r.tenantClusterClient.CordonAndDrainNode(ctx, nodeName)
+		if err != nil {
+			klog.Errorf("%s: failed to cordon and drain the node, with error: %v", nodeName, err)
+		}
+		r.lock.Lock()
+		defer r.lock.Unlock()
+		delete(r.draining, nodeName)
+		// A failed drain is retried by the next poll
+		r.drained[nodeName] = err == nil
+	}()
+}
+
+// uncordon marks the node of the Machine schedulable again, unless it was already cordoned before its drain, and
+// removes the record of its drain from the Machine
+func (r *infraDrainReconciler) uncordon(ctx context.Context, machine *machinev1.Machine, infraNodeName string) {
+	nodeName := nodeNameOf(machine)
+	r.lock.Lock()
+	draining := r.draining[nodeName]
+	r.lock.Unlock()
+	if draining {
+		// The drain would cordon the node again, it is uncordoned by the poll after it
+		return
+	}
+
+	if _, cordoned := machine.Annotations[NodeCordonedAnnotation]; cordoned {
+		klog.Infof("%s: Virtual Machine Instance runs on the healthy infra-cluster node %s - the node %s was cordoned before its drain, leave it cordoned",
+			machine.Name, infraNodeName, nodeName)
+	} else {
+		klog.Infof("%s: Virtual Machine Instance runs on the healthy infra-cluster node %s - uncordon the node %s", machine.Name, infraNodeName, nodeName)
+		if err := r.tenantClusterClient.UncordonNode(ctx, nodeName); err != nil {
+			klog.Errorf("%s: failed to uncordon the node, with error: %v", nodeName, err)
+			return
+		}
+	}
+	originMachineCopy := machine.DeepCopy()
+	delete(machine.Annotations, InfraDrainedAnnotation)
+	delete(machine.Annotations, NodeCordonedAnnotation)
+	if err := r.tenantClusterClient.PatchMachine(machine, originMachineCopy); err != nil {
+		klog.Errorf("%s: failed to remove the record of the drain of the node from the Machine, with error: %v", machine.Name, err)
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.drained, nodeName)
+}
+
+// nodeNameOf returns the name of the node of the Machine: the nodeRef once it is set, and the InternalDNS address,
+// which is the hostname of the guest, before
+func nodeNameOf(machine *machinev1.Machine) string {
+	if machine.Status.NodeRef != nil {
+		return machine.Status.NodeRef.Name
+	}
+	for _, address := range machine.Status.Addresses {
+		if address.Type == corev1.NodeInternalDNS {
+			return address.Address
+		}
+	}
+	return ""
+}
+
+// isNodeDisrupted returns true when the infra-cluster node is cordoned or not ready
+func isNodeDisrupted(node corev1.Node) bool {
+	if node.Spec.Unschedulable {
+		return true
+	}
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady && condition.Status != corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// Add registers a new infra drain runnable with the controller manager, the Machines are checked against the
+// infra-cluster of machineInfraClients
+func Add(mgr manager.Manager, infraClusterClient infracluster.Client, machineInfraClients infracluster.MachineClients, tenantClusterClient tenantcluster.Client,
+	pollInterval time.Duration) error {
+	r := newInfraDrainReconciler(infraClusterClient, tenantClusterClient, pollInterval)
+	r.machineInfraClients = machineInfraClients
+	return mgr.Add(r)
+}
+
+func newInfraDrainReconciler(infraClusterClient infracluster.Client, tenantClusterClient tenantcluster.Client, pollInterval time.Duration) *infraDrainReconciler {
+	return &infraDrainReconciler{
+		infraClusterClient:  infraClusterClient,
+		tenantClusterClient: tenantClusterClient,
+		pollInterval:        pollInterval,
+		draining:            map[string]bool{},
+		drained:             map[string]bool{},
+	}
+}
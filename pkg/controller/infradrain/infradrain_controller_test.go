@@ -0,0 +1,323 @@
+package infradrain
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster"
+	mockInfraClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster/mock"
+	mockTenantClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster/mock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/kubevirt"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/testutils"
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtapiv1 "kubevirt.io/client-go/api/v1"
+)
+
+func stubInfraNode(name string, unschedulable bool, ready corev1.ConditionStatus) corev1.Node {
+	return corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       corev1.NodeSpec{Unschedulable: unschedulable},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: ready}},
+		},
+	}
+}
+
+func stubVMI(name string, nodeName string) kubevirtapiv1.VirtualMachineInstance {
+	return kubevirtapiv1.VirtualMachineInstance{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testutils.InfraNamespace},
+		Status:     kubevirtapiv1.VirtualMachineInstanceStatus{NodeName: nodeName},
+	}
+}
+
+// stubMachine returns a Machine of the Virtual Machine vmName, whose node is nodeName, and whose node was cordoned
+// for the drainedFor infra-cluster node unless it is empty
+func stubMachine(vmName string, nodeName string, drainedFor string) machinev1.Machine {
+	machine := testutils.StubMachineOfMachineSet(vmName, "")
+	machine.Spec.ProviderID = testutils.StringPointer(kubevirt.FormatProviderID(testutils.InfraNamespace, vmName))
+	machine.Status.Addresses = []corev1.NodeAddress{{Type: corev1.NodeInternalDNS, Address: nodeName}}
+	if drainedFor != "" {
+		machine.Annotations = map[string]string{InfraDrainedAnnotation: drainedFor}
+	}
+	return machine
+}
+
+func TestReconcile(t *testing.T) {
+	cMap := map[string]string{
+		configMapInfraNamespaceKeyName: testutils.InfraNamespace,
+	}
+	vmis := &kubevirtapiv1.VirtualMachineInstanceList{
+		Items: []kubevirtapiv1.VirtualMachineInstance{
+			stubVMI("worker-a", "infra-node-1"),
+			stubVMI("worker-b", "infra-node-2"),
+			stubVMI("not-owned", "infra-node-1"),
+		},
+	}
+	// The node of worker-b has an overridden hostname
+	machines := func() []machinev1.Machine {
+		return []machinev1.Machine{
+			stubMachine("worker-a", "worker-a", ""),
+			stubMachine("worker-b", "worker-b.example.com", ""),
+		}
+	}
+	cordonedMachines := func() []machinev1.Machine {
+		return []machinev1.Machine{
+			stubMachine("worker-a", "worker-a", "infra-node-1"),
+			stubMachine("worker-b", "worker-b.example.com", "infra-node-2"),
+		}
+	}
+	infraNodes := func(node1Unschedulable bool, node2Ready corev1.ConditionStatus) *corev1.NodeList {
+		return &corev1.NodeList{
+			Items: []corev1.Node{
+				stubInfraNode("infra-node-1", node1Unschedulable, corev1.ConditionTrue),
+				stubInfraNode("infra-node-2", false, node2Ready),
+			},
+		}
+	}
+	patchedAnnotation := func(expected string) func(*machinev1.Machine, *machinev1.Machine) error {
+		return func(machine *machinev1.Machine, _ *machinev1.Machine) error {
+			assert.Equal(t, machine.Annotations[InfraDrainedAnnotation], expected)
+			return nil
+		}
+	}
+
+	cases := []struct {
+		name        string
+		drained     []string
+		expectedErr string
+		expect      func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient)
+	}{
+		{
+			name: "Success no disrupted infra nodes",
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+				tenantClient.EXPECT().ListMachines(gomock.Any()).Return(machines(), nil).Times(1)
+				infraClient.EXPECT().ListNodes(gomock.Any(), gomock.Any()).Return(infraNodes(false, corev1.ConditionTrue), nil).Times(1)
+				infraClient.EXPECT().ListVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(vmis, nil).Times(1)
+			},
+		},
+		{
+			name: "Success drain nodes of cordoned infra node",
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+				tenantClient.EXPECT().ListMachines(gomock.Any()).Return(machines(), nil).Times(1)
+				infraClient.EXPECT().ListNodes(gomock.Any(), gomock.Any()).Return(infraNodes(true, corev1.ConditionTrue), nil).Times(1)
+				infraClient.EXPECT().ListVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(vmis, nil).Times(1)
+				tenantClient.EXPECT().IsNodeUnschedulable(gomock.Any(), "worker-a").Return(false, nil).Times(1)
+				tenantClient.EXPECT().PatchMachine(gomock.Any(), gomock.Any()).DoAndReturn(patchedAnnotation("infra-node-1")).Times(1)
+				tenantClient.EXPECT().CordonAndDrainNode(gomock.Any(), "worker-a").Return(nil).Times(1)
+			},
+		},
+		{
+			name: "Success drain nodes already cordoned",
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+				tenantClient.EXPECT().ListMachines(gomock.Any()).Return(machines(), nil).Times(1)
+				infraClient.EXPECT().ListNodes(gomock.Any(), gomock.Any()).Return(infraNodes(true, corev1.ConditionTrue), nil).Times(1)
+				infraClient.EXPECT().ListVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(vmis, nil).Times(1)
+				tenantClient.EXPECT().IsNodeUnschedulable(gomock.Any(), "worker-a").Return(true, nil).Times(1)
+				tenantClient.EXPECT().PatchMachine(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(machine *machinev1.Machine, _ *machinev1.Machine) error {
+						assert.Equal(t, machine.Annotations[InfraDrainedAnnotation], "infra-node-1")
+						assert.Equal(t, machine.Annotations[NodeCordonedAnnotation], "true")
+						return nil
+					}).Times(1)
+				tenantClient.EXPECT().CordonAndDrainNode(gomock.Any(), "worker-a").Return(nil).Times(1)
+			},
+		},
+		{
+			name: "Failure check whether the node is cordoned",
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+				tenantClient.EXPECT().ListMachines(gomock.Any()).Return(machines(), nil).Times(1)
+				infraClient.EXPECT().ListNodes(gomock.Any(), gomock.Any()).Return(infraNodes(true, corev1.ConditionTrue), nil).Times(1)
+				infraClient.EXPECT().ListVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(vmis, nil).Times(1)
+				tenantClient.EXPECT().IsNodeUnschedulable(gomock.Any(), "worker-a").Return(false, fmt.Errorf("test error")).Times(1)
+			},
+		},
+		{
+			name: "Success drain nodes of not ready infra node by their hostname",
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+				tenantClient.EXPECT().ListMachines(gomock.Any()).Return(machines(), nil).Times(1)
+				infraClient.EXPECT().ListNodes(gomock.Any(), gomock.Any()).Return(infraNodes(false, corev1.ConditionUnknown), nil).Times(1)
+				infraClient.EXPECT().ListVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(vmis, nil).Times(1)
+				tenantClient.EXPECT().IsNodeUnschedulable(gomock.Any(), "worker-b.example.com").Return(false, nil).Times(1)
+				tenantClient.EXPECT().PatchMachine(gomock.Any(), gomock.Any()).DoAndReturn(patchedAnnotation("infra-node-2")).Times(1)
+				tenantClient.EXPECT().CordonAndDrainNode(gomock.Any(), "worker-b.example.com").Return(nil).Times(1)
+			},
+		},
+		{
+			name:    "Success drained nodes aren't drained again",
+			drained: []string{"worker-a"},
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+				tenantClient.EXPECT().ListMachines(gomock.Any()).Return(cordonedMachines()[:1], nil).Times(1)
+				infraClient.EXPECT().ListNodes(gomock.Any(), gomock.Any()).Return(infraNodes(true, corev1.ConditionTrue), nil).Times(1)
+				infraClient.EXPECT().ListVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(vmis, nil).Times(1)
+			},
+		},
+		{
+			name: "Success cordoned nodes drained again after a restart",
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+				tenantClient.EXPECT().ListMachines(gomock.Any()).Return(cordonedMachines()[:1], nil).Times(1)
+				infraClient.EXPECT().ListNodes(gomock.Any(), gomock.Any()).Return(infraNodes(true, corev1.ConditionTrue), nil).Times(1)
+				infraClient.EXPECT().ListVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(vmis, nil).Times(1)
+				tenantClient.EXPECT().CordonAndDrainNode(gomock.Any(), "worker-a").Return(nil).Times(1)
+			},
+		},
+		{
+			name:    "Success uncordon nodes of recovered infra nodes",
+			drained: []string{"worker-a", "worker-b.example.com"},
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+				tenantClient.EXPECT().ListMachines(gomock.Any()).Return(cordonedMachines(), nil).Times(1)
+				infraClient.EXPECT().ListNodes(gomock.Any(), gomock.Any()).Return(infraNodes(false, corev1.ConditionTrue), nil).Times(1)
+				infraClient.EXPECT().ListVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(vmis, nil).Times(1)
+				tenantClient.EXPECT().UncordonNode(gomock.Any(), "worker-a").Return(nil).Times(1)
+				tenantClient.EXPECT().UncordonNode(gomock.Any(), "worker-b.example.com").Return(nil).Times(1)
+				tenantClient.EXPECT().PatchMachine(gomock.Any(), gomock.Any()).DoAndReturn(patchedAnnotation("")).Times(2)
+			},
+		},
+		{
+			name:    "Success nodes cordoned before their drain are left cordoned",
+			drained: []string{"worker-a"},
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				machine := stubMachine("worker-a", "worker-a", "infra-node-1")
+				machine.Annotations[NodeCordonedAnnotation] = "true"
+				tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+				tenantClient.EXPECT().ListMachines(gomock.Any()).Return([]machinev1.Machine{machine}, nil).Times(1)
+				infraClient.EXPECT().ListNodes(gomock.Any(), gomock.Any()).Return(infraNodes(false, corev1.ConditionTrue), nil).Times(1)
+				infraClient.EXPECT().ListVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(vmis, nil).Times(1)
+				tenantClient.EXPECT().UncordonNode(gomock.Any(), gomock.Any()).Times(0)
+				tenantClient.EXPECT().PatchMachine(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(machine *machinev1.Machine, _ *machinev1.Machine) error {
+						_, drained := machine.Annotations[InfraDrainedAnnotation]
+						_, cordoned := machine.Annotations[NodeCordonedAnnotation]
+						assert.Assert(t, !drained && !cordoned)
+						return nil
+					}).Times(1)
+			},
+		},
+		{
+			name:    "Success uncordon nodes migrated away from disrupted infra nodes",
+			drained: []string{"worker-a"},
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+				tenantClient.EXPECT().ListMachines(gomock.Any()).Return(cordonedMachines()[:1], nil).Times(1)
+				infraClient.EXPECT().ListNodes(gomock.Any(), gomock.Any()).Return(infraNodes(true, corev1.ConditionTrue), nil).Times(1)
+				infraClient.EXPECT().ListVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(
+					&kubevirtapiv1.VirtualMachineInstanceList{Items: []kubevirtapiv1.VirtualMachineInstance{stubVMI("worker-a", "infra-node-2")}}, nil).Times(1)
+				tenantClient.EXPECT().UncordonNode(gomock.Any(), "worker-a").Return(nil).Times(1)
+				tenantClient.EXPECT().PatchMachine(gomock.Any(), gomock.Any()).DoAndReturn(patchedAnnotation("")).Times(1)
+			},
+		},
+		{
+			name: "Success no Machines with a node",
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+				tenantClient.EXPECT().ListMachines(gomock.Any()).Return([]machinev1.Machine{stubMachine("worker-a", "", "")}, nil).Times(1)
+			},
+		},
+		{
+			name: "Failure list infra nodes",
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+				tenantClient.EXPECT().ListMachines(gomock.Any()).Return(machines(), nil).Times(1)
+				infraClient.EXPECT().ListNodes(gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("test error")).Times(1)
+			},
+			expectedErr: "failed to list infra-cluster nodes, with error: test error",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			infraClient := mockInfraClusterClient.NewMockClient(mockCtrl)
+			tenantClient := mockTenantClusterClient.NewMockClient(mockCtrl)
+
+			tc.expect(infraClient, tenantClient)
+
+			r := newInfraDrainReconciler(infraClient, tenantClient, 0)
+			for _, nodeName := range tc.drained {
+				r.drained[nodeName] = true
+			}
+			err := r.Reconcile(context.Background())
+			r.drains.Wait()
+			if tc.expectedErr != "" {
+				assert.Error(t, err, tc.expectedErr)
+			} else {
+				assert.NilError(t, err)
+			}
+		})
+	}
+}
+
+func TestReconcileCredentialsInfraClusters(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	controllerInfraClient := mockInfraClusterClient.NewMockClient(mockCtrl)
+	credentialsInfraClient := mockInfraClusterClient.NewMockClient(mockCtrl)
+	tenantClient := mockTenantClusterClient.NewMockClient(mockCtrl)
+	cMap := map[string]string{configMapInfraNamespaceKeyName: testutils.InfraNamespace}
+	// worker-b runs in the infra-cluster of a credentials secret, whose infra-node-1 is disrupted
+	machines := []machinev1.Machine{
+		stubMachine("worker-a", "worker-a", ""),
+		stubMachine("worker-b", "worker-b", ""),
+	}
+	machineInfraClients := func(_ context.Context, machine *machinev1.Machine) (infracluster.Client, error) {
+		if machine.Name == "worker-b" {
+			return credentialsInfraClient, nil
+		}
+		return controllerInfraClient, nil
+	}
+
+	tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+	tenantClient.EXPECT().ListMachines(gomock.Any()).Return(machines, nil).Times(1)
+	controllerInfraClient.EXPECT().ListNodes(gomock.Any(), gomock.Any()).Return(&corev1.NodeList{
+		Items: []corev1.Node{stubInfraNode("infra-node-1", false, corev1.ConditionTrue)},
+	}, nil).Times(1)
+	controllerInfraClient.EXPECT().ListVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(
+		&kubevirtapiv1.VirtualMachineInstanceList{Items: []kubevirtapiv1.VirtualMachineInstance{stubVMI("worker-a", "infra-node-1")}}, nil).Times(1)
+	credentialsInfraClient.EXPECT().ListNodes(gomock.Any(), gomock.Any()).Return(&corev1.NodeList{
+		Items: []corev1.Node{stubInfraNode("infra-node-1", true, corev1.ConditionTrue)},
+	}, nil).Times(1)
+	credentialsInfraClient.EXPECT().ListVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(
+		&kubevirtapiv1.VirtualMachineInstanceList{Items: []kubevirtapiv1.VirtualMachineInstance{stubVMI("worker-b", "infra-node-1")}}, nil).Times(1)
+	tenantClient.EXPECT().IsNodeUnschedulable(gomock.Any(), "worker-b").Return(false, nil).Times(1)
+	tenantClient.EXPECT().PatchMachine(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+	tenantClient.EXPECT().CordonAndDrainNode(gomock.Any(), "worker-b").Return(nil).Times(1)
+
+	r := newInfraDrainReconciler(controllerInfraClient, tenantClient, 0)
+	r.machineInfraClients = machineInfraClients
+	assert.NilError(t, r.Reconcile(context.Background()))
+	r.drains.Wait()
+}
+
+func TestDrainRetriedAfterFailure(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	tenantClient := mockTenantClusterClient.NewMockClient(mockCtrl)
+	r := newInfraDrainReconciler(nil, tenantClient, 0)
+	machine := stubMachine("worker-a", "worker-a", "infra-node-1")
+
+	tenantClient.EXPECT().CordonAndDrainNode(gomock.Any(), "worker-a").Return(fmt.Errorf("test error")).Times(1)
+	r.cordonAndDrain(context.Background(), &machine, "infra-node-1")
+	r.drains.Wait()
+	assert.Assert(t, !r.drained["worker-a"])
+
+	tenantClient.EXPECT().CordonAndDrainNode(gomock.Any(), "worker-a").Return(nil).Times(1)
+	r.cordonAndDrain(context.Background(), &machine, "infra-node-1")
+	r.drains.Wait()
+	assert.Assert(t, r.drained["worker-a"])
+
+	// Once drained, the node isn't drained again by the next polls
+	r.cordonAndDrain(context.Background(), &machine, "infra-node-1")
+	r.drains.Wait()
+}
@@ -0,0 +1,156 @@
+// infranamespace package implements a controller to set up the infra namespace, in which the VirtualMachines
+// of the tenant-cluster are created:
+// - Create the infra namespace if it doesn't exist, labeled as owned by the tenant-cluster
+// - Create the optional ResourceQuota and NetworkPolicy templates in the infra namespace, if they don't exist
+// It runs once when the manager starts, and retries until the infra namespace is set up.
+package infranamespace
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/yaml"
+
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/utils"
+)
+
+const (
+	configMapNamespace             = "openshift-config"
+	configMapName                  = "cloud-provider-config"
+	configMapDataKeyName           = "config"
+	configMapInfraNamespaceKeyName = "namespace"
+	configMapInfraIDKeyName        = "infraID"
+
+	// retryPeriod is the delay before retrying to set up the infra namespace
+	retryPeriod = 30 * time.Second
+)
+
+// Templates are the optional objects created in the infra namespace
+type Templates struct {
+	ResourceQuota *corev1.ResourceQuota
+	NetworkPolicy *networkingv1.NetworkPolicy
+}
+
+var _ manager.Runnable = &infraNamespaceReconciler{}
+
+type infraNamespaceReconciler struct {
+	infraClusterClient  infracluster.Client
+	tenantClusterClient tenantcluster.Client
+	templates           Templates
+}
+
+// Start sets up the infra namespace, retrying until it succeeds or the context is done
+func (r *infraNamespaceReconciler) Start(ctx context.Context) error {
+	err := wait.PollImmediateUntil(retryPeriod, func() (bool, error) {
+		if err := r.Reconcile(ctx); err != nil {
+			klog.Errorf("infra namespace: %v", err)
+			return false, nil
+		}
+		return true, nil
+	}, ctx.Done())
+	// The manager stopped before the infra namespace was set up
+	if err == wait.ErrWaitTimeout {
+		return nil
+	}
+	return err
+}
+
+// Reconcile creates the infra namespace and the templates in it, objects which already exist are left as they are
+func (r *infraNamespaceReconciler) Reconcile(ctx context.Context) error {
+	cMap, err := r.tenantClusterClient.GetConfigMapValue(ctx, configMapName, configMapNamespace, configMapDataKeyName)
+	if err != nil {
+		return err
+	}
+	infraNamespace, ok := (*cMap)[configMapInfraNamespaceKeyName]
+	if !ok {
+		return fmt.Errorf("configMap %s/%s: The map extracted with key %s doesn't contain key %s",
+			configMapNamespace, configMapName, configMapDataKeyName, configMapInfraNamespaceKeyName)
+	}
+	infraID, ok := (*cMap)[configMapInfraIDKeyName]
+	if !ok {
+		return fmt.Errorf("configMap %s/%s: The map extracted with key %s doesn't contain key %s",
+			configMapNamespace, configMapName, configMapDataKeyName, configMapInfraIDKeyName)
+	}
+
+	namespace := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   infraNamespace,
+			Labels: utils.BuildLabels(infraID),
+		},
+	}
+	if _, err := r.infraClusterClient.CreateNamespace(ctx, namespace); err != nil {
+		if !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create the infra namespace %s, with error: %v", infraNamespace, err)
+		}
+	} else {
+		klog.Infof("infra namespace %s was created", infraNamespace)
+	}
+
+	if r.templates.ResourceQuota != nil {
+		resourceQuota := r.templates.ResourceQuota.DeepCopy()
+		resourceQuota.Namespace = infraNamespace
+		if _, err := r.infraClusterClient.CreateResourceQuota(ctx, infraNamespace, resourceQuota); err != nil && !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create ResourceQuota %s in the infra namespace %s, with error: %v", resourceQuota.Name, infraNamespace, err)
+		}
+	}
+	if r.templates.NetworkPolicy != nil {
+		networkPolicy := r.templates.NetworkPolicy.DeepCopy()
+		networkPolicy.Namespace = infraNamespace
+		if _, err := r.infraClusterClient.CreateNetworkPolicy(ctx, infraNamespace, networkPolicy); err != nil && !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create NetworkPolicy %s in the infra namespace %s, with error: %v", networkPolicy.Name, infraNamespace, err)
+		}
+	}
+	return nil
+}
+
+// LoadTemplates reads the templates from the given YAML files, an empty path leaves its template unset
+func LoadTemplates(resourceQuotaPath, networkPolicyPath string) (Templates, error) {
+	templates := Templates{}
+	if resourceQuotaPath != "" {
+		templates.ResourceQuota = &corev1.ResourceQuota{}
+		if err := loadTemplate(resourceQuotaPath, templates.ResourceQuota); err != nil {
+			return Templates{}, err
+		}
+	}
+	if networkPolicyPath != "" {
+		templates.NetworkPolicy = &networkingv1.NetworkPolicy{}
+		if err := loadTemplate(networkPolicyPath, templates.NetworkPolicy); err != nil {
+			return Templates{}, err
+		}
+	}
+	return templates, nil
+}
+
+func loadTemplate(path string, obj metav1.Object) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read the template %s, with error: %v", path, err)
+	}
+	if err := yaml.UnmarshalStrict(data, obj); err != nil {
+		return fmt.Errorf("failed to parse the template %s, with error: %v", path, err)
+	}
+	if obj.GetName() == "" {
+		return fmt.Errorf("template %s has no name", path)
+	}
+	return nil
+}
+
+// Add registers the infra namespace runnable with the controller manager
+func Add(mgr manager.Manager, infraClusterClient infracluster.Client, tenantClusterClient tenantcluster.Client, templates Templates) error {
+	return mgr.Add(&infraNamespaceReconciler{
+		infraClusterClient:  infraClusterClient,
+		tenantClusterClient: tenantClusterClient,
+		templates:           templates,
+	})
+}
@@ -0,0 +1,170 @@
+package infranamespace
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	mockInfraClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster/mock"
+	mockTenantClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster/mock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/testutils"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/utils"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestReconcile(t *testing.T) {
+	cMap := map[string]string{
+		configMapInfraNamespaceKeyName: testutils.InfraNamespace,
+		configMapInfraIDKeyName:        testutils.InfraID,
+	}
+	namespace := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: testutils.InfraNamespace, Labels: utils.BuildLabels(testutils.InfraID)},
+	}
+	templates := Templates{
+		ResourceQuota: &corev1.ResourceQuota{ObjectMeta: metav1.ObjectMeta{Name: "test-quota"}},
+		NetworkPolicy: &networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: "test-policy"}},
+	}
+	resourceQuota := &corev1.ResourceQuota{ObjectMeta: metav1.ObjectMeta{Name: "test-quota", Namespace: testutils.InfraNamespace}}
+	networkPolicy := &networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: "test-policy", Namespace: testutils.InfraNamespace}}
+	alreadyExists := func(resource string) error {
+		return apierrors.NewAlreadyExists(schema.GroupResource{Resource: resource}, "test")
+	}
+
+	cases := []struct {
+		name        string
+		templates   Templates
+		expectedErr string
+		expect      func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient)
+	}{
+		{
+			name: "Success create namespace",
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+				infraClient.EXPECT().CreateNamespace(gomock.Any(), namespace).Return(namespace, nil).Times(1)
+			},
+		},
+		{
+			name:      "Success create namespace and templates",
+			templates: templates,
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+				infraClient.EXPECT().CreateNamespace(gomock.Any(), namespace).Return(namespace, nil).Times(1)
+				infraClient.EXPECT().CreateResourceQuota(gomock.Any(), testutils.InfraNamespace, resourceQuota).Return(resourceQuota, nil).Times(1)
+				infraClient.EXPECT().CreateNetworkPolicy(gomock.Any(), testutils.InfraNamespace, networkPolicy).Return(networkPolicy, nil).Times(1)
+			},
+		},
+		{
+			name:      "Success everything exists",
+			templates: templates,
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+				infraClient.EXPECT().CreateNamespace(gomock.Any(), namespace).Return(nil, alreadyExists("namespaces")).Times(1)
+				infraClient.EXPECT().CreateResourceQuota(gomock.Any(), testutils.InfraNamespace, resourceQuota).Return(nil, alreadyExists("resourcequotas")).Times(1)
+				infraClient.EXPECT().CreateNetworkPolicy(gomock.Any(), testutils.InfraNamespace, networkPolicy).Return(nil, alreadyExists("networkpolicies")).Times(1)
+			},
+		},
+		{
+			name: "Failure get configMap",
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(nil, fmt.Errorf("test error")).Times(1)
+			},
+			expectedErr: "test error",
+		},
+		{
+			name: "Failure create namespace",
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+				infraClient.EXPECT().CreateNamespace(gomock.Any(), namespace).Return(nil, fmt.Errorf("test error")).Times(1)
+			},
+			expectedErr: "failed to create the infra namespace test-infra-namespace, with error: test error",
+		},
+		{
+			name:      "Failure create resource quota",
+			templates: templates,
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+				infraClient.EXPECT().CreateNamespace(gomock.Any(), namespace).Return(namespace, nil).Times(1)
+				infraClient.EXPECT().CreateResourceQuota(gomock.Any(), testutils.InfraNamespace, resourceQuota).Return(nil, fmt.Errorf("test error")).Times(1)
+			},
+			expectedErr: "failed to create ResourceQuota test-quota in the infra namespace test-infra-namespace, with error: test error",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			infraClient := mockInfraClusterClient.NewMockClient(mockCtrl)
+			tenantClient := mockTenantClusterClient.NewMockClient(mockCtrl)
+			tc.expect(infraClient, tenantClient)
+
+			r := &infraNamespaceReconciler{
+				infraClusterClient:  infraClient,
+				tenantClusterClient: tenantClient,
+				templates:           tc.templates,
+			}
+			err := r.Reconcile(context.Background())
+			if tc.expectedErr != "" {
+				assert.Error(t, err, tc.expectedErr)
+			} else {
+				assert.NilError(t, err)
+			}
+		})
+	}
+}
+
+func TestLoadTemplates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "infranamespace")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	writeTemplate := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		assert.NilError(t, ioutil.WriteFile(path, []byte(content), 0644))
+		return path
+	}
+	resourceQuotaPath := writeTemplate("quota.yaml", `
+apiVersion: v1
+kind: ResourceQuota
+metadata:
+  name: test-quota
+spec:
+  hard:
+    requests.cpu: "16"
+`)
+	networkPolicyPath := writeTemplate("policy.yaml", `
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: test-policy
+spec:
+  podSelector: {}
+`)
+	noNamePath := writeTemplate("noname.yaml", `
+apiVersion: v1
+kind: ResourceQuota
+`)
+
+	templates, err := LoadTemplates(resourceQuotaPath, networkPolicyPath)
+	assert.NilError(t, err)
+	assert.Equal(t, templates.ResourceQuota.Name, "test-quota")
+	requestsCPU := templates.ResourceQuota.Spec.Hard[corev1.ResourceRequestsCPU]
+	assert.Equal(t, requestsCPU.String(), "16")
+	assert.Equal(t, templates.NetworkPolicy.Name, "test-policy")
+
+	templates, err = LoadTemplates("", "")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, templates, Templates{})
+
+	_, err = LoadTemplates(noNamePath, "")
+	assert.Error(t, err, fmt.Sprintf("template %s has no name", noNamePath))
+}
@@ -0,0 +1,259 @@
+// networkcheckup package implements a controller to check the network of the MachineSets before their Machines
+// are created, with the kubevirt-vm-latency checkup of the kiagnose framework:
+// - Run the checkup against the NetworkAttachmentDefinition of every MachineSet opting in with the checkup annotation
+// - Report its result as the NetworkReady condition of the MachineSet, stored in an annotation
+// - Run it again when the network of the MachineSet changes, or when the condition is removed
+// The checkup is a Job of the infra namespace, configured by a ConfigMap into which it writes its results. It runs
+// with the checkup service account, which the infra-cluster admin grants the permissions the checkup requires.
+package networkcheckup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster"
+)
+
+const (
+	configMapNamespace             = "openshift-config"
+	configMapName                  = "cloud-provider-config"
+	configMapDataKeyName           = "config"
+	configMapInfraNamespaceKeyName = "namespace"
+
+	// NetworkCheckupAnnotation opts a MachineSet in to the checkup of its network, when "true". The Machines of
+	// the MachineSet aren't created until the network is checked.
+	NetworkCheckupAnnotation = "kubevirt.machine.openshift.io/network-checkup"
+	// NetworkReadyConditionAnnotation is the NetworkReady condition of a MachineSet opting in to the checkup,
+	// as JSON, since the status of the MachineSets has no conditions
+	NetworkReadyConditionAnnotation = "kubevirt.machine.openshift.io/network-ready-condition"
+	// networkCheckedAnnotation is the namespace/name of the NetworkAttachmentDefinition the condition is about
+	networkCheckedAnnotation = "kubevirt.machine.openshift.io/network-checked"
+
+	// NetworkReadyCondition reports whether the checkup of the network of a MachineSet succeeded
+	NetworkReadyCondition  machinev1.ConditionType = "NetworkReady"
+	checkupRunningReason                           = "CheckupRunning"
+	checkupSucceededReason                         = "CheckupSucceeded"
+	checkupFailedReason                            = "CheckupFailed"
+
+	// DefaultCheckupImage is the image of the kubevirt-vm-latency checkup
+	DefaultCheckupImage = "quay.io/kiagnose/kubevirt-vm-latency:main"
+	// checkupServiceAccountName is the service account of the infra namespace the checkup runs with
+	checkupServiceAccountName = "vm-latency-checkup-sa"
+	// checkupTimeout bounds the run of the checkup, which then reports a failure
+	checkupTimeout = 5 * time.Minute
+	// checkupSampleDuration is the duration of the latency measurement between the checkup VirtualMachines
+	checkupSampleDuration = 5 * time.Second
+)
+
+var _ manager.Runnable = &networkCheckupReconciler{}
+
+type networkCheckupReconciler struct {
+	infraClusterClient  infracluster.Client
+	tenantClusterClient tenantcluster.Client
+	checkupImage        string
+	pollInterval        time.Duration
+}
+
+// Start checks the networks of the MachineSets until the context is done
+func (r *networkCheckupReconciler) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := r.Reconcile(ctx); err != nil {
+			klog.Errorf("network checkup: %v", err)
+		}
+	}, r.pollInterval)
+	return nil
+}
+
+// Reconcile runs the checkup of the network of the MachineSets which opt in to it and weren't checked yet,
+// and reports the result of the completed checkups on their MachineSet
+func (r *networkCheckupReconciler) Reconcile(ctx context.Context) error {
+	cMap, err := r.tenantClusterClient.GetConfigMapValue(ctx, configMapName, configMapNamespace, configMapDataKeyName)
+	if err != nil {
+		return err
+	}
+	infraNamespace, ok := (*cMap)[configMapInfraNamespaceKeyName]
+	if !ok {
+		return fmt.Errorf("configMap %s/%s: The map extracted with key %s doesn't contain key %s",
+			configMapNamespace, configMapName, configMapDataKeyName, configMapInfraNamespaceKeyName)
+	}
+
+	machineSets, err := r.tenantClusterClient.ListMachineSets(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list MachineSets, with error: %v", err)
+	}
+	for i := range machineSets {
+		machineSet := &machineSets[i]
+		if machineSet.Annotations[NetworkCheckupAnnotation] != "true" {
+			continue
+		}
+		providerSpec, err := kubevirtproviderv1alpha1.ProviderSpecFromRawExtension(machineSet.Spec.Template.Spec.ProviderSpec.Value)
+		if err != nil {
+			klog.Errorf("%s: failed to get the provider spec of the MachineSet, with error: %v", machineSet.Name, err)
+			continue
+		}
+		if providerSpec.NetworkName == "" {
+			continue
+		}
+		if err := r.reconcileMachineSet(ctx, machineSet, infraNamespace, providerSpec.NetworkName); err != nil {
+			klog.Errorf("%s: failed to check the network of the MachineSet, with error: %v", machineSet.Name, err)
+		}
+	}
+	return nil
+}
+
+// reconcileMachineSet starts the checkup of the network of the MachineSet, or reports its result once completed
+func (r *networkCheckupReconciler) reconcileMachineSet(ctx context.Context, machineSet *machinev1.MachineSet, infraNamespace string, networkName string) error {
+	network := infraNamespace + "/" + networkName
+	condition, err := GetNetworkReadyCondition(machineSet)
+	if err != nil {
+		klog.Warningf("%s: %v, checking the network again", machineSet.Name, err)
+	}
+	checked := machineSet.Annotations[networkCheckedAnnotation] == network
+	if checked && condition != nil && condition.Status != corev1.ConditionUnknown {
+		return nil
+	}
+
+	name := checkupName(machineSet)
+	configMap, err := r.infraClusterClient.GetConfigMap(ctx, infraNamespace, name)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to get the checkup ConfigMap %s, with error: %v", name, err)
+		}
+		klog.Infof("%s: checking the network %s of the MachineSet", machineSet.Name, network)
+		if err := r.startCheckup(ctx, infraNamespace, name, networkName); err != nil {
+			return err
+		}
+		return r.setNetworkReadyCondition(machineSet, network, corev1.ConditionUnknown, checkupRunningReason,
+			fmt.Sprintf("checking the network %s", network))
+	}
+	if configMap.Data["spec.param.networkAttachmentDefinitionName"] == networkName &&
+		configMap.Data["status.completionTimestamp"] == "" {
+		if !checked || condition == nil {
+			return r.setNetworkReadyCondition(machineSet, network, corev1.ConditionUnknown, checkupRunningReason,
+				fmt.Sprintf("checking the network %s", network))
+		}
+		return nil
+	}
+
+	if err := r.infraClusterClient.DeleteJob(ctx, infraNamespace, name); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete the checkup Job %s, with error: %v", name, err)
+	}
+	if err := r.infraClusterClient.DeleteConfigMap(ctx, infraNamespace, name); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete the checkup ConfigMap %s, with error: %v", name, err)
+	}
+	// A checkup of the previous network of the MachineSet is thrown away
+	if configMap.Data["spec.param.networkAttachmentDefinitionName"] != networkName {
+		return nil
+	}
+	if configMap.Data["status.succeeded"] != "true" {
+		klog.Warningf("%s: the checkup of the network %s of the MachineSet failed: %s", machineSet.Name, network, configMap.Data["status.failureReason"])
+		return r.setNetworkReadyCondition(machineSet, network, corev1.ConditionFalse, checkupFailedReason,
+			fmt.Sprintf("the checkup of the network %s failed: %s", network, configMap.Data["status.failureReason"]))
+	}
+	message := fmt.Sprintf("the checkup of the network %s succeeded", network)
+	if latency := configMap.Data["status.result.avgLatencyNanoSec"]; latency != "" {
+		message = fmt.Sprintf("%s, with an average latency of %sns", message, latency)
+	}
+	return r.setNetworkReadyCondition(machineSet, network, corev1.ConditionTrue, checkupSucceededReason, message)
+}
+
+// startCheckup creates the ConfigMap of the checkup of the network, and the Job running it
+func (r *networkCheckupReconciler) startCheckup(ctx context.Context, infraNamespace string, name string, networkName string) error {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: infraNamespace},
+		Data: map[string]string{
+			"spec.timeout": checkupTimeout.String(),
+			"spec.param.networkAttachmentDefinitionNamespace": infraNamespace,
+			"spec.param.networkAttachmentDefinitionName":      networkName,
+			"spec.param.sampleDurationSeconds":                fmt.Sprintf("%d", int(checkupSampleDuration.Seconds())),
+		},
+	}
+	if _, err := r.infraClusterClient.CreateConfigMap(ctx, infraNamespace, configMap); err != nil {
+		return fmt.Errorf("failed to create the checkup ConfigMap %s, with error: %v", name, err)
+	}
+	backoffLimit := int32(0)
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: infraNamespace},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					ServiceAccountName: checkupServiceAccountName,
+					RestartPolicy:      corev1.RestartPolicyNever,
+					Containers: []corev1.Container{{
+						Name:  "network-checkup",
+						Image: r.checkupImage,
+						Env: []corev1.EnvVar{
+							{Name: "CONFIGMAP_NAMESPACE", Value: infraNamespace},
+							{Name: "CONFIGMAP_NAME", Value: name},
+						},
+					}},
+				},
+			},
+		},
+	}
+	if _, err := r.infraClusterClient.CreateJob(ctx, infraNamespace, job); err != nil {
+		return fmt.Errorf("failed to create the checkup Job %s, with error: %v", name, err)
+	}
+	return nil
+}
+
+// setNetworkReadyCondition records the NetworkReady condition of the MachineSet, about the network
+func (r *networkCheckupReconciler) setNetworkReadyCondition(machineSet *machinev1.MachineSet, network string,
+	status corev1.ConditionStatus, reason string, message string) error {
+	originMachineSetCopy := machineSet.DeepCopy()
+	value, err := json.Marshal(machinev1.Condition{
+		Type:               NetworkReadyCondition,
+		Status:             status,
+		LastTransitionTime: metav1.Now(),
+		Reason:             reason,
+		Message:            message,
+	})
+	if err != nil {
+		return err
+	}
+	machineSet.Annotations[NetworkReadyConditionAnnotation] = string(value)
+	machineSet.Annotations[networkCheckedAnnotation] = network
+	return r.tenantClusterClient.PatchMachineSet(machineSet, originMachineSetCopy)
+}
+
+// GetNetworkReadyCondition returns the NetworkReady condition of the MachineSet, or nil if it has none
+func GetNetworkReadyCondition(machineSet *machinev1.MachineSet) (*machinev1.Condition, error) {
+	value, ok := machineSet.Annotations[NetworkReadyConditionAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	condition := &machinev1.Condition{}
+	if err := json.Unmarshal([]byte(value), condition); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation, with error: %v", NetworkReadyConditionAnnotation, err)
+	}
+	return condition, nil
+}
+
+// checkupName returns the name of the ConfigMap and of the Job of the checkup of the network of the MachineSet
+func checkupName(machineSet *machinev1.MachineSet) string {
+	return machineSet.Name + "-network-checkup"
+}
+
+// Add registers the network checkup runnable with the controller manager
+func Add(mgr manager.Manager, infraClusterClient infracluster.Client, tenantClusterClient tenantcluster.Client, checkupImage string, pollInterval time.Duration) error {
+	return mgr.Add(&networkCheckupReconciler{
+		infraClusterClient:  infraClusterClient,
+		tenantClusterClient: tenantClusterClient,
+		checkupImage:        checkupImage,
+		pollInterval:        pollInterval,
+	})
+}
@@ -0,0 +1,195 @@
+package networkcheckup
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+	mockInfraClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster/mock"
+	mockTenantClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster/mock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/testutils"
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"gotest.tools/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	testNetworkName = "test-network"
+	testCheckupName = "workers-network-checkup"
+	testNetwork     = testutils.InfraNamespace + "/" + testNetworkName
+)
+
+func stubMachineSet(t *testing.T, annotations map[string]string) machinev1.MachineSet {
+	machineSet, err := testutils.StubMachineSet("workers", annotations, kubevirtproviderv1alpha1.KubevirtMachineProviderSpec{NetworkName: testNetworkName})
+	assert.NilError(t, err)
+	return machineSet
+}
+
+func stubCondition(status corev1.ConditionStatus) string {
+	if status == corev1.ConditionTrue {
+		return `{"type":"NetworkReady","status":"True","reason":"CheckupSucceeded"}`
+	}
+	return `{"type":"NetworkReady","status":"` + string(status) + `","reason":"CheckupRunning"}`
+}
+
+func stubCheckupConfigMap(networkName string, data map[string]string) *corev1.ConfigMap {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: testCheckupName, Namespace: testutils.InfraNamespace},
+		Data:       map[string]string{"spec.param.networkAttachmentDefinitionName": networkName},
+	}
+	for key, value := range data {
+		configMap.Data[key] = value
+	}
+	return configMap
+}
+
+func TestReconcile(t *testing.T) {
+	cMap := map[string]string{configMapInfraNamespaceKeyName: testutils.InfraNamespace}
+	notFoundErr := apierrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, testCheckupName)
+
+	cases := []struct {
+		name                  string
+		machineSetAnnotations map[string]string
+		expect                func(infraClient *mockInfraClusterClient.MockClient)
+		expectedStatus        corev1.ConditionStatus
+		expectedMessage       string
+	}{
+		{
+			name:                  "Success skip the machine sets not opting in",
+			machineSetAnnotations: map[string]string{},
+			expect:                func(infraClient *mockInfraClusterClient.MockClient) {},
+		},
+		{
+			name:                  "Success start the checkup",
+			machineSetAnnotations: map[string]string{NetworkCheckupAnnotation: "true"},
+			expect: func(infraClient *mockInfraClusterClient.MockClient) {
+				infraClient.EXPECT().GetConfigMap(gomock.Any(), testutils.InfraNamespace, testCheckupName).Return(nil, notFoundErr).Times(1)
+				infraClient.EXPECT().CreateConfigMap(gomock.Any(), testutils.InfraNamespace, gomock.Any()).DoAndReturn(
+					func(ctx context.Context, namespace string, configMap *corev1.ConfigMap) (*corev1.ConfigMap, error) {
+						assert.Equal(t, configMap.Data["spec.param.networkAttachmentDefinitionNamespace"], testutils.InfraNamespace)
+						assert.Equal(t, configMap.Data["spec.param.networkAttachmentDefinitionName"], testNetworkName)
+						return configMap, nil
+					}).Times(1)
+				infraClient.EXPECT().CreateJob(gomock.Any(), testutils.InfraNamespace, gomock.Any()).DoAndReturn(
+					func(ctx context.Context, namespace string, job *batchv1.Job) (*batchv1.Job, error) {
+						assert.Equal(t, job.Spec.Template.Spec.Containers[0].Image, DefaultCheckupImage)
+						assert.Equal(t, job.Spec.Template.Spec.ServiceAccountName, checkupServiceAccountName)
+						return job, nil
+					}).Times(1)
+			},
+			expectedStatus:  corev1.ConditionUnknown,
+			expectedMessage: "checking the network " + testNetwork,
+		},
+		{
+			name: "Success wait for the checkup",
+			machineSetAnnotations: map[string]string{
+				NetworkCheckupAnnotation:        "true",
+				NetworkReadyConditionAnnotation: stubCondition(corev1.ConditionUnknown),
+				networkCheckedAnnotation:        testNetwork,
+			},
+			expect: func(infraClient *mockInfraClusterClient.MockClient) {
+				infraClient.EXPECT().GetConfigMap(gomock.Any(), testutils.InfraNamespace, testCheckupName).Return(
+					stubCheckupConfigMap(testNetworkName, nil), nil).Times(1)
+			},
+		},
+		{
+			name: "Success report the succeeded checkup",
+			machineSetAnnotations: map[string]string{
+				NetworkCheckupAnnotation:        "true",
+				NetworkReadyConditionAnnotation: stubCondition(corev1.ConditionUnknown),
+				networkCheckedAnnotation:        testNetwork,
+			},
+			expect: func(infraClient *mockInfraClusterClient.MockClient) {
+				infraClient.EXPECT().GetConfigMap(gomock.Any(), testutils.InfraNamespace, testCheckupName).Return(
+					stubCheckupConfigMap(testNetworkName, map[string]string{
+						"status.completionTimestamp":      "2022-01-01T00:00:00Z",
+						"status.succeeded":                "true",
+						"status.result.avgLatencyNanoSec": "177000",
+					}), nil).Times(1)
+				infraClient.EXPECT().DeleteJob(gomock.Any(), testutils.InfraNamespace, testCheckupName).Return(nil).Times(1)
+				infraClient.EXPECT().DeleteConfigMap(gomock.Any(), testutils.InfraNamespace, testCheckupName).Return(nil).Times(1)
+			},
+			expectedStatus:  corev1.ConditionTrue,
+			expectedMessage: "the checkup of the network " + testNetwork + " succeeded, with an average latency of 177000ns",
+		},
+		{
+			name: "Success report the failed checkup",
+			machineSetAnnotations: map[string]string{
+				NetworkCheckupAnnotation:        "true",
+				NetworkReadyConditionAnnotation: stubCondition(corev1.ConditionUnknown),
+				networkCheckedAnnotation:        testNetwork,
+			},
+			expect: func(infraClient *mockInfraClusterClient.MockClient) {
+				infraClient.EXPECT().GetConfigMap(gomock.Any(), testutils.InfraNamespace, testCheckupName).Return(
+					stubCheckupConfigMap(testNetworkName, map[string]string{
+						"status.completionTimestamp": "2022-01-01T00:00:00Z",
+						"status.succeeded":           "false",
+						"status.failureReason":       "failed to connect the checkup VirtualMachines",
+					}), nil).Times(1)
+				infraClient.EXPECT().DeleteJob(gomock.Any(), testutils.InfraNamespace, testCheckupName).Return(nil).Times(1)
+				infraClient.EXPECT().DeleteConfigMap(gomock.Any(), testutils.InfraNamespace, testCheckupName).Return(nil).Times(1)
+			},
+			expectedStatus:  corev1.ConditionFalse,
+			expectedMessage: "the checkup of the network " + testNetwork + " failed: failed to connect the checkup VirtualMachines",
+		},
+		{
+			name: "Success skip the checked network",
+			machineSetAnnotations: map[string]string{
+				NetworkCheckupAnnotation:        "true",
+				NetworkReadyConditionAnnotation: stubCondition(corev1.ConditionTrue),
+				networkCheckedAnnotation:        testNetwork,
+			},
+			expect: func(infraClient *mockInfraClusterClient.MockClient) {},
+		},
+		{
+			name: "Success throw away the checkup of the previous network",
+			machineSetAnnotations: map[string]string{
+				NetworkCheckupAnnotation:        "true",
+				NetworkReadyConditionAnnotation: stubCondition(corev1.ConditionTrue),
+				networkCheckedAnnotation:        testutils.InfraNamespace + "/previous-network",
+			},
+			expect: func(infraClient *mockInfraClusterClient.MockClient) {
+				infraClient.EXPECT().GetConfigMap(gomock.Any(), testutils.InfraNamespace, testCheckupName).Return(
+					stubCheckupConfigMap("previous-network", nil), nil).Times(1)
+				infraClient.EXPECT().DeleteJob(gomock.Any(), testutils.InfraNamespace, testCheckupName).Return(nil).Times(1)
+				infraClient.EXPECT().DeleteConfigMap(gomock.Any(), testutils.InfraNamespace, testCheckupName).Return(nil).Times(1)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			infraClient := mockInfraClusterClient.NewMockClient(mockCtrl)
+			tenantClient := mockTenantClusterClient.NewMockClient(mockCtrl)
+			tc.expect(infraClient)
+			tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+			tenantClient.EXPECT().ListMachineSets(gomock.Any()).Return([]machinev1.MachineSet{stubMachineSet(t, tc.machineSetAnnotations)}, nil).Times(1)
+			var patchedMachineSet *machinev1.MachineSet
+			tenantClient.EXPECT().PatchMachineSet(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(machineSet *machinev1.MachineSet, originMachineSetCopy *machinev1.MachineSet) error {
+					patchedMachineSet = machineSet
+					return nil
+				}).AnyTimes()
+
+			r := &networkCheckupReconciler{infraClusterClient: infraClient, tenantClusterClient: tenantClient, checkupImage: DefaultCheckupImage}
+			assert.NilError(t, r.Reconcile(context.Background()))
+			if tc.expectedStatus == "" {
+				assert.Assert(t, patchedMachineSet == nil)
+				return
+			}
+			assert.Equal(t, patchedMachineSet.Annotations[networkCheckedAnnotation], testNetwork)
+			condition, err := GetNetworkReadyCondition(patchedMachineSet)
+			assert.NilError(t, err)
+			assert.Equal(t, condition.Type, NetworkReadyCondition)
+			assert.Equal(t, condition.Status, tc.expectedStatus)
+			assert.Equal(t, condition.Message, tc.expectedMessage)
+		})
+	}
+}
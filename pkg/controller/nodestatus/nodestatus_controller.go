@@ -0,0 +1,201 @@
+// nodestatus package implements a controller to sync the status of the Node of a Machine into the Machine:
+// - The Ready condition of the Node, without its heartbeat time, and the kubelet version of the Node are copied to the KubevirtMachineProviderStatus, so the health of the worker can be read from the Machine alone
+// - The Node is reconciled only when its Ready condition or its kubelet version changes, not on every heartbeat
+// - The Node is mapped to its Machine by a field index of the nodeRef of the Machines, or of the InternalDNS address when the nodeRef isn't set yet
+// - The creation of the Node is recorded as the last step of the provisioning timeline of the Machine
+package nodestatus
+
+import (
+	"context"
+	"fmt"
+
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/metrics"
+)
+
+// machineNodeNameIndex is the field index of the Machines by the name of their node: the nodeRef, or the InternalDNS
+// address before the nodeRef is set
+const machineNodeNameIndex = "status.nodeRef.name"
+
+var _ reconcile.Reconciler = &nodeStatusReconciler{}
+
+type nodeStatusReconciler struct {
+	client client.Client
+}
+
+// Reconcile copies the Ready condition and the kubelet version of a node into the provider status of its Machine
+func (r *nodeStatusReconciler) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	node := corev1.Node{}
+	if err := r.client.Get(ctx, request.NamespacedName, &node); err != nil {
+		if errors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, fmt.Errorf("error getting node: %v", err)
+	}
+
+	machines := machinev1.MachineList{}
+	if err := r.client.List(ctx, &machines, client.MatchingFields{machineNodeNameIndex: node.Name}); err != nil {
+		return reconcile.Result{}, fmt.Errorf("%s: Error listing Machines, with error: %v", node.Name, err)
+	}
+	machine := machineOfNode(machines.Items, node.Name)
+	if machine == nil {
+		return reconcile.Result{}, nil
+	}
+
+	providerStatus, err := kubevirtproviderv1alpha1.ProviderStatusFromRawExtension(machine.Status.ProviderStatus)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("%s: Error getting provider status of Machine %s, with error: %v", node.Name, machine.Name, err)
+	}
+	if !syncNodeStatus(providerStatus, &node) {
+		return reconcile.Result{}, nil
+	}
+	if machine.Status.ProviderStatus, err = kubevirtproviderv1alpha1.RawExtensionFromProviderStatus(providerStatus); err != nil {
+		return reconcile.Result{}, fmt.Errorf("%s: Error setting provider status of Machine %s, with error: %v", node.Name, machine.Name, err)
+	}
+
+	klog.Infof("%s: Node status changed - update the provider status of Machine %s", node.Name, machine.Name)
+	if err := r.client.Status().Update(ctx, machine); err != nil {
+		return reconcile.Result{}, fmt.Errorf("%s: failed updating Machine %s, with error: %v", node.Name, machine.Name, err)
+	}
+	return reconcile.Result{}, nil
+}
+
+// indexMachineNodeName returns the name of the node of the Machine for the machineNodeNameIndex
+func indexMachineNodeName(obj client.Object) []string {
+	machine, ok := obj.(*machinev1.Machine)
+	if !ok {
+		return nil
+	}
+	if machine.Status.NodeRef != nil {
+		return []string{machine.Status.NodeRef.Name}
+	}
+	var nodeNames []string
+	for _, address := range machine.Status.Addresses {
+		if address.Type == corev1.NodeInternalDNS {
+			nodeNames = append(nodeNames, address.Address)
+		}
+	}
+	return nodeNames
+}
+
+// machineOfNode returns the Machine which refers the node, or has its name as the InternalDNS address,
+// or nil if there is no such Machine
+func machineOfNode(machines []machinev1.Machine, nodeName string) *machinev1.Machine {
+	for i := range machines {
+		if machines[i].Status.NodeRef != nil && machines[i].Status.NodeRef.Name == nodeName {
+			return &machines[i]
+		}
+	}
+	for i := range machines {
+		if machines[i].Status.NodeRef != nil {
+			continue
+		}
+		for _, address := range machines[i].Status.Addresses {
+			if address.Type == corev1.NodeInternalDNS && address.Address == nodeName {
+				return &machines[i]
+			}
+		}
+	}
+	return nil
+}
+
+// syncNodeStatus sets the Ready condition and the kubelet version of the node on the provider status,
+// and returns true if the provider status was changed
+func syncNodeStatus(providerStatus *kubevirtproviderv1alpha1.KubevirtMachineProviderStatus, node *corev1.Node) bool {
+	readyCondition := nodeReadyCondition(node)
+	kubeletVersion := node.Status.NodeInfo.KubeletVersion
+
+	changed := syncNodeJoined(providerStatus, node)
+	if equality.Semantic.DeepEqual(providerStatus.NodeReadyCondition, readyCondition) && providerStatus.KubeletVersion == kubeletVersion {
+		return changed
+	}
+	providerStatus.NodeReadyCondition = readyCondition
+	providerStatus.KubeletVersion = kubeletVersion
+	return true
+}
+
+// nodeReadyCondition returns a copy of the Ready condition of the node without its heartbeat time, or nil if the node
+// has no Ready condition
+func nodeReadyCondition(node *corev1.Node) *corev1.NodeCondition {
+	for i := range node.Status.Conditions {
+		if node.Status.Conditions[i].Type == corev1.NodeReady {
+			readyCondition := node.Status.Conditions[i].DeepCopy()
+			// The heartbeat changes on every status report of the kubelet, don't follow it
+			readyCondition.LastHeartbeatTime = metav1.Time{}
+			return readyCondition
+		}
+	}
+	return nil
+}
+
+// nodeStatusChanged returns true when the Ready condition or the kubelet version of the node changed, the other
+// updates of the node, as the heartbeats of the kubelet, don't change the provider status of its Machine
+func nodeStatusChanged(e event.UpdateEvent) bool {
+	oldNode, ok := e.ObjectOld.(*corev1.Node)
+	if !ok {
+		return false
+	}
+	newNode, ok := e.ObjectNew.(*corev1.Node)
+	if !ok {
+		return false
+	}
+	return !equality.Semantic.DeepEqual(nodeReadyCondition(oldNode), nodeReadyCondition(newNode)) ||
+		oldNode.Status.NodeInfo.KubeletVersion != newNode.Status.NodeInfo.KubeletVersion
+}
+
+// syncNodeJoined records the creation of the node in the provisioning timeline, and returns true
+// if it wasn't recorded before
+func syncNodeJoined(providerStatus *kubevirtproviderv1alpha1.KubevirtMachineProviderStatus, node *corev1.Node) bool {
+	if node.CreationTimestamp.IsZero() {
+		return false
+	}
+	if providerStatus.ProvisioningTimeline == nil {
+		providerStatus.ProvisioningTimeline = &kubevirtproviderv1alpha1.ProvisioningTimeline{}
+	}
+	timeline := providerStatus.ProvisioningTimeline
+	if timeline.NodeJoined != nil {
+		return false
+	}
+	timeline.NodeJoined = node.CreationTimestamp.DeepCopy()
+	if timeline.VMIRunning != nil {
+		metrics.ObserveProvisioningPhase(metrics.ProvisioningPhaseJoin, timeline.VMIRunning.Time, timeline.NodeJoined.Time)
+	}
+	return true
+}
+
+// Add registers a new node status reconciler controller with the controller manager
+func Add(mgr manager.Manager) error {
+	c, err := controller.New("nodestatus-controller", mgr, controller.Options{Reconciler: &nodeStatusReconciler{
+		client: mgr.GetClient(),
+	}})
+	if err != nil {
+		return err
+	}
+
+	err = mgr.GetFieldIndexer().IndexField(context.Background(), &machinev1.Machine{}, machineNodeNameIndex, indexMachineNodeName)
+	if err != nil {
+		return err
+	}
+
+	//Watch the changes of the Ready condition and the kubelet version of the nodes, the deleted nodes have no status
+	//to sync
+	return c.Watch(&source.Kind{Type: &corev1.Node{}}, &handler.EnqueueRequestForObject{}, predicate.Funcs{
+		UpdateFunc: nodeStatusChanged,
+		DeleteFunc: func(event.DeleteEvent) bool { return false },
+	})
+}
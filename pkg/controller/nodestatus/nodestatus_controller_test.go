@@ -0,0 +1,209 @@
+package nodestatus
+
+import (
+	"context"
+	"testing"
+
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/testutils"
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestSyncNodeStatus(t *testing.T) {
+	transitionTime := metav1.Unix(1600000000, 0)
+	readyCondition := corev1.NodeCondition{
+		Type:               corev1.NodeReady,
+		Status:             corev1.ConditionTrue,
+		Reason:             "KubeletReady",
+		LastTransitionTime: transitionTime,
+	}
+	nodeCreated := metav1.Unix(1600000050, 0)
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-node", CreationTimestamp: nodeCreated},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeMemoryPressure, Status: corev1.ConditionFalse},
+				{
+					Type:               corev1.NodeReady,
+					Status:             corev1.ConditionTrue,
+					Reason:             "KubeletReady",
+					LastHeartbeatTime:  metav1.Unix(1600000100, 0),
+					LastTransitionTime: transitionTime,
+				},
+			},
+			NodeInfo: corev1.NodeSystemInfo{KubeletVersion: "v1.20.0"},
+		},
+	}
+
+	cases := []struct {
+		name            string
+		providerStatus  *kubevirtproviderv1alpha1.KubevirtMachineProviderStatus
+		expectedChanged bool
+	}{
+		{
+			name:            "provider status without node status",
+			providerStatus:  &kubevirtproviderv1alpha1.KubevirtMachineProviderStatus{},
+			expectedChanged: true,
+		},
+		{
+			name: "provider status with an older kubelet version",
+			providerStatus: &kubevirtproviderv1alpha1.KubevirtMachineProviderStatus{
+				NodeReadyCondition: readyCondition.DeepCopy(),
+				KubeletVersion:     "v1.19.0",
+			},
+			expectedChanged: true,
+		},
+		{
+			name: "provider status with the same node status",
+			providerStatus: &kubevirtproviderv1alpha1.KubevirtMachineProviderStatus{
+				NodeReadyCondition: readyCondition.DeepCopy(),
+				KubeletVersion:     "v1.20.0",
+				ProvisioningTimeline: &kubevirtproviderv1alpha1.ProvisioningTimeline{
+					NodeJoined: &nodeCreated,
+				},
+			},
+			expectedChanged: false,
+		},
+		{
+			name: "provider status with the same node status before the node joined",
+			providerStatus: &kubevirtproviderv1alpha1.KubevirtMachineProviderStatus{
+				NodeReadyCondition: readyCondition.DeepCopy(),
+				KubeletVersion:     "v1.20.0",
+				ProvisioningTimeline: &kubevirtproviderv1alpha1.ProvisioningTimeline{
+					VMIRunning: &transitionTime,
+				},
+			},
+			expectedChanged: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedChanged, syncNodeStatus(tc.providerStatus, node))
+			assert.DeepEqual(t, &node.CreationTimestamp, tc.providerStatus.ProvisioningTimeline.NodeJoined)
+			assert.DeepEqual(t, &readyCondition, tc.providerStatus.NodeReadyCondition)
+			assert.Equal(t, "v1.20.0", tc.providerStatus.KubeletVersion)
+		})
+	}
+}
+
+func TestMachineOfNode(t *testing.T) {
+	machines := []machinev1.Machine{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "machine-by-address"},
+			Status: machinev1.MachineStatus{
+				Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalDNS, Address: "node-b"}},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "machine-by-node-ref"},
+			Status: machinev1.MachineStatus{
+				NodeRef:   &corev1.ObjectReference{Name: "node-a"},
+				Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalDNS, Address: "node-a"}},
+			},
+		},
+	}
+	assert.Equal(t, "machine-by-node-ref", machineOfNode(machines, "node-a").Name)
+	assert.Equal(t, "machine-by-address", machineOfNode(machines, "node-b").Name)
+	assert.Assert(t, machineOfNode(machines, "node-c") == nil)
+}
+
+func TestIndexMachineNodeName(t *testing.T) {
+	machine := &machinev1.Machine{
+		Status: machinev1.MachineStatus{
+			Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeInternalIP, Address: "10.0.0.1"},
+				{Type: corev1.NodeInternalDNS, Address: "node-a"},
+			},
+		},
+	}
+	assert.DeepEqual(t, indexMachineNodeName(machine), []string{"node-a"})
+	machine.Status.NodeRef = &corev1.ObjectReference{Name: "node-b"}
+	assert.DeepEqual(t, indexMachineNodeName(machine), []string{"node-b"})
+}
+
+func TestNodeStatusChanged(t *testing.T) {
+	stubNode := func(status corev1.ConditionStatus, heartbeat int64, kubeletVersion string) *corev1.Node {
+		return &corev1.Node{Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: status, LastHeartbeatTime: metav1.Unix(heartbeat, 0)},
+				{Type: corev1.NodeMemoryPressure, Status: corev1.ConditionFalse, LastHeartbeatTime: metav1.Unix(heartbeat, 0)},
+			},
+			NodeInfo: corev1.NodeSystemInfo{KubeletVersion: kubeletVersion},
+		}}
+	}
+
+	cases := []struct {
+		name     string
+		oldNode  *corev1.Node
+		newNode  *corev1.Node
+		expected bool
+	}{
+		{
+			name:    "heartbeat only",
+			oldNode: stubNode(corev1.ConditionTrue, 1600000000, "v1.20.0"),
+			newNode: stubNode(corev1.ConditionTrue, 1600000040, "v1.20.0"),
+		},
+		{
+			name:     "ready condition changed",
+			oldNode:  stubNode(corev1.ConditionTrue, 1600000000, "v1.20.0"),
+			newNode:  stubNode(corev1.ConditionFalse, 1600000040, "v1.20.0"),
+			expected: true,
+		},
+		{
+			name:     "ready condition added",
+			oldNode:  &corev1.Node{},
+			newNode:  stubNode(corev1.ConditionFalse, 1600000000, ""),
+			expected: true,
+		},
+		{
+			name:     "kubelet version changed",
+			oldNode:  stubNode(corev1.ConditionTrue, 1600000000, "v1.20.0"),
+			newNode:  stubNode(corev1.ConditionTrue, 1600000000, "v1.21.0"),
+			expected: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, nodeStatusChanged(event.UpdateEvent{ObjectOld: tc.oldNode, ObjectNew: tc.newNode}))
+		})
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	node := corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-a"},
+		Status:     corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{KubeletVersion: "v1.20.0"}},
+	}
+	machines := []machinev1.Machine{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "machine-of-other-node"},
+			Status:     machinev1.MachineStatus{NodeRef: &corev1.ObjectReference{Name: "node-b"}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "machine-of-node"},
+			Status:     machinev1.MachineStatus{NodeRef: &corev1.ObjectReference{Name: "node-a"}},
+		},
+	}
+	fakeClient := testutils.NewFakeClient([]corev1.Node{node}, machines)
+	assert.NilError(t, fakeClient.IndexField(context.Background(), &machinev1.Machine{}, machineNodeNameIndex, indexMachineNodeName))
+
+	r := &nodeStatusReconciler{client: fakeClient}
+	_, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: types.NamespacedName{Name: node.Name}})
+	assert.NilError(t, err)
+
+	for _, machine := range fakeClient.Machines {
+		providerStatus, err := kubevirtproviderv1alpha1.ProviderStatusFromRawExtension(machine.Status.ProviderStatus)
+		assert.NilError(t, err)
+		if machine.Name == "machine-of-node" {
+			assert.Equal(t, "v1.20.0", providerStatus.KubeletVersion)
+		} else {
+			assert.Equal(t, "", providerStatus.KubeletVersion)
+		}
+	}
+}
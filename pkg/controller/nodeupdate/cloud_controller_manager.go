@@ -0,0 +1,73 @@
+package nodeupdate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+)
+
+// configMapCloudControllerManagerKeyName declares that the KubeVirt cloud-controller-manager manages the nodes,
+// when "true", without detecting it by its lease
+const configMapCloudControllerManagerKeyName = "cloudControllerManager"
+
+// topologyLabels are the labels of the nodes which the KubeVirt cloud-controller-manager sets
+var topologyLabels = []string{"topology.kubernetes.io/zone", "topology.kubernetes.io/region"}
+
+// cloudControllerManagerActive returns true when the KubeVirt cloud-controller-manager manages the providerID, the
+// zone and the deletion of the nodes: when the cloud provider configMap declares it, or its leader election lease is
+// held. The controller then leaves them to the cloud-controller-manager, for both not to fight over the nodes.
+func (r *providerIDReconciler) cloudControllerManagerActive(ctx context.Context, cMap map[string]string) (bool, error) {
+	if cMap[configMapCloudControllerManagerKeyName] == "true" {
+		return true, nil
+	}
+	if r.ccmLease.Name == "" {
+		return false, nil
+	}
+	lease, err := r.tenantClusterClient.GetLease(ctx, r.ccmLease.Name, r.ccmLease.Namespace)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("error getting the lease %s of the cloud-controller-manager: %v", r.ccmLease, err)
+	}
+	return leaseHeld(lease, time.Now()), nil
+}
+
+// leaseHeld returns true when the lease has a holder which renewed it within its duration
+func leaseHeld(lease *coordinationv1.Lease, now time.Time) bool {
+	spec := lease.Spec
+	if spec.HolderIdentity == nil || *spec.HolderIdentity == "" || spec.RenewTime == nil || spec.LeaseDurationSeconds == nil {
+		return false
+	}
+	return now.Before(spec.RenewTime.Add(time.Duration(*spec.LeaseDurationSeconds) * time.Second))
+}
+
+// withoutTopologyLabels returns a copy of the provider spec whose NodeLabels don't set the topology labels, which the
+// cloud-controller-manager sets
+func withoutTopologyLabels(providerSpec *kubevirtproviderv1alpha1.KubevirtMachineProviderSpec) *kubevirtproviderv1alpha1.KubevirtMachineProviderSpec {
+	providerSpec = providerSpec.DeepCopy()
+	for _, label := range topologyLabels {
+		delete(providerSpec.NodeLabels, label)
+	}
+	return providerSpec
+}
+
+// ParseLease parses the namespace/name of the lease of the cloud-controller-manager, an empty value disables its
+// detection by the lease
+func ParseLease(value string) (types.NamespacedName, error) {
+	if value == "" {
+		return types.NamespacedName{}, nil
+	}
+	parts := strings.Split(value, "/")
+	if len(parts) == 2 && parts[0] != "" && parts[1] != "" {
+		return types.NamespacedName{Namespace: parts[0], Name: parts[1]}, nil
+	}
+	return types.NamespacedName{}, fmt.Errorf("invalid lease %q, expected namespace/name", value)
+}
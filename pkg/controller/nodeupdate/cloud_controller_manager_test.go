@@ -0,0 +1,187 @@
+package nodeupdate
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"gotest.tools/assert"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+	mockInfraClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster/mock"
+	mockTenantClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster/mock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/kubevirt"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/testutils"
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+)
+
+var ccmLease = types.NamespacedName{Namespace: "kube-system", Name: "cloud-controller-manager"}
+
+func stubLease(holder string, renewed time.Time) *coordinationv1.Lease {
+	return &coordinationv1.Lease{
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       pointer.StringPtr(holder),
+			LeaseDurationSeconds: pointer.Int32Ptr(15),
+			RenewTime:            &metav1.MicroTime{Time: renewed},
+		},
+	}
+}
+
+func TestLeaseHeld(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		name     string
+		lease    *coordinationv1.Lease
+		expected bool
+	}{
+		{
+			name:     "lease renewed within its duration",
+			lease:    stubLease("ccm-0", now.Add(-5*time.Second)),
+			expected: true,
+		},
+		{
+			name:  "lease expired",
+			lease: stubLease("ccm-0", now.Add(-time.Minute)),
+		},
+		{
+			name:  "lease released",
+			lease: stubLease("", now),
+		},
+		{
+			name:  "lease never acquired",
+			lease: &coordinationv1.Lease{},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, leaseHeld(tc.lease, now), tc.expected)
+		})
+	}
+}
+
+func TestParseLease(t *testing.T) {
+	lease, err := ParseLease("kube-system/cloud-controller-manager")
+	assert.NilError(t, err)
+	assert.Equal(t, lease, ccmLease)
+
+	lease, err = ParseLease("")
+	assert.NilError(t, err)
+	assert.Equal(t, lease, types.NamespacedName{})
+
+	_, err = ParseLease("cloud-controller-manager")
+	assert.Error(t, err, `invalid lease "cloud-controller-manager", expected namespace/name`)
+}
+
+func TestReconcileWithCloudControllerManager(t *testing.T) {
+	const (
+		nodeName = "test-node"
+		vmID     = "test-vm-id"
+	)
+	cMap := map[string]string{
+		configMapInfraNamespaceKeyName: testutils.InfraNamespace,
+		configMapInfraIDKeyName:        testutils.InfraID,
+	}
+	ccmCMap := map[string]string{
+		configMapInfraNamespaceKeyName:         testutils.InfraNamespace,
+		configMapInfraIDKeyName:                testutils.InfraID,
+		configMapCloudControllerManagerKeyName: "true",
+	}
+	providerID := kubevirt.FormatProviderID(testutils.InfraNamespace, testutils.MachineName)
+	notFoundErr := apierrors.NewNotFound(schema.GroupResource{Resource: "virtualmachines"}, testutils.MachineName)
+	labeledMachine := stubNodeMachine(t, nodeName, vmID, func(spec *kubevirtproviderv1alpha1.KubevirtMachineProviderSpec) {
+		spec.NodeLabels = map[string]string{"node-role.kubernetes.io/infra": "", "topology.kubernetes.io/zone": "zone-a"}
+	})
+	readyVM := testutils.StubVirtualMachine(nil, nil, testutils.StringPointer(vmID))
+	readyVM.Status.Ready = true
+
+	cases := []struct {
+		name               string
+		node               corev1.Node
+		cMap               map[string]string
+		expect             func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient)
+		expectedErr        string
+		expectedProviderID string
+		expectedLabels     map[string]string
+		expectedDeleted    []string
+	}{
+		{
+			name: "Success declared in the configMap - apply the labels without the providerID and the zone",
+			node: stubNode(nodeName, ""),
+			cMap: ccmCMap,
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				infraClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(readyVM, nil).Times(1)
+			},
+			expectedLabels: map[string]string{"node-role.kubernetes.io/infra": ""},
+		},
+		{
+			name: "Success lease held - leave the deletion of the node",
+			node: stubNode(nodeName, providerID),
+			cMap: cMap,
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().GetLease(gomock.Any(), ccmLease.Name, ccmLease.Namespace).Return(stubLease("ccm-0", time.Now()), nil).Times(1)
+				infraClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(nil, notFoundErr).Times(1)
+			},
+			expectedProviderID: providerID,
+		},
+		{
+			name: "Success lease not found - set the providerID",
+			node: stubNode(nodeName, ""),
+			cMap: cMap,
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().GetLease(gomock.Any(), ccmLease.Name, ccmLease.Namespace).Return(nil,
+					apierrors.NewNotFound(schema.GroupResource{Resource: "leases"}, ccmLease.Name)).Times(1)
+				infraClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(readyVM, nil).Times(1)
+			},
+			expectedProviderID: providerID,
+			expectedLabels:     map[string]string{"node-role.kubernetes.io/infra": "", "topology.kubernetes.io/zone": "zone-a"},
+		},
+		{
+			name: "Failure get lease",
+			node: stubNode(nodeName, ""),
+			cMap: cMap,
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().GetLease(gomock.Any(), ccmLease.Name, ccmLease.Namespace).Return(nil, fmt.Errorf("test error")).Times(1)
+			},
+			expectedErr: "error getting the lease kube-system/cloud-controller-manager of the cloud-controller-manager: test error",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			infraClient := mockInfraClusterClient.NewMockClient(mockCtrl)
+			tenantClient := mockTenantClusterClient.NewMockClient(mockCtrl)
+			tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&tc.cMap, nil).Times(1)
+			tc.expect(infraClient, tenantClient)
+			fakeClient := testutils.NewFakeClient([]corev1.Node{tc.node}, []machinev1.Machine{labeledMachine})
+
+			r := &providerIDReconciler{
+				client:                 fakeClient,
+				infraClusterClient:     infraClient,
+				tenantClusterClient:    tenantClient,
+				vmNotReadyRequeueAfter: vmNotReadyRequeueAfter,
+				ccmLease:               ccmLease,
+			}
+			_, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: types.NamespacedName{Name: nodeName}})
+			if tc.expectedErr != "" {
+				assert.Error(t, err, tc.expectedErr)
+				return
+			}
+			assert.NilError(t, err)
+			assert.DeepEqual(t, tc.expectedDeleted, fakeClient.DeletedNodes)
+			node := fakeClient.Nodes[nodeName]
+			assert.Equal(t, tc.expectedProviderID, node.Spec.ProviderID)
+			assert.DeepEqual(t, tc.expectedLabels, node.Labels)
+		})
+	}
+}
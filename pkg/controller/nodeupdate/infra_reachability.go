@@ -0,0 +1,44 @@
+package nodeupdate
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster"
+)
+
+var (
+	infraReachable = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "kubevirt_infra_cluster_reachable",
+			Help: "1 when the last check of the infra-cluster API by the providerID controller succeeded, 0 while it's unreachable and the deletion of the nodes is suppressed",
+		},
+	)
+	suppressedNodeDeletions = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "kubevirt_node_deletions_suppressed_total",
+			Help: "Number of node deletions suppressed since the infra-cluster API was unreachable",
+		},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(infraReachable, suppressedNodeDeletions)
+}
+
+// infraClusterReachable returns true when the VirtualMachines of the infra-cluster namespace can be listed, for the
+// missing Virtual Machine of a node not to be mistaken for a deleted one during an outage of the infra-cluster API:
+// the circuit breaker rejecting the requests, or the KubeVirt API not being served, aren't a deleted Virtual Machine.
+func infraClusterReachable(ctx context.Context, infraClusterClient infracluster.Client, infraClusterNamespace string) bool {
+	if _, err := infraClusterClient.ListVirtualMachine(ctx, infraClusterNamespace, metav1.ListOptions{Limit: 1}); err != nil {
+		klog.Warningf("The infra-cluster API is unreachable, with error: %v", err)
+		infraReachable.Set(0)
+		return false
+	}
+	infraReachable.Set(1)
+	return true
+}
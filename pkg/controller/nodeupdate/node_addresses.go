@@ -0,0 +1,96 @@
+package nodeupdate
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+	kubevirtapiv1 "kubevirt.io/client-go/api/v1"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// externalCloudProviderTaint is set by the kubelets run with an external cloud provider, until the cloud provider
+// initializes the node
+var externalCloudProviderTaint = corev1.Taint{Key: "node.cloudprovider.kubernetes.io/uninitialized", Effect: corev1.TaintEffectNoSchedule}
+
+// reconcileNodeAddresses sets the addresses of the Virtual Machine Instance on the node, and removes the taint of
+// the external cloud provider once the node has an internal IP. It's only run in node lifecycle only mode, in which
+// the addresses aren't synced to Machines.
+func (r *providerIDReconciler) reconcileNodeAddresses(node *corev1.Node, infraClusterNamespace string, vmName string) (reconcile.Result, error) {
+	vmi, err := r.infraClusterClient.GetVirtualMachineInstance(context.Background(), infraClusterNamespace, vmName, &metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			klog.Infof("%s: Virtual Machine Instance of this node doesn't exist - requeue for %v", node.Name, r.vmNotReadyRequeueAfter)
+			return reconcile.Result{Requeue: true, RequeueAfter: r.vmNotReadyRequeueAfter}, nil
+		}
+		return reconcile.Result{}, fmt.Errorf("%s: Error getting Virtual Machine Instance, with error: %v", node.Name, err)
+	}
+
+	addresses := nodeAddresses(node.Name, vmi)
+	if !equality.Semantic.DeepEqual(node.Status.Addresses, addresses) {
+		klog.Infof("%s: syncing the addresses of the node to %v", node.Name, addresses)
+		node.Status.Addresses = addresses
+		if err := r.client.Status().Update(context.Background(), node); err != nil {
+			return reconcile.Result{}, fmt.Errorf("%s: failed updating the addresses of node, with error: %v", node.Name, err)
+		}
+	}
+
+	if !hasInternalIP(addresses) {
+		klog.Infof("%s: Virtual Machine Instance of this node has no IP yet - requeue for %v", node.Name, r.vmNotReadyRequeueAfter)
+		return reconcile.Result{Requeue: true, RequeueAfter: r.vmNotReadyRequeueAfter}, nil
+	}
+	if taintExists(node.Spec.Taints, &externalCloudProviderTaint) {
+		klog.Infof("%s: node is initialized - remove the external cloud provider taint", node.Name)
+		taints := []corev1.Taint{}
+		for _, taint := range node.Spec.Taints {
+			if !taint.MatchTaint(&externalCloudProviderTaint) {
+				taints = append(taints, taint)
+			}
+		}
+		node.Spec.Taints = taints
+		if err := r.client.Update(context.Background(), node); err != nil {
+			return reconcile.Result{}, fmt.Errorf("%s: failed updating node, with error: %v", node.Name, err)
+		}
+	}
+	return reconcile.Result{}, nil
+}
+
+// nodeAddresses returns the hostname of the node, and the IPs of the interfaces of the Virtual Machine Instance
+// as its internal IPs, without the link-local ones
+func nodeAddresses(nodeName string, vmi *kubevirtapiv1.VirtualMachineInstance) []corev1.NodeAddress {
+	addresses := []corev1.NodeAddress{
+		{Type: corev1.NodeHostName, Address: nodeName},
+		{Type: corev1.NodeInternalDNS, Address: nodeName},
+	}
+	seen := map[string]bool{}
+	for _, iface := range vmi.Status.Interfaces {
+		ips := iface.IPs
+		if len(ips) == 0 && iface.IP != "" {
+			ips = []string{iface.IP}
+		}
+		for _, address := range ips {
+			ip := net.ParseIP(address)
+			if ip == nil || ip.IsLinkLocalUnicast() || seen[ip.String()] {
+				continue
+			}
+			seen[ip.String()] = true
+			addresses = append(addresses, corev1.NodeAddress{Type: corev1.NodeInternalIP, Address: ip.String()})
+		}
+	}
+	return addresses
+}
+
+// hasInternalIP returns true when the addresses have an internal IP
+func hasInternalIP(addresses []corev1.NodeAddress) bool {
+	for _, address := range addresses {
+		if address.Type == corev1.NodeInternalIP {
+			return true
+		}
+	}
+	return false
+}
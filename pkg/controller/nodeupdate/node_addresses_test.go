@@ -0,0 +1,177 @@
+package nodeupdate
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	kubevirtapiv1 "kubevirt.io/client-go/api/v1"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	mockInfraClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster/mock"
+	mockTenantClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster/mock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/kubevirt"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/testutils"
+)
+
+func stubVMIWithIPs(ips ...string) *kubevirtapiv1.VirtualMachineInstance {
+	vmi := testutils.StubVirtualMachineInstance()
+	vmi.Status.Interfaces = []kubevirtapiv1.VirtualMachineInstanceNetworkInterface{{Name: "default", IPs: ips}}
+	return vmi
+}
+
+func TestNodeAddresses(t *testing.T) {
+	vmi := testutils.StubVirtualMachineInstance()
+	vmi.Status.Interfaces = []kubevirtapiv1.VirtualMachineInstanceNetworkInterface{
+		{Name: "default", IP: "10.0.0.5"},
+		{Name: "secondary", IPs: []string{"192.168.1.5", "fe80::1", "fd00::5", "10.0.0.5"}},
+	}
+	assert.DeepEqual(t, nodeAddresses("test-node", vmi), []corev1.NodeAddress{
+		{Type: corev1.NodeHostName, Address: "test-node"},
+		{Type: corev1.NodeInternalDNS, Address: "test-node"},
+		{Type: corev1.NodeInternalIP, Address: "10.0.0.5"},
+		{Type: corev1.NodeInternalIP, Address: "192.168.1.5"},
+		{Type: corev1.NodeInternalIP, Address: "fd00::5"},
+	})
+}
+
+func TestReconcileNodeLifecycleOnly(t *testing.T) {
+	const nodeName = "test-guest"
+	providerID := kubevirt.FormatProviderID(testutils.InfraNamespace, testutils.MachineName)
+	cMap := map[string]string{
+		configMapInfraNamespaceKeyName: testutils.InfraNamespace,
+		configMapInfraIDKeyName:        testutils.InfraID,
+	}
+	notFoundErr := apierrors.NewNotFound(schema.GroupResource{Resource: "virtualmachines"}, testutils.MachineName)
+	gpuTaint := corev1.Taint{Key: "nvidia.com/gpu", Value: "true", Effect: corev1.TaintEffectNoSchedule}
+	addresses := []corev1.NodeAddress{
+		{Type: corev1.NodeHostName, Address: nodeName},
+		{Type: corev1.NodeInternalDNS, Address: nodeName},
+		{Type: corev1.NodeInternalIP, Address: "10.0.0.5"},
+	}
+	readyVM := func() *kubevirtapiv1.VirtualMachine {
+		vm := testutils.StubVirtualMachine(nil, nil, nil)
+		vm.Status.Ready = true
+		return vm
+	}
+	taintedNode := func(providerID string) corev1.Node {
+		node := stubNode(nodeName, providerID)
+		node.Spec.Taints = []corev1.Taint{externalCloudProviderTaint, gpuTaint}
+		return node
+	}
+
+	cases := []struct {
+		name               string
+		node               corev1.Node
+		expect             func(infraClient *mockInfraClusterClient.MockClient)
+		expectedResult     reconcile.Result
+		expectedProviderID string
+		expectedAddresses  []corev1.NodeAddress
+		expectedTaints     []corev1.Taint
+		expectedDeleted    []string
+	}{
+		{
+			name: "Success set providerID and addresses, and remove the external cloud provider taint",
+			node: func() corev1.Node {
+				node := taintedNode("")
+				node.Name = testutils.MachineName
+				return node
+			}(),
+			expect: func(infraClient *mockInfraClusterClient.MockClient) {
+				infraClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(readyVM(), nil).Times(1)
+				infraClient.EXPECT().GetVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(stubVMIWithIPs("10.0.0.5"), nil).Times(1)
+			},
+			expectedProviderID: providerID,
+			expectedAddresses: []corev1.NodeAddress{
+				{Type: corev1.NodeHostName, Address: testutils.MachineName},
+				{Type: corev1.NodeInternalDNS, Address: testutils.MachineName},
+				{Type: corev1.NodeInternalIP, Address: "10.0.0.5"},
+			},
+			expectedTaints: []corev1.Taint{gpuTaint},
+		},
+		{
+			name: "Success vm found by the providerID of the node",
+			node: taintedNode(providerID),
+			expect: func(infraClient *mockInfraClusterClient.MockClient) {
+				infraClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(readyVM(), nil).Times(1)
+				infraClient.EXPECT().GetVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(stubVMIWithIPs("10.0.0.5"), nil).Times(1)
+			},
+			expectedProviderID: providerID,
+			expectedAddresses:  addresses,
+			expectedTaints:     []corev1.Taint{gpuTaint},
+		},
+		{
+			name: "Success vmi without IP - keep the taint and requeue",
+			node: taintedNode(providerID),
+			expect: func(infraClient *mockInfraClusterClient.MockClient) {
+				infraClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(readyVM(), nil).Times(1)
+				infraClient.EXPECT().GetVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(stubVMIWithIPs(), nil).Times(1)
+			},
+			expectedResult:     reconcile.Result{Requeue: true, RequeueAfter: vmNotReadyRequeueAfter},
+			expectedProviderID: providerID,
+			expectedAddresses:  addresses[:2],
+			expectedTaints:     []corev1.Taint{externalCloudProviderTaint, gpuTaint},
+		},
+		{
+			name: "Success vm missing - delete the node",
+			node: stubNode(nodeName, providerID),
+			expect: func(infraClient *mockInfraClusterClient.MockClient) {
+				infraClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(nil, notFoundErr).Times(1)
+				infraClient.EXPECT().ListVirtualMachine(gomock.Any(), testutils.InfraNamespace, metav1.ListOptions{Limit: 1}).Return(&kubevirtapiv1.VirtualMachineList{}, nil).Times(1)
+			},
+			expectedDeleted: []string{nodeName},
+		},
+		{
+			name:               "Success node of another infra namespace - do nothing",
+			node:               stubNode(nodeName, kubevirt.FormatProviderID("other-infra-namespace", testutils.MachineName)),
+			expect:             func(infraClient *mockInfraClusterClient.MockClient) {},
+			expectedProviderID: kubevirt.FormatProviderID("other-infra-namespace", testutils.MachineName),
+		},
+		{
+			name: "Success vm missing of a node without providerID - do nothing",
+			node: stubNode(testutils.MachineName, ""),
+			expect: func(infraClient *mockInfraClusterClient.MockClient) {
+				infraClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(nil, notFoundErr).Times(1)
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			infraClient := mockInfraClusterClient.NewMockClient(mockCtrl)
+			tenantClient := mockTenantClusterClient.NewMockClient(mockCtrl)
+			tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+			tc.expect(infraClient)
+			// The Machines are never listed in node lifecycle only mode
+			fakeClient := testutils.NewFakeClient([]corev1.Node{tc.node}, nil)
+			fakeClient.ListErr = fmt.Errorf("unexpected list")
+
+			r := &providerIDReconciler{
+				client:                 fakeClient,
+				infraClusterClient:     infraClient,
+				tenantClusterClient:    tenantClient,
+				vmNotReadyRequeueAfter: vmNotReadyRequeueAfter,
+				nodeLifecycleOnly:      true,
+			}
+			result, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: types.NamespacedName{Name: tc.node.Name}})
+			assert.NilError(t, err)
+			assert.DeepEqual(t, tc.expectedResult, result)
+			assert.DeepEqual(t, tc.expectedDeleted, fakeClient.DeletedNodes)
+			if node, ok := fakeClient.Nodes[tc.node.Name]; ok {
+				assert.Equal(t, tc.expectedProviderID, node.Spec.ProviderID)
+				assert.DeepEqual(t, tc.expectedAddresses, node.Status.Addresses)
+				if tc.expectedTaints != nil {
+					assert.DeepEqual(t, tc.expectedTaints, node.Spec.Taints)
+				}
+			}
+		})
+	}
+}
@@ -1,7 +1,21 @@
 // nodeupdate package implements a controller to reconcile updates on the Node of the Machine:
 // - Update providerID spec property on nodes in order to identify a machine by a node and vice versa.
-// - In case the infrastructure machine (kubevirt VirtualMachine) was delete, delete its node
+// - The node is mapped to its Machine by the InternalDNS address, as the hostname may differ from the Machine name
+// - The VirtualMachine is read from the infra-cluster of the Machine, named by the credentials secret of its provider spec
+// - The VirtualMachine is verified against the VmId annotation of the Machine
+// - Apply the NodeLabels and NodeTaints of the Machine provider spec to the node, whenever it's linked to its Machine
+// - With a NodeSmokeCheck, remove the uninitialized taint the kubelet registered the node with once it passes the check
+// - In case the infrastructure machine (kubevirt VirtualMachine) of a Machine was delete, delete its node
+// - Nodes with a providerID of another provider, or which aren't linked to a Machine, are never deleted
+// - The node isn't deleted while the infra-cluster API is unreachable, as its Virtual Machine can't be told apart
+// from a deleted one: the outage is reported by the kubevirt_infra_cluster_reachable metric, and the node is requeued
 // - In case the infrastructure machine (kubevirt VirtualMachine) is not ready, requeue to re-check
+// - The Virtual Machines are watched as well, so the node is reconciled once its Virtual Machine turns ready
+// - In node lifecycle only mode, for the Virtual Machines created by other means than Machines, the nodes aren't
+// linked to Machines: their addresses are synced from the Virtual Machine Instance, the taint of the external
+// cloud provider is removed once they are, and they are deleted with their Virtual Machine
+// - When the KubeVirt cloud-controller-manager manages the nodes, the providerID, the topology labels, the addresses
+// and the deletion of the nodes are left to it
 // This functionality is traditionally (but not mandatory) a part of a
 // cloud-provider implementation and it is what makes auto-scaling works.
 package nodeupdate
@@ -13,17 +27,24 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/klog"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
 	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster"
 	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster"
 	"github.com/openshift/cluster-api-provider-kubevirt/pkg/kubevirt"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/utils"
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -33,15 +54,23 @@ const (
 	configMapDataKeyName           = "config"
 	configMapInfraNamespaceKeyName = "namespace"
 	configMapInfraIDKeyName        = "infraID"
-	requeueDurationWhenVMNotReady  = 60 * time.Second
 )
 
 var _ reconcile.Reconciler = &providerIDReconciler{}
 
 type providerIDReconciler struct {
-	client              client.Client
-	infraClusterClient  infracluster.Client
+	client             client.Client
+	infraClusterClient infracluster.Client
+	// machineInfraClients returns the Client of the infra-cluster of a Machine, which differs from the infra-cluster
+	// of the controller when its provider spec names a credentials secret
+	machineInfraClients infracluster.MachineClients
 	tenantClusterClient tenantcluster.Client
+	// vmNotReadyRequeueAfter is the delay before re-checking a node whose Virtual Machine isn't ready
+	vmNotReadyRequeueAfter time.Duration
+	// nodeLifecycleOnly reconciles the nodes without Machines, of the Virtual Machines created by other means
+	nodeLifecycleOnly bool
+	// ccmLease is the leader election lease of the KubeVirt cloud-controller-manager, which is detected by it when set
+	ccmLease types.NamespacedName
 }
 
 // Reconcile make sure a node has a ProviderID set. The providerID is the ID
@@ -75,10 +104,62 @@ func (r *providerIDReconciler) Reconcile(ctx context.Context, request reconcile.
 		return reconcile.Result{}, fmt.Errorf("ProviderID: configMap %s/%s: The map extracted with key %s doesn't contain key %s",
 			configMapNamespace, configMapName, configMapDataKeyName, configMapInfraNamespaceKeyName)
 	}
+	ccmActive, err := r.cloudControllerManagerActive(context.Background(), *cMap)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
 
-	vm, err := r.infraClusterClient.GetVirtualMachine(context.Background(), infraClusterNamespace, node.Name, &metav1.GetOptions{})
+	var machine *machinev1.Machine
+	if !r.nodeLifecycleOnly {
+		if machine, err = r.machineOfNode(node); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+	// The node name is the hostname of the guest, the Virtual Machine is found by the providerID of the Machine,
+	// or of the node in node lifecycle only mode
+	vmName := node.Name
+	if r.nodeLifecycleOnly && node.Spec.ProviderID != "" {
+		var vmNamespace string
+		if vmNamespace, vmName, err = kubevirt.ParseProviderID(node.Spec.ProviderID); err != nil {
+			return reconcile.Result{}, fmt.Errorf("%s: Error parsing providerID of node, with error: %v", node.Name, err)
+		}
+		// The Virtual Machines of other infra namespaces aren't watched, nor are their nodes managed
+		if vmNamespace != infraClusterNamespace {
+			klog.Infof("%s: providerID of this node is in infra namespace %s, not in %s - do nothing", node.Name, vmNamespace, infraClusterNamespace)
+			return reconcile.Result{}, nil
+		}
+	}
+	if machine != nil {
+		vmName = machine.Name
+		if machine.Spec.ProviderID != nil && *machine.Spec.ProviderID != "" {
+			if _, vmName, err = kubevirt.ParseProviderID(*machine.Spec.ProviderID); err != nil {
+				return reconcile.Result{}, fmt.Errorf("%s: Error parsing providerID of Machine %s, with error: %v", node.Name, machine.Name, err)
+			}
+		}
+	}
+
+	infraClusterClient, err := r.infraClusterClientOf(context.Background(), machine)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("%s: Error getting the infra-cluster client of Machine %s, with error: %v", node.Name, machine.Name, err)
+	}
+	vm, err := infraClusterClient.GetVirtualMachine(context.Background(), infraClusterNamespace, vmName, &metav1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
+			// In node lifecycle only mode, the node is linked to its Virtual Machine by the providerID set by the controller
+			ownedNode := r.nodeLifecycleOnly && node.Spec.ProviderID != ""
+			if !ownedNode && (machine == nil || machine.Annotations[machinescope.KubevirtIdAnnotationKey] == "") {
+				klog.Infof("%s: Virtual Machine of this node doesn't exists, but the node isn't owned by a Machine - do nothing", node.Name)
+				return reconcile.Result{}, nil
+			}
+			if ccmActive {
+				klog.Infof("%s: Virtual Machine of this node doesn't exists - leave the node to the cloud-controller-manager", node.Name)
+				return reconcile.Result{}, nil
+			}
+			if !infraClusterReachable(context.Background(), infraClusterClient, infraClusterNamespace) {
+				klog.Warningf("%s: Virtual Machine of this node isn't found while the infra-cluster API is unreachable - keep the node and requeue for %v", node.Name, r.vmNotReadyRequeueAfter)
+				suppressedNodeDeletions.Inc()
+				return reconcile.Result{Requeue: true, RequeueAfter: r.vmNotReadyRequeueAfter}, nil
+			}
 			klog.Infof("%s: Virtual Machine of this node doesn't exists - delete the node", node.Name)
 			if err := r.client.Delete(context.Background(), &node); err != nil {
 				return reconcile.Result{}, fmt.Errorf("%s: Error deleting Node, with error: %v", node.Name, err)
@@ -88,33 +169,174 @@ func (r *providerIDReconciler) Reconcile(ctx context.Context, request reconcile.
 		return reconcile.Result{}, fmt.Errorf("%s: Error getting Virtual Machine, with error: %v", node.Name, err)
 	}
 
+	if machine != nil {
+		if vmID, ok := machine.Annotations[machinescope.KubevirtIdAnnotationKey]; ok && vmID != string(vm.UID) {
+			klog.Infof("%s: Virtual Machine %s doesn't match the VmId annotation of Machine %s - requeue for %v", node.Name, vmName, machine.Name, r.vmNotReadyRequeueAfter)
+			return reconcile.Result{Requeue: true, RequeueAfter: r.vmNotReadyRequeueAfter}, nil
+		}
+	}
+
 	if !vm.Status.Ready {
-		klog.Infof("%s: Virtual Machine of this node isn't ready - requeue for 1 minute", node.Name)
-		return reconcile.Result{Requeue: true, RequeueAfter: requeueDurationWhenVMNotReady}, nil
+		klog.Infof("%s: Virtual Machine of this node isn't ready - requeue for %v", node.Name, r.vmNotReadyRequeueAfter)
+		return reconcile.Result{Requeue: true, RequeueAfter: r.vmNotReadyRequeueAfter}, nil
+	}
+
+	var providerSpec *kubevirtproviderv1alpha1.KubevirtMachineProviderSpec
+	if machine != nil {
+		providerSpec, err = kubevirtproviderv1alpha1.ProviderSpecFromRawExtension(machine.Spec.ProviderSpec.Value)
+		if err != nil {
+			return reconcile.Result{}, fmt.Errorf("%s: Error getting provider spec of Machine %s, with error: %v", node.Name, machine.Name, err)
+		}
 	}
 
 	if node.Spec.ProviderID != "" {
+		if providerSpec != nil {
+			// The labels and taints are applied on every reconcile, so those added to the provider spec after the
+			// node joined, or removed from the node, are applied as well
+			if ccmActive {
+				providerSpec = withoutTopologyLabels(providerSpec)
+			}
+			if applyNodeLabelsAndTaints(&node, providerSpec) {
+				klog.Infof("%s: labels or taints of the Machine are missing on the node - apply them", node.Name)
+				if err = r.client.Update(context.Background(), &node); err != nil {
+					return reconcile.Result{}, fmt.Errorf("%s: failed updating node, with error: %v", node.Name, err)
+				}
+			}
+			return r.reconcileSmokeCheck(&node, providerSpec.NodeSmokeCheck)
+		}
+		if r.nodeLifecycleOnly && !ccmActive {
+			return r.reconcileNodeAddresses(&node, infraClusterNamespace, vmName)
+		}
 		return reconcile.Result{}, nil
 	}
 
-	klog.Infof("%s: ProviderID is not updated in the node - update it", node.Name)
+	if providerSpec != nil {
+		if providerSpec.WaitForGuestAgent {
+			vmi, err := infraClusterClient.GetVirtualMachineInstance(context.Background(), infraClusterNamespace, vmName, &metav1.GetOptions{})
+			if err != nil && !errors.IsNotFound(err) {
+				return reconcile.Result{}, fmt.Errorf("%s: Error getting Virtual Machine Instance, with error: %v", node.Name, err)
+			}
+			if !utils.IsGuestAgentConnected(vmi) {
+				klog.Infof("%s: guest agent of this node isn't connected - requeue for %v", node.Name, r.vmNotReadyRequeueAfter)
+				return reconcile.Result{Requeue: true, RequeueAfter: r.vmNotReadyRequeueAfter}, nil
+			}
+		}
+	}
 
-	node.Spec.ProviderID = kubevirt.FormatProviderID(infraClusterNamespace, node.Name)
+	if ccmActive {
+		// The labels and taints are applied before the cloud-controller-manager sets the providerID as well
+		if providerSpec == nil {
+			klog.Infof("%s: ProviderID is not updated in the node - leave it to the cloud-controller-manager", node.Name)
+			return reconcile.Result{}, nil
+		}
+		klog.Infof("%s: ProviderID is not updated in the node - apply the labels and taints, and leave the providerID to the cloud-controller-manager", node.Name)
+		providerSpec = withoutTopologyLabels(providerSpec)
+	} else {
+		klog.Infof("%s: ProviderID is not updated in the node - update it", node.Name)
+		node.Spec.ProviderID = kubevirt.FormatProviderID(infraClusterNamespace, vmName)
+	}
+	if providerSpec != nil {
+		applyNodeLabelsAndTaints(&node, providerSpec)
+	}
 
 	if err = r.client.Update(context.Background(), &node); err != nil {
 		return reconcile.Result{}, fmt.Errorf("%s: failed updating node, with error: %v", node.Name, err)
 	}
 
+	if providerSpec != nil && providerSpec.NodeSmokeCheck != nil {
+		return reconcile.Result{Requeue: true, RequeueAfter: smokeCheckRetryPeriod}, nil
+	}
+	if r.nodeLifecycleOnly && !ccmActive {
+		return r.reconcileNodeAddresses(&node, infraClusterNamespace, vmName)
+	}
 	return reconcile.Result{}, nil
 }
 
-// Add registers a new provider ID reconciler controller with the controller manager
-func Add(mgr manager.Manager, infraClusterClient infracluster.Client, tenantClusterClient tenantcluster.Client) error {
-	reconciler, err := NewProviderIDReconciler(mgr, infraClusterClient, tenantClusterClient)
+// infraClusterClientOf returns the Client of the infra-cluster of the Machine, and the Client of the controller
+// for the nodes without a Machine
+func (r *providerIDReconciler) infraClusterClientOf(ctx context.Context, machine *machinev1.Machine) (infracluster.Client, error) {
+	if machine == nil || r.machineInfraClients == nil {
+		return r.infraClusterClient, nil
+	}
+	return r.machineInfraClients(ctx, machine)
+}
+
+// machineOfNode returns the Machine which has the name of the node as its InternalDNS address,
+// or nil if there is no such Machine
+func (r *providerIDReconciler) machineOfNode(node corev1.Node) (*machinev1.Machine, error) {
+	machines := machinev1.MachineList{}
+	if err := r.client.List(context.Background(), &machines); err != nil {
+		return nil, fmt.Errorf("%s: Error listing Machines, with error: %v", node.Name, err)
+	}
+	for i := range machines.Items {
+		for _, address := range machines.Items[i].Status.Addresses {
+			if address.Type == corev1.NodeInternalDNS && address.Address == node.Name {
+				return &machines.Items[i], nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// applyNodeLabelsAndTaints sets the NodeLabels of the provider spec on the node, and adds its NodeTaints
+// which the node doesn't have yet. It returns whether the node changed.
+func applyNodeLabelsAndTaints(node *corev1.Node, providerSpec *kubevirtproviderv1alpha1.KubevirtMachineProviderSpec) bool {
+	changed := false
+	if len(providerSpec.NodeLabels) > 0 && node.Labels == nil {
+		node.Labels = map[string]string{}
+	}
+	for key, value := range providerSpec.NodeLabels {
+		if current, ok := node.Labels[key]; !ok || current != value {
+			node.Labels[key] = value
+			changed = true
+		}
+	}
+	for i := range providerSpec.NodeTaints {
+		if !taintExists(node.Spec.Taints, &providerSpec.NodeTaints[i]) {
+			node.Spec.Taints = append(node.Spec.Taints, providerSpec.NodeTaints[i])
+			changed = true
+		}
+	}
+	return changed
+}
+
+// taintExists returns true when a taint with the same key and effect is in the list
+func taintExists(taints []corev1.Taint, taint *corev1.Taint) bool {
+	for i := range taints {
+		if taints[i].MatchTaint(taint) {
+			return true
+		}
+	}
+	return false
+}
+
+// isKubevirtNode filters out the nodes which have the providerID of another provider
+func isKubevirtNode(obj client.Object) bool {
+	node, ok := obj.(*corev1.Node)
+	if !ok {
+		return false
+	}
+	if node.Spec.ProviderID == "" {
+		return true
+	}
+	_, _, err := kubevirt.ParseProviderID(node.Spec.ProviderID)
+	return err == nil
+}
+
+// Add registers a new provider ID reconciler controller with the controller manager. The Virtual Machines of the
+// Machines are read from the infra-cluster of machineInfraClients. With nodeLifecycleOnly, the
+// nodes are reconciled without Machines, for the Virtual Machines created by other means. The KubeVirt
+// cloud-controller-manager is detected by the ccmLease, unless its name is empty.
+func Add(mgr manager.Manager, infraClusterClient infracluster.Client, machineInfraClients infracluster.MachineClients, tenantClusterClient tenantcluster.Client,
+	vmNotReadyRequeueAfter time.Duration, nodeLifecycleOnly bool, ccmLease types.NamespacedName) error {
+	reconciler, err := NewProviderIDReconciler(mgr, infraClusterClient, tenantClusterClient, vmNotReadyRequeueAfter)
 
 	if err != nil {
 		return fmt.Errorf("error building reconciler: %v", err)
 	}
+	reconciler.machineInfraClients = machineInfraClients
+	reconciler.nodeLifecycleOnly = nodeLifecycleOnly
+	reconciler.ccmLease = ccmLease
 
 	c, err := controller.New("provdierID-controller", mgr, controller.Options{Reconciler: reconciler})
 	if err != nil {
@@ -122,20 +344,38 @@ func Add(mgr manager.Manager, infraClusterClient infracluster.Client, tenantClus
 	}
 
 	//Watch node changes
-	err = c.Watch(&source.Kind{Type: &corev1.Node{}}, &handler.EnqueueRequestForObject{})
+	err = c.Watch(&source.Kind{Type: &corev1.Node{}}, &handler.EnqueueRequestForObject{}, predicate.NewPredicateFuncs(isKubevirtNode))
+	if err != nil {
+		return err
+	}
+
+	//Watch the Virtual Machines turning ready, and reconcile their nodes, whose Machines are found by providerID
+	err = mgr.GetFieldIndexer().IndexField(context.Background(), &machinev1.Machine{}, machineProviderIDIndex, indexMachineProviderID)
+	if err != nil {
+		return err
+	}
+	vmEvents := make(chan event.GenericEvent)
+	err = c.Watch(&source.Channel{Source: vmEvents}, handler.EnqueueRequestsFromMapFunc(reconciler.nodeRequestsOfVM))
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return mgr.Add(&vmReadyWatcher{
+		infraClusterClient:  infraClusterClient,
+		tenantClusterClient: tenantClusterClient,
+		events:              vmEvents,
+		nodeLifecycleOnly:   nodeLifecycleOnly,
+	})
 }
 
 // NewProviderIDReconciler creates a new providerID reconciler
-func NewProviderIDReconciler(mgr manager.Manager, infraClusterClient infracluster.Client, tenantClusterClient tenantcluster.Client) (*providerIDReconciler, error) {
+func NewProviderIDReconciler(mgr manager.Manager, infraClusterClient infracluster.Client, tenantClusterClient tenantcluster.Client,
+	vmNotReadyRequeueAfter time.Duration) (*providerIDReconciler, error) {
 	r := providerIDReconciler{
-		client:              mgr.GetClient(),
-		infraClusterClient:  infraClusterClient,
-		tenantClusterClient: tenantClusterClient,
+		client:                 mgr.GetClient(),
+		infraClusterClient:     infraClusterClient,
+		tenantClusterClient:    tenantClusterClient,
+		vmNotReadyRequeueAfter: vmNotReadyRequeueAfter,
 	}
 	return &r, nil
 }
@@ -0,0 +1,565 @@
+package nodeupdate
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster"
+	mockInfraClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster/mock"
+	mockTenantClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster/mock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/kubevirt"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/testutils"
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	kubevirtapiv1 "kubevirt.io/client-go/api/v1"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const vmNotReadyRequeueAfter = 60 * time.Second
+
+func TestIsKubevirtNode(t *testing.T) {
+	cases := []struct {
+		name           string
+		providerID     string
+		expectedResult bool
+	}{
+		{
+			name:           "node without providerID",
+			expectedResult: true,
+		},
+		{
+			name:           "node with kubevirt providerID",
+			providerID:     "kubevirt://test-namespace/test-vm",
+			expectedResult: true,
+		},
+		{
+			name:           "node with providerID of another provider",
+			providerID:     "aws:///us-east-1a/i-0123456789",
+			expectedResult: false,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			node := &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-node"},
+				Spec:       corev1.NodeSpec{ProviderID: tc.providerID},
+			}
+			assert.Equal(t, tc.expectedResult, isKubevirtNode(node))
+		})
+	}
+}
+
+func TestApplyNodeLabelsAndTaints(t *testing.T) {
+	gpuTaint := corev1.Taint{Key: "nvidia.com/gpu", Value: "true", Effect: corev1.TaintEffectNoSchedule}
+	cases := []struct {
+		name            string
+		nodeLabels      map[string]string
+		nodeTaints      []corev1.Taint
+		labels          map[string]string
+		taints          []corev1.Taint
+		expectedLabels  map[string]string
+		expectedTaints  []corev1.Taint
+		expectedChanged bool
+	}{
+		{
+			name:            "node without labels and taints",
+			labels:          map[string]string{"node-role.kubernetes.io/gpu": ""},
+			taints:          []corev1.Taint{gpuTaint},
+			expectedLabels:  map[string]string{"node-role.kubernetes.io/gpu": ""},
+			expectedTaints:  []corev1.Taint{gpuTaint},
+			expectedChanged: true,
+		},
+		{
+			name:            "node with labels and the same taint",
+			nodeLabels:      map[string]string{"kubernetes.io/hostname": "test-node", "pool": "default"},
+			nodeTaints:      []corev1.Taint{gpuTaint},
+			labels:          map[string]string{"pool": "gpu"},
+			taints:          []corev1.Taint{gpuTaint},
+			expectedLabels:  map[string]string{"kubernetes.io/hostname": "test-node", "pool": "gpu"},
+			expectedTaints:  []corev1.Taint{gpuTaint},
+			expectedChanged: true,
+		},
+		{
+			name:           "node with the labels and taints already applied",
+			nodeLabels:     map[string]string{"pool": "gpu"},
+			nodeTaints:     []corev1.Taint{gpuTaint},
+			labels:         map[string]string{"pool": "gpu"},
+			taints:         []corev1.Taint{gpuTaint},
+			expectedLabels: map[string]string{"pool": "gpu"},
+			expectedTaints: []corev1.Taint{gpuTaint},
+		},
+		{
+			name: "empty provider spec",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			node := &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-node", Labels: tc.nodeLabels},
+				Spec:       corev1.NodeSpec{Taints: tc.nodeTaints},
+			}
+			changed := applyNodeLabelsAndTaints(node, &kubevirtproviderv1alpha1.KubevirtMachineProviderSpec{
+				NodeLabels: tc.labels,
+				NodeTaints: tc.taints,
+			})
+			assert.Equal(t, tc.expectedChanged, changed)
+			assert.DeepEqual(t, tc.expectedLabels, node.Labels)
+			assert.DeepEqual(t, tc.expectedTaints, node.Spec.Taints)
+		})
+	}
+}
+
+func stubNode(name string, providerID string) corev1.Node {
+	return corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       corev1.NodeSpec{ProviderID: providerID},
+	}
+}
+
+// stubUninitializedNode returns a node registered by the kubelet with the uninitialized taint
+func stubUninitializedNode(name string, providerID string) corev1.Node {
+	node := stubNode(name, providerID)
+	node.Spec.Taints = []corev1.Taint{kubevirt.UninitializedTaint}
+	return node
+}
+
+func stubSmokeCheckedNode(name string, providerID string, readySince time.Time) corev1.Node {
+	node := stubUninitializedNode(name, providerID)
+	node.Annotations = map[string]string{"k8s.ovn.org/node-subnets": "{}"}
+	node.Status.Conditions = []corev1.NodeCondition{
+		{Type: corev1.NodeReady, Status: corev1.ConditionTrue, LastTransitionTime: metav1.NewTime(readySince)},
+	}
+	return node
+}
+
+func stubNodeMachine(t *testing.T, nodeName string, vmID string, modifyProviderSpec func(spec *kubevirtproviderv1alpha1.KubevirtMachineProviderSpec)) machinev1.Machine {
+	providerSpec := testutils.ProviderSpec
+	if modifyProviderSpec != nil {
+		modifyProviderSpec(&providerSpec)
+	}
+	machine, err := testutils.StubMachine()
+	assert.NilError(t, err)
+	machine.Spec.ProviderSpec.Value, err = kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&providerSpec)
+	assert.NilError(t, err)
+	machine.Spec.ProviderID = testutils.StringPointer(kubevirt.FormatProviderID(testutils.InfraNamespace, testutils.MachineName))
+	machine.Annotations = map[string]string{machinescope.KubevirtIdAnnotationKey: vmID}
+	machine.Status.Addresses = []corev1.NodeAddress{{Type: corev1.NodeInternalDNS, Address: nodeName}}
+	return *machine
+}
+
+func TestReconcile(t *testing.T) {
+	const (
+		nodeName = "test-node"
+		vmID     = "test-vm-id"
+	)
+	providerID := kubevirt.FormatProviderID(testutils.InfraNamespace, testutils.MachineName)
+	cMap := map[string]string{
+		configMapInfraNamespaceKeyName: testutils.InfraNamespace,
+		configMapInfraIDKeyName:        testutils.InfraID,
+	}
+	notFoundErr := apierrors.NewNotFound(schema.GroupResource{Resource: "virtualmachines"}, testutils.MachineName)
+	gpuTaint := corev1.Taint{Key: "nvidia.com/gpu", Value: "true", Effect: corev1.TaintEffectNoSchedule}
+	smokeCheck := &kubevirtproviderv1alpha1.NodeSmokeCheck{ReadySeconds: 60, RequiredAnnotations: []string{"k8s.ovn.org/node-subnets"}}
+	readySince := time.Now().Add(-2 * time.Minute)
+
+	cases := []struct {
+		name               string
+		nodes              []corev1.Node
+		machines           []machinev1.Machine
+		expect             func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient)
+		expectedResult     reconcile.Result
+		expectedErr        string
+		expectedProviderID string
+		expectedDeleted    []string
+		expectedTaints     []corev1.Taint
+	}{
+		{
+			name:  "Success set providerID, labels and taints",
+			nodes: []corev1.Node{stubNode(nodeName, "")},
+			machines: []machinev1.Machine{stubNodeMachine(t, nodeName, vmID, func(spec *kubevirtproviderv1alpha1.KubevirtMachineProviderSpec) {
+				spec.NodeTaints = []corev1.Taint{gpuTaint}
+			})},
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+				vm := testutils.StubVirtualMachine(nil, nil, testutils.StringPointer(vmID))
+				vm.Status.Ready = true
+				infraClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vm, nil).Times(1)
+			},
+			expectedProviderID: providerID,
+			expectedTaints:     []corev1.Taint{gpuTaint},
+		},
+		{
+			name:     "Success set providerID of a node registered with the uninitialized taint",
+			nodes:    []corev1.Node{stubUninitializedNode(nodeName, "")},
+			machines: []machinev1.Machine{stubNodeMachine(t, nodeName, vmID, func(spec *kubevirtproviderv1alpha1.KubevirtMachineProviderSpec) { spec.NodeSmokeCheck = smokeCheck })},
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+				vm := testutils.StubVirtualMachine(nil, nil, testutils.StringPointer(vmID))
+				vm.Status.Ready = true
+				infraClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vm, nil).Times(1)
+			},
+			expectedResult:     reconcile.Result{Requeue: true, RequeueAfter: smokeCheckRetryPeriod},
+			expectedProviderID: providerID,
+			expectedTaints:     []corev1.Taint{kubevirt.UninitializedTaint},
+		},
+		{
+			name:  "Success apply the taints added to the Machine after the node joined",
+			nodes: []corev1.Node{stubNode(nodeName, providerID)},
+			machines: []machinev1.Machine{stubNodeMachine(t, nodeName, vmID, func(spec *kubevirtproviderv1alpha1.KubevirtMachineProviderSpec) {
+				spec.NodeTaints = []corev1.Taint{gpuTaint}
+			})},
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+				vm := testutils.StubVirtualMachine(nil, nil, testutils.StringPointer(vmID))
+				vm.Status.Ready = true
+				infraClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vm, nil).Times(1)
+			},
+			expectedProviderID: providerID,
+			expectedTaints:     []corev1.Taint{gpuTaint},
+		},
+		{
+			name:     "Success smoke check passed - remove the uninitialized taint",
+			nodes:    []corev1.Node{stubSmokeCheckedNode(nodeName, providerID, readySince)},
+			machines: []machinev1.Machine{stubNodeMachine(t, nodeName, vmID, func(spec *kubevirtproviderv1alpha1.KubevirtMachineProviderSpec) { spec.NodeSmokeCheck = smokeCheck })},
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+				vm := testutils.StubVirtualMachine(nil, nil, testutils.StringPointer(vmID))
+				vm.Status.Ready = true
+				infraClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vm, nil).Times(1)
+			},
+			expectedProviderID: providerID,
+			expectedTaints:     []corev1.Taint{},
+		},
+		{
+			name:  "Success set providerID of a node without a Machine",
+			nodes: []corev1.Node{stubNode(testutils.MachineName, "")},
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+				vm := testutils.StubVirtualMachine(nil, nil, nil)
+				vm.Status.Ready = true
+				infraClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vm, nil).Times(1)
+			},
+			expectedProviderID: providerID,
+		},
+		{
+			name:     "Success vm not ready - requeue",
+			nodes:    []corev1.Node{stubNode(nodeName, "")},
+			machines: []machinev1.Machine{stubNodeMachine(t, nodeName, vmID, nil)},
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+				vm := testutils.StubVirtualMachine(nil, nil, testutils.StringPointer(vmID))
+				infraClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vm, nil).Times(1)
+			},
+			expectedResult: reconcile.Result{Requeue: true, RequeueAfter: vmNotReadyRequeueAfter},
+		},
+		{
+			name:     "Success vm of another Machine - requeue",
+			nodes:    []corev1.Node{stubNode(nodeName, "")},
+			machines: []machinev1.Machine{stubNodeMachine(t, nodeName, vmID, nil)},
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+				vm := testutils.StubVirtualMachine(nil, nil, testutils.StringPointer("another-vm-id"))
+				vm.Status.Ready = true
+				infraClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vm, nil).Times(1)
+			},
+			expectedResult: reconcile.Result{Requeue: true, RequeueAfter: vmNotReadyRequeueAfter},
+		},
+		{
+			name:     "Success guest agent not connected - requeue",
+			nodes:    []corev1.Node{stubNode(nodeName, "")},
+			machines: []machinev1.Machine{stubNodeMachine(t, nodeName, vmID, func(spec *kubevirtproviderv1alpha1.KubevirtMachineProviderSpec) { spec.WaitForGuestAgent = true })},
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+				vm := testutils.StubVirtualMachine(nil, nil, testutils.StringPointer(vmID))
+				vm.Status.Ready = true
+				infraClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vm, nil).Times(1)
+				infraClient.EXPECT().GetVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(testutils.StubVirtualMachineInstance(), nil).Times(1)
+			},
+			expectedResult: reconcile.Result{Requeue: true, RequeueAfter: vmNotReadyRequeueAfter},
+		},
+		{
+			name:     "Success vm missing - delete the node",
+			nodes:    []corev1.Node{stubNode(nodeName, providerID)},
+			machines: []machinev1.Machine{stubNodeMachine(t, nodeName, vmID, nil)},
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+				infraClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(nil, notFoundErr).Times(1)
+				infraClient.EXPECT().ListVirtualMachine(gomock.Any(), testutils.InfraNamespace, metav1.ListOptions{Limit: 1}).Return(&kubevirtapiv1.VirtualMachineList{}, nil).Times(1)
+			},
+			expectedProviderID: providerID,
+			expectedDeleted:    []string{nodeName},
+		},
+		{
+			name:     "Success vm missing while the infra-cluster is unreachable - keep the node and requeue",
+			nodes:    []corev1.Node{stubNode(nodeName, providerID)},
+			machines: []machinev1.Machine{stubNodeMachine(t, nodeName, vmID, nil)},
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+				infraClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(nil, notFoundErr).Times(1)
+				infraClient.EXPECT().ListVirtualMachine(gomock.Any(), testutils.InfraNamespace, metav1.ListOptions{Limit: 1}).Return(nil, notFoundErr).Times(1)
+			},
+			expectedResult:     reconcile.Result{Requeue: true, RequeueAfter: vmNotReadyRequeueAfter},
+			expectedProviderID: providerID,
+		},
+		{
+			name:  "Success vm missing of a node without a Machine - do nothing",
+			nodes: []corev1.Node{stubNode(testutils.MachineName, "")},
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+				infraClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(nil, notFoundErr).Times(1)
+			},
+		},
+		{
+			name: "Success node not found",
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+			},
+		},
+		{
+			name:  "Failure get configMap",
+			nodes: []corev1.Node{stubNode(nodeName, "")},
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(nil, fmt.Errorf("test error")).Times(1)
+			},
+			expectedErr: "test error",
+		},
+		{
+			name:  "Failure configMap without namespace",
+			nodes: []corev1.Node{stubNode(nodeName, "")},
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&map[string]string{}, nil).Times(1)
+			},
+			expectedErr: "ProviderID: configMap openshift-config/cloud-provider-config: The map extracted with key config doesn't contain key namespace",
+		},
+		{
+			name:     "Failure get vm",
+			nodes:    []corev1.Node{stubNode(nodeName, "")},
+			machines: []machinev1.Machine{stubNodeMachine(t, nodeName, vmID, nil)},
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+				infraClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(nil, fmt.Errorf("test error")).Times(1)
+			},
+			expectedErr: "test-node: Error getting Virtual Machine, with error: test error",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			infraClient := mockInfraClusterClient.NewMockClient(mockCtrl)
+			tenantClient := mockTenantClusterClient.NewMockClient(mockCtrl)
+			fakeClient := testutils.NewFakeClient(tc.nodes, tc.machines)
+
+			tc.expect(infraClient, tenantClient)
+
+			r := &providerIDReconciler{
+				client:                 fakeClient,
+				infraClusterClient:     infraClient,
+				tenantClusterClient:    tenantClient,
+				vmNotReadyRequeueAfter: vmNotReadyRequeueAfter,
+			}
+			requestName := nodeName
+			if len(tc.nodes) > 0 {
+				requestName = tc.nodes[0].Name
+			}
+			result, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: types.NamespacedName{Name: requestName}})
+			if tc.expectedErr != "" {
+				assert.Error(t, err, tc.expectedErr)
+				return
+			}
+			assert.NilError(t, err)
+			assert.DeepEqual(t, tc.expectedResult, result)
+			assert.DeepEqual(t, tc.expectedDeleted, fakeClient.DeletedNodes)
+			if node, ok := fakeClient.Nodes[requestName]; ok {
+				assert.Equal(t, tc.expectedProviderID, node.Spec.ProviderID)
+				assert.DeepEqual(t, tc.expectedTaints, node.Spec.Taints)
+			}
+		})
+	}
+}
+
+func TestReconcileCredentialsInfraCluster(t *testing.T) {
+	const nodeName = "test-node"
+	cMap := map[string]string{
+		configMapInfraNamespaceKeyName: testutils.InfraNamespace,
+		configMapInfraIDKeyName:        testutils.InfraID,
+	}
+	notFoundErr := apierrors.NewNotFound(schema.GroupResource{Resource: "virtualmachines"}, testutils.MachineName)
+	providerID := kubevirt.FormatProviderID(testutils.InfraNamespace, testutils.MachineName)
+
+	cases := []struct {
+		name            string
+		vmNotFound      bool
+		expectedDeleted []string
+	}{
+		{
+			name: "vm in the infra-cluster of the credentials secret - keep the node",
+		},
+		{
+			name:            "vm missing in the infra-cluster of the credentials secret - delete the node",
+			vmNotFound:      true,
+			expectedDeleted: []string{nodeName},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			// The infra-cluster of the controller doesn't have the Virtual Machine, and isn't asked for it
+			controllerInfraClient := mockInfraClusterClient.NewMockClient(mockCtrl)
+			credentialsInfraClient := mockInfraClusterClient.NewMockClient(mockCtrl)
+			tenantClient := mockTenantClusterClient.NewMockClient(mockCtrl)
+			machine := stubNodeMachine(t, nodeName, "test-vm-id", func(spec *kubevirtproviderv1alpha1.KubevirtMachineProviderSpec) {
+				spec.CredentialsSecretName = "tenant-credentials"
+			})
+			fakeClient := testutils.NewFakeClient([]corev1.Node{stubNode(nodeName, providerID)}, []machinev1.Machine{machine})
+
+			tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+			if tc.vmNotFound {
+				credentialsInfraClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(nil, notFoundErr).Times(1)
+				credentialsInfraClient.EXPECT().ListVirtualMachine(gomock.Any(), testutils.InfraNamespace, metav1.ListOptions{Limit: 1}).Return(&kubevirtapiv1.VirtualMachineList{}, nil).Times(1)
+			} else {
+				vm := testutils.StubVirtualMachine(nil, nil, testutils.StringPointer("test-vm-id"))
+				vm.Status.Ready = true
+				credentialsInfraClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vm, nil).Times(1)
+			}
+
+			r := &providerIDReconciler{
+				client:             fakeClient,
+				infraClusterClient: controllerInfraClient,
+				machineInfraClients: func(_ context.Context, m *machinev1.Machine) (infracluster.Client, error) {
+					assert.Equal(t, m.Name, machine.Name)
+					return credentialsInfraClient, nil
+				},
+				tenantClusterClient:    tenantClient,
+				vmNotReadyRequeueAfter: vmNotReadyRequeueAfter,
+			}
+			_, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: types.NamespacedName{Name: nodeName}})
+			assert.NilError(t, err)
+			assert.DeepEqual(t, tc.expectedDeleted, fakeClient.DeletedNodes)
+		})
+	}
+}
+
+func TestNodeRequestsOfVM(t *testing.T) {
+	vm := testutils.StubVirtualMachine(nil, nil, nil)
+	machine := stubNodeMachine(t, "test-node", "test-vm-id", nil)
+
+	cases := []struct {
+		name              string
+		machines          []machinev1.Machine
+		nodes             []corev1.Node
+		nodeLifecycleOnly bool
+		otherInfraCluster bool
+		listErr           error
+		expectedNodeName  string
+	}{
+		{
+			name:             "vm of a Machine",
+			machines:         []machinev1.Machine{machine},
+			expectedNodeName: "test-node",
+		},
+		{
+			name:              "vm with the providerID of a Machine of another infra-cluster",
+			machines:          []machinev1.Machine{machine},
+			otherInfraCluster: true,
+			expectedNodeName:  testutils.MachineName,
+		},
+		{
+			name:             "vm without a Machine",
+			expectedNodeName: testutils.MachineName,
+		},
+		{
+			name:             "failure listing Machines",
+			machines:         []machinev1.Machine{machine},
+			listErr:          fmt.Errorf("test error"),
+			expectedNodeName: testutils.MachineName,
+		},
+		{
+			name:              "vm of a node in node lifecycle only mode",
+			machines:          []machinev1.Machine{machine},
+			nodes:             []corev1.Node{stubNode("test-guest", kubevirt.FormatProviderID(vm.Namespace, vm.Name))},
+			nodeLifecycleOnly: true,
+			expectedNodeName:  "test-guest",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeClient := testutils.NewFakeClient(tc.nodes, tc.machines)
+			fakeClient.ListErr = tc.listErr
+			assert.NilError(t, fakeClient.IndexField(context.Background(), &machinev1.Machine{}, machineProviderIDIndex, indexMachineProviderID))
+			r := &providerIDReconciler{client: fakeClient, nodeLifecycleOnly: tc.nodeLifecycleOnly}
+			if tc.otherInfraCluster {
+				r.machineInfraClients = func(context.Context, *machinev1.Machine) (infracluster.Client, error) {
+					return mockInfraClusterClient.NewMockClient(gomock.NewController(t)), nil
+				}
+			}
+			assert.DeepEqual(t, []reconcile.Request{{NamespacedName: types.NamespacedName{Name: tc.expectedNodeName}}}, r.nodeRequestsOfVM(vm))
+		})
+	}
+}
+
+func TestSmokeCheckPassed(t *testing.T) {
+	now := time.Now()
+	smokeCheck := &kubevirtproviderv1alpha1.NodeSmokeCheck{ReadySeconds: 60, RequiredAnnotations: []string{"k8s.ovn.org/node-subnets"}}
+
+	cases := []struct {
+		name               string
+		modifyNode         func(node *corev1.Node)
+		expectedPassed     bool
+		expectedRetryAfter time.Duration
+	}{
+		{
+			name:           "node ready for long enough with the annotations",
+			expectedPassed: true,
+		},
+		{
+			name: "node ready for too short",
+			modifyNode: func(node *corev1.Node) {
+				node.Status.Conditions[0].LastTransitionTime = metav1.NewTime(now.Add(-20 * time.Second))
+			},
+			expectedRetryAfter: 40 * time.Second,
+		},
+		{
+			name: "node not ready",
+			modifyNode: func(node *corev1.Node) {
+				node.Status.Conditions[0].Status = corev1.ConditionFalse
+			},
+			expectedRetryAfter: smokeCheckRetryPeriod,
+		},
+		{
+			name: "node without the annotations",
+			modifyNode: func(node *corev1.Node) {
+				node.Annotations = nil
+			},
+			expectedRetryAfter: smokeCheckRetryPeriod,
+		},
+		{
+			name: "node without a Ready condition",
+			modifyNode: func(node *corev1.Node) {
+				node.Status.Conditions = nil
+			},
+			expectedRetryAfter: smokeCheckRetryPeriod,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			node := stubSmokeCheckedNode("test-node", "", now.Add(-2*time.Minute))
+			if tc.modifyNode != nil {
+				tc.modifyNode(&node)
+			}
+			passed, retryAfter := smokeCheckPassed(&node, smokeCheck, now)
+			assert.Equal(t, tc.expectedPassed, passed)
+			assert.Equal(t, tc.expectedRetryAfter, retryAfter)
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package nodeupdate
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/kubevirt"
+)
+
+// smokeCheckRetryPeriod is the delay before re-checking a node which doesn't pass the smoke check
+const smokeCheckRetryPeriod = 30 * time.Second
+
+// reconcileSmokeCheck removes the uninitialized taint, which the kubelet registered the node with, from the node
+// once it passes the smoke check of the provider spec, or requeues until then
+func (r *providerIDReconciler) reconcileSmokeCheck(node *corev1.Node, smokeCheck *kubevirtproviderv1alpha1.NodeSmokeCheck) (reconcile.Result, error) {
+	if smokeCheck == nil || !taintExists(node.Spec.Taints, &kubevirt.UninitializedTaint) {
+		return reconcile.Result{}, nil
+	}
+
+	passed, retryAfter := smokeCheckPassed(node, smokeCheck, time.Now())
+	if !passed {
+		klog.Infof("%s: smoke check didn't pass yet - requeue for %v", node.Name, retryAfter)
+		return reconcile.Result{Requeue: true, RequeueAfter: retryAfter}, nil
+	}
+
+	klog.Infof("%s: smoke check passed - remove the %s taint", node.Name, kubevirt.UninitializedTaintKey)
+	taints := []corev1.Taint{}
+	for _, taint := range node.Spec.Taints {
+		if !taint.MatchTaint(&kubevirt.UninitializedTaint) {
+			taints = append(taints, taint)
+		}
+	}
+	node.Spec.Taints = taints
+	if err := r.client.Update(context.Background(), node); err != nil {
+		return reconcile.Result{}, fmt.Errorf("%s: failed updating node, with error: %v", node.Name, err)
+	}
+	return reconcile.Result{}, nil
+}
+
+// smokeCheckPassed returns true when the node carries the required annotations and is Ready for long enough,
+// otherwise it returns the time to wait before checking again
+func smokeCheckPassed(node *corev1.Node, smokeCheck *kubevirtproviderv1alpha1.NodeSmokeCheck, now time.Time) (bool, time.Duration) {
+	for _, annotation := range smokeCheck.RequiredAnnotations {
+		if _, ok := node.Annotations[annotation]; !ok {
+			// A new annotation triggers a reconcile of the node anyway
+			return false, smokeCheckRetryPeriod
+		}
+	}
+	for _, condition := range node.Status.Conditions {
+		if condition.Type != corev1.NodeReady {
+			continue
+		}
+		if condition.Status != corev1.ConditionTrue {
+			return false, smokeCheckRetryPeriod
+		}
+		readyFor := now.Sub(condition.LastTransitionTime.Time)
+		required := time.Duration(smokeCheck.ReadySeconds) * time.Second
+		if readyFor < required {
+			return false, required - readyFor
+		}
+		return true, 0
+	}
+	return false, smokeCheckRetryPeriod
+}
@@ -0,0 +1,201 @@
+package nodeupdate
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog"
+	kubevirtapiv1 "kubevirt.io/client-go/api/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/kubevirt"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/utils"
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+)
+
+// vmInformerRetryPeriod is the delay before retrying to start the informer of the Virtual Machines, when the infra
+// namespace of the tenant-cluster can't be read
+const vmInformerRetryPeriod = 10 * time.Second
+
+// machineProviderIDIndex is the field index of the Machines by providerID
+const machineProviderIDIndex = "spec.providerID"
+
+var _ manager.Runnable = &vmReadyWatcher{}
+
+// vmReadyWatcher watches the Virtual Machines of this tenant-cluster in the infra-cluster with an informer,
+// and emits an event for each Virtual Machine which is ready, so the node of the Virtual Machine
+// is reconciled as soon as possible instead of waiting for the not ready requeue.
+// In node lifecycle only mode, all the Virtual Machines of the infra namespace are watched, as they aren't labeled
+// by Machines, and the deleted ones are emitted as well, so their node is deleted.
+type vmReadyWatcher struct {
+	infraClusterClient  infracluster.Client
+	tenantClusterClient tenantcluster.Client
+	events              chan event.GenericEvent
+	nodeLifecycleOnly   bool
+}
+
+// Start runs the informer of the Virtual Machines until the context is done
+func (w *vmReadyWatcher) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		informer, err := w.newInformer(ctx)
+		if err != nil {
+			klog.Errorf("virtual machine informer: %v", err)
+			return
+		}
+		informer.Run(ctx.Done())
+	}, vmInformerRetryPeriod)
+	return nil
+}
+
+// newInformer returns the informer of the Virtual Machines of the infra namespace of the tenant-cluster, which emits
+// an event for the Virtual Machines whose node is reconciled
+func (w *vmReadyWatcher) newInformer(ctx context.Context) (cache.SharedIndexInformer, error) {
+	cMap, err := w.tenantClusterClient.GetConfigMapValue(ctx, configMapName, configMapNamespace, configMapDataKeyName)
+	if err != nil {
+		return nil, err
+	}
+	infraNamespace, ok := (*cMap)[configMapInfraNamespaceKeyName]
+	if !ok {
+		return nil, fmt.Errorf("configMap %s/%s: The map extracted with key %s doesn't contain key %s",
+			configMapNamespace, configMapName, configMapDataKeyName, configMapInfraNamespaceKeyName)
+	}
+	infraID, ok := (*cMap)[configMapInfraIDKeyName]
+	if !ok {
+		return nil, fmt.Errorf("configMap %s/%s: The map extracted with key %s doesn't contain key %s",
+			configMapNamespace, configMapName, configMapDataKeyName, configMapInfraIDKeyName)
+	}
+
+	selector := labels.SelectorFromSet(utils.BuildLabels(infraID))
+	if w.nodeLifecycleOnly {
+		selector = nil
+	}
+	vmResource := w.infraClusterClient.GetCapabilities().VirtualMachineResource()
+	informer := w.infraClusterClient.NewInformer(vmResource, infraNamespace, selector, 0)
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			w.emit(ctx, obj, false)
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			w.emit(ctx, obj, false)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if deleted, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = deleted.Obj
+			}
+			w.emit(ctx, obj, true)
+		},
+	})
+	return informer, nil
+}
+
+// emit emits an event for the unstructured Virtual Machine of the informer when its node is reconciled
+func (w *vmReadyWatcher) emit(ctx context.Context, obj interface{}, deleted bool) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+	vm := &kubevirtapiv1.VirtualMachine{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, vm); err != nil {
+		klog.Errorf("%s: Error converting the virtual machine, with error: %v", u.GetName(), err)
+		return
+	}
+	if !w.emits(deleted, vm) {
+		return
+	}
+	select {
+	case w.events <- event.GenericEvent{Object: vm}:
+	case <-ctx.Done():
+	}
+}
+
+// emits returns true for the Virtual Machines whose node is reconciled: the ready ones, and the deleted ones
+// in node lifecycle only mode
+func (w *vmReadyWatcher) emits(deleted bool, vm *kubevirtapiv1.VirtualMachine) bool {
+	if deleted {
+		return w.nodeLifecycleOnly
+	}
+	return vm.Status.Ready
+}
+
+// indexMachineProviderID returns the providerID of the Machine for the machineProviderIDIndex
+func indexMachineProviderID(obj client.Object) []string {
+	machine, ok := obj.(*machinev1.Machine)
+	if !ok || machine.Spec.ProviderID == nil {
+		return nil
+	}
+	return []string{*machine.Spec.ProviderID}
+}
+
+// nodeRequestsOfVM maps a Virtual Machine to the node of its Machine, which is found by the providerID index.
+// The Virtual Machine name is used when there is no such Machine, or it has no node yet.
+func (r *providerIDReconciler) nodeRequestsOfVM(obj client.Object) []reconcile.Request {
+	nodeName := obj.GetName()
+	if r.nodeLifecycleOnly {
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: r.nodeOfProviderID(obj)}}}
+	}
+	machines := machinev1.MachineList{}
+	providerID := kubevirt.FormatProviderID(obj.GetNamespace(), obj.GetName())
+	if err := r.client.List(context.Background(), &machines, client.MatchingFields{machineProviderIDIndex: providerID}); err != nil {
+		klog.Errorf("%s: Error listing Machines, with error: %v", obj.GetName(), err)
+	} else if machine := r.watchedMachine(machines.Items); machine != nil {
+		if machine.Status.NodeRef != nil {
+			nodeName = machine.Status.NodeRef.Name
+		} else {
+			for _, address := range machine.Status.Addresses {
+				if address.Type == corev1.NodeInternalDNS {
+					nodeName = address.Address
+					break
+				}
+			}
+		}
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: nodeName}}}
+}
+
+// watchedMachine returns the Machine whose Virtual Machine is in the watched infra-cluster, or nil if there is no
+// such Machine
+func (r *providerIDReconciler) watchedMachine(machines []machinev1.Machine) *machinev1.Machine {
+	for i := range machines {
+		if r.watchedInfraCluster(&machines[i]) {
+			return &machines[i]
+		}
+	}
+	return nil
+}
+
+// watchedInfraCluster returns true when the Virtual Machine of the Machine is in the watched infra-cluster of the
+// controller, the Virtual Machines of the infra-clusters of the credentials secrets have the same providerIDs
+func (r *providerIDReconciler) watchedInfraCluster(machine *machinev1.Machine) bool {
+	infraClusterClient, err := r.infraClusterClientOf(context.Background(), machine)
+	return err == nil && infraClusterClient == r.infraClusterClient
+}
+
+// nodeOfProviderID returns the name of the node with the providerID of the Virtual Machine, or the Virtual Machine
+// name when there is no such node yet
+func (r *providerIDReconciler) nodeOfProviderID(obj client.Object) string {
+	providerID := kubevirt.FormatProviderID(obj.GetNamespace(), obj.GetName())
+	nodes := corev1.NodeList{}
+	if err := r.client.List(context.Background(), &nodes); err != nil {
+		klog.Errorf("%s: Error listing nodes, with error: %v", obj.GetName(), err)
+		return obj.GetName()
+	}
+	for i := range nodes.Items {
+		if nodes.Items[i].Spec.ProviderID == providerID {
+			return nodes.Items[i].Name
+		}
+	}
+	return obj.GetName()
+}
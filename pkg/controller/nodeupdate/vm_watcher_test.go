@@ -0,0 +1,182 @@
+package nodeupdate
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster"
+	mockInfraClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster/mock"
+	mockTenantClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster/mock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/testutils"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/utils"
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"gotest.tools/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	kubevirtapiv1 "kubevirt.io/client-go/api/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func stubVM(name string, ready bool) *kubevirtapiv1.VirtualMachine {
+	vm := testutils.StubVirtualMachine(testutils.StringPointer(name), nil, nil)
+	vm.Status.Ready = ready
+	return vm
+}
+
+// newFakeInformer returns an informer of the listed Virtual Machines, followed by the events
+func newFakeInformer(t *testing.T, listed []*kubevirtapiv1.VirtualMachine, events []watch.Event) cache.SharedIndexInformer {
+	toUnstructured := func(vm *kubevirtapiv1.VirtualMachine) *unstructured.Unstructured {
+		obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(vm)
+		assert.NilError(t, err)
+		return &unstructured.Unstructured{Object: obj}
+	}
+	list := &unstructured.UnstructuredList{}
+	for _, vm := range listed {
+		list.Items = append(list.Items, *toUnstructured(vm))
+	}
+	fakeWatch := watch.NewFakeWithChanSize(len(events), false)
+	for _, e := range events {
+		fakeWatch.Action(e.Type, toUnstructured(e.Object.(*kubevirtapiv1.VirtualMachine)))
+	}
+	watched := false
+	listWatch := &cache.ListWatch{
+		ListFunc: func(metav1.ListOptions) (runtime.Object, error) {
+			return list, nil
+		},
+		WatchFunc: func(metav1.ListOptions) (watch.Interface, error) {
+			if watched {
+				return watch.NewFake(), nil
+			}
+			watched = true
+			return fakeWatch, nil
+		},
+	}
+	return cache.NewSharedIndexInformer(listWatch, &unstructured.Unstructured{}, 0, cache.Indexers{})
+}
+
+func TestVMReadyWatcher(t *testing.T) {
+	cMap := map[string]string{
+		configMapInfraNamespaceKeyName: testutils.InfraNamespace,
+		configMapInfraIDKeyName:        testutils.InfraID,
+	}
+
+	cases := []struct {
+		name              string
+		nodeLifecycleOnly bool
+		listed            []*kubevirtapiv1.VirtualMachine
+		watchEvents       []watch.Event
+		expectedSelector  labels.Selector
+		expectedEvents    []string
+	}{
+		{
+			name:   "Success emit ready VMs only",
+			listed: []*kubevirtapiv1.VirtualMachine{stubVM("worker-a", false)},
+			watchEvents: []watch.Event{
+				{Type: watch.Modified, Object: stubVM("worker-a", true)},
+				{Type: watch.Added, Object: stubVM("worker-b", true)},
+				{Type: watch.Deleted, Object: stubVM("worker-a", true)},
+				{Type: watch.Added, Object: stubVM("worker-c", true)},
+			},
+			expectedSelector: labels.SelectorFromSet(utils.BuildLabels(testutils.InfraID)),
+			expectedEvents:   []string{"worker-a", "worker-b", "worker-c"},
+		},
+		{
+			name:              "Success emit deleted VMs in node lifecycle only mode",
+			nodeLifecycleOnly: true,
+			listed:            []*kubevirtapiv1.VirtualMachine{stubVM("worker-b", true)},
+			watchEvents: []watch.Event{
+				{Type: watch.Added, Object: stubVM("worker-a", false)},
+				{Type: watch.Deleted, Object: stubVM("worker-a", false)},
+			},
+			expectedEvents: []string{"worker-b", "worker-a"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			infraClient := mockInfraClusterClient.NewMockClient(mockCtrl)
+			tenantClient := mockTenantClusterClient.NewMockClient(mockCtrl)
+
+			tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+			infraClient.EXPECT().GetCapabilities().Return(infracluster.Capabilities{}).Times(1)
+			infraClient.EXPECT().NewInformer(infracluster.VirtualMachineResource, testutils.InfraNamespace, tc.expectedSelector, gomock.Any()).
+				Return(newFakeInformer(t, tc.listed, tc.watchEvents)).Times(1)
+
+			events := make(chan event.GenericEvent)
+			w := &vmReadyWatcher{
+				infraClusterClient:  infraClient,
+				tenantClusterClient: tenantClient,
+				events:              events,
+				nodeLifecycleOnly:   tc.nodeLifecycleOnly,
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				assert.NilError(t, w.Start(ctx))
+			}()
+			var emitted []string
+			for len(emitted) < len(tc.expectedEvents) {
+				select {
+				case e := <-events:
+					emitted = append(emitted, e.Object.GetName())
+				case <-time.After(10 * time.Second):
+					t.Fatalf("emitted %v, expected %v", emitted, tc.expectedEvents)
+				}
+			}
+			cancel()
+			<-done
+			assert.DeepEqual(t, tc.expectedEvents, emitted)
+		})
+	}
+}
+
+func TestVMReadyWatcherNewInformer(t *testing.T) {
+	cases := []struct {
+		name        string
+		cMap        map[string]string
+		cMapErr     error
+		expectedErr string
+	}{
+		{
+			name:        "Failure configMap without infraID",
+			cMap:        map[string]string{configMapInfraNamespaceKeyName: testutils.InfraNamespace},
+			expectedErr: "configMap openshift-config/cloud-provider-config: The map extracted with key config doesn't contain key infraID",
+		},
+		{
+			name:        "Failure get configMap",
+			cMapErr:     fmt.Errorf("test error"),
+			expectedErr: "test error",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			tenantClient := mockTenantClusterClient.NewMockClient(mockCtrl)
+
+			tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&tc.cMap, tc.cMapErr).Times(1)
+
+			w := &vmReadyWatcher{
+				infraClusterClient:  mockInfraClusterClient.NewMockClient(mockCtrl),
+				tenantClusterClient: tenantClient,
+			}
+			_, err := w.newInformer(context.Background())
+			assert.Error(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func TestIndexMachineProviderID(t *testing.T) {
+	assert.Assert(t, indexMachineProviderID(&machinev1.Machine{}) == nil)
+	machine := &machinev1.Machine{Spec: machinev1.MachineSpec{ProviderID: testutils.StringPointer("kubevirt://test-namespace/worker-a")}}
+	assert.DeepEqual(t, indexMachineProviderID(machine), []string{"kubevirt://test-namespace/worker-a"})
+}
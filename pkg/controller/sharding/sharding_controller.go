@@ -0,0 +1,175 @@
+// sharding package implements the sharding of the Machines between several replicas of the controller:
+// - Every Machine is assigned to a shard by a label, from the hash of its namespace and MachineSet
+// - Every replica is elected the leader of its shard, and only caches and reconciles the Machines of its shard
+// - The replica of the shard 0 labels the Machines, and runs the node controllers and the cluster-wide runnables
+// with a view of all the Machines
+// The shards of the Machines aren't rebalanced once the shard count is raised, only the Machines whose shard label
+// is out of the shard count are labeled again.
+package sharding
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"time"
+
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster"
+)
+
+// ShardLabel is the index of the shard of the Machine, set by the replica of the shard 0
+const ShardLabel = "kubevirt.machine.openshift.io/shard"
+
+// Options are the shard count and the shard of the replica, the sharding is disabled with a single shard
+type Options struct {
+	Count int
+	Index int
+}
+
+// Enabled returns true when the Machines are sharded between several replicas
+func (o Options) Enabled() bool {
+	return o.Count > 1
+}
+
+// Validate returns an error when the shard of the replica isn't within the shard count
+func (o Options) Validate() error {
+	if o.Count < 1 {
+		return fmt.Errorf("invalid shard count %d, expected at least 1", o.Count)
+	}
+	if o.Index < 0 || o.Index >= o.Count {
+		return fmt.Errorf("invalid shard index %d, expected between 0 and %d", o.Index, o.Count-1)
+	}
+	return nil
+}
+
+// RunsClusterWide returns true when the replica runs the node controllers and the cluster-wide runnables, which
+// need all the Machines: without sharding, or in the shard 0
+func (o Options) RunsClusterWide() bool {
+	return o.Index == 0
+}
+
+// LeaderElectionID returns the leader election ID of the shard, every shard electing its own leader
+func (o Options) LeaderElectionID(id string) string {
+	if !o.Enabled() {
+		return id
+	}
+	return fmt.Sprintf("%s-shard-%d", id, o.Index)
+}
+
+// NewCache returns the cache of the manager, restricted to the Machines of the shard
+func (o Options) NewCache() cache.NewCacheFunc {
+	return cache.BuilderWithOptions(cache.Options{
+		SelectorsByObject: cache.SelectorsByObject{
+			&machinev1.Machine{}: {Label: labels.SelectorFromSet(labels.Set{ShardLabel: strconv.Itoa(o.Index)})},
+		},
+	})
+}
+
+// ShardOf returns the shard of the Machine, from the hash of its namespace and MachineSet, so that the Machines of
+// a MachineSet are in the same shard. The Machines without a MachineSet are hashed by their own name.
+func ShardOf(machine *machinev1.Machine, count int) int {
+	name := machine.Name
+	if owner := metav1.GetControllerOf(machine); owner != nil && owner.Kind == "MachineSet" {
+		name = owner.Name
+	}
+	hash := fnv.New32a()
+	hash.Write([]byte(machine.Namespace + "/" + name))
+	return int(hash.Sum32() % uint32(count))
+}
+
+// hasShard returns true when the Machine is labeled with a shard within the shard count
+func hasShard(machine *machinev1.Machine, count int) bool {
+	shard, err := strconv.Atoi(machine.Labels[ShardLabel])
+	return err == nil && shard >= 0 && shard < count
+}
+
+// fullViewManager is a manager whose client reads all the Machines, rather than the Machines of the shard
+type fullViewManager struct {
+	manager.Manager
+	cluster cluster.Cluster
+}
+
+// GetClient returns the client of the cluster caching all the Machines
+func (m *fullViewManager) GetClient() client.Client {
+	return m.cluster.GetClient()
+}
+
+// FullView returns the manager for the node controllers and the cluster-wide runnables of the shard 0: its client
+// reads from a cache of all the Machines, started with the manager
+func FullView(mgr manager.Manager, cfg *rest.Config, namespace string) (manager.Manager, error) {
+	fullCluster, err := cluster.New(cfg, func(clusterOptions *cluster.Options) {
+		clusterOptions.Scheme = mgr.GetScheme()
+		clusterOptions.Namespace = namespace
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := mgr.Add(fullCluster); err != nil {
+		return nil, err
+	}
+	return &fullViewManager{Manager: mgr, cluster: fullCluster}, nil
+}
+
+var _ manager.Runnable = &shardLabeler{}
+
+type shardLabeler struct {
+	tenantClusterClient tenantcluster.Client
+	count               int
+	pollInterval        time.Duration
+}
+
+// Start labels the Machines with their shard until the context is done
+func (r *shardLabeler) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := r.Reconcile(ctx); err != nil {
+			klog.Errorf("sharding: %v", err)
+		}
+	}, r.pollInterval)
+	return nil
+}
+
+// Reconcile labels the Machines without a shard within the shard count with their shard
+func (r *shardLabeler) Reconcile(ctx context.Context) error {
+	machines, err := r.tenantClusterClient.ListMachines(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list Machines, with error: %v", err)
+	}
+	for i := range machines {
+		machine := &machines[i]
+		if hasShard(machine, r.count) {
+			continue
+		}
+		originMachineCopy := machine.DeepCopy()
+		if machine.Labels == nil {
+			machine.Labels = map[string]string{}
+		}
+		shard := ShardOf(machine, r.count)
+		machine.Labels[ShardLabel] = strconv.Itoa(shard)
+		klog.Infof("%s: assigning the machine to shard %d", machine.Name, shard)
+		if err := r.tenantClusterClient.PatchMachine(machine, originMachineCopy); err != nil {
+			klog.Errorf("%s: failed to label the machine with its shard, with error: %v", machine.Name, err)
+		}
+	}
+	return nil
+}
+
+// Add registers the runnable labeling the Machines with their shard, its tenant-cluster client must read all the
+// Machines
+func Add(mgr manager.Manager, tenantClusterClient tenantcluster.Client, options Options, pollInterval time.Duration) error {
+	return mgr.Add(&shardLabeler{
+		tenantClusterClient: tenantClusterClient,
+		count:               options.Count,
+		pollInterval:        pollInterval,
+	})
+}
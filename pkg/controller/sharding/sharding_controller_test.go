@@ -0,0 +1,77 @@
+package sharding
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	mockTenantClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster/mock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/testutils"
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"gotest.tools/assert"
+)
+
+func stubMachine(name string, machineSet string, labels map[string]string) machinev1.Machine {
+	machine := testutils.StubMachineOfMachineSet(name, machineSet)
+	machine.Labels = labels
+	return machine
+}
+
+func TestOptions(t *testing.T) {
+	assert.NilError(t, Options{Count: 1}.Validate())
+	assert.NilError(t, Options{Count: 4, Index: 3}.Validate())
+	assert.Error(t, Options{Count: 0}.Validate(), "invalid shard count 0, expected at least 1")
+	assert.Error(t, Options{Count: 4, Index: 4}.Validate(), "invalid shard index 4, expected between 0 and 3")
+
+	assert.Assert(t, !Options{Count: 1}.Enabled())
+	assert.Equal(t, Options{Count: 1}.LeaderElectionID("leader"), "leader")
+	assert.Equal(t, Options{Count: 4, Index: 2}.LeaderElectionID("leader"), "leader-shard-2")
+	assert.Assert(t, Options{Count: 4}.RunsClusterWide())
+	assert.Assert(t, !Options{Count: 4, Index: 2}.RunsClusterWide())
+}
+
+func TestShardOf(t *testing.T) {
+	for _, count := range []int{2, 3, 16} {
+		shards := map[int]bool{}
+		for i := 0; i < 32; i++ {
+			machine := stubMachine(fmt.Sprintf("workers-%d", i), "workers", nil)
+			shard := ShardOf(&machine, count)
+			assert.Assert(t, shard >= 0 && shard < count)
+			shards[shard] = true
+		}
+		// The Machines of a MachineSet are in the same shard
+		assert.Equal(t, len(shards), 1)
+	}
+
+	// The Machines without a MachineSet are spread by their name
+	shards := map[int]bool{}
+	for i := 0; i < 32; i++ {
+		machine := stubMachine(fmt.Sprintf("machine-%d", i), "", nil)
+		shards[ShardOf(&machine, 4)] = true
+	}
+	assert.Assert(t, len(shards) > 1)
+}
+
+func TestReconcile(t *testing.T) {
+	unlabeled := stubMachine("workers-a", "workers", nil)
+	labeled := stubMachine("workers-b", "workers", map[string]string{ShardLabel: "1"})
+	outOfCount := stubMachine("workers-c", "workers", map[string]string{ShardLabel: "7"})
+	expectedShard := fmt.Sprint(ShardOf(&unlabeled, 4))
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	tenantClient := mockTenantClusterClient.NewMockClient(mockCtrl)
+	tenantClient.EXPECT().ListMachines(gomock.Any()).Return([]machinev1.Machine{unlabeled, labeled, outOfCount}, nil).Times(1)
+	var patched []string
+	tenantClient.EXPECT().PatchMachine(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(machine *machinev1.Machine, originMachineCopy *machinev1.Machine) error {
+			patched = append(patched, machine.Name)
+			assert.Equal(t, machine.Labels[ShardLabel], expectedShard)
+			return nil
+		}).Times(2)
+
+	r := &shardLabeler{tenantClusterClient: tenantClient, count: 4}
+	assert.NilError(t, r.Reconcile(context.Background()))
+	assert.DeepEqual(t, patched, []string{"workers-a", "workers-c"})
+}
@@ -0,0 +1,116 @@
+// stuckmachine package implements a controller to report the Machines stuck in a phase:
+// - Find the Machines in a transitional phase: Provisioning, Provisioned or Deleting
+// - Compute the time since they entered their phase, from their creation, the creation of their VirtualMachine or
+// their deletion, which survives the restarts of the controller
+// - Publish the Machines in their phase for longer than the threshold as a metric, for alerting on provisioning stalls
+package stuckmachine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster"
+)
+
+// The transitional phases of the Machines, set by the machine controller
+const (
+	phaseProvisioning = "Provisioning"
+	phaseProvisioned  = "Provisioned"
+	phaseDeleting     = "Deleting"
+)
+
+var stuckMachines = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "kubevirt_machine_stuck_in_phase_seconds",
+		Help: "Time the Machine has been in its phase, for the Machines in a transitional phase for longer than the stuck threshold",
+	},
+	[]string{"namespace", "name", "phase"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(stuckMachines)
+}
+
+var _ manager.Runnable = &stuckMachineReconciler{}
+
+type stuckMachineReconciler struct {
+	tenantClusterClient tenantcluster.Client
+	threshold           time.Duration
+	pollInterval        time.Duration
+}
+
+// Start reports the stuck Machines until the context is done
+func (r *stuckMachineReconciler) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := r.Reconcile(ctx, time.Now()); err != nil {
+			klog.Errorf("stuck machine: %v", err)
+		}
+	}, r.pollInterval)
+	return nil
+}
+
+// Reconcile publishes the Machines which are in a transitional phase for longer than the threshold
+func (r *stuckMachineReconciler) Reconcile(ctx context.Context, now time.Time) error {
+	machines, err := r.tenantClusterClient.ListMachines(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list Machines, with error: %v", err)
+	}
+	// Machines which were deleted, or left their phase, aren't stuck anymore
+	stuckMachines.Reset()
+	for i := range machines {
+		machine := &machines[i]
+		phase, since, ok := phaseStart(machine)
+		if !ok {
+			continue
+		}
+		inPhase := now.Sub(since)
+		if inPhase < r.threshold {
+			continue
+		}
+		klog.Warningf("%s: machine is stuck in phase %s for %v", machine.Name, phase, inPhase.Round(time.Second))
+		stuckMachines.WithLabelValues(machine.Namespace, machine.Name, phase).Set(inPhase.Seconds())
+	}
+	return nil
+}
+
+// phaseStart returns the transitional phase of the Machine and the time it entered it, or false when the Machine
+// isn't in a transitional phase
+func phaseStart(machine *machinev1.Machine) (string, time.Time, bool) {
+	if machine.DeletionTimestamp != nil {
+		return phaseDeleting, machine.DeletionTimestamp.Time, true
+	}
+	phase := ""
+	if machine.Status.Phase != nil {
+		phase = *machine.Status.Phase
+	}
+	switch phase {
+	case "", phaseProvisioning:
+		return phaseProvisioning, machine.CreationTimestamp.Time, true
+	case phaseProvisioned:
+		// The Machine is provisioned once its VirtualMachine is created, and waits for its node
+		providerStatus, err := kubevirtproviderv1alpha1.ProviderStatusFromRawExtension(machine.Status.ProviderStatus)
+		if err == nil && providerStatus.ProvisioningTimeline != nil && providerStatus.ProvisioningTimeline.VMCreated != nil {
+			return phaseProvisioned, providerStatus.ProvisioningTimeline.VMCreated.Time, true
+		}
+		return phaseProvisioned, machine.CreationTimestamp.Time, true
+	}
+	return "", time.Time{}, false
+}
+
+// Add registers the runnable reporting the Machines stuck in a phase for longer than the threshold
+func Add(mgr manager.Manager, tenantClusterClient tenantcluster.Client, threshold time.Duration, pollInterval time.Duration) error {
+	return mgr.Add(&stuckMachineReconciler{
+		tenantClusterClient: tenantClusterClient,
+		threshold:           threshold,
+		pollInterval:        pollInterval,
+	})
+}
@@ -0,0 +1,97 @@
+package stuckmachine
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+	mockTenantClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster/mock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/testutils"
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"gotest.tools/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func stubMachine(t *testing.T, name string, phase string, created time.Time, vmCreated *time.Time) machinev1.Machine {
+	machine := testutils.StubMachineOfMachineSet(name, "")
+	machine.CreationTimestamp = metav1.NewTime(created)
+	if phase != "" {
+		machine.Status.Phase = &phase
+	}
+	if vmCreated != nil {
+		providerStatus, err := kubevirtproviderv1alpha1.RawExtensionFromProviderStatus(&kubevirtproviderv1alpha1.KubevirtMachineProviderStatus{
+			ProvisioningTimeline: &kubevirtproviderv1alpha1.ProvisioningTimeline{VMCreated: &metav1.Time{Time: *vmCreated}},
+		})
+		assert.NilError(t, err)
+		machine.Status.ProviderStatus = providerStatus
+	}
+	return machine
+}
+
+func TestReconcile(t *testing.T) {
+	// The provider status keeps the times to the second
+	now := time.Now().Truncate(time.Second)
+	hourAgo := now.Add(-time.Hour)
+	minuteAgo := now.Add(-time.Minute)
+	deleting := stubMachine(t, "deleting", "Running", hourAgo, nil)
+	deleting.DeletionTimestamp = &metav1.Time{Time: now.Add(-45 * time.Minute)}
+
+	cases := []struct {
+		name     string
+		machines []machinev1.Machine
+		expected map[string]float64
+	}{
+		{
+			name: "Success report the machines stuck in their phase",
+			machines: []machinev1.Machine{
+				stubMachine(t, "provisioning", "Provisioning", hourAgo, nil),
+				stubMachine(t, "without-phase", "", hourAgo, nil),
+				stubMachine(t, "provisioned", "Provisioned", hourAgo, &hourAgo),
+				deleting,
+			},
+			expected: map[string]float64{
+				"provisioning/Provisioning":  3600,
+				"without-phase/Provisioning": 3600,
+				"provisioned/Provisioned":    3600,
+				"deleting/Deleting":          2700,
+			},
+		},
+		{
+			name: "Success machines within the threshold or not in a transitional phase aren't stuck",
+			machines: []machinev1.Machine{
+				stubMachine(t, "new", "Provisioning", minuteAgo, nil),
+				stubMachine(t, "booting", "Provisioned", hourAgo, &minuteAgo),
+				stubMachine(t, "running", "Running", hourAgo, &hourAgo),
+				stubMachine(t, "failed", "Failed", hourAgo, nil),
+			},
+			expected: map[string]float64{},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			tenantClient := mockTenantClusterClient.NewMockClient(mockCtrl)
+			tenantClient.EXPECT().ListMachines(gomock.Any()).Return(tc.machines, nil).Times(1)
+
+			r := &stuckMachineReconciler{tenantClusterClient: tenantClient, threshold: 30 * time.Minute}
+			assert.NilError(t, r.Reconcile(context.Background(), now))
+			values, err := testutils.GaugeValues(stuckMachines, "name", "phase")
+			assert.NilError(t, err)
+			assert.DeepEqual(t, values, tc.expected)
+		})
+	}
+}
+
+func TestReconcileListError(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	tenantClient := mockTenantClusterClient.NewMockClient(mockCtrl)
+	tenantClient.EXPECT().ListMachines(gomock.Any()).Return(nil, fmt.Errorf("test error")).Times(1)
+
+	r := &stuckMachineReconciler{tenantClusterClient: tenantClient, threshold: 30 * time.Minute}
+	assert.Error(t, r.Reconcile(context.Background(), time.Now()), "failed to list Machines, with error: test error")
+}
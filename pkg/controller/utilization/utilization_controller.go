@@ -0,0 +1,144 @@
+// utilization package implements an optional collector of the resource usage of the Machines:
+// - Read the metrics API of the infra-cluster for the virt-launcher pods of this tenant-cluster
+// - Map each virt-launcher pod to its VirtualMachine, and the VirtualMachine to its Machine by the providerID
+// - Expose the CPU and memory usage of every Machine as metrics of the provider, for right-sizing the MachineSets
+// The usage includes the overhead of the virt-launcher pod, on top of the usage of the guest.
+package utilization
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/kubevirt"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/utils"
+)
+
+const (
+	configMapNamespace             = "openshift-config"
+	configMapName                  = "cloud-provider-config"
+	configMapDataKeyName           = "config"
+	configMapInfraNamespaceKeyName = "namespace"
+	configMapInfraIDKeyName        = "infraID"
+
+	// virtualMachineLabelKey is the label of the virt-launcher pod holding the name of its VirtualMachine
+	virtualMachineLabelKey = "kubevirt.io/vm"
+)
+
+var (
+	cpuUsage = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kubevirt_machine_cpu_usage_cores",
+			Help: "CPU usage of the virt-launcher pod of the Machine in the infra-cluster, in cores",
+		},
+		[]string{"namespace", "name", "virtual_machine"},
+	)
+	memoryUsage = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kubevirt_machine_memory_usage_bytes",
+			Help: "Memory usage of the virt-launcher pod of the Machine in the infra-cluster, in bytes",
+		},
+		[]string{"namespace", "name", "virtual_machine"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(cpuUsage, memoryUsage)
+}
+
+var _ manager.Runnable = &utilizationReconciler{}
+
+type utilizationReconciler struct {
+	infraClusterClient  infracluster.Client
+	tenantClusterClient tenantcluster.Client
+	pollInterval        time.Duration
+}
+
+// Start collects the usage of the Machines until the context is done
+func (r *utilizationReconciler) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := r.Reconcile(ctx); err != nil {
+			klog.Errorf("utilization: %v", err)
+		}
+	}, r.pollInterval)
+	return nil
+}
+
+// Reconcile sets the usage metrics of every Machine whose virt-launcher pod is reported by the metrics API
+func (r *utilizationReconciler) Reconcile(ctx context.Context) error {
+	cMap, err := r.tenantClusterClient.GetConfigMapValue(ctx, configMapName, configMapNamespace, configMapDataKeyName)
+	if err != nil {
+		return err
+	}
+	infraNamespace, ok := (*cMap)[configMapInfraNamespaceKeyName]
+	if !ok {
+		return fmt.Errorf("configMap %s/%s: The map extracted with key %s doesn't contain key %s",
+			configMapNamespace, configMapName, configMapDataKeyName, configMapInfraNamespaceKeyName)
+	}
+	infraID, ok := (*cMap)[configMapInfraIDKeyName]
+	if !ok {
+		return fmt.Errorf("configMap %s/%s: The map extracted with key %s doesn't contain key %s",
+			configMapNamespace, configMapName, configMapDataKeyName, configMapInfraIDKeyName)
+	}
+
+	podMetrics, err := r.infraClusterClient.ListPodMetrics(ctx, infraNamespace, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(utils.BuildLabels(infraID)).String(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list the pod metrics of the infra namespace, with error: %v", err)
+	}
+	usageByVirtualMachine := map[string]corev1.ResourceList{}
+	for _, pod := range podMetrics {
+		if virtualMachineName := pod.Labels[virtualMachineLabelKey]; virtualMachineName != "" {
+			usageByVirtualMachine[virtualMachineName] = pod.Usage
+		}
+	}
+
+	machines, err := r.tenantClusterClient.ListMachines(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list Machines, with error: %v", err)
+	}
+	// Machines which were deleted, or whose VirtualMachine isn't running, have no usage
+	cpuUsage.Reset()
+	memoryUsage.Reset()
+	for _, machine := range machines {
+		if machine.Spec.ProviderID == nil {
+			continue
+		}
+		namespace, virtualMachineName, err := kubevirt.ParseProviderID(*machine.Spec.ProviderID)
+		if err != nil || namespace != infraNamespace {
+			continue
+		}
+		usage, ok := usageByVirtualMachine[virtualMachineName]
+		if !ok {
+			continue
+		}
+		if cpu, ok := usage[corev1.ResourceCPU]; ok {
+			cpuUsage.WithLabelValues(machine.Namespace, machine.Name, virtualMachineName).Set(float64(cpu.MilliValue()) / 1000)
+		}
+		if memory, ok := usage[corev1.ResourceMemory]; ok {
+			memoryUsage.WithLabelValues(machine.Namespace, machine.Name, virtualMachineName).Set(float64(memory.Value()))
+		}
+	}
+	return nil
+}
+
+// Add registers the utilization runnable with the controller manager
+func Add(mgr manager.Manager, infraClusterClient infracluster.Client, tenantClusterClient tenantcluster.Client, pollInterval time.Duration) error {
+	return mgr.Add(&utilizationReconciler{
+		infraClusterClient:  infraClusterClient,
+		tenantClusterClient: tenantClusterClient,
+		pollInterval:        pollInterval,
+	})
+}
@@ -0,0 +1,69 @@
+package utilization
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster"
+	mockInfraClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster/mock"
+	mockTenantClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster/mock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/testutils"
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestReconcile(t *testing.T) {
+	cMap := map[string]string{
+		configMapInfraNamespaceKeyName: testutils.InfraNamespace,
+		configMapInfraIDKeyName:        testutils.InfraID,
+	}
+	podMetrics := []infracluster.PodMetrics{
+		{
+			Name:   "virt-launcher-vm-a-xyz",
+			Labels: map[string]string{virtualMachineLabelKey: "vm-a"},
+			Usage: corev1.ResourceList{
+				corev1.ResourceCPU:    apiresource.MustParse("1500m"),
+				corev1.ResourceMemory: apiresource.MustParse("2Gi"),
+			},
+		},
+		{
+			Name:   "virt-launcher-vm-orphan-xyz",
+			Labels: map[string]string{virtualMachineLabelKey: "vm-orphan"},
+			Usage:  corev1.ResourceList{corev1.ResourceCPU: apiresource.MustParse("1")},
+		},
+	}
+	machines := []machinev1.Machine{
+		testutils.StubMachineOfMachineSet("machine-a", ""),
+		testutils.StubMachineOfMachineSet("machine-b", ""),
+		testutils.StubMachineOfMachineSet("machine-provisioning", ""),
+	}
+	machines[0].Spec.ProviderID = testutils.StringPointer(fmt.Sprintf("kubevirt://%s/vm-a", testutils.InfraNamespace))
+	machines[1].Spec.ProviderID = testutils.StringPointer(fmt.Sprintf("kubevirt://%s/vm-b", testutils.InfraNamespace))
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	infraClient := mockInfraClusterClient.NewMockClient(mockCtrl)
+	tenantClient := mockTenantClusterClient.NewMockClient(mockCtrl)
+	tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+	infraClient.EXPECT().ListPodMetrics(gomock.Any(), testutils.InfraNamespace, gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ string, options metav1.ListOptions) ([]infracluster.PodMetrics, error) {
+			assert.Equal(t, options.LabelSelector, "tenantcluster-"+testutils.InfraID+"-machine.openshift.io=owned")
+			return podMetrics, nil
+		}).Times(1)
+	tenantClient.EXPECT().ListMachines(gomock.Any()).Return(machines, nil).Times(1)
+
+	r := &utilizationReconciler{infraClusterClient: infraClient, tenantClusterClient: tenantClient}
+	assert.NilError(t, r.Reconcile(context.Background()))
+
+	values, err := testutils.GaugeValues(cpuUsage, "name")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, values, map[string]float64{"machine-a": 1.5})
+	values, err = testutils.GaugeValues(memoryUsage, "name")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, values, map[string]float64{"machine-a": float64(2 * 1024 * 1024 * 1024)})
+}
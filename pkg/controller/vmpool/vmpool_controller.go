@@ -0,0 +1,144 @@
+// vmpool package implements a controller to scale the VirtualMachinePools backing MachineSets:
+// - Find the kubevirt MachineSets whose provider spec names a VirtualMachinePool
+// - Count the VirtualMachines of the pool adopted by Machines
+// - Set the replicas of the pool to the replicas of the MachineSet, but never below the adopted VirtualMachines
+// The pool thus never removes the VirtualMachine of a Machine which wasn't deleted yet.
+package vmpool
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
+)
+
+const (
+	configMapNamespace             = "openshift-config"
+	configMapName                  = "cloud-provider-config"
+	configMapDataKeyName           = "config"
+	configMapInfraNamespaceKeyName = "namespace"
+)
+
+var _ manager.Runnable = &vmPoolReconciler{}
+
+type vmPoolReconciler struct {
+	infraClusterClient  infracluster.Client
+	tenantClusterClient tenantcluster.Client
+	pollInterval        time.Duration
+}
+
+// Start scales the VirtualMachinePools until the context is done
+func (r *vmPoolReconciler) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := r.Reconcile(ctx); err != nil {
+			klog.Errorf("vm pool: %v", err)
+		}
+	}, r.pollInterval)
+	return nil
+}
+
+// Reconcile sets the replicas of the VirtualMachinePool of every MachineSet backed by a pool
+func (r *vmPoolReconciler) Reconcile(ctx context.Context) error {
+	if len(r.infraClusterClient.GetCapabilities().PoolVersions) == 0 {
+		return nil
+	}
+	machineSets, err := r.tenantClusterClient.ListMachineSets(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list MachineSets, with error: %v", err)
+	}
+	pools := map[string]*machinev1.MachineSet{}
+	for i := range machineSets {
+		machineSet := &machineSets[i]
+		providerSpec, err := kubevirtproviderv1alpha1.ProviderSpecFromRawExtension(machineSet.Spec.Template.Spec.ProviderSpec.Value)
+		if err != nil {
+			klog.Errorf("%s: failed to get the provider spec of the MachineSet, with error: %v", machineSet.Name, err)
+			continue
+		}
+		if providerSpec.VirtualMachinePoolName != "" {
+			pools[providerSpec.VirtualMachinePoolName] = machineSet
+		}
+	}
+	if len(pools) == 0 {
+		return nil
+	}
+
+	cMap, err := r.tenantClusterClient.GetConfigMapValue(ctx, configMapName, configMapNamespace, configMapDataKeyName)
+	if err != nil {
+		return err
+	}
+	infraNamespace, ok := (*cMap)[configMapInfraNamespaceKeyName]
+	if !ok {
+		return fmt.Errorf("configMap %s/%s: The map extracted with key %s doesn't contain key %s",
+			configMapNamespace, configMapName, configMapDataKeyName, configMapInfraNamespaceKeyName)
+	}
+	vms, err := r.infraClusterClient.ListVirtualMachine(ctx, infraNamespace, metav1.ListOptions{LabelSelector: machinescope.AdoptedByLabel})
+	if err != nil {
+		return fmt.Errorf("failed to list the adopted VirtualMachines of the infra namespace, with error: %v", err)
+	}
+
+	for poolName, machineSet := range pools {
+		adopted := 0
+		for i := range vms.Items {
+			if machinescope.IsPoolVirtualMachine(&vms.Items[i], poolName) {
+				adopted++
+			}
+		}
+		replicas := machineSetReplicas(machineSet)
+		if replicas < adopted {
+			replicas = adopted
+		}
+		if err := r.scalePool(ctx, infraNamespace, poolName, int64(replicas)); err != nil {
+			klog.Errorf("%s: failed to scale VirtualMachinePool %s, with error: %v", machineSet.Name, poolName, err)
+		}
+	}
+	return nil
+}
+
+// scalePool sets the replicas of the VirtualMachinePool
+func (r *vmPoolReconciler) scalePool(ctx context.Context, namespace string, poolName string, replicas int64) error {
+	pool, err := r.infraClusterClient.GetVirtualMachinePool(ctx, namespace, poolName)
+	if err != nil {
+		return err
+	}
+	current, found, err := unstructured.NestedInt64(pool.Object, "spec", "replicas")
+	if err != nil {
+		return err
+	}
+	if found && current == replicas {
+		return nil
+	}
+	if err := unstructured.SetNestedField(pool.Object, replicas, "spec", "replicas"); err != nil {
+		return err
+	}
+	klog.Infof("vm pool: scale VirtualMachinePool %s from %d to %d replicas", poolName, current, replicas)
+	_, err = r.infraClusterClient.UpdateVirtualMachinePool(ctx, namespace, pool)
+	return err
+}
+
+// machineSetReplicas returns the replicas of the MachineSet, which default to 1
+func machineSetReplicas(machineSet *machinev1.MachineSet) int {
+	if machineSet.Spec.Replicas == nil {
+		return 1
+	}
+	return int(*machineSet.Spec.Replicas)
+}
+
+// Add registers the vm pool runnable with the controller manager
+func Add(mgr manager.Manager, infraClusterClient infracluster.Client, tenantClusterClient tenantcluster.Client, pollInterval time.Duration) error {
+	return mgr.Add(&vmPoolReconciler{
+		infraClusterClient:  infraClusterClient,
+		tenantClusterClient: tenantClusterClient,
+		pollInterval:        pollInterval,
+	})
+}
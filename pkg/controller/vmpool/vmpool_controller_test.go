@@ -0,0 +1,121 @@
+package vmpool
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster"
+	mockInfraClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster/mock"
+	mockTenantClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster/mock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/testutils"
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"gotest.tools/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/utils/pointer"
+	kubevirtapiv1 "kubevirt.io/client-go/api/v1"
+)
+
+func stubMachineSet(t *testing.T, name string, replicas int32, poolName string) machinev1.MachineSet {
+	machineSet, err := testutils.StubMachineSet(name, nil, kubevirtproviderv1alpha1.KubevirtMachineProviderSpec{VirtualMachinePoolName: poolName})
+	assert.NilError(t, err)
+	machineSet.Spec.Replicas = pointer.Int32Ptr(replicas)
+	return machineSet
+}
+
+func stubPool(replicas int64) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "workers-pool", "namespace": testutils.InfraNamespace},
+		"spec":     map[string]interface{}{"replicas": replicas},
+	}}
+}
+
+func stubAdoptedVirtualMachine(poolName string) kubevirtapiv1.VirtualMachine {
+	return kubevirtapiv1.VirtualMachine{ObjectMeta: metav1.ObjectMeta{
+		Labels:          map[string]string{machinescope.AdoptedByLabel: "machine"},
+		OwnerReferences: []metav1.OwnerReference{{Kind: "VirtualMachinePool", Name: poolName}},
+	}}
+}
+
+func TestReconcile(t *testing.T) {
+	cMap := map[string]string{configMapInfraNamespaceKeyName: testutils.InfraNamespace}
+	capabilities := infracluster.Capabilities{PoolVersions: []string{"v1alpha1"}}
+	adopted := &kubevirtapiv1.VirtualMachineList{Items: []kubevirtapiv1.VirtualMachine{
+		stubAdoptedVirtualMachine("workers-pool"),
+		stubAdoptedVirtualMachine("workers-pool"),
+		stubAdoptedVirtualMachine("other-pool"),
+	}}
+
+	cases := []struct {
+		name        string
+		expectedErr string
+		expect      func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient)
+	}{
+		{
+			name: "Success scale up the pool",
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().ListMachineSets(gomock.Any()).Return([]machinev1.MachineSet{
+					stubMachineSet(t, "workers", 3, "workers-pool"),
+					stubMachineSet(t, "infra", 2, ""),
+				}, nil).Times(1)
+				tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+				infraClient.EXPECT().ListVirtualMachine(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(adopted, nil).Times(1)
+				infraClient.EXPECT().GetVirtualMachinePool(gomock.Any(), testutils.InfraNamespace, "workers-pool").Return(stubPool(2), nil).Times(1)
+				infraClient.EXPECT().UpdateVirtualMachinePool(gomock.Any(), testutils.InfraNamespace, stubPool(3)).Return(stubPool(3), nil).Times(1)
+			},
+		},
+		{
+			name: "Success keep the adopted virtual machines on scale down",
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().ListMachineSets(gomock.Any()).Return([]machinev1.MachineSet{
+					stubMachineSet(t, "workers", 1, "workers-pool"),
+				}, nil).Times(1)
+				tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+				infraClient.EXPECT().ListVirtualMachine(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(adopted, nil).Times(1)
+				infraClient.EXPECT().GetVirtualMachinePool(gomock.Any(), testutils.InfraNamespace, "workers-pool").Return(stubPool(2), nil).Times(1)
+			},
+		},
+		{
+			name: "Success without pool backed machine sets",
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().ListMachineSets(gomock.Any()).Return([]machinev1.MachineSet{
+					stubMachineSet(t, "infra", 2, ""),
+				}, nil).Times(1)
+			},
+		},
+		{
+			name: "Failure list virtual machines",
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().ListMachineSets(gomock.Any()).Return([]machinev1.MachineSet{
+					stubMachineSet(t, "workers", 3, "workers-pool"),
+				}, nil).Times(1)
+				tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+				infraClient.EXPECT().ListVirtualMachine(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(nil, fmt.Errorf("test error")).Times(1)
+			},
+			expectedErr: "failed to list the adopted VirtualMachines of the infra namespace, with error: test error",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			infraClient := mockInfraClusterClient.NewMockClient(mockCtrl)
+			tenantClient := mockTenantClusterClient.NewMockClient(mockCtrl)
+			tc.expect(infraClient, tenantClient)
+			infraClient.EXPECT().GetCapabilities().Return(capabilities).AnyTimes()
+
+			r := &vmPoolReconciler{infraClusterClient: infraClient, tenantClusterClient: tenantClient}
+			err := r.Reconcile(context.Background())
+			if tc.expectedErr != "" {
+				assert.Error(t, err, tc.expectedErr)
+			} else {
+				assert.NilError(t, err)
+			}
+		})
+	}
+}
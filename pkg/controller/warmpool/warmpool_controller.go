@@ -0,0 +1,176 @@
+// warmpool package implements a controller to keep a warm pool of boot volumes cloned ahead of time:
+// - Read the warm pool size annotation of every kubevirt MachineSet
+// - Sum the sizes of the MachineSets by the shape of their boot volume
+// - Clone the missing boot DataVolumes of every shape in the infra namespace, and delete the surplus ones
+// A new Machine claims a cloned DataVolume of its shape, instead of waiting for the clone of its boot volume.
+package warmpool
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog"
+	cdiv1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/utils"
+)
+
+const (
+	configMapNamespace             = "openshift-config"
+	configMapName                  = "cloud-provider-config"
+	configMapDataKeyName           = "config"
+	configMapInfraNamespaceKeyName = "namespace"
+	configMapInfraIDKeyName        = "infraID"
+
+	// WarmPoolSizeAnnotation is the number of boot volumes of the shape of the MachineSet to clone ahead of time
+	WarmPoolSizeAnnotation = "kubevirt.machine.openshift.io/warm-pool-size"
+)
+
+var _ manager.Runnable = &warmPoolReconciler{}
+
+type warmPoolReconciler struct {
+	infraClusterClient  infracluster.Client
+	tenantClusterClient tenantcluster.Client
+	pollInterval        time.Duration
+}
+
+// Start keeps the warm pool filled until the context is done
+func (r *warmPoolReconciler) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := r.Reconcile(ctx); err != nil {
+			klog.Errorf("warm pool: %v", err)
+		}
+	}, r.pollInterval)
+	return nil
+}
+
+// Reconcile clones the missing boot DataVolumes of the warm pool, and deletes the ones no MachineSet wants anymore
+func (r *warmPoolReconciler) Reconcile(ctx context.Context) error {
+	if len(r.infraClusterClient.GetCapabilities().CDIVersions) == 0 {
+		return nil
+	}
+	cMap, err := r.tenantClusterClient.GetConfigMapValue(ctx, configMapName, configMapNamespace, configMapDataKeyName)
+	if err != nil {
+		return err
+	}
+	infraNamespace, ok := (*cMap)[configMapInfraNamespaceKeyName]
+	if !ok {
+		return fmt.Errorf("configMap %s/%s: The map extracted with key %s doesn't contain key %s",
+			configMapNamespace, configMapName, configMapDataKeyName, configMapInfraNamespaceKeyName)
+	}
+	infraID, ok := (*cMap)[configMapInfraIDKeyName]
+	if !ok {
+		return fmt.Errorf("configMap %s/%s: The map extracted with key %s doesn't contain key %s",
+			configMapNamespace, configMapName, configMapDataKeyName, configMapInfraIDKeyName)
+	}
+
+	machineSets, err := r.tenantClusterClient.ListMachineSets(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list MachineSets, with error: %v", err)
+	}
+	desired := map[string]int{}
+	specs := map[string]*kubevirtproviderv1alpha1.KubevirtMachineProviderSpec{}
+	for _, machineSet := range machineSets {
+		value, ok := machineSet.Annotations[WarmPoolSizeAnnotation]
+		if !ok {
+			continue
+		}
+		size, err := strconv.Atoi(value)
+		if err != nil || size < 0 {
+			klog.Errorf("%s: invalid warm pool size %q of the MachineSet", machineSet.Name, value)
+			continue
+		}
+		providerSpec, err := kubevirtproviderv1alpha1.ProviderSpecFromRawExtension(machineSet.Spec.Template.Spec.ProviderSpec.Value)
+		if err != nil {
+			klog.Errorf("%s: failed to get the provider spec of the MachineSet, with error: %v", machineSet.Name, err)
+			continue
+		}
+		// Boot volumes cloned ahead of time would miss the updates of the golden image of a DataSource
+		if providerSpec.DataSourceName != "" {
+			klog.Infof("%s: the warm pool of a MachineSet cloning DataSource %s isn't supported", machineSet.Name, providerSpec.DataSourceName)
+			continue
+		}
+		// The source PVC of a template is only known for every Machine
+		if machinescope.IsTemplate(providerSpec.SourcePvcName) {
+			klog.Infof("%s: the warm pool of a MachineSet whose SourcePvcName is a template isn't supported", machineSet.Name)
+			continue
+		}
+		shape := machinescope.BootVolumeShape(providerSpec)
+		desired[shape] += size
+		specs[shape] = providerSpec
+	}
+
+	pool, err := r.listWarmPool(ctx, infraNamespace, infraID)
+	if err != nil {
+		return fmt.Errorf("failed to list the DataVolumes of the warm pool, with error: %v", err)
+	}
+	existing := map[string][]cdiv1.DataVolume{}
+	for _, dataVolume := range pool {
+		if dataVolume.DeletionTimestamp != nil {
+			continue
+		}
+		shape := dataVolume.Labels[machinescope.WarmPoolShapeLabel]
+		existing[shape] = append(existing[shape], dataVolume)
+	}
+
+	for shape, dataVolumes := range existing {
+		// The oldest DataVolumes are the most likely to be cloned already, the newest ones are deleted first
+		sort.Slice(dataVolumes, func(i, j int) bool {
+			return dataVolumes[i].CreationTimestamp.Before(&dataVolumes[j].CreationTimestamp)
+		})
+		if len(dataVolumes) <= desired[shape] {
+			continue
+		}
+		for _, dataVolume := range dataVolumes[desired[shape]:] {
+			klog.Infof("warm pool: delete the surplus DataVolume %s of shape %s", dataVolume.Name, shape)
+			if err := r.infraClusterClient.DeleteDataVolume(ctx, infraNamespace, dataVolume.Name); err != nil && !errors.IsNotFound(err) {
+				klog.Errorf("warm pool: failed to delete DataVolume %s, with error: %v", dataVolume.Name, err)
+			}
+		}
+	}
+	for shape, size := range desired {
+		for missing := size - len(existing[shape]); missing > 0; missing-- {
+			dataVolume := machinescope.BuildWarmBootVolume(specs[shape], infraNamespace, infraID)
+			created, err := r.infraClusterClient.CreateDataVolume(ctx, infraNamespace, dataVolume)
+			if err != nil {
+				klog.Errorf("warm pool: failed to create a DataVolume of shape %s, with error: %v", shape, err)
+				break
+			}
+			klog.Infof("warm pool: cloning DataVolume %s of shape %s", created.Name, shape)
+		}
+	}
+	return nil
+}
+
+// listWarmPool lists the unclaimed DataVolumes of the warm pool of the tenant cluster
+func (r *warmPoolReconciler) listWarmPool(ctx context.Context, infraNamespace, infraID string) ([]cdiv1.DataVolume, error) {
+	selector := labels.SelectorFromSet(utils.BuildLabels(infraID))
+	requirement, err := labels.NewRequirement(machinescope.WarmPoolShapeLabel, selection.Exists, nil)
+	if err != nil {
+		return nil, err
+	}
+	selector = selector.Add(*requirement)
+	return r.infraClusterClient.ListDataVolumes(ctx, infraNamespace, metav1.ListOptions{LabelSelector: selector.String()})
+}
+
+// Add registers the warm pool runnable with the controller manager
+func Add(mgr manager.Manager, infraClusterClient infracluster.Client, tenantClusterClient tenantcluster.Client, pollInterval time.Duration) error {
+	return mgr.Add(&warmPoolReconciler{
+		infraClusterClient:  infraClusterClient,
+		tenantClusterClient: tenantClusterClient,
+		pollInterval:        pollInterval,
+	})
+}
@@ -0,0 +1,119 @@
+package warmpool
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster"
+	mockInfraClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster/mock"
+	mockTenantClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster/mock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/testutils"
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"gotest.tools/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cdiv1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
+)
+
+func stubMachineSet(t *testing.T, name string, size string, providerSpec kubevirtproviderv1alpha1.KubevirtMachineProviderSpec) machinev1.MachineSet {
+	machineSet, err := testutils.StubMachineSet(name, map[string]string{WarmPoolSizeAnnotation: size}, providerSpec)
+	assert.NilError(t, err)
+	return machineSet
+}
+
+func stubDataVolume(name string, shape string, age time.Duration) cdiv1.DataVolume {
+	return cdiv1.DataVolume{ObjectMeta: metav1.ObjectMeta{
+		Name:              name,
+		Labels:            map[string]string{machinescope.WarmPoolShapeLabel: shape},
+		CreationTimestamp: metav1.NewTime(time.Now().Add(-age)),
+	}}
+}
+
+func TestReconcile(t *testing.T) {
+	cMap := map[string]string{configMapInfraNamespaceKeyName: testutils.InfraNamespace, configMapInfraIDKeyName: testutils.InfraID}
+	capabilities := infracluster.Capabilities{CDIVersions: []string{"v1beta1"}}
+	rhcosSpec := kubevirtproviderv1alpha1.KubevirtMachineProviderSpec{SourcePvcName: "rhcos", RequestedStorage: "35Gi"}
+	otherSpec := kubevirtproviderv1alpha1.KubevirtMachineProviderSpec{SourcePvcName: "other", RequestedStorage: "35Gi"}
+	rhcosShape := machinescope.BootVolumeShape(&rhcosSpec)
+	otherShape := machinescope.BootVolumeShape(&otherSpec)
+
+	cases := []struct {
+		name        string
+		expectedErr string
+		expect      func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient)
+	}{
+		{
+			name: "Success clone the missing boot volumes",
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				workers := stubMachineSet(t, "workers", "2", rhcosSpec)
+				infra := stubMachineSet(t, "infra", "1", rhcosSpec)
+				tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+				tenantClient.EXPECT().ListMachineSets(gomock.Any()).Return([]machinev1.MachineSet{workers, infra}, nil).Times(1)
+				infraClient.EXPECT().ListDataVolumes(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(
+					[]cdiv1.DataVolume{stubDataVolume("warm-1", rhcosShape, time.Hour)}, nil).Times(1)
+				infraClient.EXPECT().CreateDataVolume(gomock.Any(), testutils.InfraNamespace, gomock.Any()).DoAndReturn(
+					func(ctx context.Context, namespace string, dataVolume *cdiv1.DataVolume) (*cdiv1.DataVolume, error) {
+						assert.Equal(t, dataVolume.Labels[machinescope.WarmPoolShapeLabel], rhcosShape)
+						assert.Equal(t, dataVolume.Spec.Source.PVC.Name, "rhcos")
+						return dataVolume, nil
+					}).Times(2)
+			},
+		},
+		{
+			name: "Success delete the surplus boot volumes",
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				workers := stubMachineSet(t, "workers", "1", rhcosSpec)
+				tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+				tenantClient.EXPECT().ListMachineSets(gomock.Any()).Return([]machinev1.MachineSet{workers}, nil).Times(1)
+				infraClient.EXPECT().ListDataVolumes(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return([]cdiv1.DataVolume{
+					stubDataVolume("warm-new", rhcosShape, time.Minute),
+					stubDataVolume("warm-old", rhcosShape, time.Hour),
+					stubDataVolume("warm-other", otherShape, time.Hour),
+				}, nil).Times(1)
+				infraClient.EXPECT().DeleteDataVolume(gomock.Any(), testutils.InfraNamespace, "warm-new").Return(nil).Times(1)
+				infraClient.EXPECT().DeleteDataVolume(gomock.Any(), testutils.InfraNamespace, "warm-other").Return(nil).Times(1)
+			},
+		},
+		{
+			name: "Success skip invalid warm pool size",
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				workers := stubMachineSet(t, "workers", "many", rhcosSpec)
+				tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+				tenantClient.EXPECT().ListMachineSets(gomock.Any()).Return([]machinev1.MachineSet{workers}, nil).Times(1)
+				infraClient.EXPECT().ListDataVolumes(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(nil, nil).Times(1)
+			},
+		},
+		{
+			name: "Failure list data volumes",
+			expect: func(infraClient *mockInfraClusterClient.MockClient, tenantClient *mockTenantClusterClient.MockClient) {
+				tenantClient.EXPECT().GetConfigMapValue(gomock.Any(), configMapName, configMapNamespace, configMapDataKeyName).Return(&cMap, nil).Times(1)
+				tenantClient.EXPECT().ListMachineSets(gomock.Any()).Return(nil, nil).Times(1)
+				infraClient.EXPECT().ListDataVolumes(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(nil, fmt.Errorf("test error")).Times(1)
+			},
+			expectedErr: "failed to list the DataVolumes of the warm pool, with error: test error",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			infraClient := mockInfraClusterClient.NewMockClient(mockCtrl)
+			tenantClient := mockTenantClusterClient.NewMockClient(mockCtrl)
+			tc.expect(infraClient, tenantClient)
+			infraClient.EXPECT().GetCapabilities().Return(capabilities).AnyTimes()
+
+			r := &warmPoolReconciler{infraClusterClient: infraClient, tenantClusterClient: tenantClient}
+			err := r.Reconcile(context.Background())
+			if tc.expectedErr != "" {
+				assert.Error(t, err, tc.expectedErr)
+			} else {
+				assert.NilError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+// gather package collects the objects of a Machine into a tarball, to standardize the support data of the provider:
+// - The Machine and its rendered KubevirtMachineProviderStatus from the tenant-cluster
+// - The VirtualMachine, the VirtualMachineInstance, its virt-launcher pods and the DataVolumes from the infra-cluster
+// - The events of these objects in the infra namespace
+// An object which can't be read is recorded in errors.txt of the tarball, and doesn't fail the gather
+package gather
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+	kubevirtapiv1 "kubevirt.io/client-go/api/v1"
+	"sigs.k8s.io/yaml"
+
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/kubevirt"
+)
+
+const (
+	configMapNamespace             = "openshift-config"
+	configMapName                  = "cloud-provider-config"
+	configMapDataKeyName           = "config"
+	configMapInfraNamespaceKeyName = "namespace"
+	// launcherCreatedByLabel is set by KubeVirt on the virt-launcher pods, to the UID of their VirtualMachineInstance
+	launcherCreatedByLabel = "kubevirt.io/created-by"
+	errorsFileName         = "errors.txt"
+)
+
+// file is an entry of the tarball
+type file struct {
+	name    string
+	content []byte
+}
+
+// Gatherer collects the objects of the Machines
+type Gatherer struct {
+	infraClusterClient  infracluster.Client
+	tenantClusterClient tenantcluster.Client
+	files               []file
+	errors              []string
+}
+
+// New creates a Gatherer reading from the infra-cluster and the tenant-cluster
+func New(infraClusterClient infracluster.Client, tenantClusterClient tenantcluster.Client) *Gatherer {
+	return &Gatherer{
+		infraClusterClient:  infraClusterClient,
+		tenantClusterClient: tenantClusterClient,
+	}
+}
+
+// Gather writes a gzipped tarball of the objects of the machine to out
+func (g *Gatherer) Gather(ctx context.Context, machineName string, machineNamespace string, out io.Writer) error {
+	g.files, g.errors = nil, nil
+
+	machine, err := g.tenantClusterClient.GetMachine(ctx, machineName, machineNamespace)
+	if err != nil {
+		return fmt.Errorf("failed to get Machine %s/%s, with error: %v", machineNamespace, machineName, err)
+	}
+	g.add("machine.yaml", machine)
+	if providerStatus, err := kubevirtproviderv1alpha1.ProviderStatusFromRawExtension(machine.Status.ProviderStatus); err != nil {
+		g.addError("provider status", err)
+	} else {
+		g.add("provider-status.yaml", providerStatus)
+	}
+
+	vmNamespace, vmName, err := g.virtualMachineOfMachine(ctx, machine)
+	if err != nil {
+		g.addError("VirtualMachine name", err)
+		return g.write(out)
+	}
+	// The names of the objects whose events are gathered, the VirtualMachineInstance is named as its VirtualMachine
+	involvedObjects := map[string]bool{vmName: true}
+
+	vm, err := g.infraClusterClient.GetVirtualMachine(ctx, vmNamespace, vmName, &metav1.GetOptions{})
+	if err != nil {
+		g.addError("VirtualMachine", err)
+	} else {
+		g.add("virtualmachine.yaml", vm)
+		for _, dataVolumeTemplate := range vm.Spec.DataVolumeTemplates {
+			involvedObjects[dataVolumeTemplate.Name] = true
+			dataVolume, err := g.infraClusterClient.GetDataVolume(ctx, vmNamespace, dataVolumeTemplate.Name)
+			if err != nil {
+				g.addError("DataVolume "+dataVolumeTemplate.Name, err)
+				continue
+			}
+			g.add(fmt.Sprintf("datavolumes/%s.yaml", dataVolumeTemplate.Name), dataVolume)
+		}
+	}
+
+	vmi, err := g.infraClusterClient.GetVirtualMachineInstance(ctx, vmNamespace, vmName, &metav1.GetOptions{})
+	if err != nil {
+		g.addError("VirtualMachineInstance", err)
+	} else {
+		g.add("virtualmachineinstance.yaml", vmi)
+		for _, pod := range g.launcherPods(ctx, vmi) {
+			involvedObjects[pod.Name] = true
+			g.add(fmt.Sprintf("pods/%s.yaml", pod.Name), pod)
+		}
+	}
+
+	events, err := g.infraClusterClient.ListEvents(ctx, vmNamespace, metav1.ListOptions{})
+	if err != nil {
+		g.addError("events", err)
+	} else {
+		g.add("events.yaml", relatedEvents(events.Items, involvedObjects))
+	}
+
+	return g.write(out)
+}
+
+// virtualMachineOfMachine returns the namespace and the name of the VirtualMachine of the machine, from its
+// providerID, or from the infra namespace of the cloud provider config when the providerID isn't set yet
+func (g *Gatherer) virtualMachineOfMachine(ctx context.Context, machine *machinev1.Machine) (string, string, error) {
+	if machine.Spec.ProviderID != nil && *machine.Spec.ProviderID != "" {
+		return kubevirt.ParseProviderID(*machine.Spec.ProviderID)
+	}
+	cMap, err := g.tenantClusterClient.GetConfigMapValue(ctx, configMapName, configMapNamespace, configMapDataKeyName)
+	if err != nil {
+		return "", "", err
+	}
+	infraNamespace, ok := (*cMap)[configMapInfraNamespaceKeyName]
+	if !ok {
+		return "", "", fmt.Errorf("configMap %s/%s doesn't contain the key %s", configMapNamespace, configMapName, configMapInfraNamespaceKeyName)
+	}
+	return infraNamespace, machine.Name, nil
+}
+
+// launcherPods returns the virt-launcher pods of the VirtualMachineInstance
+func (g *Gatherer) launcherPods(ctx context.Context, vmi *kubevirtapiv1.VirtualMachineInstance) []corev1.Pod {
+	pods, err := g.infraClusterClient.ListPods(ctx, vmi.Namespace, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", launcherCreatedByLabel, vmi.UID),
+	})
+	if err != nil {
+		g.addError("virt-launcher pods", err)
+		return nil
+	}
+	return pods.Items
+}
+
+// relatedEvents returns the events of the involved objects, in the order they were listed
+func relatedEvents(events []corev1.Event, involvedObjects map[string]bool) []corev1.Event {
+	result := []corev1.Event{}
+	for _, event := range events {
+		if involvedObjects[event.InvolvedObject.Name] {
+			result = append(result, event)
+		}
+	}
+	return result
+}
+
+func (g *Gatherer) add(name string, obj interface{}) {
+	content, err := yaml.Marshal(obj)
+	if err != nil {
+		g.addError(name, err)
+		return
+	}
+	g.files = append(g.files, file{name: name, content: content})
+}
+
+func (g *Gatherer) addError(what string, err error) {
+	klog.Warningf("failed to gather the %s, with error: %v", what, err)
+	g.errors = append(g.errors, fmt.Sprintf("%s: %v", what, err))
+}
+
+// write writes the gathered files, and the errors if any, as a gzipped tarball
+func (g *Gatherer) write(out io.Writer) error {
+	files := g.files
+	if len(g.errors) > 0 {
+		content := strings.Join(g.errors, "\n") + "\n"
+		files = append(files, file{name: errorsFileName, content: []byte(content)})
+	}
+
+	gzipWriter := gzip.NewWriter(out)
+	tarWriter := tar.NewWriter(gzipWriter)
+	now := time.Now()
+	for _, f := range files {
+		header := &tar.Header{Name: f.name, Mode: 0644, Size: int64(len(f.content)), ModTime: now}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := tarWriter.Write(f.content); err != nil {
+			return err
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		return err
+	}
+	return gzipWriter.Close()
+}
@@ -0,0 +1,87 @@
+package gather
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	mockInfraClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster/mock"
+	mockTenantClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/tenantcluster/mock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/testutils"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+func TestGather(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	infraClient := mockInfraClusterClient.NewMockClient(mockCtrl)
+	tenantClient := mockTenantClusterClient.NewMockClient(mockCtrl)
+
+	machine, err := testutils.StubMachine()
+	assert.NilError(t, err)
+	machine.Spec.ProviderID = pointer.StringPtr(fmt.Sprintf("kubevirt://%s/%s", testutils.InfraNamespace, testutils.MachineName))
+	vm := testutils.StubVirtualMachine(nil, nil, nil)
+	vmi := testutils.StubVirtualMachineInstance()
+	vmi.Namespace = testutils.InfraNamespace
+	vmi.UID = "vmi-uid"
+	dataVolumeName := vm.Spec.DataVolumeTemplates[0].Name
+
+	tenantClient.EXPECT().GetMachine(gomock.Any(), machine.Name, machine.Namespace).Return(machine, nil).Times(1)
+	infraClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vm, nil).Times(1)
+	infraClient.EXPECT().GetDataVolume(gomock.Any(), testutils.InfraNamespace, dataVolumeName).Return(nil, fmt.Errorf("test error")).Times(1)
+	infraClient.EXPECT().GetVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vmi, nil).Times(1)
+	infraClient.EXPECT().ListPods(gomock.Any(), testutils.InfraNamespace, metav1.ListOptions{LabelSelector: "kubevirt.io/created-by=vmi-uid"}).Return(&corev1.PodList{Items: []corev1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "virt-launcher-test"}},
+	}}, nil).Times(1)
+	infraClient.EXPECT().ListEvents(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(&corev1.EventList{Items: []corev1.Event{
+		{ObjectMeta: metav1.ObjectMeta{Name: "launcher-event"}, InvolvedObject: corev1.ObjectReference{Name: "virt-launcher-test"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "other-event"}, InvolvedObject: corev1.ObjectReference{Name: "other-pod"}},
+	}}, nil).Times(1)
+
+	out := &bytes.Buffer{}
+	assert.NilError(t, New(infraClient, tenantClient).Gather(context.Background(), machine.Name, machine.Namespace, out))
+
+	files := readTarball(t, out)
+	for _, name := range []string{"machine.yaml", "provider-status.yaml", "virtualmachine.yaml", "virtualmachineinstance.yaml", "pods/virt-launcher-test.yaml", "events.yaml"} {
+		_, ok := files[name]
+		assert.Assert(t, ok, "missing %s", name)
+	}
+	assert.Assert(t, bytes.Contains(files["events.yaml"], []byte("launcher-event")))
+	assert.Assert(t, !bytes.Contains(files["events.yaml"], []byte("other-event")))
+	assert.Equal(t, string(files[errorsFileName]), fmt.Sprintf("DataVolume %s: test error\n", dataVolumeName))
+}
+
+func TestGatherMachineNotFound(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	tenantClient := mockTenantClusterClient.NewMockClient(mockCtrl)
+	tenantClient.EXPECT().GetMachine(gomock.Any(), "missing", "test").Return(nil, fmt.Errorf("test error")).Times(1)
+
+	err := New(nil, tenantClient).Gather(context.Background(), "missing", "test", &bytes.Buffer{})
+	assert.Error(t, err, "failed to get Machine test/missing, with error: test error")
+}
+
+func readTarball(t *testing.T, in io.Reader) map[string][]byte {
+	gzipReader, err := gzip.NewReader(in)
+	assert.NilError(t, err)
+	tarReader := tar.NewReader(gzipReader)
+	files := map[string][]byte{}
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return files
+		}
+		assert.NilError(t, err)
+		content, err := io.ReadAll(tarReader)
+		assert.NilError(t, err)
+		files[header.Name] = content
+	}
+}
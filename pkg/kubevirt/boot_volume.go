@@ -0,0 +1,107 @@
+package kubevirt
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog"
+	kubevirtapiv1 "kubevirt.io/client-go/api/v1"
+	cdiv1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
+
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
+)
+
+// retainBootVolume releases the boot DataVolume from the VirtualMachine, so the garbage collector doesn't
+// delete it with the VirtualMachine, and labels it with the retain policy and the name of the Machine
+func (m *manager) retainBootVolume(vm *kubevirtapiv1.VirtualMachine, machineName string) error {
+	dataVolumeName := bootDataVolumeName(vm)
+	if dataVolumeName == "" {
+		return nil
+	}
+	resp, err := m.infraClusterClient.GetDataVolume(context.Background(), vm.Namespace, dataVolumeName)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			klog.Infof("%s: boot DataVolume %s does not exist - nothing to retain", machineName, dataVolumeName)
+			return nil
+		}
+		return err
+	}
+	var dataVolume cdiv1.DataVolume
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(resp.UnstructuredContent(), &dataVolume); err != nil {
+		return err
+	}
+
+	ownerReferences := make([]k8smetav1.OwnerReference, 0, len(dataVolume.OwnerReferences))
+	for _, ownerReference := range dataVolume.OwnerReferences {
+		if ownerReference.UID != vm.UID {
+			ownerReferences = append(ownerReferences, ownerReference)
+		}
+	}
+	if len(ownerReferences) == len(dataVolume.OwnerReferences) &&
+		dataVolume.Labels[machinescope.BootVolumeDeletePolicyLabel] == string(kubevirtproviderv1alpha1.BootVolumeDeletePolicyRetain) {
+		return nil
+	}
+	dataVolume.OwnerReferences = ownerReferences
+	if dataVolume.Labels == nil {
+		dataVolume.Labels = map[string]string{}
+	}
+	dataVolume.Labels[machinescope.BootVolumeDeletePolicyLabel] = string(kubevirtproviderv1alpha1.BootVolumeDeletePolicyRetain)
+	dataVolume.Labels[machinescope.RetainedFromMachineLabel] = machineName
+	if _, err := m.infraClusterClient.UpdateDataVolume(machineContext(machineName), vm.Namespace, &dataVolume); err != nil {
+		return err
+	}
+	klog.Infof("%s: boot DataVolume %s is retained after the deletion of the Machine", machineName, dataVolumeName)
+	return nil
+}
+
+// bootDataVolumeName returns the name of the DataVolume the VirtualMachine boots from, which backs its first disk
+func bootDataVolumeName(vm *kubevirtapiv1.VirtualMachine) string {
+	if vm.Spec.Template == nil {
+		return ""
+	}
+	for _, volume := range vm.Spec.Template.Spec.Volumes {
+		if volume.DataVolume != nil {
+			return volume.DataVolume.Name
+		}
+	}
+	return ""
+}
+
+// resolveDataSource makes the boot volume of the VirtualMachine clone the source PVC the CDI DataSource of
+// the Machine currently points to
+func (m *manager) resolveDataSource(machineScope machinescope.MachineScope, vm *kubevirtapiv1.VirtualMachine) error {
+	namespace, name := machineScope.GetDataSource()
+	if name == "" || len(vm.Spec.DataVolumeTemplates) == 0 {
+		return nil
+	}
+	sourcePvc, err := m.infraClusterClient.GetDataSourcePVC(context.Background(), namespace, name)
+	if err != nil {
+		return err
+	}
+	vm.Spec.DataVolumeTemplates[0].Spec.Source.PVC = sourcePvc
+	return nil
+}
+
+// verifySourceImage refuses to clone the boot volume of a Machine pinned to a source image, unless the image
+// digest annotation of the source PVC matches the pinned digest
+func (m *manager) verifySourceImage(machineScope machinescope.MachineScope, vm *kubevirtapiv1.VirtualMachine) error {
+	expectedDigest, err := machineScope.GetSourceImageDigest()
+	if err != nil || expectedDigest == "" {
+		return err
+	}
+	if len(vm.Spec.DataVolumeTemplates) == 0 || vm.Spec.DataVolumeTemplates[0].Spec.Source.PVC == nil {
+		return nil
+	}
+	source := vm.Spec.DataVolumeTemplates[0].Spec.Source.PVC
+	sourcePvc, err := m.infraClusterClient.GetPersistentVolumeClaim(context.Background(), source.Namespace, source.Name)
+	if err != nil {
+		return err
+	}
+	if digest := sourcePvc.Annotations[machinescope.SourceImageDigestAnnotation]; digest != expectedDigest {
+		return &ImageDigestMismatchError{PVC: source.Name, ExpectedDigest: expectedDigest, Digest: digest}
+	}
+	return nil
+}
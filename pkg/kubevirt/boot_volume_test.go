@@ -0,0 +1,149 @@
+package kubevirt
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	mockInfraClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster/mock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
+	mockMachineScope "github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope/mock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/testutils"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cdiv1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
+)
+
+func TestVerifySourceImage(t *testing.T) {
+	const pinnedDigest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	stubSourcePvc := func(digest string) *corev1.PersistentVolumeClaim {
+		sourcePvc := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "rhcos", Namespace: testutils.InfraNamespace}}
+		if digest != "" {
+			sourcePvc.Annotations = map[string]string{machinescope.SourceImageDigestAnnotation: digest}
+		}
+		return sourcePvc
+	}
+
+	cases := []struct {
+		name           string
+		digest         string
+		expect         func(infraClient *mockInfraClusterClient.MockClient)
+		expectedErr    string
+		expectMismatch bool
+	}{
+		{
+			name:   "Success source image not pinned",
+			expect: func(infraClient *mockInfraClusterClient.MockClient) {},
+		},
+		{
+			name:   "Success source image matches the pinned digest",
+			digest: pinnedDigest,
+			expect: func(infraClient *mockInfraClusterClient.MockClient) {
+				infraClient.EXPECT().GetPersistentVolumeClaim(gomock.Any(), testutils.InfraNamespace, "rhcos").Return(stubSourcePvc(pinnedDigest), nil).Times(1)
+			},
+		},
+		{
+			name:   "Failure source image mutated",
+			digest: pinnedDigest,
+			expect: func(infraClient *mockInfraClusterClient.MockClient) {
+				infraClient.EXPECT().GetPersistentVolumeClaim(gomock.Any(), testutils.InfraNamespace, "rhcos").Return(stubSourcePvc("sha256:other"), nil).Times(1)
+			},
+			expectedErr:    fmt.Sprintf("the source PVC rhcos has image digest \"sha256:other\", while the Machine is pinned to %s", pinnedDigest),
+			expectMismatch: true,
+		},
+		{
+			name:   "Failure source image without digest",
+			digest: pinnedDigest,
+			expect: func(infraClient *mockInfraClusterClient.MockClient) {
+				infraClient.EXPECT().GetPersistentVolumeClaim(gomock.Any(), testutils.InfraNamespace, "rhcos").Return(stubSourcePvc(""), nil).Times(1)
+			},
+			expectedErr:    fmt.Sprintf("the source PVC rhcos has image digest \"\", while the Machine is pinned to %s", pinnedDigest),
+			expectMismatch: true,
+		},
+		{
+			name:   "Failure get source pvc",
+			digest: pinnedDigest,
+			expect: func(infraClient *mockInfraClusterClient.MockClient) {
+				infraClient.EXPECT().GetPersistentVolumeClaim(gomock.Any(), testutils.InfraNamespace, "rhcos").Return(nil, fmt.Errorf("test error")).Times(1)
+			},
+			expectedErr: "test error",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			infraClient := mockInfraClusterClient.NewMockClient(mockCtrl)
+			machineScope := mockMachineScope.NewMockMachineScope(mockCtrl)
+			tc.expect(infraClient)
+			machineScope.EXPECT().GetSourceImageDigest().Return(tc.digest, nil).AnyTimes()
+
+			m := &manager{infraClusterClient: infraClient, requeueAfter: requeueAfter}
+			vm := testutils.StubVirtualMachine(nil, nil, nil)
+			vm.Spec.DataVolumeTemplates[0].Spec.Source.PVC.Name = "rhcos"
+			err := m.verifySourceImage(machineScope, vm)
+			if tc.expectedErr != "" {
+				assert.Error(t, err, tc.expectedErr)
+				assert.Equal(t, IsImageDigestMismatch(err), tc.expectMismatch)
+			} else {
+				assert.NilError(t, err)
+			}
+		})
+	}
+}
+
+func TestResolveDataSource(t *testing.T) {
+	cases := []struct {
+		name           string
+		dataSourceName string
+		expect         func(infraClient *mockInfraClusterClient.MockClient)
+		expectedSource *cdiv1.DataVolumeSourcePVC
+		expectedErr    string
+	}{
+		{
+			name:           "Success clone the source pvc",
+			expect:         func(infraClient *mockInfraClusterClient.MockClient) {},
+			expectedSource: testutils.StubVirtualMachine(nil, nil, nil).Spec.DataVolumeTemplates[0].Spec.Source.PVC,
+		},
+		{
+			name:           "Success clone the pvc of the data source",
+			dataSourceName: "rhcos",
+			expect: func(infraClient *mockInfraClusterClient.MockClient) {
+				infraClient.EXPECT().GetDataSourcePVC(gomock.Any(), "os-images", "rhcos").Return(
+					&cdiv1.DataVolumeSourcePVC{Name: "rhcos-a1b2c3", Namespace: "os-images"}, nil).Times(1)
+			},
+			expectedSource: &cdiv1.DataVolumeSourcePVC{Name: "rhcos-a1b2c3", Namespace: "os-images"},
+		},
+		{
+			name:           "Failure get the data source",
+			dataSourceName: "rhcos",
+			expect: func(infraClient *mockInfraClusterClient.MockClient) {
+				infraClient.EXPECT().GetDataSourcePVC(gomock.Any(), "os-images", "rhcos").Return(nil, fmt.Errorf("test error")).Times(1)
+			},
+			expectedErr: "test error",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			infraClient := mockInfraClusterClient.NewMockClient(mockCtrl)
+			machineScope := mockMachineScope.NewMockMachineScope(mockCtrl)
+			tc.expect(infraClient)
+			machineScope.EXPECT().GetDataSource().Return("os-images", tc.dataSourceName).AnyTimes()
+
+			m := &manager{infraClusterClient: infraClient, requeueAfter: requeueAfter}
+			vm := testutils.StubVirtualMachine(nil, nil, nil)
+			err := m.resolveDataSource(machineScope, vm)
+			if tc.expectedErr != "" {
+				assert.Error(t, err, tc.expectedErr)
+			} else {
+				assert.NilError(t, err)
+				assert.DeepEqual(t, vm.Spec.DataVolumeTemplates[0].Spec.Source.PVC, tc.expectedSource)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package kubevirt
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
+)
+
+// trustBundlePath is where the machine config server writes the additional trust bundle of the cluster
+const trustBundlePath = "/etc/pki/ca-trust/source/anchors/openshift-config-user-ca-bundle.crt"
+
+// addClusterProxyToUserData sets the cluster-wide proxy as the proxy the guest fetches the remote ignition
+// through, and trusts the additional trust bundle both for the fetch and in the guest
+func addClusterProxyToUserData(src []byte, proxy *machinescope.ClusterProxy) ([]byte, error) {
+	var dataMap map[string]interface{}
+	if err := json.Unmarshal(src, &dataMap); err != nil {
+		return nil, fmt.Errorf("failed to parse userData, with error: %v", err)
+	}
+	ignition, _ := dataMap["ignition"].(map[string]interface{})
+	version, _ := ignition["version"].(string)
+	if version == "" {
+		return nil, fmt.Errorf("failed to get the ignition version of userData")
+	}
+
+	if proxy.HTTPProxy != "" || proxy.HTTPSProxy != "" {
+		// The proxy of the ignition fetch was introduced by spec version 3.1
+		if strings.HasPrefix(version, "2.") || strings.HasPrefix(version, "3.0.") {
+			return nil, fmt.Errorf("ignition version %s of userData doesn't support a proxy", version)
+		}
+		ignitionProxy := map[string]interface{}{}
+		if proxy.HTTPProxy != "" {
+			ignitionProxy["httpProxy"] = proxy.HTTPProxy
+		}
+		if proxy.HTTPSProxy != "" {
+			ignitionProxy["httpsProxy"] = proxy.HTTPSProxy
+		}
+		var noProxy []interface{}
+		for _, host := range strings.Split(proxy.NoProxy, ",") {
+			if host = strings.TrimSpace(host); host != "" {
+				noProxy = append(noProxy, host)
+			}
+		}
+		if len(noProxy) > 0 {
+			ignitionProxy["noProxy"] = noProxy
+		}
+		ignition["proxy"] = ignitionProxy
+	}
+
+	if proxy.TrustBundle == "" {
+		return json.Marshal(dataMap)
+	}
+	trustBundleSource := fmt.Sprintf("data:text/plain;charset=utf-8;base64,%s", base64.StdEncoding.EncodeToString([]byte(proxy.TrustBundle)))
+	if _, ok := ignition["security"].(map[string]interface{}); !ok {
+		ignition["security"] = map[string]interface{}{}
+	}
+	security := ignition["security"].(map[string]interface{})
+	if _, ok := security["tls"].(map[string]interface{}); !ok {
+		security["tls"] = map[string]interface{}{}
+	}
+	tls := security["tls"].(map[string]interface{})
+	var certificateAuthorities []interface{}
+	if existing, ok := tls["certificateAuthorities"].([]interface{}); ok {
+		certificateAuthorities = existing
+	}
+	tls["certificateAuthorities"] = append(certificateAuthorities, map[string]interface{}{"source": trustBundleSource})
+
+	result, err := json.Marshal(dataMap)
+	if err != nil {
+		return nil, err
+	}
+	return addFileToUserData(result, trustBundlePath, trustBundleSource)
+}
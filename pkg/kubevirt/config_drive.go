@@ -0,0 +1,280 @@
+package kubevirt
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// configDriveLabel is the volume label ignition looks the config drive up by
+	configDriveLabel = "config-2"
+	// isoSectorSize is the size of the logical blocks of an ISO 9660 image
+	isoSectorSize = 2048
+	// isoMaxIdentifierLength is the maximal length of a file identifier of an ISO 9660 level 2 image
+	isoMaxIdentifierLength = 30
+	// isoFirstDirectorySector is the sector of the root directory, after the system area, the volume descriptors
+	// and the little and big endian path tables
+	isoFirstDirectorySector = 20
+)
+
+// configDriveFile is a file of the config drive, at a slash separated path relative to its root
+type configDriveFile struct {
+	path string
+	data []byte
+}
+
+// isoDirectory is a directory of the ISO 9660 image, numbered by its position in the path table
+type isoDirectory struct {
+	name       string
+	identifier string
+	parent     *isoDirectory
+	number     int
+	sector     int
+	children   []*isoDirectory
+	files      []*isoFile
+}
+
+type isoFile struct {
+	name       string
+	identifier string
+	data       []byte
+	sector     int
+}
+
+// buildConfigDrive builds the ISO 9660 image of a config drive holding the files, as KubeVirt builds the image of a
+// CloudInitConfigDrive volume. The identifiers are recorded in uppercase, with their names in Rock Ridge entries,
+// and the isofs driver of Linux lists them in lowercase without Rock Ridge, so the paths of the files may only
+// consist of lowercase letters, digits, underscores and dots.
+func buildConfigDrive(files []configDriveFile) ([]byte, error) {
+	root := &isoDirectory{identifier: "\x00"}
+	for _, file := range files {
+		parts := strings.Split(file.path, "/")
+		directory := root
+		for _, part := range parts[:len(parts)-1] {
+			identifier, err := isoIdentifier(part, false)
+			if err != nil {
+				return nil, err
+			}
+			directory = directory.child(part, identifier)
+		}
+		identifier, err := isoIdentifier(parts[len(parts)-1], true)
+		if err != nil {
+			return nil, err
+		}
+		directory.files = append(directory.files, &isoFile{name: parts[len(parts)-1], identifier: identifier, data: file.data})
+	}
+
+	// The directories are numbered breadth first, by their parent and their identifier, as the path table orders them
+	directories := []*isoDirectory{root}
+	for i := 0; i < len(directories); i++ {
+		directory := directories[i]
+		directory.number = i + 1
+		directory.sector = isoFirstDirectorySector + i
+		sort.Slice(directory.children, func(a, b int) bool { return directory.children[a].identifier < directory.children[b].identifier })
+		sort.Slice(directory.files, func(a, b int) bool { return directory.files[a].identifier < directory.files[b].identifier })
+		directories = append(directories, directory.children...)
+	}
+	sector := isoFirstDirectorySector + len(directories)
+	for _, directory := range directories {
+		for _, file := range directory.files {
+			file.sector = sector
+			sector += (len(file.data) + isoSectorSize - 1) / isoSectorSize
+		}
+	}
+
+	image := make([]byte, sector*isoSectorSize)
+	littleEndianPathTable, bigEndianPathTable := isoPathTables(directories)
+	if len(littleEndianPathTable) > isoSectorSize {
+		return nil, fmt.Errorf("config drive has too many directories")
+	}
+	copy(image[18*isoSectorSize:], littleEndianPathTable)
+	copy(image[19*isoSectorSize:], bigEndianPathTable)
+	for _, directory := range directories {
+		records := directory.records()
+		if len(records) > isoSectorSize {
+			return nil, fmt.Errorf("config drive directory %s has too many entries", directory.identifier)
+		}
+		copy(image[directory.sector*isoSectorSize:], records)
+		for _, file := range directory.files {
+			copy(image[file.sector*isoSectorSize:], file.data)
+		}
+	}
+	writePrimaryVolumeDescriptor(image[16*isoSectorSize:17*isoSectorSize], sector, len(littleEndianPathTable), root)
+	terminator := image[17*isoSectorSize:]
+	terminator[0] = 255
+	copy(terminator[1:6], "CD001")
+	terminator[6] = 1
+	return image, nil
+}
+
+// isoIdentifier returns the ISO 9660 identifier of a directory or a file, files are identified with an extension
+// separator and the version 1
+func isoIdentifier(name string, file bool) (string, error) {
+	identifier := strings.ToUpper(name)
+	if identifier == "" || strings.Trim(identifier, "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_.") != "" ||
+		strings.Count(identifier, ".") > 1 || (!file && strings.Contains(identifier, ".")) || len(identifier) > isoMaxIdentifierLength {
+		return "", fmt.Errorf("config drive path element %q is not a valid ISO 9660 identifier", name)
+	}
+	if !file {
+		return identifier, nil
+	}
+	if !strings.Contains(identifier, ".") {
+		identifier += "."
+	}
+	return identifier + ";1", nil
+}
+
+// child returns the child directory with the identifier, which is added if it doesn't exist yet
+func (d *isoDirectory) child(name string, identifier string) *isoDirectory {
+	for _, child := range d.children {
+		if child.identifier == identifier {
+			return child
+		}
+	}
+	child := &isoDirectory{name: name, identifier: identifier, parent: d}
+	d.children = append(d.children, child)
+	return child
+}
+
+// records returns the directory records of the directory, itself and its parent first, then its entries
+// ordered by identifier. The record of the root directory itself announces the Rock Ridge entries.
+func (d *isoDirectory) records() []byte {
+	parent := d.parent
+	var systemUse []byte
+	if parent == nil {
+		parent = d
+		systemUse = rockRidgeAnnouncement()
+	}
+	records := isoDirectoryRecord("\x00", d.sector, isoSectorSize, true, systemUse)
+	records = append(records, isoDirectoryRecord("\x01", parent.sector, isoSectorSize, true, nil)...)
+	type entry struct {
+		identifier string
+		record     []byte
+	}
+	var entries []entry
+	for _, child := range d.children {
+		entries = append(entries, entry{child.identifier,
+			isoDirectoryRecord(child.identifier, child.sector, isoSectorSize, true, rockRidgeName(child.name))})
+	}
+	for _, file := range d.files {
+		entries = append(entries, entry{file.identifier,
+			isoDirectoryRecord(file.identifier, file.sector, len(file.data), false, rockRidgeName(file.name))})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].identifier < entries[j].identifier })
+	for _, entry := range entries {
+		records = append(records, entry.record...)
+	}
+	return records
+}
+
+// isoDirectoryRecord returns the directory record of a directory or a file, recorded on 1970-01-01 UTC, with the
+// system use entries
+func isoDirectoryRecord(identifier string, sector int, size int, directory bool, systemUse []byte) []byte {
+	systemUseOffset := 33 + len(identifier)
+	if systemUseOffset%2 == 1 {
+		systemUseOffset++
+	}
+	length := systemUseOffset + len(systemUse)
+	if length%2 == 1 {
+		length++
+	}
+	record := make([]byte, length)
+	record[0] = byte(length)
+	putBothEndian32(record[2:], uint32(sector))
+	putBothEndian32(record[10:], uint32(size))
+	copy(record[18:25], []byte{70, 1, 1, 0, 0, 0, 0})
+	if directory {
+		record[25] = 2
+	}
+	putBothEndian16(record[28:], 1)
+	record[32] = byte(len(identifier))
+	copy(record[33:], identifier)
+	copy(record[systemUseOffset:], systemUse)
+	return record
+}
+
+// rockRidgeAnnouncement returns the SUSP indicator and the Rock Ridge extension reference, of the record of the
+// root directory itself
+func rockRidgeAnnouncement() []byte {
+	const extensionID = "RRIP_1991A"
+	announcement := []byte{'S', 'P', 7, 1, 0xbe, 0xef, 0}
+	announcement = append(announcement, 'E', 'R', byte(8+len(extensionID)), 1, byte(len(extensionID)), 0, 0, 1)
+	return append(announcement, extensionID...)
+}
+
+// rockRidgeName returns the Rock Ridge alternate name entry of the name
+func rockRidgeName(name string) []byte {
+	return append([]byte{'N', 'M', byte(5 + len(name)), 1, 0}, name...)
+}
+
+// isoPathTables returns the little and the big endian path tables of the directories
+func isoPathTables(directories []*isoDirectory) ([]byte, []byte) {
+	var littleEndian, bigEndian []byte
+	for _, directory := range directories {
+		parent := directory.parent
+		if parent == nil {
+			parent = directory
+		}
+		littleEndian = append(littleEndian, isoPathTableRecord(directory.identifier, directory.sector, parent.number, binary.LittleEndian)...)
+		bigEndian = append(bigEndian, isoPathTableRecord(directory.identifier, directory.sector, parent.number, binary.BigEndian)...)
+	}
+	return littleEndian, bigEndian
+}
+
+func isoPathTableRecord(identifier string, sector int, parentNumber int, order binary.ByteOrder) []byte {
+	length := 8 + len(identifier)
+	if length%2 == 1 {
+		length++
+	}
+	record := make([]byte, length)
+	record[0] = byte(len(identifier))
+	order.PutUint32(record[2:], uint32(sector))
+	order.PutUint16(record[6:], uint16(parentNumber))
+	copy(record[8:], identifier)
+	return record
+}
+
+// writePrimaryVolumeDescriptor writes the primary volume descriptor of an image of the sectors, labelled config-2
+func writePrimaryVolumeDescriptor(descriptor []byte, sectors int, pathTableSize int, root *isoDirectory) {
+	descriptor[0] = 1
+	copy(descriptor[1:6], "CD001")
+	descriptor[6] = 1
+	fillISOString(descriptor[8:40], "")
+	fillISOString(descriptor[40:72], configDriveLabel)
+	putBothEndian32(descriptor[80:], uint32(sectors))
+	putBothEndian16(descriptor[120:], 1)
+	putBothEndian16(descriptor[124:], 1)
+	putBothEndian16(descriptor[128:], isoSectorSize)
+	putBothEndian32(descriptor[132:], uint32(pathTableSize))
+	binary.LittleEndian.PutUint32(descriptor[140:], 18)
+	binary.BigEndian.PutUint32(descriptor[148:], 19)
+	copy(descriptor[156:190], isoDirectoryRecord("\x00", root.sector, isoSectorSize, true, nil))
+	// The volume set, publisher, preparer and application identifiers, and the copyright, abstract and
+	// bibliographic files are left blank
+	fillISOString(descriptor[190:813], "")
+	// The creation, modification, expiration and effective dates are unspecified
+	for offset := 813; offset < 881; offset += 17 {
+		fillISOString(descriptor[offset:offset+16], "0000000000000000")
+	}
+	descriptor[881] = 1
+}
+
+// fillISOString writes the value to the field, padded with spaces
+func fillISOString(field []byte, value string) {
+	for i := range field {
+		field[i] = ' '
+	}
+	copy(field, value)
+}
+
+func putBothEndian16(field []byte, value uint16) {
+	binary.LittleEndian.PutUint16(field, value)
+	binary.BigEndian.PutUint16(field[2:], value)
+}
+
+func putBothEndian32(field []byte, value uint32) {
+	binary.LittleEndian.PutUint32(field, value)
+	binary.BigEndian.PutUint32(field[4:], value)
+}
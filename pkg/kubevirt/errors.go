@@ -0,0 +1,202 @@
+package kubevirt
+
+import (
+	goerrors "errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog"
+)
+
+// Stage is the step of a KubevirtVM operation which failed
+type Stage string
+
+const (
+	StageBuildIgnitionSecret       Stage = "failed to build ignition secret struct"
+	StageBuildVirtualMachine       Stage = "failed to build Virtual Machine struct"
+	StageCreateIgnitionSecret      Stage = "failed to create ignition secret in infraCluster"
+	StageCreateVirtualMachine      Stage = "failed to create Virtual Machine in infraCluster"
+	StageGetVirtualMachine         Stage = "failed to get Virtual Machine from infraCluster"
+	StageUpdateVirtualMachine      Stage = "failed to update Virtual Machine in infraCluster"
+	StageStopVirtualMachine        Stage = "failed to stop Virtual Machine in infraCluster"
+	StageDeleteVirtualMachine      Stage = "failed to delete Virtual Machine in infraCluster"
+	StageGetVirtualMachineInstance Stage = "failed to get vmi of the Machine"
+	StageSyncMachine               Stage = "failed to sync the Machine"
+	StageEnsureNetworkPolicy       Stage = "failed to ensure the NetworkPolicy of the tenant-cluster in infraCluster"
+	StageVerifyVirtualMachine      Stage = "refused to operate on the Virtual Machine in infraCluster"
+	StageAdoptVirtualMachine       Stage = "failed to adopt a Virtual Machine of the pool in infraCluster"
+	StageRetainBootVolume          Stage = "failed to retain the boot volume in infraCluster"
+	StageVerifySourceImage         Stage = "refused to clone the source image in infraCluster"
+	StageResolveDataSource         Stage = "failed to resolve the DataSource of the boot volume in infraCluster"
+	StageCheckIgnitionSource       Stage = "failed to reach the ignition source from the network of the Machine"
+	StageUploadIgnition            Stage = "failed to upload the ignition to its DataVolume in infraCluster"
+	StageDeleteIgnitionDataVolume  Stage = "failed to delete the ignition DataVolume in infraCluster"
+	StageListEvents                Stage = "failed to list the events of the infra namespace"
+)
+
+// transientErrorRequeueAfter is the delay before retrying an operation the infra cluster failed transiently,
+// when it doesn't suggest one
+const transientErrorRequeueAfter = 20 * time.Second
+
+// OperationError is returned when a KubevirtVM operation fails. It keeps the failed stage and the
+// original error, so callers can still classify the cause with errors.As or errors.IsNotFound.
+// The errors of the infra cluster API are also translated to the errors of the machine controller, see As.
+type OperationError struct {
+	MachineName string
+	Operation   string
+	Stage       Stage
+	Err         error
+}
+
+func (e *OperationError) Error() string {
+	return fmt.Sprintf("%s: Error during %s: %s, with error: %v", e.MachineName, e.Operation, e.Stage, e.Err)
+}
+
+// Unwrap returns the original error
+func (e *OperationError) Unwrap() error {
+	return e.Err
+}
+
+// As translates the error of the infra cluster API to the errors of the machine controller:
+//   - a transient error (Timeout, ServerTimeout, TooManyRequests or an open circuit) is a RequeueAfterError,
+//     the operation is retried once the infra cluster recovers
+//   - an Invalid error of the creation is an InvalidMachineConfiguration error, the Machine fails
+//   - an Invalid or Forbidden error is otherwise the MachineError of the operation, e.g. a CreateMachine error
+func (e *OperationError) As(target interface{}) bool {
+	switch target := target.(type) {
+	case **machinecontroller.RequeueAfterError:
+		if requeueErr := e.requeueAfterError(); requeueErr != nil {
+			*target = requeueErr
+			return true
+		}
+	case **machinecontroller.MachineError:
+		if machineErr := e.machineError(); machineErr != nil {
+			*target = machineErr
+			return true
+		}
+	}
+	return false
+}
+
+// isTransient returns true when the infra cluster API failed transiently
+func (e *OperationError) isTransient() bool {
+	return errors.IsTimeout(e.Err) || errors.IsServerTimeout(e.Err) || errors.IsTooManyRequests(e.Err) ||
+		infracluster.IsCircuitOpen(e.Err)
+}
+
+func (e *OperationError) requeueAfterError() *machinecontroller.RequeueAfterError {
+	if !e.isTransient() {
+		return nil
+	}
+	requeueAfter := transientErrorRequeueAfter
+	if seconds, ok := errors.SuggestsClientDelay(e.Err); ok && seconds > 0 {
+		requeueAfter = time.Duration(seconds) * time.Second
+	}
+	return &machinecontroller.RequeueAfterError{RequeueAfter: requeueAfter}
+}
+
+func (e *OperationError) machineError() *machinecontroller.MachineError {
+	// A MachineError of the original error is found by unwrapping it
+	var machineErr *machinecontroller.MachineError
+	if goerrors.As(e.Err, &machineErr) {
+		return nil
+	}
+	invalid := errors.IsInvalid(e.Err)
+	if !invalid && !errors.IsForbidden(e.Err) {
+		return nil
+	}
+	switch e.Operation {
+	case "Create":
+		if invalid {
+			return machinecontroller.InvalidMachineConfiguration("%s", e.Error())
+		}
+		return machinecontroller.CreateMachine("%s", e.Error())
+	case "Update":
+		return machinecontroller.UpdateMachine("%s", e.Error())
+	case "Delete":
+		return machinecontroller.DeleteMachine("%s", e.Error())
+	default:
+		return nil
+	}
+}
+
+// newOperationError logs and returns an OperationError
+func newOperationError(machineName string, operation string, stage Stage, err error) error {
+	operationErr := &OperationError{
+		MachineName: machineName,
+		Operation:   operation,
+		Stage:       stage,
+		Err:         err,
+	}
+	klog.Errorf(operationErr.Error())
+	return operationErr
+}
+
+// VirtualMachineMismatchError is returned when the Virtual Machine the providerID of the Machine points to
+// isn't the one the Machine was synced with, e.g. an unrelated Virtual Machine recreated with the same name
+type VirtualMachineMismatchError struct {
+	ProviderID  string
+	ExpectedUID string
+	UID         string
+}
+
+func (e *VirtualMachineMismatchError) Error() string {
+	return fmt.Sprintf("the Virtual Machine of providerID %s has UID %s, while the Machine was synced with UID %s",
+		e.ProviderID, e.UID, e.ExpectedUID)
+}
+
+// IsVirtualMachineMismatch returns true when the operation was refused since the Virtual Machine
+// isn't the one of the Machine
+func IsVirtualMachineMismatch(err error) bool {
+	var mismatchErr *VirtualMachineMismatchError
+	return goerrors.As(err, &mismatchErr)
+}
+
+// ImageDigestMismatchError is returned when the image of the source PVC of the boot volume isn't the one
+// the Machine is pinned to, e.g. a golden image mutated in place
+type ImageDigestMismatchError struct {
+	PVC            string
+	ExpectedDigest string
+	Digest         string
+}
+
+func (e *ImageDigestMismatchError) Error() string {
+	return fmt.Sprintf("the source PVC %s has image digest %q, while the Machine is pinned to %s",
+		e.PVC, e.Digest, e.ExpectedDigest)
+}
+
+// IsImageDigestMismatch returns true when the operation was refused since the source image of the boot volume
+// isn't the one the Machine is pinned to
+func IsImageDigestMismatch(err error) bool {
+	var mismatchErr *ImageDigestMismatchError
+	return goerrors.As(err, &mismatchErr)
+}
+
+// IgnitionSourceUnreachableError is returned when the remote ignition sources of the userData aren't reachable
+// from the network of the Machine, so the guest would hang at the fetch of its ignition
+type IgnitionSourceUnreachableError struct {
+	Sources []string
+	Message string
+}
+
+func (e *IgnitionSourceUnreachableError) Error() string {
+	return fmt.Sprintf("the ignition sources %s are unreachable from the network of the Machine: %s",
+		strings.Join(e.Sources, ", "), e.Message)
+}
+
+// IsIgnitionSourceUnreachable returns true when the creation failed since the ignition sources of the userData
+// are unreachable from the network of the Machine
+func IsIgnitionSourceUnreachable(err error) bool {
+	var unreachableErr *IgnitionSourceUnreachableError
+	return goerrors.As(err, &unreachableErr)
+}
+
+// IsQuotaExceeded returns true when the infra cluster rejected the request since it exceeds a
+// ResourceQuota of the infra namespace
+func IsQuotaExceeded(err error) bool {
+	return errors.IsForbidden(err) && strings.Contains(err.Error(), "exceeded quota")
+}
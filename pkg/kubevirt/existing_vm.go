@@ -0,0 +1,114 @@
+package kubevirt
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog"
+	kubevirtapiv1 "kubevirt.io/client-go/api/v1"
+
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
+)
+
+// adoptExistingVirtualMachine adopts the halted VirtualMachine pre-staged for the Machine: the ignition of the
+// Machine is injected in its config drive, and it is started. A VirtualMachine the Machine already adopted is
+// only synced.
+func (m *manager) adoptExistingVirtualMachine(machineScope machinescope.MachineScope, virtualMachineFromMachine *kubevirtapiv1.VirtualMachine,
+	secretFromMachine *corev1.Secret, ignitionDataVolumeName string, machineName string) (bool, error) {
+	if secretFromMachine != nil {
+		if _, err := m.infraClusterClient.CreateSecret(machineContext(machineName), secretFromMachine.Namespace, secretFromMachine); err != nil && !errors.IsAlreadyExists(err) {
+			return false, newOperationError(machineName, "Create", StageCreateIgnitionSecret, err)
+		}
+	}
+
+	existingVM, err := m.getInraClusterVM(virtualMachineFromMachine.Name, virtualMachineFromMachine.Namespace)
+	if err != nil {
+		return false, newOperationError(machineName, "Create", StageGetVirtualMachine, err)
+	}
+	switch adoptedBy := existingVM.Labels[machinescope.AdoptedByLabel]; adoptedBy {
+	case machineName:
+		klog.Infof("%s: pre-staged VirtualMachine %s was already adopted by the Machine", machineName, existingVM.Name)
+	case "":
+		if !isHalted(existingVM) {
+			return false, newOperationError(machineName, "Create", StageVerifyVirtualMachine,
+				fmt.Errorf("pre-staged VirtualMachine %s isn't halted", existingVM.Name))
+		}
+		useConfigDriveOf(existingVM, virtualMachineFromMachine)
+		if ignitionDataVolumeName != "" {
+			useIgnitionDataVolume(existingVM, ignitionDataVolumeName)
+		}
+		if existingVM.Labels == nil {
+			existingVM.Labels = map[string]string{}
+		}
+		existingVM.Labels[machinescope.AdoptedByLabel] = machineName
+		runAlways := kubevirtapiv1.RunStrategyAlways
+		existingVM.Spec.RunStrategy = &runAlways
+		existingVM.Spec.Running = nil
+		if existingVM, err = m.infraClusterClient.UpdateVirtualMachine(machineContext(machineName), existingVM.Namespace, existingVM); err != nil {
+			return false, newOperationError(machineName, "Create", StageUpdateVirtualMachine, err)
+		}
+		klog.Infof("%s: pre-staged VirtualMachine %s was adopted and started for the Machine", machineName, existingVM.Name)
+	default:
+		return false, newOperationError(machineName, "Create", StageVerifyVirtualMachine,
+			fmt.Errorf("pre-staged VirtualMachine %s is already adopted by Machine %s", existingVM.Name, adoptedBy))
+	}
+	if secretFromMachine != nil {
+		if err := m.adoptIgnitionSecret(existingVM, secretFromMachine.Name, machineName); err != nil {
+			klog.Errorf("%s: failed to set the VirtualMachine as the owner of its ignition secret %s, with error: %v", machineName, secretFromMachine.Name, err)
+		}
+	}
+	if ignitionDataVolumeName != "" {
+		if err := m.adoptIgnitionDataVolume(existingVM, ignitionDataVolumeName, machineName); err != nil {
+			klog.Errorf("%s: failed to set the VirtualMachine as the owner of its ignition DataVolume %s, with error: %v", machineName, ignitionDataVolumeName, err)
+		}
+	}
+
+	return m.syncMachine(*existingVM, machineScope, machineName, "Create")
+}
+
+// useConfigDriveOf replaces the cloud-init volumes of the VirtualMachine, and their disks, by the config drive
+// carrying the ignition of the other VirtualMachine
+func useConfigDriveOf(vm *kubevirtapiv1.VirtualMachine, from *kubevirtapiv1.VirtualMachine) {
+	if vm.Spec.Template == nil || from.Spec.Template == nil {
+		return
+	}
+	spec := &vm.Spec.Template.Spec
+	removed := map[string]bool{}
+	volumes := spec.Volumes[:0]
+	for _, volume := range spec.Volumes {
+		if volume.CloudInitConfigDrive != nil || volume.CloudInitNoCloud != nil {
+			removed[volume.Name] = true
+			continue
+		}
+		volumes = append(volumes, volume)
+	}
+	disks := spec.Domain.Devices.Disks[:0]
+	for _, disk := range spec.Domain.Devices.Disks {
+		if !removed[disk.Name] {
+			disks = append(disks, disk)
+		}
+	}
+
+	for _, volume := range from.Spec.Template.Spec.Volumes {
+		if volume.CloudInitConfigDrive == nil {
+			continue
+		}
+		volumes = append(volumes, volume)
+		for _, disk := range from.Spec.Template.Spec.Domain.Devices.Disks {
+			if disk.Name == volume.Name {
+				disks = append(disks, disk)
+			}
+		}
+	}
+	spec.Volumes = volumes
+	spec.Domain.Devices.Disks = disks
+}
+
+// isHalted returns whether the VirtualMachine isn't meant to run
+func isHalted(vm *kubevirtapiv1.VirtualMachine) bool {
+	if vm.Spec.RunStrategy != nil {
+		return *vm.Spec.RunStrategy == kubevirtapiv1.RunStrategyHalted
+	}
+	return vm.Spec.Running == nil || !*vm.Spec.Running
+}
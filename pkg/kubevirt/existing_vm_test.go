@@ -0,0 +1,126 @@
+package kubevirt
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	mockInfraClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster/mock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
+	mockMachineScope "github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope/mock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/testutils"
+	"gotest.tools/assert"
+	"k8s.io/utils/pointer"
+	kubevirtapiv1 "kubevirt.io/client-go/api/v1"
+	cdiv1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
+)
+
+// stubStagedVirtualMachine returns a halted VirtualMachine shell, with a cloud-init volume of its own
+func stubStagedVirtualMachine(adoptedBy string) *kubevirtapiv1.VirtualMachine {
+	vm := testutils.StubVirtualMachine(nil, nil, nil)
+	vm.Spec.RunStrategy = nil
+	vm.Spec.Running = pointer.BoolPtr(false)
+	vm.Spec.Template.Spec.Volumes[1].CloudInitConfigDrive.UserDataSecretRef = nil
+	vm.Spec.Template.Spec.Volumes[1].CloudInitConfigDrive.UserData = "staged"
+	if adoptedBy != "" {
+		vm.Labels = map[string]string{machinescope.AdoptedByLabel: adoptedBy}
+	}
+	return vm
+}
+
+func TestUseConfigDriveOf(t *testing.T) {
+	vm := stubStagedVirtualMachine("")
+	from := testutils.StubVirtualMachine(nil, nil, nil)
+
+	useConfigDriveOf(vm, from)
+	assert.Equal(t, len(vm.Spec.Template.Spec.Volumes), 2)
+	assert.Equal(t, len(vm.Spec.Template.Spec.Domain.Devices.Disks), 2)
+	assert.Equal(t, ignitionSecretRef(&vm.Spec.Template.Spec), ignitionSecretRef(&from.Spec.Template.Spec))
+	assert.Equal(t, vm.Spec.Template.Spec.Volumes[1].CloudInitConfigDrive.UserData, "")
+}
+
+func TestAdoptExistingVirtualMachine(t *testing.T) {
+	cases := []struct {
+		name                   string
+		existingVM             *kubevirtapiv1.VirtualMachine
+		ignitionDataVolumeName string
+		expect                 func(infraClient *mockInfraClusterClient.MockClient, machineScope *mockMachineScope.MockMachineScope)
+		expectedErr            string
+	}{
+		{
+			name:       "Success adopt and start the staged virtual machine",
+			existingVM: stubStagedVirtualMachine(""),
+			expect: func(infraClient *mockInfraClusterClient.MockClient, machineScope *mockMachineScope.MockMachineScope) {
+				infraClient.EXPECT().UpdateVirtualMachine(gomock.Any(), testutils.InfraNamespace, gomock.Any()).DoAndReturn(
+					func(ctx context.Context, namespace string, vm *kubevirtapiv1.VirtualMachine) (*kubevirtapiv1.VirtualMachine, error) {
+						assert.Equal(t, vm.Labels[machinescope.AdoptedByLabel], testutils.MachineName)
+						assert.Equal(t, *vm.Spec.RunStrategy, kubevirtapiv1.RunStrategyAlways)
+						assert.Assert(t, vm.Spec.Running == nil)
+						assert.Equal(t, ignitionSecretRef(&vm.Spec.Template.Spec), "test-machine-name-ignition")
+						return vm, nil
+					}).Times(1)
+				machineScope.EXPECT().SyncMachine(gomock.Any(), nil, fmt.Sprintf(providerIDFmt, testutils.InfraNamespace, testutils.MachineName)).Return(nil).Times(1)
+			},
+		},
+		{
+			name:                   "Success adopt the staged virtual machine with the uploaded ignition",
+			existingVM:             stubStagedVirtualMachine(""),
+			ignitionDataVolumeName: "test-machine-name-ignition",
+			expect: func(infraClient *mockInfraClusterClient.MockClient, machineScope *mockMachineScope.MockMachineScope) {
+				infraClient.EXPECT().UpdateVirtualMachine(gomock.Any(), testutils.InfraNamespace, gomock.Any()).DoAndReturn(
+					func(ctx context.Context, namespace string, vm *kubevirtapiv1.VirtualMachine) (*kubevirtapiv1.VirtualMachine, error) {
+						assert.DeepEqual(t, vm.Spec.Template.Spec.Volumes[1].VolumeSource, kubevirtapiv1.VolumeSource{
+							DataVolume: &kubevirtapiv1.DataVolumeSource{Name: "test-machine-name-ignition"},
+						})
+						return vm, nil
+					}).Times(1)
+				infraClient.EXPECT().GetDataVolume(gomock.Any(), testutils.InfraNamespace, "test-machine-name-ignition").Return(
+					stubUnstructuredDataVolume(stubIgnitionDataVolume(), cdiv1.Succeeded), nil).Times(1)
+				infraClient.EXPECT().UpdateDataVolume(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(nil, nil).Times(1)
+				machineScope.EXPECT().SyncMachine(gomock.Any(), nil, fmt.Sprintf(providerIDFmt, testutils.InfraNamespace, testutils.MachineName)).Return(nil).Times(1)
+			},
+		},
+		{
+			name:       "Success already adopted by the machine",
+			existingVM: stubStagedVirtualMachine(testutils.MachineName),
+			expect: func(infraClient *mockInfraClusterClient.MockClient, machineScope *mockMachineScope.MockMachineScope) {
+				machineScope.EXPECT().SyncMachine(gomock.Any(), nil, fmt.Sprintf(providerIDFmt, testutils.InfraNamespace, testutils.MachineName)).Return(nil).Times(1)
+			},
+		},
+		{
+			name:       "Failure adopted by another machine",
+			existingVM: stubStagedVirtualMachine("other-machine"),
+			expect: func(infraClient *mockInfraClusterClient.MockClient, machineScope *mockMachineScope.MockMachineScope) {
+			},
+			expectedErr: "test-machine-name: Error during Create: refused to operate on the Virtual Machine in infraCluster, with error: pre-staged VirtualMachine test-machine-name is already adopted by Machine other-machine",
+		},
+		{
+			name:       "Failure staged virtual machine running",
+			existingVM: testutils.StubVirtualMachine(nil, nil, nil),
+			expect: func(infraClient *mockInfraClusterClient.MockClient, machineScope *mockMachineScope.MockMachineScope) {
+			},
+			expectedErr: "test-machine-name: Error during Create: refused to operate on the Virtual Machine in infraCluster, with error: pre-staged VirtualMachine test-machine-name isn't halted",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			infraClient := mockInfraClusterClient.NewMockClient(mockCtrl)
+			machineScope := mockMachineScope.NewMockMachineScope(mockCtrl)
+			tc.expect(infraClient, machineScope)
+			infraClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(tc.existingVM, nil).Times(1)
+
+			virtualMachineFromMachine := testutils.StubVirtualMachine(nil, nil, nil)
+			m := &manager{infraClusterClient: infraClient, requeueAfter: requeueAfter}
+			_, err := m.adoptExistingVirtualMachine(machineScope, virtualMachineFromMachine, nil, tc.ignitionDataVolumeName, testutils.MachineName)
+			if tc.expectedErr != "" {
+				assert.Error(t, err, tc.expectedErr)
+			} else {
+				assert.NilError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package kubevirt
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
+	kubevirtapiv1 "kubevirt.io/client-go/api/v1"
+)
+
+// restartRequiredCondition is set by KubeVirt on a VirtualMachine whose changes can't be applied live to its
+// running VirtualMachineInstance
+const restartRequiredCondition kubevirtapiv1.VirtualMachineConditionType = "RestartRequired"
+
+// useHotplugResources moves the CPU and memory requests of the VirtualMachine to CPU sockets and guest memory,
+// which KubeVirt hot-plugs to the running VirtualMachineInstance when the infra-cluster rolls out live updates
+func useHotplugResources(vm *kubevirtapiv1.VirtualMachine) {
+	domain := &vm.Spec.Template.Spec.Domain
+	requests := domain.Resources.Requests
+	if cpu, ok := requests[corev1.ResourceCPU]; ok {
+		domain.CPU = &kubevirtapiv1.CPU{Sockets: uint32(cpu.Value()), Cores: 1, Threads: 1}
+		delete(requests, corev1.ResourceCPU)
+	}
+	if memory, ok := requests[corev1.ResourceMemory]; ok {
+		domain.Memory = &kubevirtapiv1.Memory{Guest: &memory}
+		delete(requests, corev1.ResourceMemory)
+	}
+}
+
+// hotplugResources returns true if the CPU and memory of the domain are set as CPU sockets and guest memory
+func hotplugResources(domain *kubevirtapiv1.DomainSpec) bool {
+	return domain.CPU != nil && domain.CPU.Sockets > 0 && domain.Memory != nil && domain.Memory.Guest != nil
+}
+
+// domainResources returns the vCPUs and the memory of the domain, from its CPU topology and guest memory when set,
+// otherwise from its resource requests
+func domainResources(domain *kubevirtapiv1.DomainSpec) (apiresource.Quantity, apiresource.Quantity) {
+	cpu := domain.Resources.Requests[corev1.ResourceCPU]
+	if domain.CPU != nil && domain.CPU.Sockets > 0 {
+		vcpus := int64(domain.CPU.Sockets) * int64(maxUint32(domain.CPU.Cores, 1)) * int64(maxUint32(domain.CPU.Threads, 1))
+		cpu = *apiresource.NewQuantity(vcpus, apiresource.DecimalSI)
+	}
+	memory := domain.Resources.Requests[corev1.ResourceMemory]
+	if domain.Memory != nil && domain.Memory.Guest != nil {
+		memory = *domain.Memory.Guest
+	}
+	return cpu, memory
+}
+
+// liveResizePossible returns true if the resources of the running domain can be grown to the requested ones live,
+// which needs the running domain to use CPU sockets and guest memory, as only increases are hot-plugged
+func liveResizePossible(requested, running *kubevirtapiv1.DomainSpec) bool {
+	if !hotplugResources(running) {
+		return false
+	}
+	requestedCPU, requestedMemory := domainResources(requested)
+	runningCPU, runningMemory := domainResources(running)
+	return requestedCPU.Cmp(runningCPU) >= 0 && requestedMemory.Cmp(runningMemory) >= 0
+}
+
+// vmRestartRequired returns true if KubeVirt reports the changes of the VirtualMachine can't be applied live
+func vmRestartRequired(vm *kubevirtapiv1.VirtualMachine) bool {
+	for _, condition := range vm.Status.Conditions {
+		if condition.Type == restartRequiredCondition {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func maxUint32(a, b uint32) uint32 {
+	if a > b {
+		return a
+	}
+	return b
+}
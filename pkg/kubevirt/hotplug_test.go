@@ -0,0 +1,109 @@
+package kubevirt
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster"
+	mockInfraClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster/mock"
+	mockMachineScope "github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope/mock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/testutils"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
+	kubevirtapiv1 "kubevirt.io/client-go/api/v1"
+)
+
+func TestUseHotplugResources(t *testing.T) {
+	vm := testutils.StubVirtualMachine(nil, nil, nil)
+	domain := &vm.Spec.Template.Spec.Domain
+	domain.Resources.Requests[corev1.ResourceCPU] = apiresource.MustParse("2")
+	cpu, memory := domainResources(domain)
+
+	useHotplugResources(vm)
+	assert.Equal(t, len(domain.Resources.Requests), 0)
+	assert.DeepEqual(t, domain.CPU, &kubevirtapiv1.CPU{Sockets: 2, Cores: 1, Threads: 1})
+	assert.Assert(t, hotplugResources(domain))
+	hotplugCPU, hotplugMemory := domainResources(domain)
+	assert.Equal(t, hotplugCPU.Cmp(cpu), 0)
+	assert.Equal(t, hotplugMemory.Cmp(memory), 0)
+}
+
+func TestLiveResizePossible(t *testing.T) {
+	domain := func(sockets uint32, memory string) *kubevirtapiv1.DomainSpec {
+		guest := apiresource.MustParse(memory)
+		return &kubevirtapiv1.DomainSpec{
+			CPU:    &kubevirtapiv1.CPU{Sockets: sockets, Cores: 1, Threads: 1},
+			Memory: &kubevirtapiv1.Memory{Guest: &guest},
+		}
+	}
+	requestsDomain := &kubevirtapiv1.DomainSpec{Resources: kubevirtapiv1.ResourceRequirements{Requests: corev1.ResourceList{
+		corev1.ResourceCPU:    apiresource.MustParse("2"),
+		corev1.ResourceMemory: apiresource.MustParse("4Gi"),
+	}}}
+
+	assert.Assert(t, liveResizePossible(domain(4, "8Gi"), domain(2, "4Gi")))
+	assert.Assert(t, !liveResizePossible(domain(1, "8Gi"), domain(2, "4Gi")))
+	assert.Assert(t, !liveResizePossible(domain(4, "2Gi"), domain(2, "4Gi")))
+	assert.Assert(t, !liveResizePossible(domain(4, "8Gi"), requestsDomain))
+}
+
+func TestRestartRequiredLiveUpdate(t *testing.T) {
+	cases := []struct {
+		name             string
+		runningSockets   uint32
+		restartCondition bool
+		expectedGetVM    bool
+		expectedResult   bool
+	}{
+		{
+			name:           "Success CPU increase is hot-plugged",
+			runningSockets: 1,
+			expectedGetVM:  true,
+		},
+		{
+			name:             "Success CPU increase exceeds the hotplug limits",
+			runningSockets:   1,
+			restartCondition: true,
+			expectedGetVM:    true,
+			expectedResult:   true,
+		},
+		{
+			name:           "Success CPU decrease requires a restart",
+			runningSockets: 4,
+			expectedResult: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockInfraClusterClient := mockInfraClusterClient.NewMockClient(mockCtrl)
+			mockMachineScope := mockMachineScope.NewMockMachineScope(mockCtrl)
+
+			vm := testutils.StubVirtualMachine(nil, nil, nil)
+			vm.Spec.Template.Spec.Domain.Resources.Requests[corev1.ResourceCPU] = apiresource.MustParse("2")
+			guest := vm.Spec.Template.Spec.Domain.Resources.Requests[corev1.ResourceMemory]
+			vmi := testutils.StubVirtualMachineInstance()
+			vmi.Spec.Domain.CPU = &kubevirtapiv1.CPU{Sockets: tc.runningSockets, Cores: 1, Threads: 1}
+			vmi.Spec.Domain.Memory = &kubevirtapiv1.Memory{Guest: &guest}
+
+			mockInfraClusterClient.EXPECT().GetCapabilities().Return(infracluster.Capabilities{LiveUpdate: true}).AnyTimes()
+			mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).AnyTimes()
+			mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vm, nil).Times(1)
+			mockInfraClusterClient.EXPECT().GetVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vmi, nil).Times(1)
+			if tc.expectedGetVM {
+				existingVM := testutils.StubVirtualMachine(nil, nil, nil)
+				if tc.restartCondition {
+					existingVM.Status.Conditions = []kubevirtapiv1.VirtualMachineCondition{{Type: restartRequiredCondition, Status: corev1.ConditionTrue}}
+				}
+				mockInfraClusterClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(existingVM, nil).Times(1)
+			}
+
+			kubevirtVM := New(mockInfraClusterClient, requeueAfter)
+			result, err := kubevirtVM.RestartRequired(mockMachineScope)
+			assert.NilError(t, err)
+			assert.Equal(t, result, tc.expectedResult)
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package kubevirt
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog"
+
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
+)
+
+// checkIgnitionSources checks that the remote ignition sources of the userData are reachable from the network of
+// the Machine, with a checkup pod attached to the network in the infra namespace. It returns false while the
+// checkup pod runs, and an IgnitionSourceUnreachableError when a source is unreachable.
+func (m *manager) checkIgnitionSources(machineScope machinescope.MachineScope, userData []byte, machineName string) (bool, error) {
+	if machineScope.GetIgnitionSourceCheck() == nil || machineScope.CreateStepDone(machinescope.CreateStepIgnitionSourceChecked) ||
+		machineScope.CreateStepDone(machinescope.CreateStepVMCreated) {
+		return true, nil
+	}
+	sources, err := remoteUserDataSources(userData)
+	if err != nil || len(sources) == 0 {
+		return err == nil, err
+	}
+	podFromMachine, err := machineScope.CreateIgnitionSourceCheckPodFromMachine(sources)
+	if err != nil {
+		return false, err
+	}
+
+	pod, err := m.infraClusterClient.GetPod(context.Background(), podFromMachine.Namespace, podFromMachine.Name)
+	if errors.IsNotFound(err) {
+		klog.Infof("%s: checking that the ignition sources %v are reachable from the network of the Machine", machineName, sources)
+		if _, err := m.infraClusterClient.CreatePod(machineContext(machineName), podFromMachine.Namespace, podFromMachine); err != nil && !errors.IsAlreadyExists(err) {
+			return false, err
+		}
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	switch pod.Status.Phase {
+	case corev1.PodSucceeded:
+		m.deleteIgnitionSourceCheckPod(pod, machineName)
+		recordCreateStep(machineScope, machinescope.CreateStepIgnitionSourceChecked, machineName)
+		return true, nil
+	case corev1.PodFailed:
+		// The checkup pod is deleted, so the next creation checks the sources again
+		m.deleteIgnitionSourceCheckPod(pod, machineName)
+		return false, &IgnitionSourceUnreachableError{Sources: sources, Message: ignitionSourceCheckMessage(pod)}
+	default:
+		return false, nil
+	}
+}
+
+func (m *manager) deleteIgnitionSourceCheckPod(pod *corev1.Pod, machineName string) {
+	if err := m.infraClusterClient.DeletePod(machineContext(machineName), pod.Namespace, pod.Name); err != nil && !errors.IsNotFound(err) {
+		klog.Errorf("%s: failed to delete the ignition source checkup pod %s, with error: %v", machineName, pod.Name, err)
+	}
+}
+
+// ignitionSourceCheckMessage returns why the checkup pod failed, the error of curl when it terminated
+func ignitionSourceCheckMessage(pod *corev1.Pod) string {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Terminated != nil && status.State.Terminated.Message != "" {
+			return status.State.Terminated.Message
+		}
+	}
+	if pod.Status.Message != "" {
+		return pod.Status.Message
+	}
+	return "the checkup pod failed"
+}
@@ -0,0 +1,123 @@
+package kubevirt
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+	mockInfraClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster/mock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
+	mockMachineScope "github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope/mock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/testutils"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	testIgnitionSource       = "https://192.168.123.15:22623/config/worker"
+	testIgnitionCheckPodName = "test-machine-name-ignition-check"
+)
+
+func stubIgnitionCheckPod(phase corev1.PodPhase, message string) *corev1.Pod {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: testIgnitionCheckPodName, Namespace: testutils.InfraNamespace}}
+	pod.Status.Phase = phase
+	if message != "" {
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+			State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 1, Message: message}},
+		}}
+	}
+	return pod
+}
+
+func TestCheckIgnitionSources(t *testing.T) {
+	cases := []struct {
+		name            string
+		check           *kubevirtproviderv1alpha1.IgnitionSourceCheck
+		stepDone        bool
+		expect          func(infraClient *mockInfraClusterClient.MockClient, machineScope *mockMachineScope.MockMachineScope)
+		expectedChecked bool
+		expectedErr     string
+	}{
+		{
+			name: "Success skip without check",
+			expect: func(infraClient *mockInfraClusterClient.MockClient, machineScope *mockMachineScope.MockMachineScope) {
+			},
+			expectedChecked: true,
+		},
+		{
+			name:     "Success skip the sources already checked",
+			check:    &kubevirtproviderv1alpha1.IgnitionSourceCheck{},
+			stepDone: true,
+			expect: func(infraClient *mockInfraClusterClient.MockClient, machineScope *mockMachineScope.MockMachineScope) {
+			},
+			expectedChecked: true,
+		},
+		{
+			name:  "Success start the checkup pod",
+			check: &kubevirtproviderv1alpha1.IgnitionSourceCheck{},
+			expect: func(infraClient *mockInfraClusterClient.MockClient, machineScope *mockMachineScope.MockMachineScope) {
+				infraClient.EXPECT().GetPod(gomock.Any(), testutils.InfraNamespace, testIgnitionCheckPodName).Return(
+					nil, apierr.NewNotFound(schema.GroupResource{Resource: "pods"}, testIgnitionCheckPodName)).Times(1)
+				infraClient.EXPECT().CreatePod(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(nil, nil).Times(1)
+			},
+		},
+		{
+			name:  "Success wait for the checkup pod",
+			check: &kubevirtproviderv1alpha1.IgnitionSourceCheck{},
+			expect: func(infraClient *mockInfraClusterClient.MockClient, machineScope *mockMachineScope.MockMachineScope) {
+				infraClient.EXPECT().GetPod(gomock.Any(), testutils.InfraNamespace, testIgnitionCheckPodName).Return(
+					stubIgnitionCheckPod(corev1.PodRunning, ""), nil).Times(1)
+			},
+		},
+		{
+			name:  "Success reachable sources",
+			check: &kubevirtproviderv1alpha1.IgnitionSourceCheck{},
+			expect: func(infraClient *mockInfraClusterClient.MockClient, machineScope *mockMachineScope.MockMachineScope) {
+				infraClient.EXPECT().GetPod(gomock.Any(), testutils.InfraNamespace, testIgnitionCheckPodName).Return(
+					stubIgnitionCheckPod(corev1.PodSucceeded, ""), nil).Times(1)
+				infraClient.EXPECT().DeletePod(gomock.Any(), testutils.InfraNamespace, testIgnitionCheckPodName).Return(nil).Times(1)
+				machineScope.EXPECT().SetCreateStepDone(machinescope.CreateStepIgnitionSourceChecked).Return(nil).Times(1)
+			},
+			expectedChecked: true,
+		},
+		{
+			name:  "Failure unreachable sources",
+			check: &kubevirtproviderv1alpha1.IgnitionSourceCheck{},
+			expect: func(infraClient *mockInfraClusterClient.MockClient, machineScope *mockMachineScope.MockMachineScope) {
+				infraClient.EXPECT().GetPod(gomock.Any(), testutils.InfraNamespace, testIgnitionCheckPodName).Return(
+					stubIgnitionCheckPod(corev1.PodFailed, "curl: (28) Connection timed out"), nil).Times(1)
+				infraClient.EXPECT().DeletePod(gomock.Any(), testutils.InfraNamespace, testIgnitionCheckPodName).Return(nil).Times(1)
+			},
+			expectedErr: fmt.Sprintf("the ignition sources %s are unreachable from the network of the Machine: curl: (28) Connection timed out", testIgnitionSource),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			infraClient := mockInfraClusterClient.NewMockClient(mockCtrl)
+			machineScope := mockMachineScope.NewMockMachineScope(mockCtrl)
+			tc.expect(infraClient, machineScope)
+			machineScope.EXPECT().GetIgnitionSourceCheck().Return(tc.check).AnyTimes()
+			machineScope.EXPECT().CreateStepDone(machinescope.CreateStepIgnitionSourceChecked).Return(tc.stepDone).AnyTimes()
+			machineScope.EXPECT().CreateStepDone(machinescope.CreateStepVMCreated).Return(false).AnyTimes()
+			machineScope.EXPECT().CreateIgnitionSourceCheckPodFromMachine([]string{testIgnitionSource}).Return(
+				&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: testIgnitionCheckPodName, Namespace: testutils.InfraNamespace}}, nil).AnyTimes()
+
+			m := &manager{infraClusterClient: infraClient, requeueAfter: requeueAfter}
+			checked, err := m.checkIgnitionSources(machineScope, []byte(testutils.SrcUserData), testutils.MachineName)
+			if tc.expectedErr != "" {
+				assert.Error(t, err, tc.expectedErr)
+				assert.Assert(t, IsIgnitionSourceUnreachable(err))
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, checked, tc.expectedChecked)
+		})
+	}
+}
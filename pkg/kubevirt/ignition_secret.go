@@ -0,0 +1,122 @@
+package kubevirt
+
+import (
+	"context"
+	"reflect"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+	kubevirtapiv1 "kubevirt.io/client-go/api/v1"
+
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
+)
+
+// ignitionSecretRef returns the name of the ignition secret referenced by the config drive of the
+// VirtualMachineInstance spec, empty when the ignition isn't propagated via a secret
+func ignitionSecretRef(spec *kubevirtapiv1.VirtualMachineInstanceSpec) string {
+	for _, volume := range spec.Volumes {
+		if volume.CloudInitConfigDrive != nil && volume.CloudInitConfigDrive.UserDataSecretRef != nil {
+			return volume.CloudInitConfigDrive.UserDataSecretRef.Name
+		}
+	}
+	return ""
+}
+
+// useIgnitionSecret references the ignition secret in the config drive of the VirtualMachine
+func useIgnitionSecret(vm *kubevirtapiv1.VirtualMachine, secretName string) {
+	if vm.Spec.Template == nil {
+		return
+	}
+	for _, volume := range vm.Spec.Template.Spec.Volumes {
+		if volume.CloudInitConfigDrive != nil && volume.CloudInitConfigDrive.UserDataSecretRef != nil {
+			volume.CloudInitConfigDrive.UserDataSecretRef = &corev1.LocalObjectReference{Name: secretName}
+		}
+	}
+}
+
+// keepIgnitionSecret keeps the VirtualMachine referencing the version of the ignition secret the existing
+// VirtualMachine references
+func keepIgnitionSecret(vm *kubevirtapiv1.VirtualMachine, existingVM *kubevirtapiv1.VirtualMachine) {
+	if existingVM.Spec.Template == nil {
+		return
+	}
+	if secretName := ignitionSecretRef(&existingVM.Spec.Template.Spec); secretName != "" {
+		useIgnitionSecret(vm, secretName)
+	}
+}
+
+// createIgnitionSecret creates the ignition secret, or updates the existing secret of the same name to the
+// userData of the Machine, e.g. when an interrupted creation left a secret of a former userData. It returns whether
+// the secret was created.
+func (m *manager) createIgnitionSecret(secret *corev1.Secret, machineName string) (bool, error) {
+	_, err := m.infraClusterClient.CreateSecret(machineContext(machineName), secret.Namespace, secret)
+	if err == nil {
+		return true, nil
+	}
+	if !errors.IsAlreadyExists(err) {
+		return false, err
+	}
+	existingSecret, err := m.infraClusterClient.GetSecret(context.Background(), secret.Namespace, secret.Name)
+	if err != nil {
+		return false, err
+	}
+	if reflect.DeepEqual(existingSecret.Data, secret.Data) {
+		return false, nil
+	}
+	klog.Infof("%s: ignition secret %s already exists with another userData - update it", machineName, secret.Name)
+	existingSecret.Data = secret.Data
+	_, err = m.infraClusterClient.UpdateSecret(machineContext(machineName), secret.Namespace, existingSecret)
+	return false, err
+}
+
+// adoptIgnitionSecret sets the VirtualMachine as the owner of its ignition secret, so the secret is deleted together
+// with the VirtualMachine, even when the VirtualMachine is deleted without the Machine, e.g. during a disaster recovery
+func (m *manager) adoptIgnitionSecret(vm *kubevirtapiv1.VirtualMachine, secretName string, machineName string) error {
+	secret, err := m.infraClusterClient.GetSecret(context.Background(), vm.Namespace, secretName)
+	if err != nil {
+		return err
+	}
+	if !addOwnerReference(&secret.ObjectMeta, vm) {
+		return nil
+	}
+	_, err = m.infraClusterClient.UpdateSecret(machineContext(machineName), vm.Namespace, secret)
+	return err
+}
+
+// collectIgnitionSecrets deletes the versions of the ignition secret of the VirtualMachine which are referenced
+// neither by the VirtualMachine, nil once deleted, nor by its VirtualMachineInstance. The failures are only logged,
+// the versions are collected again by the next operation.
+func (m *manager) collectIgnitionSecrets(namespace string, vmName string, vm *kubevirtapiv1.VirtualMachine, machineName string) {
+	inUse := map[string]bool{}
+	if vm != nil && vm.Spec.Template != nil {
+		inUse[ignitionSecretRef(&vm.Spec.Template.Spec)] = true
+	}
+	vmi, err := m.infraClusterClient.GetVirtualMachineInstance(context.Background(), namespace, vmName, &k8smetav1.GetOptions{})
+	switch {
+	case err == nil:
+		inUse[ignitionSecretRef(&vmi.Spec)] = true
+	case !errors.IsNotFound(err):
+		klog.Errorf("%s: failed to get the VirtualMachineInstance referencing the ignition secrets, with error: %v", machineName, err)
+		return
+	}
+
+	secrets, err := m.infraClusterClient.ListSecrets(context.Background(), namespace, k8smetav1.ListOptions{
+		LabelSelector: machinescope.IgnitionSecretOfLabel + "=" + vmName,
+	})
+	if err != nil {
+		klog.Errorf("%s: failed to list the versions of the ignition secret, with error: %v", machineName, err)
+		return
+	}
+	for _, secret := range secrets.Items {
+		if inUse[secret.Name] {
+			continue
+		}
+		if err := m.infraClusterClient.DeleteSecret(machineContext(machineName), namespace, secret.Name); err != nil && !errors.IsNotFound(err) {
+			klog.Errorf("%s: failed to delete the unused ignition secret %s, with error: %v", machineName, secret.Name, err)
+			continue
+		}
+		klog.Infof("%s: deleted the unused ignition secret %s", machineName, secret.Name)
+	}
+}
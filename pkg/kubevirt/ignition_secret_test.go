@@ -0,0 +1,155 @@
+package kubevirt
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	mockInfraClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster/mock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/testutils"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kubevirtapiv1 "kubevirt.io/client-go/api/v1"
+)
+
+func stubIgnitionSecretVersions(names ...string) *corev1.SecretList {
+	secrets := &corev1.SecretList{}
+	for _, name := range names {
+		secrets.Items = append(secrets.Items, corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testutils.InfraNamespace}})
+	}
+	return secrets
+}
+
+func TestUseIgnitionSecret(t *testing.T) {
+	vm := testutils.StubVirtualMachine(nil, nil, nil)
+	useIgnitionSecret(vm, "test-machine-name-ignition-0123456789")
+	assert.Equal(t, ignitionSecretRef(&vm.Spec.Template.Spec), "test-machine-name-ignition-0123456789")
+
+	updatedVM := testutils.StubVirtualMachine(nil, nil, nil)
+	keepIgnitionSecret(updatedVM, vm)
+	assert.DeepEqual(t, updatedVM.Spec, vm.Spec)
+
+	// The ignition uploaded to a DataVolume doesn't reference a secret
+	useIgnitionDataVolume(vm, "test-machine-name-ignition")
+	useIgnitionSecret(vm, "test-machine-name-ignition-0123456789")
+	assert.Equal(t, ignitionSecretRef(&vm.Spec.Template.Spec), "")
+}
+
+func TestCollectIgnitionSecrets(t *testing.T) {
+	listOptions := metav1.ListOptions{LabelSelector: machinescope.IgnitionSecretOfLabel + "=" + testutils.MachineName}
+	vmiNotFound := apierr.NewNotFound(schema.GroupResource{Resource: "virtualmachineinstances"}, testutils.MachineName)
+
+	cases := []struct {
+		name   string
+		vm     *kubevirtapiv1.VirtualMachine
+		expect func(infraClient *mockInfraClusterClient.MockClient)
+	}{
+		{
+			name: "delete the versions of a former VirtualMachine",
+			vm:   stubVMReferencingIgnitionSecret("test-machine-name-ignition-new"),
+			expect: func(infraClient *mockInfraClusterClient.MockClient) {
+				infraClient.EXPECT().GetVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).
+					Return(nil, vmiNotFound).Times(1)
+				infraClient.EXPECT().ListSecrets(gomock.Any(), testutils.InfraNamespace, listOptions).
+					Return(stubIgnitionSecretVersions("test-machine-name-ignition-old", "test-machine-name-ignition-new"), nil).Times(1)
+				infraClient.EXPECT().DeleteSecret(gomock.Any(), testutils.InfraNamespace, "test-machine-name-ignition-old").Return(nil).Times(1)
+			},
+		},
+		{
+			name: "keep the version of the running VirtualMachineInstance",
+			vm:   stubVMReferencingIgnitionSecret("test-machine-name-ignition-new"),
+			expect: func(infraClient *mockInfraClusterClient.MockClient) {
+				vmi := testutils.StubVirtualMachineInstance()
+				vmi.Spec.Volumes = stubVMReferencingIgnitionSecret("test-machine-name-ignition-old").Spec.Template.Spec.Volumes
+				infraClient.EXPECT().GetVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).
+					Return(vmi, nil).Times(1)
+				infraClient.EXPECT().ListSecrets(gomock.Any(), testutils.InfraNamespace, listOptions).
+					Return(stubIgnitionSecretVersions("test-machine-name-ignition-old", "test-machine-name-ignition-new"), nil).Times(1)
+			},
+		},
+		{
+			name: "delete every version once the VirtualMachine is deleted",
+			expect: func(infraClient *mockInfraClusterClient.MockClient) {
+				infraClient.EXPECT().GetVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).
+					Return(nil, vmiNotFound).Times(1)
+				infraClient.EXPECT().ListSecrets(gomock.Any(), testutils.InfraNamespace, listOptions).
+					Return(stubIgnitionSecretVersions("test-machine-name-ignition-old", "test-machine-name-ignition-new"), nil).Times(1)
+				infraClient.EXPECT().DeleteSecret(gomock.Any(), testutils.InfraNamespace, "test-machine-name-ignition-old").Return(nil).Times(1)
+				infraClient.EXPECT().DeleteSecret(gomock.Any(), testutils.InfraNamespace, "test-machine-name-ignition-new").Return(nil).Times(1)
+			},
+		},
+		{
+			name: "keep every version when the VirtualMachineInstance is unknown",
+			vm:   stubVMReferencingIgnitionSecret("test-machine-name-ignition-new"),
+			expect: func(infraClient *mockInfraClusterClient.MockClient) {
+				infraClient.EXPECT().GetVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).
+					Return(nil, apierr.NewServiceUnavailable("unavailable")).Times(1)
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			infraClient := mockInfraClusterClient.NewMockClient(mockCtrl)
+			tc.expect(infraClient)
+
+			m := &manager{infraClusterClient: infraClient, requeueAfter: requeueAfter}
+			m.collectIgnitionSecrets(testutils.InfraNamespace, testutils.MachineName, tc.vm, testutils.MachineName)
+		})
+	}
+}
+
+func stubVMReferencingIgnitionSecret(secretName string) *kubevirtapiv1.VirtualMachine {
+	vm := testutils.StubVirtualMachine(nil, nil, nil)
+	useIgnitionSecret(vm, secretName)
+	return vm
+}
+
+func TestAdoptIgnitionSecret(t *testing.T) {
+	vm := testutils.StubVirtualMachine(nil, nil, testutils.StringPointer("test-vm-uid"))
+	ownerReference := metav1.OwnerReference{
+		APIVersion: machinescope.APIVersion,
+		Kind:       machinescope.Kind,
+		Name:       testutils.MachineName,
+		UID:        "test-vm-uid",
+	}
+
+	cases := []struct {
+		name   string
+		expect func(infraClient *mockInfraClusterClient.MockClient)
+	}{
+		{
+			name: "set the VirtualMachine as the owner of the secret",
+			expect: func(infraClient *mockInfraClusterClient.MockClient) {
+				secret := testutils.StubIgnitionSecret()
+				ownedSecret := secret.DeepCopy()
+				ownedSecret.OwnerReferences = []metav1.OwnerReference{ownerReference}
+				infraClient.EXPECT().GetSecret(gomock.Any(), testutils.InfraNamespace, secret.Name).Return(secret, nil).Times(1)
+				infraClient.EXPECT().UpdateSecret(gomock.Any(), testutils.InfraNamespace, ownedSecret).Return(ownedSecret, nil).Times(1)
+			},
+		},
+		{
+			name: "keep the secret already owned by the VirtualMachine",
+			expect: func(infraClient *mockInfraClusterClient.MockClient) {
+				secret := testutils.StubIgnitionSecret()
+				secret.OwnerReferences = []metav1.OwnerReference{ownerReference}
+				infraClient.EXPECT().GetSecret(gomock.Any(), testutils.InfraNamespace, secret.Name).Return(secret, nil).Times(1)
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			infraClient := mockInfraClusterClient.NewMockClient(mockCtrl)
+			tc.expect(infraClient)
+
+			m := &manager{infraClusterClient: infraClient, requeueAfter: requeueAfter}
+			assert.NilError(t, m.adoptIgnitionSecret(vm, testutils.StubIgnitionSecret().Name, testutils.MachineName))
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package kubevirt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog"
+	kubevirtapiv1 "kubevirt.io/client-go/api/v1"
+	cdiv1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
+
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
+)
+
+// uploadIgnition uploads the config drive image holding the userData, together with the metadata and the network
+// data of the VirtualMachine, to the ignition DataVolume through the CDI upload proxy, for the VirtualMachine to
+// mount the DataVolume as its config drive. The ignition isn't written to the VirtualMachine, where it would be
+// readable by everyone reading the VirtualMachine, and would count against the size limit of the object.
+// It returns the name of the DataVolume, and false while the DataVolume isn't uploaded yet, the caller requeues.
+func (m *manager) uploadIgnition(machineScope machinescope.MachineScope, vm *kubevirtapiv1.VirtualMachine, userData []byte, machineName string) (string, bool, error) {
+	hostname, err := machineScope.GetHostname()
+	if err != nil {
+		return "", false, err
+	}
+	image, err := buildConfigDrive(configDriveFiles(vm, hostname, userData))
+	if err != nil {
+		return "", false, err
+	}
+	dataVolume, err := machineScope.CreateIgnitionDataVolumeFromMachine(int64(len(image)))
+	if err != nil {
+		return "", false, err
+	}
+
+	existingDataVolume, err := m.infraClusterClient.GetDataVolume(context.Background(), dataVolume.Namespace, dataVolume.Name)
+	if errors.IsNotFound(err) {
+		if _, err := m.infraClusterClient.CreateDataVolume(machineContext(machineName), dataVolume.Namespace, dataVolume); err != nil && !errors.IsAlreadyExists(err) {
+			return "", false, err
+		}
+		klog.Infof("%s: ignition DataVolume %s was created in infracluster - waiting for the upload", machineName, dataVolume.Name)
+		return dataVolume.Name, false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	phase, _, err := unstructured.NestedString(existingDataVolume.Object, "status", "phase")
+	if err != nil {
+		return "", false, err
+	}
+	switch cdiv1.DataVolumePhase(phase) {
+	case cdiv1.Succeeded:
+		return dataVolume.Name, true, nil
+	case cdiv1.UploadReady:
+		if err := m.infraClusterClient.UploadDataVolume(machineContext(machineName), dataVolume.Namespace, dataVolume.Name, image); err != nil {
+			return "", false, err
+		}
+		klog.Infof("%s: ignition was uploaded to DataVolume %s in infracluster", machineName, dataVolume.Name)
+		return dataVolume.Name, false, nil
+	case cdiv1.Failed:
+		// The DataVolume is created again by the next attempt
+		if err := m.infraClusterClient.DeleteDataVolume(machineContext(machineName), dataVolume.Namespace, dataVolume.Name); err != nil && !errors.IsNotFound(err) {
+			klog.Errorf("%s: failed to delete the failed ignition DataVolume %s, with error: %v", machineName, dataVolume.Name, err)
+		}
+		return "", false, fmt.Errorf("upload to the ignition DataVolume %s failed", dataVolume.Name)
+	}
+	klog.Infof("%s: ignition DataVolume %s is %s - waiting for the upload", machineName, dataVolume.Name, phase)
+	return dataVolume.Name, false, nil
+}
+
+// configDriveFiles returns the files of the config drive of the VirtualMachine, laid out as KubeVirt lays out the
+// config drive of a CloudInitConfigDrive volume, with the hostname of the Machine
+func configDriveFiles(vm *kubevirtapiv1.VirtualMachine, hostname string, userData []byte) []configDriveFile {
+	metadata, _ := json.Marshal(map[string]string{
+		"instance_id": fmt.Sprintf("%s.%s", vm.Name, vm.Namespace),
+		"hostname":    hostname,
+	})
+	files := []configDriveFile{
+		{path: "openstack/latest/meta_data.json", data: metadata},
+		{path: "openstack/latest/user_data", data: userData},
+	}
+	if networkData := configDriveNetworkData(vm); networkData != "" {
+		files = append(files, configDriveFile{path: "openstack/latest/network_data.json", data: []byte(networkData)})
+	}
+	return files
+}
+
+// configDriveNetworkData returns the network data of the config drive of the VirtualMachine
+func configDriveNetworkData(vm *kubevirtapiv1.VirtualMachine) string {
+	if vm.Spec.Template == nil {
+		return ""
+	}
+	for _, volume := range vm.Spec.Template.Spec.Volumes {
+		if volume.CloudInitConfigDrive != nil {
+			return volume.CloudInitConfigDrive.NetworkData
+		}
+	}
+	return ""
+}
+
+// useIgnitionDataVolume replaces the config drive of the VirtualMachine by the ignition DataVolume
+func useIgnitionDataVolume(vm *kubevirtapiv1.VirtualMachine, dataVolumeName string) {
+	if vm.Spec.Template == nil {
+		return
+	}
+	for i := range vm.Spec.Template.Spec.Volumes {
+		if vm.Spec.Template.Spec.Volumes[i].CloudInitConfigDrive != nil {
+			vm.Spec.Template.Spec.Volumes[i].VolumeSource = kubevirtapiv1.VolumeSource{
+				DataVolume: &kubevirtapiv1.DataVolumeSource{Name: dataVolumeName},
+			}
+		}
+	}
+}
+
+// keepIgnitionDataVolume keeps the VirtualMachine mounting the ignition DataVolume the existing VirtualMachine
+// mounts as its config drive
+func keepIgnitionDataVolume(vm *kubevirtapiv1.VirtualMachine, existingVM *kubevirtapiv1.VirtualMachine) {
+	if vm.Spec.Template == nil || existingVM.Spec.Template == nil {
+		return
+	}
+	for i := range vm.Spec.Template.Spec.Volumes {
+		volume := &vm.Spec.Template.Spec.Volumes[i]
+		if volume.CloudInitConfigDrive == nil {
+			continue
+		}
+		for _, existingVolume := range existingVM.Spec.Template.Spec.Volumes {
+			if existingVolume.Name == volume.Name && existingVolume.DataVolume != nil {
+				volume.VolumeSource = *existingVolume.VolumeSource.DeepCopy()
+			}
+		}
+	}
+}
+
+// adoptIgnitionDataVolume sets the VirtualMachine as the owner of its ignition DataVolume, so the DataVolume is
+// deleted together with the VirtualMachine
+func (m *manager) adoptIgnitionDataVolume(vm *kubevirtapiv1.VirtualMachine, dataVolumeName string, machineName string) error {
+	existingDataVolume, err := m.infraClusterClient.GetDataVolume(context.Background(), vm.Namespace, dataVolumeName)
+	if err != nil {
+		return err
+	}
+	var dataVolume cdiv1.DataVolume
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(existingDataVolume.Object, &dataVolume); err != nil {
+		return err
+	}
+	if !addOwnerReference(&dataVolume.ObjectMeta, vm) {
+		return nil
+	}
+	_, err = m.infraClusterClient.UpdateDataVolume(machineContext(machineName), vm.Namespace, &dataVolume)
+	return err
+}
+
+// deleteIgnitionDataVolume deletes the ignition DataVolume of a Machine whose VirtualMachine doesn't exist, e.g. when
+// the Machine is deleted before its VirtualMachine was created. The DataVolume of a VirtualMachine is owned by it.
+func (m *manager) deleteIgnitionDataVolume(machineScope machinescope.MachineScope, machineName string) error {
+	dataVolume, err := machineScope.CreateIgnitionDataVolumeFromMachine(0)
+	if err != nil {
+		return err
+	}
+	if err := m.infraClusterClient.DeleteDataVolume(machineContext(machineName), dataVolume.Namespace, dataVolume.Name); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
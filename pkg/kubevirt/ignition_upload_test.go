@@ -0,0 +1,103 @@
+package kubevirt
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/testutils"
+	"gotest.tools/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubevirtapiv1 "kubevirt.io/client-go/api/v1"
+	cdiv1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
+)
+
+func stubIgnitionDataVolume() *cdiv1.DataVolume {
+	return &cdiv1.DataVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-ignition", testutils.MachineName), Namespace: testutils.InfraNamespace},
+		Spec:       cdiv1.DataVolumeSpec{Source: cdiv1.DataVolumeSource{Upload: &cdiv1.DataVolumeSourceUpload{}}},
+	}
+}
+
+func stubUnstructuredDataVolume(dataVolume *cdiv1.DataVolume, phase cdiv1.DataVolumePhase) *unstructured.Unstructured {
+	dataVolume = dataVolume.DeepCopy()
+	dataVolume.Status.Phase = phase
+	object, _ := runtime.DefaultUnstructuredConverter.ToUnstructured(dataVolume)
+	return &unstructured.Unstructured{Object: object}
+}
+
+// readISOFile returns the content of the file at the path of the ISO 9660 image, looked up by the Rock Ridge
+// names of the directory records
+func readISOFile(t *testing.T, image []byte, path string) []byte {
+	rootRecord := image[16*isoSectorSize+156:]
+	sector, size := binary.LittleEndian.Uint32(rootRecord[2:]), binary.LittleEndian.Uint32(rootRecord[10:])
+	for _, name := range strings.Split(path, "/") {
+		found := false
+		directory := image[int(sector)*isoSectorSize : int(sector)*isoSectorSize+int(size)]
+		for offset := 0; offset < len(directory) && directory[offset] > 0; offset += int(directory[offset]) {
+			record := directory[offset : offset+int(directory[offset])]
+			systemUse := record[33+int(record[32])+(1-int(record[32])%2):]
+			if len(systemUse) > 5 && string(systemUse[:2]) == "NM" && string(systemUse[5:systemUse[2]]) == name {
+				sector, size = binary.LittleEndian.Uint32(record[2:]), binary.LittleEndian.Uint32(record[10:])
+				found = true
+				break
+			}
+		}
+		assert.Assert(t, found, "%s not found in the image", path)
+	}
+	return image[int(sector)*isoSectorSize : int(sector)*isoSectorSize+int(size)]
+}
+
+func TestBuildConfigDrive(t *testing.T) {
+	largeFile := []byte(strings.Repeat("x", 3*isoSectorSize+1))
+	image, err := buildConfigDrive([]configDriveFile{
+		{path: "openstack/latest/user_data", data: []byte(`{"ignition":{"version":"3.1.0"}}`)},
+		{path: "openstack/latest/network_data.json", data: largeFile},
+		{path: "openstack/content/0000", data: []byte("content")},
+	})
+	assert.NilError(t, err)
+
+	assert.Equal(t, string(image[16*isoSectorSize+1:16*isoSectorSize+6]), "CD001")
+	assert.Equal(t, strings.TrimSpace(string(image[16*isoSectorSize+40:16*isoSectorSize+72])), "config-2")
+	assert.Equal(t, binary.LittleEndian.Uint32(image[16*isoSectorSize+80:]), uint32(len(image)/isoSectorSize))
+	assert.Equal(t, image[17*isoSectorSize], byte(255))
+
+	assert.Equal(t, string(readISOFile(t, image, "openstack/latest/user_data")), `{"ignition":{"version":"3.1.0"}}`)
+	assert.DeepEqual(t, readISOFile(t, image, "openstack/latest/network_data.json"), largeFile)
+	assert.Equal(t, string(readISOFile(t, image, "openstack/content/0000")), "content")
+
+	_, err = buildConfigDrive([]configDriveFile{{path: "openstack/latest/user-data", data: []byte("{}")}})
+	assert.Error(t, err, `config drive path element "user-data" is not a valid ISO 9660 identifier`)
+}
+
+func TestConfigDriveFiles(t *testing.T) {
+	vm := testutils.StubVirtualMachine(nil, nil, nil)
+	vm.Spec.Template.Spec.Volumes[1].CloudInitConfigDrive.NetworkData = `{"links":[]}`
+
+	image, err := buildConfigDrive(configDriveFiles(vm, "test-hostname-override", []byte(`{"ignition":{"version":"3.1.0"}}`)))
+	assert.NilError(t, err)
+	assert.Equal(t, string(readISOFile(t, image, "openstack/latest/user_data")), `{"ignition":{"version":"3.1.0"}}`)
+	assert.Equal(t, string(readISOFile(t, image, "openstack/latest/network_data.json")), `{"links":[]}`)
+	assert.Equal(t, string(readISOFile(t, image, "openstack/latest/meta_data.json")),
+		fmt.Sprintf(`{"hostname":"test-hostname-override","instance_id":"%s.%s"}`, testutils.MachineName, testutils.InfraNamespace))
+}
+
+func TestUseIgnitionDataVolume(t *testing.T) {
+	vm := testutils.StubVirtualMachine(nil, nil, nil)
+	useIgnitionDataVolume(vm, "test-machine-name-ignition")
+	expectedVolume := kubevirtapiv1.VolumeSource{DataVolume: &kubevirtapiv1.DataVolumeSource{Name: "test-machine-name-ignition"}}
+	assert.DeepEqual(t, vm.Spec.Template.Spec.Volumes[1].VolumeSource, expectedVolume)
+
+	// The VirtualMachine built from the Machine again keeps mounting the uploaded ignition
+	updatedVM := testutils.StubVirtualMachine(nil, nil, nil)
+	keepIgnitionDataVolume(updatedVM, vm)
+	assert.DeepEqual(t, updatedVM.Spec.Template.Spec.Volumes[1].VolumeSource, expectedVolume)
+
+	// The ignition propagated via a secret is kept as is
+	updatedVM = testutils.StubVirtualMachine(nil, nil, nil)
+	keepIgnitionDataVolume(updatedVM, testutils.StubVirtualMachine(nil, nil, nil))
+	assert.DeepEqual(t, updatedVM.Spec, testutils.StubVirtualMachine(nil, nil, nil).Spec)
+}
@@ -0,0 +1,87 @@
+package kubevirt
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
+)
+
+const (
+	// UninitializedTaintKey is the taint the kubelet registers the Node of a Machine with a NodeSmokeCheck with,
+	// which keeps workloads away from the Node until it passes the check
+	UninitializedTaintKey = "kubevirt.machine.openshift.io/uninitialized"
+	// kubeletDropinName is the name of the drop-in of the kubelet unit which passes the flags of the Machine
+	kubeletDropinName = "20-kubevirt-machine.conf"
+)
+
+// UninitializedTaint is the taint the kubelet registers the Node of a Machine with a NodeSmokeCheck with
+var UninitializedTaint = corev1.Taint{Key: UninitializedTaintKey, Effect: corev1.TaintEffectNoSchedule}
+
+// injectKubeletDropin adds a drop-in to the kubelet unit, which passes the flags the kubelet registers the Node of
+// the Machine with, so the Node has them from its first registration on. With the afterburn provider hints, the
+// kubelet reads the KUBELET_PROVIDERID of the hints, and is passed the instance type label.
+func injectKubeletDropin(machineScope machinescope.MachineScope, userData []byte) ([]byte, error) {
+	directives := []string{"[Service]"}
+	var kubeletArgs []string
+	if afterburnMetadata := machineScope.GetAfterburnMetadata(); afterburnMetadata != nil {
+		directives = append(directives, "EnvironmentFile="+afterburnMetadataPath)
+		if afterburnMetadata.InstanceType != "" {
+			kubeletArgs = append(kubeletArgs, fmt.Sprintf("--node-labels=%s=%s", corev1.LabelInstanceTypeStable, afterburnMetadata.InstanceType))
+		}
+	}
+	if machineScope.NodeSmokeCheckRequired() {
+		kubeletArgs = append(kubeletArgs, fmt.Sprintf("--register-with-taints=%s=:%s", UninitializedTaint.Key, UninitializedTaint.Effect))
+	}
+	// The kubelet units of the images pass the KUBELET_PROVIDERID and the KUBELET_EXTRA_ARGS of their environment
+	// to the kubelet
+	if len(kubeletArgs) > 0 {
+		directives = append(directives, fmt.Sprintf("Environment=\"KUBELET_EXTRA_ARGS=%s\"", strings.Join(kubeletArgs, " ")))
+	}
+	if len(directives) == 1 {
+		return userData, nil
+	}
+	return addUnitDropinToUserData(userData, "kubelet.service", kubeletDropinName, strings.Join(directives, "\n")+"\n")
+}
+
+// addUnitDropinToUserData adds a drop-in to the systemd unit in the systemd section of the ignition
+func addUnitDropinToUserData(src []byte, unitName string, dropinName string, contents string) ([]byte, error) {
+	var dataMap map[string]interface{}
+	if err := json.Unmarshal(src, &dataMap); err != nil {
+		return nil, fmt.Errorf("failed to parse userData, with error: %v", err)
+	}
+	if _, ok := dataMap["systemd"].(map[string]interface{}); !ok {
+		dataMap["systemd"] = map[string]interface{}{}
+	}
+	systemd := dataMap["systemd"].(map[string]interface{})
+	units, _ := systemd["units"].([]interface{})
+	dropin := map[string]interface{}{
+		"name":     dropinName,
+		"contents": contents,
+	}
+	found := false
+	for _, unit := range units {
+		unit, ok := unit.(map[string]interface{})
+		if !ok || unit["name"] != unitName {
+			continue
+		}
+		dropins, _ := unit["dropins"].([]interface{})
+		unit["dropins"] = append(dropins, dropin)
+		found = true
+	}
+	if !found {
+		units = append(units, map[string]interface{}{
+			"name":    unitName,
+			"dropins": []interface{}{dropin},
+		})
+	}
+	systemd["units"] = units
+	result, err := json.Marshal(dataMap)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
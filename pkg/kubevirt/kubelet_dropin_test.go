@@ -0,0 +1,69 @@
+package kubevirt
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+	mockMachineScope "github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope/mock"
+	"gotest.tools/assert"
+)
+
+func TestInjectKubeletDropin(t *testing.T) {
+	dropin := `{"contents":"[Service]\nEnvironment=\"KUBELET_EXTRA_ARGS=--register-with-taints=kubevirt.machine.openshift.io/uninitialized=:NoSchedule\"\n","name":"20-kubevirt-machine.conf"}`
+	cases := []struct {
+		name              string
+		userData          string
+		afterburnMetadata *kubevirtproviderv1alpha1.AfterburnMetadata
+		smokeCheckEnabled bool
+		expected          string
+	}{
+		{
+			name:     "no flags for the kubelet",
+			userData: `{"ignition":{"version":"3.1.0"}}`,
+			expected: `{"ignition":{"version":"3.1.0"}}`,
+		},
+		{
+			name:              "uninitialized taint registered by the kubelet",
+			userData:          `{"ignition":{"version":"3.1.0"}}`,
+			smokeCheckEnabled: true,
+			expected:          `{"ignition":{"version":"3.1.0"},"systemd":{"units":[{"dropins":[` + dropin + `],"name":"kubelet.service"}]}}`,
+		},
+		{
+			name:              "providerID and instance type of the afterburn provider hints",
+			userData:          `{"ignition":{"version":"3.1.0"}}`,
+			afterburnMetadata: &kubevirtproviderv1alpha1.AfterburnMetadata{InstanceType: "large"},
+			smokeCheckEnabled: true,
+			expected: `{"ignition":{"version":"3.1.0"},"systemd":{"units":[{"dropins":[{"contents":"[Service]\nEnvironmentFile=/etc/kubevirt/afterburn.env\n` +
+				`Environment=\"KUBELET_EXTRA_ARGS=--node-labels=node.kubernetes.io/instance-type=large ` +
+				`--register-with-taints=kubevirt.machine.openshift.io/uninitialized=:NoSchedule\"\n","name":"20-kubevirt-machine.conf"}],"name":"kubelet.service"}]}}`,
+		},
+		{
+			name:              "providerID of the afterburn provider hints",
+			userData:          `{"ignition":{"version":"3.1.0"}}`,
+			afterburnMetadata: &kubevirtproviderv1alpha1.AfterburnMetadata{},
+			expected: `{"ignition":{"version":"3.1.0"},"systemd":{"units":[{"dropins":[{"contents":"[Service]\nEnvironmentFile=/etc/kubevirt/afterburn.env\n",` +
+				`"name":"20-kubevirt-machine.conf"}],"name":"kubelet.service"}]}}`,
+		},
+		{
+			name:              "drop-in added to the kubelet unit of the ignition",
+			userData:          `{"ignition":{"version":"3.1.0"},"systemd":{"units":[{"name":"crio.service","enabled":true},{"name":"kubelet.service","dropins":[{"name":"10-mco-default-env.conf"}]}]}}`,
+			smokeCheckEnabled: true,
+			expected: `{"ignition":{"version":"3.1.0"},"systemd":{"units":[{"enabled":true,"name":"crio.service"},` +
+				`{"dropins":[{"name":"10-mco-default-env.conf"},` + dropin + `],"name":"kubelet.service"}]}}`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockMachineScope := mockMachineScope.NewMockMachineScope(mockCtrl)
+			mockMachineScope.EXPECT().GetAfterburnMetadata().Return(tc.afterburnMetadata).Times(1)
+			mockMachineScope.EXPECT().NodeSmokeCheckRequired().Return(tc.smokeCheckEnabled).Times(1)
+
+			result, err := injectKubeletDropin(mockMachineScope, []byte(tc.userData))
+			assert.NilError(t, err)
+			assert.Equal(t, string(result), tc.expected)
+		})
+	}
+}
@@ -2,11 +2,22 @@ package kubevirt
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
 	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster"
 	"github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/utils"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 
 	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -15,12 +26,18 @@ import (
 )
 
 const (
-	requeueAfterSeconds      = 20
-	requeueAfterFatalSeconds = 180
-	masterLabel              = "node-role.kubevirt.io/master"
-	idFormat                 = "kubevirt://%s/%s"
+	masterLabel           = "node-role.kubevirt.io/master"
+	providerIDPrefix      = "kubevirt://"
+	idFormat              = providerIDPrefix + "%s/%s"
+	instanceMetadataPath  = "/etc/kubevirt/instance-metadata.json"
+	afterburnMetadataPath = "/etc/kubevirt/afterburn.env"
+	instanceIDMetadataKey = "instance-id"
 )
 
+// vmNotFoundWindow is the time since the last update of a Machine during which its VirtualMachine, just created,
+// may not be visible yet: an Update which doesn't find it within the window is requeued rather than failed
+const vmNotFoundWindow = 20 * time.Second
+
 //go:generate mockgen -source=./kubevirt.go -destination=./mock/kubevirt_generated.go -package=mock
 // KubevirtVM runs the logic to reconciles a machine resource towards its desired state
 type KubevirtVM interface {
@@ -31,76 +48,363 @@ type KubevirtVM interface {
 	// Update updates the VirtualMachine of the provided Machine in the InfraCluster with the changes in the Machine
 	// Update finds the relevant VirtualMachine and reconciles the Machine resource status against it.
 	Update(machineScope machinescope.MachineScope) (bool, bool, error)
+	// SyncStatus syncs the status of the provided Machine from its VirtualMachine, without changing anything
+	// in the InfraCluster
+	SyncStatus(machineScope machinescope.MachineScope) (bool, error)
 	// Exists check if the VirtualMachine of the provided Machine exists in the InfraCluster
 	Exists(machineName string, infraNamespace string) (bool, error)
+	// QuotaVersion returns a version of the ResourceQuotas of the infra namespace, which changes whenever
+	// the hard limits or the usage of any of the quotas change
+	QuotaVersion(infraNamespace string) (string, error)
+	// ExceededQuotas returns the ResourceQuotas of the infra namespace, with their resource, which don't have room
+	// left for the VirtualMachine of the provided Machine
+	ExceededQuotas(machineScope machinescope.MachineScope) ([]string, error)
+	// QuotaRejection returns the message of the rejection by a ResourceQuota of the pod of the VirtualMachineInstance,
+	// or of the PVCs of the DataVolumes, of the provided Machine, which happen after its VirtualMachine is created.
+	// It returns an empty message when nothing was rejected.
+	QuotaRejection(machineScope machinescope.MachineScope) (string, error)
+	// RestartRequired checks if the running VirtualMachineInstance of the provided Machine requests other CPU or memory
+	// than the Machine, so the VirtualMachine has to be restarted to apply them
+	RestartRequired(machineScope machinescope.MachineScope) (bool, error)
+	// Stop halts the VirtualMachine of the provided Machine, and returns true once its VirtualMachineInstance is gone
+	Stop(machineScope machinescope.MachineScope) (bool, error)
 }
 
 // manager is the struct which implement KubevirtVM interface
 type manager struct {
 	infraClusterClient infracluster.Client
+	// requeueAfter is the delay before re-checking an operation which is still in progress in the InfraCluster
+	requeueAfter time.Duration
 }
 
 // New creates provider vm instance
-func New(infraClusterClient infracluster.Client) KubevirtVM {
+func New(infraClusterClient infracluster.Client, requeueAfter time.Duration) KubevirtVM {
 	return &manager{
 		infraClusterClient: infraClusterClient,
+		requeueAfter:       requeueAfter,
+	}
+}
+
+// CredentialsKubevirtVMs returns the KubevirtVM of the InfraCluster of a credentials secret of the tenant-cluster
+type CredentialsKubevirtVMs func(ctx context.Context, secretNamespace string, secretName string) (KubevirtVM, error)
+
+// NewCredentialsKubevirtVMs returns the CredentialsKubevirtVMs operating the InfraClusters with the clients
+// built from the credentials secrets
+func NewCredentialsKubevirtVMs(clients *infracluster.CredentialsClients, requeueAfter time.Duration) CredentialsKubevirtVMs {
+	return func(ctx context.Context, secretNamespace string, secretName string) (KubevirtVM, error) {
+		infraClusterClient, err := clients.Get(ctx, secretNamespace, secretName)
+		if err != nil {
+			return nil, err
+		}
+		return New(infraClusterClient, requeueAfter), nil
 	}
 }
 
 func (m *manager) Create(machineScope machinescope.MachineScope, userData []byte) (ready bool, resultErr error) {
 	machineName := machineScope.GetMachineName()
 
-	fullUserData, err := addHostnameToUserData(userData, machineName)
-	if err != nil {
-		return false, err
+	// The VirtualMachines of a pool are created by KubeVirt, the Machine adopts one instead
+	if machineScope.GetVirtualMachinePoolName() != "" {
+		return m.adoptPoolVirtualMachine(machineScope, machineName)
+	}
+
+	// A pre-provisioned ignition secret in the infra-cluster is referenced as is, the userData isn't copied
+	referencesInfraSecret := machineScope.GetInfraIgnitionSecretName() != ""
+	viaSecret := !referencesInfraSecret && machineScope.IgnitionPropagatedViaSecret()
+	uploaded := !referencesInfraSecret && !viaSecret
+
+	var fullUserData []byte
+	if !referencesInfraSecret {
+		var err error
+		if fullUserData, err = buildUserData(machineScope, userData); err != nil {
+			return false, err
+		}
+	}
+	if !referencesInfraSecret {
+		checked, err := m.checkIgnitionSources(machineScope, fullUserData, machineName)
+		if err != nil {
+			return false, newOperationError(machineName, "Create", StageCheckIgnitionSource, err)
+		}
+		if !checked {
+			klog.Infof("%s: waiting for the check of the ignition sources", machineName)
+			return false, &machinecontroller.RequeueAfterError{RequeueAfter: m.requeueAfter}
+		}
+	}
+
+	var secretFromMachine *corev1.Secret
+	if viaSecret {
+		var err error
+		if secretFromMachine, err = machineScope.CreateIgnitionSecretFromMachine(fullUserData); err != nil {
+			return false, newOperationError(machineName, "Create", StageBuildIgnitionSecret, err)
+		}
 	}
 
-	secretFromMachine := machineScope.CreateIgnitionSecretFromMachine(fullUserData)
+	if err := m.ensureNetworkPolicy(machineScope); err != nil {
+		return false, newOperationError(machineName, "Create", StageEnsureNetworkPolicy, err)
+	}
 
-	if _, err := m.infraClusterClient.CreateSecret(context.Background(), secretFromMachine.Namespace, secretFromMachine); err != nil {
-		msg := fmt.Sprintf("%s: Error during Create: failed to create ignition secret in infraCluster, with error: %v", machineName, err)
-		klog.Errorf(msg)
-		return false, fmt.Errorf(msg)
+	if machineScope.PersistentVolumeAccessModeDetectionRequired() {
+		m.selectAccessMode(machineScope)
 	}
 
 	virtualMachineFromMachine, err := machineScope.CreateVirtualMachineFromMachine()
 	if err != nil {
-		msg := fmt.Sprintf("%s: Error during Create: failed to build Virtual Machine struct, with error: %v", machineName, err)
-		klog.Errorf(msg)
-		return false, fmt.Errorf(msg)
+		return false, newOperationError(machineName, "Create", StageBuildVirtualMachine, err)
+	}
+	var ignitionDataVolumeName string
+	if uploaded {
+		var ignitionUploaded bool
+		if ignitionDataVolumeName, ignitionUploaded, err = m.uploadIgnition(machineScope, virtualMachineFromMachine, fullUserData, machineName); err != nil {
+			return false, newOperationError(machineName, "Create", StageUploadIgnition, err)
+		}
+		if !ignitionUploaded {
+			return false, &machinecontroller.RequeueAfterError{RequeueAfter: m.requeueAfter}
+		}
+	}
+	if secretFromMachine != nil {
+		useIgnitionSecret(virtualMachineFromMachine, secretFromMachine.Name)
+	}
+	if m.infraClusterClient.GetCapabilities().LiveUpdate {
+		useHotplugResources(virtualMachineFromMachine)
+	}
+	// A VirtualMachine pre-staged by the infra admins is adopted instead of created
+	if machineScope.GetExistingVMName() != "" {
+		return m.adoptExistingVirtualMachine(machineScope, virtualMachineFromMachine, secretFromMachine, ignitionDataVolumeName, machineName)
+	}
+	if ignitionDataVolumeName != "" {
+		useIgnitionDataVolume(virtualMachineFromMachine, ignitionDataVolumeName)
+	}
+	if err := m.resolveDataSource(machineScope, virtualMachineFromMachine); err != nil {
+		return false, newOperationError(machineName, "Create", StageResolveDataSource, err)
+	}
+	if err := m.verifySourceImage(machineScope, virtualMachineFromMachine); err != nil {
+		return false, newOperationError(machineName, "Create", StageVerifySourceImage, err)
+	}
+	// A boot volume cloned ahead of time by the warm pool spares the VirtualMachine the wait for its clone
+	warmBootVolume := m.claimWarmBootVolumeOrClone(machineScope, virtualMachineFromMachine, machineName)
+
+	// The ignition secret and the Virtual Machine are created concurrently, the VirtualMachineInstance
+	// waits for its secret volume to be available. The steps an interrupted creation completed are skipped.
+	createSecret := viaSecret && !machineScope.CreateStepDone(machinescope.CreateStepSecretCreated)
+	var secretCreated bool
+	var secretErr error
+	var wg sync.WaitGroup
+	if createSecret {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			secretCreated, secretErr = m.createIgnitionSecret(secretFromMachine, machineName)
+		}()
+	}
+	createdVM, vmCreated, err := m.createVirtualMachine(machineScope, virtualMachineFromMachine, machineName)
+	wg.Wait()
+
+	if secretErr != nil {
+		if vmCreated {
+			// Without its ignition the Virtual Machine would never join the cluster, and an existing
+			// Virtual Machine isn't created again - delete it so the next Create retries both. A Virtual
+			// Machine an interrupted creation already created is kept, the next Create resumes it.
+			if deleteErr := m.infraClusterClient.DeleteVirtualMachine(machineContext(machineName), createdVM.Namespace, createdVM.Name, &k8smetav1.DeleteOptions{}); deleteErr != nil {
+				klog.Errorf("%s: failed to delete the Virtual Machine without ignition secret, with error: %v", machineName, deleteErr)
+			}
+		}
+		return false, newOperationError(machineName, "Create", StageCreateIgnitionSecret, secretErr)
 	}
-
-	createdVM, err := m.infraClusterClient.CreateVirtualMachine(context.Background(), virtualMachineFromMachine.Namespace, virtualMachineFromMachine)
 	if err != nil {
-		msg := fmt.Sprintf("%s: Error during Create: failed to create Virtual Machine in infraCluster, with error: %v", machineName, err)
-		klog.Errorf(msg)
-		return false, fmt.Errorf(msg)
+		// No Virtual Machine owns the new ignition secret yet, it would be left behind if the Machine is deleted
+		if secretCreated {
+			if deleteErr := m.infraClusterClient.DeleteSecret(machineContext(machineName), secretFromMachine.Namespace, secretFromMachine.Name); deleteErr != nil && !errors.IsNotFound(deleteErr) {
+				klog.Errorf("%s: failed to delete the ignition secret of the Virtual Machine which failed to be created, with error: %v", machineName, deleteErr)
+			}
+		}
+		return false, newOperationError(machineName, "Create", StageCreateVirtualMachine, err)
 	}
 
 	klog.Infof("%s: VirtualMachine was created in infracluster for the Machine", machineName)
+	if createSecret {
+		recordCreateStep(machineScope, machinescope.CreateStepSecretCreated, machineName)
+	}
+	recordCreateStep(machineScope, machinescope.CreateStepVMCreated, machineName)
+	// The versions of the ignition secret of a former VirtualMachine of the Machine are no longer in use
+	if createSecret && machineScope.IgnitionSecretVersioned() {
+		m.collectIgnitionSecrets(createdVM.Namespace, createdVM.Name, createdVM, machineName)
+	}
+	if viaSecret {
+		if err := m.adoptIgnitionSecret(createdVM, secretFromMachine.Name, machineName); err != nil {
+			klog.Errorf("%s: failed to set the VirtualMachine as the owner of its ignition secret %s, with error: %v", machineName, secretFromMachine.Name, err)
+		}
+	}
+	if ignitionDataVolumeName != "" {
+		if err := m.adoptIgnitionDataVolume(createdVM, ignitionDataVolumeName, machineName); err != nil {
+			klog.Errorf("%s: failed to set the VirtualMachine as the owner of its ignition DataVolume %s, with error: %v", machineName, ignitionDataVolumeName, err)
+		}
+	}
+	if warmBootVolume != nil {
+		if err := m.adoptWarmBootVolume(createdVM, warmBootVolume, machineName); err != nil {
+			klog.Errorf("%s: failed to set the VirtualMachine as the owner of its DataVolume %s, with error: %v", machineName, warmBootVolume.Name, err)
+		}
+	}
+
+	ready, err = m.syncMachine(*createdVM, machineScope, machineName, "Create")
+	if err == nil {
+		recordCreateStep(machineScope, machinescope.CreateStepSynced, machineName)
+	}
+	return ready, err
+}
+
+// createVirtualMachine creates the Virtual Machine, or returns the Virtual Machine an interrupted creation
+// of the Machine already created. It returns whether this call created the Virtual Machine.
+func (m *manager) createVirtualMachine(machineScope machinescope.MachineScope, virtualMachineFromMachine *kubevirtapiv1.VirtualMachine, machineName string) (*kubevirtapiv1.VirtualMachine, bool, error) {
+	if machineScope.CreateStepDone(machinescope.CreateStepVMCreated) {
+		existingVM, err := m.getInraClusterVM(virtualMachineFromMachine.Name, virtualMachineFromMachine.Namespace)
+		if err == nil {
+			klog.Infof("%s: VirtualMachine was already created in infracluster for the Machine - resume its creation", machineName)
+			return existingVM, false, nil
+		}
+		if !errors.IsNotFound(err) {
+			return nil, false, err
+		}
+	}
 
-	return createdVM.Status.Ready, m.syncMachine(*createdVM, machineScope, machineName, "Create")
+	createdVM, err := m.infraClusterClient.CreateVirtualMachine(machineContext(machineName), virtualMachineFromMachine.Namespace, virtualMachineFromMachine)
+	if errors.IsAlreadyExists(err) {
+		// The creation may have been interrupted before its step was recorded
+		if existingVM, getErr := m.getInraClusterVM(virtualMachineFromMachine.Name, virtualMachineFromMachine.Namespace); getErr == nil && createdBy(existingVM, virtualMachineFromMachine) {
+			klog.Infof("%s: VirtualMachine was already created in infracluster for the Machine - resume its creation", machineName)
+			return existingVM, false, nil
+		}
+	}
+	return createdVM, err == nil, err
+}
+
+// recordCreateStep records the completed step of the creation on the Machine. The steps only spare repeating
+// them, a step which isn't recorded is repeated, so a failure to persist it doesn't fail the creation.
+func recordCreateStep(machineScope machinescope.MachineScope, step machinescope.CreateStep, machineName string) {
+	if err := machineScope.SetCreateStepDone(step); err != nil {
+		klog.Errorf("%s: failed to record the %s step of the creation, with error: %v", machineName, step, err)
+	}
+}
+
+// createdBy returns true if the existing Virtual Machine was created for the Machine rather than by someone else, or
+// for another Machine, with the same name: it carries all the labels of the Virtual Machine built from the Machine,
+// and the UID of the Machine
+func createdBy(existingVM, virtualMachineFromMachine *kubevirtapiv1.VirtualMachine) bool {
+	machineUID := virtualMachineFromMachine.Annotations[machinescope.MachineUIDAnnotation]
+	if machineUID == "" || existingVM.Annotations[machinescope.MachineUIDAnnotation] != machineUID {
+		return false
+	}
+	for key, value := range virtualMachineFromMachine.Labels {
+		if existingValue, ok := existingVM.Labels[key]; !ok || existingValue != value {
+			return false
+		}
+	}
+	return true
+}
+
+// ensureNetworkPolicy creates the NetworkPolicy of the tenant-cluster if the Machine requires it,
+// or restores its spec if it was changed
+func (m *manager) ensureNetworkPolicy(machineScope machinescope.MachineScope) error {
+	networkPolicy := machineScope.CreateNetworkPolicyFromMachine()
+	if networkPolicy == nil {
+		return nil
+	}
+	existingNetworkPolicy, err := m.infraClusterClient.GetNetworkPolicy(context.Background(), networkPolicy.Namespace, networkPolicy.Name)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		if _, err := m.infraClusterClient.CreateNetworkPolicy(machineContext(machineScope.GetMachineName()), networkPolicy.Namespace, networkPolicy); err != nil && !errors.IsAlreadyExists(err) {
+			return err
+		}
+		klog.Infof("%s: NetworkPolicy %s was created in infracluster", machineScope.GetMachineName(), networkPolicy.Name)
+		return nil
+	}
+	if equality.Semantic.DeepEqual(existingNetworkPolicy.Spec, networkPolicy.Spec) {
+		return nil
+	}
+	existingNetworkPolicy.Spec = networkPolicy.Spec
+	if _, err := m.infraClusterClient.UpdateNetworkPolicy(machineContext(machineScope.GetMachineName()), existingNetworkPolicy.Namespace, existingNetworkPolicy); err != nil {
+		return err
+	}
+	klog.Infof("%s: NetworkPolicy %s was restored in infracluster", machineScope.GetMachineName(), networkPolicy.Name)
+	return nil
+}
+
+// selectAccessMode selects the access mode of the boot volume by the access modes its storage class supports,
+// failing to read them leaves the default access mode
+func (m *manager) selectAccessMode(machineScope machinescope.MachineScope) {
+	storageClassName, accessModes, err := m.infraClusterClient.GetStorageClassAccessModes(context.Background(), machineScope.GetStorageClassName())
+	if err != nil {
+		klog.Errorf("%s: failed to get the access modes of the storage class, with error: %v", machineScope.GetMachineName(), err)
+	}
+	machineScope.SelectPersistentVolumeAccessMode(storageClassName, accessModes)
+}
+
+// keepDataVolumeAccessModes sets the access modes the existing DataVolumes were created with,
+// DataVolumes are not updated with the VirtualMachine
+func keepDataVolumeAccessModes(vm *kubevirtapiv1.VirtualMachine, existingVM *kubevirtapiv1.VirtualMachine) {
+	for i := range vm.Spec.DataVolumeTemplates {
+		for _, existing := range existingVM.Spec.DataVolumeTemplates {
+			if existing.Name == vm.Spec.DataVolumeTemplates[i].Name && existing.Spec.PVC != nil && vm.Spec.DataVolumeTemplates[i].Spec.PVC != nil {
+				vm.Spec.DataVolumeTemplates[i].Spec.PVC.AccessModes = existing.Spec.PVC.AccessModes
+			}
+		}
+	}
 }
 
 func addHostnameToUserData(src []byte, hostname string) ([]byte, error) {
+	return addFileToUserData(src, "/etc/hostname", fmt.Sprintf("data:,%s", hostname))
+}
+
+// addInstanceMetadataToUserData writes the instance metadata as a json file, for in-guest tooling to consume
+func addInstanceMetadataToUserData(src []byte, metadata map[string]string) ([]byte, error) {
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, err
+	}
+	return addFileToUserData(src, instanceMetadataPath,
+		fmt.Sprintf("data:text/plain;charset=utf-8;base64,%s", base64.StdEncoding.EncodeToString(metadataJSON)))
+}
+
+// addAfterburnMetadataToUserData writes the attributes as an environment file, in the format of the attributes
+// file of afterburn, for in-guest units to source
+func addAfterburnMetadataToUserData(src []byte, attributes map[string]string) ([]byte, error) {
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var env strings.Builder
+	for _, key := range keys {
+		fmt.Fprintf(&env, "%s=%s\n", key, attributes[key])
+	}
+	return addFileToUserData(src, afterburnMetadataPath,
+		fmt.Sprintf("data:text/plain;charset=utf-8;base64,%s", base64.StdEncoding.EncodeToString([]byte(env.String()))))
+}
+
+// addFileToUserData appends a file with the given data url source to the storage section of the ignition
+func addFileToUserData(src []byte, path string, source string) ([]byte, error) {
 	var dataMap map[string]interface{}
-	json.Unmarshal([]byte(src), &dataMap)
+	if err := json.Unmarshal(src, &dataMap); err != nil {
+		return nil, fmt.Errorf("failed to parse userData, with error: %v", err)
+	}
 	if _, ok := dataMap["storage"]; !ok {
 		dataMap["storage"] = map[string]interface{}{}
 	}
 	storage := (dataMap["storage"]).(map[string]interface{})
-	if _, ok := storage["files"]; !ok {
-		storage["files"] = []map[string]interface{}{}
+	var files []interface{}
+	if existingFiles, ok := storage["files"].([]interface{}); ok {
+		files = existingFiles
 	}
 	newFile := map[string]interface{}{
 		"filesystem": "root",
-		"path":       "/etc/hostname",
+		"path":       path,
 		"mode":       420,
 	}
 	newFile["contents"] = map[string]interface{}{
-		"source": fmt.Sprintf("data:,%s", hostname),
+		"source": source,
 	}
-	storage["files"] = append(storage["files"].([]map[string]interface{}), newFile)
+	storage["files"] = append(files, newFile)
 	result, err := json.Marshal(dataMap)
 	if err != nil {
 		return nil, err
@@ -108,68 +412,297 @@ func addHostnameToUserData(src []byte, hostname string) ([]byte, error) {
 	return result, nil
 }
 
+// mergeUserData returns an ignition of the same spec version as src, which only merges the ignition
+// from the source, trusting the CA bundle of the source
+func mergeUserData(src []byte, source *kubevirtproviderv1alpha1.IgnitionMergeSource) ([]byte, error) {
+	var dataMap map[string]interface{}
+	if err := json.Unmarshal(src, &dataMap); err != nil {
+		return nil, fmt.Errorf("failed to parse userData, with error: %v", err)
+	}
+	ignition, _ := dataMap["ignition"].(map[string]interface{})
+	version, _ := ignition["version"].(string)
+	if version == "" {
+		return nil, fmt.Errorf("failed to get the ignition version of userData")
+	}
+	// The merge directive was named append before spec version 3
+	mergeKey := "merge"
+	if strings.HasPrefix(version, "2.") {
+		mergeKey = "append"
+	}
+
+	mergeIgnition := map[string]interface{}{
+		"version": version,
+		"config": map[string]interface{}{
+			mergeKey: []interface{}{
+				map[string]interface{}{"source": source.URL},
+			},
+		},
+	}
+	if source.CABundle != "" {
+		mergeIgnition["security"] = map[string]interface{}{
+			"tls": map[string]interface{}{
+				"certificateAuthorities": []interface{}{
+					map[string]interface{}{
+						"source": fmt.Sprintf("data:text/plain;charset=utf-8;base64,%s", base64.StdEncoding.EncodeToString([]byte(source.CABundle))),
+					},
+				},
+			},
+		}
+	}
+	return json.Marshal(map[string]interface{}{"ignition": mergeIgnition})
+}
+
+// rewriteUserDataSources sets the host of the remote ignitions merged or replaced by src to host
+func rewriteUserDataSources(src []byte, host string) ([]byte, error) {
+	var dataMap map[string]interface{}
+	if err := json.Unmarshal(src, &dataMap); err != nil {
+		return nil, fmt.Errorf("failed to parse userData, with error: %v", err)
+	}
+	for _, reference := range configReferences(dataMap) {
+		source, _ := reference["source"].(string)
+		sourceURL, err := url.Parse(source)
+		if err != nil || (sourceURL.Scheme != "http" && sourceURL.Scheme != "https") {
+			continue
+		}
+		sourceURL.Host = host
+		reference["source"] = sourceURL.String()
+	}
+	return json.Marshal(dataMap)
+}
+
+// remoteUserDataSources returns the http and https sources of the ignitions merged or replaced by src
+func remoteUserDataSources(src []byte) ([]string, error) {
+	var dataMap map[string]interface{}
+	if err := json.Unmarshal(src, &dataMap); err != nil {
+		return nil, fmt.Errorf("failed to parse userData, with error: %v", err)
+	}
+	var sources []string
+	for _, reference := range configReferences(dataMap) {
+		source, _ := reference["source"].(string)
+		sourceURL, err := url.Parse(source)
+		if err != nil || (sourceURL.Scheme != "http" && sourceURL.Scheme != "https") {
+			continue
+		}
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
+
+// configReferences returns the references of the ignitions merged or replaced by the ignition of dataMap
+func configReferences(dataMap map[string]interface{}) []map[string]interface{} {
+	ignition, _ := dataMap["ignition"].(map[string]interface{})
+	config, _ := ignition["config"].(map[string]interface{})
+	var references []interface{}
+	// The merge directive was named append before spec version 3
+	for _, mergeKey := range []string{"merge", "append"} {
+		if merged, ok := config[mergeKey].([]interface{}); ok {
+			references = append(references, merged...)
+		}
+	}
+	if replaced, ok := config["replace"]; ok {
+		references = append(references, replaced)
+	}
+	var result []map[string]interface{}
+	for _, reference := range references {
+		if reference, ok := reference.(map[string]interface{}); ok {
+			result = append(result, reference)
+		}
+	}
+	return result
+}
+
+// keepNetworkData copies the network data of the config drive of the existing VirtualMachine to vm
+func keepNetworkData(vm *kubevirtapiv1.VirtualMachine, existingVM *kubevirtapiv1.VirtualMachine) {
+	if vm.Spec.Template == nil || existingVM.Spec.Template == nil {
+		return
+	}
+	networkData := ""
+	for _, volume := range existingVM.Spec.Template.Spec.Volumes {
+		if volume.CloudInitConfigDrive != nil {
+			networkData = volume.CloudInitConfigDrive.NetworkData
+		}
+	}
+	for _, volume := range vm.Spec.Template.Spec.Volumes {
+		if volume.CloudInitConfigDrive != nil {
+			volume.CloudInitConfigDrive.NetworkData = networkData
+		}
+	}
+}
+
 func (m *manager) Delete(machineScope machinescope.MachineScope) error {
 	machineName := machineScope.GetMachineName()
 
 	virtualMachineFromMachine, err := machineScope.CreateVirtualMachineFromMachine()
 	if err != nil {
-		msg := fmt.Sprintf("%s: Error during Delete: failed to build Virtual Machine struct, with error: %v", machineName, err)
-		klog.Errorf(msg)
-		return fmt.Errorf(msg)
+		return newOperationError(machineName, "Delete", StageBuildVirtualMachine, err)
 	}
 
 	existingVM, err := m.getInraClusterVM(virtualMachineFromMachine.GetName(), virtualMachineFromMachine.GetNamespace())
 	if err != nil {
 		if errors.IsNotFound(err) {
 			klog.Infof("%s: Virtual Machine does not exist (already deleted - return)", machineName)
+			// The ignition uploaded before the Virtual Machine was created isn't owned by it
+			if machineScope.GetInfraIgnitionSecretName() == "" && !machineScope.IgnitionPropagatedViaSecret() {
+				if err := m.deleteIgnitionDataVolume(machineScope, machineName); err != nil {
+					return newOperationError(machineName, "Delete", StageDeleteIgnitionDataVolume, err)
+				}
+			}
 			return nil
 		}
 
-		msg := fmt.Sprintf("%s: Error during Delete: failed to get Virtual Machine from infraCluster, with error: %v", machineName, err)
-		klog.Errorf(msg)
-		return fmt.Errorf(msg)
+		return newOperationError(machineName, "Delete", StageGetVirtualMachine, err)
+	}
+	if err := verifyVirtualMachine(machineScope, existingVM); err != nil {
+		return newOperationError(machineName, "Delete", StageVerifyVirtualMachine, err)
+	}
+	deletePolicy, err := machineScope.GetBootVolumeDeletePolicy()
+	if err != nil {
+		return newOperationError(machineName, "Delete", StageBuildVirtualMachine, err)
+	}
+
+	vmiIsGone, err := m.stopVirtualMachine(existingVM, machineName)
+	if err != nil {
+		return newOperationError(machineName, "Delete", StageStopVirtualMachine, err)
+	}
+	if !vmiIsGone {
+		klog.Infof("%s: VirtualMachineInstance is still shutting down - requeue", machineName)
+		return &machinecontroller.RequeueAfterError{RequeueAfter: m.requeueAfter}
+	}
+
+	if deletePolicy == kubevirtproviderv1alpha1.BootVolumeDeletePolicyRetain {
+		if err := m.retainBootVolume(existingVM, machineName); err != nil {
+			return newOperationError(machineName, "Delete", StageRetainBootVolume, err)
+		}
 	}
 
 	gracePeriod := int64(10)
-	if err := m.infraClusterClient.DeleteVirtualMachine(context.Background(),
+	if err := m.infraClusterClient.DeleteVirtualMachine(machineContext(machineName),
 		existingVM.GetNamespace(),
 		existingVM.GetName(),
 		&k8smetav1.DeleteOptions{GracePeriodSeconds: &gracePeriod}); err != nil {
-		msg := fmt.Sprintf("%s: Error during Delete: failed to delete Virtual Machine in infraCluster, with error: %v", machineName, err)
-		klog.Errorf(msg)
-		return fmt.Errorf(msg)
+		return newOperationError(machineName, "Delete", StageDeleteVirtualMachine, err)
 	}
 
 	klog.Infof("%s: VirtualMachine was deleted in infracluster for the Machine", machineName)
+	// The uploaded ignition is owned by the VirtualMachine, unless setting it as the owner failed
+	if machineScope.GetInfraIgnitionSecretName() == "" && !machineScope.IgnitionPropagatedViaSecret() {
+		if err := m.deleteIgnitionDataVolume(machineScope, machineName); err != nil {
+			return newOperationError(machineName, "Delete", StageDeleteIgnitionDataVolume, err)
+		}
+	}
+	if machineScope.IgnitionSecretVersioned() {
+		m.collectIgnitionSecrets(existingVM.Namespace, existingVM.Name, nil, machineName)
+	}
 
 	return nil
 }
 
+// verifyVirtualMachine returns a VirtualMachineMismatchError if the providerID of the Machine points to the
+// Virtual Machine, but the Virtual Machine doesn't have the UID the Machine was first synced with. The recreated
+// Virtual Machine isn't adopted, the Machine is marked with the MachineRequiresReplacement condition instead.
+func verifyVirtualMachine(machineScope machinescope.MachineScope, vm *kubevirtapiv1.VirtualMachine) error {
+	machine := machineScope.GetMachine()
+	if machine.Spec.ProviderID == nil || *machine.Spec.ProviderID != FormatProviderID(vm.Namespace, vm.Name) {
+		return nil
+	}
+	vmID := machineScope.GetVirtualMachineUID()
+	if vmID == "" || vmID == string(vm.UID) {
+		return nil
+	}
+	mismatchErr := &VirtualMachineMismatchError{ProviderID: *machine.Spec.ProviderID, ExpectedUID: vmID, UID: string(vm.UID)}
+	if err := machineScope.SetMachineRequiresReplacementCondition(mismatchErr); err != nil {
+		klog.Errorf("%s: failed to set the machine requires replacement condition, with error: %v", machine.Name, err)
+	}
+	return mismatchErr
+}
+
+// stopVirtualMachine halts the VirtualMachine (virtctl stop semantics), which triggers a guest-initiated
+// shutdown of its VirtualMachineInstance, and returns whether the VirtualMachineInstance is already gone.
+func (m *manager) stopVirtualMachine(vm *kubevirtapiv1.VirtualMachine, machineName string) (bool, error) {
+	if vm.Spec.RunStrategy == nil || *vm.Spec.RunStrategy != kubevirtapiv1.RunStrategyHalted {
+		runHalted := kubevirtapiv1.RunStrategyHalted
+		vm.Spec.RunStrategy = &runHalted
+		vm.Spec.Running = nil
+		if _, err := m.infraClusterClient.UpdateVirtualMachine(machineContext(machineName), vm.Namespace, vm); err != nil {
+			return false, err
+		}
+		klog.Infof("%s: VirtualMachine was stopped in infracluster", machineName)
+	}
+
+	if _, err := m.infraClusterClient.GetVirtualMachineInstance(context.Background(), vm.Namespace, vm.Name, &k8smetav1.GetOptions{}); err != nil {
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}
+	return false, nil
+}
+
 func (m *manager) Update(machineScope machinescope.MachineScope) (bool, bool, error) {
 	machineName := machineScope.GetMachineName()
 
+	mode, err := machineScope.GetReconciliationMode()
+	if err != nil {
+		return false, false, newOperationError(machineName, "Update", StageBuildVirtualMachine, err)
+	}
+	if mode == kubevirtproviderv1alpha1.ReconciliationModeNone {
+		klog.Infof("%s: reconciliation of the Machine is disabled - skip the update", machineName)
+		return false, true, nil
+	}
+
 	virtualMachineFromMachine, err := machineScope.CreateVirtualMachineFromMachine()
 	if err != nil {
-		msg := fmt.Sprintf("%s: Error during Update: failed to build Virtual Machine struct, with error: %v", machineName, err)
-		klog.Errorf(msg)
-		return false, false, fmt.Errorf(msg)
+		return false, false, newOperationError(machineName, "Update", StageBuildVirtualMachine, err)
+	}
+
+	if err := m.ensureNetworkPolicy(machineScope); err != nil {
+		return false, false, newOperationError(machineName, "Update", StageEnsureNetworkPolicy, err)
 	}
 
 	existingVM, err := m.getInraClusterVM(virtualMachineFromMachine.GetName(), virtualMachineFromMachine.GetNamespace())
 	if err != nil {
-		msg := fmt.Sprintf("%s: Error during Update: failed to get Virtual Machine from infraCluster, with error: %v", machineName, err)
-		klog.Errorf(msg)
-		return false, false, fmt.Errorf(msg)
+		// A Virtual Machine which was just created may not be visible yet, requeue within its window
+		if errors.IsNotFound(err) && machineScope.UpdateAllowed(machinescope.UpdatePolicy{Window: vmNotFoundWindow}) {
+			klog.Infof("%s: Virtual Machine was not found yet - requeue", machineName)
+			return false, false, &machinecontroller.RequeueAfterError{RequeueAfter: m.requeueAfter}
+		}
+		return false, false, newOperationError(machineName, "Update", StageGetVirtualMachine, err)
+	}
+	if err := verifyVirtualMachine(machineScope, existingVM); err != nil {
+		return false, false, newOperationError(machineName, "Update", StageVerifyVirtualMachine, err)
+	}
+
+	if mode == kubevirtproviderv1alpha1.ReconciliationModeStatusOnly {
+		klog.Infof("%s: the spec of the VirtualMachine isn't reconciled - only sync the Machine", machineName)
+		ready, err := m.syncMachine(*existingVM, machineScope, machineName, "Update")
+		return false, ready, err
+	}
+
+	// The uploaded ignition is not known to the Machine, keep the DataVolume the VirtualMachine mounts
+	keepIgnitionDataVolume(virtualMachineFromMachine, existingVM)
+	// The version of the ignition secret is not known to the Machine, keep the one the VirtualMachine references
+	if machineScope.IgnitionSecretVersioned() {
+		keepIgnitionSecret(virtualMachineFromMachine, existingVM)
+	}
+	// The claimed addresses are not known to the Machine, keep the network data the VirtualMachine was created with
+	keepNetworkData(virtualMachineFromMachine, existingVM)
+	// The boot volume claimed from the warm pool is not known to the Machine, keep the VirtualMachine booting from it
+	keepWarmBootVolume(virtualMachineFromMachine, existingVM)
+	// The selected access mode is not known to the Machine, keep the one the VirtualMachine was created with
+	if machineScope.PersistentVolumeAccessModeDetectionRequired() {
+		keepDataVolumeAccessModes(virtualMachineFromMachine, existingVM)
+	}
+	// CPU and memory increases are hot-plugged when the infra-cluster rolls out live updates
+	if m.infraClusterClient.GetCapabilities().LiveUpdate {
+		useHotplugResources(virtualMachineFromMachine)
 	}
 
 	previousResourceVersion := existingVM.ResourceVersion
 	virtualMachineFromMachine.ObjectMeta.ResourceVersion = previousResourceVersion
 
-	updatedVM, err := m.infraClusterClient.UpdateVirtualMachine(context.Background(), virtualMachineFromMachine.Namespace, virtualMachineFromMachine)
+	updatedVM, err := m.infraClusterClient.UpdateVirtualMachine(machineContext(machineName), virtualMachineFromMachine.Namespace, virtualMachineFromMachine)
 	if err != nil {
-		msg := fmt.Sprintf("%s: Error during Update: failed to update Virtual Machine in infraCluster, with error: %v", machineName, err)
-		klog.Errorf(msg)
-		return false, false, fmt.Errorf(msg)
+		return false, false, newOperationError(machineName, "Update", StageUpdateVirtualMachine, err)
 	}
 	currentResourceVersion := updatedVM.ResourceVersion
 
@@ -183,31 +716,60 @@ func (m *manager) Update(machineScope machinescope.MachineScope) (bool, bool, er
 	klog.Infof("%s: VirtualMachine update was called in infracluster for the Machine, result: %s (oldVersion = %s, newVersion = %s)",
 		machineName, updateText, previousResourceVersion, currentResourceVersion)
 
-	err = m.syncMachine(*updatedVM, machineScope, machineName, "Update")
+	ready, err := m.syncMachine(*updatedVM, machineScope, machineName, "Update")
 
-	return wasUpdated, updatedVM.Status.Ready, err
+	return wasUpdated, ready, err
 }
 
-func (m *manager) syncMachine(vm kubevirtapiv1.VirtualMachine, machineScope machinescope.MachineScope, machineName string, operation string) error {
+// syncMachine syncs the Machine with the VirtualMachine and, once it is ready, its VirtualMachineInstance.
+// It returns whether the VirtualMachine is ready, so the caller requeues until the VirtualMachineInstance is synced.
+func (m *manager) syncMachine(vm kubevirtapiv1.VirtualMachine, machineScope machinescope.MachineScope, machineName string, operation string) (bool, error) {
 	var vmi *kubevirtapiv1.VirtualMachineInstance
 	if vm.Status.Ready {
 		var err error
 		vmi, err = m.infraClusterClient.GetVirtualMachineInstance(context.Background(), vm.Namespace, vm.Name, &k8smetav1.GetOptions{})
 		if err != nil {
-			msg := fmt.Sprintf("%s: Error during %s: failed to get vmi of the Machine, with error: %v", machineName, operation, err)
-			klog.Errorf(msg)
-			return fmt.Errorf(msg)
+			if !errors.IsNotFound(err) {
+				return false, newOperationError(machineName, operation, StageGetVirtualMachineInstance, err)
+			}
+			// The VirtualMachineInstance is still being created (or recreated), sync what is available
+			klog.Infof("%s: VirtualMachineInstance of the Machine doesn't exist yet", machineName)
+			vmi = nil
 		}
 	}
 
+	if vmi != nil && machineScope.GuestAgentRequired() && !utils.IsGuestAgentConnected(vmi) {
+		// The addresses of the Machine are synced only once the guest agent is connected
+		klog.Infof("%s: guest agent of the VirtualMachineInstance isn't connected yet", machineName)
+		vmi = nil
+	}
+
 	providerID := FormatProviderID(vm.GetNamespace(), vm.GetName())
 
 	if err := machineScope.SyncMachine(vm, vmi, providerID); err != nil {
-		msg := fmt.Sprintf("%s: Error during %s: failed to sync the Machine, with error: %v", machineName, operation, err)
-		klog.Errorf(msg)
-		return fmt.Errorf(msg)
+		if machinescope.IsStaleSync(err) {
+			klog.Infof("%s: skipping the sync of the Machine - requeue, %v", machineName, err)
+			return false, &machinecontroller.RequeueAfterError{RequeueAfter: m.requeueAfter}
+		}
+		return false, newOperationError(machineName, operation, StageSyncMachine, err)
 	}
-	return nil
+	return vm.Status.Ready && vmi != nil, nil
+}
+
+func (m *manager) SyncStatus(machineScope machinescope.MachineScope) (bool, error) {
+	machineName := machineScope.GetMachineName()
+	virtualMachineName, err := machineScope.GetVirtualMachineName()
+	if err != nil {
+		return false, newOperationError(machineName, "SyncStatus", StageBuildVirtualMachine, err)
+	}
+	existingVM, err := m.getInraClusterVM(virtualMachineName, machineScope.GetInfraNamespace())
+	if err != nil {
+		return false, newOperationError(machineName, "SyncStatus", StageGetVirtualMachine, err)
+	}
+	if err := verifyVirtualMachine(machineScope, existingVM); err != nil {
+		return false, newOperationError(machineName, "SyncStatus", StageVerifyVirtualMachine, err)
+	}
+	return m.syncMachine(*existingVM, machineScope, machineName, "SyncStatus")
 }
 
 func (m *manager) Exists(machineName string, infraNamespace string) (bool, error) {
@@ -218,14 +780,78 @@ func (m *manager) Exists(machineName string, infraNamespace string) (bool, error
 			klog.Infof("%s: Virtual Machine of this Machine does not exist", machineName)
 			return false, nil
 		}
-		msg := fmt.Sprintf("%s: Error during Exists: failed to get vm of the Machine, with error: %v", machineName, err)
-		klog.Errorf(msg)
-		return false, fmt.Errorf(msg)
+		return false, newOperationError(machineName, "Exists", StageGetVirtualMachine, err)
 	}
 
 	return true, nil
 }
 
+func (m *manager) RestartRequired(machineScope machinescope.MachineScope) (bool, error) {
+	machineName := machineScope.GetMachineName()
+
+	virtualMachineFromMachine, err := machineScope.CreateVirtualMachineFromMachine()
+	if err != nil {
+		return false, newOperationError(machineName, "RestartRequired", StageBuildVirtualMachine, err)
+	}
+	liveUpdate := m.infraClusterClient.GetCapabilities().LiveUpdate
+	if liveUpdate {
+		useHotplugResources(virtualMachineFromMachine)
+	}
+
+	vmi, err := m.infraClusterClient.GetVirtualMachineInstance(context.Background(), virtualMachineFromMachine.Namespace, virtualMachineFromMachine.Name, &k8smetav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, newOperationError(machineName, "RestartRequired", StageGetVirtualMachineInstance, err)
+	}
+
+	requested := &virtualMachineFromMachine.Spec.Template.Spec.Domain
+	requestedCPU, requestedMemory := domainResources(requested)
+	runningCPU, runningMemory := domainResources(&vmi.Spec.Domain)
+	if requestedCPU.Cmp(runningCPU) == 0 && requestedMemory.Cmp(runningMemory) == 0 {
+		return false, nil
+	}
+	klog.Infof("%s: VirtualMachineInstance runs with %s CPU and %s memory, the Machine requests %s CPU and %s memory",
+		machineName, runningCPU.String(), runningMemory.String(), requestedCPU.String(), requestedMemory.String())
+
+	if !liveUpdate || !liveResizePossible(requested, &vmi.Spec.Domain) {
+		return true, nil
+	}
+	// The increase is hot-plugged, unless it exceeds the limits KubeVirt set when the VirtualMachineInstance started
+	existingVM, err := m.getInraClusterVM(virtualMachineFromMachine.GetName(), virtualMachineFromMachine.GetNamespace())
+	if err != nil {
+		return false, newOperationError(machineName, "RestartRequired", StageGetVirtualMachine, err)
+	}
+	return vmRestartRequired(existingVM), nil
+}
+
+func (m *manager) Stop(machineScope machinescope.MachineScope) (bool, error) {
+	machineName := machineScope.GetMachineName()
+
+	virtualMachineFromMachine, err := machineScope.CreateVirtualMachineFromMachine()
+	if err != nil {
+		return false, newOperationError(machineName, "Stop", StageBuildVirtualMachine, err)
+	}
+
+	existingVM, err := m.getInraClusterVM(virtualMachineFromMachine.GetName(), virtualMachineFromMachine.GetNamespace())
+	if err != nil {
+		return false, newOperationError(machineName, "Stop", StageGetVirtualMachine, err)
+	}
+
+	vmiIsGone, err := m.stopVirtualMachine(existingVM, machineName)
+	if err != nil {
+		return false, newOperationError(machineName, "Stop", StageStopVirtualMachine, err)
+	}
+	return vmiIsGone, nil
+}
+
+// machineContext returns the context of the mutating requests to the infra-cluster made for the Machine, which
+// the audit of the infra-cluster client records
+func machineContext(machineName string) context.Context {
+	return infracluster.WithRequester(context.Background(), machineName)
+}
+
 func (m *manager) getInraClusterVM(vmName, vmNamespace string) (*kubevirtapiv1.VirtualMachine, error) {
 	return m.infraClusterClient.GetVirtualMachine(context.Background(), vmNamespace, vmName, &k8smetav1.GetOptions{})
 }
@@ -236,3 +862,16 @@ func (m *manager) getInraClusterVM(vmName, vmNamespace string) (*kubevirtapiv1.V
 func FormatProviderID(namespace, name string) string {
 	return fmt.Sprintf(idFormat, namespace, name)
 }
+
+// ParseProviderID returns the namespace and name of the VM from a provider ID
+// built by FormatProviderID
+func ParseProviderID(providerID string) (namespace, name string, err error) {
+	if !strings.HasPrefix(providerID, providerIDPrefix) {
+		return "", "", fmt.Errorf("providerID %q doesn't start with %q", providerID, providerIDPrefix)
+	}
+	parts := strings.Split(strings.TrimPrefix(providerID, providerIDPrefix), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("providerID %q isn't of the format %q", providerID, idFormat)
+	}
+	return parts[0], parts[1], nil
+}
@@ -1,31 +1,54 @@
 package kubevirt
 
 import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster"
 	mockInfraClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster/mock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
 	mockMachineScope "github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope/mock"
 	"github.com/openshift/cluster-api-provider-kubevirt/pkg/testutils"
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
 	"gotest.tools/assert"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	apierr "k8s.io/apimachinery/pkg/api/errors"
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/pointer"
 	kubevirtapiv1 "kubevirt.io/client-go/api/v1"
+	cdiv1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
 )
 
 const (
 	clusterNamespace = "kubevirt-actuator-cluster"
 	infraID          = "test-id-asdfg"
 	providerIDFmt    = "kubevirt://%s/%s"
+	requeueAfter     = 20 * time.Second
 )
 
 func TestCreate(t *testing.T) {
 	cases := []struct {
-		name        string
-		expectedErr string
-		expect      func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope)
+		name          string
+		expectedErr   string
+		expectedReady bool
+		// stepsDone are the steps recorded by an interrupted creation
+		stepsDone []machinescope.CreateStep
+		// expectedSteps are the steps recorded by the creation
+		expectedSteps []machinescope.CreateStep
+		expect        func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope)
 	}{
 		{
 			name: "Success",
@@ -36,13 +59,101 @@ func TestCreate(t *testing.T) {
 				ignitionSecret := testutils.StubIgnitionSecret()
 
 				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
-				mockMachineScope.EXPECT().CreateIgnitionSecretFromMachine([]byte(fmt.Sprintf(testutils.FullUserDataFmt, testutils.MachineName))).Return(ignitionSecret).Times(1)
+				mockMachineScope.EXPECT().GetInfraIgnitionSecretName().Return("").Times(1)
+				mockMachineScope.EXPECT().GetIgnitionSourceHost().Return("", nil).Times(1)
+				mockMachineScope.EXPECT().GetIgnitionMergeSource().Return(nil, nil).Times(1)
+				mockMachineScope.EXPECT().GetClusterProxy().Return(nil).Times(1)
+				mockMachineScope.EXPECT().HostnameInjectionEnabled().Return(true).Times(1)
+				mockMachineScope.EXPECT().NodeSmokeCheckRequired().Return(false).Times(1)
+				mockMachineScope.EXPECT().GetHostname().Return(testutils.MachineName, nil).Times(1)
+				mockMachineScope.EXPECT().GetInstanceMetadata().Return(nil).Times(1)
+				mockMachineScope.EXPECT().GetAfterburnMetadata().Return(nil).Times(2)
+				mockMachineScope.EXPECT().IgnitionPropagatedViaSecret().Return(true).Times(1)
+				mockMachineScope.EXPECT().CreateIgnitionSecretFromMachine([]byte(fmt.Sprintf(testutils.FullUserDataFmt, testutils.MachineName))).Return(ignitionSecret, nil).Times(1)
+				mockInfraClusterClient.EXPECT().CreateSecret(gomock.Any(), testutils.InfraNamespace, ignitionSecret).Return(&corev1.Secret{}, nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetSecret(gomock.Any(), testutils.InfraNamespace, ignitionSecret.Name).Return(ignitionSecret, nil).Times(1)
+				mockInfraClusterClient.EXPECT().UpdateSecret(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(&corev1.Secret{}, nil).Times(1)
+				mockMachineScope.EXPECT().CreateNetworkPolicyFromMachine().Return(nil).Times(1)
+				mockMachineScope.EXPECT().PersistentVolumeAccessModeDetectionRequired().Return(false).Times(1)
+				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vm, nil).Times(1)
+				mockInfraClusterClient.EXPECT().CreateVirtualMachine(gomock.Any(), testutils.InfraNamespace, vm).Return(vm, nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vmi, nil).Times(1)
+				mockMachineScope.EXPECT().GuestAgentRequired().Return(false).Times(1)
+				mockMachineScope.EXPECT().SyncMachine(*vm, vmi, fmt.Sprintf(providerIDFmt, testutils.InfraNamespace, testutils.MachineName)).Return(nil).Times(1)
+			},
+			expectedReady: true,
+			expectedSteps: []machinescope.CreateStep{machinescope.CreateStepSecretCreated, machinescope.CreateStepVMCreated, machinescope.CreateStepSynced},
+		},
+		{
+			name:      "Success resume an interrupted creation",
+			stepsDone: []machinescope.CreateStep{machinescope.CreateStepSecretCreated, machinescope.CreateStepVMCreated},
+			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope) {
+				vm := testutils.StubVirtualMachine(nil, nil, nil)
+				vm.Status.Ready = true
+				vmi := testutils.StubVirtualMachineInstance()
+				ignitionSecret := testutils.StubIgnitionSecret()
+
+				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
+				mockMachineScope.EXPECT().GetInfraIgnitionSecretName().Return("").Times(1)
+				mockMachineScope.EXPECT().GetIgnitionSourceHost().Return("", nil).Times(1)
+				mockMachineScope.EXPECT().GetIgnitionMergeSource().Return(nil, nil).Times(1)
+				mockMachineScope.EXPECT().GetClusterProxy().Return(nil).Times(1)
+				mockMachineScope.EXPECT().HostnameInjectionEnabled().Return(true).Times(1)
+				mockMachineScope.EXPECT().NodeSmokeCheckRequired().Return(false).Times(1)
+				mockMachineScope.EXPECT().GetHostname().Return(testutils.MachineName, nil).Times(1)
+				mockMachineScope.EXPECT().GetInstanceMetadata().Return(nil).Times(1)
+				mockMachineScope.EXPECT().GetAfterburnMetadata().Return(nil).Times(2)
+				mockMachineScope.EXPECT().IgnitionPropagatedViaSecret().Return(true).Times(1)
+				mockMachineScope.EXPECT().CreateIgnitionSecretFromMachine(gomock.Any()).Return(ignitionSecret, nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetSecret(gomock.Any(), testutils.InfraNamespace, ignitionSecret.Name).Return(ignitionSecret, nil).Times(1)
+				mockInfraClusterClient.EXPECT().UpdateSecret(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(&corev1.Secret{}, nil).Times(1)
+				mockMachineScope.EXPECT().CreateNetworkPolicyFromMachine().Return(nil).Times(1)
+				mockMachineScope.EXPECT().PersistentVolumeAccessModeDetectionRequired().Return(false).Times(1)
+				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vm, nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vm, nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vmi, nil).Times(1)
+				mockMachineScope.EXPECT().GuestAgentRequired().Return(false).Times(1)
+				mockMachineScope.EXPECT().SyncMachine(*vm, vmi, fmt.Sprintf(providerIDFmt, testutils.InfraNamespace, testutils.MachineName)).Return(nil).Times(1)
+			},
+			expectedReady: true,
+			expectedSteps: []machinescope.CreateStep{machinescope.CreateStepVMCreated, machinescope.CreateStepSynced},
+		},
+		{
+			name: "Success access mode selected by the storage class",
+			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope) {
+				vm := testutils.StubVirtualMachine(nil, nil, nil)
+				vm.Status.Ready = true
+				vmi := testutils.StubVirtualMachineInstance()
+				ignitionSecret := testutils.StubIgnitionSecret()
+				accessModes := []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
+
+				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
+				mockMachineScope.EXPECT().GetInfraIgnitionSecretName().Return("").Times(1)
+				mockMachineScope.EXPECT().GetIgnitionSourceHost().Return("", nil).Times(1)
+				mockMachineScope.EXPECT().GetIgnitionMergeSource().Return(nil, nil).Times(1)
+				mockMachineScope.EXPECT().GetClusterProxy().Return(nil).Times(1)
+				mockMachineScope.EXPECT().HostnameInjectionEnabled().Return(true).Times(1)
+				mockMachineScope.EXPECT().NodeSmokeCheckRequired().Return(false).Times(1)
+				mockMachineScope.EXPECT().GetHostname().Return(testutils.MachineName, nil).Times(1)
+				mockMachineScope.EXPECT().GetInstanceMetadata().Return(nil).Times(1)
+				mockMachineScope.EXPECT().GetAfterburnMetadata().Return(nil).Times(2)
+				mockMachineScope.EXPECT().IgnitionPropagatedViaSecret().Return(true).Times(1)
+				mockMachineScope.EXPECT().CreateIgnitionSecretFromMachine([]byte(fmt.Sprintf(testutils.FullUserDataFmt, testutils.MachineName))).Return(ignitionSecret, nil).Times(1)
 				mockInfraClusterClient.EXPECT().CreateSecret(gomock.Any(), testutils.InfraNamespace, ignitionSecret).Return(&corev1.Secret{}, nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetSecret(gomock.Any(), testutils.InfraNamespace, ignitionSecret.Name).Return(ignitionSecret, nil).Times(1)
+				mockInfraClusterClient.EXPECT().UpdateSecret(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(&corev1.Secret{}, nil).Times(1)
+				mockMachineScope.EXPECT().CreateNetworkPolicyFromMachine().Return(nil).Times(1)
+				mockMachineScope.EXPECT().PersistentVolumeAccessModeDetectionRequired().Return(true).Times(1)
+				mockMachineScope.EXPECT().GetStorageClassName().Return("").Times(1)
+				mockInfraClusterClient.EXPECT().GetStorageClassAccessModes(gomock.Any(), "").Return("test-storage-class", accessModes, nil).Times(1)
+				mockMachineScope.EXPECT().SelectPersistentVolumeAccessMode("test-storage-class", accessModes).Return("test decision").Times(1)
 				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vm, nil).Times(1)
 				mockInfraClusterClient.EXPECT().CreateVirtualMachine(gomock.Any(), testutils.InfraNamespace, vm).Return(vm, nil).Times(1)
 				mockInfraClusterClient.EXPECT().GetVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vmi, nil).Times(1)
+				mockMachineScope.EXPECT().GuestAgentRequired().Return(false).Times(1)
 				mockMachineScope.EXPECT().SyncMachine(*vm, vmi, fmt.Sprintf(providerIDFmt, testutils.InfraNamespace, testutils.MachineName)).Return(nil).Times(1)
 			},
+			expectedReady: true,
 		},
 		{
 			name: "Success vm not ready",
@@ -51,21 +162,316 @@ func TestCreate(t *testing.T) {
 				ignitionSecret := testutils.StubIgnitionSecret()
 
 				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
-				mockMachineScope.EXPECT().CreateIgnitionSecretFromMachine([]byte(fmt.Sprintf(testutils.FullUserDataFmt, testutils.MachineName))).Return(ignitionSecret).Times(1)
+				mockMachineScope.EXPECT().GetInfraIgnitionSecretName().Return("").Times(1)
+				mockMachineScope.EXPECT().GetIgnitionSourceHost().Return("", nil).Times(1)
+				mockMachineScope.EXPECT().GetIgnitionMergeSource().Return(nil, nil).Times(1)
+				mockMachineScope.EXPECT().GetClusterProxy().Return(nil).Times(1)
+				mockMachineScope.EXPECT().HostnameInjectionEnabled().Return(true).Times(1)
+				mockMachineScope.EXPECT().NodeSmokeCheckRequired().Return(false).Times(1)
+				mockMachineScope.EXPECT().GetHostname().Return(testutils.MachineName, nil).Times(1)
+				mockMachineScope.EXPECT().GetInstanceMetadata().Return(nil).Times(1)
+				mockMachineScope.EXPECT().GetAfterburnMetadata().Return(nil).Times(2)
+				mockMachineScope.EXPECT().IgnitionPropagatedViaSecret().Return(true).Times(1)
+				mockMachineScope.EXPECT().CreateIgnitionSecretFromMachine([]byte(fmt.Sprintf(testutils.FullUserDataFmt, testutils.MachineName))).Return(ignitionSecret, nil).Times(1)
+				mockInfraClusterClient.EXPECT().CreateSecret(gomock.Any(), testutils.InfraNamespace, ignitionSecret).Return(&corev1.Secret{}, nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetSecret(gomock.Any(), testutils.InfraNamespace, ignitionSecret.Name).Return(ignitionSecret, nil).Times(1)
+				mockInfraClusterClient.EXPECT().UpdateSecret(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(&corev1.Secret{}, nil).Times(1)
+				mockMachineScope.EXPECT().CreateNetworkPolicyFromMachine().Return(nil).Times(1)
+				mockMachineScope.EXPECT().PersistentVolumeAccessModeDetectionRequired().Return(false).Times(1)
+				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vm, nil).Times(1)
+				mockInfraClusterClient.EXPECT().CreateVirtualMachine(gomock.Any(), testutils.InfraNamespace, vm).Return(vm, nil).Times(1)
+				mockMachineScope.EXPECT().SyncMachine(*vm, nil, fmt.Sprintf(providerIDFmt, testutils.InfraNamespace, testutils.MachineName)).Return(nil).Times(1)
+			},
+		},
+		{
+			name: "Success vm created vmi pending",
+			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope) {
+				vm := testutils.StubVirtualMachine(nil, nil, nil)
+				vm.Status.Created = true
+				vm.Status.Ready = true
+				ignitionSecret := testutils.StubIgnitionSecret()
+
+				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
+				mockMachineScope.EXPECT().GetInfraIgnitionSecretName().Return("").Times(1)
+				mockMachineScope.EXPECT().GetIgnitionSourceHost().Return("", nil).Times(1)
+				mockMachineScope.EXPECT().GetIgnitionMergeSource().Return(nil, nil).Times(1)
+				mockMachineScope.EXPECT().GetClusterProxy().Return(nil).Times(1)
+				mockMachineScope.EXPECT().HostnameInjectionEnabled().Return(true).Times(1)
+				mockMachineScope.EXPECT().NodeSmokeCheckRequired().Return(false).Times(1)
+				mockMachineScope.EXPECT().GetHostname().Return(testutils.MachineName, nil).Times(1)
+				mockMachineScope.EXPECT().GetInstanceMetadata().Return(nil).Times(1)
+				mockMachineScope.EXPECT().GetAfterburnMetadata().Return(nil).Times(2)
+				mockMachineScope.EXPECT().IgnitionPropagatedViaSecret().Return(true).Times(1)
+				mockMachineScope.EXPECT().CreateIgnitionSecretFromMachine([]byte(fmt.Sprintf(testutils.FullUserDataFmt, testutils.MachineName))).Return(ignitionSecret, nil).Times(1)
+				mockInfraClusterClient.EXPECT().CreateSecret(gomock.Any(), testutils.InfraNamespace, ignitionSecret).Return(&corev1.Secret{}, nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetSecret(gomock.Any(), testutils.InfraNamespace, ignitionSecret.Name).Return(ignitionSecret, nil).Times(1)
+				mockInfraClusterClient.EXPECT().UpdateSecret(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(&corev1.Secret{}, nil).Times(1)
+				mockMachineScope.EXPECT().CreateNetworkPolicyFromMachine().Return(nil).Times(1)
+				mockMachineScope.EXPECT().PersistentVolumeAccessModeDetectionRequired().Return(false).Times(1)
+				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vm, nil).Times(1)
+				mockInfraClusterClient.EXPECT().CreateVirtualMachine(gomock.Any(), testutils.InfraNamespace, vm).Return(vm, nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(nil,
+					apierr.NewNotFound(schema.GroupResource{Group: "", Resource: "test"}, "3")).Times(1)
+				mockMachineScope.EXPECT().SyncMachine(*vm, nil, fmt.Sprintf(providerIDFmt, testutils.InfraNamespace, testutils.MachineName)).Return(nil).Times(1)
+			},
+		},
+		{
+			name: "Success ignition uploaded to its data volume",
+			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope) {
+				vm := testutils.StubVirtualMachine(nil, nil, nil)
+				ignitionDataVolume := stubIgnitionDataVolume()
+				expectedVM := vm.DeepCopy()
+				expectedVM.Spec.Template.Spec.Volumes[1].VolumeSource = kubevirtapiv1.VolumeSource{
+					DataVolume: &kubevirtapiv1.DataVolumeSource{Name: ignitionDataVolume.Name},
+				}
+
+				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
+				mockMachineScope.EXPECT().GetInfraIgnitionSecretName().Return("").Times(1)
+				mockMachineScope.EXPECT().GetIgnitionSourceHost().Return("", nil).Times(1)
+				mockMachineScope.EXPECT().GetIgnitionMergeSource().Return(nil, nil).Times(1)
+				mockMachineScope.EXPECT().GetClusterProxy().Return(nil).Times(1)
+				mockMachineScope.EXPECT().HostnameInjectionEnabled().Return(true).Times(1)
+				mockMachineScope.EXPECT().NodeSmokeCheckRequired().Return(false).Times(1)
+				mockMachineScope.EXPECT().GetHostname().Return(testutils.MachineName, nil).Times(2)
+				mockMachineScope.EXPECT().GetInstanceMetadata().Return(nil).Times(1)
+				mockMachineScope.EXPECT().GetAfterburnMetadata().Return(nil).Times(2)
+				mockMachineScope.EXPECT().IgnitionPropagatedViaSecret().Return(false).Times(1)
+				mockMachineScope.EXPECT().CreateNetworkPolicyFromMachine().Return(nil).Times(1)
+				mockMachineScope.EXPECT().PersistentVolumeAccessModeDetectionRequired().Return(false).Times(1)
+				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vm, nil).Times(1)
+				mockMachineScope.EXPECT().CreateIgnitionDataVolumeFromMachine(gomock.Any()).Return(ignitionDataVolume, nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetDataVolume(gomock.Any(), testutils.InfraNamespace, ignitionDataVolume.Name).Return(
+					stubUnstructuredDataVolume(ignitionDataVolume, cdiv1.Succeeded), nil).Times(2)
+				mockInfraClusterClient.EXPECT().CreateVirtualMachine(gomock.Any(), testutils.InfraNamespace, expectedVM).Return(expectedVM, nil).Times(1)
+				// The virtual machine owns its ignition data volume
+				mockInfraClusterClient.EXPECT().UpdateDataVolume(gomock.Any(), testutils.InfraNamespace, gomock.Any()).DoAndReturn(
+					func(ctx context.Context, namespace string, dataVolume *cdiv1.DataVolume) (*cdiv1.DataVolume, error) {
+						assert.Equal(t, dataVolume.OwnerReferences[0].Name, testutils.MachineName)
+						return dataVolume, nil
+					}).Times(1)
+				mockMachineScope.EXPECT().SyncMachine(*expectedVM, nil, fmt.Sprintf(providerIDFmt, testutils.InfraNamespace, testutils.MachineName)).Return(nil).Times(1)
+			},
+		},
+		{
+			name: "Requeue ignition data volume created",
+			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope) {
+				vm := testutils.StubVirtualMachine(nil, nil, nil)
+				ignitionDataVolume := stubIgnitionDataVolume()
+
+				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
+				mockMachineScope.EXPECT().GetInfraIgnitionSecretName().Return("").Times(1)
+				mockMachineScope.EXPECT().GetIgnitionSourceHost().Return("", nil).Times(1)
+				mockMachineScope.EXPECT().GetIgnitionMergeSource().Return(nil, nil).Times(1)
+				mockMachineScope.EXPECT().GetClusterProxy().Return(nil).Times(1)
+				mockMachineScope.EXPECT().HostnameInjectionEnabled().Return(true).Times(1)
+				mockMachineScope.EXPECT().NodeSmokeCheckRequired().Return(false).Times(1)
+				mockMachineScope.EXPECT().GetHostname().Return(testutils.MachineName, nil).Times(2)
+				mockMachineScope.EXPECT().GetInstanceMetadata().Return(nil).Times(1)
+				mockMachineScope.EXPECT().GetAfterburnMetadata().Return(nil).Times(2)
+				mockMachineScope.EXPECT().IgnitionPropagatedViaSecret().Return(false).Times(1)
+				mockMachineScope.EXPECT().CreateNetworkPolicyFromMachine().Return(nil).Times(1)
+				mockMachineScope.EXPECT().PersistentVolumeAccessModeDetectionRequired().Return(false).Times(1)
+				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vm, nil).Times(1)
+				mockMachineScope.EXPECT().CreateIgnitionDataVolumeFromMachine(gomock.Any()).Return(ignitionDataVolume, nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetDataVolume(gomock.Any(), testutils.InfraNamespace, ignitionDataVolume.Name).Return(nil,
+					apierr.NewNotFound(schema.GroupResource{Resource: "datavolumes"}, ignitionDataVolume.Name)).Times(1)
+				mockInfraClusterClient.EXPECT().CreateDataVolume(gomock.Any(), testutils.InfraNamespace, ignitionDataVolume).Return(ignitionDataVolume, nil).Times(1)
+			},
+			expectedErr: "requeue in: 20s",
+		},
+		{
+			name: "Requeue ignition uploaded to the ready data volume",
+			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope) {
+				vm := testutils.StubVirtualMachine(nil, nil, nil)
+				ignitionDataVolume := stubIgnitionDataVolume()
+
+				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
+				mockMachineScope.EXPECT().GetInfraIgnitionSecretName().Return("").Times(1)
+				mockMachineScope.EXPECT().GetIgnitionSourceHost().Return("", nil).Times(1)
+				mockMachineScope.EXPECT().GetIgnitionMergeSource().Return(nil, nil).Times(1)
+				mockMachineScope.EXPECT().GetClusterProxy().Return(nil).Times(1)
+				mockMachineScope.EXPECT().HostnameInjectionEnabled().Return(true).Times(1)
+				mockMachineScope.EXPECT().NodeSmokeCheckRequired().Return(false).Times(1)
+				mockMachineScope.EXPECT().GetHostname().Return(testutils.MachineName, nil).Times(2)
+				mockMachineScope.EXPECT().GetInstanceMetadata().Return(nil).Times(1)
+				mockMachineScope.EXPECT().GetAfterburnMetadata().Return(nil).Times(2)
+				mockMachineScope.EXPECT().IgnitionPropagatedViaSecret().Return(false).Times(1)
+				mockMachineScope.EXPECT().CreateNetworkPolicyFromMachine().Return(nil).Times(1)
+				mockMachineScope.EXPECT().PersistentVolumeAccessModeDetectionRequired().Return(false).Times(1)
+				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vm, nil).Times(1)
+				mockMachineScope.EXPECT().CreateIgnitionDataVolumeFromMachine(gomock.Any()).Return(ignitionDataVolume, nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetDataVolume(gomock.Any(), testutils.InfraNamespace, ignitionDataVolume.Name).Return(
+					stubUnstructuredDataVolume(ignitionDataVolume, cdiv1.UploadReady), nil).Times(1)
+				mockInfraClusterClient.EXPECT().UploadDataVolume(gomock.Any(), testutils.InfraNamespace, ignitionDataVolume.Name, gomock.Any()).DoAndReturn(
+					func(ctx context.Context, namespace string, name string, image []byte) error {
+						assert.Equal(t, string(image[16*isoSectorSize+40:16*isoSectorSize+48]), configDriveLabel)
+						return nil
+					}).Times(1)
+			},
+			expectedErr: "requeue in: 20s",
+		},
+		{
+			name: "Failure ignition upload failed",
+			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope) {
+				vm := testutils.StubVirtualMachine(nil, nil, nil)
+				ignitionDataVolume := stubIgnitionDataVolume()
+
+				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
+				mockMachineScope.EXPECT().GetInfraIgnitionSecretName().Return("").Times(1)
+				mockMachineScope.EXPECT().GetIgnitionSourceHost().Return("", nil).Times(1)
+				mockMachineScope.EXPECT().GetIgnitionMergeSource().Return(nil, nil).Times(1)
+				mockMachineScope.EXPECT().GetClusterProxy().Return(nil).Times(1)
+				mockMachineScope.EXPECT().HostnameInjectionEnabled().Return(true).Times(1)
+				mockMachineScope.EXPECT().NodeSmokeCheckRequired().Return(false).Times(1)
+				mockMachineScope.EXPECT().GetHostname().Return(testutils.MachineName, nil).Times(2)
+				mockMachineScope.EXPECT().GetInstanceMetadata().Return(nil).Times(1)
+				mockMachineScope.EXPECT().GetAfterburnMetadata().Return(nil).Times(2)
+				mockMachineScope.EXPECT().IgnitionPropagatedViaSecret().Return(false).Times(1)
+				mockMachineScope.EXPECT().CreateNetworkPolicyFromMachine().Return(nil).Times(1)
+				mockMachineScope.EXPECT().PersistentVolumeAccessModeDetectionRequired().Return(false).Times(1)
+				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vm, nil).Times(1)
+				mockMachineScope.EXPECT().CreateIgnitionDataVolumeFromMachine(gomock.Any()).Return(ignitionDataVolume, nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetDataVolume(gomock.Any(), testutils.InfraNamespace, ignitionDataVolume.Name).Return(
+					stubUnstructuredDataVolume(ignitionDataVolume, cdiv1.Failed), nil).Times(1)
+				mockInfraClusterClient.EXPECT().DeleteDataVolume(gomock.Any(), testutils.InfraNamespace, ignitionDataVolume.Name).Return(nil).Times(1)
+			},
+			expectedErr: "test-machine-name: Error during Create: failed to upload the ignition to its DataVolume in infraCluster, with error: upload to the ignition DataVolume test-machine-name-ignition failed",
+		},
+		{
+			name: "Success ignition secret pre-provisioned in the infra cluster",
+			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope) {
+				vm := testutils.StubVirtualMachine(nil, nil, nil)
+
+				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
+				mockMachineScope.EXPECT().GetInfraIgnitionSecretName().Return("infra-ignition").Times(1)
+				mockMachineScope.EXPECT().CreateNetworkPolicyFromMachine().Return(nil).Times(1)
+				mockMachineScope.EXPECT().PersistentVolumeAccessModeDetectionRequired().Return(false).Times(1)
+				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vm, nil).Times(1)
+				mockInfraClusterClient.EXPECT().CreateVirtualMachine(gomock.Any(), testutils.InfraNamespace, vm).Return(vm, nil).Times(1)
+				mockMachineScope.EXPECT().SyncMachine(*vm, nil, fmt.Sprintf(providerIDFmt, testutils.InfraNamespace, testutils.MachineName)).Return(nil).Times(1)
+			},
+		},
+		{
+			name: "Success hostname injection disabled",
+			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope) {
+				vm := testutils.StubVirtualMachine(nil, nil, nil)
+				ignitionSecret := testutils.StubIgnitionSecret()
+
+				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
+				mockMachineScope.EXPECT().GetInfraIgnitionSecretName().Return("").Times(1)
+				mockMachineScope.EXPECT().GetIgnitionSourceHost().Return("", nil).Times(1)
+				mockMachineScope.EXPECT().GetIgnitionMergeSource().Return(nil, nil).Times(1)
+				mockMachineScope.EXPECT().GetClusterProxy().Return(nil).Times(1)
+				mockMachineScope.EXPECT().HostnameInjectionEnabled().Return(false).Times(1)
+				mockMachineScope.EXPECT().NodeSmokeCheckRequired().Return(false).Times(1)
+				mockMachineScope.EXPECT().GetInstanceMetadata().Return(nil).Times(1)
+				mockMachineScope.EXPECT().GetAfterburnMetadata().Return(nil).Times(2)
+				mockMachineScope.EXPECT().IgnitionPropagatedViaSecret().Return(true).Times(1)
+				mockMachineScope.EXPECT().CreateIgnitionSecretFromMachine([]byte(testutils.SrcUserData)).Return(ignitionSecret, nil).Times(1)
+				mockInfraClusterClient.EXPECT().CreateSecret(gomock.Any(), testutils.InfraNamespace, ignitionSecret).Return(&corev1.Secret{}, nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetSecret(gomock.Any(), testutils.InfraNamespace, ignitionSecret.Name).Return(ignitionSecret, nil).Times(1)
+				mockInfraClusterClient.EXPECT().UpdateSecret(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(&corev1.Secret{}, nil).Times(1)
+				mockMachineScope.EXPECT().CreateNetworkPolicyFromMachine().Return(nil).Times(1)
+				mockMachineScope.EXPECT().PersistentVolumeAccessModeDetectionRequired().Return(false).Times(1)
+				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vm, nil).Times(1)
+				mockInfraClusterClient.EXPECT().CreateVirtualMachine(gomock.Any(), testutils.InfraNamespace, vm).Return(vm, nil).Times(1)
+				mockMachineScope.EXPECT().SyncMachine(*vm, nil, fmt.Sprintf(providerIDFmt, testutils.InfraNamespace, testutils.MachineName)).Return(nil).Times(1)
+			},
+		},
+		{
+			name: "Success hostname override",
+			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope) {
+				vm := testutils.StubVirtualMachine(nil, nil, nil)
+				ignitionSecret := testutils.StubIgnitionSecret()
+
+				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
+				mockMachineScope.EXPECT().GetInfraIgnitionSecretName().Return("").Times(1)
+				mockMachineScope.EXPECT().GetIgnitionSourceHost().Return("", nil).Times(1)
+				mockMachineScope.EXPECT().GetIgnitionMergeSource().Return(nil, nil).Times(1)
+				mockMachineScope.EXPECT().GetClusterProxy().Return(nil).Times(1)
+				mockMachineScope.EXPECT().HostnameInjectionEnabled().Return(true).Times(1)
+				mockMachineScope.EXPECT().NodeSmokeCheckRequired().Return(false).Times(1)
+				mockMachineScope.EXPECT().GetHostname().Return("test-hostname", nil).Times(1)
+				mockMachineScope.EXPECT().GetInstanceMetadata().Return(nil).Times(1)
+				mockMachineScope.EXPECT().GetAfterburnMetadata().Return(nil).Times(2)
+				mockMachineScope.EXPECT().IgnitionPropagatedViaSecret().Return(true).Times(1)
+				mockMachineScope.EXPECT().CreateIgnitionSecretFromMachine([]byte(fmt.Sprintf(testutils.FullUserDataFmt, "test-hostname"))).Return(ignitionSecret, nil).Times(1)
 				mockInfraClusterClient.EXPECT().CreateSecret(gomock.Any(), testutils.InfraNamespace, ignitionSecret).Return(&corev1.Secret{}, nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetSecret(gomock.Any(), testutils.InfraNamespace, ignitionSecret.Name).Return(ignitionSecret, nil).Times(1)
+				mockInfraClusterClient.EXPECT().UpdateSecret(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(&corev1.Secret{}, nil).Times(1)
+				mockMachineScope.EXPECT().CreateNetworkPolicyFromMachine().Return(nil).Times(1)
+				mockMachineScope.EXPECT().PersistentVolumeAccessModeDetectionRequired().Return(false).Times(1)
 				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vm, nil).Times(1)
 				mockInfraClusterClient.EXPECT().CreateVirtualMachine(gomock.Any(), testutils.InfraNamespace, vm).Return(vm, nil).Times(1)
 				mockMachineScope.EXPECT().SyncMachine(*vm, nil, fmt.Sprintf(providerIDFmt, testutils.InfraNamespace, testutils.MachineName)).Return(nil).Times(1)
 			},
 		},
+		{
+			name: "Failure invalid hostname override",
+			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope) {
+				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
+				mockMachineScope.EXPECT().GetInfraIgnitionSecretName().Return("").Times(1)
+				mockMachineScope.EXPECT().GetIgnitionSourceHost().Return("", nil).Times(1)
+				mockMachineScope.EXPECT().GetIgnitionMergeSource().Return(nil, nil).Times(1)
+				mockMachineScope.EXPECT().GetClusterProxy().Return(nil).Times(1)
+				mockMachineScope.EXPECT().HostnameInjectionEnabled().Return(true).Times(1)
+				mockMachineScope.EXPECT().GetHostname().Return("", fmt.Errorf("test error")).Times(1)
+				mockMachineScope.EXPECT().IgnitionPropagatedViaSecret().Return(true).Times(1)
+			},
+			expectedErr: "test error",
+		},
 		{
 			name: "Failure create ignition secret",
 			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope) {
+				vm := testutils.StubVirtualMachine(nil, nil, nil)
+				ignitionSecret := testutils.StubIgnitionSecret()
+
+				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
+				mockMachineScope.EXPECT().GetInfraIgnitionSecretName().Return("").Times(1)
+				mockMachineScope.EXPECT().GetIgnitionSourceHost().Return("", nil).Times(1)
+				mockMachineScope.EXPECT().GetIgnitionMergeSource().Return(nil, nil).Times(1)
+				mockMachineScope.EXPECT().GetClusterProxy().Return(nil).Times(1)
+				mockMachineScope.EXPECT().HostnameInjectionEnabled().Return(true).Times(1)
+				mockMachineScope.EXPECT().NodeSmokeCheckRequired().Return(false).Times(1)
+				mockMachineScope.EXPECT().GetHostname().Return(testutils.MachineName, nil).Times(1)
+				mockMachineScope.EXPECT().GetInstanceMetadata().Return(nil).Times(1)
+				mockMachineScope.EXPECT().GetAfterburnMetadata().Return(nil).Times(2)
+				mockMachineScope.EXPECT().IgnitionPropagatedViaSecret().Return(true).Times(1)
+				mockMachineScope.EXPECT().CreateIgnitionSecretFromMachine([]byte(fmt.Sprintf(testutils.FullUserDataFmt, testutils.MachineName))).Return(ignitionSecret, nil).Times(1)
+				mockMachineScope.EXPECT().CreateNetworkPolicyFromMachine().Return(nil).Times(1)
+				mockMachineScope.EXPECT().PersistentVolumeAccessModeDetectionRequired().Return(false).Times(1)
+				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vm, nil).Times(1)
+				mockInfraClusterClient.EXPECT().CreateSecret(gomock.Any(), testutils.InfraNamespace, ignitionSecret).Return(&corev1.Secret{}, fmt.Errorf("test error")).Times(1)
+				mockInfraClusterClient.EXPECT().CreateVirtualMachine(gomock.Any(), testutils.InfraNamespace, vm).Return(vm, nil).Times(1)
+				mockInfraClusterClient.EXPECT().DeleteVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(nil).Times(1)
+			},
+			expectedErr: "test-machine-name: Error during Create: failed to create ignition secret in infraCluster, with error: test error",
+		},
+		{
+			name:      "Failure create ignition secret of a resumed creation - keep the virtual machine",
+			stepsDone: []machinescope.CreateStep{machinescope.CreateStepVMCreated},
+			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope) {
+				vm := testutils.StubVirtualMachine(nil, nil, nil)
 				ignitionSecret := testutils.StubIgnitionSecret()
 
 				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
-				mockMachineScope.EXPECT().CreateIgnitionSecretFromMachine([]byte(fmt.Sprintf(testutils.FullUserDataFmt, testutils.MachineName))).Return(ignitionSecret).Times(1)
+				mockMachineScope.EXPECT().GetInfraIgnitionSecretName().Return("").Times(1)
+				mockMachineScope.EXPECT().GetIgnitionSourceHost().Return("", nil).Times(1)
+				mockMachineScope.EXPECT().GetIgnitionMergeSource().Return(nil, nil).Times(1)
+				mockMachineScope.EXPECT().GetClusterProxy().Return(nil).Times(1)
+				mockMachineScope.EXPECT().HostnameInjectionEnabled().Return(true).Times(1)
+				mockMachineScope.EXPECT().NodeSmokeCheckRequired().Return(false).Times(1)
+				mockMachineScope.EXPECT().GetHostname().Return(testutils.MachineName, nil).Times(1)
+				mockMachineScope.EXPECT().GetInstanceMetadata().Return(nil).Times(1)
+				mockMachineScope.EXPECT().GetAfterburnMetadata().Return(nil).Times(2)
+				mockMachineScope.EXPECT().IgnitionPropagatedViaSecret().Return(true).Times(1)
+				mockMachineScope.EXPECT().CreateIgnitionSecretFromMachine([]byte(fmt.Sprintf(testutils.FullUserDataFmt, testutils.MachineName))).Return(ignitionSecret, nil).Times(1)
+				mockMachineScope.EXPECT().CreateNetworkPolicyFromMachine().Return(nil).Times(1)
+				mockMachineScope.EXPECT().PersistentVolumeAccessModeDetectionRequired().Return(false).Times(1)
+				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vm, nil).Times(1)
 				mockInfraClusterClient.EXPECT().CreateSecret(gomock.Any(), testutils.InfraNamespace, ignitionSecret).Return(&corev1.Secret{}, fmt.Errorf("test error")).Times(1)
+				mockInfraClusterClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vm, nil).Times(1)
+				mockInfraClusterClient.EXPECT().DeleteVirtualMachine(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 			},
 			expectedErr: "test-machine-name: Error during Create: failed to create ignition secret in infraCluster, with error: test error",
 		},
@@ -76,8 +482,19 @@ func TestCreate(t *testing.T) {
 				ignitionSecret := testutils.StubIgnitionSecret()
 
 				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
-				mockMachineScope.EXPECT().CreateIgnitionSecretFromMachine([]byte(fmt.Sprintf(testutils.FullUserDataFmt, testutils.MachineName))).Return(ignitionSecret).Times(1)
-				mockInfraClusterClient.EXPECT().CreateSecret(gomock.Any(), testutils.InfraNamespace, ignitionSecret).Return(&corev1.Secret{}, nil).Times(1)
+				mockMachineScope.EXPECT().GetInfraIgnitionSecretName().Return("").Times(1)
+				mockMachineScope.EXPECT().GetIgnitionSourceHost().Return("", nil).Times(1)
+				mockMachineScope.EXPECT().GetIgnitionMergeSource().Return(nil, nil).Times(1)
+				mockMachineScope.EXPECT().GetClusterProxy().Return(nil).Times(1)
+				mockMachineScope.EXPECT().HostnameInjectionEnabled().Return(true).Times(1)
+				mockMachineScope.EXPECT().NodeSmokeCheckRequired().Return(false).Times(1)
+				mockMachineScope.EXPECT().GetHostname().Return(testutils.MachineName, nil).Times(1)
+				mockMachineScope.EXPECT().GetInstanceMetadata().Return(nil).Times(1)
+				mockMachineScope.EXPECT().GetAfterburnMetadata().Return(nil).Times(2)
+				mockMachineScope.EXPECT().IgnitionPropagatedViaSecret().Return(true).Times(1)
+				mockMachineScope.EXPECT().CreateIgnitionSecretFromMachine([]byte(fmt.Sprintf(testutils.FullUserDataFmt, testutils.MachineName))).Return(ignitionSecret, nil).Times(1)
+				mockMachineScope.EXPECT().CreateNetworkPolicyFromMachine().Return(nil).Times(1)
+				mockMachineScope.EXPECT().PersistentVolumeAccessModeDetectionRequired().Return(false).Times(1)
 				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vm, fmt.Errorf("test error")).Times(1)
 			},
 			expectedErr: "test-machine-name: Error during Create: failed to build Virtual Machine struct, with error: test error",
@@ -89,13 +506,90 @@ func TestCreate(t *testing.T) {
 				ignitionSecret := testutils.StubIgnitionSecret()
 
 				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
-				mockMachineScope.EXPECT().CreateIgnitionSecretFromMachine([]byte(fmt.Sprintf(testutils.FullUserDataFmt, testutils.MachineName))).Return(ignitionSecret).Times(1)
+				mockMachineScope.EXPECT().GetInfraIgnitionSecretName().Return("").Times(1)
+				mockMachineScope.EXPECT().GetIgnitionSourceHost().Return("", nil).Times(1)
+				mockMachineScope.EXPECT().GetIgnitionMergeSource().Return(nil, nil).Times(1)
+				mockMachineScope.EXPECT().GetClusterProxy().Return(nil).Times(1)
+				mockMachineScope.EXPECT().HostnameInjectionEnabled().Return(true).Times(1)
+				mockMachineScope.EXPECT().NodeSmokeCheckRequired().Return(false).Times(1)
+				mockMachineScope.EXPECT().GetHostname().Return(testutils.MachineName, nil).Times(1)
+				mockMachineScope.EXPECT().GetInstanceMetadata().Return(nil).Times(1)
+				mockMachineScope.EXPECT().GetAfterburnMetadata().Return(nil).Times(2)
+				mockMachineScope.EXPECT().IgnitionPropagatedViaSecret().Return(true).Times(1)
+				mockMachineScope.EXPECT().CreateIgnitionSecretFromMachine([]byte(fmt.Sprintf(testutils.FullUserDataFmt, testutils.MachineName))).Return(ignitionSecret, nil).Times(1)
 				mockInfraClusterClient.EXPECT().CreateSecret(gomock.Any(), testutils.InfraNamespace, ignitionSecret).Return(&corev1.Secret{}, nil).Times(1)
+				mockMachineScope.EXPECT().CreateNetworkPolicyFromMachine().Return(nil).Times(1)
+				mockMachineScope.EXPECT().PersistentVolumeAccessModeDetectionRequired().Return(false).Times(1)
 				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vm, nil).Times(1)
 				mockInfraClusterClient.EXPECT().CreateVirtualMachine(gomock.Any(), testutils.InfraNamespace, vm).Return(vm, fmt.Errorf("test error")).Times(1)
+				mockInfraClusterClient.EXPECT().DeleteSecret(gomock.Any(), testutils.InfraNamespace, ignitionSecret.Name).Return(nil).Times(1)
 			},
 			expectedErr: "test-machine-name: Error during Create: failed to create Virtual Machine in infraCluster, with error: test error",
 		},
+		{
+			name: "Success ignition secret already exists",
+			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope) {
+				vm := testutils.StubVirtualMachine(nil, nil, nil)
+				ignitionSecret := testutils.StubIgnitionSecret()
+
+				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
+				mockMachineScope.EXPECT().GetInfraIgnitionSecretName().Return("").Times(1)
+				mockMachineScope.EXPECT().GetIgnitionSourceHost().Return("", nil).Times(1)
+				mockMachineScope.EXPECT().GetIgnitionMergeSource().Return(nil, nil).Times(1)
+				mockMachineScope.EXPECT().GetClusterProxy().Return(nil).Times(1)
+				mockMachineScope.EXPECT().HostnameInjectionEnabled().Return(true).Times(1)
+				mockMachineScope.EXPECT().NodeSmokeCheckRequired().Return(false).Times(1)
+				mockMachineScope.EXPECT().GetHostname().Return(testutils.MachineName, nil).Times(1)
+				mockMachineScope.EXPECT().GetInstanceMetadata().Return(nil).Times(1)
+				mockMachineScope.EXPECT().GetAfterburnMetadata().Return(nil).Times(2)
+				mockMachineScope.EXPECT().IgnitionPropagatedViaSecret().Return(true).Times(1)
+				mockMachineScope.EXPECT().CreateIgnitionSecretFromMachine([]byte(fmt.Sprintf(testutils.FullUserDataFmt, testutils.MachineName))).Return(ignitionSecret, nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetSecret(gomock.Any(), testutils.InfraNamespace, ignitionSecret.Name).Return(ignitionSecret, nil).Times(2)
+				mockInfraClusterClient.EXPECT().UpdateSecret(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(&corev1.Secret{}, nil).Times(1)
+				mockMachineScope.EXPECT().CreateNetworkPolicyFromMachine().Return(nil).Times(1)
+				mockMachineScope.EXPECT().PersistentVolumeAccessModeDetectionRequired().Return(false).Times(1)
+				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vm, nil).Times(1)
+				mockInfraClusterClient.EXPECT().CreateSecret(gomock.Any(), testutils.InfraNamespace, ignitionSecret).Return(nil,
+					apierr.NewAlreadyExists(schema.GroupResource{Group: "", Resource: "secrets"}, ignitionSecret.Name)).Times(1)
+				mockInfraClusterClient.EXPECT().CreateVirtualMachine(gomock.Any(), testutils.InfraNamespace, vm).Return(vm, nil).Times(1)
+				mockMachineScope.EXPECT().SyncMachine(*vm, nil, fmt.Sprintf(providerIDFmt, testutils.InfraNamespace, testutils.MachineName)).Return(nil).Times(1)
+			},
+		},
+		{
+			name: "Success ignition secret already exists with another userData",
+			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope) {
+				vm := testutils.StubVirtualMachine(nil, nil, nil)
+				ignitionSecret := testutils.StubIgnitionSecret()
+				staleSecret := testutils.StubIgnitionSecret()
+				staleSecret.Data = map[string][]byte{"userdata": []byte("{}")}
+
+				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
+				mockMachineScope.EXPECT().GetInfraIgnitionSecretName().Return("").Times(1)
+				mockMachineScope.EXPECT().GetIgnitionSourceHost().Return("", nil).Times(1)
+				mockMachineScope.EXPECT().GetIgnitionMergeSource().Return(nil, nil).Times(1)
+				mockMachineScope.EXPECT().GetClusterProxy().Return(nil).Times(1)
+				mockMachineScope.EXPECT().HostnameInjectionEnabled().Return(true).Times(1)
+				mockMachineScope.EXPECT().NodeSmokeCheckRequired().Return(false).Times(1)
+				mockMachineScope.EXPECT().GetHostname().Return(testutils.MachineName, nil).Times(1)
+				mockMachineScope.EXPECT().GetInstanceMetadata().Return(nil).Times(1)
+				mockMachineScope.EXPECT().GetAfterburnMetadata().Return(nil).Times(2)
+				mockMachineScope.EXPECT().IgnitionPropagatedViaSecret().Return(true).Times(1)
+				mockMachineScope.EXPECT().CreateIgnitionSecretFromMachine([]byte(fmt.Sprintf(testutils.FullUserDataFmt, testutils.MachineName))).Return(ignitionSecret, nil).Times(1)
+				gomock.InOrder(
+					mockInfraClusterClient.EXPECT().GetSecret(gomock.Any(), testutils.InfraNamespace, ignitionSecret.Name).Return(staleSecret, nil).Times(1),
+					mockInfraClusterClient.EXPECT().UpdateSecret(gomock.Any(), testutils.InfraNamespace, ignitionSecret).Return(ignitionSecret, nil).Times(1),
+					mockInfraClusterClient.EXPECT().GetSecret(gomock.Any(), testutils.InfraNamespace, ignitionSecret.Name).Return(ignitionSecret, nil).Times(1),
+				)
+				mockInfraClusterClient.EXPECT().UpdateSecret(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(&corev1.Secret{}, nil).Times(1)
+				mockMachineScope.EXPECT().CreateNetworkPolicyFromMachine().Return(nil).Times(1)
+				mockMachineScope.EXPECT().PersistentVolumeAccessModeDetectionRequired().Return(false).Times(1)
+				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vm, nil).Times(1)
+				mockInfraClusterClient.EXPECT().CreateSecret(gomock.Any(), testutils.InfraNamespace, ignitionSecret).Return(nil,
+					apierr.NewAlreadyExists(schema.GroupResource{Group: "", Resource: "secrets"}, ignitionSecret.Name)).Times(1)
+				mockInfraClusterClient.EXPECT().CreateVirtualMachine(gomock.Any(), testutils.InfraNamespace, vm).Return(vm, nil).Times(1)
+				mockMachineScope.EXPECT().SyncMachine(*vm, nil, fmt.Sprintf(providerIDFmt, testutils.InfraNamespace, testutils.MachineName)).Return(nil).Times(1)
+			},
+		},
 		{
 			name: "Failure get virtual machine instance",
 			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope) {
@@ -105,8 +599,22 @@ func TestCreate(t *testing.T) {
 				ignitionSecret := testutils.StubIgnitionSecret()
 
 				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
-				mockMachineScope.EXPECT().CreateIgnitionSecretFromMachine([]byte(fmt.Sprintf(testutils.FullUserDataFmt, testutils.MachineName))).Return(ignitionSecret).Times(1)
+				mockMachineScope.EXPECT().GetInfraIgnitionSecretName().Return("").Times(1)
+				mockMachineScope.EXPECT().GetIgnitionSourceHost().Return("", nil).Times(1)
+				mockMachineScope.EXPECT().GetIgnitionMergeSource().Return(nil, nil).Times(1)
+				mockMachineScope.EXPECT().GetClusterProxy().Return(nil).Times(1)
+				mockMachineScope.EXPECT().HostnameInjectionEnabled().Return(true).Times(1)
+				mockMachineScope.EXPECT().NodeSmokeCheckRequired().Return(false).Times(1)
+				mockMachineScope.EXPECT().GetHostname().Return(testutils.MachineName, nil).Times(1)
+				mockMachineScope.EXPECT().GetInstanceMetadata().Return(nil).Times(1)
+				mockMachineScope.EXPECT().GetAfterburnMetadata().Return(nil).Times(2)
+				mockMachineScope.EXPECT().IgnitionPropagatedViaSecret().Return(true).Times(1)
+				mockMachineScope.EXPECT().CreateIgnitionSecretFromMachine([]byte(fmt.Sprintf(testutils.FullUserDataFmt, testutils.MachineName))).Return(ignitionSecret, nil).Times(1)
 				mockInfraClusterClient.EXPECT().CreateSecret(gomock.Any(), testutils.InfraNamespace, ignitionSecret).Return(&corev1.Secret{}, nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetSecret(gomock.Any(), testutils.InfraNamespace, ignitionSecret.Name).Return(ignitionSecret, nil).Times(1)
+				mockInfraClusterClient.EXPECT().UpdateSecret(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(&corev1.Secret{}, nil).Times(1)
+				mockMachineScope.EXPECT().CreateNetworkPolicyFromMachine().Return(nil).Times(1)
+				mockMachineScope.EXPECT().PersistentVolumeAccessModeDetectionRequired().Return(false).Times(1)
 				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vm, nil).Times(1)
 				mockInfraClusterClient.EXPECT().CreateVirtualMachine(gomock.Any(), testutils.InfraNamespace, vm).Return(vm, nil).Times(1)
 				mockInfraClusterClient.EXPECT().GetVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vmi, fmt.Errorf("test error")).Times(1)
@@ -122,11 +630,26 @@ func TestCreate(t *testing.T) {
 				ignitionSecret := testutils.StubIgnitionSecret()
 
 				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
-				mockMachineScope.EXPECT().CreateIgnitionSecretFromMachine([]byte(fmt.Sprintf(testutils.FullUserDataFmt, testutils.MachineName))).Return(ignitionSecret).Times(1)
+				mockMachineScope.EXPECT().GetInfraIgnitionSecretName().Return("").Times(1)
+				mockMachineScope.EXPECT().GetIgnitionSourceHost().Return("", nil).Times(1)
+				mockMachineScope.EXPECT().GetIgnitionMergeSource().Return(nil, nil).Times(1)
+				mockMachineScope.EXPECT().GetClusterProxy().Return(nil).Times(1)
+				mockMachineScope.EXPECT().HostnameInjectionEnabled().Return(true).Times(1)
+				mockMachineScope.EXPECT().NodeSmokeCheckRequired().Return(false).Times(1)
+				mockMachineScope.EXPECT().GetHostname().Return(testutils.MachineName, nil).Times(1)
+				mockMachineScope.EXPECT().GetInstanceMetadata().Return(nil).Times(1)
+				mockMachineScope.EXPECT().GetAfterburnMetadata().Return(nil).Times(2)
+				mockMachineScope.EXPECT().IgnitionPropagatedViaSecret().Return(true).Times(1)
+				mockMachineScope.EXPECT().CreateIgnitionSecretFromMachine([]byte(fmt.Sprintf(testutils.FullUserDataFmt, testutils.MachineName))).Return(ignitionSecret, nil).Times(1)
 				mockInfraClusterClient.EXPECT().CreateSecret(gomock.Any(), testutils.InfraNamespace, ignitionSecret).Return(&corev1.Secret{}, nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetSecret(gomock.Any(), testutils.InfraNamespace, ignitionSecret.Name).Return(ignitionSecret, nil).Times(1)
+				mockInfraClusterClient.EXPECT().UpdateSecret(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(&corev1.Secret{}, nil).Times(1)
+				mockMachineScope.EXPECT().CreateNetworkPolicyFromMachine().Return(nil).Times(1)
+				mockMachineScope.EXPECT().PersistentVolumeAccessModeDetectionRequired().Return(false).Times(1)
 				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vm, nil).Times(1)
 				mockInfraClusterClient.EXPECT().CreateVirtualMachine(gomock.Any(), testutils.InfraNamespace, vm).Return(vm, nil).Times(1)
 				mockInfraClusterClient.EXPECT().GetVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vmi, nil).Times(1)
+				mockMachineScope.EXPECT().GuestAgentRequired().Return(false).Times(1)
 				mockMachineScope.EXPECT().SyncMachine(*vm, vmi, fmt.Sprintf(providerIDFmt, testutils.InfraNamespace, testutils.MachineName)).Return(fmt.Errorf("test error")).Times(1)
 			},
 			expectedErr: "test-machine-name: Error during Create: failed to sync the Machine, with error: test error",
@@ -137,26 +660,61 @@ func TestCreate(t *testing.T) {
 			mockCtrl := gomock.NewController(t)
 			defer mockCtrl.Finish()
 			mockInfraClusterClient := mockInfraClusterClient.NewMockClient(mockCtrl)
+			mockInfraClusterClient.EXPECT().GetCapabilities().Return(infracluster.Capabilities{}).AnyTimes()
 			mockMachineScope := mockMachineScope.NewMockMachineScope(mockCtrl)
 
 			tc.expect(mockInfraClusterClient, mockMachineScope)
+			mockMachineScope.EXPECT().GetVirtualMachinePoolName().Return("").AnyTimes()
+			mockMachineScope.EXPECT().GetExistingVMName().Return("").AnyTimes()
+			mockMachineScope.EXPECT().GetSourceImageDigest().Return("", nil).AnyTimes()
+			mockMachineScope.EXPECT().GetDataSource().Return(testutils.InfraNamespace, "").AnyTimes()
+			mockMachineScope.EXPECT().GetIgnitionSourceCheck().Return(nil).AnyTimes()
+			mockMachineScope.EXPECT().IgnitionSecretVersioned().Return(false).AnyTimes()
+			mockMachineScope.EXPECT().CreateStepDone(gomock.Any()).DoAndReturn(func(step machinescope.CreateStep) bool {
+				for _, done := range tc.stepsDone {
+					if done == step {
+						return true
+					}
+				}
+				return false
+			}).AnyTimes()
+			var recordedSteps []machinescope.CreateStep
+			mockMachineScope.EXPECT().SetCreateStepDone(gomock.Any()).DoAndReturn(func(step machinescope.CreateStep) error {
+				recordedSteps = append(recordedSteps, step)
+				return nil
+			}).AnyTimes()
 
-			kubevirtVM := New(mockInfraClusterClient)
-			_, err := kubevirtVM.Create(mockMachineScope, []byte(testutils.SrcUserData))
+			kubevirtVM := New(mockInfraClusterClient, requeueAfter)
+			ready, err := kubevirtVM.Create(mockMachineScope, []byte(testutils.SrcUserData))
 			if tc.expectedErr != "" {
 				assert.Error(t, err, tc.expectedErr)
 			} else {
 				assert.NilError(t, err)
+				assert.Equal(t, tc.expectedReady, ready)
+			}
+			if tc.expectedSteps != nil {
+				assert.DeepEqual(t, recordedSteps, tc.expectedSteps)
 			}
 		})
 	}
 }
 
+func haltedVM(vm *kubevirtapiv1.VirtualMachine) *kubevirtapiv1.VirtualMachine {
+	result := vm.DeepCopy()
+	runHalted := kubevirtapiv1.RunStrategyHalted
+	result.Spec.RunStrategy = &runHalted
+	return result
+}
+
 func TestDelete(t *testing.T) {
+	notFoundErr := apierr.NewNotFound(schema.GroupResource{Group: "", Resource: "test"}, "3")
+
 	cases := []struct {
-		name        string
-		expectedErr string
-		expect      func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope)
+		name         string
+		expectedErr  string
+		syncedVMID   string
+		deletePolicy kubevirtproviderv1alpha1.BootVolumeDeletePolicy
+		expect       func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope)
 	}{
 		{
 			name: "Success",
@@ -166,19 +724,149 @@ func TestDelete(t *testing.T) {
 				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
 				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vm, nil).Times(1)
 				mockInfraClusterClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vm, nil).Times(1)
+				mockInfraClusterClient.EXPECT().UpdateVirtualMachine(gomock.Any(), testutils.InfraNamespace, haltedVM(vm)).Return(vm, nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(nil, notFoundErr).Times(1)
+				mockInfraClusterClient.EXPECT().DeleteVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(nil).Times(1)
+			},
+		},
+		{
+			name:       "Failure virtual machine recreated with the same name",
+			syncedVMID: "previous-vm-uid",
+			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope) {
+				vm := testutils.StubVirtualMachine(nil, nil, pointer.StringPtr("test-vm-uid"))
+
+				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
+				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vm, nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vm, nil).Times(1)
+				mockMachineScope.EXPECT().SetMachineRequiresReplacementCondition(gomock.Any()).Return(nil).Times(1)
+			},
+			expectedErr: "test-machine-name: Error during Delete: refused to operate on the Virtual Machine in infraCluster, with error: " +
+				"the Virtual Machine of providerID kubevirt://test-infra-namespace/test-machine-name has UID test-vm-uid, while the Machine was synced with UID previous-vm-uid",
+		},
+		{
+			name: "Success virtual machine already halted",
+			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope) {
+				vm := haltedVM(testutils.StubVirtualMachine(nil, nil, nil))
+
+				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
+				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vm, nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vm, nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(nil, notFoundErr).Times(1)
+				mockInfraClusterClient.EXPECT().DeleteVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(nil).Times(1)
+			},
+		},
+		{
+			name:         "Success retain the boot volume",
+			deletePolicy: kubevirtproviderv1alpha1.BootVolumeDeletePolicyRetain,
+			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope) {
+				vm := haltedVM(testutils.StubVirtualMachine(nil, nil, pointer.StringPtr("test-vm-uid")))
+				bootVolume := &unstructured.Unstructured{Object: map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"name":      vm.Spec.DataVolumeTemplates[0].Name,
+						"namespace": testutils.InfraNamespace,
+						"ownerReferences": []interface{}{
+							map[string]interface{}{"apiVersion": machinescope.APIVersion, "kind": machinescope.Kind, "name": testutils.MachineName, "uid": "test-vm-uid"},
+						},
+					},
+				}}
+
+				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
+				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vm, nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vm, nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(nil, notFoundErr).Times(1)
+				mockInfraClusterClient.EXPECT().GetDataVolume(gomock.Any(), testutils.InfraNamespace, vm.Spec.DataVolumeTemplates[0].Name).Return(bootVolume, nil).Times(1)
+				mockInfraClusterClient.EXPECT().UpdateDataVolume(gomock.Any(), testutils.InfraNamespace, gomock.Any()).DoAndReturn(
+					func(ctx context.Context, namespace string, dataVolume *cdiv1.DataVolume) (*cdiv1.DataVolume, error) {
+						assert.Equal(t, len(dataVolume.OwnerReferences), 0)
+						assert.Equal(t, dataVolume.Labels[machinescope.BootVolumeDeletePolicyLabel], "Retain")
+						assert.Equal(t, dataVolume.Labels[machinescope.RetainedFromMachineLabel], testutils.MachineName)
+						return dataVolume, nil
+					}).Times(1)
+				mockInfraClusterClient.EXPECT().DeleteVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(nil).Times(1)
+			},
+		},
+		{
+			name:         "Failure retain the boot volume",
+			deletePolicy: kubevirtproviderv1alpha1.BootVolumeDeletePolicyRetain,
+			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope) {
+				vm := haltedVM(testutils.StubVirtualMachine(nil, nil, nil))
+
+				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
+				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vm, nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vm, nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(nil, notFoundErr).Times(1)
+				mockInfraClusterClient.EXPECT().GetDataVolume(gomock.Any(), testutils.InfraNamespace, vm.Spec.DataVolumeTemplates[0].Name).Return(nil, fmt.Errorf("test error")).Times(1)
+			},
+			expectedErr: "test-machine-name: Error during Delete: failed to retain the boot volume in infraCluster, with error: test error",
+		},
+		{
+			name: "Success delete the uploaded ignition not owned by the virtual machine",
+			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope) {
+				vm := haltedVM(testutils.StubVirtualMachine(nil, nil, nil))
+				ignitionDataVolume := stubIgnitionDataVolume()
+
+				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
+				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vm, nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vm, nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(nil, notFoundErr).Times(1)
 				mockInfraClusterClient.EXPECT().DeleteVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(nil).Times(1)
+				mockMachineScope.EXPECT().GetInfraIgnitionSecretName().Return("").Times(1)
+				mockMachineScope.EXPECT().IgnitionPropagatedViaSecret().Return(false).Times(1)
+				mockMachineScope.EXPECT().CreateIgnitionDataVolumeFromMachine(int64(0)).Return(ignitionDataVolume, nil).Times(1)
+				mockInfraClusterClient.EXPECT().DeleteDataVolume(gomock.Any(), testutils.InfraNamespace, ignitionDataVolume.Name).Return(nil).Times(1)
+			},
+		},
+		{
+			name: "Requeue virtual machine instance still shutting down",
+			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope) {
+				vm := testutils.StubVirtualMachine(nil, nil, nil)
+
+				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
+				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vm, nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vm, nil).Times(1)
+				mockInfraClusterClient.EXPECT().UpdateVirtualMachine(gomock.Any(), testutils.InfraNamespace, haltedVM(vm)).Return(vm, nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(testutils.StubVirtualMachineInstance(), nil).Times(1)
+			},
+			expectedErr: "requeue in: 20s",
+		},
+		{
+			name: "Failure stop virtual machine",
+			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope) {
+				vm := testutils.StubVirtualMachine(nil, nil, nil)
+
+				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
+				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vm, nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vm, nil).Times(1)
+				mockInfraClusterClient.EXPECT().UpdateVirtualMachine(gomock.Any(), testutils.InfraNamespace, haltedVM(vm)).Return(nil, fmt.Errorf("test error")).Times(1)
 			},
+			expectedErr: "test-machine-name: Error during Delete: failed to stop Virtual Machine in infraCluster, with error: test error",
 		},
 		{
 			name: "Success virtual machine not found",
 			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope) {
 				vm := testutils.StubVirtualMachine(nil, nil, nil)
 
-				notFoundErr := apierr.NewNotFound(schema.GroupResource{Group: "", Resource: "test"}, "3")
+				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
+				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vm, nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(nil, notFoundErr).Times(1)
+				mockMachineScope.EXPECT().GetInfraIgnitionSecretName().Return("").Times(1)
+				mockMachineScope.EXPECT().IgnitionPropagatedViaSecret().Return(true).Times(1)
+			},
+		},
+		{
+			name: "Success virtual machine not found delete the uploaded ignition",
+			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope) {
+				vm := testutils.StubVirtualMachine(nil, nil, nil)
+				ignitionDataVolume := stubIgnitionDataVolume()
 
 				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
 				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vm, nil).Times(1)
 				mockInfraClusterClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(nil, notFoundErr).Times(1)
+				mockMachineScope.EXPECT().GetInfraIgnitionSecretName().Return("").Times(1)
+				mockMachineScope.EXPECT().IgnitionPropagatedViaSecret().Return(false).Times(1)
+				mockMachineScope.EXPECT().CreateIgnitionDataVolumeFromMachine(int64(0)).Return(ignitionDataVolume, nil).Times(1)
+				mockInfraClusterClient.EXPECT().DeleteDataVolume(gomock.Any(), testutils.InfraNamespace, ignitionDataVolume.Name).Return(
+					apierr.NewNotFound(schema.GroupResource{Resource: "datavolumes"}, ignitionDataVolume.Name)).Times(1)
 			},
 		},
 		{
@@ -210,6 +898,8 @@ func TestDelete(t *testing.T) {
 				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
 				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vm, nil).Times(1)
 				mockInfraClusterClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vm, nil).Times(1)
+				mockInfraClusterClient.EXPECT().UpdateVirtualMachine(gomock.Any(), testutils.InfraNamespace, haltedVM(vm)).Return(vm, nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(nil, notFoundErr).Times(1)
 				mockInfraClusterClient.EXPECT().DeleteVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(fmt.Errorf("test error")).Times(1)
 			},
 			expectedErr: "test-machine-name: Error during Delete: failed to delete Virtual Machine in infraCluster, with error: test error",
@@ -220,11 +910,22 @@ func TestDelete(t *testing.T) {
 			mockCtrl := gomock.NewController(t)
 			defer mockCtrl.Finish()
 			mockInfraClusterClient := mockInfraClusterClient.NewMockClient(mockCtrl)
+			mockInfraClusterClient.EXPECT().GetCapabilities().Return(infracluster.Capabilities{}).AnyTimes()
 			mockMachineScope := mockMachineScope.NewMockMachineScope(mockCtrl)
 
 			tc.expect(mockInfraClusterClient, mockMachineScope)
+			mockMachineScope.EXPECT().GetMachine().Return(stubMachineSyncedWith(t, tc.syncedVMID)).AnyTimes()
+			mockMachineScope.EXPECT().GetVirtualMachineUID().Return(tc.syncedVMID).AnyTimes()
+			deletePolicy := tc.deletePolicy
+			if deletePolicy == "" {
+				deletePolicy = kubevirtproviderv1alpha1.BootVolumeDeletePolicyDelete
+			}
+			mockMachineScope.EXPECT().GetBootVolumeDeletePolicy().Return(deletePolicy, nil).AnyTimes()
+			mockMachineScope.EXPECT().IgnitionSecretVersioned().Return(false).AnyTimes()
+			mockMachineScope.EXPECT().GetInfraIgnitionSecretName().Return("").AnyTimes()
+			mockMachineScope.EXPECT().IgnitionPropagatedViaSecret().Return(true).AnyTimes()
 
-			kubevirtVM := New(mockInfraClusterClient)
+			kubevirtVM := New(mockInfraClusterClient, requeueAfter)
 			err := kubevirtVM.Delete(mockMachineScope)
 			if tc.expectedErr != "" {
 				assert.Error(t, err, tc.expectedErr)
@@ -265,7 +966,7 @@ func TestExists(t *testing.T) {
 			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient) {
 				mockInfraClusterClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(nil, fmt.Errorf("test error")).Times(1)
 			},
-			expectedErr: "test-machine-name: Error during Exists: failed to get vm of the Machine, with error: test error",
+			expectedErr: "test-machine-name: Error during Exists: failed to get Virtual Machine from infraCluster, with error: test error",
 		},
 	}
 	for _, tc := range cases {
@@ -273,10 +974,11 @@ func TestExists(t *testing.T) {
 			mockCtrl := gomock.NewController(t)
 			defer mockCtrl.Finish()
 			mockInfraClusterClient := mockInfraClusterClient.NewMockClient(mockCtrl)
+			mockInfraClusterClient.EXPECT().GetCapabilities().Return(infracluster.Capabilities{}).AnyTimes()
 
 			tc.expect(mockInfraClusterClient)
 
-			kubevirtVM := New(mockInfraClusterClient)
+			kubevirtVM := New(mockInfraClusterClient, requeueAfter)
 			result, err := kubevirtVM.Exists(testutils.MachineName, testutils.InfraNamespace)
 			if tc.expectedErr != "" {
 				assert.Error(t, err, tc.expectedErr)
@@ -288,31 +990,140 @@ func TestExists(t *testing.T) {
 	}
 }
 
-type vmsForUpdate struct {
-	createdVM  *kubevirtapiv1.VirtualMachine
-	existingVM *kubevirtapiv1.VirtualMachine
-	updateVM   *kubevirtapiv1.VirtualMachine
-	resultVM   *kubevirtapiv1.VirtualMachine
-}
-
-func TestUpdate(t *testing.T) {
+func TestSyncStatus(t *testing.T) {
 	cases := []struct {
-		name           string
-		expectedErr    string
-		expectedResult bool
-		expect         func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope, vms vmsForUpdate)
+		name          string
+		expectedErr   string
+		expectedReady bool
+		expect        func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope)
 	}{
 		{
-			name: "Success",
-			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope, vms vmsForUpdate) {
+			name: "Success sync the machine without updating the virtual machine",
+			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope) {
+				vm := testutils.StubVirtualMachine(nil, nil, nil)
+				vm.Status.Ready = true
 				vmi := testutils.StubVirtualMachineInstance()
 
-				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
-				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vms.createdVM, nil).Times(1)
-				mockInfraClusterClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vms.existingVM, nil).Times(1)
-				mockInfraClusterClient.EXPECT().UpdateVirtualMachine(gomock.Any(), testutils.InfraNamespace, vms.updateVM).Return(vms.resultVM, nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vm, nil).Times(1)
 				mockInfraClusterClient.EXPECT().GetVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vmi, nil).Times(1)
-				mockMachineScope.EXPECT().SyncMachine(*vms.resultVM, vmi, fmt.Sprintf(providerIDFmt, testutils.InfraNamespace, testutils.MachineName)).Return(nil).Times(1)
+				mockMachineScope.EXPECT().GuestAgentRequired().Return(false).Times(1)
+				mockMachineScope.EXPECT().SyncMachine(*vm, vmi, fmt.Sprintf(providerIDFmt, testutils.InfraNamespace, testutils.MachineName)).Return(nil).Times(1)
+			},
+			expectedReady: true,
+		},
+		{
+			name: "Failure get virtual machine",
+			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope) {
+				mockInfraClusterClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(nil, fmt.Errorf("test error")).Times(1)
+			},
+			expectedErr: "test-machine-name: Error during SyncStatus: failed to get Virtual Machine from infraCluster, with error: test error",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockInfraClusterClient := mockInfraClusterClient.NewMockClient(mockCtrl)
+			mockMachineScope := mockMachineScope.NewMockMachineScope(mockCtrl)
+
+			tc.expect(mockInfraClusterClient, mockMachineScope)
+			machine, err := testutils.StubMachine()
+			assert.NilError(t, err)
+			mockMachineScope.EXPECT().GetMachine().Return(machine).AnyTimes()
+			mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).AnyTimes()
+			mockMachineScope.EXPECT().GetVirtualMachineName().Return(testutils.MachineName, nil).AnyTimes()
+			mockMachineScope.EXPECT().GetInfraNamespace().Return(testutils.InfraNamespace).AnyTimes()
+
+			kubevirtVM := New(mockInfraClusterClient, requeueAfter)
+			ready, err := kubevirtVM.SyncStatus(mockMachineScope)
+			if tc.expectedErr != "" {
+				assert.Error(t, err, tc.expectedErr)
+			} else {
+				assert.NilError(t, err)
+				assert.Equal(t, ready, tc.expectedReady)
+			}
+		})
+	}
+}
+
+type vmsForUpdate struct {
+	createdVM  *kubevirtapiv1.VirtualMachine
+	existingVM *kubevirtapiv1.VirtualMachine
+	updateVM   *kubevirtapiv1.VirtualMachine
+	resultVM   *kubevirtapiv1.VirtualMachine
+}
+
+func TestUpdate(t *testing.T) {
+	cases := []struct {
+		name               string
+		expectedErr        string
+		expectedResult     bool
+		expectedReady      bool
+		reconciliationMode kubevirtproviderv1alpha1.ReconciliationMode
+		syncedVMID         string
+		expect             func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope, vms vmsForUpdate)
+	}{
+		{
+			name:               "Success reconciliation disabled",
+			reconciliationMode: kubevirtproviderv1alpha1.ReconciliationModeNone,
+			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope, vms vmsForUpdate) {
+				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
+			},
+			expectedReady: true,
+		},
+		{
+			name:               "Success status only",
+			reconciliationMode: kubevirtproviderv1alpha1.ReconciliationModeStatusOnly,
+			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope, vms vmsForUpdate) {
+				vmi := testutils.StubVirtualMachineInstance()
+
+				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
+				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vms.createdVM, nil).Times(1)
+				mockMachineScope.EXPECT().CreateNetworkPolicyFromMachine().Return(nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vms.existingVM, nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vmi, nil).Times(1)
+				mockMachineScope.EXPECT().GuestAgentRequired().Return(false).Times(1)
+				mockMachineScope.EXPECT().SyncMachine(*vms.existingVM, vmi, fmt.Sprintf(providerIDFmt, testutils.InfraNamespace, testutils.MachineName)).Return(nil).Times(1)
+			},
+			expectedReady: true,
+		},
+		{
+			name:       "Failure virtual machine recreated with the same name",
+			syncedVMID: "previous-vm-uid",
+			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope, vms vmsForUpdate) {
+				vms.existingVM.UID = "test-vm-uid"
+
+				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
+				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vms.createdVM, nil).Times(1)
+				mockMachineScope.EXPECT().CreateNetworkPolicyFromMachine().Return(nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vms.existingVM, nil).Times(1)
+				mockMachineScope.EXPECT().SetMachineRequiresReplacementCondition(gomock.Any()).Return(nil).Times(1)
+			},
+			expectedErr: "test-machine-name: Error during Update: refused to operate on the Virtual Machine in infraCluster, with error: " +
+				"the Virtual Machine of providerID kubevirt://test-infra-namespace/test-machine-name has UID test-vm-uid, while the Machine was synced with UID previous-vm-uid",
+		},
+		{
+			name:               "Failure invalid reconciliation mode",
+			reconciliationMode: "some",
+			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope, vms vmsForUpdate) {
+				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
+			},
+			expectedErr: "test-machine-name: Error during Update: failed to build Virtual Machine struct, with error: test error",
+		},
+		{
+			name: "Success",
+			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope, vms vmsForUpdate) {
+				vmi := testutils.StubVirtualMachineInstance()
+
+				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
+				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vms.createdVM, nil).Times(1)
+				mockMachineScope.EXPECT().CreateNetworkPolicyFromMachine().Return(nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vms.existingVM, nil).Times(1)
+				mockMachineScope.EXPECT().PersistentVolumeAccessModeDetectionRequired().Return(false).Times(1)
+				mockInfraClusterClient.EXPECT().UpdateVirtualMachine(gomock.Any(), testutils.InfraNamespace, vms.updateVM).Return(vms.resultVM, nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vmi, nil).Times(1)
+				mockMachineScope.EXPECT().GuestAgentRequired().Return(false).Times(1)
+				mockMachineScope.EXPECT().SyncMachine(*vms.resultVM, vmi, fmt.Sprintf(providerIDFmt, testutils.InfraNamespace, testutils.MachineName)).Return(nil).Times(1)
 			},
 			expectedResult: true,
 		},
@@ -323,12 +1134,66 @@ func TestUpdate(t *testing.T) {
 
 				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
 				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vms.createdVM, nil).Times(1)
+				mockMachineScope.EXPECT().CreateNetworkPolicyFromMachine().Return(nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vms.existingVM, nil).Times(1)
+				mockMachineScope.EXPECT().PersistentVolumeAccessModeDetectionRequired().Return(false).Times(1)
+				mockInfraClusterClient.EXPECT().UpdateVirtualMachine(gomock.Any(), testutils.InfraNamespace, vms.updateVM).Return(vms.resultVM, nil).Times(1)
+				mockMachineScope.EXPECT().SyncMachine(*vms.resultVM, nil, fmt.Sprintf(providerIDFmt, testutils.InfraNamespace, testutils.MachineName)).Return(nil).Times(1)
+			},
+			expectedResult: true,
+		},
+		{
+			name: "Success vmi pending",
+			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope, vms vmsForUpdate) {
+				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
+				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vms.createdVM, nil).Times(1)
+				mockMachineScope.EXPECT().CreateNetworkPolicyFromMachine().Return(nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vms.existingVM, nil).Times(1)
+				mockMachineScope.EXPECT().PersistentVolumeAccessModeDetectionRequired().Return(false).Times(1)
+				mockInfraClusterClient.EXPECT().UpdateVirtualMachine(gomock.Any(), testutils.InfraNamespace, vms.updateVM).Return(vms.resultVM, nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(nil,
+					apierr.NewNotFound(schema.GroupResource{Group: "", Resource: "test"}, "3")).Times(1)
+				mockMachineScope.EXPECT().SyncMachine(*vms.resultVM, nil, fmt.Sprintf(providerIDFmt, testutils.InfraNamespace, testutils.MachineName)).Return(nil).Times(1)
+			},
+			expectedResult: true,
+		},
+		{
+			name: "Success guest agent not connected",
+			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope, vms vmsForUpdate) {
+				vmi := testutils.StubVirtualMachineInstance()
+
+				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
+				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vms.createdVM, nil).Times(1)
+				mockMachineScope.EXPECT().CreateNetworkPolicyFromMachine().Return(nil).Times(1)
 				mockInfraClusterClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vms.existingVM, nil).Times(1)
+				mockMachineScope.EXPECT().PersistentVolumeAccessModeDetectionRequired().Return(false).Times(1)
 				mockInfraClusterClient.EXPECT().UpdateVirtualMachine(gomock.Any(), testutils.InfraNamespace, vms.updateVM).Return(vms.resultVM, nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vmi, nil).Times(1)
+				mockMachineScope.EXPECT().GuestAgentRequired().Return(true).Times(1)
 				mockMachineScope.EXPECT().SyncMachine(*vms.resultVM, nil, fmt.Sprintf(providerIDFmt, testutils.InfraNamespace, testutils.MachineName)).Return(nil).Times(1)
 			},
 			expectedResult: true,
 		},
+		{
+			name: "Success guest agent connected",
+			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope, vms vmsForUpdate) {
+				vmi := testutils.StubVirtualMachineInstance()
+				vmi.Status.Conditions = []kubevirtapiv1.VirtualMachineInstanceCondition{
+					{Type: kubevirtapiv1.VirtualMachineInstanceAgentConnected, Status: corev1.ConditionTrue},
+				}
+
+				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
+				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vms.createdVM, nil).Times(1)
+				mockMachineScope.EXPECT().CreateNetworkPolicyFromMachine().Return(nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vms.existingVM, nil).Times(1)
+				mockMachineScope.EXPECT().PersistentVolumeAccessModeDetectionRequired().Return(false).Times(1)
+				mockInfraClusterClient.EXPECT().UpdateVirtualMachine(gomock.Any(), testutils.InfraNamespace, vms.updateVM).Return(vms.resultVM, nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vmi, nil).Times(1)
+				mockMachineScope.EXPECT().GuestAgentRequired().Return(true).Times(1)
+				mockMachineScope.EXPECT().SyncMachine(*vms.resultVM, vmi, fmt.Sprintf(providerIDFmt, testutils.InfraNamespace, testutils.MachineName)).Return(nil).Times(1)
+			},
+			expectedResult: true,
+		},
 		{
 			name: "Success wasn't update",
 			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope, vms vmsForUpdate) {
@@ -338,9 +1203,12 @@ func TestUpdate(t *testing.T) {
 
 				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
 				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vms.createdVM, nil).Times(1)
+				mockMachineScope.EXPECT().CreateNetworkPolicyFromMachine().Return(nil).Times(1)
 				mockInfraClusterClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vms.existingVM, nil).Times(1)
+				mockMachineScope.EXPECT().PersistentVolumeAccessModeDetectionRequired().Return(false).Times(1)
 				mockInfraClusterClient.EXPECT().UpdateVirtualMachine(gomock.Any(), testutils.InfraNamespace, vms.updateVM).Return(vms.resultVM, nil).Times(1)
 				mockInfraClusterClient.EXPECT().GetVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vmi, nil).Times(1)
+				mockMachineScope.EXPECT().GuestAgentRequired().Return(false).Times(1)
 				mockMachineScope.EXPECT().SyncMachine(*vms.resultVM, vmi, fmt.Sprintf(providerIDFmt, testutils.InfraNamespace, testutils.MachineName)).Return(nil).Times(1)
 			},
 			expectedResult: false,
@@ -358,16 +1226,43 @@ func TestUpdate(t *testing.T) {
 			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope, vms vmsForUpdate) {
 				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
 				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vms.createdVM, nil).Times(1)
+				mockMachineScope.EXPECT().CreateNetworkPolicyFromMachine().Return(nil).Times(1)
 				mockInfraClusterClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(nil, fmt.Errorf("test error")).Times(1)
 			},
 			expectedErr: "test-machine-name: Error during Update: failed to get Virtual Machine from infraCluster, with error: test error",
 		},
+		{
+			name: "Failure virtual machine not found yet - requeue",
+			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope, vms vmsForUpdate) {
+				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
+				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vms.createdVM, nil).Times(1)
+				mockMachineScope.EXPECT().CreateNetworkPolicyFromMachine().Return(nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(nil,
+					apierr.NewNotFound(schema.GroupResource{Group: "", Resource: "test"}, "3")).Times(1)
+				mockMachineScope.EXPECT().UpdateAllowed(machinescope.UpdatePolicy{Window: vmNotFoundWindow}).Return(true).Times(1)
+			},
+			expectedErr: "requeue in: 20s",
+		},
+		{
+			name: "Failure virtual machine not found",
+			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope, vms vmsForUpdate) {
+				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
+				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vms.createdVM, nil).Times(1)
+				mockMachineScope.EXPECT().CreateNetworkPolicyFromMachine().Return(nil).Times(1)
+				mockInfraClusterClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(nil,
+					apierr.NewNotFound(schema.GroupResource{Group: "", Resource: "test"}, "3")).Times(1)
+				mockMachineScope.EXPECT().UpdateAllowed(machinescope.UpdatePolicy{Window: vmNotFoundWindow}).Return(false).Times(1)
+			},
+			expectedErr: "test-machine-name: Error during Update: failed to get Virtual Machine from infraCluster, with error: test \"3\" not found",
+		},
 		{
 			name: "Failure update virtual machine",
 			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope, vms vmsForUpdate) {
 				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
 				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vms.createdVM, nil).Times(1)
+				mockMachineScope.EXPECT().CreateNetworkPolicyFromMachine().Return(nil).Times(1)
 				mockInfraClusterClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vms.existingVM, nil).Times(1)
+				mockMachineScope.EXPECT().PersistentVolumeAccessModeDetectionRequired().Return(false).Times(1)
 				mockInfraClusterClient.EXPECT().UpdateVirtualMachine(gomock.Any(), testutils.InfraNamespace, vms.updateVM).Return(nil, fmt.Errorf("test error")).Times(1)
 			},
 			expectedErr: "test-machine-name: Error during Update: failed to update Virtual Machine in infraCluster, with error: test error",
@@ -377,7 +1272,9 @@ func TestUpdate(t *testing.T) {
 			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope, vms vmsForUpdate) {
 				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
 				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vms.createdVM, nil).Times(1)
+				mockMachineScope.EXPECT().CreateNetworkPolicyFromMachine().Return(nil).Times(1)
 				mockInfraClusterClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vms.existingVM, nil).Times(1)
+				mockMachineScope.EXPECT().PersistentVolumeAccessModeDetectionRequired().Return(false).Times(1)
 				mockInfraClusterClient.EXPECT().UpdateVirtualMachine(gomock.Any(), testutils.InfraNamespace, vms.updateVM).Return(vms.resultVM, nil).Times(1)
 				mockInfraClusterClient.EXPECT().GetVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(nil, fmt.Errorf("test error")).Times(1)
 			},
@@ -390,9 +1287,12 @@ func TestUpdate(t *testing.T) {
 
 				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
 				mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vms.createdVM, nil).Times(1)
+				mockMachineScope.EXPECT().CreateNetworkPolicyFromMachine().Return(nil).Times(1)
 				mockInfraClusterClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vms.existingVM, nil).Times(1)
+				mockMachineScope.EXPECT().PersistentVolumeAccessModeDetectionRequired().Return(false).Times(1)
 				mockInfraClusterClient.EXPECT().UpdateVirtualMachine(gomock.Any(), testutils.InfraNamespace, vms.updateVM).Return(vms.resultVM, nil).Times(1)
 				mockInfraClusterClient.EXPECT().GetVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vmi, nil).Times(1)
+				mockMachineScope.EXPECT().GuestAgentRequired().Return(false).Times(1)
 				mockMachineScope.EXPECT().SyncMachine(*vms.resultVM, vmi, fmt.Sprintf(providerIDFmt, testutils.InfraNamespace, testutils.MachineName)).Return(fmt.Errorf("test error")).Times(1)
 			},
 			expectedErr: "test-machine-name: Error during Update: failed to sync the Machine, with error: test error",
@@ -403,6 +1303,7 @@ func TestUpdate(t *testing.T) {
 			mockCtrl := gomock.NewController(t)
 			defer mockCtrl.Finish()
 			mockInfraClusterClient := mockInfraClusterClient.NewMockClient(mockCtrl)
+			mockInfraClusterClient.EXPECT().GetCapabilities().Return(infracluster.Capabilities{}).AnyTimes()
 			mockMachineScope := mockMachineScope.NewMockMachineScope(mockCtrl)
 
 			vms := vmsForUpdate{
@@ -421,21 +1322,547 @@ func TestUpdate(t *testing.T) {
 			vms.resultVM.Status.Ready = true
 
 			tc.expect(mockInfraClusterClient, mockMachineScope, vms)
+			mockMachineScope.EXPECT().GetMachine().Return(stubMachineSyncedWith(t, tc.syncedVMID)).AnyTimes()
+			mockMachineScope.EXPECT().GetVirtualMachineUID().Return(tc.syncedVMID).AnyTimes()
+			mockMachineScope.EXPECT().IgnitionSecretVersioned().Return(false).AnyTimes()
+			switch tc.reconciliationMode {
+			case "":
+				mockMachineScope.EXPECT().GetReconciliationMode().Return(kubevirtproviderv1alpha1.ReconciliationModeFull, nil).Times(1)
+			case kubevirtproviderv1alpha1.ReconciliationModeNone, kubevirtproviderv1alpha1.ReconciliationModeStatusOnly:
+				mockMachineScope.EXPECT().GetReconciliationMode().Return(tc.reconciliationMode, nil).Times(1)
+			default:
+				mockMachineScope.EXPECT().GetReconciliationMode().Return(kubevirtproviderv1alpha1.ReconciliationMode(""), fmt.Errorf("test error")).Times(1)
+			}
 
-			kubevirtVM := New(mockInfraClusterClient)
-			isUpdated, _, err := kubevirtVM.Update(mockMachineScope)
+			kubevirtVM := New(mockInfraClusterClient, requeueAfter)
+			isUpdated, ready, err := kubevirtVM.Update(mockMachineScope)
 			if tc.expectedErr != "" {
 				assert.Error(t, err, tc.expectedErr)
 			} else {
 				assert.NilError(t, err)
 				assert.Equal(t, isUpdated, tc.expectedResult)
+				if tc.reconciliationMode != "" {
+					assert.Equal(t, ready, tc.expectedReady)
+				}
+			}
+		})
+	}
+}
+
+func TestEnsureNetworkPolicy(t *testing.T) {
+	networkPolicy := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-policy", Namespace: testutils.InfraNamespace},
+		Spec:       networkingv1.NetworkPolicySpec{PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}},
+	}
+	changedNetworkPolicy := networkPolicy.DeepCopy()
+	changedNetworkPolicy.ResourceVersion = "1234"
+	changedNetworkPolicy.Spec.PolicyTypes = nil
+	restoredNetworkPolicy := networkPolicy.DeepCopy()
+	restoredNetworkPolicy.ResourceVersion = "1234"
+	notFound := apierr.NewNotFound(schema.GroupResource{Resource: "networkpolicies"}, "test-policy")
+
+	cases := []struct {
+		name        string
+		expectedErr string
+		expect      func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope)
+	}{
+		{
+			name: "isolation not required",
+			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope) {
+				mockMachineScope.EXPECT().CreateNetworkPolicyFromMachine().Return(nil).Times(1)
+			},
+		},
+		{
+			name: "create network policy",
+			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope) {
+				mockMachineScope.EXPECT().CreateNetworkPolicyFromMachine().Return(networkPolicy).Times(1)
+				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).AnyTimes()
+				mockInfraClusterClient.EXPECT().GetNetworkPolicy(gomock.Any(), testutils.InfraNamespace, "test-policy").Return(nil, notFound).Times(1)
+				mockInfraClusterClient.EXPECT().CreateNetworkPolicy(gomock.Any(), testutils.InfraNamespace, networkPolicy).Return(networkPolicy, nil).Times(1)
+			},
+		},
+		{
+			name: "network policy unchanged",
+			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope) {
+				mockMachineScope.EXPECT().CreateNetworkPolicyFromMachine().Return(networkPolicy).Times(1)
+				mockInfraClusterClient.EXPECT().GetNetworkPolicy(gomock.Any(), testutils.InfraNamespace, "test-policy").Return(restoredNetworkPolicy.DeepCopy(), nil).Times(1)
+			},
+		},
+		{
+			name: "restore changed network policy",
+			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope) {
+				mockMachineScope.EXPECT().CreateNetworkPolicyFromMachine().Return(networkPolicy).Times(1)
+				mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).AnyTimes()
+				mockInfraClusterClient.EXPECT().GetNetworkPolicy(gomock.Any(), testutils.InfraNamespace, "test-policy").Return(changedNetworkPolicy.DeepCopy(), nil).Times(1)
+				mockInfraClusterClient.EXPECT().UpdateNetworkPolicy(gomock.Any(), testutils.InfraNamespace, restoredNetworkPolicy).Return(restoredNetworkPolicy, nil).Times(1)
+			},
+		},
+		{
+			name: "failure get network policy",
+			expect: func(mockInfraClusterClient *mockInfraClusterClient.MockClient, mockMachineScope *mockMachineScope.MockMachineScope) {
+				mockMachineScope.EXPECT().CreateNetworkPolicyFromMachine().Return(networkPolicy).Times(1)
+				mockInfraClusterClient.EXPECT().GetNetworkPolicy(gomock.Any(), testutils.InfraNamespace, "test-policy").Return(nil, fmt.Errorf("test error")).Times(1)
+			},
+			expectedErr: "test error",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockInfraClusterClient := mockInfraClusterClient.NewMockClient(mockCtrl)
+			mockInfraClusterClient.EXPECT().GetCapabilities().Return(infracluster.Capabilities{}).AnyTimes()
+			mockMachineScope := mockMachineScope.NewMockMachineScope(mockCtrl)
+			tc.expect(mockInfraClusterClient, mockMachineScope)
+
+			m := &manager{infraClusterClient: mockInfraClusterClient, requeueAfter: requeueAfter}
+			err := m.ensureNetworkPolicy(mockMachineScope)
+			if tc.expectedErr != "" {
+				assert.Error(t, err, tc.expectedErr)
+			} else {
+				assert.NilError(t, err)
 			}
 		})
 	}
 }
 
+func TestKeepDataVolumeAccessModes(t *testing.T) {
+	existingVM := testutils.StubVirtualMachine(nil, nil, nil)
+	existingVM.Spec.DataVolumeTemplates[0].Spec.PVC.AccessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
+	vm := testutils.StubVirtualMachine(nil, nil, nil)
+	vm.Spec.DataVolumeTemplates[0].Spec.PVC.AccessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany}
+
+	keepDataVolumeAccessModes(vm, existingVM)
+	assert.DeepEqual(t, vm.Spec.DataVolumeTemplates[0].Spec.PVC.AccessModes, []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce})
+}
+
+func TestKeepNetworkData(t *testing.T) {
+	vm := testutils.StubVirtualMachine(nil, nil, nil)
+	existingVM := testutils.StubVirtualMachine(nil, nil, nil)
+	existingVM.Spec.Template.Spec.Volumes[1].CloudInitConfigDrive.NetworkData = `{"links":[]}`
+
+	keepNetworkData(vm, existingVM)
+	assert.Equal(t, vm.Spec.Template.Spec.Volumes[1].CloudInitConfigDrive.NetworkData, `{"links":[]}`)
+}
+
+// stubMachineSyncedWith returns a Machine synced with the VirtualMachine of the UID, or a Machine which
+// wasn't synced yet when the UID is empty
+func stubMachineSyncedWith(t *testing.T, vmID string) *machinev1.Machine {
+	machine, err := testutils.StubMachine()
+	assert.NilError(t, err)
+	if vmID != "" {
+		machine.Spec.ProviderID = pointer.StringPtr(FormatProviderID(testutils.InfraNamespace, testutils.MachineName))
+		machine.Annotations = map[string]string{machinescope.KubevirtIdAnnotationKey: vmID}
+	}
+	return machine
+}
+
+func TestVerifyVirtualMachine(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	vm := testutils.StubVirtualMachine(nil, nil, pointer.StringPtr("test-vm-uid"))
+
+	verify := func(machine *machinev1.Machine, vmID string) error {
+		mockMachineScope := mockMachineScope.NewMockMachineScope(mockCtrl)
+		mockMachineScope.EXPECT().GetMachine().Return(machine).Times(1)
+		mockMachineScope.EXPECT().GetVirtualMachineUID().Return(vmID).AnyTimes()
+		if vmID != "" && vmID != "test-vm-uid" {
+			mockMachineScope.EXPECT().SetMachineRequiresReplacementCondition(gomock.Any()).Return(nil).Times(1)
+		}
+		return verifyVirtualMachine(mockMachineScope, vm)
+	}
+
+	assert.NilError(t, verify(stubMachineSyncedWith(t, ""), ""))
+	assert.NilError(t, verify(stubMachineSyncedWith(t, "test-vm-uid"), "test-vm-uid"))
+	err := verify(stubMachineSyncedWith(t, "previous-vm-uid"), "previous-vm-uid")
+	assert.Assert(t, IsVirtualMachineMismatch(err))
+	// The VirtualMachine isn't the one the providerID points to
+	otherMachine := stubMachineSyncedWith(t, "previous-vm-uid")
+	otherMachine.Spec.ProviderID = pointer.StringPtr(FormatProviderID(testutils.InfraNamespace, "other-vm"))
+	assert.NilError(t, verify(otherMachine, ""))
+}
+
+func TestCreatedBy(t *testing.T) {
+	withMachineUID := func(vm *kubevirtapiv1.VirtualMachine, machineUID string) *kubevirtapiv1.VirtualMachine {
+		vm.Annotations = map[string]string{machinescope.MachineUIDAnnotation: machineUID}
+		return vm
+	}
+	virtualMachineFromMachine := withMachineUID(testutils.StubVirtualMachine(nil, nil, nil), "machine-uid")
+	existingVM := withMachineUID(testutils.StubVirtualMachine(nil, nil, nil), "machine-uid")
+	existingVM.Labels["extra"] = "label"
+	assert.Assert(t, createdBy(existingVM, virtualMachineFromMachine))
+
+	foreignVM := withMachineUID(testutils.StubVirtualMachine(nil, nil, nil), "machine-uid")
+	foreignVM.Labels = map[string]string{}
+	assert.Assert(t, !createdBy(foreignVM, virtualMachineFromMachine))
+
+	// A Virtual Machine of another Machine of the cluster carries the same labels
+	otherMachineVM := withMachineUID(testutils.StubVirtualMachine(nil, nil, nil), "other-machine-uid")
+	assert.Assert(t, !createdBy(otherMachineVM, virtualMachineFromMachine))
+	assert.Assert(t, !createdBy(testutils.StubVirtualMachine(nil, nil, nil), virtualMachineFromMachine))
+
+	// Without the UID of the Machine, the ownership can't be told
+	assert.Assert(t, !createdBy(existingVM, testutils.StubVirtualMachine(nil, nil, nil)))
+}
+
 func TestAddHostNameToUserData(t *testing.T) {
 	result, _ := addHostnameToUserData([]byte(testutils.SrcUserData), testutils.MachineName)
 	assert.Equal(t, string(result), fmt.Sprintf(testutils.FullUserDataFmt, testutils.MachineName))
 
 }
+
+func TestAddInstanceMetadataToUserData(t *testing.T) {
+	userData, err := addHostnameToUserData([]byte(testutils.SrcUserData), testutils.MachineName)
+	assert.NilError(t, err)
+	result, err := addInstanceMetadataToUserData(userData, map[string]string{"instance-id": "kubevirt://ns/name", "zone": "a"})
+	assert.NilError(t, err)
+
+	var dataMap map[string]interface{}
+	assert.NilError(t, json.Unmarshal(result, &dataMap))
+	files := dataMap["storage"].(map[string]interface{})["files"].([]interface{})
+	assert.Equal(t, len(files), 2)
+	metadataFile := files[1].(map[string]interface{})
+	assert.Equal(t, metadataFile["path"], "/etc/kubevirt/instance-metadata.json")
+	assert.Equal(t, metadataFile["contents"].(map[string]interface{})["source"],
+		"data:text/plain;charset=utf-8;base64,"+base64.StdEncoding.EncodeToString([]byte(`{"instance-id":"kubevirt://ns/name","zone":"a"}`)))
+}
+
+func TestAddAfterburnMetadataToUserData(t *testing.T) {
+	result, err := addAfterburnMetadataToUserData([]byte(testutils.SrcUserData), map[string]string{
+		"AFTERBURN_KUBEVIRT_PROVIDER_ID":   "kubevirt://ns/name",
+		"AFTERBURN_KUBEVIRT_INSTANCE_TYPE": "large",
+	})
+	assert.NilError(t, err)
+
+	var dataMap map[string]interface{}
+	assert.NilError(t, json.Unmarshal(result, &dataMap))
+	files := dataMap["storage"].(map[string]interface{})["files"].([]interface{})
+	assert.Equal(t, len(files), 1)
+	afterburnFile := files[0].(map[string]interface{})
+	assert.Equal(t, afterburnFile["path"], "/etc/kubevirt/afterburn.env")
+	assert.Equal(t, afterburnFile["contents"].(map[string]interface{})["source"],
+		"data:text/plain;charset=utf-8;base64,"+base64.StdEncoding.EncodeToString([]byte(
+			"AFTERBURN_KUBEVIRT_INSTANCE_TYPE=large\nAFTERBURN_KUBEVIRT_PROVIDER_ID=kubevirt://ns/name\n")))
+}
+
+func TestMergeUserData(t *testing.T) {
+	source := &kubevirtproviderv1alpha1.IgnitionMergeSource{URL: "https://api-int.tenant.example.com:22623/config/worker", CABundle: "ca"}
+	caSource := "data:text/plain;charset=utf-8;base64," + base64.StdEncoding.EncodeToString([]byte("ca"))
+
+	result, err := mergeUserData([]byte(testutils.SrcUserData), source)
+	assert.NilError(t, err)
+	assert.Equal(t, string(result), fmt.Sprintf(
+		`{"ignition":{"config":{"merge":[{"source":"%s"}]},"security":{"tls":{"certificateAuthorities":[{"source":"%s"}]}},"version":"3.1.0"}}`,
+		source.URL, caSource))
+
+	result, err = mergeUserData([]byte(`{"ignition":{"version":"2.2.0"},"passwd":{"users":[{"name":"core"}]}}`),
+		&kubevirtproviderv1alpha1.IgnitionMergeSource{URL: source.URL})
+	assert.NilError(t, err)
+	assert.Equal(t, string(result), fmt.Sprintf(`{"ignition":{"config":{"append":[{"source":"%s"}]},"version":"2.2.0"}}`, source.URL))
+
+	_, err = mergeUserData([]byte(`{"storage":{}}`), source)
+	assert.Error(t, err, "failed to get the ignition version of userData")
+}
+
+func TestRewriteUserDataSources(t *testing.T) {
+	result, err := rewriteUserDataSources([]byte(`{"ignition":{"config":{"merge":[`+
+		`{"source":"https://api-int.tenant.example.com:22623/config/worker"},{"source":"data:,inline"}]},"version":"3.1.0"}}`), "192.168.10.5:22623")
+	assert.NilError(t, err)
+	assert.Equal(t, string(result), `{"ignition":{"config":{"merge":[`+
+		`{"source":"https://192.168.10.5:22623/config/worker"},{"source":"data:,inline"}]},"version":"3.1.0"}}`)
+
+	result, err = rewriteUserDataSources([]byte(`{"ignition":{"config":{"append":[`+
+		`{"source":"https://api-int.tenant.example.com:22623/config/worker"}]},"version":"2.2.0"}}`), "mcs.tenant.example.com")
+	assert.NilError(t, err)
+	assert.Equal(t, string(result), `{"ignition":{"config":{"append":[`+
+		`{"source":"https://mcs.tenant.example.com/config/worker"}]},"version":"2.2.0"}}`)
+
+	result, err = rewriteUserDataSources([]byte(testutils.SrcUserData), "10.200.0.15:22623")
+	assert.NilError(t, err)
+	assert.Equal(t, string(result), strings.Replace(testutils.SrcUserData, "192.168.123.15", "10.200.0.15", 1))
+}
+
+func TestAddClusterProxyToUserData(t *testing.T) {
+	trustBundleSource := "data:text/plain;charset=utf-8;base64," + base64.StdEncoding.EncodeToString([]byte("bundle"))
+	mergeSource := &kubevirtproviderv1alpha1.IgnitionMergeSource{URL: "https://api-int.tenant.example.com:22623/config/worker", CABundle: "ca"}
+	mergedUserData, err := mergeUserData([]byte(testutils.SrcUserData), mergeSource)
+	assert.NilError(t, err)
+
+	cases := []struct {
+		name        string
+		userData    string
+		proxy       *machinescope.ClusterProxy
+		expected    string
+		expectedErr string
+	}{
+		{
+			name:     "Success proxy and trust bundle",
+			userData: string(mergedUserData),
+			proxy: &machinescope.ClusterProxy{
+				HTTPProxy:   "http://proxy.example.com:3128",
+				HTTPSProxy:  "http://proxy.example.com:3128",
+				NoProxy:     ".cluster.local, 10.0.0.0/16",
+				TrustBundle: "bundle",
+			},
+			expected: fmt.Sprintf(`{"ignition":{"config":{"merge":[{"source":"%s"}]},`+
+				`"proxy":{"httpProxy":"http://proxy.example.com:3128","httpsProxy":"http://proxy.example.com:3128","noProxy":[".cluster.local","10.0.0.0/16"]},`+
+				`"security":{"tls":{"certificateAuthorities":[{"source":"%s"},{"source":"%s"}]}},"version":"3.1.0"},`+
+				`"storage":{"files":[{"contents":{"source":"%s"},"filesystem":"root","mode":420,"path":"%s"}]}}`,
+				mergeSource.URL, "data:text/plain;charset=utf-8;base64,"+base64.StdEncoding.EncodeToString([]byte("ca")),
+				trustBundleSource, trustBundleSource, trustBundlePath),
+		},
+		{
+			name:     "Success trust bundle without proxy",
+			userData: `{"ignition":{"version":"2.2.0"}}`,
+			proxy:    &machinescope.ClusterProxy{TrustBundle: "bundle"},
+			expected: fmt.Sprintf(`{"ignition":{"security":{"tls":{"certificateAuthorities":[{"source":"%s"}]}},"version":"2.2.0"},`+
+				`"storage":{"files":[{"contents":{"source":"%s"},"filesystem":"root","mode":420,"path":"%s"}]}}`,
+				trustBundleSource, trustBundleSource, trustBundlePath),
+		},
+		{
+			name:        "Failure proxy unsupported by the ignition version",
+			userData:    `{"ignition":{"version":"3.0.0"}}`,
+			proxy:       &machinescope.ClusterProxy{HTTPSProxy: "http://proxy.example.com:3128"},
+			expectedErr: "ignition version 3.0.0 of userData doesn't support a proxy",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := addClusterProxyToUserData([]byte(tc.userData), tc.proxy)
+			if tc.expectedErr != "" {
+				assert.Error(t, err, tc.expectedErr)
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, string(result), tc.expected)
+		})
+	}
+}
+
+func TestAddHostNameToUserDataInvalidJSON(t *testing.T) {
+	_, err := addHostnameToUserData([]byte("not json"), testutils.MachineName)
+	assert.ErrorContains(t, err, "failed to parse userData")
+}
+
+func TestParseProviderID(t *testing.T) {
+	cases := []struct {
+		name              string
+		providerID        string
+		expectedNamespace string
+		expectedName      string
+		expectedErr       string
+	}{
+		{
+			name:              "Success",
+			providerID:        FormatProviderID(testutils.InfraNamespace, testutils.MachineName),
+			expectedNamespace: testutils.InfraNamespace,
+			expectedName:      testutils.MachineName,
+		},
+		{
+			name:        "Failure wrong prefix",
+			providerID:  "aws:///us-east-1a/i-0123456789",
+			expectedErr: "providerID \"aws:///us-east-1a/i-0123456789\" doesn't start with \"kubevirt://\"",
+		},
+		{
+			name:        "Failure missing name",
+			providerID:  "kubevirt://test-namespace",
+			expectedErr: "providerID \"kubevirt://test-namespace\" isn't of the format \"kubevirt://%s/%s\"",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			namespace, name, err := ParseProviderID(tc.providerID)
+			if tc.expectedErr != "" {
+				assert.Error(t, err, tc.expectedErr)
+			} else {
+				assert.NilError(t, err)
+				assert.Equal(t, tc.expectedNamespace, namespace)
+				assert.Equal(t, tc.expectedName, name)
+			}
+		})
+	}
+}
+
+func TestOperationErrorKeepsCause(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockInfraClusterClient := mockInfraClusterClient.NewMockClient(mockCtrl)
+	mockInfraClusterClient.EXPECT().GetCapabilities().Return(infracluster.Capabilities{}).AnyTimes()
+	mockMachineScope := mockMachineScope.NewMockMachineScope(mockCtrl)
+
+	vm := testutils.StubVirtualMachine(nil, nil, nil)
+	mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
+	mockMachineScope.EXPECT().GetReconciliationMode().Return(kubevirtproviderv1alpha1.ReconciliationModeFull, nil).Times(1)
+	mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vm, nil).Times(1)
+	mockMachineScope.EXPECT().CreateNetworkPolicyFromMachine().Return(nil).Times(1)
+	mockInfraClusterClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(nil,
+		apierr.NewNotFound(schema.GroupResource{Group: "", Resource: "test"}, "3")).Times(1)
+	mockMachineScope.EXPECT().UpdateAllowed(machinescope.UpdatePolicy{Window: vmNotFoundWindow}).Return(false).Times(1)
+
+	kubevirtVM := New(mockInfraClusterClient, requeueAfter)
+	_, _, err := kubevirtVM.Update(mockMachineScope)
+
+	var operationErr *OperationError
+	assert.Assert(t, errors.As(err, &operationErr))
+	assert.Equal(t, "Update", operationErr.Operation)
+	assert.Equal(t, StageGetVirtualMachine, operationErr.Stage)
+	assert.Assert(t, apierr.IsNotFound(err))
+}
+
+func TestIsQuotaExceeded(t *testing.T) {
+	gr := schema.GroupResource{Group: "kubevirt.io", Resource: "virtualmachines"}
+	quotaErr := apierr.NewForbidden(gr, testutils.MachineName,
+		fmt.Errorf("exceeded quota: tenant-quota, requested: count/virtualmachines.kubevirt.io=1, used: count/virtualmachines.kubevirt.io=5, limited: count/virtualmachines.kubevirt.io=5"))
+
+	assert.Assert(t, IsQuotaExceeded(quotaErr))
+	assert.Assert(t, IsQuotaExceeded(&OperationError{MachineName: testutils.MachineName, Operation: "Create", Stage: StageCreateVirtualMachine, Err: quotaErr}))
+	assert.Assert(t, !IsQuotaExceeded(apierr.NewForbidden(gr, testutils.MachineName, fmt.Errorf("not allowed"))))
+	assert.Assert(t, !IsQuotaExceeded(fmt.Errorf("exceeded quota")))
+	assert.Assert(t, !IsQuotaExceeded(nil))
+}
+
+func TestOperationErrorTranslation(t *testing.T) {
+	gr := schema.GroupResource{Group: "kubevirt.io", Resource: "virtualmachines"}
+	cases := []struct {
+		name                 string
+		operation            string
+		err                  error
+		expectedRequeueAfter time.Duration
+		expectedReason       machinev1.MachineStatusError
+	}{
+		{
+			name:                 "timeout",
+			operation:            "Create",
+			err:                  apierr.NewTimeoutError("request timed out", 0),
+			expectedRequeueAfter: transientErrorRequeueAfter,
+		},
+		{
+			name:                 "too many requests with a suggested delay",
+			operation:            "Delete",
+			err:                  apierr.NewTooManyRequests("slow down", 5),
+			expectedRequeueAfter: 5 * time.Second,
+		},
+		{
+			name:                 "circuit open",
+			operation:            "Update",
+			err:                  &infracluster.CircuitOpenError{Until: time.Now()},
+			expectedRequeueAfter: transientErrorRequeueAfter,
+		},
+		{
+			name:           "invalid creation",
+			operation:      "Create",
+			err:            apierr.NewInvalid(schema.GroupKind{Group: "kubevirt.io", Kind: "VirtualMachine"}, testutils.MachineName, nil),
+			expectedReason: machinev1.InvalidConfigurationMachineError,
+		},
+		{
+			name:           "invalid update",
+			operation:      "Update",
+			err:            apierr.NewInvalid(schema.GroupKind{Group: "kubevirt.io", Kind: "VirtualMachine"}, testutils.MachineName, nil),
+			expectedReason: machinev1.UpdateMachineError,
+		},
+		{
+			name:           "forbidden creation",
+			operation:      "Create",
+			err:            apierr.NewForbidden(gr, testutils.MachineName, fmt.Errorf("exceeded quota")),
+			expectedReason: machinev1.CreateMachineError,
+		},
+		{
+			name:      "not found",
+			operation: "Delete",
+			err:       apierr.NewNotFound(gr, testutils.MachineName),
+		},
+		{
+			name:           "machine error of the machine scope",
+			operation:      "Create",
+			err:            machinecontroller.InvalidMachineConfiguration("missing value for NetworkName"),
+			expectedReason: machinev1.InvalidConfigurationMachineError,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := newOperationError(testutils.MachineName, tc.operation, StageGetVirtualMachine, tc.err)
+
+			var requeueErr *machinecontroller.RequeueAfterError
+			if tc.expectedRequeueAfter != 0 {
+				assert.Assert(t, errors.As(err, &requeueErr))
+				assert.Equal(t, requeueErr.RequeueAfter, tc.expectedRequeueAfter)
+			} else {
+				assert.Assert(t, !errors.As(err, &requeueErr))
+			}
+
+			var machineErr *machinecontroller.MachineError
+			if tc.expectedReason != "" {
+				assert.Assert(t, errors.As(err, &machineErr))
+				assert.Equal(t, machineErr.Reason, tc.expectedReason)
+			} else {
+				assert.Assert(t, !errors.As(err, &machineErr))
+			}
+			// The original error is still found by unwrapping the OperationError
+			assert.Assert(t, errors.Is(err, tc.err))
+		})
+	}
+}
+
+func TestRestartRequired(t *testing.T) {
+	notFoundErr := apierr.NewNotFound(schema.GroupResource{Group: "", Resource: "test"}, "3")
+	cases := []struct {
+		name           string
+		vmi            func(vm *kubevirtapiv1.VirtualMachine) *kubevirtapiv1.VirtualMachineInstance
+		expectedResult bool
+	}{
+		{
+			name: "Success VirtualMachineInstance runs with the requested resources",
+			vmi: func(vm *kubevirtapiv1.VirtualMachine) *kubevirtapiv1.VirtualMachineInstance {
+				vmi := testutils.StubVirtualMachineInstance()
+				vm.Spec.Template.Spec.Domain.Resources.DeepCopyInto(&vmi.Spec.Domain.Resources)
+				return vmi
+			},
+		},
+		{
+			name: "Success VirtualMachineInstance runs with other memory",
+			vmi: func(vm *kubevirtapiv1.VirtualMachine) *kubevirtapiv1.VirtualMachineInstance {
+				vmi := testutils.StubVirtualMachineInstance()
+				vm.Spec.Template.Spec.Domain.Resources.DeepCopyInto(&vmi.Spec.Domain.Resources)
+				vmi.Spec.Domain.Resources.Requests[corev1.ResourceMemory] = apiresource.MustParse("1Gi")
+				return vmi
+			},
+			expectedResult: true,
+		},
+		{
+			name: "Success VirtualMachineInstance runs without the requested CPU",
+			vmi: func(vm *kubevirtapiv1.VirtualMachine) *kubevirtapiv1.VirtualMachineInstance {
+				vmi := testutils.StubVirtualMachineInstance()
+				vm.Spec.Template.Spec.Domain.Resources.DeepCopyInto(&vmi.Spec.Domain.Resources)
+				vm.Spec.Template.Spec.Domain.Resources.Requests[corev1.ResourceCPU] = apiresource.MustParse("2")
+				return vmi
+			},
+			expectedResult: true,
+		},
+		{
+			name: "Success VirtualMachineInstance doesn't exist",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockInfraClusterClient := mockInfraClusterClient.NewMockClient(mockCtrl)
+			mockInfraClusterClient.EXPECT().GetCapabilities().Return(infracluster.Capabilities{}).AnyTimes()
+			mockMachineScope := mockMachineScope.NewMockMachineScope(mockCtrl)
+
+			vm := testutils.StubVirtualMachine(nil, nil, nil)
+			mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).AnyTimes()
+			mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(vm, nil).Times(1)
+			if tc.vmi != nil {
+				mockInfraClusterClient.EXPECT().GetVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(tc.vmi(vm), nil).Times(1)
+			} else {
+				mockInfraClusterClient.EXPECT().GetVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(nil, notFoundErr).Times(1)
+			}
+
+			kubevirtVM := New(mockInfraClusterClient, requeueAfter)
+			result, err := kubevirtVM.RestartRequired(mockMachineScope)
+			assert.NilError(t, err)
+			assert.Equal(t, result, tc.expectedResult)
+		})
+	}
+}
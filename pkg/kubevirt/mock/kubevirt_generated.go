@@ -78,6 +78,21 @@ func (mr *MockKubevirtVMMockRecorder) Update(machineScope interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockKubevirtVM)(nil).Update), machineScope)
 }
 
+// SyncStatus mocks base method
+func (m *MockKubevirtVM) SyncStatus(machineScope machinescope.MachineScope) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SyncStatus", machineScope)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SyncStatus indicates an expected call of SyncStatus
+func (mr *MockKubevirtVMMockRecorder) SyncStatus(machineScope interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SyncStatus", reflect.TypeOf((*MockKubevirtVM)(nil).SyncStatus), machineScope)
+}
+
 // Exists mocks base method
 func (m *MockKubevirtVM) Exists(machineName, infraNamespace string) (bool, error) {
 	m.ctrl.T.Helper()
@@ -92,3 +107,78 @@ func (mr *MockKubevirtVMMockRecorder) Exists(machineName, infraNamespace interfa
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Exists", reflect.TypeOf((*MockKubevirtVM)(nil).Exists), machineName, infraNamespace)
 }
+
+// QuotaVersion mocks base method
+func (m *MockKubevirtVM) QuotaVersion(infraNamespace string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QuotaVersion", infraNamespace)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QuotaVersion indicates an expected call of QuotaVersion
+func (mr *MockKubevirtVMMockRecorder) QuotaVersion(infraNamespace interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QuotaVersion", reflect.TypeOf((*MockKubevirtVM)(nil).QuotaVersion), infraNamespace)
+}
+
+// ExceededQuotas mocks base method
+func (m *MockKubevirtVM) ExceededQuotas(machineScope machinescope.MachineScope) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExceededQuotas", machineScope)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExceededQuotas indicates an expected call of ExceededQuotas
+func (mr *MockKubevirtVMMockRecorder) ExceededQuotas(machineScope interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExceededQuotas", reflect.TypeOf((*MockKubevirtVM)(nil).ExceededQuotas), machineScope)
+}
+
+// QuotaRejection mocks base method
+func (m *MockKubevirtVM) QuotaRejection(machineScope machinescope.MachineScope) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QuotaRejection", machineScope)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QuotaRejection indicates an expected call of QuotaRejection
+func (mr *MockKubevirtVMMockRecorder) QuotaRejection(machineScope interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QuotaRejection", reflect.TypeOf((*MockKubevirtVM)(nil).QuotaRejection), machineScope)
+}
+
+// RestartRequired mocks base method
+func (m *MockKubevirtVM) RestartRequired(machineScope machinescope.MachineScope) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestartRequired", machineScope)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RestartRequired indicates an expected call of RestartRequired
+func (mr *MockKubevirtVMMockRecorder) RestartRequired(machineScope interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestartRequired", reflect.TypeOf((*MockKubevirtVM)(nil).RestartRequired), machineScope)
+}
+
+// Stop mocks base method
+func (m *MockKubevirtVM) Stop(machineScope machinescope.MachineScope) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stop", machineScope)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Stop indicates an expected call of Stop
+func (mr *MockKubevirtVMMockRecorder) Stop(machineScope interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stop", reflect.TypeOf((*MockKubevirtVM)(nil).Stop), machineScope)
+}
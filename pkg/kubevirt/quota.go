@@ -0,0 +1,152 @@
+package kubevirt
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtapiv1 "kubevirt.io/client-go/api/v1"
+)
+
+// quotaExceededMessage is contained in the messages of the requests rejected by a ResourceQuota
+const quotaExceededMessage = "exceeded quota"
+
+func (m *manager) QuotaVersion(infraNamespace string) (string, error) {
+	quotas, err := m.infraClusterClient.ListResourceQuotas(context.Background(), infraNamespace)
+	if err != nil {
+		return "", err
+	}
+	// The hard limits and the usage are versioned rather than the ResourceVersion, which the quota controller
+	// changes on every resync
+	var versions []string
+	for _, quota := range quotas.Items {
+		for name, hard := range quota.Status.Hard {
+			used := quota.Status.Used[name]
+			versions = append(versions, fmt.Sprintf("%s/%s=%s/%s", quota.Name, name, used.String(), hard.String()))
+		}
+	}
+	sort.Strings(versions)
+	return strings.Join(versions, ","), nil
+}
+
+func (m *manager) ExceededQuotas(machineScope machinescope.MachineScope) ([]string, error) {
+	machineName := machineScope.GetMachineName()
+	virtualMachineFromMachine, err := machineScope.CreateVirtualMachineFromMachine()
+	if err != nil {
+		return nil, newOperationError(machineName, "ExceededQuotas", StageBuildVirtualMachine, err)
+	}
+	quotas, err := m.infraClusterClient.ListResourceQuotas(context.Background(), virtualMachineFromMachine.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	requested := requestedResources(virtualMachineFromMachine)
+	var exceeded []string
+	for _, quota := range quotas.Items {
+		for name, hard := range quota.Status.Hard {
+			request, ok := requested[name]
+			if !ok {
+				continue
+			}
+			used := quota.Status.Used[name]
+			used.Add(request)
+			if used.Cmp(hard) > 0 {
+				exceeded = append(exceeded, fmt.Sprintf("%s/%s", quota.Name, name))
+			}
+		}
+	}
+	sort.Strings(exceeded)
+	return exceeded, nil
+}
+
+// requestedResources returns the quota resources requested by the VirtualMachine once it runs: the pod of its
+// VirtualMachineInstance and the PVCs of its DataVolumes. The overhead of virt-launcher isn't accounted for.
+func requestedResources(vm *kubevirtapiv1.VirtualMachine) corev1.ResourceList {
+	requested := corev1.ResourceList{
+		corev1.ResourcePods: apiresource.MustParse("1"),
+	}
+	resources := vm.Spec.Template.Spec.Domain.Resources
+	if cpu, ok := resources.Requests[corev1.ResourceCPU]; ok {
+		requested[corev1.ResourceCPU] = cpu
+		requested[corev1.ResourceRequestsCPU] = cpu
+	}
+	if memory, ok := resources.Requests[corev1.ResourceMemory]; ok {
+		requested[corev1.ResourceMemory] = memory
+		requested[corev1.ResourceRequestsMemory] = memory
+	}
+	if cpu, ok := resources.Limits[corev1.ResourceCPU]; ok {
+		requested[corev1.ResourceLimitsCPU] = cpu
+	}
+	if memory, ok := resources.Limits[corev1.ResourceMemory]; ok {
+		requested[corev1.ResourceLimitsMemory] = memory
+	}
+	storage := apiresource.Quantity{}
+	for _, dataVolume := range vm.Spec.DataVolumeTemplates {
+		if dataVolume.Spec.PVC != nil {
+			storage.Add(dataVolume.Spec.PVC.Resources.Requests[corev1.ResourceStorage])
+		}
+	}
+	if count := len(vm.Spec.DataVolumeTemplates); count > 0 {
+		requested[corev1.ResourcePersistentVolumeClaims] = *apiresource.NewQuantity(int64(count), apiresource.DecimalSI)
+		requested[corev1.ResourceRequestsStorage] = storage
+	}
+	return requested
+}
+
+func (m *manager) QuotaRejection(machineScope machinescope.MachineScope) (string, error) {
+	ctx := context.Background()
+	machineName := machineScope.GetMachineName()
+	infraNamespace := machineScope.GetInfraNamespace()
+	virtualMachineName, err := machineScope.GetVirtualMachineName()
+	if err != nil {
+		return "", newOperationError(machineName, "QuotaRejection", StageBuildVirtualMachine, err)
+	}
+	vm, err := m.infraClusterClient.GetVirtualMachine(ctx, infraNamespace, virtualMachineName, &k8smetav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", newOperationError(machineName, "QuotaRejection", StageGetVirtualMachine, err)
+	}
+	for _, condition := range vm.Status.Conditions {
+		if condition.Status == corev1.ConditionTrue && strings.Contains(condition.Message, quotaExceededMessage) {
+			return condition.Message, nil
+		}
+	}
+	vmi, err := m.infraClusterClient.GetVirtualMachineInstance(ctx, infraNamespace, virtualMachineName, &k8smetav1.GetOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return "", newOperationError(machineName, "QuotaRejection", StageGetVirtualMachineInstance, err)
+	}
+	if err == nil {
+		for _, condition := range vmi.Status.Conditions {
+			if condition.Status == corev1.ConditionFalse && strings.Contains(condition.Message, quotaExceededMessage) {
+				return condition.Message, nil
+			}
+		}
+	}
+
+	// The PVCs of the DataVolumes are rejected by CDI, which only reports it with events of the DataVolumes
+	involved := map[string]bool{virtualMachineName: true}
+	for _, dataVolume := range vm.Spec.DataVolumeTemplates {
+		involved[dataVolume.Name] = true
+	}
+	events, err := m.infraClusterClient.ListEvents(ctx, infraNamespace, k8smetav1.ListOptions{})
+	if err != nil {
+		return "", newOperationError(machineName, "QuotaRejection", StageListEvents, err)
+	}
+	for _, event := range events.Items {
+		// The events of an earlier VirtualMachine of the same name are ignored
+		if event.LastTimestamp.Before(&vm.CreationTimestamp) {
+			continue
+		}
+		if involved[event.InvolvedObject.Name] && event.Type == corev1.EventTypeWarning && strings.Contains(event.Message, quotaExceededMessage) {
+			return event.Message, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,191 @@
+package kubevirt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockInfraClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster/mock"
+	mockMachineScope "github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope/mock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/testutils"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierr "k8s.io/apimachinery/pkg/api/errors"
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kubevirtapiv1 "kubevirt.io/client-go/api/v1"
+)
+
+func stubResourceQuota(name string, resourceVersion string, hard corev1.ResourceList, used corev1.ResourceList) corev1.ResourceQuota {
+	return corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: name, ResourceVersion: resourceVersion},
+		Status:     corev1.ResourceQuotaStatus{Hard: hard, Used: used},
+	}
+}
+
+func TestQuotaVersion(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockInfraClusterClient := mockInfraClusterClient.NewMockClient(mockCtrl)
+
+	quotas := []corev1.ResourceQuota{
+		stubResourceQuota("storage", "12", corev1.ResourceList{corev1.ResourceRequestsStorage: apiresource.MustParse("1Ti")},
+			corev1.ResourceList{corev1.ResourceRequestsStorage: apiresource.MustParse("666Gi")}),
+		stubResourceQuota("compute", "34", corev1.ResourceList{corev1.ResourcePods: apiresource.MustParse("5")},
+			corev1.ResourceList{corev1.ResourcePods: apiresource.MustParse("5")}),
+	}
+	mockInfraClusterClient.EXPECT().ListResourceQuotas(gomock.Any(), testutils.InfraNamespace).Return(&corev1.ResourceQuotaList{Items: quotas}, nil).Times(1)
+	kubevirtVM := New(mockInfraClusterClient, requeueAfter)
+	version, err := kubevirtVM.QuotaVersion(testutils.InfraNamespace)
+	assert.NilError(t, err)
+	assert.Equal(t, version, "compute/pods=5/5,storage/requests.storage=666Gi/1Ti")
+
+	// A resync of the quota controller changes the ResourceVersion, but not the version of the quotas
+	quotas[1].ResourceVersion = "35"
+	mockInfraClusterClient.EXPECT().ListResourceQuotas(gomock.Any(), testutils.InfraNamespace).Return(&corev1.ResourceQuotaList{Items: quotas}, nil).Times(1)
+	resyncedVersion, err := kubevirtVM.QuotaVersion(testutils.InfraNamespace)
+	assert.NilError(t, err)
+	assert.Equal(t, resyncedVersion, version)
+}
+
+func TestExceededQuotas(t *testing.T) {
+	cases := []struct {
+		name     string
+		quotas   []corev1.ResourceQuota
+		expected []string
+	}{
+		{
+			name: "quotas with room for the machine",
+			quotas: []corev1.ResourceQuota{
+				stubResourceQuota("compute", "1",
+					corev1.ResourceList{corev1.ResourceRequestsMemory: apiresource.MustParse("200G"), corev1.ResourcePods: apiresource.MustParse("5")},
+					corev1.ResourceList{corev1.ResourceRequestsMemory: apiresource.MustParse("50G"), corev1.ResourcePods: apiresource.MustParse("4")}),
+				// The resources the machine doesn't request don't matter
+				stubResourceQuota("services", "1", corev1.ResourceList{corev1.ResourceServices: apiresource.MustParse("1")},
+					corev1.ResourceList{corev1.ResourceServices: apiresource.MustParse("1")}),
+			},
+		},
+		{
+			name: "quotas without room for the machine",
+			quotas: []corev1.ResourceQuota{
+				stubResourceQuota("compute", "1",
+					corev1.ResourceList{corev1.ResourceRequestsMemory: apiresource.MustParse("200G"), corev1.ResourcePods: apiresource.MustParse("5")},
+					corev1.ResourceList{corev1.ResourceRequestsMemory: apiresource.MustParse("100G"), corev1.ResourcePods: apiresource.MustParse("4")}),
+				stubResourceQuota("storage", "1",
+					corev1.ResourceList{corev1.ResourceRequestsStorage: apiresource.MustParse("1Ti"), corev1.ResourcePersistentVolumeClaims: apiresource.MustParse("10")},
+					corev1.ResourceList{corev1.ResourceRequestsStorage: apiresource.MustParse("500Gi"), corev1.ResourcePersistentVolumeClaims: apiresource.MustParse("2")}),
+			},
+			expected: []string{"compute/requests.memory", "storage/requests.storage"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockInfraClusterClient := mockInfraClusterClient.NewMockClient(mockCtrl)
+			mockMachineScope := mockMachineScope.NewMockMachineScope(mockCtrl)
+
+			mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).AnyTimes()
+			mockMachineScope.EXPECT().CreateVirtualMachineFromMachine().Return(testutils.StubVirtualMachine(nil, nil, nil), nil).Times(1)
+			mockInfraClusterClient.EXPECT().ListResourceQuotas(gomock.Any(), testutils.InfraNamespace).Return(&corev1.ResourceQuotaList{Items: tc.quotas}, nil).Times(1)
+
+			kubevirtVM := New(mockInfraClusterClient, requeueAfter)
+			exceeded, err := kubevirtVM.ExceededQuotas(mockMachineScope)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, exceeded, tc.expected)
+		})
+	}
+}
+
+func TestQuotaRejection(t *testing.T) {
+	podRejection := `Error creating pod: pods "virt-launcher-test-machine-name-x2v4k" is forbidden: exceeded quota: compute`
+	pvcRejection := `persistentvolumeclaims "test-machine-name-bootvolume" is forbidden: exceeded quota: storage`
+	created := metav1.NewTime(time.Now().Add(-time.Minute))
+	cases := []struct {
+		name     string
+		vm       func(vm *kubevirtapiv1.VirtualMachine)
+		vmi      *kubevirtapiv1.VirtualMachineInstance
+		events   []corev1.Event
+		expected string
+	}{
+		{
+			name: "pod rejected, reported by the VirtualMachine",
+			vm: func(vm *kubevirtapiv1.VirtualMachine) {
+				vm.Status.Conditions = []kubevirtapiv1.VirtualMachineCondition{
+					{Type: kubevirtapiv1.VirtualMachineFailure, Status: corev1.ConditionTrue, Reason: "FailedCreate", Message: podRejection},
+				}
+			},
+			expected: podRejection,
+		},
+		{
+			name: "pod rejected, reported by the VirtualMachineInstance",
+			vmi: &kubevirtapiv1.VirtualMachineInstance{Status: kubevirtapiv1.VirtualMachineInstanceStatus{
+				Conditions: []kubevirtapiv1.VirtualMachineInstanceCondition{
+					{Type: kubevirtapiv1.VirtualMachineInstanceSynchronized, Status: corev1.ConditionFalse, Reason: "FailedCreate", Message: podRejection},
+				},
+			}},
+			expected: podRejection,
+		},
+		{
+			name: "PVC of the DataVolume rejected",
+			events: []corev1.Event{
+				{
+					InvolvedObject: corev1.ObjectReference{Kind: "DataVolume", Name: "test-machine-name-bootvolume"},
+					Type:           corev1.EventTypeWarning,
+					Reason:         "ErrExceededQuota",
+					Message:        pvcRejection,
+					LastTimestamp:  metav1.Now(),
+				},
+			},
+			expected: pvcRejection,
+		},
+		{
+			name: "rejections of other VirtualMachines and of an earlier VirtualMachine are ignored",
+			events: []corev1.Event{
+				{
+					InvolvedObject: corev1.ObjectReference{Kind: "DataVolume", Name: "other-bootvolume"},
+					Type:           corev1.EventTypeWarning,
+					Message:        pvcRejection,
+					LastTimestamp:  metav1.Now(),
+				},
+				{
+					InvolvedObject: corev1.ObjectReference{Kind: "VirtualMachineInstance", Name: testutils.MachineName},
+					Type:           corev1.EventTypeWarning,
+					Message:        podRejection,
+					LastTimestamp:  metav1.NewTime(created.Add(-time.Hour)),
+				},
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockInfraClusterClient := mockInfraClusterClient.NewMockClient(mockCtrl)
+			mockMachineScope := mockMachineScope.NewMockMachineScope(mockCtrl)
+
+			vm := testutils.StubVirtualMachine(nil, nil, nil)
+			vm.CreationTimestamp = created
+			if tc.vm != nil {
+				tc.vm(vm)
+			}
+			mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).AnyTimes()
+			mockMachineScope.EXPECT().GetInfraNamespace().Return(testutils.InfraNamespace).AnyTimes()
+			mockMachineScope.EXPECT().GetVirtualMachineName().Return(testutils.MachineName, nil).AnyTimes()
+			mockInfraClusterClient.EXPECT().GetVirtualMachine(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(vm, nil).Times(1)
+			if tc.vmi != nil {
+				mockInfraClusterClient.EXPECT().GetVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(tc.vmi, nil).AnyTimes()
+			} else {
+				mockInfraClusterClient.EXPECT().GetVirtualMachineInstance(gomock.Any(), testutils.InfraNamespace, testutils.MachineName, gomock.Any()).Return(nil,
+					apierr.NewNotFound(schema.GroupResource{Group: "kubevirt.io", Resource: "virtualmachineinstances"}, testutils.MachineName)).AnyTimes()
+			}
+			mockInfraClusterClient.EXPECT().ListEvents(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(&corev1.EventList{Items: tc.events}, nil).AnyTimes()
+
+			kubevirtVM := New(mockInfraClusterClient, requeueAfter)
+			rejection, err := kubevirtVM.QuotaRejection(mockMachineScope)
+			assert.NilError(t, err)
+			assert.Equal(t, rejection, tc.expected)
+		})
+	}
+}
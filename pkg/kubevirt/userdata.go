@@ -0,0 +1,132 @@
+package kubevirt
+
+import (
+	"sync"
+
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
+)
+
+// UserDataMutator edits the ignition of the Machine before it is written to the InfraCluster,
+// e.g. to add site-specific files or units to the guest
+type UserDataMutator func(machineScope machinescope.MachineScope, userData []byte) ([]byte, error)
+
+var (
+	userDataMutatorsLock sync.RWMutex
+	// userDataMutators are applied in order, the built-in mutators first
+	userDataMutators = []UserDataMutator{
+		rewriteIgnitionSource,
+		mergeIgnitionSource,
+		injectClusterProxy,
+		injectHostname,
+		injectInstanceMetadata,
+		injectAfterburnMetadata,
+		injectKubeletDropin,
+	}
+)
+
+// RegisterUserDataMutator appends the mutator to the mutators applied to the ignition of every Machine, after
+// the built-in ones and the mutators registered before it. It is meant to be called before the controller starts.
+func RegisterUserDataMutator(mutator UserDataMutator) {
+	userDataMutatorsLock.Lock()
+	defer userDataMutatorsLock.Unlock()
+	userDataMutators = append(userDataMutators, mutator)
+}
+
+// buildUserData applies the mutators to the userData of the Machine
+func buildUserData(machineScope machinescope.MachineScope, userData []byte) ([]byte, error) {
+	userDataMutatorsLock.RLock()
+	mutators := userDataMutators
+	userDataMutatorsLock.RUnlock()
+
+	fullUserData := userData
+	for _, mutator := range mutators {
+		var err error
+		if fullUserData, err = mutator(machineScope, fullUserData); err != nil {
+			return nil, err
+		}
+	}
+	return fullUserData, nil
+}
+
+// rewriteIgnitionSource rewrites the host of the remote ignitions merged by the userData, when the Machine
+// reaches them through another address
+func rewriteIgnitionSource(machineScope machinescope.MachineScope, userData []byte) ([]byte, error) {
+	host, err := machineScope.GetIgnitionSourceHost()
+	if err != nil || host == "" {
+		return userData, err
+	}
+	return rewriteUserDataSources(userData, host)
+}
+
+// mergeIgnitionSource replaces the userData by an ignition merging the userData from the merge source of
+// the Machine, so only a reference to the ignition is written to the infra-cluster
+func mergeIgnitionSource(machineScope machinescope.MachineScope, userData []byte) ([]byte, error) {
+	mergeSource, err := machineScope.GetIgnitionMergeSource()
+	if err != nil || mergeSource == nil {
+		return userData, err
+	}
+	return mergeUserData(userData, mergeSource)
+}
+
+// injectClusterProxy merges the cluster-wide proxy of the TenantCluster, when the Machine propagates it
+func injectClusterProxy(machineScope machinescope.MachineScope, userData []byte) ([]byte, error) {
+	proxy := machineScope.GetClusterProxy()
+	if proxy == nil {
+		return userData, nil
+	}
+	return addClusterProxyToUserData(userData, proxy)
+}
+
+// injectHostname writes the hostname of the guest, unless the Machine disables the hostname injection
+func injectHostname(machineScope machinescope.MachineScope, userData []byte) ([]byte, error) {
+	if !machineScope.HostnameInjectionEnabled() {
+		return userData, nil
+	}
+	hostname, err := machineScope.GetHostname()
+	if err != nil {
+		return nil, err
+	}
+	return addHostnameToUserData(userData, hostname)
+}
+
+// injectInstanceMetadata writes the custom instance metadata of the Machine, together with its instance-id
+func injectInstanceMetadata(machineScope machinescope.MachineScope, userData []byte) ([]byte, error) {
+	metadata := machineScope.GetInstanceMetadata()
+	if len(metadata) == 0 {
+		return userData, nil
+	}
+	virtualMachineName, err := machineScope.GetVirtualMachineName()
+	if err != nil {
+		return nil, err
+	}
+	metadata[instanceIDMetadataKey] = FormatProviderID(machineScope.GetInfraNamespace(), virtualMachineName)
+	return addInstanceMetadataToUserData(userData, metadata)
+}
+
+// injectAfterburnMetadata writes the afterburn provider hints, when the Machine requires them
+func injectAfterburnMetadata(machineScope machinescope.MachineScope, userData []byte) ([]byte, error) {
+	afterburnMetadata := machineScope.GetAfterburnMetadata()
+	if afterburnMetadata == nil {
+		return userData, nil
+	}
+	virtualMachineName, err := machineScope.GetVirtualMachineName()
+	if err != nil {
+		return nil, err
+	}
+	hostname, err := machineScope.GetHostname()
+	if err != nil {
+		return nil, err
+	}
+	providerID := FormatProviderID(machineScope.GetInfraNamespace(), virtualMachineName)
+	attributes := map[string]string{
+		"AFTERBURN_KUBEVIRT_INSTANCE_ID": providerID,
+		"AFTERBURN_KUBEVIRT_PROVIDER_ID": providerID,
+		"AFTERBURN_KUBEVIRT_HOSTNAME":    hostname,
+		// KUBELET_PROVIDERID is read by the kubelet, through its drop-in
+		"KUBELET_PROVIDERID": providerID,
+	}
+	if afterburnMetadata.InstanceType != "" {
+		attributes["AFTERBURN_KUBEVIRT_INSTANCE_TYPE"] = afterburnMetadata.InstanceType
+	}
+	return addAfterburnMetadataToUserData(userData, attributes)
+}
@@ -0,0 +1,72 @@
+package kubevirt
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
+	mockMachineScope "github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope/mock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/testutils"
+	"gotest.tools/assert"
+)
+
+func TestRegisterUserDataMutator(t *testing.T) {
+	builtinMutators := userDataMutators
+	defer func() { userDataMutators = builtinMutators }()
+
+	RegisterUserDataMutator(func(machineScope machinescope.MachineScope, userData []byte) ([]byte, error) {
+		return addFileToUserData(userData, "/etc/site.conf", "data:,"+machineScope.GetMachineName())
+	})
+	RegisterUserDataMutator(func(machineScope machinescope.MachineScope, userData []byte) ([]byte, error) {
+		return addFileToUserData(userData, "/etc/zone.conf", "data:,zone-a")
+	})
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockMachineScope := mockMachineScope.NewMockMachineScope(mockCtrl)
+	mockMachineScope.EXPECT().GetIgnitionSourceHost().Return("", nil).Times(1)
+	mockMachineScope.EXPECT().GetIgnitionMergeSource().Return(nil, nil).Times(1)
+	mockMachineScope.EXPECT().GetClusterProxy().Return(nil).Times(1)
+	mockMachineScope.EXPECT().HostnameInjectionEnabled().Return(true).Times(1)
+	mockMachineScope.EXPECT().NodeSmokeCheckRequired().Return(false).Times(1)
+	mockMachineScope.EXPECT().GetHostname().Return(testutils.MachineName, nil).Times(1)
+	mockMachineScope.EXPECT().GetInstanceMetadata().Return(nil).Times(1)
+	mockMachineScope.EXPECT().GetAfterburnMetadata().Return(nil).Times(2)
+	mockMachineScope.EXPECT().GetMachineName().Return(testutils.MachineName).Times(1)
+
+	result, err := buildUserData(mockMachineScope, []byte(testutils.SrcUserData))
+	assert.NilError(t, err)
+
+	expected := []byte(testutils.SrcUserData)
+	expected, err = addHostnameToUserData(expected, testutils.MachineName)
+	assert.NilError(t, err)
+	expected, err = addFileToUserData(expected, "/etc/site.conf", "data:,"+testutils.MachineName)
+	assert.NilError(t, err)
+	expected, err = addFileToUserData(expected, "/etc/zone.conf", "data:,zone-a")
+	assert.NilError(t, err)
+	assert.Equal(t, string(result), string(expected))
+}
+
+func TestRegisterUserDataMutatorError(t *testing.T) {
+	builtinMutators := userDataMutators
+	defer func() { userDataMutators = builtinMutators }()
+
+	RegisterUserDataMutator(func(machineScope machinescope.MachineScope, userData []byte) ([]byte, error) {
+		return nil, fmt.Errorf("test error")
+	})
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockMachineScope := mockMachineScope.NewMockMachineScope(mockCtrl)
+	mockMachineScope.EXPECT().GetIgnitionSourceHost().Return("", nil).Times(1)
+	mockMachineScope.EXPECT().GetIgnitionMergeSource().Return(nil, nil).Times(1)
+	mockMachineScope.EXPECT().GetClusterProxy().Return(nil).Times(1)
+	mockMachineScope.EXPECT().HostnameInjectionEnabled().Return(false).Times(1)
+	mockMachineScope.EXPECT().NodeSmokeCheckRequired().Return(false).Times(1)
+	mockMachineScope.EXPECT().GetInstanceMetadata().Return(nil).Times(1)
+	mockMachineScope.EXPECT().GetAfterburnMetadata().Return(nil).Times(2)
+
+	_, err := buildUserData(mockMachineScope, []byte(testutils.SrcUserData))
+	assert.Error(t, err, "test error")
+}
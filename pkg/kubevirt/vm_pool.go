@@ -0,0 +1,69 @@
+package kubevirt
+
+import (
+	"context"
+
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"k8s.io/apimachinery/pkg/api/errors"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+	kubevirtapiv1 "kubevirt.io/client-go/api/v1"
+
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
+)
+
+// adoptPoolVirtualMachine adopts a VirtualMachine of the pool backing the Machine, and syncs the Machine with it.
+// The Machine requeues until the pool has a VirtualMachine no other Machine adopted.
+func (m *manager) adoptPoolVirtualMachine(machineScope machinescope.MachineScope, machineName string) (bool, error) {
+	poolName := machineScope.GetVirtualMachinePoolName()
+	namespace := machineScope.GetInfraNamespace()
+	vms, err := m.infraClusterClient.ListVirtualMachine(context.Background(), namespace, k8smetav1.ListOptions{})
+	if err != nil {
+		return false, newOperationError(machineName, "Create", StageAdoptVirtualMachine, err)
+	}
+
+	adopted, err := m.claimPoolVirtualMachine(vms.Items, poolName, machineName)
+	if err != nil {
+		return false, newOperationError(machineName, "Create", StageAdoptVirtualMachine, err)
+	}
+	if adopted == nil {
+		klog.Infof("%s: VirtualMachinePool %s has no VirtualMachine to adopt yet - requeue", machineName, poolName)
+		return false, &machinecontroller.RequeueAfterError{RequeueAfter: m.requeueAfter}
+	}
+	klog.Infof("%s: VirtualMachine %s of VirtualMachinePool %s was adopted by the Machine", machineName, adopted.Name, poolName)
+	machineScope.SetPoolVirtualMachine(adopted.Name)
+
+	return m.syncMachine(*adopted, machineScope, machineName, "Create")
+}
+
+// claimPoolVirtualMachine labels a VirtualMachine of the pool as adopted by the Machine, and returns it.
+// The VirtualMachine an interrupted adoption already labelled is returned as is, and nil is returned when
+// every VirtualMachine of the pool is adopted.
+func (m *manager) claimPoolVirtualMachine(vms []kubevirtapiv1.VirtualMachine, poolName string, machineName string) (*kubevirtapiv1.VirtualMachine, error) {
+	for i := range vms {
+		vm := &vms[i]
+		if machinescope.IsPoolVirtualMachine(vm, poolName) && vm.Labels[machinescope.AdoptedByLabel] == machineName {
+			return vm, nil
+		}
+	}
+	for i := range vms {
+		vm := &vms[i]
+		if !machinescope.IsPoolVirtualMachine(vm, poolName) || vm.DeletionTimestamp != nil || vm.Labels[machinescope.AdoptedByLabel] != "" {
+			continue
+		}
+		if vm.Labels == nil {
+			vm.Labels = map[string]string{}
+		}
+		vm.Labels[machinescope.AdoptedByLabel] = machineName
+		// The update fails with a conflict if the VirtualMachine was adopted concurrently
+		adopted, err := m.infraClusterClient.UpdateVirtualMachine(machineContext(machineName), vm.Namespace, vm)
+		if err != nil {
+			if errors.IsConflict(err) {
+				continue
+			}
+			return nil, err
+		}
+		return adopted, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,126 @@
+package kubevirt
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster"
+	mockInfraClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster/mock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
+	mockMachineScope "github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope/mock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/testutils"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"gotest.tools/assert"
+	apierr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kubevirtapiv1 "kubevirt.io/client-go/api/v1"
+)
+
+const testPoolName = "test-pool"
+
+func stubPoolVirtualMachine(name string, poolName string, adoptedBy string) kubevirtapiv1.VirtualMachine {
+	vm := testutils.StubVirtualMachine(nil, nil, nil)
+	vm.Name = name
+	vm.Labels = map[string]string{}
+	if adoptedBy != "" {
+		vm.Labels[machinescope.AdoptedByLabel] = adoptedBy
+	}
+	vm.OwnerReferences = []metav1.OwnerReference{{Kind: "VirtualMachinePool", Name: poolName}}
+	return *vm
+}
+
+func TestAdoptPoolVirtualMachine(t *testing.T) {
+	cases := []struct {
+		name            string
+		vms             []kubevirtapiv1.VirtualMachine
+		expect          func(infraClient *mockInfraClusterClient.MockClient, machineScope *mockMachineScope.MockMachineScope)
+		expectedAdopted string
+		expectedRequeue bool
+		expectedErr     string
+	}{
+		{
+			name: "Success adopt a free virtual machine of the pool",
+			vms: []kubevirtapiv1.VirtualMachine{
+				stubPoolVirtualMachine("other-pool-0", "other-pool", ""),
+				stubPoolVirtualMachine("test-pool-0", testPoolName, "other-machine"),
+				stubPoolVirtualMachine("test-pool-1", testPoolName, ""),
+				stubPoolVirtualMachine("test-pool-2", testPoolName, ""),
+			},
+			expect: func(infraClient *mockInfraClusterClient.MockClient, machineScope *mockMachineScope.MockMachineScope) {
+				infraClient.EXPECT().UpdateVirtualMachine(gomock.Any(), testutils.InfraNamespace, gomock.Any()).DoAndReturn(
+					func(ctx context.Context, namespace string, vm *kubevirtapiv1.VirtualMachine) (*kubevirtapiv1.VirtualMachine, error) {
+						assert.Equal(t, vm.Labels[machinescope.AdoptedByLabel], testutils.MachineName)
+						if vm.Name == "test-pool-1" {
+							return nil, apierr.NewConflict(schema.GroupResource{Resource: "virtualmachines"}, vm.Name, nil)
+						}
+						return vm, nil
+					}).Times(2)
+			},
+			expectedAdopted: "test-pool-2",
+		},
+		{
+			name: "Success resume an interrupted adoption",
+			vms: []kubevirtapiv1.VirtualMachine{
+				stubPoolVirtualMachine("test-pool-0", testPoolName, ""),
+				stubPoolVirtualMachine("test-pool-1", testPoolName, testutils.MachineName),
+			},
+			expect: func(infraClient *mockInfraClusterClient.MockClient, machineScope *mockMachineScope.MockMachineScope) {
+			},
+			expectedAdopted: "test-pool-1",
+		},
+		{
+			name: "Success requeue without free virtual machine",
+			vms: []kubevirtapiv1.VirtualMachine{
+				stubPoolVirtualMachine("test-pool-0", testPoolName, "other-machine"),
+			},
+			expect: func(infraClient *mockInfraClusterClient.MockClient, machineScope *mockMachineScope.MockMachineScope) {
+			},
+			expectedRequeue: true,
+		},
+		{
+			name: "Failure label the virtual machine",
+			vms: []kubevirtapiv1.VirtualMachine{
+				stubPoolVirtualMachine("test-pool-0", testPoolName, ""),
+			},
+			expect: func(infraClient *mockInfraClusterClient.MockClient, machineScope *mockMachineScope.MockMachineScope) {
+				infraClient.EXPECT().UpdateVirtualMachine(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(nil, fmt.Errorf("test error")).Times(1)
+			},
+			expectedErr: "test-machine-name: Error during Create: failed to adopt a Virtual Machine of the pool in infraCluster, with error: test error",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			infraClient := mockInfraClusterClient.NewMockClient(mockCtrl)
+			machineScope := mockMachineScope.NewMockMachineScope(mockCtrl)
+			tc.expect(infraClient, machineScope)
+			infraClient.EXPECT().GetCapabilities().Return(infracluster.Capabilities{}).AnyTimes()
+			infraClient.EXPECT().ListVirtualMachine(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(
+				&kubevirtapiv1.VirtualMachineList{Items: tc.vms}, nil).Times(1)
+			machineScope.EXPECT().GetMachineName().Return(testutils.MachineName).AnyTimes()
+			machineScope.EXPECT().GetInfraNamespace().Return(testutils.InfraNamespace).AnyTimes()
+			machineScope.EXPECT().GetVirtualMachinePoolName().Return(testPoolName).AnyTimes()
+			if tc.expectedAdopted != "" {
+				machineScope.EXPECT().SetPoolVirtualMachine(tc.expectedAdopted).Times(1)
+				machineScope.EXPECT().SyncMachine(gomock.Any(), nil, fmt.Sprintf(providerIDFmt, testutils.InfraNamespace, tc.expectedAdopted)).Return(nil).Times(1)
+			}
+
+			kubevirtVM := New(infraClient, requeueAfter)
+			_, err := kubevirtVM.Create(machineScope, []byte(testutils.SrcUserData))
+			switch {
+			case tc.expectedErr != "":
+				assert.Error(t, err, tc.expectedErr)
+			case tc.expectedRequeue:
+				_, requeue := err.(*machinecontroller.RequeueAfterError)
+				assert.Assert(t, requeue)
+			default:
+				assert.NilError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package kubevirt
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+	kubevirtapiv1 "kubevirt.io/client-go/api/v1"
+	cdiv1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
+
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
+)
+
+// claimWarmBootVolume claims a cloned DataVolume of the warm pool for the VirtualMachine, of the shape of the
+// boot volume of the Machine, and makes the VirtualMachine boot from it instead of cloning its own boot volume.
+// It returns nil when the warm pool has no cloned DataVolume of the shape.
+func (m *manager) claimWarmBootVolume(machineScope machinescope.MachineScope, vm *kubevirtapiv1.VirtualMachine, machineName string) (*cdiv1.DataVolume, error) {
+	if len(m.infraClusterClient.GetCapabilities().CDIVersions) == 0 {
+		return nil, nil
+	}
+
+	// An interrupted creation of the VirtualMachine resumes with the DataVolume it already claimed
+	claimed, err := m.infraClusterClient.ListDataVolumes(context.Background(), vm.Namespace, k8smetav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", machinescope.WarmPoolClaimedByLabel, vm.Name),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(claimed) > 0 {
+		useWarmBootVolume(vm, claimed[0].Name)
+		return &claimed[0], nil
+	}
+
+	pool, err := m.infraClusterClient.ListDataVolumes(context.Background(), vm.Namespace, k8smetav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", machinescope.WarmPoolShapeLabel, machineScope.GetBootVolumeShape()),
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(pool, func(i, j int) bool {
+		return pool[i].CreationTimestamp.Before(&pool[j].CreationTimestamp)
+	})
+	for i := range pool {
+		dataVolume := &pool[i]
+		if dataVolume.DeletionTimestamp != nil || dataVolume.Status.Phase != cdiv1.Succeeded {
+			continue
+		}
+		delete(dataVolume.Labels, machinescope.WarmPoolShapeLabel)
+		dataVolume.Labels[machinescope.WarmPoolClaimedByLabel] = vm.Name
+		// The update fails with a conflict if the DataVolume was claimed concurrently
+		claimedDataVolume, err := m.infraClusterClient.UpdateDataVolume(machineContext(machineName), vm.Namespace, dataVolume)
+		if err != nil {
+			if errors.IsConflict(err) {
+				continue
+			}
+			return nil, err
+		}
+		useWarmBootVolume(vm, claimedDataVolume.Name)
+		return claimedDataVolume, nil
+	}
+	return nil, nil
+}
+
+// adoptWarmBootVolume sets the VirtualMachine as the owner of its DataVolume from the warm pool,
+// so the DataVolume is deleted together with the VirtualMachine
+func (m *manager) adoptWarmBootVolume(vm *kubevirtapiv1.VirtualMachine, dataVolume *cdiv1.DataVolume, machineName string) error {
+	if !addOwnerReference(&dataVolume.ObjectMeta, vm) {
+		return nil
+	}
+	_, err := m.infraClusterClient.UpdateDataVolume(machineContext(machineName), dataVolume.Namespace, dataVolume)
+	return err
+}
+
+// addOwnerReference sets the VirtualMachine as an owner of the object, so the garbage collector of the infra-cluster
+// deletes the object with the VirtualMachine. It returns false when the VirtualMachine already owns the object.
+func addOwnerReference(object *k8smetav1.ObjectMeta, vm *kubevirtapiv1.VirtualMachine) bool {
+	for _, ownerReference := range object.OwnerReferences {
+		if ownerReference.UID == vm.UID {
+			return false
+		}
+	}
+	object.OwnerReferences = append(object.OwnerReferences, k8smetav1.OwnerReference{
+		APIVersion: machinescope.APIVersion,
+		Kind:       machinescope.Kind,
+		Name:       vm.Name,
+		UID:        vm.UID,
+	})
+	return true
+}
+
+// useWarmBootVolume makes the VirtualMachine boot from the DataVolume, instead of the DataVolume template of
+// its boot volume
+func useWarmBootVolume(vm *kubevirtapiv1.VirtualMachine, dataVolumeName string) {
+	if len(vm.Spec.DataVolumeTemplates) == 0 {
+		return
+	}
+	bootVolumeName := vm.Spec.DataVolumeTemplates[0].Name
+	vm.Spec.DataVolumeTemplates = vm.Spec.DataVolumeTemplates[1:]
+	if len(vm.Spec.DataVolumeTemplates) == 0 {
+		vm.Spec.DataVolumeTemplates = nil
+	}
+	if vm.Spec.Template != nil {
+		for _, volume := range vm.Spec.Template.Spec.Volumes {
+			if volume.DataVolume != nil && volume.DataVolume.Name == bootVolumeName {
+				volume.DataVolume.Name = dataVolumeName
+			}
+		}
+	}
+	if vm.Annotations == nil {
+		vm.Annotations = map[string]string{}
+	}
+	vm.Annotations[machinescope.WarmBootVolumeAnnotation] = dataVolumeName
+}
+
+// keepWarmBootVolume keeps the DataVolume of the warm pool the existing VirtualMachine boots from
+func keepWarmBootVolume(vm *kubevirtapiv1.VirtualMachine, existingVM *kubevirtapiv1.VirtualMachine) {
+	if dataVolumeName := existingVM.Annotations[machinescope.WarmBootVolumeAnnotation]; dataVolumeName != "" {
+		useWarmBootVolume(vm, dataVolumeName)
+	}
+}
+
+// claimWarmBootVolumeOrClone claims a DataVolume of the warm pool for the VirtualMachine, or leaves the
+// VirtualMachine cloning its own boot volume when none can be claimed
+func (m *manager) claimWarmBootVolumeOrClone(machineScope machinescope.MachineScope, vm *kubevirtapiv1.VirtualMachine, machineName string) *cdiv1.DataVolume {
+	dataVolume, err := m.claimWarmBootVolume(machineScope, vm, machineName)
+	if err != nil {
+		klog.Warningf("%s: failed to claim a boot volume from the warm pool, cloning it instead, with error: %v", machineName, err)
+		return nil
+	}
+	if dataVolume != nil {
+		klog.Infof("%s: VirtualMachine boots from DataVolume %s of the warm pool", machineName, dataVolume.Name)
+	}
+	return dataVolume
+}
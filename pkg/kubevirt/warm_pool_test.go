@@ -0,0 +1,125 @@
+package kubevirt
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster"
+	mockInfraClusterClient "github.com/openshift/cluster-api-provider-kubevirt/pkg/clients/infracluster/mock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
+	mockMachineScope "github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope/mock"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/testutils"
+	"gotest.tools/assert"
+	apierr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	cdiv1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
+)
+
+func stubWarmDataVolume(name string, phase cdiv1.DataVolumePhase) cdiv1.DataVolume {
+	return cdiv1.DataVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: testutils.InfraNamespace,
+			Labels:    map[string]string{machinescope.WarmPoolShapeLabel: "shape"},
+		},
+		Status: cdiv1.DataVolumeStatus{Phase: phase},
+	}
+}
+
+func TestUseWarmBootVolume(t *testing.T) {
+	vm := testutils.StubVirtualMachine(nil, nil, nil)
+
+	useWarmBootVolume(vm, "warm-1")
+	assert.Equal(t, len(vm.Spec.DataVolumeTemplates), 0)
+	assert.Equal(t, vm.Spec.Template.Spec.Volumes[0].DataVolume.Name, "warm-1")
+	assert.Equal(t, vm.Annotations[machinescope.WarmBootVolumeAnnotation], "warm-1")
+
+	updatedVM := testutils.StubVirtualMachine(nil, nil, nil)
+	keepWarmBootVolume(updatedVM, vm)
+	assert.DeepEqual(t, updatedVM.Spec, vm.Spec)
+}
+
+func TestClaimWarmBootVolume(t *testing.T) {
+	capabilities := infracluster.Capabilities{CDIVersions: []string{"v1beta1"}}
+
+	cases := []struct {
+		name               string
+		capabilities       infracluster.Capabilities
+		expect             func(infraClient *mockInfraClusterClient.MockClient)
+		expectedDataVolume string
+	}{
+		{
+			name:         "Success skip without CDI",
+			capabilities: infracluster.Capabilities{},
+			expect:       func(infraClient *mockInfraClusterClient.MockClient) {},
+		},
+		{
+			name:         "Success claim a cloned data volume",
+			capabilities: capabilities,
+			expect: func(infraClient *mockInfraClusterClient.MockClient) {
+				infraClient.EXPECT().ListDataVolumes(gomock.Any(), testutils.InfraNamespace, metav1.ListOptions{
+					LabelSelector: machinescope.WarmPoolClaimedByLabel + "=" + testutils.MachineName,
+				}).Return(nil, nil).Times(1)
+				infraClient.EXPECT().ListDataVolumes(gomock.Any(), testutils.InfraNamespace, metav1.ListOptions{
+					LabelSelector: machinescope.WarmPoolShapeLabel + "=shape",
+				}).Return([]cdiv1.DataVolume{
+					stubWarmDataVolume("warm-cloning", cdiv1.CloneInProgress),
+					stubWarmDataVolume("warm-taken", cdiv1.Succeeded),
+					stubWarmDataVolume("warm-free", cdiv1.Succeeded),
+				}, nil).Times(1)
+				infraClient.EXPECT().UpdateDataVolume(gomock.Any(), testutils.InfraNamespace, gomock.Any()).DoAndReturn(
+					func(ctx context.Context, namespace string, dataVolume *cdiv1.DataVolume) (*cdiv1.DataVolume, error) {
+						assert.Equal(t, dataVolume.Labels[machinescope.WarmPoolClaimedByLabel], testutils.MachineName)
+						_, ok := dataVolume.Labels[machinescope.WarmPoolShapeLabel]
+						assert.Assert(t, !ok)
+						if dataVolume.Name == "warm-taken" {
+							return nil, apierr.NewConflict(schema.GroupResource{Resource: "datavolumes"}, dataVolume.Name, nil)
+						}
+						return dataVolume, nil
+					}).Times(2)
+			},
+			expectedDataVolume: "warm-free",
+		},
+		{
+			name:         "Success resume the claimed data volume",
+			capabilities: capabilities,
+			expect: func(infraClient *mockInfraClusterClient.MockClient) {
+				infraClient.EXPECT().ListDataVolumes(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(
+					[]cdiv1.DataVolume{stubWarmDataVolume("warm-claimed", cdiv1.Succeeded)}, nil).Times(1)
+			},
+			expectedDataVolume: "warm-claimed",
+		},
+		{
+			name:         "Success empty warm pool",
+			capabilities: capabilities,
+			expect: func(infraClient *mockInfraClusterClient.MockClient) {
+				infraClient.EXPECT().ListDataVolumes(gomock.Any(), testutils.InfraNamespace, gomock.Any()).Return(nil, nil).Times(2)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			infraClient := mockInfraClusterClient.NewMockClient(mockCtrl)
+			machineScope := mockMachineScope.NewMockMachineScope(mockCtrl)
+			tc.expect(infraClient)
+			infraClient.EXPECT().GetCapabilities().Return(tc.capabilities).AnyTimes()
+			machineScope.EXPECT().GetBootVolumeShape().Return("shape").AnyTimes()
+
+			m := &manager{infraClusterClient: infraClient, requeueAfter: requeueAfter}
+			vm := testutils.StubVirtualMachine(nil, nil, nil)
+			dataVolume := m.claimWarmBootVolumeOrClone(machineScope, vm, testutils.MachineName)
+			if tc.expectedDataVolume == "" {
+				assert.Assert(t, dataVolume == nil)
+				assert.Equal(t, len(vm.Spec.DataVolumeTemplates), 1)
+				return
+			}
+			assert.Equal(t, dataVolume.Name, tc.expectedDataVolume)
+			assert.Equal(t, vm.Annotations[machinescope.WarmBootVolumeAnnotation], tc.expectedDataVolume)
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package machinescope
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog"
+)
+
+// preferredAccessModes are the access modes a boot volume can use, by order of preference:
+// a ReadWriteMany volume allows the live migration of the VirtualMachine
+var preferredAccessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany, corev1.ReadWriteOnce}
+
+func (s *machineScope) GetStorageClassName() string {
+	return s.machineProviderSpec.StorageClassName
+}
+
+func (s *machineScope) PersistentVolumeAccessModeDetectionRequired() bool {
+	return s.machineProviderSpec.PersistentVolumeAccessMode == ""
+}
+
+func (s *machineScope) SelectPersistentVolumeAccessMode(storageClassName string, supported []corev1.PersistentVolumeAccessMode) string {
+	s.selectedAccessMode, s.accessModeDecision = selectAccessMode(storageClassName, supported)
+	klog.Infof("%s - SelectPersistentVolumeAccessMode: %s", s.GetMachineName(), s.accessModeDecision)
+	return s.accessModeDecision
+}
+
+func (s *machineScope) GetPersistentVolumeAccessModeDecision() string {
+	return s.accessModeDecision
+}
+
+// selectAccessMode returns the most preferred access mode the storage class supports, or the default access mode
+// when the capabilities of the storage class are unknown, together with a message describing the decision
+func selectAccessMode(storageClassName string, supported []corev1.PersistentVolumeAccessMode) (corev1.PersistentVolumeAccessMode, string) {
+	if storageClassName == "" {
+		return defaultPersistentVolumeAccessMode, fmt.Sprintf("no default storage class was found, using the default access mode %s",
+			defaultPersistentVolumeAccessMode)
+	}
+	if len(supported) == 0 {
+		return defaultPersistentVolumeAccessMode, fmt.Sprintf("the StorageProfile of storage class %s has no access modes, using the default access mode %s",
+			storageClassName, defaultPersistentVolumeAccessMode)
+	}
+	for _, preferred := range preferredAccessModes {
+		for _, mode := range supported {
+			if mode == preferred {
+				return mode, fmt.Sprintf("storage class %s supports %v, using access mode %s", storageClassName, supported, mode)
+			}
+		}
+	}
+	return defaultPersistentVolumeAccessMode, fmt.Sprintf("storage class %s supports neither of %v, using the default access mode %s",
+		storageClassName, preferredAccessModes, defaultPersistentVolumeAccessMode)
+}
@@ -0,0 +1,27 @@
+package machinescope
+
+// ClusterProxy is the cluster-wide proxy of the tenant-cluster
+type ClusterProxy struct {
+	HTTPProxy  string
+	HTTPSProxy string
+	NoProxy    string
+	// TrustBundle is the PEM encoded additional trust bundle of the tenant-cluster, e.g. the CA of the proxy
+	// or of a mirror registry
+	TrustBundle string
+}
+
+func (s *machineScope) ClusterProxyPropagationRequired() bool {
+	return s.machineProviderSpec.PropagateClusterProxy
+}
+
+func (s *machineScope) SetClusterProxy(proxy *ClusterProxy) {
+	s.clusterProxy = proxy
+}
+
+func (s *machineScope) GetClusterProxy() *ClusterProxy {
+	if !s.machineProviderSpec.PropagateClusterProxy || s.clusterProxy == nil {
+		return nil
+	}
+	proxy := *s.clusterProxy
+	return &proxy
+}
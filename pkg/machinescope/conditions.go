@@ -0,0 +1,80 @@
+package machinescope
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+)
+
+func conditionSucceeded() kubevirtproviderv1alpha1.KubevirtMachineProviderCondition {
+	return kubevirtproviderv1alpha1.KubevirtMachineProviderCondition{
+		Type:   kubevirtproviderv1alpha1.MachineCreation,
+		Status: corev1.ConditionTrue,
+		Reason: kubevirtproviderv1alpha1.MachineCreationSucceeded,
+	}
+}
+
+func conditionFailed(err error) kubevirtproviderv1alpha1.KubevirtMachineProviderCondition {
+	return kubevirtproviderv1alpha1.KubevirtMachineProviderCondition{
+		Type:    kubevirtproviderv1alpha1.MachineCreation,
+		Status:  corev1.ConditionFalse,
+		Reason:  kubevirtproviderv1alpha1.MachineCreationFailed,
+		Message: err.Error(),
+	}
+}
+
+// setProviderCondition sets the condition in the list of conditions, the LastTransitionTime
+// is only moved when the status of the condition changes
+func setProviderCondition(conditions []kubevirtproviderv1alpha1.KubevirtMachineProviderCondition,
+	condition kubevirtproviderv1alpha1.KubevirtMachineProviderCondition) []kubevirtproviderv1alpha1.KubevirtMachineProviderCondition {
+	now := metav1.Now()
+	condition.LastProbeTime = now
+	condition.LastTransitionTime = now
+	for i := range conditions {
+		if conditions[i].Type != condition.Type {
+			continue
+		}
+		if conditions[i].Status == condition.Status {
+			condition.LastTransitionTime = conditions[i].LastTransitionTime
+		}
+		conditions[i] = condition
+		return conditions
+	}
+	return append(conditions, condition)
+}
+
+func (s *machineScope) SetMachineCreationCondition(err error) error {
+	condition := conditionSucceeded()
+	if err != nil {
+		condition = conditionFailed(err)
+	}
+	return s.setCondition(condition, "SetMachineCreationCondition")
+}
+
+func (s *machineScope) SetMachineRequiresReplacementCondition(err error) error {
+	return s.setCondition(kubevirtproviderv1alpha1.KubevirtMachineProviderCondition{
+		Type:    kubevirtproviderv1alpha1.MachineRequiresReplacement,
+		Status:  corev1.ConditionTrue,
+		Reason:  kubevirtproviderv1alpha1.VirtualMachineRecreated,
+		Message: err.Error(),
+	}, "SetMachineRequiresReplacementCondition")
+}
+
+// setCondition sets the condition in the ProviderStatus of the Machine
+func (s *machineScope) setCondition(condition kubevirtproviderv1alpha1.KubevirtMachineProviderCondition, caller string) error {
+	providerStatus, statusErr := kubevirtproviderv1alpha1.ProviderStatusFromRawExtension(s.machine.Status.ProviderStatus)
+	if statusErr != nil {
+		return machinecontroller.InvalidMachineConfiguration("failed to get machine provider status: %v", statusErr.Error())
+	}
+	providerStatus.ProviderConditions = setProviderCondition(providerStatus.ProviderConditions, condition)
+	rawProviderStatus, statusErr := kubevirtproviderv1alpha1.RawExtensionFromProviderStatus(providerStatus)
+	if statusErr != nil {
+		return machinecontroller.InvalidMachineConfiguration("failed to get machine provider status: %v", statusErr.Error())
+	}
+	s.machine.Status.ProviderStatus = rawProviderStatus
+	klog.Infof("%s - %s: successfully set the %s condition to %s", s.GetMachineName(), caller, condition.Type, condition.Reason)
+	return nil
+}
@@ -0,0 +1,60 @@
+package machinescope
+
+import (
+	"sort"
+	"strings"
+)
+
+// CreateStepsAnnotation records the completed steps of the creation of the Machine in the InfraCluster, as a
+// comma separated list, so a creation interrupted by a restart of the controller resumes after its last step
+const CreateStepsAnnotation = "kubevirt.machine.openshift.io/create-steps"
+
+// CreateStep is a step of the creation of the Machine in the InfraCluster
+type CreateStep string
+
+const (
+	// CreateStepIgnitionSourceChecked is completed once the remote ignition sources were reachable from the
+	// network of the Machine
+	CreateStepIgnitionSourceChecked CreateStep = "ignition-source-checked"
+	// CreateStepSecretCreated is completed once the ignition secret exists in the InfraCluster
+	CreateStepSecretCreated CreateStep = "secret-created"
+	// CreateStepVMCreated is completed once the VirtualMachine exists in the InfraCluster
+	CreateStepVMCreated CreateStep = "vm-created"
+	// CreateStepSynced is completed once the Machine was synced with its VirtualMachine
+	CreateStepSynced CreateStep = "synced"
+)
+
+func (s *machineScope) CreateStepDone(step CreateStep) bool {
+	for _, done := range s.createStepsDone() {
+		if done == string(step) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *machineScope) SetCreateStepDone(step CreateStep) error {
+	if s.CreateStepDone(step) {
+		return nil
+	}
+	steps := append(s.createStepsDone(), string(step))
+	sort.Strings(steps)
+	if s.machine.Annotations == nil {
+		s.machine.Annotations = map[string]string{}
+	}
+	s.machine.Annotations[CreateStepsAnnotation] = strings.Join(steps, ",")
+	// The steps are persisted as they are recorded, for a restarted controller to resume the creation
+	if s.machinePatcher == nil {
+		return nil
+	}
+	return s.PatchMachineMetadata()
+}
+
+// createStepsDone returns the completed steps recorded on the machine
+func (s *machineScope) createStepsDone() []string {
+	value := s.machine.Annotations[CreateStepsAnnotation]
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
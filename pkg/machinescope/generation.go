@@ -0,0 +1,57 @@
+package machinescope
+
+import (
+	goerrors "errors"
+	"fmt"
+
+	kubevirtapiv1 "kubevirt.io/client-go/api/v1"
+
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/metrics"
+)
+
+// StaleSyncError is returned when the Machine would be synced with data older than the one it was already synced
+// with, e.g. a VirtualMachine read before its latest update, or the VirtualMachineInstance of a former
+// VirtualMachine of the same name. The Machine is left as is, to be synced again with fresh data.
+type StaleSyncError struct {
+	Message string
+}
+
+func (e *StaleSyncError) Error() string {
+	return fmt.Sprintf("stale sync: %s", e.Message)
+}
+
+// IsStaleSync returns true when the sync of the Machine was skipped since it was based on stale data
+func IsStaleSync(err error) bool {
+	var staleErr *StaleSyncError
+	return goerrors.As(err, &staleErr)
+}
+
+// newStaleSyncError counts the skipped sync and returns its StaleSyncError
+func newStaleSyncError(message string) error {
+	metrics.ObserveStaleSync()
+	return &StaleSyncError{Message: message}
+}
+
+// checkStaleSync returns a StaleSyncError when the VirtualMachine is of an older generation than the one the Machine
+// was synced with, or when the VirtualMachineInstance isn't owned by the VirtualMachine
+func (s *machineScope) checkStaleSync(vm kubevirtapiv1.VirtualMachine, vmi *kubevirtapiv1.VirtualMachineInstance) error {
+	// The generations are only comparable for the VirtualMachine the Machine was synced with, not a recreated one
+	if s.machine.Annotations[KubevirtIdAnnotationKey] == string(vm.UID) {
+		status, err := kubevirtproviderv1alpha1.ProviderStatusFromRawExtension(s.machine.Status.ProviderStatus)
+		if err == nil && status.VirtualMachineObservedGeneration > vm.Generation {
+			return newStaleSyncError(fmt.Sprintf("the VirtualMachine %s has generation %d, while the Machine was synced with generation %d",
+				vm.Name, vm.Generation, status.VirtualMachineObservedGeneration))
+		}
+	}
+	if vmi == nil || vm.UID == "" {
+		return nil
+	}
+	for _, owner := range vmi.OwnerReferences {
+		if owner.Controller != nil && *owner.Controller && owner.Kind == "VirtualMachine" && owner.UID != vm.UID {
+			return newStaleSyncError(fmt.Sprintf("the VirtualMachineInstance %s is owned by the VirtualMachine with UID %s, not %s",
+				vmi.Name, owner.UID, vm.UID))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package machinescope
+
+import (
+	"fmt"
+
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// defaultIgnitionSourceCheckImage provides the sh and curl the checkup pod runs
+	defaultIgnitionSourceCheckImage = "registry.access.redhat.com/ubi8/ubi-minimal:latest"
+	// multusNetworksAnnotation attaches the checkup pod to the network of the Machine, as its net1 interface
+	multusNetworksAnnotation = "k8s.v1.cni.cncf.io/networks"
+	// ignitionSourceCheckDeadlineSeconds bounds the run of the checkup pod
+	ignitionSourceCheckDeadlineSeconds = 120
+	// ignitionSourceCheckScript fetches every source given as argument from the network of the Machine, any
+	// HTTP response proves the source reachable
+	ignitionSourceCheckScript = `for source in "$@"; do curl --insecure --silent --show-error --output /dev/null ` +
+		`--connect-timeout 10 --max-time 30 --interface net1 "$source" || exit 1; done`
+)
+
+func (s *machineScope) GetIgnitionSourceCheck() *kubevirtproviderv1alpha1.IgnitionSourceCheck {
+	return s.machineProviderSpec.IgnitionSourceCheck.DeepCopy()
+}
+
+// BuildIgnitionSourceCheckPodName returns the name of the checkup pod of the ignition sources of a VirtualMachine
+func BuildIgnitionSourceCheckPodName(virtualMachineName string) string {
+	return fmt.Sprintf("%s-ignition-check", virtualMachineName)
+}
+
+func (s *machineScope) CreateIgnitionSourceCheckPodFromMachine(sources []string) (*corev1.Pod, error) {
+	virtualMachineName, err := s.GetVirtualMachineName()
+	if err != nil {
+		return nil, err
+	}
+	networkName, err := s.networkName()
+	if err != nil {
+		return nil, err
+	}
+	image := defaultIgnitionSourceCheckImage
+	if check := s.machineProviderSpec.IgnitionSourceCheck; check != nil && check.Image != "" {
+		image = check.Image
+	}
+	deadline := int64(ignitionSourceCheckDeadlineSeconds)
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        BuildIgnitionSourceCheckPodName(virtualMachineName),
+			Namespace:   s.infraNamespace,
+			Labels:      utils.BuildLabels(s.infraID),
+			Annotations: map[string]string{multusNetworksAnnotation: networkName},
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy:         corev1.RestartPolicyNever,
+			ActiveDeadlineSeconds: &deadline,
+			Containers: []corev1.Container{
+				{
+					Name:                     "ignition-check",
+					Image:                    image,
+					Command:                  append([]string{"/bin/sh", "-c", ignitionSourceCheckScript, "ignition-check"}, sources...),
+					TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+				},
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,25 @@
+package machinescope
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+const (
+	// IgnitionSecretOfLabel is set on the versioned ignition secrets to the name of their VirtualMachine, it selects
+	// the versions of the ignition secret of a VirtualMachine
+	IgnitionSecretOfLabel = "kubevirt.machine.openshift.io/ignition-of"
+	// ignitionSecretHashLength is the number of hex digits of the hash of the ignition suffixing a versioned secret
+	ignitionSecretHashLength = 10
+)
+
+func (s *machineScope) IgnitionSecretVersioned() bool {
+	return s.machineProviderSpec.VersionedIgnitionSecret
+}
+
+// buildVersionedIgnitionSecretName returns the name of the version of the ignition secret holding the userData
+func buildVersionedIgnitionSecretName(virtualMachineName string, userData []byte) string {
+	hash := sha256.Sum256(userData)
+	return fmt.Sprintf("%s-%s", buildIgnitionSecretName(virtualMachineName), hex.EncodeToString(hash[:])[:ignitionSecretHashLength])
+}
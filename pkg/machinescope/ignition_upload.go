@@ -0,0 +1,51 @@
+package machinescope
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cdiv1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
+
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/utils"
+)
+
+// ignitionDataVolumeOverhead is added to the size of the uploaded image for the filesystem of the PVC of the
+// ignition DataVolume, the image is stored as a disk image file on it
+const ignitionDataVolumeOverhead = 64 * 1024 * 1024
+
+func (s *machineScope) CreateIgnitionDataVolumeFromMachine(imageSize int64) (*cdiv1.DataVolume, error) {
+	virtualMachineName, err := s.GetVirtualMachineName()
+	if err != nil {
+		return nil, err
+	}
+	// The size is rounded up to MiB, storage provisioners allocate whole MiBs anyway
+	const mebibyte = 1024 * 1024
+	storage := (imageSize + ignitionDataVolumeOverhead + mebibyte - 1) / mebibyte * mebibyte
+
+	persistentVolumeClaimSpec := corev1.PersistentVolumeClaimSpec{
+		AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceStorage: *apiresource.NewQuantity(storage, apiresource.BinarySI),
+			},
+		},
+	}
+	if storageClassName := s.machineProviderSpec.StorageClassName; storageClassName != "" {
+		persistentVolumeClaimSpec.StorageClassName = &storageClassName
+	}
+
+	return &cdiv1.DataVolume{
+		TypeMeta: metav1.TypeMeta{APIVersion: cdiv1.SchemeGroupVersion.String(), Kind: "DataVolume"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      buildIgnitionSecretName(virtualMachineName),
+			Namespace: s.infraNamespace,
+			Labels:    utils.BuildLabels(s.infraID),
+		},
+		Spec: cdiv1.DataVolumeSpec{
+			Source: cdiv1.DataVolumeSource{
+				Upload: &cdiv1.DataVolumeSourceUpload{},
+			},
+			PVC: &persistentVolumeClaimSpec,
+		},
+	}, nil
+}
@@ -0,0 +1,45 @@
+package machinescope
+
+import (
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	kubevirtapiv1 "kubevirt.io/client-go/api/v1"
+
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+)
+
+// instanceState is a standard value of the instance-state annotation, as set by the other providers
+type instanceState string
+
+const (
+	instanceStatePending    instanceState = "pending"
+	instanceStateRunning    instanceState = "running"
+	instanceStateStopped    instanceState = "stopped"
+	instanceStateTerminated instanceState = "terminated"
+)
+
+// getInstanceStateValues returns the values of the instance-state annotation of the Machine, Standard when it isn't set
+func (s *machineScope) getInstanceStateValues() (kubevirtproviderv1alpha1.InstanceStateValues, error) {
+	switch values := s.machineProviderSpec.InstanceStateValues; values {
+	case "":
+		return kubevirtproviderv1alpha1.InstanceStateValuesStandard, nil
+	case kubevirtproviderv1alpha1.InstanceStateValuesStandard, kubevirtproviderv1alpha1.InstanceStateValuesKubeVirt:
+		return values, nil
+	default:
+		return "", machinecontroller.InvalidMachineConfiguration("%v: InstanceStateValues %q is not one of Standard, KubeVirt", s.machine.GetName(), values)
+	}
+}
+
+// standardInstanceState maps the printed status of the VirtualMachine to the standard instance state: the
+// VirtualMachines which are starting, or whose state is unknown, are pending, and the failed ones are stopped
+func standardInstanceState(vm kubevirtapiv1.VirtualMachine, vmi *kubevirtapiv1.VirtualMachineInstance) instanceState {
+	switch printedStatus(vm, vmi) {
+	case "Terminating":
+		return instanceStateTerminated
+	case "Running":
+		return instanceStateRunning
+	case "Stopped", "Stopping", "Failed":
+		return instanceStateStopped
+	default:
+		return instanceStatePending
+	}
+}
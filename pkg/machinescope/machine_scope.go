@@ -3,7 +3,10 @@ package machinescope
 import (
 	"fmt"
 	"net"
-	"time"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
 
 	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
 
@@ -11,8 +14,10 @@ import (
 	"github.com/openshift/cluster-api-provider-kubevirt/pkg/utils"
 	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	apiresource "k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
 
 	"k8s.io/klog"
 	kubevirtapiv1 "kubevirt.io/client-go/api/v1"
@@ -34,21 +39,53 @@ const (
 	defaultDataVolumeDiskName         = "datavolumedisk1"
 	defaultCloudInitVolumeDiskName    = "cloudinitdisk"
 	defaultBootVolumeDiskName         = "bootvolume"
-	kubevirtIdAnnotationKey           = "VmId"
+	KubevirtIdAnnotationKey           = "VmId"
+	macAddressAnnotationKey           = "VmMacAddress"
 	defaultBus                        = "virtio"
 	APIVersion                        = "kubevirt.io/v1alpha3"
 	Kind                              = "VirtualMachine"
 	mainNetworkName                   = "main"
 	terminationGracePeriodSeconds     = 600
+	// userDataSecretAnnotation names the user-data secret of a Machine which doesn't set IgnitionSecretName,
+	// e.g. through the template of its MachineSet
+	userDataSecretAnnotation = "machine.openshift.io/user-data"
+	// machineRoleLabel is the role of the Machine, the installer names its user-data secret <role>-user-data
+	machineRoleLabel = "machine.openshift.io/cluster-api-machine-role"
+	// BootVolumeDeletePolicyLabel is set on a retained boot DataVolume to its delete policy
+	BootVolumeDeletePolicyLabel = "kubevirt.machine.openshift.io/boot-volume-delete-policy"
+	// RetainedFromMachineLabel is set on a retained boot DataVolume to the name of the deleted Machine
+	RetainedFromMachineLabel = "kubevirt.machine.openshift.io/retained-from-machine"
+	// SourceImageDigestAnnotation is set on the source PVC of the boot volumes to the digest of its image
+	SourceImageDigestAnnotation = "kubevirt.machine.openshift.io/image-digest"
+	// InfraNamespaceAnnotation is the infra namespace of the VirtualMachine of the Machine
+	InfraNamespaceAnnotation = "kubevirt.machine.openshift.io/infra-namespace"
+	// MachineUIDAnnotation is set on the VirtualMachine to the UID of the Machine it was created for
+	MachineUIDAnnotation = "kubevirt.machine.openshift.io/machine-uid"
+	// VMPhaseAnnotation is the phase of the VirtualMachineInstance of the Machine, absent while it isn't running
+	VMPhaseAnnotation = "kubevirt.machine.openshift.io/vm-phase"
+	// InfraHostAnnotation is the infra-cluster node running the VirtualMachineInstance of the Machine
+	InfraHostAnnotation = "kubevirt.machine.openshift.io/infra-host"
+	// VMStateAnnotation is the KubeVirt specific state of the VirtualMachine of the Machine
+	VMStateAnnotation = "kubevirt.machine.openshift.io/vm-state"
+	// deschedulerEvictAnnotation lets the descheduler evict a pod it would otherwise skip
+	deschedulerEvictAnnotation = "descheduler.alpha.kubernetes.io/evict"
+	// deschedulerPreferNoEvictionAnnotation asks the descheduler not to evict a pod
+	deschedulerPreferNoEvictionAnnotation = "descheduler.alpha.kubernetes.io/prefer-no-eviction"
 )
 
+// MachineScope holds a Machine and its provider spec, and builds the infra-cluster objects of the Machine
+//
 //go:generate mockgen -source=./machine_scope.go -destination=./mock/machine_scope_generated.go -package=mock
 type MachineScope interface {
 	// UpdateAllowed check if conditions allow update the Virtual Machine of this Machine
-	// UpdateAllowed validates that updates come in the right order
-	UpdateAllowed(requeueAfterSeconds time.Duration) bool
+	// UpdateAllowed validates that updates come in the right order: the Machine has a providerID
+	// and was last updated within the window of the policy
+	UpdateAllowed(policy UpdatePolicy) bool
 	// CreateIgnitionSecretFromMachine builds *corev1.Secret struct, based on the data saved in the Machine
-	CreateIgnitionSecretFromMachine(userData []byte) *corev1.Secret
+	CreateIgnitionSecretFromMachine(userData []byte) (*corev1.Secret, error)
+	// CreateNetworkPolicyFromMachine builds the *networkingv1.NetworkPolicy which isolates the VirtualMachines
+	// of the tenant-cluster in the InfraCluster, or returns nil when the Machine doesn't require the isolation
+	CreateNetworkPolicyFromMachine() *networkingv1.NetworkPolicy
 	// SyncMachine update the Machine status, base of provided VirtualMachine and VirtualMachineInstance
 	// The following information is synced:
 	// ProviderID, Annotations, Labels, NetworkAddresses, ProviderStatus
@@ -59,20 +96,164 @@ type MachineScope interface {
 	GetMachine() *machinev1.Machine
 	// GetMachineName returns this Machine's name
 	GetMachineName() string
+	// GetVirtualMachineName returns the name of this Machine's VirtualMachine in the InfraCluster
+	GetVirtualMachineName() (string, error)
 	// GetMachineNamespace returns this Machine's namespace
 	GetMachineNamespace() string
+	// GetCredentialsSecretName returns the name of the secret, in this Machine's namespace, holding the kubeconfig
+	// of the InfraCluster of this Machine, or an empty string for the InfraCluster of the controller
+	GetCredentialsSecretName() string
 	// GetInfraNamespace return the namespace in the InfraCluster, in which all resources are created
 	GetInfraNamespace() string
 	// GetIgnitionSecretName returns name of the IgnitionSecret should be used durring current Machine`s
-	// VirtualMachine creation
+	// VirtualMachine creation. When IgnitionSecretName isn't set, the secret is named by the
+	// machine.openshift.io/user-data annotation of the Machine, or after its role as <role>-user-data.
 	GetIgnitionSecretName() string
+	// IgnitionSecretNameDefaulted returns whether the IgnitionSecret is named by the fallback to the
+	// annotation or the role of the Machine
+	IgnitionSecretNameDefaulted() bool
+	// GetInfraIgnitionSecretName returns the name of the pre-provisioned ignition secret in the InfraCluster,
+	// or an empty string when the ignition is copied from the TenantCluster
+	GetInfraIgnitionSecretName() string
+	// IgnitionPropagatedViaSecret returns whether the ignition is propagated to the VirtualMachine via
+	// a secret in the InfraCluster, or uploaded to a DataVolume
+	IgnitionPropagatedViaSecret() bool
+	// CreateIgnitionDataVolumeFromMachine builds the *cdiv1.DataVolume the config drive image holding the
+	// userData is uploaded to, of the size of the image, when the ignition isn't propagated via a secret
+	CreateIgnitionDataVolumeFromMachine(imageSize int64) (*cdiv1.DataVolume, error)
+	// IgnitionSecretVersioned returns whether the ignition secret is named after the hash of the ignition, so a
+	// new ignition is created in a new version of the secret instead of updating the one in use
+	IgnitionSecretVersioned() bool
+	// GetIgnitionMergeSource returns the source the guest merges its ignition from, or nil when the ignition
+	// content is passed to the guest as is
+	GetIgnitionMergeSource() (*kubevirtproviderv1alpha1.IgnitionMergeSource, error)
+	// GetIgnitionSourceHost returns the host the remote ignition sources of the userData are rewritten to,
+	// or an empty string when they are kept as is
+	GetIgnitionSourceHost() (string, error)
+	// GetIgnitionSourceCheck returns the check of the remote ignition sources of the userData, or nil when
+	// they aren't checked
+	GetIgnitionSourceCheck() *kubevirtproviderv1alpha1.IgnitionSourceCheck
+	// CreateIgnitionSourceCheckPodFromMachine builds the *corev1.Pod which checks that the sources are reachable
+	// from the network of the Machine
+	CreateIgnitionSourceCheckPodFromMachine(sources []string) (*corev1.Pod, error)
+	// GetInstanceMetadata returns a copy of the custom instance metadata to expose to the guest
+	GetInstanceMetadata() map[string]string
+	// GetAfterburnMetadata returns the afterburn provider hints to write to the guest, or nil when they aren't required
+	GetAfterburnMetadata() *kubevirtproviderv1alpha1.AfterburnMetadata
+	// GetAddressesFromPools returns the pools the static addresses of the guest are claimed from
+	GetAddressesFromPools() []kubevirtproviderv1alpha1.AddressesFromPool
+	// SetStaticAddresses sets the claimed addresses, which are written to the network data of the VirtualMachine
+	SetStaticAddresses(addresses []StaticAddress)
+	// CreateStepDone returns whether the step of the creation was recorded as completed on the Machine
+	CreateStepDone(step CreateStep) bool
+	// SetCreateStepDone records the step of the creation as completed on the Machine, and persists it
+	// with the machine patcher when set
+	SetCreateStepDone(step CreateStep) error
+	// GetOriginalMachine returns the Machine as it was read, or last patched
+	GetOriginalMachine() *machinev1.Machine
+	// PatchMachine persists the changes of the metadata, the spec and the status of the Machine since it was read,
+	// or last patched
+	PatchMachine() error
+	// PatchMachineMetadata persists the changes of the metadata and the spec of the Machine only, e.g. while it is
+	// created, its status is persisted by the next PatchMachine
+	PatchMachineMetadata() error
+	// HostnameInjectionEnabled returns whether the hostname of the guest is set through the ignition
+	HostnameInjectionEnabled() bool
+	// GetHostname returns the hostname of the guest, which is the name of its Node in the TenantCluster
+	GetHostname() (string, error)
+	// GuestAgentRequired returns whether the Machine is ready only once the guest agent of its
+	// VirtualMachineInstance is connected
+	GuestAgentRequired() bool
+	// GetReconciliationMode returns what the update of the Machine reconciles, full when it isn't set
+	GetReconciliationMode() (kubevirtproviderv1alpha1.ReconciliationMode, error)
+	// NodeDrainRequiredBeforeDelete returns whether the VirtualMachine may be deleted only once the Node
+	// of the Machine is drained
+	NodeDrainRequiredBeforeDelete() bool
+	// NodeSmokeCheckRequired returns whether the Node of the Machine registers with the uninitialized taint,
+	// which is removed once the Node passes the NodeSmokeCheck
+	NodeSmokeCheckRequired() bool
+	// SetMachineCreationCondition sets the MachineCreation condition of the ProviderStatus,
+	// succeeded when err is nil and failed with the message of err otherwise
+	SetMachineCreationCondition(err error) error
+	// SetMachineRequiresReplacementCondition sets the MachineRequiresReplacement condition of the ProviderStatus,
+	// with the message of err, once the VirtualMachine of the Machine was recreated
+	SetMachineRequiresReplacementCondition(err error) error
+	// GetVirtualMachineUID returns the UID of the VirtualMachine the Machine was first synced with, empty until
+	// the Machine is synced
+	GetVirtualMachineUID() string
+	// GetStorageClassName returns the storage class of the boot volume, empty for the default storage class
+	GetStorageClassName() string
+	// PersistentVolumeAccessModeDetectionRequired returns whether the access mode of the boot volume is left
+	// to be selected by the capabilities of its storage class
+	PersistentVolumeAccessModeDetectionRequired() bool
+	// SelectPersistentVolumeAccessMode selects the access mode of the boot volume among the access modes
+	// supported by its storage class, and returns a message describing the decision
+	SelectPersistentVolumeAccessMode(storageClassName string, supported []corev1.PersistentVolumeAccessMode) string
+	// GetPersistentVolumeAccessModeDecision returns the message of the last access mode selection,
+	// or an empty string if the access mode wasn't selected
+	GetPersistentVolumeAccessModeDecision() string
+	// GetBootVolumeShape returns the shape of the boot volume of the Machine, which selects the DataVolumes
+	// of the warm pool the Machine may boot from
+	GetBootVolumeShape() string
+	// GetVirtualMachinePoolName returns the name of the VirtualMachinePool backing the Machine, or an empty
+	// string when the Machine creates its own VirtualMachine
+	GetVirtualMachinePoolName() string
+	// SetPoolVirtualMachine records the VirtualMachine of the pool adopted by the Machine, which is the
+	// VirtualMachine of the Machine from then on
+	SetPoolVirtualMachine(name string)
+	// GetExistingVMName returns the name of the pre-staged VirtualMachine adopted by the Machine, or an empty
+	// string when the Machine doesn't adopt a pre-staged VirtualMachine
+	GetExistingVMName() string
+	// GetBootVolumeDeletePolicy returns whether the boot volume is deleted with the Machine, Delete when it isn't set
+	GetBootVolumeDeletePolicy() (kubevirtproviderv1alpha1.BootVolumeDeletePolicy, error)
+	// GetSourceImageDigest returns the digest the image of the source PVC of the boot volume is pinned to,
+	// empty when the image isn't pinned
+	GetSourceImageDigest() (string, error)
+	// GetDataSource returns the namespace and the name of the CDI DataSource the boot volume is cloned from,
+	// the name is empty when the boot volume is cloned from SourcePvcName
+	GetDataSource() (string, string)
+	// ClusterProxyPropagationRequired returns whether the cluster-wide proxy of the TenantCluster is merged
+	// into the ignition of the Machine
+	ClusterProxyPropagationRequired() bool
+	// SetClusterProxy sets the cluster-wide proxy of the TenantCluster, which is merged into the ignition
+	SetClusterProxy(proxy *ClusterProxy)
+	// GetClusterProxy returns the cluster-wide proxy to merge into the ignition, or nil when it isn't propagated
+	GetClusterProxy() *ClusterProxy
+	// GetZone returns the infra zone the VirtualMachine is scheduled in, empty when it isn't pinned to a zone
+	GetZone() string
+	// GetZoneChoices returns the zones of ZoneNetworkNames, in order, when the Machine has to be assigned one of them
+	GetZoneChoices() []string
+	// SetZone assigns the infra zone to the Machine, through its zone label
+	SetZone(zone string)
 }
 
+// sourceImageDigestRegexp matches the digests the source image can be pinned to
+var sourceImageDigestRegexp = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
+// supportedInterfaceModels are the network device models KubeVirt can emulate
+var supportedInterfaceModels = []string{"virtio", "e1000", "e1000e", "ne2k_pci", "pcnet", "rtl8139"}
+
 type machineScope struct {
 	machine             *machinev1.Machine
 	machineProviderSpec *kubevirtproviderv1alpha1.KubevirtMachineProviderSpec
 	infraNamespace      string
 	infraID             string
+	// selectedAccessMode is the access mode selected by the capabilities of the storage class
+	selectedAccessMode corev1.PersistentVolumeAccessMode
+	// accessModeDecision describes how selectedAccessMode was selected
+	accessModeDecision string
+	// staticAddresses are the addresses claimed for the main interface of the guest
+	staticAddresses []StaticAddress
+	// clusterProxy is the cluster-wide proxy of the tenant-cluster, merged into the ignition of the guest
+	clusterProxy *ClusterProxy
+	// machinePatcher persists the changes of the machine since originMachineCopy, nil when the machine is only read
+	machinePatcher MachinePatcher
+	// originMachineCopy is the machine as it was read, or last patched
+	originMachineCopy *machinev1.Machine
+	// patchLock serializes the patches of the machine, and the updates of originMachineCopy
+	patchLock sync.Mutex
+	// metadataFilter selects the labels and annotations of the machine copied onto its VirtualMachine
+	metadataFilter MetadataFilter
 }
 
 func (s *machineScope) GetInfraNamespace() string {
@@ -83,15 +264,35 @@ func (s *machineScope) CreateVirtualMachineFromMachine() (*kubevirtapiv1.Virtual
 	if err := s.assertMandatoryParams(); err != nil {
 		return nil, err
 	}
+	virtualMachineName, err := s.GetVirtualMachineName()
+	if err != nil {
+		return nil, err
+	}
 	runAlways := kubevirtapiv1.RunStrategyAlways
 
-	vmiTemplate := s.buildVMITemplate(s.infraNamespace)
+	networkName, err := s.networkName()
+	if err != nil {
+		return nil, err
+	}
+	sourcePvcName, err := s.renderField("SourcePvcName", s.machineProviderSpec.SourcePvcName)
+	if err != nil {
+		return nil, err
+	}
+	if errs := validation.IsDNS1123Subdomain(sourcePvcName); sourcePvcName != "" && len(errs) > 0 {
+		return nil, machinecontroller.InvalidMachineConfiguration("%v: SourcePvcName rendered an invalid name %q: %v",
+			s.machine.GetName(), sourcePvcName, strings.Join(errs, ", "))
+	}
+
+	vmiTemplate := s.buildVMITemplate(virtualMachineName, networkName)
 
 	pvcRequestsStorage := s.machineProviderSpec.RequestedStorage
 	if pvcRequestsStorage == "" {
 		pvcRequestsStorage = defaultRequestedStorage
 	}
 	PVCAccessMode := defaultPersistentVolumeAccessMode
+	if s.selectedAccessMode != "" {
+		PVCAccessMode = s.selectedAccessMode
+	}
 	if s.machineProviderSpec.PersistentVolumeAccessMode != "" {
 		accessMode := corev1.PersistentVolumeAccessMode(s.machineProviderSpec.PersistentVolumeAccessMode)
 		switch accessMode {
@@ -107,13 +308,53 @@ func (s *machineScope) CreateVirtualMachineFromMachine() (*kubevirtapiv1.Virtual
 		}
 	}
 
+	if s.machineProviderSpec.MacAddress != "" {
+		if _, err := net.ParseMAC(s.machineProviderSpec.MacAddress); err != nil {
+			return nil, machinecontroller.InvalidMachineConfiguration("%v: Value of MacAddress is not valid: %v", s.machine.GetName(), err)
+		}
+		// The Machines of a MachineSet share its provider spec, their VirtualMachines would share the MAC address
+		if owner := metav1.GetControllerOf(s.machine); owner != nil && owner.Kind == "MachineSet" {
+			return nil, machinecontroller.InvalidMachineConfiguration("%v: MacAddress can't be set on the Machines of MachineSet %v",
+				s.machine.GetName(), owner.Name)
+		}
+	}
+
+	if !isSupportedInterfaceModel(s.machineProviderSpec.InterfaceModel) {
+		return nil, machinecontroller.InvalidMachineConfiguration("%v: Value of InterfaceModel, can be only one of: %v",
+			s.machine.GetName(), strings.Join(supportedInterfaceModels, ", "))
+	}
+
+	switch s.machineProviderSpec.DNSPolicy {
+	case "", corev1.DNSClusterFirst, corev1.DNSClusterFirstWithHostNet, corev1.DNSDefault:
+	case corev1.DNSNone:
+		if s.machineProviderSpec.DNSConfig == nil || len(s.machineProviderSpec.DNSConfig.Nameservers) == 0 {
+			return nil, machinecontroller.InvalidMachineConfiguration("%v: DNSPolicy %v requires DNSConfig with at least one nameserver",
+				s.machine.GetName(), corev1.DNSNone)
+		}
+	default:
+		return nil, machinecontroller.InvalidMachineConfiguration("%v: Value of DNSPolicy, can be only one of: %v, %v, %v, %v",
+			s.machine.GetName(), corev1.DNSClusterFirst, corev1.DNSClusterFirstWithHostNet, corev1.DNSDefault, corev1.DNSNone)
+	}
+
+	switch s.machineProviderSpec.DeschedulerEviction {
+	case "", kubevirtproviderv1alpha1.DeschedulerEvictionEvictable, kubevirtproviderv1alpha1.DeschedulerEvictionNotEvictable:
+	default:
+		return nil, machinecontroller.InvalidMachineConfiguration("%v: Value of DeschedulerEviction, can be only one of: %v, %v",
+			s.machine.GetName(), kubevirtproviderv1alpha1.DeschedulerEvictionEvictable, kubevirtproviderv1alpha1.DeschedulerEvictionNotEvictable)
+	}
+
+	if networkName == "" {
+		return nil, machinecontroller.InvalidMachineConfiguration("%v: missing value for NetworkName, or in ZoneNetworkNames for zone %q",
+			s.machine.GetName(), s.GetZone())
+	}
+
 	virtualMachine := kubevirtapiv1.VirtualMachine{
 		Spec: kubevirtapiv1.VirtualMachineSpec{
 			RunStrategy: &runAlways,
 			DataVolumeTemplates: []cdiv1.DataVolume{
 				*buildBootVolumeDataVolumeTemplate(
-					s.machine.GetName(),
-					s.machineProviderSpec.SourcePvcName,
+					virtualMachineName,
+					sourcePvcName,
 					s.infraNamespace,
 					s.machineProviderSpec.StorageClassName,
 					pvcRequestsStorage,
@@ -123,19 +364,37 @@ func (s *machineScope) CreateVirtualMachineFromMachine() (*kubevirtapiv1.Virtual
 			Template: vmiTemplate,
 		},
 	}
+	if s.machineProviderSpec.DataSourceName != "" {
+		// The source PVC the DataSource points to is resolved in the infra-cluster when the VirtualMachine is created
+		virtualMachine.Spec.DataVolumeTemplates[0].Spec.Source.PVC = nil
+	}
 
+	machineLabels, droppedLabels := s.metadataFilter.Filter(s.machine.Labels)
+	annotations, droppedAnnotations := s.metadataFilter.FilterAnnotations(s.machine.Annotations)
+	if len(droppedLabels) > 0 || len(droppedAnnotations) > 0 {
+		klog.Infof("%s: labels %v and annotations %v of the Machine aren't copied onto the VirtualMachine by the metadata filter",
+			s.machine.GetName(), droppedLabels, droppedAnnotations)
+	}
 	labels := utils.BuildLabels(s.infraID)
-	for k, v := range s.machine.Labels {
+	for k, v := range machineLabels {
 		labels[k] = v
 	}
+	// The UID of the Machine tells its VirtualMachine apart from a VirtualMachine of the same name created for
+	// another Machine
+	if s.machine.UID != "" {
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[MachineUIDAnnotation] = string(s.machine.UID)
+	}
 
 	virtualMachine.APIVersion = APIVersion
 	virtualMachine.Kind = Kind
 	virtualMachine.ObjectMeta = metav1.ObjectMeta{
-		Name:            s.machine.Name,
+		Name:            virtualMachineName,
 		Namespace:       s.infraNamespace,
 		Labels:          labels,
-		Annotations:     s.machine.Annotations,
+		Annotations:     annotations,
 		OwnerReferences: nil,
 		ClusterName:     s.machine.ClusterName,
 	}
@@ -143,21 +402,34 @@ func (s *machineScope) CreateVirtualMachineFromMachine() (*kubevirtapiv1.Virtual
 	return &virtualMachine, nil
 }
 
+func isSupportedInterfaceModel(model string) bool {
+	if model == "" {
+		return true
+	}
+	for _, supportedModel := range supportedInterfaceModels {
+		if model == supportedModel {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *machineScope) assertMandatoryParams() error {
 	switch {
-	case s.machineProviderSpec.SourcePvcName == "":
-		return machinecontroller.InvalidMachineConfiguration("%v: missing value for SourcePvcName", s.machine.GetName())
-	case s.machineProviderSpec.IgnitionSecretName == "":
+	case s.machineProviderSpec.SourcePvcName == "" && s.machineProviderSpec.DataSourceName == "":
+		return machinecontroller.InvalidMachineConfiguration("%v: missing value for SourcePvcName or DataSourceName", s.machine.GetName())
+	case s.machineProviderSpec.SourcePvcName != "" && s.machineProviderSpec.DataSourceName != "":
+		return machinecontroller.InvalidMachineConfiguration("%v: SourcePvcName and DataSourceName are mutually exclusive", s.machine.GetName())
+	case s.GetIgnitionSecretName() == "" && s.machineProviderSpec.InfraIgnitionSecretName == "":
 		return machinecontroller.InvalidMachineConfiguration("%v: missing value for IgnitionSecretName", s.machine.GetName())
-	case s.machineProviderSpec.NetworkName == "":
+	case s.machineProviderSpec.NetworkName == "" && len(s.machineProviderSpec.ZoneNetworkNames) == 0:
 		return machinecontroller.InvalidMachineConfiguration("%v: missing value for NetworkName", s.machine.GetName())
 	default:
 		return nil
 	}
 }
 
-func (s *machineScope) buildVMITemplate(namespace string) *kubevirtapiv1.VirtualMachineInstanceTemplateSpec {
-	virtualMachineName := s.machine.GetName()
+func (s *machineScope) buildVMITemplate(virtualMachineName string, networkName string) *kubevirtapiv1.VirtualMachineInstanceTemplateSpec {
 	interfaceBindingMethod := kubevirtapiv1.InterfaceBindingMethod{
 		Bridge: &kubevirtapiv1.InterfaceBridge{},
 	}
@@ -169,15 +441,39 @@ func (s *machineScope) buildVMITemplate(namespace string) *kubevirtapiv1.Virtual
 
 	template := &kubevirtapiv1.VirtualMachineInstanceTemplateSpec{}
 
+	// The ownership labels are propagated to the virt-launcher pod, and select it in the NetworkPolicy of the tenant-cluster
+	templateLabels := utils.BuildLabels(s.infraID)
+	templateLabels["kubevirt.io/vm"] = virtualMachineName
+	templateLabels["name"] = virtualMachineName
 	template.ObjectMeta = metav1.ObjectMeta{
-		Labels: map[string]string{"kubevirt.io/vm": virtualMachineName, "name": virtualMachineName},
+		Labels: templateLabels,
 	}
 
 	ignitionSecretName := buildIgnitionSecretName(virtualMachineName)
+	if s.machineProviderSpec.InfraIgnitionSecretName != "" {
+		ignitionSecretName = s.machineProviderSpec.InfraIgnitionSecretName
+	}
 
 	terminationGracePeriod := int64(terminationGracePeriodSeconds)
 	template.Spec = kubevirtapiv1.VirtualMachineInstanceSpec{
 		TerminationGracePeriodSeconds: &terminationGracePeriod,
+		DNSPolicy:                     s.machineProviderSpec.DNSPolicy,
+	}
+	if zone := s.GetZone(); zone != "" {
+		template.Spec.NodeSelector = map[string]string{InfraZoneLabel: zone}
+	}
+	if s.machineProviderSpec.DNSConfig != nil {
+		template.Spec.DNSConfig = s.machineProviderSpec.DNSConfig.DeepCopy()
+	}
+	// The annotations of the template are propagated to the virt-launcher pod, which the descheduler evicts
+	switch s.machineProviderSpec.DeschedulerEviction {
+	case kubevirtproviderv1alpha1.DeschedulerEvictionEvictable:
+		template.ObjectMeta.Annotations = map[string]string{deschedulerEvictAnnotation: "true"}
+		// The eviction of the virt-launcher pod live migrates the VirtualMachine instead of shutting it down
+		liveMigrate := kubevirtapiv1.EvictionStrategyLiveMigrate
+		template.Spec.EvictionStrategy = &liveMigrate
+	case kubevirtproviderv1alpha1.DeschedulerEvictionNotEvictable:
+		template.ObjectMeta.Annotations = map[string]string{deschedulerPreferNoEvictionAnnotation: "true"}
 	}
 	template.Spec.Volumes = []kubevirtapiv1.Volume{
 		{
@@ -195,12 +491,13 @@ func (s *machineScope) buildVMITemplate(namespace string) *kubevirtapiv1.Virtual
 					UserDataSecretRef: &corev1.LocalObjectReference{
 						Name: ignitionSecretName,
 					},
+					NetworkData: s.buildNetworkData(),
 				},
 			},
 		},
 	}
 	multusNetwork := &kubevirtapiv1.MultusNetwork{
-		NetworkName: s.machineProviderSpec.NetworkName,
+		NetworkName: networkName,
 	}
 	template.Spec.Networks = []kubevirtapiv1.Network{
 		{
@@ -251,6 +548,8 @@ func (s *machineScope) buildVMITemplate(namespace string) *kubevirtapiv1.Virtual
 		Interfaces: []kubevirtapiv1.Interface{
 			{
 				Name:                   mainNetworkName,
+				Model:                  s.machineProviderSpec.InterfaceModel,
+				MacAddress:             s.macAddress(),
 				InterfaceBindingMethod: interfaceBindingMethod,
 			},
 		},
@@ -259,6 +558,14 @@ func (s *machineScope) buildVMITemplate(namespace string) *kubevirtapiv1.Virtual
 	return template
 }
 
+// macAddress returns the MAC address requested in the provider spec, or the one recorded on the Machine
+func (s *machineScope) macAddress() string {
+	if s.machineProviderSpec.MacAddress != "" {
+		return s.machineProviderSpec.MacAddress
+	}
+	return s.machine.GetAnnotations()[macAddressAnnotationKey]
+}
+
 func (s *machineScope) GetMachine() *machinev1.Machine {
 	return s.machine
 }
@@ -267,15 +574,47 @@ func (s *machineScope) GetMachineName() string {
 	return s.machine.GetName()
 }
 
+func (s *machineScope) GetVirtualMachineName() (string, error) {
+	if name := s.machine.Annotations[PoolVirtualMachineAnnotation]; name != "" {
+		return name, nil
+	}
+	if s.machineProviderSpec.ExistingVMName != "" {
+		return s.machineProviderSpec.ExistingVMName, nil
+	}
+	if s.machineProviderSpec.NameTemplate == "" {
+		return s.machine.GetName(), nil
+	}
+	name, err := s.renderTemplate("NameTemplate", s.machineProviderSpec.NameTemplate)
+	if err != nil {
+		return "", err
+	}
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return "", machinecontroller.InvalidMachineConfiguration("%v: NameTemplate rendered an invalid name %q: %v",
+			s.machine.GetName(), name, strings.Join(errs, ", "))
+	}
+	return name, nil
+}
+
 func (s *machineScope) GetMachineNamespace() string {
 	return s.machine.GetNamespace()
 }
 
-func (s *machineScope) UpdateAllowed(requeueAfterSeconds time.Duration) bool {
+func (s *machineScope) GetVirtualMachineUID() string {
+	if status, err := kubevirtproviderv1alpha1.ProviderStatusFromRawExtension(s.machine.Status.ProviderStatus); err == nil && status.VirtualMachineUID != "" {
+		return status.VirtualMachineUID
+	}
+	// The Machines synced before the UID was recorded in their ProviderStatus only have the VmId annotation
+	return s.machine.Annotations[KubevirtIdAnnotationKey]
+}
+
+func (s *machineScope) GetCredentialsSecretName() string {
+	return s.machineProviderSpec.CredentialsSecretName
+}
+
+func (s *machineScope) UpdateAllowed(policy UpdatePolicy) bool {
 	return s.machine.Spec.ProviderID != nil &&
 		*s.machine.Spec.ProviderID != "" &&
-		(s.machine.Status.LastUpdated == nil ||
-			s.machine.Status.LastUpdated.Add(requeueAfterSeconds*time.Second).After(time.Now()))
+		policy.allows(s.machine.Status.LastUpdated)
 }
 
 func buildBootVolumeName(virtualMachineName string) string {
@@ -286,10 +625,17 @@ func buildIgnitionSecretName(virtualMachineName string) string {
 	return fmt.Sprintf("%s-ignition", virtualMachineName)
 }
 
-func (s *machineScope) CreateIgnitionSecretFromMachine(userData []byte) *corev1.Secret {
-	virtualMachineName := s.machine.GetName()
+func (s *machineScope) CreateIgnitionSecretFromMachine(userData []byte) (*corev1.Secret, error) {
+	virtualMachineName, err := s.GetVirtualMachineName()
+	if err != nil {
+		return nil, err
+	}
 	ignitionSecretName := buildIgnitionSecretName(virtualMachineName)
 	labels := utils.BuildLabels(s.infraID)
+	if s.IgnitionSecretVersioned() {
+		ignitionSecretName = buildVersionedIgnitionSecretName(virtualMachineName, userData)
+		labels[IgnitionSecretOfLabel] = virtualMachineName
+	}
 
 	resultSecret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
@@ -302,11 +648,147 @@ func (s *machineScope) CreateIgnitionSecretFromMachine(userData []byte) *corev1.
 		},
 	}
 
-	return resultSecret
+	return resultSecret, nil
 }
 
 func (s *machineScope) GetIgnitionSecretName() string {
-	return s.machineProviderSpec.IgnitionSecretName
+	if s.machineProviderSpec.IgnitionSecretName != "" {
+		return s.machineProviderSpec.IgnitionSecretName
+	}
+	if secretName := s.machine.Annotations[userDataSecretAnnotation]; secretName != "" {
+		return secretName
+	}
+	if role := s.machine.Labels[machineRoleLabel]; role != "" {
+		return role + "-user-data"
+	}
+	return ""
+}
+
+func (s *machineScope) IgnitionSecretNameDefaulted() bool {
+	return s.machineProviderSpec.IgnitionSecretName == "" && s.GetIgnitionSecretName() != ""
+}
+
+func (s *machineScope) GetInfraIgnitionSecretName() string {
+	return s.machineProviderSpec.InfraIgnitionSecretName
+}
+
+func (s *machineScope) IgnitionPropagatedViaSecret() bool {
+	return s.machineProviderSpec.PropagateIgnitionViaSecret == nil || *s.machineProviderSpec.PropagateIgnitionViaSecret
+}
+
+func (s *machineScope) GetIgnitionMergeSource() (*kubevirtproviderv1alpha1.IgnitionMergeSource, error) {
+	source := s.machineProviderSpec.IgnitionMergeSource
+	if source == nil {
+		return nil, nil
+	}
+	sourceURL, err := url.Parse(source.URL)
+	if err != nil || sourceURL.Scheme != "https" || sourceURL.Host == "" {
+		return nil, machinecontroller.InvalidMachineConfiguration("%v: IgnitionMergeSource URL %q is not an https URL", s.machine.GetName(), source.URL)
+	}
+	return source.DeepCopy(), nil
+}
+
+func (s *machineScope) GetIgnitionSourceHost() (string, error) {
+	host := s.machineProviderSpec.IgnitionSourceHost
+	if host == "" {
+		return "", nil
+	}
+	hostURL, err := url.Parse("//" + host)
+	if err != nil || hostURL.Host != host || hostURL.User != nil {
+		return "", machinecontroller.InvalidMachineConfiguration("%v: IgnitionSourceHost %q is not a host or host:port", s.machine.GetName(), host)
+	}
+	return host, nil
+}
+
+func (s *machineScope) GetInstanceMetadata() map[string]string {
+	if len(s.machineProviderSpec.InstanceMetadata) == 0 {
+		return nil
+	}
+	metadata := make(map[string]string, len(s.machineProviderSpec.InstanceMetadata))
+	for k, v := range s.machineProviderSpec.InstanceMetadata {
+		metadata[k] = v
+	}
+	return metadata
+}
+
+func (s *machineScope) GetAfterburnMetadata() *kubevirtproviderv1alpha1.AfterburnMetadata {
+	return s.machineProviderSpec.AfterburnMetadata.DeepCopy()
+}
+
+func (s *machineScope) HostnameInjectionEnabled() bool {
+	return !s.machineProviderSpec.DisableHostnameInjection
+}
+
+func (s *machineScope) GuestAgentRequired() bool {
+	return s.machineProviderSpec.WaitForGuestAgent
+}
+
+func (s *machineScope) GetReconciliationMode() (kubevirtproviderv1alpha1.ReconciliationMode, error) {
+	switch mode := s.machineProviderSpec.ReconciliationMode; mode {
+	case "":
+		if s.machineProviderSpec.VirtualMachinePoolName != "" || s.machineProviderSpec.ExistingVMName != "" {
+			return kubevirtproviderv1alpha1.ReconciliationModeStatusOnly, nil
+		}
+		return kubevirtproviderv1alpha1.ReconciliationModeFull, nil
+	case kubevirtproviderv1alpha1.ReconciliationModeNone, kubevirtproviderv1alpha1.ReconciliationModeStatusOnly,
+		kubevirtproviderv1alpha1.ReconciliationModeFull:
+		return mode, nil
+	default:
+		return "", machinecontroller.InvalidMachineConfiguration("%v: ReconciliationMode %q is not one of none, status-only, full", s.machine.GetName(), mode)
+	}
+}
+
+func (s *machineScope) GetBootVolumeDeletePolicy() (kubevirtproviderv1alpha1.BootVolumeDeletePolicy, error) {
+	switch policy := s.machineProviderSpec.BootVolumeDeletePolicy; policy {
+	case "":
+		return kubevirtproviderv1alpha1.BootVolumeDeletePolicyDelete, nil
+	case kubevirtproviderv1alpha1.BootVolumeDeletePolicyDelete, kubevirtproviderv1alpha1.BootVolumeDeletePolicyRetain:
+		return policy, nil
+	default:
+		return "", machinecontroller.InvalidMachineConfiguration("%v: BootVolumeDeletePolicy %q is not one of Delete, Retain", s.machine.GetName(), policy)
+	}
+}
+
+func (s *machineScope) GetSourceImageDigest() (string, error) {
+	digest := s.machineProviderSpec.SourceImageDigest
+	if digest != "" && !sourceImageDigestRegexp.MatchString(digest) {
+		return "", machinecontroller.InvalidMachineConfiguration("%v: SourceImageDigest %q is not of the form sha256:<hex>", s.machine.GetName(), digest)
+	}
+	return digest, nil
+}
+
+func (s *machineScope) GetDataSource() (string, string) {
+	namespace := s.machineProviderSpec.DataSourceNamespace
+	if namespace == "" {
+		namespace = s.infraNamespace
+	}
+	return namespace, s.machineProviderSpec.DataSourceName
+}
+
+func (s *machineScope) GetExistingVMName() string {
+	return s.machineProviderSpec.ExistingVMName
+}
+
+func (s *machineScope) NodeDrainRequiredBeforeDelete() bool {
+	return s.machineProviderSpec.RequireNodeDrainedBeforeDelete
+}
+
+func (s *machineScope) NodeSmokeCheckRequired() bool {
+	return s.machineProviderSpec.NodeSmokeCheck != nil
+}
+
+func (s *machineScope) GetHostname() (string, error) {
+	if s.machineProviderSpec.HostnameOverride == "" {
+		return s.machine.GetName(), nil
+	}
+	hostname, err := s.renderTemplate("HostnameOverride", s.machineProviderSpec.HostnameOverride)
+	if err != nil {
+		return "", err
+	}
+	if hostname == "" {
+		return "", machinecontroller.InvalidMachineConfiguration("%v: HostnameOverride rendered an empty hostname", s.machine.GetName())
+	}
+	return hostname, nil
 }
 
 func buildBootVolumeDataVolumeTemplate(virtualMachineName, pvcName, dvNamespace, storageClassName,
@@ -345,12 +827,19 @@ func buildBootVolumeDataVolumeTemplate(virtualMachineName, pvcName, dvNamespace,
 }
 
 func (s *machineScope) SyncMachine(vm kubevirtapiv1.VirtualMachine, vmi *kubevirtapiv1.VirtualMachineInstance, providerID string) error {
+	if err := s.checkStaleSync(vm, vmi); err != nil {
+		return err
+	}
+	instanceStateValues, err := s.getInstanceStateValues()
+	if err != nil {
+		return err
+	}
 	s.syncProviderID(vm, providerID)
-	s.syncMachineAnnotationsAndLabels(vm)
+	s.syncMachineAnnotationsAndLabels(vm, vmi, instanceStateValues)
 	if vmi != nil {
 		s.syncNetworkAddresses(*vmi)
 	}
-	return s.syncProviderStatus(vm)
+	return s.syncProviderStatus(vm, vmi)
 }
 
 // syncProviderID adds providerID in the machine spec
@@ -366,7 +855,8 @@ func (s *machineScope) syncProviderID(vm kubevirtapiv1.VirtualMachine, providerI
 	klog.Infof("%s - syncProviderID: successfully synced machine.Spec.ProviderID to %s", s.GetMachineName(), providerID)
 }
 
-func (s *machineScope) syncMachineAnnotationsAndLabels(vm kubevirtapiv1.VirtualMachine) {
+func (s *machineScope) syncMachineAnnotationsAndLabels(vm kubevirtapiv1.VirtualMachine, vmi *kubevirtapiv1.VirtualMachineInstance,
+	instanceStateValues kubevirtproviderv1alpha1.InstanceStateValues) {
 	if s.machine.Labels == nil {
 		s.machine.Labels = make(map[string]string)
 	}
@@ -383,18 +873,70 @@ func (s *machineScope) syncMachineAnnotationsAndLabels(vm kubevirtapiv1.VirtualM
 		}
 	}
 
-	s.machine.ObjectMeta.Annotations[kubevirtIdAnnotationKey] = string(vm.UID)
+	s.machine.ObjectMeta.Annotations[KubevirtIdAnnotationKey] = string(vm.UID)
 	if vm.Spec.Template != nil {
 		s.machine.Labels[machinecontroller.MachineInstanceTypeLabelName] = vm.Spec.Template.Spec.Domain.Machine.Type
+		if zone := vm.Spec.Template.Spec.NodeSelector[InfraZoneLabel]; zone != "" {
+			s.machine.Labels[machinecontroller.MachineAZLabelName] = zone
+		}
+		for _, iface := range vm.Spec.Template.Spec.Domain.Devices.Interfaces {
+			if iface.Name == mainNetworkName && iface.MacAddress != "" {
+				s.machine.Annotations[macAddressAnnotationKey] = iface.MacAddress
+			}
+		}
 	}
-	s.machine.Annotations[machinecontroller.MachineInstanceStateAnnotationName] = string(vmState)
+	s.machine.Annotations[VMStateAnnotation] = string(vmState)
+	if instanceStateValues == kubevirtproviderv1alpha1.InstanceStateValuesKubeVirt {
+		s.machine.Annotations[machinecontroller.MachineInstanceStateAnnotationName] = string(vmState)
+	} else {
+		s.machine.Annotations[machinecontroller.MachineInstanceStateAnnotationName] = string(standardInstanceState(vm, vmi))
+	}
+
+	// The placement of the VirtualMachine in the infra-cluster, for the Machine views of the tenant cluster
+	s.machine.Annotations[InfraNamespaceAnnotation] = vm.Namespace
+	var vmPhase, infraHost string
+	if vmi != nil {
+		vmPhase, infraHost = string(vmi.Status.Phase), vmi.Status.NodeName
+	}
+	setOrDeleteAnnotation(s.machine.Annotations, VMPhaseAnnotation, vmPhase)
+	setOrDeleteAnnotation(s.machine.Annotations, InfraHostAnnotation, infraHost)
 	klog.Infof("%s - syncMachineAnnotationsAndLabels: successfully synced", s.GetMachineName())
 }
 
-func (s *machineScope) syncProviderStatus(vm kubevirtapiv1.VirtualMachine) error {
-	providerStatus, err := kubevirtproviderv1alpha1.RawExtensionFromProviderStatus(&kubevirtproviderv1alpha1.KubevirtMachineProviderStatus{
-		VirtualMachineStatus: vm.Status,
-	})
+// setOrDeleteAnnotation sets the annotation to the value, or deletes it when the value is empty
+func setOrDeleteAnnotation(annotations map[string]string, key string, value string) {
+	if value == "" {
+		delete(annotations, key)
+		return
+	}
+	annotations[key] = value
+}
+
+func (s *machineScope) syncProviderStatus(vm kubevirtapiv1.VirtualMachine, vmi *kubevirtapiv1.VirtualMachineInstance) error {
+	status := &kubevirtproviderv1alpha1.KubevirtMachineProviderStatus{
+		VirtualMachine:                   summarizeVirtualMachine(vm, vmi),
+		ObservedGeneration:               s.machine.Generation,
+		VirtualMachineObservedGeneration: vm.Generation,
+		VirtualMachineUID:                string(vm.UID),
+	}
+	if !s.machineProviderSpec.OmitVirtualMachineStatus {
+		status.VirtualMachineStatus = vm.Status
+	}
+	// The status of the Node is synced by the nodestatus controller, and the conditions are set
+	// by the actuator, keep them
+	if existingStatus, err := kubevirtproviderv1alpha1.ProviderStatusFromRawExtension(s.machine.Status.ProviderStatus); err == nil {
+		status.NodeReadyCondition = existingStatus.NodeReadyCondition
+		status.KubeletVersion = existingStatus.KubeletVersion
+		status.ProviderConditions = existingStatus.ProviderConditions
+		status.ProvisioningTimeline = existingStatus.ProvisioningTimeline
+		// The UID of the VirtualMachine the Machine was first synced with is kept, a recreated VirtualMachine is
+		// refused by the verification of the operations instead
+		if existingStatus.VirtualMachineUID != "" {
+			status.VirtualMachineUID = existingStatus.VirtualMachineUID
+		}
+	}
+	status.ProvisioningTimeline = syncProvisioningTimeline(status.ProvisioningTimeline, vm, vmi)
+	providerStatus, err := kubevirtproviderv1alpha1.RawExtensionFromProviderStatus(status)
 	if err != nil {
 		return machinecontroller.InvalidMachineConfiguration("failed to get machine provider status: %v", err.Error())
 	}
@@ -403,10 +945,88 @@ func (s *machineScope) syncProviderStatus(vm kubevirtapiv1.VirtualMachine) error
 	return nil
 }
 
+// summarizeVirtualMachine returns the summary of the VirtualMachine and its VirtualMachineInstance, which may be nil
+func summarizeVirtualMachine(vm kubevirtapiv1.VirtualMachine, vmi *kubevirtapiv1.VirtualMachineInstance) *kubevirtproviderv1alpha1.VirtualMachineSummary {
+	summary := &kubevirtproviderv1alpha1.VirtualMachineSummary{
+		Created:       vm.Status.Created,
+		Ready:         vm.Status.Ready,
+		PrintedStatus: printedStatus(vm, vmi),
+	}
+	if vmi != nil {
+		summary.Phase = string(vmi.Status.Phase)
+		summary.NodeName = vmi.Status.NodeName
+		summary.Migration = summarizeMigration(vmi.Status.MigrationState)
+	}
+	for _, condition := range vm.Status.Conditions {
+		summary.Conditions = append(summary.Conditions, kubevirtproviderv1alpha1.VirtualMachineSummaryCondition{
+			Type:               string(condition.Type),
+			Status:             condition.Status,
+			LastTransitionTime: condition.LastTransitionTime,
+			Reason:             condition.Reason,
+			Message:            condition.Message,
+		})
+	}
+	return summary
+}
+
+// summarizeMigration returns the summary of the last live migration of the VirtualMachineInstance, or nil when it
+// never migrated
+func summarizeMigration(state *kubevirtapiv1.VirtualMachineInstanceMigrationState) *kubevirtproviderv1alpha1.VirtualMachineMigration {
+	if state == nil {
+		return nil
+	}
+	return &kubevirtproviderv1alpha1.VirtualMachineMigration{
+		UID:        string(state.MigrationUID),
+		SourceNode: state.SourceNode,
+		TargetNode: state.TargetNode,
+		StartTime:  state.StartTimestamp,
+		EndTime:    state.EndTimestamp,
+		Completed:  state.Completed,
+		Failed:     state.Failed,
+	}
+}
+
+// printedStatus returns the human readable status of the VirtualMachine, as printed by virtctl
+func printedStatus(vm kubevirtapiv1.VirtualMachine, vmi *kubevirtapiv1.VirtualMachineInstance) string {
+	if vm.DeletionTimestamp != nil {
+		return "Terminating"
+	}
+	if vmi == nil {
+		if vm.Spec.RunStrategy != nil && *vm.Spec.RunStrategy == kubevirtapiv1.RunStrategyHalted ||
+			vm.Spec.Running != nil && !*vm.Spec.Running {
+			return "Stopped"
+		}
+		return "Provisioning"
+	}
+	if vmi.DeletionTimestamp != nil {
+		return "Stopping"
+	}
+	switch vmi.Status.Phase {
+	case kubevirtapiv1.Pending, kubevirtapiv1.Scheduling, kubevirtapiv1.Scheduled:
+		return "Starting"
+	case kubevirtapiv1.Running:
+		return "Running"
+	case kubevirtapiv1.Succeeded:
+		return "Stopped"
+	case kubevirtapiv1.Failed:
+		return "Failed"
+	default:
+		return "Unknown"
+	}
+}
+
 func (s *machineScope) syncNetworkAddresses(vmi kubevirtapiv1.VirtualMachineInstance) {
+	// The hostname is the name of the Node, which is used to link the Node back to this Machine
+	hostname := vmi.Name
+	if s.machineProviderSpec.HostnameOverride != "" || s.machineProviderSpec.NameTemplate != "" {
+		if renderedHostname, err := s.GetHostname(); err == nil {
+			hostname = renderedHostname
+		}
+	}
+
 	// update nodeAddresses
-	networkAddresses := []corev1.NodeAddress{{Address: vmi.Name, Type: corev1.NodeInternalDNS}}
-	if ips, err := net.LookupIP(vmi.Name); err == nil {
+	networkAddresses := []corev1.NodeAddress{{Address: hostname, Type: corev1.NodeInternalDNS}}
+	if ips, err := net.LookupIP(hostname); err == nil {
 		for _, ip := range ips {
 			if ip.To4() != nil {
 				networkAddresses = append(networkAddresses, corev1.NodeAddress{Type: corev1.NodeInternalIP, Address: ip.String()})
@@ -1,3 +1,8 @@
+// machinescope package renders the infra-cluster objects (VirtualMachine, ignition secret) of a Machine
+// and syncs the Machine back from them. It only depends on the Machine, the infra-cluster namespace and
+// the infraID, so it can be reused outside of the actuator, which reads them from the cloud-provider-config.
+// The changes of the Machine are persisted by the MachineScope, against the Machine as it was read, with the
+// MachinePatcher of its creator.
 package machinescope
 
 import (
@@ -6,15 +11,22 @@ import (
 	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
 )
 
+// MachineScopeCreator creates the MachineScope of a Machine
 type MachineScopeCreator interface {
 	// CreateMachineScope creates MachineScope struct
 	CreateMachineScope(machine *machinev1.Machine, infraNamespace string, infraID string) (MachineScope, error)
 }
 
-type machineScopeCreator struct{}
+type machineScopeCreator struct {
+	metadataFilter MetadataFilter
+	machinePatcher MachinePatcher
+}
 
-func New() MachineScopeCreator {
-	return machineScopeCreator{}
+// New creates a MachineScopeCreator, whose MachineScopes copy the labels and annotations of their Machine selected by
+// the metadata filter onto its VirtualMachine, and persist their Machine with the machine patcher, which may be nil
+// when the Machines are only read
+func New(metadataFilter MetadataFilter, machinePatcher MachinePatcher) MachineScopeCreator {
+	return machineScopeCreator{metadataFilter: metadataFilter, machinePatcher: machinePatcher}
 }
 
 func (creator machineScopeCreator) CreateMachineScope(machine *machinev1.Machine, infraNamespace string, infraID string) (MachineScope, error) {
@@ -37,5 +49,8 @@ func (creator machineScopeCreator) CreateMachineScope(machine *machinev1.Machine
 		machineProviderSpec: providerSpec,
 		infraNamespace:      infraNamespace,
 		infraID:             infraID,
+		metadataFilter:      creator.metadataFilter,
+		machinePatcher:      creator.machinePatcher,
+		originMachineCopy:   machine.DeepCopy(),
 	}, nil
 }
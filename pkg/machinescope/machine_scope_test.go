@@ -1,22 +1,35 @@
 package machinescope
 
 import (
+	"encoding/json"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
 	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
 	"github.com/openshift/cluster-api-provider-kubevirt/pkg/testutils"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/utils"
 	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
 	"gotest.tools/assert"
 	corev1 "k8s.io/api/core/v1"
 	apiresource "k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/clock"
 	kubevirtapiv1 "kubevirt.io/client-go/api/v1"
 )
 
 func TestUpdateAllowed(t *testing.T) {
-	requeueAfterSeconds := 20
+	now := time.Date(2021, time.June, 1, 12, 0, 0, 0, time.UTC)
+	policy := UpdatePolicy{Window: 20 * time.Second, Clock: clock.NewFakePassiveClock(now)}
+	lastUpdated := func(ago time.Duration) func(machine *machinev1.Machine) error {
+		return func(machine *machinev1.Machine) error {
+			machine.Status.LastUpdated = &metav1.Time{Time: now.Add(-ago)}
+			return nil
+		}
+	}
 
 	cases := []struct {
 		name           string
@@ -28,18 +41,24 @@ func TestUpdateAllowed(t *testing.T) {
 			expectedResult: true,
 		},
 		{
-			name:           "allowed LastUpdated not empty",
+			name:           "allowed LastUpdated within the window",
 			expectedResult: true,
-			modifyMachine: func(machine *machinev1.Machine) error {
-				now := time.Now()
-				duration := time.Duration(-1*(requeueAfterSeconds-1)) * time.Second
-				lastUpdated := now.Add(duration)
-
-				machine.Status.LastUpdated = &metav1.Time{
-					Time: lastUpdated,
-				}
-				return nil
-			},
+			modifyMachine:  lastUpdated(19 * time.Second),
+		},
+		{
+			name:           "allowed LastUpdated exactly at the end of the window",
+			expectedResult: true,
+			modifyMachine:  lastUpdated(20 * time.Second),
+		},
+		{
+			name:           "allowed LastUpdated in the future",
+			expectedResult: true,
+			modifyMachine:  lastUpdated(-5 * time.Second),
+		},
+		{
+			name:           "not allowed LastUpdated past the window",
+			expectedResult: false,
+			modifyMachine:  lastUpdated(20*time.Second + time.Nanosecond),
 		},
 		{
 			name:           "not allowed ProviderID nil",
@@ -58,25 +77,11 @@ func TestUpdateAllowed(t *testing.T) {
 				return nil
 			},
 		},
-		{
-			name:           "not allowed time passed since LastUpdated too short",
-			expectedResult: false,
-			modifyMachine: func(machine *machinev1.Machine) error {
-				now := time.Now()
-				duration := time.Duration(-1*requeueAfterSeconds) * time.Second
-				lastUpdated := now.Add(duration)
-
-				machine.Status.LastUpdated = &metav1.Time{
-					Time: lastUpdated,
-				}
-				return nil
-			},
-		},
 	}
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
 			machineScope, _ := initializeMachineScope(t, tc.modifyMachine)
-			result := machineScope.UpdateAllowed(time.Duration(requeueAfterSeconds))
+			result := machineScope.UpdateAllowed(policy)
 			assert.Equal(t, tc.expectedResult, result)
 		})
 	}
@@ -92,7 +97,7 @@ func initializeMachineScope(t *testing.T, modifyMachine func(machine *machinev1.
 			t.Fatalf("Error durring modify machine: %v", err)
 		}
 	}
-	machineScope, err := New().CreateMachineScope(machine, testutils.InfraNamespace, testutils.InfraID)
+	machineScope, err := New(MetadataFilter{}, nil).CreateMachineScope(machine, testutils.InfraNamespace, testutils.InfraID)
 	if err != nil {
 		t.Fatalf("Error durring machineScope creation: %v", err)
 	}
@@ -102,22 +107,58 @@ func initializeMachineScope(t *testing.T, modifyMachine func(machine *machinev1.
 func TestCreateIgnitionSecretFromMachine(t *testing.T) {
 	machineScope, _ := initializeMachineScope(t, nil)
 	expectedResult := testutils.StubIgnitionSecret()
-	result := machineScope.CreateIgnitionSecretFromMachine([]byte(fmt.Sprintf(testutils.FullUserDataFmt, testutils.MachineName)))
+	result, err := machineScope.CreateIgnitionSecretFromMachine([]byte(fmt.Sprintf(testutils.FullUserDataFmt, testutils.MachineName)))
+	assert.NilError(t, err)
 	assert.DeepEqual(t, expectedResult, result)
 }
 
+func TestCreateVersionedIgnitionSecretFromMachine(t *testing.T) {
+	machineScope, _ := initializeMachineScope(t, func(machine *machinev1.Machine) error {
+		modifyProviderSpec := testutils.ProviderSpec
+		modifyProviderSpec.VersionedIgnitionSecret = true
+		val, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&modifyProviderSpec)
+		machine.Spec.ProviderSpec = machinev1.ProviderSpec{Value: val}
+		return err
+	})
+	first, err := machineScope.CreateIgnitionSecretFromMachine([]byte(`{"ignition":{"version":"3.2.0"}}`))
+	assert.NilError(t, err)
+	assert.Equal(t, first.Name, "test-machine-name-ignition-22a8389a38")
+	assert.Equal(t, first.Labels[IgnitionSecretOfLabel], testutils.MachineName)
+
+	// A new ignition is a new version of the secret
+	second, err := machineScope.CreateIgnitionSecretFromMachine([]byte(`{"ignition":{"version":"3.1.0"}}`))
+	assert.NilError(t, err)
+	assert.Assert(t, second.Name != first.Name)
+}
+
+func TestCreateIgnitionDataVolumeFromMachine(t *testing.T) {
+	machineScope, _ := initializeMachineScope(t, nil)
+	result, err := machineScope.CreateIgnitionDataVolumeFromMachine(3 * 1024 * 1024 / 2)
+	assert.NilError(t, err)
+	assert.Equal(t, result.Name, "test-machine-name-ignition")
+	assert.Equal(t, result.Namespace, testutils.InfraNamespace)
+	assert.DeepEqual(t, result.Labels, utils.BuildLabels(testutils.InfraID))
+	assert.Assert(t, result.Spec.Source.Upload != nil)
+	// The image is stored on the filesystem of the PVC, which is rounded up to MiB
+	storage := result.Spec.PVC.Resources.Requests[corev1.ResourceStorage]
+	assert.Equal(t, storage.String(), "66Mi")
+	assert.Equal(t, *result.Spec.PVC.StorageClassName, testutils.ProviderSpec.StorageClassName)
+}
+
 func TestSyncMachine(t *testing.T) {
 	cases := []struct {
 		name                  string
 		expectedErr           string
 		modifyExpectedMachine func(machine *machinev1.Machine)
 		modifyVM              func(vm *kubevirtapiv1.VirtualMachine)
+		modifyVMI             func(vmi *kubevirtapiv1.VirtualMachineInstance)
 		providerIDExists      bool
+		kubeletVersion        string
 	}{
 		{
 			name: "success status created and ready",
 			modifyExpectedMachine: func(machine *machinev1.Machine) {
-				machine.Annotations["machine.openshift.io/instance-state"] = "vmWasCreatedAndReady"
+				machine.Annotations[VMStateAnnotation] = "vmWasCreatedAndReady"
 			},
 			modifyVM: func(vm *kubevirtapiv1.VirtualMachine) {
 				vm.Status.Created = true
@@ -127,7 +168,7 @@ func TestSyncMachine(t *testing.T) {
 		{
 			name: "success status created and not ready",
 			modifyExpectedMachine: func(machine *machinev1.Machine) {
-				machine.Annotations["machine.openshift.io/instance-state"] = "vmWasCreatedButNotReady"
+				machine.Annotations[VMStateAnnotation] = "vmWasCreatedButNotReady"
 			},
 			modifyVM: func(vm *kubevirtapiv1.VirtualMachine) {
 				vm.Status.Created = true
@@ -137,7 +178,7 @@ func TestSyncMachine(t *testing.T) {
 		{
 			name: "success status not Created",
 			modifyExpectedMachine: func(machine *machinev1.Machine) {
-				machine.Annotations["machine.openshift.io/instance-state"] = "vmNotCreated"
+				machine.Annotations[VMStateAnnotation] = "vmNotCreated"
 			},
 			modifyVM: func(vm *kubevirtapiv1.VirtualMachine) {
 				vm.Status.Created = false
@@ -147,7 +188,7 @@ func TestSyncMachine(t *testing.T) {
 		{
 			name: "success status created and ready",
 			modifyExpectedMachine: func(machine *machinev1.Machine) {
-				machine.Annotations["machine.openshift.io/instance-state"] = "vmWasCreatedAndReady"
+				machine.Annotations[VMStateAnnotation] = "vmWasCreatedAndReady"
 				delete(machine.Labels, "machine.openshift.io/instance-type")
 			},
 			modifyVM: func(vm *kubevirtapiv1.VirtualMachine) {
@@ -156,10 +197,24 @@ func TestSyncMachine(t *testing.T) {
 				vm.Spec.Template = nil
 			},
 		},
+		{
+			name: "success mac address recorded",
+			modifyExpectedMachine: func(machine *machinev1.Machine) {
+				machine.Annotations[VMStateAnnotation] = "vmWasCreatedAndReady"
+				machine.Annotations["VmMacAddress"] = "02:00:00:00:00:01"
+			},
+			modifyVM: func(vm *kubevirtapiv1.VirtualMachine) {
+				vm.Status.Created = true
+				vm.Status.Ready = true
+				vm.Spec.Template.Spec.Domain.Devices.Interfaces = []kubevirtapiv1.Interface{
+					{Name: "main", MacAddress: "02:00:00:00:00:01"},
+				}
+			},
+		},
 		{
 			name: "success providerID exists",
 			modifyExpectedMachine: func(machine *machinev1.Machine) {
-				machine.Annotations["machine.openshift.io/instance-state"] = "vmWasCreatedAndReady"
+				machine.Annotations[VMStateAnnotation] = "vmWasCreatedAndReady"
 			},
 			modifyVM: func(vm *kubevirtapiv1.VirtualMachine) {
 				vm.Status.Created = true
@@ -167,6 +222,33 @@ func TestSyncMachine(t *testing.T) {
 			},
 			providerIDExists: true,
 		},
+		{
+			name: "success placement of the scheduled vmi",
+			modifyExpectedMachine: func(machine *machinev1.Machine) {
+				machine.Annotations[VMStateAnnotation] = "vmWasCreatedAndReady"
+				machine.Annotations[VMPhaseAnnotation] = "Scheduled"
+				machine.Annotations[InfraHostAnnotation] = "infra-node-1"
+			},
+			modifyVM: func(vm *kubevirtapiv1.VirtualMachine) {
+				vm.Status.Created = true
+				vm.Status.Ready = true
+			},
+			modifyVMI: func(vmi *kubevirtapiv1.VirtualMachineInstance) {
+				vmi.Status.Phase = kubevirtapiv1.Scheduled
+				vmi.Status.NodeName = "infra-node-1"
+			},
+		},
+		{
+			name: "success keeps the synced node status",
+			modifyExpectedMachine: func(machine *machinev1.Machine) {
+				machine.Annotations[VMStateAnnotation] = "vmWasCreatedAndReady"
+			},
+			modifyVM: func(vm *kubevirtapiv1.VirtualMachine) {
+				vm.Status.Created = true
+				vm.Status.Ready = true
+			},
+			kubeletVersion: "v1.20.0",
+		},
 	}
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -186,9 +268,27 @@ func TestSyncMachine(t *testing.T) {
 			}
 
 			vmi := testutils.StubVirtualMachineInstance()
+			if tc.modifyVMI != nil {
+				tc.modifyVMI(vmi)
+			}
 
 			expectedResultMachine := stubExpectedResultMachine(t, vm, vmi, providerID, machineType, tc.modifyExpectedMachine)
 
+			if tc.kubeletVersion != "" {
+				var err error
+				machine.Status.ProviderStatus, err = kubevirtproviderv1alpha1.RawExtensionFromProviderStatus(&kubevirtproviderv1alpha1.KubevirtMachineProviderStatus{
+					KubeletVersion: tc.kubeletVersion,
+				})
+				assert.NilError(t, err)
+				expectedResultMachine.Status.ProviderStatus, err = kubevirtproviderv1alpha1.RawExtensionFromProviderStatus(&kubevirtproviderv1alpha1.KubevirtMachineProviderStatus{
+					VirtualMachineStatus: vm.Status,
+					VirtualMachine:       summarizeVirtualMachine(*vm, vmi),
+					KubeletVersion:       tc.kubeletVersion,
+					VirtualMachineUID:    string(vm.UID),
+				})
+				assert.NilError(t, err)
+			}
+
 			err := machineScope.SyncMachine(*vm, vmi, providerID)
 			if tc.expectedErr != "" {
 				assert.Error(t, err, tc.expectedErr)
@@ -200,10 +300,117 @@ func TestSyncMachine(t *testing.T) {
 	}
 }
 
+func TestInstanceStateAnnotation(t *testing.T) {
+	halted := kubevirtapiv1.RunStrategyHalted
+	cases := []struct {
+		name                  string
+		instanceStateValues   kubevirtproviderv1alpha1.InstanceStateValues
+		modifyVM              func(vm *kubevirtapiv1.VirtualMachine)
+		vmiPhase              kubevirtapiv1.VirtualMachineInstancePhase
+		noVMI                 bool
+		expectedInstanceState string
+		expectedVMState       string
+		expectedErr           string
+	}{
+		{
+			name:                  "provisioning VirtualMachine is pending",
+			noVMI:                 true,
+			expectedInstanceState: "pending",
+			expectedVMState:       "vmNotCreated",
+		},
+		{
+			name: "running VirtualMachine is running",
+			modifyVM: func(vm *kubevirtapiv1.VirtualMachine) {
+				vm.Status.Created = true
+				vm.Status.Ready = true
+			},
+			vmiPhase:              kubevirtapiv1.Running,
+			expectedInstanceState: "running",
+			expectedVMState:       "vmWasCreatedAndReady",
+		},
+		{
+			name: "halted VirtualMachine is stopped",
+			modifyVM: func(vm *kubevirtapiv1.VirtualMachine) {
+				vm.Spec.RunStrategy = &halted
+			},
+			noVMI:                 true,
+			expectedInstanceState: "stopped",
+			expectedVMState:       "vmNotCreated",
+		},
+		{
+			name: "failed VirtualMachine is stopped",
+			modifyVM: func(vm *kubevirtapiv1.VirtualMachine) {
+				vm.Status.Created = true
+			},
+			vmiPhase:              kubevirtapiv1.Failed,
+			expectedInstanceState: "stopped",
+			expectedVMState:       "vmWasCreatedButNotReady",
+		},
+		{
+			name: "deleted VirtualMachine is terminated",
+			modifyVM: func(vm *kubevirtapiv1.VirtualMachine) {
+				vm.DeletionTimestamp = &metav1.Time{}
+			},
+			vmiPhase:              kubevirtapiv1.Running,
+			expectedInstanceState: "terminated",
+			expectedVMState:       "vmNotCreated",
+		},
+		{
+			name:                "KubeVirt values",
+			instanceStateValues: kubevirtproviderv1alpha1.InstanceStateValuesKubeVirt,
+			modifyVM: func(vm *kubevirtapiv1.VirtualMachine) {
+				vm.Status.Created = true
+				vm.Status.Ready = true
+			},
+			vmiPhase:              kubevirtapiv1.Running,
+			expectedInstanceState: "vmWasCreatedAndReady",
+			expectedVMState:       "vmWasCreatedAndReady",
+		},
+		{
+			name:                "invalid values",
+			instanceStateValues: "Legacy",
+			expectedErr:         `test-machine-name: InstanceStateValues "Legacy" is not one of Standard, KubeVirt`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			machineScope, machine := initializeMachineScope(t, func(machine *machinev1.Machine) error {
+				providerSpec, err := kubevirtproviderv1alpha1.ProviderSpecFromRawExtension(machine.Spec.ProviderSpec.Value)
+				if err != nil {
+					return err
+				}
+				providerSpec.InstanceStateValues = tc.instanceStateValues
+				machine.Spec.ProviderSpec.Value, err = kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(providerSpec)
+				return err
+			})
+			vm := testutils.StubVirtualMachine(nil, nil, nil)
+			if tc.modifyVM != nil {
+				tc.modifyVM(vm)
+			}
+			var vmi *kubevirtapiv1.VirtualMachineInstance
+			if !tc.noVMI {
+				vmi = testutils.StubVirtualMachineInstance()
+				vmi.Status.Phase = tc.vmiPhase
+			}
+
+			err := machineScope.SyncMachine(*vm, vmi, "kubevirt://test")
+			if tc.expectedErr != "" {
+				assert.Error(t, err, tc.expectedErr)
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, machine.Annotations["machine.openshift.io/instance-state"], tc.expectedInstanceState)
+			assert.Equal(t, machine.Annotations[VMStateAnnotation], tc.expectedVMState)
+		})
+	}
+}
+
 func stubExpectedResultMachine(t *testing.T, vm *kubevirtapiv1.VirtualMachine, vmi *kubevirtapiv1.VirtualMachineInstance,
 	providerID string, machineType string, modifyExpectedMachine func(machine *machinev1.Machine)) *machinev1.Machine {
 	providerStatus, err := kubevirtproviderv1alpha1.RawExtensionFromProviderStatus(&kubevirtproviderv1alpha1.KubevirtMachineProviderStatus{
 		VirtualMachineStatus: vm.Status,
+		VirtualMachine:       summarizeVirtualMachine(*vm, vmi),
+		VirtualMachineUID:    string(vm.UID),
 	})
 	if err != nil {
 		t.Fatalf("Error durring providerStatus creation: %v", err)
@@ -212,7 +419,11 @@ func stubExpectedResultMachine(t *testing.T, vm *kubevirtapiv1.VirtualMachine, v
 	if err != nil {
 		t.Fatalf("Error durring stubMachine creation: %v", err)
 	}
-	expectedResultMachine.Annotations = map[string]string{"VmId": string(vm.UID)}
+	expectedResultMachine.Annotations = map[string]string{
+		"VmId":                                string(vm.UID),
+		InfraNamespaceAnnotation:              vm.Namespace,
+		"machine.openshift.io/instance-state": "pending",
+	}
 	expectedResultMachine.Spec.ProviderID = &providerID
 	expectedResultMachine.Labels["machine.openshift.io/instance-type"] = machineType
 	expectedResultMachine.Status.ProviderStatus = providerStatus
@@ -291,134 +502,1641 @@ func TestCreateVirtualMachineFromMachine(t *testing.T) {
 			},
 		},
 		{
-			name: "failure source pvc name empty",
+			name: "success dns config",
 			modifyMachine: func(machine *machinev1.Machine) error {
 				modifyProviderSpec := testutils.ProviderSpec
-				modifyProviderSpec.SourcePvcName = ""
+				modifyProviderSpec.DNSPolicy = corev1.DNSNone
+				modifyProviderSpec.DNSConfig = &corev1.PodDNSConfig{
+					Nameservers: []string{"192.168.1.1"},
+					Searches:    []string{"tenant.example.com"},
+				}
 				val, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&modifyProviderSpec)
 				machine.Spec.ProviderSpec = machinev1.ProviderSpec{Value: val}
 
 				return err
 			},
-			expectedErr: "test-machine-name: missing value for SourcePvcName",
+			modifyExpectedVM: func(vm *kubevirtapiv1.VirtualMachine) {
+				vm.Spec.Template.Spec.DNSPolicy = corev1.DNSNone
+				vm.Spec.Template.Spec.DNSConfig = &corev1.PodDNSConfig{
+					Nameservers: []string{"192.168.1.1"},
+					Searches:    []string{"tenant.example.com"},
+				}
+			},
 		},
 		{
-			name: "failure ignition secret name empty",
+			name: "failure dns policy none without nameservers",
 			modifyMachine: func(machine *machinev1.Machine) error {
 				modifyProviderSpec := testutils.ProviderSpec
-				modifyProviderSpec.IgnitionSecretName = ""
+				modifyProviderSpec.DNSPolicy = corev1.DNSNone
 				val, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&modifyProviderSpec)
 				machine.Spec.ProviderSpec = machinev1.ProviderSpec{Value: val}
 
 				return err
 			},
-			expectedErr: "test-machine-name: missing value for IgnitionSecretName",
+			expectedErr: "test-machine-name: DNSPolicy None requires DNSConfig with at least one nameserver",
 		},
 		{
-			name: "failure network name empty",
+			name: "failure dns policy not valid",
 			modifyMachine: func(machine *machinev1.Machine) error {
 				modifyProviderSpec := testutils.ProviderSpec
-				modifyProviderSpec.NetworkName = ""
+				modifyProviderSpec.DNSPolicy = "NotValid"
 				val, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&modifyProviderSpec)
 				machine.Spec.ProviderSpec = machinev1.ProviderSpec{Value: val}
 
 				return err
 			},
-			expectedErr: "test-machine-name: missing value for NetworkName",
+			expectedErr: "test-machine-name: Value of DNSPolicy, can be only one of: ClusterFirst, ClusterFirstWithHostNet, Default, None",
 		},
 		{
-			name: "failure access mode not valid",
+			name: "success interface model",
 			modifyMachine: func(machine *machinev1.Machine) error {
 				modifyProviderSpec := testutils.ProviderSpec
-				modifyProviderSpec.PersistentVolumeAccessMode = "NotValid"
+				modifyProviderSpec.InterfaceModel = "e1000"
 				val, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&modifyProviderSpec)
 				machine.Spec.ProviderSpec = machinev1.ProviderSpec{Value: val}
 
 				return err
 			},
-			expectedErr: "test-machine-name: Value of PersistentVolumeAccessMode, can be only one of: ReadWriteMany, ReadOnlyMany, ReadWriteOnce",
+			modifyExpectedVM: func(vm *kubevirtapiv1.VirtualMachine) {
+				vm.Spec.Template.Spec.Domain.Devices.Interfaces[0].Model = "e1000"
+			},
 		},
-	}
-	for _, tc := range cases {
-		t.Run(tc.name, func(t *testing.T) {
-			machineScope, _ := initializeMachineScope(t, tc.modifyMachine)
-			expectedVM := testutils.StubVirtualMachine(nil, nil, nil)
-			if tc.modifyExpectedVM != nil {
-				tc.modifyExpectedVM(expectedVM)
-			}
+		{
+			name: "success descheduler evictable",
+			modifyMachine: func(machine *machinev1.Machine) error {
+				modifyProviderSpec := testutils.ProviderSpec
+				modifyProviderSpec.DeschedulerEviction = kubevirtproviderv1alpha1.DeschedulerEvictionEvictable
+				val, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&modifyProviderSpec)
+				machine.Spec.ProviderSpec = machinev1.ProviderSpec{Value: val}
 
-			result, err := machineScope.CreateVirtualMachineFromMachine()
-			if tc.expectedErr != "" {
-				assert.Error(t, err, tc.expectedErr)
-			} else {
-				assert.NilError(t, err)
-				assert.DeepEqual(t, result, expectedVM)
-			}
-		})
-	}
-}
+				return err
+			},
+			modifyExpectedVM: func(vm *kubevirtapiv1.VirtualMachine) {
+				liveMigrate := kubevirtapiv1.EvictionStrategyLiveMigrate
+				vm.Spec.Template.ObjectMeta.Annotations = map[string]string{"descheduler.alpha.kubernetes.io/evict": "true"}
+				vm.Spec.Template.Spec.EvictionStrategy = &liveMigrate
+			},
+		},
+		{
+			name: "success descheduler not evictable",
+			modifyMachine: func(machine *machinev1.Machine) error {
+				modifyProviderSpec := testutils.ProviderSpec
+				modifyProviderSpec.DeschedulerEviction = kubevirtproviderv1alpha1.DeschedulerEvictionNotEvictable
+				val, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&modifyProviderSpec)
+				machine.Spec.ProviderSpec = machinev1.ProviderSpec{Value: val}
 
-func TestGetMachine(t *testing.T) {
-	machine, err := testutils.StubMachine()
-	if err != nil {
-		t.Fatalf("Error durring stubMachine creation: %v", err)
-	}
-	machineScope, err := New().CreateMachineScope(machine, testutils.InfraNamespace, testutils.InfraID)
-	if err != nil {
-		t.Fatalf("Error durring machineScope creation: %v", err)
-	}
-	result := machineScope.GetMachine()
-	assert.Equal(t, machine, result)
-}
+				return err
+			},
+			modifyExpectedVM: func(vm *kubevirtapiv1.VirtualMachine) {
+				vm.Spec.Template.ObjectMeta.Annotations = map[string]string{"descheduler.alpha.kubernetes.io/prefer-no-eviction": "true"}
+			},
+		},
+		{
+			name: "failure descheduler eviction not valid",
+			modifyMachine: func(machine *machinev1.Machine) error {
+				modifyProviderSpec := testutils.ProviderSpec
+				modifyProviderSpec.DeschedulerEviction = "Sometimes"
+				val, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&modifyProviderSpec)
+				machine.Spec.ProviderSpec = machinev1.ProviderSpec{Value: val}
 
-func TestGetMachineName(t *testing.T) {
-	machine, err := testutils.StubMachine()
-	if err != nil {
-		t.Fatalf("Error durring stubMachine creation: %v", err)
-	}
-	machineScope, err := New().CreateMachineScope(machine, testutils.InfraNamespace, testutils.InfraID)
-	if err != nil {
-		t.Fatalf("Error durring machineScope creation: %v", err)
-	}
-	result := machineScope.GetMachineName()
-	assert.Equal(t, machine.GetName(), result)
-}
+				return err
+			},
+			expectedErr: "test-machine-name: Value of DeschedulerEviction, can be only one of: Evictable, NotEvictable",
+		},
+		{
+			name: "failure interface model not valid",
+			modifyMachine: func(machine *machinev1.Machine) error {
+				modifyProviderSpec := testutils.ProviderSpec
+				modifyProviderSpec.InterfaceModel = "NotValid"
+				val, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&modifyProviderSpec)
+				machine.Spec.ProviderSpec = machinev1.ProviderSpec{Value: val}
 
-func TestGetMachineNamespace(t *testing.T) {
-	machine, err := testutils.StubMachine()
-	if err != nil {
-		t.Fatalf("Error durring stubMachine creation: %v", err)
-	}
-	machineScope, err := New().CreateMachineScope(machine, testutils.InfraNamespace, testutils.InfraID)
-	if err != nil {
-		t.Fatalf("Error durring machineScope creation: %v", err)
-	}
-	result := machineScope.GetMachineNamespace()
-	assert.Equal(t, machine.GetNamespace(), result)
-}
+				return err
+			},
+			expectedErr: "test-machine-name: Value of InterfaceModel, can be only one of: virtio, e1000, e1000e, ne2k_pci, pcnet, rtl8139",
+		},
+		{
+			name: "success mac address",
+			modifyMachine: func(machine *machinev1.Machine) error {
+				modifyProviderSpec := testutils.ProviderSpec
+				modifyProviderSpec.MacAddress = "02:00:00:00:00:01"
+				val, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&modifyProviderSpec)
+				machine.Spec.ProviderSpec = machinev1.ProviderSpec{Value: val}
 
-func TestGetInfraNamespace(t *testing.T) {
-	machine, err := testutils.StubMachine()
-	if err != nil {
-		t.Fatalf("Error durring stubMachine creation: %v", err)
-	}
-	machineScope, err := New().CreateMachineScope(machine, testutils.InfraNamespace, testutils.InfraID)
-	if err != nil {
-		t.Fatalf("Error durring machineScope creation: %v", err)
-	}
-	result := machineScope.GetInfraNamespace()
-	assert.Equal(t, testutils.InfraNamespace, result)
-}
+				return err
+			},
+			modifyExpectedVM: func(vm *kubevirtapiv1.VirtualMachine) {
+				vm.Spec.Template.Spec.Domain.Devices.Interfaces[0].MacAddress = "02:00:00:00:00:01"
+			},
+		},
+		{
+			name: "success mac address recorded on the machine",
+			modifyMachine: func(machine *machinev1.Machine) error {
+				machine.Annotations = map[string]string{"VmMacAddress": "02:00:00:00:00:02"}
+				return nil
+			},
+			modifyExpectedVM: func(vm *kubevirtapiv1.VirtualMachine) {
+				vm.Spec.Template.Spec.Domain.Devices.Interfaces[0].MacAddress = "02:00:00:00:00:02"
+			},
+		},
+		{
+			name: "success uid of the machine",
+			modifyMachine: func(machine *machinev1.Machine) error {
+				machine.UID = "test-machine-uid"
+				return nil
+			},
+			modifyExpectedVM: func(vm *kubevirtapiv1.VirtualMachine) {
+				vm.Annotations = map[string]string{MachineUIDAnnotation: "test-machine-uid"}
+			},
+		},
+		{
+			name: "failure mac address on a machine of a machine set",
+			modifyMachine: func(machine *machinev1.Machine) error {
+				modifyProviderSpec := testutils.ProviderSpec
+				modifyProviderSpec.MacAddress = "02:00:00:00:00:01"
+				val, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&modifyProviderSpec)
+				machine.Spec.ProviderSpec = machinev1.ProviderSpec{Value: val}
+				controller := true
+				machine.OwnerReferences = []metav1.OwnerReference{{Kind: "MachineSet", Name: "test-machine-set", Controller: &controller}}
+
+				return err
+			},
+			expectedErr: "test-machine-name: MacAddress can't be set on the Machines of MachineSet test-machine-set",
+		},
+		{
+			name: "failure mac address not valid",
+			modifyMachine: func(machine *machinev1.Machine) error {
+				modifyProviderSpec := testutils.ProviderSpec
+				modifyProviderSpec.MacAddress = "NotValid"
+				val, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&modifyProviderSpec)
+				machine.Spec.ProviderSpec = machinev1.ProviderSpec{Value: val}
+
+				return err
+			},
+			expectedErr: "test-machine-name: Value of MacAddress is not valid: address NotValid: invalid MAC address",
+		},
+		{
+			name: "failure source pvc name empty",
+			modifyMachine: func(machine *machinev1.Machine) error {
+				modifyProviderSpec := testutils.ProviderSpec
+				modifyProviderSpec.SourcePvcName = ""
+				val, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&modifyProviderSpec)
+				machine.Spec.ProviderSpec = machinev1.ProviderSpec{Value: val}
+
+				return err
+			},
+			expectedErr: "test-machine-name: missing value for SourcePvcName or DataSourceName",
+		},
+		{
+			name: "success data source",
+			modifyMachine: func(machine *machinev1.Machine) error {
+				modifyProviderSpec := testutils.ProviderSpec
+				modifyProviderSpec.SourcePvcName = ""
+				modifyProviderSpec.DataSourceName = "rhcos"
+				val, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&modifyProviderSpec)
+				machine.Spec.ProviderSpec = machinev1.ProviderSpec{Value: val}
+
+				return err
+			},
+			modifyExpectedVM: func(vm *kubevirtapiv1.VirtualMachine) {
+				vm.Spec.DataVolumeTemplates[0].Spec.Source.PVC = nil
+			},
+		},
+		{
+			name: "failure source pvc name and data source",
+			modifyMachine: func(machine *machinev1.Machine) error {
+				modifyProviderSpec := testutils.ProviderSpec
+				modifyProviderSpec.DataSourceName = "rhcos"
+				val, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&modifyProviderSpec)
+				machine.Spec.ProviderSpec = machinev1.ProviderSpec{Value: val}
+
+				return err
+			},
+			expectedErr: "test-machine-name: SourcePvcName and DataSourceName are mutually exclusive",
+		},
+		{
+			name: "failure ignition secret name empty",
+			modifyMachine: func(machine *machinev1.Machine) error {
+				modifyProviderSpec := testutils.ProviderSpec
+				modifyProviderSpec.IgnitionSecretName = ""
+				val, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&modifyProviderSpec)
+				machine.Spec.ProviderSpec = machinev1.ProviderSpec{Value: val}
+
+				return err
+			},
+			expectedErr: "test-machine-name: missing value for IgnitionSecretName",
+		},
+		{
+			name: "success ignition secret pre-provisioned in the infra cluster",
+			modifyMachine: func(machine *machinev1.Machine) error {
+				modifyProviderSpec := testutils.ProviderSpec
+				modifyProviderSpec.IgnitionSecretName = ""
+				modifyProviderSpec.InfraIgnitionSecretName = "infra-ignition"
+				val, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&modifyProviderSpec)
+				machine.Spec.ProviderSpec = machinev1.ProviderSpec{Value: val}
+
+				return err
+			},
+			modifyExpectedVM: func(vm *kubevirtapiv1.VirtualMachine) {
+				vm.Spec.Template.Spec.Volumes[1].CloudInitConfigDrive.UserDataSecretRef.Name = "infra-ignition"
+			},
+		},
+		{
+			name: "success name template",
+			modifyMachine: func(machine *machinev1.Machine) error {
+				modifyProviderSpec := testutils.ProviderSpec
+				modifyProviderSpec.NameTemplate = "{{.InfraID}}-{{.MachineName}}"
+				val, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&modifyProviderSpec)
+				machine.Spec.ProviderSpec = machinev1.ProviderSpec{Value: val}
+
+				return err
+			},
+			modifyExpectedVM: func(vm *kubevirtapiv1.VirtualMachine) {
+				vmName := testutils.InfraID + "-" + testutils.MachineName
+				vm.Name = vmName
+				vm.Spec.DataVolumeTemplates[0].Name = vmName + "-bootvolume"
+				vm.Spec.Template.ObjectMeta.Labels["kubevirt.io/vm"] = vmName
+				vm.Spec.Template.ObjectMeta.Labels["name"] = vmName
+				vm.Spec.Template.Spec.Volumes[0].DataVolume.Name = vmName + "-bootvolume"
+				vm.Spec.Template.Spec.Volumes[1].CloudInitConfigDrive.UserDataSecretRef.Name = vmName + "-ignition"
+			},
+		},
+		{
+			name: "failure name template renders an invalid name",
+			modifyMachine: func(machine *machinev1.Machine) error {
+				modifyProviderSpec := testutils.ProviderSpec
+				modifyProviderSpec.NameTemplate = "{{.MachineName}}_vm"
+				val, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&modifyProviderSpec)
+				machine.Spec.ProviderSpec = machinev1.ProviderSpec{Value: val}
+
+				return err
+			},
+			expectedErr: "test-machine-name: NameTemplate rendered an invalid name \"test-machine-name_vm\": a lowercase RFC 1123 subdomain must consist of lower case alphanumeric characters, '-' or '.', and must start and end with an alphanumeric character (e.g. 'example.com', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?(\\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*')",
+		},
+		{
+			name: "failure network name empty",
+			modifyMachine: func(machine *machinev1.Machine) error {
+				modifyProviderSpec := testutils.ProviderSpec
+				modifyProviderSpec.NetworkName = ""
+				val, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&modifyProviderSpec)
+				machine.Spec.ProviderSpec = machinev1.ProviderSpec{Value: val}
+
+				return err
+			},
+			expectedErr: "test-machine-name: missing value for NetworkName",
+		},
+		{
+			name: "success network of the zone",
+			modifyMachine: func(machine *machinev1.Machine) error {
+				modifyProviderSpec := testutils.ProviderSpec
+				modifyProviderSpec.Zone = "zone-b"
+				modifyProviderSpec.ZoneNetworkNames = map[string]string{"zone-a": "network-a", "zone-b": "network-b"}
+				val, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&modifyProviderSpec)
+				machine.Spec.ProviderSpec = machinev1.ProviderSpec{Value: val}
+
+				return err
+			},
+			modifyExpectedVM: func(vm *kubevirtapiv1.VirtualMachine) {
+				vm.Spec.Template.Spec.NodeSelector = map[string]string{InfraZoneLabel: "zone-b"}
+				vm.Spec.Template.Spec.Networks[0].Multus.NetworkName = "network-b"
+			},
+		},
+		{
+			name: "success network of the zone assigned to the machine",
+			modifyMachine: func(machine *machinev1.Machine) error {
+				modifyProviderSpec := testutils.ProviderSpec
+				modifyProviderSpec.NetworkName = ""
+				modifyProviderSpec.ZoneNetworkNames = map[string]string{"zone-a": "network-a", "zone-b": "network-b"}
+				val, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&modifyProviderSpec)
+				machine.Spec.ProviderSpec = machinev1.ProviderSpec{Value: val}
+				machine.Labels[machinecontroller.MachineAZLabelName] = "zone-a"
+
+				return err
+			},
+			modifyExpectedVM: func(vm *kubevirtapiv1.VirtualMachine) {
+				vm.Labels[machinecontroller.MachineAZLabelName] = "zone-a"
+				vm.Spec.Template.Spec.NodeSelector = map[string]string{InfraZoneLabel: "zone-a"}
+				vm.Spec.Template.Spec.Networks[0].Multus.NetworkName = "network-a"
+			},
+		},
+		{
+			name: "success templates of the source pvc and the network",
+			modifyMachine: func(machine *machinev1.Machine) error {
+				modifyProviderSpec := testutils.ProviderSpec
+				modifyProviderSpec.Zone = "zone-b"
+				modifyProviderSpec.SourcePvcName = "rhcos-{{.Zone}}"
+				modifyProviderSpec.NetworkName = "{{.InfraNamespace}}/network-{{.Index}}"
+				val, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&modifyProviderSpec)
+				machine.Spec.ProviderSpec = machinev1.ProviderSpec{Value: val}
+
+				return err
+			},
+			modifyExpectedVM: func(vm *kubevirtapiv1.VirtualMachine) {
+				vm.Spec.DataVolumeTemplates[0].Spec.Source.PVC.Name = "rhcos-zone-b"
+				vm.Spec.Template.Spec.NodeSelector = map[string]string{InfraZoneLabel: "zone-b"}
+				vm.Spec.Template.Spec.Networks[0].Multus.NetworkName = testutils.InfraNamespace + "/network-name"
+			},
+		},
+		{
+			name: "failure source pvc template renders an invalid name",
+			modifyMachine: func(machine *machinev1.Machine) error {
+				modifyProviderSpec := testutils.ProviderSpec
+				modifyProviderSpec.SourcePvcName = "rhcos-{{.Zone}}"
+				val, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&modifyProviderSpec)
+				machine.Spec.ProviderSpec = machinev1.ProviderSpec{Value: val}
+
+				return err
+			},
+			expectedErr: "test-machine-name: SourcePvcName rendered an invalid name \"rhcos-\": a lowercase RFC 1123 subdomain must consist of lower case alphanumeric characters, '-' or '.', and must start and end with an alphanumeric character (e.g. 'example.com', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?(\\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*')",
+		},
+		{
+			name: "failure no network of the zone",
+			modifyMachine: func(machine *machinev1.Machine) error {
+				modifyProviderSpec := testutils.ProviderSpec
+				modifyProviderSpec.NetworkName = ""
+				modifyProviderSpec.Zone = "zone-c"
+				modifyProviderSpec.ZoneNetworkNames = map[string]string{"zone-a": "network-a"}
+				val, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&modifyProviderSpec)
+				machine.Spec.ProviderSpec = machinev1.ProviderSpec{Value: val}
+
+				return err
+			},
+			expectedErr: "test-machine-name: missing value for NetworkName, or in ZoneNetworkNames for zone \"zone-c\"",
+		},
+		{
+			name: "failure access mode not valid",
+			modifyMachine: func(machine *machinev1.Machine) error {
+				modifyProviderSpec := testutils.ProviderSpec
+				modifyProviderSpec.PersistentVolumeAccessMode = "NotValid"
+				val, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&modifyProviderSpec)
+				machine.Spec.ProviderSpec = machinev1.ProviderSpec{Value: val}
+
+				return err
+			},
+			expectedErr: "test-machine-name: Value of PersistentVolumeAccessMode, can be only one of: ReadWriteMany, ReadOnlyMany, ReadWriteOnce",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			machineScope, _ := initializeMachineScope(t, tc.modifyMachine)
+			expectedVM := testutils.StubVirtualMachine(nil, nil, nil)
+			if tc.modifyExpectedVM != nil {
+				tc.modifyExpectedVM(expectedVM)
+			}
+
+			result, err := machineScope.CreateVirtualMachineFromMachine()
+			if tc.expectedErr != "" {
+				assert.Error(t, err, tc.expectedErr)
+			} else {
+				assert.NilError(t, err)
+				assert.DeepEqual(t, result, expectedVM)
+			}
+		})
+	}
+}
+
+func TestBuildNetworkData(t *testing.T) {
+	scope, _ := initializeMachineScope(t, func(machine *machinev1.Machine) error {
+		modifyProviderSpec := testutils.ProviderSpec
+		modifyProviderSpec.MacAddress = "02:00:00:00:00:01"
+		modifyProviderSpec.Nameservers = []string{"10.0.0.53"}
+		val, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&modifyProviderSpec)
+		machine.Spec.ProviderSpec = machinev1.ProviderSpec{Value: val}
+		return err
+	})
+	machineScope := scope.(*machineScope)
+	assert.Equal(t, machineScope.buildNetworkData(), "")
+
+	machineScope.SetStaticAddresses([]StaticAddress{
+		{Address: "10.0.0.5", Prefix: 24, Gateway: "10.0.0.1"},
+		{Address: "fd00::5", Prefix: 64},
+	})
+	assert.Equal(t, machineScope.buildNetworkData(), `{"links":[{"id":"main","type":"phy","ethernet_mac_address":"02:00:00:00:00:01"}],`+
+		`"networks":[{"id":"network0","type":"ipv4","link":"main","ip_address":"10.0.0.5","netmask":"255.255.255.0",`+
+		`"routes":[{"network":"0.0.0.0","netmask":"0.0.0.0","gateway":"10.0.0.1"}]},`+
+		`{"id":"network1","type":"ipv6","link":"main","ip_address":"fd00::5","netmask":"ffff:ffff:ffff:ffff::"}],`+
+		`"services":[{"type":"dns","address":"10.0.0.53"}]}`)
+}
+
+func TestGetMachine(t *testing.T) {
+	machine, err := testutils.StubMachine()
+	if err != nil {
+		t.Fatalf("Error durring stubMachine creation: %v", err)
+	}
+	machineScope, err := New(MetadataFilter{}, nil).CreateMachineScope(machine, testutils.InfraNamespace, testutils.InfraID)
+	if err != nil {
+		t.Fatalf("Error durring machineScope creation: %v", err)
+	}
+	result := machineScope.GetMachine()
+	assert.Equal(t, machine, result)
+}
+
+func TestGetMachineName(t *testing.T) {
+	machine, err := testutils.StubMachine()
+	if err != nil {
+		t.Fatalf("Error durring stubMachine creation: %v", err)
+	}
+	machineScope, err := New(MetadataFilter{}, nil).CreateMachineScope(machine, testutils.InfraNamespace, testutils.InfraID)
+	if err != nil {
+		t.Fatalf("Error durring machineScope creation: %v", err)
+	}
+	result := machineScope.GetMachineName()
+	assert.Equal(t, machine.GetName(), result)
+}
+
+func TestGetMachineNamespace(t *testing.T) {
+	machine, err := testutils.StubMachine()
+	if err != nil {
+		t.Fatalf("Error durring stubMachine creation: %v", err)
+	}
+	machineScope, err := New(MetadataFilter{}, nil).CreateMachineScope(machine, testutils.InfraNamespace, testutils.InfraID)
+	if err != nil {
+		t.Fatalf("Error durring machineScope creation: %v", err)
+	}
+	result := machineScope.GetMachineNamespace()
+	assert.Equal(t, machine.GetNamespace(), result)
+}
+
+func TestGetInfraNamespace(t *testing.T) {
+	machine, err := testutils.StubMachine()
+	if err != nil {
+		t.Fatalf("Error durring stubMachine creation: %v", err)
+	}
+	machineScope, err := New(MetadataFilter{}, nil).CreateMachineScope(machine, testutils.InfraNamespace, testutils.InfraID)
+	if err != nil {
+		t.Fatalf("Error durring machineScope creation: %v", err)
+	}
+	result := machineScope.GetInfraNamespace()
+	assert.Equal(t, testutils.InfraNamespace, result)
+}
 
 func TestGetIgnitionSecretName(t *testing.T) {
 	machine, err := testutils.StubMachine()
 	if err != nil {
 		t.Fatalf("Error durring stubMachine creation: %v", err)
 	}
-	machineScope, err := New().CreateMachineScope(machine, testutils.InfraNamespace, testutils.InfraID)
+	machineScope, err := New(MetadataFilter{}, nil).CreateMachineScope(machine, testutils.InfraNamespace, testutils.InfraID)
 	if err != nil {
 		t.Fatalf("Error durring machineScope creation: %v", err)
 	}
 	result := machineScope.GetIgnitionSecretName()
 	assert.Equal(t, testutils.IgnitionSecretName, result)
+	assert.Assert(t, !machineScope.IgnitionSecretNameDefaulted())
+}
+
+func TestGetIgnitionSecretNameFallback(t *testing.T) {
+	cases := []struct {
+		name               string
+		annotations        map[string]string
+		labels             map[string]string
+		expectedSecretName string
+	}{
+		{
+			name:               "user-data annotation",
+			annotations:        map[string]string{userDataSecretAnnotation: "custom-user-data"},
+			labels:             map[string]string{machineRoleLabel: "worker"},
+			expectedSecretName: "custom-user-data",
+		},
+		{
+			name:               "role label",
+			labels:             map[string]string{machineRoleLabel: "worker"},
+			expectedSecretName: "worker-user-data",
+		},
+		{
+			name: "no fallback",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			machineScope, _ := initializeMachineScope(t, func(machine *machinev1.Machine) error {
+				machine.Annotations = tc.annotations
+				for key, value := range tc.labels {
+					machine.Labels[key] = value
+				}
+				modifyProviderSpec := testutils.ProviderSpec
+				modifyProviderSpec.IgnitionSecretName = ""
+				val, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&modifyProviderSpec)
+				machine.Spec.ProviderSpec = machinev1.ProviderSpec{Value: val}
+				return err
+			})
+			assert.Equal(t, machineScope.GetIgnitionSecretName(), tc.expectedSecretName)
+			assert.Equal(t, machineScope.IgnitionSecretNameDefaulted(), tc.expectedSecretName != "")
+		})
+	}
+}
+
+func TestIgnitionPropagatedViaSecret(t *testing.T) {
+	cases := []struct {
+		name           string
+		value          *bool
+		expectedResult bool
+	}{
+		{
+			name:           "default",
+			expectedResult: true,
+		},
+		{
+			name:           "via secret",
+			value:          func(src bool) *bool { return &src }(true),
+			expectedResult: true,
+		},
+		{
+			name:           "embedded in the virtual machine",
+			value:          func(src bool) *bool { return &src }(false),
+			expectedResult: false,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			machineScope, _ := initializeMachineScope(t, func(machine *machinev1.Machine) error {
+				modifyProviderSpec := testutils.ProviderSpec
+				modifyProviderSpec.PropagateIgnitionViaSecret = tc.value
+				val, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&modifyProviderSpec)
+				machine.Spec.ProviderSpec = machinev1.ProviderSpec{Value: val}
+				return err
+			})
+			assert.Equal(t, tc.expectedResult, machineScope.IgnitionPropagatedViaSecret())
+		})
+	}
+}
+
+func TestGetIgnitionMergeSource(t *testing.T) {
+	cases := []struct {
+		name        string
+		value       *kubevirtproviderv1alpha1.IgnitionMergeSource
+		expectedErr string
+	}{
+		{
+			name: "not set",
+		},
+		{
+			name:  "https source",
+			value: &kubevirtproviderv1alpha1.IgnitionMergeSource{URL: "https://api-int.tenant.example.com:22623/config/worker", CABundle: "ca"},
+		},
+		{
+			name:        "http source",
+			value:       &kubevirtproviderv1alpha1.IgnitionMergeSource{URL: "http://api-int.tenant.example.com:22623/config/worker"},
+			expectedErr: "test-machine-name: IgnitionMergeSource URL \"http://api-int.tenant.example.com:22623/config/worker\" is not an https URL",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			machineScope, _ := initializeMachineScope(t, func(machine *machinev1.Machine) error {
+				modifyProviderSpec := testutils.ProviderSpec
+				modifyProviderSpec.IgnitionMergeSource = tc.value
+				val, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&modifyProviderSpec)
+				machine.Spec.ProviderSpec = machinev1.ProviderSpec{Value: val}
+				return err
+			})
+			source, err := machineScope.GetIgnitionMergeSource()
+			if tc.expectedErr != "" {
+				assert.Error(t, err, tc.expectedErr)
+				return
+			}
+			assert.NilError(t, err)
+			assert.DeepEqual(t, source, tc.value)
+		})
+	}
+}
+
+func TestGetIgnitionSourceHost(t *testing.T) {
+	cases := []struct {
+		name        string
+		value       string
+		expectedErr string
+	}{
+		{
+			name: "not set",
+		},
+		{
+			name:  "host",
+			value: "192.168.10.5",
+		},
+		{
+			name:  "host and port",
+			value: "mcs.tenant.example.com:22623",
+		},
+		{
+			name:        "url",
+			value:       "https://mcs.tenant.example.com:22623/config/worker",
+			expectedErr: "test-machine-name: IgnitionSourceHost \"https://mcs.tenant.example.com:22623/config/worker\" is not a host or host:port",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			machineScope, _ := initializeMachineScope(t, func(machine *machinev1.Machine) error {
+				modifyProviderSpec := testutils.ProviderSpec
+				modifyProviderSpec.IgnitionSourceHost = tc.value
+				val, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&modifyProviderSpec)
+				machine.Spec.ProviderSpec = machinev1.ProviderSpec{Value: val}
+				return err
+			})
+			host, err := machineScope.GetIgnitionSourceHost()
+			if tc.expectedErr != "" {
+				assert.Error(t, err, tc.expectedErr)
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, host, tc.value)
+		})
+	}
+}
+
+func TestGetHostname(t *testing.T) {
+	cases := []struct {
+		name             string
+		hostnameOverride string
+		expectedHostname string
+		expectedErr      string
+	}{
+		{
+			name:             "default",
+			expectedHostname: testutils.MachineName,
+		},
+		{
+			name:             "static hostname",
+			hostnameOverride: "test-hostname",
+			expectedHostname: "test-hostname",
+		},
+		{
+			name:             "templated hostname",
+			hostnameOverride: "node-{{.MachineName}}",
+			expectedHostname: "node-" + testutils.MachineName,
+		},
+		{
+			name:             "invalid template",
+			hostnameOverride: "node-{{.MachineName",
+			expectedErr:      "test-machine-name: failed to parse the template of HostnameOverride",
+		},
+		{
+			name:             "unknown template field",
+			hostnameOverride: "node-{{.Unknown}}",
+			expectedErr:      "test-machine-name: failed to render the template of HostnameOverride",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			machineScope, _ := initializeMachineScope(t, func(machine *machinev1.Machine) error {
+				modifyProviderSpec := testutils.ProviderSpec
+				modifyProviderSpec.HostnameOverride = tc.hostnameOverride
+				val, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&modifyProviderSpec)
+				machine.Spec.ProviderSpec = machinev1.ProviderSpec{Value: val}
+				return err
+			})
+			hostname, err := machineScope.GetHostname()
+			if tc.expectedErr != "" {
+				assert.ErrorContains(t, err, tc.expectedErr)
+			} else {
+				assert.NilError(t, err)
+				assert.Equal(t, tc.expectedHostname, hostname)
+			}
+		})
+	}
+}
+
+func TestGetReconciliationMode(t *testing.T) {
+	cases := []struct {
+		name         string
+		mode         kubevirtproviderv1alpha1.ReconciliationMode
+		poolName     string
+		expectedMode kubevirtproviderv1alpha1.ReconciliationMode
+		expectedErr  string
+	}{
+		{
+			name:         "default",
+			expectedMode: kubevirtproviderv1alpha1.ReconciliationModeFull,
+		},
+		{
+			name:         "default backed by a pool",
+			poolName:     "test-pool",
+			expectedMode: kubevirtproviderv1alpha1.ReconciliationModeStatusOnly,
+		},
+		{
+			name:         "status only",
+			mode:         kubevirtproviderv1alpha1.ReconciliationModeStatusOnly,
+			expectedMode: kubevirtproviderv1alpha1.ReconciliationModeStatusOnly,
+		},
+		{
+			name:         "none",
+			mode:         kubevirtproviderv1alpha1.ReconciliationModeNone,
+			expectedMode: kubevirtproviderv1alpha1.ReconciliationModeNone,
+		},
+		{
+			name:        "unknown mode",
+			mode:        "partial",
+			expectedErr: "test-machine-name: ReconciliationMode \"partial\" is not one of none, status-only, full",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			machineScope, _ := initializeMachineScope(t, func(machine *machinev1.Machine) error {
+				modifyProviderSpec := testutils.ProviderSpec
+				modifyProviderSpec.ReconciliationMode = tc.mode
+				modifyProviderSpec.VirtualMachinePoolName = tc.poolName
+				val, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&modifyProviderSpec)
+				machine.Spec.ProviderSpec = machinev1.ProviderSpec{Value: val}
+				return err
+			})
+			mode, err := machineScope.GetReconciliationMode()
+			if tc.expectedErr != "" {
+				assert.ErrorContains(t, err, tc.expectedErr)
+			} else {
+				assert.NilError(t, err)
+				assert.Equal(t, tc.expectedMode, mode)
+			}
+		})
+	}
+}
+
+func TestSummarizeVirtualMachine(t *testing.T) {
+	notRunning := false
+	cases := []struct {
+		name            string
+		modifyVM        func(vm *kubevirtapiv1.VirtualMachine)
+		vmiPhase        kubevirtapiv1.VirtualMachineInstancePhase
+		migrationState  *kubevirtapiv1.VirtualMachineInstanceMigrationState
+		expectedSummary kubevirtproviderv1alpha1.VirtualMachineSummary
+	}{
+		{
+			name: "vmi not created yet",
+			expectedSummary: kubevirtproviderv1alpha1.VirtualMachineSummary{
+				PrintedStatus: "Provisioning",
+			},
+		},
+		{
+			name: "stopped",
+			modifyVM: func(vm *kubevirtapiv1.VirtualMachine) {
+				vm.Spec.RunStrategy = nil
+				vm.Spec.Running = &notRunning
+			},
+			expectedSummary: kubevirtproviderv1alpha1.VirtualMachineSummary{
+				PrintedStatus: "Stopped",
+			},
+		},
+		{
+			name: "scheduling",
+			modifyVM: func(vm *kubevirtapiv1.VirtualMachine) {
+				vm.Status.Created = true
+			},
+			vmiPhase: kubevirtapiv1.Scheduling,
+			expectedSummary: kubevirtproviderv1alpha1.VirtualMachineSummary{
+				Created:       true,
+				Phase:         "Scheduling",
+				PrintedStatus: "Starting",
+				NodeName:      "infra-node",
+			},
+		},
+		{
+			name: "running",
+			modifyVM: func(vm *kubevirtapiv1.VirtualMachine) {
+				vm.Status.Created = true
+				vm.Status.Ready = true
+				vm.Status.Conditions = []kubevirtapiv1.VirtualMachineCondition{
+					{Type: kubevirtapiv1.VirtualMachineReady, Status: corev1.ConditionTrue, Reason: "GuestReady"},
+				}
+			},
+			vmiPhase: kubevirtapiv1.Running,
+			expectedSummary: kubevirtproviderv1alpha1.VirtualMachineSummary{
+				Created:       true,
+				Ready:         true,
+				Phase:         "Running",
+				PrintedStatus: "Running",
+				NodeName:      "infra-node",
+				Conditions: []kubevirtproviderv1alpha1.VirtualMachineSummaryCondition{
+					{Type: "Ready", Status: corev1.ConditionTrue, Reason: "GuestReady"},
+				},
+			},
+		},
+		{
+			name: "live migrated",
+			modifyVM: func(vm *kubevirtapiv1.VirtualMachine) {
+				vm.Status.Created = true
+				vm.Status.Ready = true
+			},
+			vmiPhase: kubevirtapiv1.Running,
+			migrationState: &kubevirtapiv1.VirtualMachineInstanceMigrationState{
+				MigrationUID: "migration-uid",
+				SourceNode:   "infra-source",
+				TargetNode:   "infra-node",
+				Completed:    true,
+			},
+			expectedSummary: kubevirtproviderv1alpha1.VirtualMachineSummary{
+				Created:       true,
+				Ready:         true,
+				Phase:         "Running",
+				PrintedStatus: "Running",
+				NodeName:      "infra-node",
+				Migration: &kubevirtproviderv1alpha1.VirtualMachineMigration{
+					UID:        "migration-uid",
+					SourceNode: "infra-source",
+					TargetNode: "infra-node",
+					Completed:  true,
+				},
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			vm := testutils.StubVirtualMachine(nil, nil, nil)
+			if tc.modifyVM != nil {
+				tc.modifyVM(vm)
+			}
+			var vmi *kubevirtapiv1.VirtualMachineInstance
+			if tc.vmiPhase != "" {
+				vmi = testutils.StubVirtualMachineInstance()
+				vmi.Status.Phase = tc.vmiPhase
+				vmi.Status.NodeName = "infra-node"
+				vmi.Status.MigrationState = tc.migrationState
+			}
+			assert.DeepEqual(t, *summarizeVirtualMachine(*vm, vmi), tc.expectedSummary)
+		})
+	}
+}
+
+func TestOmitVirtualMachineStatus(t *testing.T) {
+	machineScope, machine := initializeMachineScope(t, func(machine *machinev1.Machine) error {
+		providerSpec := testutils.ProviderSpec
+		providerSpec.OmitVirtualMachineStatus = true
+		val, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&providerSpec)
+		machine.Spec.ProviderSpec = machinev1.ProviderSpec{Value: val}
+		return err
+	})
+	vm := testutils.StubVirtualMachine(nil, nil, nil)
+	vm.Status.Created = true
+	vm.Status.Ready = true
+	vmi := testutils.StubVirtualMachineInstance()
+	vmi.Status.Phase = kubevirtapiv1.Running
+
+	assert.NilError(t, machineScope.SyncMachine(*vm, vmi, "kubevirt://test"))
+	providerStatus, err := kubevirtproviderv1alpha1.ProviderStatusFromRawExtension(machine.Status.ProviderStatus)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, providerStatus.VirtualMachineStatus, kubevirtapiv1.VirtualMachineStatus{})
+	assert.Assert(t, providerStatus.VirtualMachine.Ready)
+	assert.Equal(t, providerStatus.VirtualMachine.PrintedStatus, "Running")
+	rawStatus := map[string]interface{}{}
+	assert.NilError(t, json.Unmarshal(machine.Status.ProviderStatus.Raw, &rawStatus))
+	_, hasReady := rawStatus["ready"]
+	assert.Assert(t, !hasReady)
+}
+
+func TestStaleSync(t *testing.T) {
+	controller := true
+	cases := []struct {
+		name          string
+		vmGeneration  int64
+		vmUID         string
+		vmiOwnerUID   string
+		expectedStale bool
+	}{
+		{
+			name:         "newer generation of the VirtualMachine",
+			vmGeneration: 4,
+			vmUID:        "test-vm-id",
+			vmiOwnerUID:  "test-vm-id",
+		},
+		{
+			name:          "older generation of the VirtualMachine",
+			vmGeneration:  2,
+			vmUID:         "test-vm-id",
+			expectedStale: true,
+		},
+		{
+			name:         "recreated VirtualMachine",
+			vmGeneration: 1,
+			vmUID:        "recreated-vm-id",
+		},
+		{
+			name:          "VirtualMachineInstance of a former VirtualMachine",
+			vmGeneration:  3,
+			vmUID:         "test-vm-id",
+			vmiOwnerUID:   "former-vm-id",
+			expectedStale: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			machineScope, machine := initializeMachineScope(t, func(machine *machinev1.Machine) error {
+				machine.Generation = 5
+				machine.Annotations = map[string]string{KubevirtIdAnnotationKey: "test-vm-id"}
+				var err error
+				machine.Status.ProviderStatus, err = kubevirtproviderv1alpha1.RawExtensionFromProviderStatus(&kubevirtproviderv1alpha1.KubevirtMachineProviderStatus{
+					VirtualMachineObservedGeneration: 3,
+				})
+				return err
+			})
+			vm := testutils.StubVirtualMachine(nil, nil, nil)
+			vm.UID = types.UID(tc.vmUID)
+			vm.Generation = tc.vmGeneration
+			var vmi *kubevirtapiv1.VirtualMachineInstance
+			if tc.vmiOwnerUID != "" {
+				vmi = testutils.StubVirtualMachineInstance()
+				vmi.OwnerReferences = []metav1.OwnerReference{{Kind: "VirtualMachine", UID: types.UID(tc.vmiOwnerUID), Controller: &controller}}
+			}
+			originalMachine := machine.DeepCopy()
+
+			err := machineScope.SyncMachine(*vm, vmi, "kubevirt://test")
+			if tc.expectedStale {
+				assert.Assert(t, IsStaleSync(err))
+				assert.DeepEqual(t, machine, originalMachine)
+				return
+			}
+			assert.NilError(t, err)
+			providerStatus, err := kubevirtproviderv1alpha1.ProviderStatusFromRawExtension(machine.Status.ProviderStatus)
+			assert.NilError(t, err)
+			assert.Equal(t, providerStatus.ObservedGeneration, int64(5))
+			assert.Equal(t, providerStatus.VirtualMachineObservedGeneration, tc.vmGeneration)
+		})
+	}
+}
+
+func TestSetMachineCreationCondition(t *testing.T) {
+	machineScope, machine := initializeMachineScope(t, nil)
+
+	getCondition := func() kubevirtproviderv1alpha1.KubevirtMachineProviderCondition {
+		providerStatus, err := kubevirtproviderv1alpha1.ProviderStatusFromRawExtension(machine.Status.ProviderStatus)
+		assert.NilError(t, err)
+		assert.Equal(t, len(providerStatus.ProviderConditions), 1)
+		return providerStatus.ProviderConditions[0]
+	}
+
+	assert.NilError(t, machineScope.SetMachineCreationCondition(fmt.Errorf("test error")))
+	failedCondition := getCondition()
+	assert.Equal(t, failedCondition.Type, kubevirtproviderv1alpha1.MachineCreation)
+	assert.Equal(t, failedCondition.Status, corev1.ConditionFalse)
+	assert.Equal(t, failedCondition.Reason, kubevirtproviderv1alpha1.MachineCreationFailed)
+	assert.Equal(t, failedCondition.Message, "test error")
+
+	assert.NilError(t, machineScope.SetMachineCreationCondition(fmt.Errorf("another test error")))
+	assert.Equal(t, getCondition().Message, "another test error")
+	assert.Equal(t, getCondition().LastTransitionTime, failedCondition.LastTransitionTime)
+
+	assert.NilError(t, machineScope.SetMachineCreationCondition(nil))
+	succeededCondition := getCondition()
+	assert.Equal(t, succeededCondition.Status, corev1.ConditionTrue)
+	assert.Equal(t, succeededCondition.Reason, kubevirtproviderv1alpha1.MachineCreationSucceeded)
+	assert.Equal(t, succeededCondition.Message, "")
+}
+
+func TestSetMachineRequiresReplacementCondition(t *testing.T) {
+	machineScope, machine := initializeMachineScope(t, nil)
+	assert.NilError(t, machineScope.SetMachineCreationCondition(nil))
+	assert.NilError(t, machineScope.SetMachineRequiresReplacementCondition(fmt.Errorf("test error")))
+
+	providerStatus, err := kubevirtproviderv1alpha1.ProviderStatusFromRawExtension(machine.Status.ProviderStatus)
+	assert.NilError(t, err)
+	assert.Equal(t, len(providerStatus.ProviderConditions), 2)
+	condition := providerStatus.ProviderConditions[1]
+	assert.Equal(t, condition.Type, kubevirtproviderv1alpha1.MachineRequiresReplacement)
+	assert.Equal(t, condition.Status, corev1.ConditionTrue)
+	assert.Equal(t, condition.Reason, kubevirtproviderv1alpha1.VirtualMachineRecreated)
+	assert.Equal(t, condition.Message, "test error")
+}
+
+func TestGetVirtualMachineUID(t *testing.T) {
+	machineScope, machine := initializeMachineScope(t, nil)
+	assert.Equal(t, machineScope.GetVirtualMachineUID(), "")
+
+	// The Machines synced before the UID was recorded in their ProviderStatus
+	machine.Annotations = map[string]string{KubevirtIdAnnotationKey: "test-vm-id"}
+	assert.Equal(t, machineScope.GetVirtualMachineUID(), "test-vm-id")
+
+	vm := testutils.StubVirtualMachine(nil, nil, nil)
+	vm.UID = "test-vm-id"
+	assert.NilError(t, machineScope.SyncMachine(*vm, nil, "kubevirt://test"))
+	// The UID of the VirtualMachine the Machine was first synced with is kept
+	vm.UID = types.UID("recreated-vm-id")
+	assert.NilError(t, machineScope.SyncMachine(*vm, nil, "kubevirt://test"))
+	assert.Equal(t, machine.Annotations[KubevirtIdAnnotationKey], "recreated-vm-id")
+	assert.Equal(t, machineScope.GetVirtualMachineUID(), "test-vm-id")
+}
+
+// fakeMachinePatcher records the machines it patches, as stored by the tenant-cluster
+type fakeMachinePatcher struct {
+	patchErr       error
+	statusPatchErr error
+	patched        []*machinev1.Machine
+	statusPatched  []*machinev1.Machine
+}
+
+func (p *fakeMachinePatcher) PatchMachine(machine *machinev1.Machine, originMachineCopy *machinev1.Machine) error {
+	if p.patchErr != nil {
+		return p.patchErr
+	}
+	p.patched = append(p.patched, originMachineCopy.DeepCopy())
+	// The patch returns the machine as stored, whose status is the one persisted before
+	machine.Status = *originMachineCopy.Status.DeepCopy()
+	return nil
+}
+
+func (p *fakeMachinePatcher) StatusPatchMachine(machine *machinev1.Machine, originMachineCopy *machinev1.Machine) error {
+	if p.statusPatchErr != nil {
+		return p.statusPatchErr
+	}
+	p.statusPatched = append(p.statusPatched, originMachineCopy.DeepCopy())
+	return nil
+}
+
+func setMachinePatcher(scope MachineScope, patcher MachinePatcher) {
+	scope.(*machineScope).machinePatcher = patcher
+}
+
+func TestSetCreateStepDone(t *testing.T) {
+	machineScope, machine := initializeMachineScope(t, nil)
+	assert.Assert(t, !machineScope.CreateStepDone(CreateStepVMCreated))
+	// The steps aren't persisted without a machine patcher
+	assert.NilError(t, machineScope.SetCreateStepDone(CreateStepIgnitionSourceChecked))
+
+	patcher := &fakeMachinePatcher{}
+	setMachinePatcher(machineScope, patcher)
+	assert.NilError(t, machineScope.SetCreateStepDone(CreateStepVMCreated))
+	assert.NilError(t, machineScope.SetCreateStepDone(CreateStepSecretCreated))
+	// A step which is already done isn't persisted again
+	assert.NilError(t, machineScope.SetCreateStepDone(CreateStepVMCreated))
+	assert.Equal(t, len(patcher.patched), 2)
+	// Each step is patched against the machine as persisted with the previous one
+	assert.Equal(t, patcher.patched[0].Annotations[CreateStepsAnnotation], "")
+	assert.Equal(t, patcher.patched[1].Annotations[CreateStepsAnnotation], "ignition-source-checked,vm-created")
+	assert.Equal(t, machine.Annotations[CreateStepsAnnotation], "ignition-source-checked,secret-created,vm-created")
+	assert.Equal(t, machineScope.GetOriginalMachine().Annotations[CreateStepsAnnotation], machine.Annotations[CreateStepsAnnotation])
+	assert.Assert(t, machineScope.CreateStepDone(CreateStepSecretCreated))
+	assert.Assert(t, machineScope.CreateStepDone(CreateStepVMCreated))
+	assert.Assert(t, !machineScope.CreateStepDone(CreateStepSynced))
+
+	patcher.patchErr = fmt.Errorf("test error")
+	assert.Error(t, machineScope.SetCreateStepDone(CreateStepSynced), "failed to patch machine: test error")
+}
+
+func TestPatchMachine(t *testing.T) {
+	cases := []struct {
+		name           string
+		patchErr       error
+		statusPatchErr error
+		expectedError  string
+	}{
+		{
+			name: "patched",
+		},
+		{
+			name:          "patch failed",
+			patchErr:      fmt.Errorf("test error"),
+			expectedError: "failed to patch machine: test error",
+		},
+		{
+			name:           "status patch failed",
+			statusPatchErr: fmt.Errorf("test error"),
+			expectedError:  "failed to patch machine status: test error",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			machineScope, machine := initializeMachineScope(t, nil)
+			patcher := &fakeMachinePatcher{}
+			setMachinePatcher(machineScope, patcher)
+
+			errorMessage := "test message"
+			machine.Status.ErrorMessage = &errorMessage
+			machine.Labels["test-label"] = "test-value"
+			assert.NilError(t, machineScope.PatchMachineMetadata())
+			// The status isn't persisted by the metadata patch, and remains a change of the original machine
+			assert.Equal(t, *machine.Status.ErrorMessage, "test message")
+			assert.Equal(t, machineScope.GetOriginalMachine().Labels["test-label"], "test-value")
+			assert.Assert(t, machineScope.GetOriginalMachine().Status.ErrorMessage == nil)
+
+			patcher.patchErr = tc.patchErr
+			patcher.statusPatchErr = tc.statusPatchErr
+			err := machineScope.PatchMachine()
+			if tc.expectedError != "" {
+				assert.Error(t, err, tc.expectedError)
+				assert.Assert(t, machineScope.GetOriginalMachine().Status.ErrorMessage == nil)
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, len(patcher.statusPatched), 1)
+			assert.Assert(t, patcher.statusPatched[0].Status.ErrorMessage == nil)
+			assert.Equal(t, *machine.Status.ErrorMessage, "test message")
+			assert.Equal(t, *machineScope.GetOriginalMachine().Status.ErrorMessage, "test message")
+		})
+	}
+
+	machineScope, _ := initializeMachineScope(t, nil)
+	assert.ErrorContains(t, machineScope.PatchMachine(), "the machine scope has no machine patcher")
+}
+
+func TestSyncProvisioningTimeline(t *testing.T) {
+	vmCreated := metav1.Unix(1600000000, 0)
+	vmiCreated := metav1.Unix(1600000100, 0)
+	vmiReady := metav1.Unix(1600000200, 0)
+	earlier := metav1.Unix(1500000000, 0)
+
+	vm := kubevirtapiv1.VirtualMachine{ObjectMeta: metav1.ObjectMeta{CreationTimestamp: vmCreated}}
+	pendingVMI := &kubevirtapiv1.VirtualMachineInstance{
+		ObjectMeta: metav1.ObjectMeta{CreationTimestamp: vmiCreated},
+		Status:     kubevirtapiv1.VirtualMachineInstanceStatus{Phase: kubevirtapiv1.Pending},
+	}
+	runningVMI := &kubevirtapiv1.VirtualMachineInstance{
+		ObjectMeta: metav1.ObjectMeta{CreationTimestamp: vmiCreated},
+		Status: kubevirtapiv1.VirtualMachineInstanceStatus{
+			Phase: kubevirtapiv1.Running,
+			Conditions: []kubevirtapiv1.VirtualMachineInstanceCondition{
+				{Type: kubevirtapiv1.VirtualMachineInstanceReady, Status: corev1.ConditionTrue, LastTransitionTime: vmiReady},
+			},
+		},
+	}
+
+	cases := []struct {
+		name     string
+		timeline *kubevirtproviderv1alpha1.ProvisioningTimeline
+		vm       kubevirtapiv1.VirtualMachine
+		vmi      *kubevirtapiv1.VirtualMachineInstance
+		expected *kubevirtproviderv1alpha1.ProvisioningTimeline
+	}{
+		{
+			name:     "nothing created yet",
+			vm:       kubevirtapiv1.VirtualMachine{},
+			expected: nil,
+		},
+		{
+			name:     "vm created",
+			vm:       vm,
+			expected: &kubevirtproviderv1alpha1.ProvisioningTimeline{VMCreated: &vmCreated},
+		},
+		{
+			name: "vmi pending",
+			vm:   vm,
+			vmi:  pendingVMI,
+			expected: &kubevirtproviderv1alpha1.ProvisioningTimeline{
+				VMCreated:       &vmCreated,
+				DataVolumeReady: &vmiCreated,
+			},
+		},
+		{
+			name: "vmi running",
+			vm:   vm,
+			vmi:  runningVMI,
+			expected: &kubevirtproviderv1alpha1.ProvisioningTimeline{
+				VMCreated:       &vmCreated,
+				DataVolumeReady: &vmiCreated,
+				VMIRunning:      &vmiReady,
+			},
+		},
+		{
+			name: "recorded steps are kept",
+			timeline: &kubevirtproviderv1alpha1.ProvisioningTimeline{
+				VMCreated:       &earlier,
+				DataVolumeReady: &earlier,
+				VMIRunning:      &earlier,
+			},
+			vm:  vm,
+			vmi: runningVMI,
+			expected: &kubevirtproviderv1alpha1.ProvisioningTimeline{
+				VMCreated:       &earlier,
+				DataVolumeReady: &earlier,
+				VMIRunning:      &earlier,
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.DeepEqual(t, syncProvisioningTimeline(tc.timeline, tc.vm, tc.vmi), tc.expected)
+		})
+	}
+}
+
+func TestSelectAccessMode(t *testing.T) {
+	cases := []struct {
+		name             string
+		storageClassName string
+		supported        []corev1.PersistentVolumeAccessMode
+		expected         corev1.PersistentVolumeAccessMode
+		expectedDecision string
+	}{
+		{
+			name:             "no default storage class",
+			expected:         corev1.ReadWriteMany,
+			expectedDecision: "no default storage class was found, using the default access mode ReadWriteMany",
+		},
+		{
+			name:             "unknown access modes",
+			storageClassName: "test-storage-class",
+			expected:         corev1.ReadWriteMany,
+			expectedDecision: "the StorageProfile of storage class test-storage-class has no access modes, using the default access mode ReadWriteMany",
+		},
+		{
+			name:             "read write many is preferred",
+			storageClassName: "test-storage-class",
+			supported:        []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce, corev1.ReadWriteMany},
+			expected:         corev1.ReadWriteMany,
+			expectedDecision: "storage class test-storage-class supports [ReadWriteOnce ReadWriteMany], using access mode ReadWriteMany",
+		},
+		{
+			name:             "read write once",
+			storageClassName: "test-storage-class",
+			supported:        []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			expected:         corev1.ReadWriteOnce,
+			expectedDecision: "storage class test-storage-class supports [ReadWriteOnce], using access mode ReadWriteOnce",
+		},
+		{
+			name:             "no writable access mode",
+			storageClassName: "test-storage-class",
+			supported:        []corev1.PersistentVolumeAccessMode{corev1.ReadOnlyMany},
+			expected:         corev1.ReadWriteMany,
+			expectedDecision: "storage class test-storage-class supports neither of [ReadWriteMany ReadWriteOnce], using the default access mode ReadWriteMany",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			accessMode, decision := selectAccessMode(tc.storageClassName, tc.supported)
+			assert.Equal(t, accessMode, tc.expected)
+			assert.Equal(t, decision, tc.expectedDecision)
+		})
+	}
+}
+
+func TestSelectPersistentVolumeAccessMode(t *testing.T) {
+	machineScope, _ := initializeMachineScope(t, func(machine *machinev1.Machine) error {
+		modifyProviderSpec := testutils.ProviderSpec
+		modifyProviderSpec.PersistentVolumeAccessMode = ""
+		val, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&modifyProviderSpec)
+		machine.Spec.ProviderSpec = machinev1.ProviderSpec{Value: val}
+
+		return err
+	})
+	assert.Equal(t, machineScope.PersistentVolumeAccessModeDetectionRequired(), true)
+	assert.Equal(t, machineScope.GetPersistentVolumeAccessModeDecision(), "")
+
+	decision := machineScope.SelectPersistentVolumeAccessMode("test-storage-class", []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce})
+	assert.Equal(t, machineScope.GetPersistentVolumeAccessModeDecision(), decision)
+	vm, err := machineScope.CreateVirtualMachineFromMachine()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, vm.Spec.DataVolumeTemplates[0].Spec.PVC.AccessModes, []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce})
+}
+
+func TestCreateNetworkPolicyFromMachine(t *testing.T) {
+	machineScope, _ := initializeMachineScope(t, nil)
+	assert.Assert(t, machineScope.CreateNetworkPolicyFromMachine() == nil)
+
+	machineScope, _ = initializeMachineScope(t, func(machine *machinev1.Machine) error {
+		modifyProviderSpec := testutils.ProviderSpec
+		modifyProviderSpec.IsolateTenantNetwork = true
+		val, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&modifyProviderSpec)
+		machine.Spec.ProviderSpec = machinev1.ProviderSpec{Value: val}
+
+		return err
+	})
+	networkPolicy := machineScope.CreateNetworkPolicyFromMachine()
+	assert.Equal(t, networkPolicy.Name, "tenantcluster-test-infra-id-isolation")
+	assert.Equal(t, networkPolicy.Namespace, testutils.InfraNamespace)
+	ownershipLabels := utils.BuildLabels(testutils.InfraID)
+	assert.DeepEqual(t, networkPolicy.Spec.PodSelector.MatchLabels, ownershipLabels)
+	assert.Equal(t, len(networkPolicy.Spec.Ingress), 1)
+	// Only the virt-launcher pods of the tenant-cluster are allowed, not the pods of the other namespaces
+	assert.Equal(t, len(networkPolicy.Spec.Ingress[0].From), 1)
+	assert.DeepEqual(t, networkPolicy.Spec.Ingress[0].From[0].PodSelector.MatchLabels, ownershipLabels)
+	assert.Assert(t, networkPolicy.Spec.Ingress[0].From[0].NamespaceSelector == nil)
+
+	// The virt-launcher pods of the tenant-cluster are selected by the NetworkPolicy
+	vm, err := machineScope.CreateVirtualMachineFromMachine()
+	assert.NilError(t, err)
+	for k, v := range ownershipLabels {
+		assert.Equal(t, vm.Spec.Template.ObjectMeta.Labels[k], v)
+	}
+
+	// The pods of the allowed namespaces are allowed as well
+	machineScope, _ = initializeMachineScope(t, func(machine *machinev1.Machine) error {
+		modifyProviderSpec := testutils.ProviderSpec
+		modifyProviderSpec.IsolateTenantNetwork = true
+		modifyProviderSpec.TenantNetworkAllowedNamespaces = []string{"openshift-ingress", "openshift-monitoring"}
+		val, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&modifyProviderSpec)
+		machine.Spec.ProviderSpec = machinev1.ProviderSpec{Value: val}
+
+		return err
+	})
+	networkPolicy = machineScope.CreateNetworkPolicyFromMachine()
+	assert.Equal(t, len(networkPolicy.Spec.Ingress[0].From), 2)
+	assert.DeepEqual(t, networkPolicy.Spec.Ingress[0].From[1].NamespaceSelector.MatchExpressions, []metav1.LabelSelectorRequirement{
+		{Key: "kubernetes.io/metadata.name", Operator: metav1.LabelSelectorOpIn, Values: []string{"openshift-ingress", "openshift-monitoring"}},
+	})
+}
+
+func TestBuildWarmBootVolume(t *testing.T) {
+	providerSpec := testutils.ProviderSpec
+	otherStorageSpec := testutils.ProviderSpec
+	otherStorageSpec.RequestedStorage = "100Gi"
+	otherMemorySpec := testutils.ProviderSpec
+	otherMemorySpec.RequestedMemory = "64Gi"
+	dataSourceSpec := testutils.ProviderSpec
+	dataSourceSpec.SourcePvcName = ""
+	dataSourceSpec.DataSourceName = testutils.ProviderSpec.SourcePvcName
+
+	shape := BootVolumeShape(&providerSpec)
+	assert.Assert(t, shape != BootVolumeShape(&otherStorageSpec))
+	assert.Assert(t, shape != BootVolumeShape(&dataSourceSpec))
+	assert.Equal(t, shape, BootVolumeShape(&otherMemorySpec))
+
+	dataVolume := BuildWarmBootVolume(&providerSpec, testutils.InfraNamespace, testutils.InfraID)
+	assert.Equal(t, dataVolume.Name, "")
+	assert.Equal(t, dataVolume.GenerateName, fmt.Sprintf("%s-warm-", testutils.InfraID))
+	assert.Equal(t, dataVolume.Namespace, testutils.InfraNamespace)
+	assert.Equal(t, dataVolume.Labels[WarmPoolShapeLabel], shape)
+	assert.Equal(t, dataVolume.Labels[fmt.Sprintf("tenantcluster-%s-machine.openshift.io", testutils.InfraID)], "owned")
+	assert.Equal(t, dataVolume.Spec.Source.PVC.Name, providerSpec.SourcePvcName)
+}
+
+func TestSetPoolVirtualMachine(t *testing.T) {
+	machineScope, _ := initializeMachineScope(t, func(machine *machinev1.Machine) error {
+		modifyProviderSpec := testutils.ProviderSpec
+		modifyProviderSpec.VirtualMachinePoolName = "test-pool"
+		val, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&modifyProviderSpec)
+		machine.Spec.ProviderSpec = machinev1.ProviderSpec{Value: val}
+		return err
+	})
+	assert.Equal(t, machineScope.GetVirtualMachinePoolName(), "test-pool")
+	name, err := machineScope.GetVirtualMachineName()
+	assert.NilError(t, err)
+	assert.Equal(t, name, testutils.MachineName)
+
+	machineScope.SetPoolVirtualMachine("test-pool-2")
+	name, err = machineScope.GetVirtualMachineName()
+	assert.NilError(t, err)
+	assert.Equal(t, name, "test-pool-2")
+	assert.Equal(t, machineScope.GetMachine().Annotations[PoolVirtualMachineAnnotation], "test-pool-2")
+}
+
+func TestGetExistingVMName(t *testing.T) {
+	machineScope, _ := initializeMachineScope(t, func(machine *machinev1.Machine) error {
+		modifyProviderSpec := testutils.ProviderSpec
+		modifyProviderSpec.ExistingVMName = "staged-vm"
+		modifyProviderSpec.NameTemplate = "{{.InfraID}}-{{.MachineName}}"
+		val, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&modifyProviderSpec)
+		machine.Spec.ProviderSpec = machinev1.ProviderSpec{Value: val}
+		return err
+	})
+	assert.Equal(t, machineScope.GetExistingVMName(), "staged-vm")
+	name, err := machineScope.GetVirtualMachineName()
+	assert.NilError(t, err)
+	assert.Equal(t, name, "staged-vm")
+	mode, err := machineScope.GetReconciliationMode()
+	assert.NilError(t, err)
+	assert.Equal(t, mode, kubevirtproviderv1alpha1.ReconciliationModeStatusOnly)
+}
+
+func TestGetBootVolumeDeletePolicy(t *testing.T) {
+	cases := []struct {
+		name           string
+		policy         kubevirtproviderv1alpha1.BootVolumeDeletePolicy
+		expectedPolicy kubevirtproviderv1alpha1.BootVolumeDeletePolicy
+		expectedErr    string
+	}{
+		{
+			name:           "default",
+			expectedPolicy: kubevirtproviderv1alpha1.BootVolumeDeletePolicyDelete,
+		},
+		{
+			name:           "retain",
+			policy:         kubevirtproviderv1alpha1.BootVolumeDeletePolicyRetain,
+			expectedPolicy: kubevirtproviderv1alpha1.BootVolumeDeletePolicyRetain,
+		},
+		{
+			name:        "unknown policy",
+			policy:      "Recycle",
+			expectedErr: "test-machine-name: BootVolumeDeletePolicy \"Recycle\" is not one of Delete, Retain",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			machineScope, _ := initializeMachineScope(t, func(machine *machinev1.Machine) error {
+				modifyProviderSpec := testutils.ProviderSpec
+				modifyProviderSpec.BootVolumeDeletePolicy = tc.policy
+				val, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&modifyProviderSpec)
+				machine.Spec.ProviderSpec = machinev1.ProviderSpec{Value: val}
+				return err
+			})
+			policy, err := machineScope.GetBootVolumeDeletePolicy()
+			if tc.expectedErr != "" {
+				assert.ErrorContains(t, err, tc.expectedErr)
+			} else {
+				assert.NilError(t, err)
+				assert.Equal(t, tc.expectedPolicy, policy)
+			}
+		})
+	}
+}
+
+func TestGetSourceImageDigest(t *testing.T) {
+	const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	cases := []struct {
+		name        string
+		digest      string
+		expectedErr string
+	}{
+		{
+			name: "not pinned",
+		},
+		{
+			name:   "pinned",
+			digest: digest,
+		},
+		{
+			name:        "invalid digest",
+			digest:      "sha256:rhcos",
+			expectedErr: "test-machine-name: SourceImageDigest \"sha256:rhcos\" is not of the form sha256:<hex>",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			machineScope, _ := initializeMachineScope(t, func(machine *machinev1.Machine) error {
+				modifyProviderSpec := testutils.ProviderSpec
+				modifyProviderSpec.SourceImageDigest = tc.digest
+				val, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&modifyProviderSpec)
+				machine.Spec.ProviderSpec = machinev1.ProviderSpec{Value: val}
+				return err
+			})
+			pinnedDigest, err := machineScope.GetSourceImageDigest()
+			if tc.expectedErr != "" {
+				assert.ErrorContains(t, err, tc.expectedErr)
+			} else {
+				assert.NilError(t, err)
+				assert.Equal(t, tc.digest, pinnedDigest)
+			}
+		})
+	}
+}
+
+func TestGetDataSource(t *testing.T) {
+	cases := []struct {
+		name              string
+		namespace         string
+		expectedNamespace string
+	}{
+		{
+			name:              "default namespace",
+			expectedNamespace: testutils.InfraNamespace,
+		},
+		{
+			name:              "golden images namespace",
+			namespace:         "openshift-virtualization-os-images",
+			expectedNamespace: "openshift-virtualization-os-images",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			machineScope, _ := initializeMachineScope(t, func(machine *machinev1.Machine) error {
+				modifyProviderSpec := testutils.ProviderSpec
+				modifyProviderSpec.SourcePvcName = ""
+				modifyProviderSpec.DataSourceName = "rhcos"
+				modifyProviderSpec.DataSourceNamespace = tc.namespace
+				val, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&modifyProviderSpec)
+				machine.Spec.ProviderSpec = machinev1.ProviderSpec{Value: val}
+				return err
+			})
+			namespace, name := machineScope.GetDataSource()
+			assert.Equal(t, tc.expectedNamespace, namespace)
+			assert.Equal(t, "rhcos", name)
+		})
+	}
+}
+
+func TestMetadataFilter(t *testing.T) {
+	metadata := map[string]string{
+		"machine.openshift.io/cluster-api-cluster":         "test-cluster-id",
+		"machine.openshift.io/cluster-api-machineset":      "workers",
+		"kubectl.kubernetes.io/last-applied-configuration": strings.Repeat("x", 100),
+		"tenant.example.com/secret-owner":                  "alice",
+	}
+	cases := []struct {
+		name             string
+		filter           MetadataFilter
+		expectedMetadata map[string]string
+		expectedDropped  []string
+	}{
+		{
+			name:             "copy all the metadata by default",
+			expectedMetadata: metadata,
+		},
+		{
+			name:   "copy the allowed prefixes only",
+			filter: MetadataFilter{AllowedPrefixes: []string{"machine.openshift.io/"}},
+			expectedMetadata: map[string]string{
+				"machine.openshift.io/cluster-api-cluster":    "test-cluster-id",
+				"machine.openshift.io/cluster-api-machineset": "workers",
+			},
+			expectedDropped: []string{"kubectl.kubernetes.io/last-applied-configuration", "tenant.example.com/secret-owner"},
+		},
+		{
+			name: "never copy the denied prefixes, even allowed",
+			filter: MetadataFilter{
+				AllowedPrefixes: []string{"machine.openshift.io/", "tenant.example.com/"},
+				DeniedPrefixes:  []string{"tenant.example.com/secret"},
+			},
+			expectedMetadata: map[string]string{
+				"machine.openshift.io/cluster-api-cluster":    "test-cluster-id",
+				"machine.openshift.io/cluster-api-machineset": "workers",
+			},
+			expectedDropped: []string{"kubectl.kubernetes.io/last-applied-configuration", "tenant.example.com/secret-owner"},
+		},
+		{
+			name:   "drop the values longer than the max length",
+			filter: MetadataFilter{MaxValueLength: 50},
+			expectedMetadata: map[string]string{
+				"machine.openshift.io/cluster-api-cluster":    "test-cluster-id",
+				"machine.openshift.io/cluster-api-machineset": "workers",
+				"tenant.example.com/secret-owner":             "alice",
+			},
+			expectedDropped: []string{"kubectl.kubernetes.io/last-applied-configuration"},
+		},
+		{
+			name:   "copy the keys in order within the max size",
+			filter: MetadataFilter{MaxTotalSize: 110},
+			expectedMetadata: map[string]string{
+				"machine.openshift.io/cluster-api-cluster":    "test-cluster-id",
+				"machine.openshift.io/cluster-api-machineset": "workers",
+			},
+			expectedDropped: []string{"kubectl.kubernetes.io/last-applied-configuration", "tenant.example.com/secret-owner"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			filtered, dropped := tc.filter.Filter(metadata)
+			assert.DeepEqual(t, filtered, tc.expectedMetadata)
+			assert.DeepEqual(t, dropped, tc.expectedDropped)
+		})
+	}
+}
+
+func TestMetadataFilterAnnotations(t *testing.T) {
+	annotations := map[string]string{
+		"kubectl.kubernetes.io/last-applied-configuration": `{"apiVersion":"machine.openshift.io/v1beta1"}`,
+		"machine.openshift.io/instance-state":              "Running",
+		"machine.openshift.io/exclude-node-draining":       "",
+		"cluster.x-k8s.io/paused":                          "",
+		"kubevirt.machine.openshift.io/vm-state":           "Running",
+		"VmId":                                             "test-vm-id",
+		"VmMacAddress":                                     "02:00:00:00:00:02",
+		"tenant.example.com/cost-center":                   "1234",
+		"tenant.example.com/notes":                         "internal",
+	}
+	filter := MetadataFilter{DeniedPrefixes: []string{"tenant.example.com/notes"}}
+
+	filtered, dropped := filter.FilterAnnotations(annotations)
+	assert.DeepEqual(t, filtered, map[string]string{"tenant.example.com/cost-center": "1234"})
+	// The internal annotations are never copied, without being reported as dropped by the filter
+	assert.DeepEqual(t, dropped, []string{"tenant.example.com/notes"})
+
+	filtered, dropped = MetadataFilter{}.FilterAnnotations(map[string]string{"VmId": "test-vm-id"})
+	assert.Assert(t, filtered == nil)
+	assert.Assert(t, dropped == nil)
+}
+
+func TestCreateVirtualMachineFromMachineMetadataFilter(t *testing.T) {
+	machine, err := testutils.StubMachine()
+	assert.NilError(t, err)
+	machine.Labels["tenant.example.com/team"] = "payments"
+	machine.Annotations = map[string]string{"tenant.example.com/notes": "internal"}
+	filter := MetadataFilter{DeniedPrefixes: []string{"tenant.example.com/"}}
+	machineScope, err := New(filter, nil).CreateMachineScope(machine, testutils.InfraNamespace, testutils.InfraID)
+	assert.NilError(t, err)
+
+	vm, err := machineScope.CreateVirtualMachineFromMachine()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, vm.Labels, map[string]string{
+		fmt.Sprintf("tenantcluster-%s-machine.openshift.io", testutils.InfraID): "owned",
+		machinev1.MachineClusterIDLabel:                                         "test-cluster-id",
+	})
+	assert.DeepEqual(t, vm.Annotations, map[string]string{})
+	// The Machine keeps its metadata
+	assert.Equal(t, machine.Labels["tenant.example.com/team"], "payments")
+}
+
+// createVirtualMachineAllocsBudget is the number of allocations rendering the VirtualMachine of a machine may take,
+// it runs on every reconcile of the machines being created
+const createVirtualMachineAllocsBudget = 60
+
+func BenchmarkCreateVirtualMachineFromMachine(b *testing.B) {
+	machine, err := testutils.StubMachine()
+	if err != nil {
+		b.Fatalf("Error durring stubMachine creation: %v", err)
+	}
+	machineScope, err := New(MetadataFilter{}, nil).CreateMachineScope(machine, testutils.InfraNamespace, testutils.InfraID)
+	if err != nil {
+		b.Fatalf("Error durring machineScope creation: %v", err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := machineScope.CreateVirtualMachineFromMachine(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestCreateVirtualMachineFromMachineAllocations(t *testing.T) {
+	machineScope, _ := initializeMachineScope(t, nil)
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, err := machineScope.CreateVirtualMachineFromMachine(); err != nil {
+			t.Fatal(err)
+		}
+	})
+	assert.Assert(t, allocs <= createVirtualMachineAllocsBudget, "rendering the VirtualMachine took %v allocations, over the budget of %d", allocs, createVirtualMachineAllocsBudget)
 }
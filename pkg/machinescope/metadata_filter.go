@@ -0,0 +1,104 @@
+package machinescope
+
+import (
+	"sort"
+	"strings"
+)
+
+// internalAnnotationPrefixes are the prefixes of the annotations of the Machines internal to kubectl, the machine-api
+// and the provider, which are never copied onto the VirtualMachine: they only bloat it, e.g. the
+// last-applied-configuration of kubectl, up to exceeding the size limit of its annotations
+var internalAnnotationPrefixes = []string{
+	"kubectl.kubernetes.io/",
+	"machine.openshift.io/",
+	"cluster.x-k8s.io/",
+	"kubevirt.machine.openshift.io/",
+}
+
+// internalAnnotations are the annotations of the Machines set by the provider without a prefix
+var internalAnnotations = map[string]bool{
+	KubevirtIdAnnotationKey: true,
+	macAddressAnnotationKey: true,
+}
+
+// MetadataFilter selects the labels and annotations of a Machine which are copied onto its VirtualMachine, so huge or
+// sensitive metadata of the tenant-cluster doesn't leak into the infra-cluster. The zero filter copies them all.
+type MetadataFilter struct {
+	// AllowedPrefixes only copies the keys with one of the prefixes, when set
+	AllowedPrefixes []string
+	// DeniedPrefixes never copies the keys with one of the prefixes, even allowed ones
+	DeniedPrefixes []string
+	// MaxValueLength drops the values longer than it, zero is unlimited
+	MaxValueLength int
+	// MaxTotalSize caps the size of the keys and values copied, of the labels and of the annotations each: the keys
+	// are copied in order, but those exceeding the cap. Zero is unlimited.
+	MaxTotalSize int
+}
+
+// Filter returns the metadata copied onto the VirtualMachine, and the keys which are dropped, sorted
+func (f MetadataFilter) Filter(metadata map[string]string) (map[string]string, []string) {
+	if len(metadata) == 0 {
+		return nil, nil
+	}
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	filtered := make(map[string]string, len(metadata))
+	var dropped []string
+	size := 0
+	for _, key := range keys {
+		value := metadata[key]
+		if !f.allows(key) || f.MaxValueLength > 0 && len(value) > f.MaxValueLength ||
+			f.MaxTotalSize > 0 && size+len(key)+len(value) > f.MaxTotalSize {
+			dropped = append(dropped, key)
+			continue
+		}
+		size += len(key) + len(value)
+		filtered[key] = value
+	}
+	return filtered, dropped
+}
+
+// FilterAnnotations returns the annotations copied onto the VirtualMachine, without the internal annotations, and
+// the annotations which the filter drops, sorted
+func (f MetadataFilter) FilterAnnotations(annotations map[string]string) (map[string]string, []string) {
+	sanitized := make(map[string]string, len(annotations))
+	for key, value := range annotations {
+		if !isInternalAnnotation(key) {
+			sanitized[key] = value
+		}
+	}
+	return f.Filter(sanitized)
+}
+
+func isInternalAnnotation(key string) bool {
+	if internalAnnotations[key] {
+		return true
+	}
+	for _, prefix := range internalAnnotationPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f MetadataFilter) allows(key string) bool {
+	for _, prefix := range f.DeniedPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return false
+		}
+	}
+	if len(f.AllowedPrefixes) == 0 {
+		return true
+	}
+	for _, prefix := range f.AllowedPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -6,11 +6,14 @@ package mock
 
 import (
 	gomock "github.com/golang/mock/gomock"
+	v1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+	machinescope "github.com/openshift/cluster-api-provider-kubevirt/pkg/machinescope"
 	v1beta1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
 	v1 "k8s.io/api/core/v1"
-	v10 "kubevirt.io/client-go/api/v1"
+	v10 "k8s.io/api/networking/v1"
+	v11 "kubevirt.io/client-go/api/v1"
+	v1alpha10 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
 	reflect "reflect"
-	time "time"
 )
 
 // MockMachineScope is a mock of MachineScope interface
@@ -37,25 +40,26 @@ func (m *MockMachineScope) EXPECT() *MockMachineScopeMockRecorder {
 }
 
 // UpdateAllowed mocks base method
-func (m *MockMachineScope) UpdateAllowed(requeueAfterSeconds time.Duration) bool {
+func (m *MockMachineScope) UpdateAllowed(policy machinescope.UpdatePolicy) bool {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateAllowed", requeueAfterSeconds)
+	ret := m.ctrl.Call(m, "UpdateAllowed", policy)
 	ret0, _ := ret[0].(bool)
 	return ret0
 }
 
 // UpdateAllowed indicates an expected call of UpdateAllowed
-func (mr *MockMachineScopeMockRecorder) UpdateAllowed(requeueAfterSeconds interface{}) *gomock.Call {
+func (mr *MockMachineScopeMockRecorder) UpdateAllowed(policy interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAllowed", reflect.TypeOf((*MockMachineScope)(nil).UpdateAllowed), requeueAfterSeconds)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAllowed", reflect.TypeOf((*MockMachineScope)(nil).UpdateAllowed), policy)
 }
 
 // CreateIgnitionSecretFromMachine mocks base method
-func (m *MockMachineScope) CreateIgnitionSecretFromMachine(userData []byte) *v1.Secret {
+func (m *MockMachineScope) CreateIgnitionSecretFromMachine(userData []byte) (*v1.Secret, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "CreateIgnitionSecretFromMachine", userData)
 	ret0, _ := ret[0].(*v1.Secret)
-	return ret0
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // CreateIgnitionSecretFromMachine indicates an expected call of CreateIgnitionSecretFromMachine
@@ -64,8 +68,22 @@ func (mr *MockMachineScopeMockRecorder) CreateIgnitionSecretFromMachine(userData
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateIgnitionSecretFromMachine", reflect.TypeOf((*MockMachineScope)(nil).CreateIgnitionSecretFromMachine), userData)
 }
 
+// CreateNetworkPolicyFromMachine mocks base method
+func (m *MockMachineScope) CreateNetworkPolicyFromMachine() *v10.NetworkPolicy {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateNetworkPolicyFromMachine")
+	ret0, _ := ret[0].(*v10.NetworkPolicy)
+	return ret0
+}
+
+// CreateNetworkPolicyFromMachine indicates an expected call of CreateNetworkPolicyFromMachine
+func (mr *MockMachineScopeMockRecorder) CreateNetworkPolicyFromMachine() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateNetworkPolicyFromMachine", reflect.TypeOf((*MockMachineScope)(nil).CreateNetworkPolicyFromMachine))
+}
+
 // SyncMachine mocks base method
-func (m *MockMachineScope) SyncMachine(vm v10.VirtualMachine, vmi *v10.VirtualMachineInstance, providerID string) error {
+func (m *MockMachineScope) SyncMachine(vm v11.VirtualMachine, vmi *v11.VirtualMachineInstance, providerID string) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "SyncMachine", vm, vmi, providerID)
 	ret0, _ := ret[0].(error)
@@ -79,10 +97,10 @@ func (mr *MockMachineScopeMockRecorder) SyncMachine(vm, vmi, providerID interfac
 }
 
 // CreateVirtualMachineFromMachine mocks base method
-func (m *MockMachineScope) CreateVirtualMachineFromMachine() (*v10.VirtualMachine, error) {
+func (m *MockMachineScope) CreateVirtualMachineFromMachine() (*v11.VirtualMachine, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "CreateVirtualMachineFromMachine")
-	ret0, _ := ret[0].(*v10.VirtualMachine)
+	ret0, _ := ret[0].(*v11.VirtualMachine)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -121,6 +139,21 @@ func (mr *MockMachineScopeMockRecorder) GetMachineName() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMachineName", reflect.TypeOf((*MockMachineScope)(nil).GetMachineName))
 }
 
+// GetVirtualMachineName mocks base method
+func (m *MockMachineScope) GetVirtualMachineName() (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVirtualMachineName")
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVirtualMachineName indicates an expected call of GetVirtualMachineName
+func (mr *MockMachineScopeMockRecorder) GetVirtualMachineName() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVirtualMachineName", reflect.TypeOf((*MockMachineScope)(nil).GetVirtualMachineName))
+}
+
 // GetMachineNamespace mocks base method
 func (m *MockMachineScope) GetMachineNamespace() string {
 	m.ctrl.T.Helper()
@@ -135,6 +168,20 @@ func (mr *MockMachineScopeMockRecorder) GetMachineNamespace() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMachineNamespace", reflect.TypeOf((*MockMachineScope)(nil).GetMachineNamespace))
 }
 
+// GetCredentialsSecretName mocks base method
+func (m *MockMachineScope) GetCredentialsSecretName() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCredentialsSecretName")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// GetCredentialsSecretName indicates an expected call of GetCredentialsSecretName
+func (mr *MockMachineScopeMockRecorder) GetCredentialsSecretName() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCredentialsSecretName", reflect.TypeOf((*MockMachineScope)(nil).GetCredentialsSecretName))
+}
+
 // GetInfraNamespace mocks base method
 func (m *MockMachineScope) GetInfraNamespace() string {
 	m.ctrl.T.Helper()
@@ -162,3 +209,620 @@ func (mr *MockMachineScopeMockRecorder) GetIgnitionSecretName() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetIgnitionSecretName", reflect.TypeOf((*MockMachineScope)(nil).GetIgnitionSecretName))
 }
+
+// IgnitionSecretNameDefaulted mocks base method
+func (m *MockMachineScope) IgnitionSecretNameDefaulted() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IgnitionSecretNameDefaulted")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// IgnitionSecretNameDefaulted indicates an expected call of IgnitionSecretNameDefaulted
+func (mr *MockMachineScopeMockRecorder) IgnitionSecretNameDefaulted() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IgnitionSecretNameDefaulted", reflect.TypeOf((*MockMachineScope)(nil).IgnitionSecretNameDefaulted))
+}
+
+// GetInfraIgnitionSecretName mocks base method
+func (m *MockMachineScope) GetInfraIgnitionSecretName() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetInfraIgnitionSecretName")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// GetInfraIgnitionSecretName indicates an expected call of GetInfraIgnitionSecretName
+func (mr *MockMachineScopeMockRecorder) GetInfraIgnitionSecretName() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInfraIgnitionSecretName", reflect.TypeOf((*MockMachineScope)(nil).GetInfraIgnitionSecretName))
+}
+
+// IgnitionPropagatedViaSecret mocks base method
+func (m *MockMachineScope) IgnitionPropagatedViaSecret() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IgnitionPropagatedViaSecret")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// IgnitionPropagatedViaSecret indicates an expected call of IgnitionPropagatedViaSecret
+func (mr *MockMachineScopeMockRecorder) IgnitionPropagatedViaSecret() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IgnitionPropagatedViaSecret", reflect.TypeOf((*MockMachineScope)(nil).IgnitionPropagatedViaSecret))
+}
+
+// CreateIgnitionDataVolumeFromMachine mocks base method
+func (m *MockMachineScope) CreateIgnitionDataVolumeFromMachine(imageSize int64) (*v1alpha10.DataVolume, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateIgnitionDataVolumeFromMachine", imageSize)
+	ret0, _ := ret[0].(*v1alpha10.DataVolume)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateIgnitionDataVolumeFromMachine indicates an expected call of CreateIgnitionDataVolumeFromMachine
+func (mr *MockMachineScopeMockRecorder) CreateIgnitionDataVolumeFromMachine(imageSize interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateIgnitionDataVolumeFromMachine", reflect.TypeOf((*MockMachineScope)(nil).CreateIgnitionDataVolumeFromMachine), imageSize)
+}
+
+// IgnitionSecretVersioned mocks base method
+func (m *MockMachineScope) IgnitionSecretVersioned() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IgnitionSecretVersioned")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// IgnitionSecretVersioned indicates an expected call of IgnitionSecretVersioned
+func (mr *MockMachineScopeMockRecorder) IgnitionSecretVersioned() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IgnitionSecretVersioned", reflect.TypeOf((*MockMachineScope)(nil).IgnitionSecretVersioned))
+}
+
+// GetIgnitionMergeSource mocks base method
+func (m *MockMachineScope) GetIgnitionMergeSource() (*v1alpha1.IgnitionMergeSource, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetIgnitionMergeSource")
+	ret0, _ := ret[0].(*v1alpha1.IgnitionMergeSource)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetIgnitionMergeSource indicates an expected call of GetIgnitionMergeSource
+func (mr *MockMachineScopeMockRecorder) GetIgnitionMergeSource() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetIgnitionMergeSource", reflect.TypeOf((*MockMachineScope)(nil).GetIgnitionMergeSource))
+}
+
+// GetIgnitionSourceHost mocks base method
+func (m *MockMachineScope) GetIgnitionSourceHost() (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetIgnitionSourceHost")
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetIgnitionSourceHost indicates an expected call of GetIgnitionSourceHost
+func (mr *MockMachineScopeMockRecorder) GetIgnitionSourceHost() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetIgnitionSourceHost", reflect.TypeOf((*MockMachineScope)(nil).GetIgnitionSourceHost))
+}
+
+// GetIgnitionSourceCheck mocks base method
+func (m *MockMachineScope) GetIgnitionSourceCheck() *v1alpha1.IgnitionSourceCheck {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetIgnitionSourceCheck")
+	ret0, _ := ret[0].(*v1alpha1.IgnitionSourceCheck)
+	return ret0
+}
+
+// GetIgnitionSourceCheck indicates an expected call of GetIgnitionSourceCheck
+func (mr *MockMachineScopeMockRecorder) GetIgnitionSourceCheck() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetIgnitionSourceCheck", reflect.TypeOf((*MockMachineScope)(nil).GetIgnitionSourceCheck))
+}
+
+// CreateIgnitionSourceCheckPodFromMachine mocks base method
+func (m *MockMachineScope) CreateIgnitionSourceCheckPodFromMachine(sources []string) (*v1.Pod, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateIgnitionSourceCheckPodFromMachine", sources)
+	ret0, _ := ret[0].(*v1.Pod)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateIgnitionSourceCheckPodFromMachine indicates an expected call of CreateIgnitionSourceCheckPodFromMachine
+func (mr *MockMachineScopeMockRecorder) CreateIgnitionSourceCheckPodFromMachine(sources interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateIgnitionSourceCheckPodFromMachine", reflect.TypeOf((*MockMachineScope)(nil).CreateIgnitionSourceCheckPodFromMachine), sources)
+}
+
+// GetInstanceMetadata mocks base method
+func (m *MockMachineScope) GetInstanceMetadata() map[string]string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetInstanceMetadata")
+	ret0, _ := ret[0].(map[string]string)
+	return ret0
+}
+
+// GetInstanceMetadata indicates an expected call of GetInstanceMetadata
+func (mr *MockMachineScopeMockRecorder) GetInstanceMetadata() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInstanceMetadata", reflect.TypeOf((*MockMachineScope)(nil).GetInstanceMetadata))
+}
+
+// GetAfterburnMetadata mocks base method
+func (m *MockMachineScope) GetAfterburnMetadata() *v1alpha1.AfterburnMetadata {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAfterburnMetadata")
+	ret0, _ := ret[0].(*v1alpha1.AfterburnMetadata)
+	return ret0
+}
+
+// GetAfterburnMetadata indicates an expected call of GetAfterburnMetadata
+func (mr *MockMachineScopeMockRecorder) GetAfterburnMetadata() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAfterburnMetadata", reflect.TypeOf((*MockMachineScope)(nil).GetAfterburnMetadata))
+}
+
+// GetAddressesFromPools mocks base method
+func (m *MockMachineScope) GetAddressesFromPools() []v1alpha1.AddressesFromPool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAddressesFromPools")
+	ret0, _ := ret[0].([]v1alpha1.AddressesFromPool)
+	return ret0
+}
+
+// GetAddressesFromPools indicates an expected call of GetAddressesFromPools
+func (mr *MockMachineScopeMockRecorder) GetAddressesFromPools() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAddressesFromPools", reflect.TypeOf((*MockMachineScope)(nil).GetAddressesFromPools))
+}
+
+// SetStaticAddresses mocks base method
+func (m *MockMachineScope) SetStaticAddresses(addresses []machinescope.StaticAddress) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetStaticAddresses", addresses)
+}
+
+// SetStaticAddresses indicates an expected call of SetStaticAddresses
+func (mr *MockMachineScopeMockRecorder) SetStaticAddresses(addresses interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetStaticAddresses", reflect.TypeOf((*MockMachineScope)(nil).SetStaticAddresses), addresses)
+}
+
+// CreateStepDone mocks base method
+func (m *MockMachineScope) CreateStepDone(step machinescope.CreateStep) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateStepDone", step)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// CreateStepDone indicates an expected call of CreateStepDone
+func (mr *MockMachineScopeMockRecorder) CreateStepDone(step interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateStepDone", reflect.TypeOf((*MockMachineScope)(nil).CreateStepDone), step)
+}
+
+// SetCreateStepDone mocks base method
+func (m *MockMachineScope) SetCreateStepDone(step machinescope.CreateStep) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetCreateStepDone", step)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetCreateStepDone indicates an expected call of SetCreateStepDone
+func (mr *MockMachineScopeMockRecorder) SetCreateStepDone(step interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCreateStepDone", reflect.TypeOf((*MockMachineScope)(nil).SetCreateStepDone), step)
+}
+
+// GetOriginalMachine mocks base method
+func (m *MockMachineScope) GetOriginalMachine() *v1beta1.Machine {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOriginalMachine")
+	ret0, _ := ret[0].(*v1beta1.Machine)
+	return ret0
+}
+
+// GetOriginalMachine indicates an expected call of GetOriginalMachine
+func (mr *MockMachineScopeMockRecorder) GetOriginalMachine() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOriginalMachine", reflect.TypeOf((*MockMachineScope)(nil).GetOriginalMachine))
+}
+
+// PatchMachine mocks base method
+func (m *MockMachineScope) PatchMachine() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PatchMachine")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PatchMachine indicates an expected call of PatchMachine
+func (mr *MockMachineScopeMockRecorder) PatchMachine() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PatchMachine", reflect.TypeOf((*MockMachineScope)(nil).PatchMachine))
+}
+
+// PatchMachineMetadata mocks base method
+func (m *MockMachineScope) PatchMachineMetadata() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PatchMachineMetadata")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PatchMachineMetadata indicates an expected call of PatchMachineMetadata
+func (mr *MockMachineScopeMockRecorder) PatchMachineMetadata() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PatchMachineMetadata", reflect.TypeOf((*MockMachineScope)(nil).PatchMachineMetadata))
+}
+
+// HostnameInjectionEnabled mocks base method
+func (m *MockMachineScope) HostnameInjectionEnabled() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HostnameInjectionEnabled")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// HostnameInjectionEnabled indicates an expected call of HostnameInjectionEnabled
+func (mr *MockMachineScopeMockRecorder) HostnameInjectionEnabled() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HostnameInjectionEnabled", reflect.TypeOf((*MockMachineScope)(nil).HostnameInjectionEnabled))
+}
+
+// GetHostname mocks base method
+func (m *MockMachineScope) GetHostname() (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHostname")
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHostname indicates an expected call of GetHostname
+func (mr *MockMachineScopeMockRecorder) GetHostname() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHostname", reflect.TypeOf((*MockMachineScope)(nil).GetHostname))
+}
+
+// GuestAgentRequired mocks base method
+func (m *MockMachineScope) GuestAgentRequired() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GuestAgentRequired")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// GuestAgentRequired indicates an expected call of GuestAgentRequired
+func (mr *MockMachineScopeMockRecorder) GuestAgentRequired() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GuestAgentRequired", reflect.TypeOf((*MockMachineScope)(nil).GuestAgentRequired))
+}
+
+// GetReconciliationMode mocks base method
+func (m *MockMachineScope) GetReconciliationMode() (v1alpha1.ReconciliationMode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReconciliationMode")
+	ret0, _ := ret[0].(v1alpha1.ReconciliationMode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReconciliationMode indicates an expected call of GetReconciliationMode
+func (mr *MockMachineScopeMockRecorder) GetReconciliationMode() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReconciliationMode", reflect.TypeOf((*MockMachineScope)(nil).GetReconciliationMode))
+}
+
+// NodeDrainRequiredBeforeDelete mocks base method
+func (m *MockMachineScope) NodeDrainRequiredBeforeDelete() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NodeDrainRequiredBeforeDelete")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// NodeDrainRequiredBeforeDelete indicates an expected call of NodeDrainRequiredBeforeDelete
+func (mr *MockMachineScopeMockRecorder) NodeDrainRequiredBeforeDelete() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NodeDrainRequiredBeforeDelete", reflect.TypeOf((*MockMachineScope)(nil).NodeDrainRequiredBeforeDelete))
+}
+
+// NodeSmokeCheckRequired mocks base method
+func (m *MockMachineScope) NodeSmokeCheckRequired() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NodeSmokeCheckRequired")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// NodeSmokeCheckRequired indicates an expected call of NodeSmokeCheckRequired
+func (mr *MockMachineScopeMockRecorder) NodeSmokeCheckRequired() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NodeSmokeCheckRequired", reflect.TypeOf((*MockMachineScope)(nil).NodeSmokeCheckRequired))
+}
+
+// SetMachineCreationCondition mocks base method
+func (m *MockMachineScope) SetMachineCreationCondition(err error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetMachineCreationCondition", err)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetMachineCreationCondition indicates an expected call of SetMachineCreationCondition
+func (mr *MockMachineScopeMockRecorder) SetMachineCreationCondition(err interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMachineCreationCondition", reflect.TypeOf((*MockMachineScope)(nil).SetMachineCreationCondition), err)
+}
+
+// SetMachineRequiresReplacementCondition mocks base method
+func (m *MockMachineScope) SetMachineRequiresReplacementCondition(err error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetMachineRequiresReplacementCondition", err)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetMachineRequiresReplacementCondition indicates an expected call of SetMachineRequiresReplacementCondition
+func (mr *MockMachineScopeMockRecorder) SetMachineRequiresReplacementCondition(err interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMachineRequiresReplacementCondition", reflect.TypeOf((*MockMachineScope)(nil).SetMachineRequiresReplacementCondition), err)
+}
+
+// GetVirtualMachineUID mocks base method
+func (m *MockMachineScope) GetVirtualMachineUID() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVirtualMachineUID")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// GetVirtualMachineUID indicates an expected call of GetVirtualMachineUID
+func (mr *MockMachineScopeMockRecorder) GetVirtualMachineUID() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVirtualMachineUID", reflect.TypeOf((*MockMachineScope)(nil).GetVirtualMachineUID))
+}
+
+// GetStorageClassName mocks base method
+func (m *MockMachineScope) GetStorageClassName() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStorageClassName")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// GetStorageClassName indicates an expected call of GetStorageClassName
+func (mr *MockMachineScopeMockRecorder) GetStorageClassName() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStorageClassName", reflect.TypeOf((*MockMachineScope)(nil).GetStorageClassName))
+}
+
+// PersistentVolumeAccessModeDetectionRequired mocks base method
+func (m *MockMachineScope) PersistentVolumeAccessModeDetectionRequired() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PersistentVolumeAccessModeDetectionRequired")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// PersistentVolumeAccessModeDetectionRequired indicates an expected call of PersistentVolumeAccessModeDetectionRequired
+func (mr *MockMachineScopeMockRecorder) PersistentVolumeAccessModeDetectionRequired() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PersistentVolumeAccessModeDetectionRequired", reflect.TypeOf((*MockMachineScope)(nil).PersistentVolumeAccessModeDetectionRequired))
+}
+
+// SelectPersistentVolumeAccessMode mocks base method
+func (m *MockMachineScope) SelectPersistentVolumeAccessMode(storageClassName string, supported []v1.PersistentVolumeAccessMode) string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SelectPersistentVolumeAccessMode", storageClassName, supported)
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// SelectPersistentVolumeAccessMode indicates an expected call of SelectPersistentVolumeAccessMode
+func (mr *MockMachineScopeMockRecorder) SelectPersistentVolumeAccessMode(storageClassName, supported interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectPersistentVolumeAccessMode", reflect.TypeOf((*MockMachineScope)(nil).SelectPersistentVolumeAccessMode), storageClassName, supported)
+}
+
+// GetPersistentVolumeAccessModeDecision mocks base method
+func (m *MockMachineScope) GetPersistentVolumeAccessModeDecision() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPersistentVolumeAccessModeDecision")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// GetPersistentVolumeAccessModeDecision indicates an expected call of GetPersistentVolumeAccessModeDecision
+func (mr *MockMachineScopeMockRecorder) GetPersistentVolumeAccessModeDecision() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPersistentVolumeAccessModeDecision", reflect.TypeOf((*MockMachineScope)(nil).GetPersistentVolumeAccessModeDecision))
+}
+
+// GetBootVolumeShape mocks base method
+func (m *MockMachineScope) GetBootVolumeShape() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBootVolumeShape")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// GetBootVolumeShape indicates an expected call of GetBootVolumeShape
+func (mr *MockMachineScopeMockRecorder) GetBootVolumeShape() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBootVolumeShape", reflect.TypeOf((*MockMachineScope)(nil).GetBootVolumeShape))
+}
+
+// GetVirtualMachinePoolName mocks base method
+func (m *MockMachineScope) GetVirtualMachinePoolName() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVirtualMachinePoolName")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// GetVirtualMachinePoolName indicates an expected call of GetVirtualMachinePoolName
+func (mr *MockMachineScopeMockRecorder) GetVirtualMachinePoolName() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVirtualMachinePoolName", reflect.TypeOf((*MockMachineScope)(nil).GetVirtualMachinePoolName))
+}
+
+// SetPoolVirtualMachine mocks base method
+func (m *MockMachineScope) SetPoolVirtualMachine(name string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetPoolVirtualMachine", name)
+}
+
+// SetPoolVirtualMachine indicates an expected call of SetPoolVirtualMachine
+func (mr *MockMachineScopeMockRecorder) SetPoolVirtualMachine(name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPoolVirtualMachine", reflect.TypeOf((*MockMachineScope)(nil).SetPoolVirtualMachine), name)
+}
+
+// GetExistingVMName mocks base method
+func (m *MockMachineScope) GetExistingVMName() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetExistingVMName")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// GetExistingVMName indicates an expected call of GetExistingVMName
+func (mr *MockMachineScopeMockRecorder) GetExistingVMName() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExistingVMName", reflect.TypeOf((*MockMachineScope)(nil).GetExistingVMName))
+}
+
+// GetBootVolumeDeletePolicy mocks base method
+func (m *MockMachineScope) GetBootVolumeDeletePolicy() (v1alpha1.BootVolumeDeletePolicy, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBootVolumeDeletePolicy")
+	ret0, _ := ret[0].(v1alpha1.BootVolumeDeletePolicy)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBootVolumeDeletePolicy indicates an expected call of GetBootVolumeDeletePolicy
+func (mr *MockMachineScopeMockRecorder) GetBootVolumeDeletePolicy() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBootVolumeDeletePolicy", reflect.TypeOf((*MockMachineScope)(nil).GetBootVolumeDeletePolicy))
+}
+
+// GetSourceImageDigest mocks base method
+func (m *MockMachineScope) GetSourceImageDigest() (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSourceImageDigest")
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSourceImageDigest indicates an expected call of GetSourceImageDigest
+func (mr *MockMachineScopeMockRecorder) GetSourceImageDigest() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSourceImageDigest", reflect.TypeOf((*MockMachineScope)(nil).GetSourceImageDigest))
+}
+
+// GetDataSource mocks base method
+func (m *MockMachineScope) GetDataSource() (string, string) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDataSource")
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(string)
+	return ret0, ret1
+}
+
+// GetDataSource indicates an expected call of GetDataSource
+func (mr *MockMachineScopeMockRecorder) GetDataSource() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDataSource", reflect.TypeOf((*MockMachineScope)(nil).GetDataSource))
+}
+
+// ClusterProxyPropagationRequired mocks base method
+func (m *MockMachineScope) ClusterProxyPropagationRequired() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClusterProxyPropagationRequired")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// ClusterProxyPropagationRequired indicates an expected call of ClusterProxyPropagationRequired
+func (mr *MockMachineScopeMockRecorder) ClusterProxyPropagationRequired() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClusterProxyPropagationRequired", reflect.TypeOf((*MockMachineScope)(nil).ClusterProxyPropagationRequired))
+}
+
+// SetClusterProxy mocks base method
+func (m *MockMachineScope) SetClusterProxy(proxy *machinescope.ClusterProxy) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetClusterProxy", proxy)
+}
+
+// SetClusterProxy indicates an expected call of SetClusterProxy
+func (mr *MockMachineScopeMockRecorder) SetClusterProxy(proxy interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetClusterProxy", reflect.TypeOf((*MockMachineScope)(nil).SetClusterProxy), proxy)
+}
+
+// GetClusterProxy mocks base method
+func (m *MockMachineScope) GetClusterProxy() *machinescope.ClusterProxy {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetClusterProxy")
+	ret0, _ := ret[0].(*machinescope.ClusterProxy)
+	return ret0
+}
+
+// GetClusterProxy indicates an expected call of GetClusterProxy
+func (mr *MockMachineScopeMockRecorder) GetClusterProxy() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetClusterProxy", reflect.TypeOf((*MockMachineScope)(nil).GetClusterProxy))
+}
+
+// GetZone mocks base method
+func (m *MockMachineScope) GetZone() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetZone")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// GetZone indicates an expected call of GetZone
+func (mr *MockMachineScopeMockRecorder) GetZone() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetZone", reflect.TypeOf((*MockMachineScope)(nil).GetZone))
+}
+
+// GetZoneChoices mocks base method
+func (m *MockMachineScope) GetZoneChoices() []string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetZoneChoices")
+	ret0, _ := ret[0].([]string)
+	return ret0
+}
+
+// GetZoneChoices indicates an expected call of GetZoneChoices
+func (mr *MockMachineScopeMockRecorder) GetZoneChoices() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetZoneChoices", reflect.TypeOf((*MockMachineScope)(nil).GetZoneChoices))
+}
+
+// SetZone mocks base method
+func (m *MockMachineScope) SetZone(zone string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetZone", zone)
+}
+
+// SetZone indicates an expected call of SetZone
+func (mr *MockMachineScopeMockRecorder) SetZone(zone interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetZone", reflect.TypeOf((*MockMachineScope)(nil).SetZone), zone)
+}
@@ -0,0 +1,101 @@
+package machinescope
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+)
+
+// StaticAddress is an address allocated to the main interface of the guest
+type StaticAddress struct {
+	Address string
+	Prefix  int64
+	Gateway string
+}
+
+// networkData is the OpenStack network_data.json format of the config drive
+type networkData struct {
+	Links    []networkDataLink    `json:"links"`
+	Networks []networkDataNetwork `json:"networks"`
+	Services []networkDataService `json:"services,omitempty"`
+}
+
+type networkDataLink struct {
+	ID                 string `json:"id"`
+	Type               string `json:"type"`
+	EthernetMACAddress string `json:"ethernet_mac_address,omitempty"`
+}
+
+type networkDataNetwork struct {
+	ID        string             `json:"id"`
+	Type      string             `json:"type"`
+	Link      string             `json:"link"`
+	IPAddress string             `json:"ip_address"`
+	Netmask   string             `json:"netmask"`
+	Routes    []networkDataRoute `json:"routes,omitempty"`
+}
+
+type networkDataRoute struct {
+	Network string `json:"network"`
+	Netmask string `json:"netmask"`
+	Gateway string `json:"gateway"`
+}
+
+type networkDataService struct {
+	Type    string `json:"type"`
+	Address string `json:"address"`
+}
+
+func (s *machineScope) GetAddressesFromPools() []kubevirtproviderv1alpha1.AddressesFromPool {
+	if len(s.machineProviderSpec.AddressesFromPools) == 0 {
+		return nil
+	}
+	pools := make([]kubevirtproviderv1alpha1.AddressesFromPool, len(s.machineProviderSpec.AddressesFromPools))
+	copy(pools, s.machineProviderSpec.AddressesFromPools)
+	return pools
+}
+
+func (s *machineScope) SetStaticAddresses(addresses []StaticAddress) {
+	s.staticAddresses = addresses
+}
+
+// buildNetworkData renders the static addresses and the nameservers of the main interface as network data,
+// or returns an empty string when no address was claimed
+func (s *machineScope) buildNetworkData() string {
+	if len(s.staticAddresses) == 0 {
+		return ""
+	}
+	data := networkData{
+		Links: []networkDataLink{{ID: mainNetworkName, Type: "phy", EthernetMACAddress: s.macAddress()}},
+	}
+	for i, address := range s.staticAddresses {
+		network := networkDataNetwork{
+			ID:        fmt.Sprintf("network%d", i),
+			Type:      "ipv4",
+			Link:      mainNetworkName,
+			IPAddress: address.Address,
+		}
+		bits, defaultNetwork := 32, "0.0.0.0"
+		if ip := net.ParseIP(address.Address); ip != nil && ip.To4() == nil {
+			network.Type = "ipv6"
+			bits, defaultNetwork = 128, "::"
+		}
+		network.Netmask = net.IP(net.CIDRMask(int(address.Prefix), bits)).String()
+		if address.Gateway != "" {
+			network.Routes = []networkDataRoute{{
+				Network: defaultNetwork,
+				Netmask: defaultNetwork,
+				Gateway: address.Gateway,
+			}}
+		}
+		data.Networks = append(data.Networks, network)
+	}
+	for _, nameserver := range s.machineProviderSpec.Nameservers {
+		data.Services = append(data.Services, networkDataService{Type: "dns", Address: nameserver})
+	}
+	// Marshalling the plain structs can't fail
+	result, _ := json.Marshal(data)
+	return string(result)
+}
@@ -0,0 +1,48 @@
+package machinescope
+
+import (
+	"fmt"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/utils"
+)
+
+// namespaceNameLabel is the label the API server sets on every namespace, with the name of the namespace
+const namespaceNameLabel = "kubernetes.io/metadata.name"
+
+func buildNetworkPolicyName(infraID string) string {
+	return fmt.Sprintf("tenantcluster-%s-isolation", infraID)
+}
+
+func (s *machineScope) CreateNetworkPolicyFromMachine() *networkingv1.NetworkPolicy {
+	if !s.machineProviderSpec.IsolateTenantNetwork {
+		return nil
+	}
+	ownershipLabels := utils.BuildLabels(s.infraID)
+	// The virt-launcher pods of this tenant-cluster, the pods of other tenant-clusters sharing the infra namespace
+	// or of any other namespace are denied
+	peers := []networkingv1.NetworkPolicyPeer{
+		{PodSelector: &metav1.LabelSelector{MatchLabels: ownershipLabels}},
+	}
+	if allowedNamespaces := s.machineProviderSpec.TenantNetworkAllowedNamespaces; len(allowedNamespaces) > 0 {
+		peers = append(peers, networkingv1.NetworkPolicyPeer{NamespaceSelector: &metav1.LabelSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: namespaceNameLabel, Operator: metav1.LabelSelectorOpIn, Values: allowedNamespaces},
+			},
+		}})
+	}
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      buildNetworkPolicyName(s.infraID),
+			Namespace: s.infraNamespace,
+			Labels:    ownershipLabels,
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: ownershipLabels},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			Ingress:     []networkingv1.NetworkPolicyIngressRule{{From: peers}},
+		},
+	}
+}
@@ -0,0 +1,64 @@
+package machinescope
+
+import (
+	"fmt"
+
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+)
+
+// MachinePatcher persists the changes of a Machine since originMachineCopy, e.g. the tenant-cluster client
+type MachinePatcher interface {
+	// PatchMachine persists the changes of the metadata and the spec of the Machine, and updates the Machine
+	// to the stored one
+	PatchMachine(machine *machinev1.Machine, originMachineCopy *machinev1.Machine) error
+	// StatusPatchMachine persists the changes of the status of the Machine
+	StatusPatchMachine(machine *machinev1.Machine, originMachineCopy *machinev1.Machine) error
+}
+
+func (s *machineScope) GetOriginalMachine() *machinev1.Machine {
+	s.patchLock.Lock()
+	defer s.patchLock.Unlock()
+	return s.originMachineCopy
+}
+
+func (s *machineScope) PatchMachineMetadata() error {
+	s.patchLock.Lock()
+	defer s.patchLock.Unlock()
+	return s.patchMachineMetadata()
+}
+
+func (s *machineScope) PatchMachine() error {
+	s.patchLock.Lock()
+	defer s.patchLock.Unlock()
+	klog.V(3).Infof("%v: patching machine", s.GetMachineName())
+
+	if err := s.patchMachineMetadata(); err != nil {
+		return err
+	}
+	if err := s.machinePatcher.StatusPatchMachine(s.machine, s.originMachineCopy); err != nil {
+		return errors.Wrap(err, "failed to patch machine status")
+	}
+	s.originMachineCopy = s.machine.DeepCopy()
+	return nil
+}
+
+// patchMachineMetadata persists the metadata and the spec of the machine, its status is kept as is to be persisted
+// by PatchMachine, and it remains a change of the original machine
+func (s *machineScope) patchMachineMetadata() error {
+	if s.machinePatcher == nil {
+		return fmt.Errorf("%s: the machine scope has no machine patcher", s.GetMachineName())
+	}
+	// The patch returns the machine as stored, keep the status which is patched afterwards
+	statusCopy := *s.machine.Status.DeepCopy()
+	if err := s.machinePatcher.PatchMachine(s.machine, s.originMachineCopy); err != nil {
+		return errors.Wrap(err, "failed to patch machine")
+	}
+	s.machine.Status = statusCopy
+
+	originStatus := s.originMachineCopy.Status
+	s.originMachineCopy = s.machine.DeepCopy()
+	s.originMachineCopy.Status = originStatus
+	return nil
+}
@@ -0,0 +1,40 @@
+package machinescope
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
+
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+)
+
+// ResourceRequests returns the CPU, memory and storage the VirtualMachine of a Machine with the provider spec requests,
+// with the defaults applied. The CPU is omitted when the provider spec doesn't request it.
+func ResourceRequests(providerSpec *kubevirtproviderv1alpha1.KubevirtMachineProviderSpec) (corev1.ResourceList, error) {
+	requestedMemory := providerSpec.RequestedMemory
+	if requestedMemory == "" {
+		requestedMemory = defaultRequestedMemory
+	}
+	memory, err := apiresource.ParseQuantity(requestedMemory)
+	if err != nil {
+		return nil, fmt.Errorf("invalid RequestedMemory %q: %v", requestedMemory, err)
+	}
+	requestedStorage := providerSpec.RequestedStorage
+	if requestedStorage == "" {
+		requestedStorage = defaultRequestedStorage
+	}
+	storage, err := apiresource.ParseQuantity(requestedStorage)
+	if err != nil {
+		return nil, fmt.Errorf("invalid RequestedStorage %q: %v", requestedStorage, err)
+	}
+
+	requests := corev1.ResourceList{
+		corev1.ResourceMemory:  memory,
+		corev1.ResourceStorage: storage,
+	}
+	if providerSpec.RequestedCPU != 0 {
+		requests[corev1.ResourceCPU] = *apiresource.NewQuantity(int64(providerSpec.RequestedCPU), apiresource.DecimalSI)
+	}
+	return requests, nil
+}
@@ -0,0 +1,60 @@
+package machinescope
+
+import (
+	"strings"
+	"text/template"
+
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+)
+
+// templateData holds the values which can be referenced by the go templates of the provider spec fields
+type templateData struct {
+	MachineName      string
+	MachineNamespace string
+	InfraID          string
+	InfraNamespace   string
+	// Index is the last dash-separated segment of the Machine name: the index of the indexed Machines, e.g. 0 for
+	// master-0, or the random suffix of the Machines of a MachineSet, which differentiates its replicas
+	Index string
+	// Zone is the infra zone of the Machine, empty when it isn't pinned to a zone
+	Zone string
+}
+
+// IsTemplate returns true when the provider spec field is a go template, which is only rendered for a Machine
+func IsTemplate(text string) bool {
+	return strings.Contains(text, "{{")
+}
+
+// renderTemplate executes the go template of a provider spec field against the data of this Machine
+func (s *machineScope) renderTemplate(fieldName string, text string) (string, error) {
+	tmpl, err := template.New(fieldName).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", machinecontroller.InvalidMachineConfiguration("%v: failed to parse the template of %v: %v", s.machine.GetName(), fieldName, err)
+	}
+	data := templateData{
+		MachineName:      s.machine.GetName(),
+		MachineNamespace: s.machine.GetNamespace(),
+		InfraID:          s.infraID,
+		InfraNamespace:   s.infraNamespace,
+		Index:            machineIndex(s.machine.GetName()),
+		Zone:             s.GetZone(),
+	}
+	var result strings.Builder
+	if err := tmpl.Execute(&result, data); err != nil {
+		return "", machinecontroller.InvalidMachineConfiguration("%v: failed to render the template of %v: %v", s.machine.GetName(), fieldName, err)
+	}
+	return result.String(), nil
+}
+
+// renderField renders the provider spec field, which may be a go template, and returns it as is otherwise
+func (s *machineScope) renderField(fieldName string, text string) (string, error) {
+	if !IsTemplate(text) {
+		return text, nil
+	}
+	return s.renderTemplate(fieldName, text)
+}
+
+// machineIndex returns the last dash-separated segment of the Machine name
+func machineIndex(machineName string) string {
+	return machineName[strings.LastIndex(machineName, "-")+1:]
+}
@@ -0,0 +1,59 @@
+package machinescope
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtapiv1 "kubevirt.io/client-go/api/v1"
+
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/metrics"
+)
+
+// syncProvisioningTimeline records the provisioning steps which are observed for the first time,
+// and the duration of the phases they complete
+func syncProvisioningTimeline(timeline *kubevirtproviderv1alpha1.ProvisioningTimeline, vm kubevirtapiv1.VirtualMachine,
+	vmi *kubevirtapiv1.VirtualMachineInstance) *kubevirtproviderv1alpha1.ProvisioningTimeline {
+	if timeline == nil {
+		timeline = &kubevirtproviderv1alpha1.ProvisioningTimeline{}
+	}
+	if timeline.VMCreated == nil && !vm.CreationTimestamp.IsZero() {
+		timeline.VMCreated = vm.CreationTimestamp.DeepCopy()
+	}
+	if vmi == nil {
+		return emptyTimelineToNil(timeline)
+	}
+	if timeline.DataVolumeReady == nil && !vmi.CreationTimestamp.IsZero() {
+		timeline.DataVolumeReady = vmi.CreationTimestamp.DeepCopy()
+		if timeline.VMCreated != nil {
+			metrics.ObserveProvisioningPhase(metrics.ProvisioningPhaseClone, timeline.VMCreated.Time, timeline.DataVolumeReady.Time)
+		}
+	}
+	if timeline.VMIRunning == nil && vmi.Status.Phase == kubevirtapiv1.Running {
+		timeline.VMIRunning = vmiReadySince(vmi)
+		if timeline.DataVolumeReady != nil {
+			metrics.ObserveProvisioningPhase(metrics.ProvisioningPhaseBoot, timeline.DataVolumeReady.Time, timeline.VMIRunning.Time)
+		}
+	}
+	return emptyTimelineToNil(timeline)
+}
+
+// emptyTimelineToNil drops a timeline without any recorded step, so it's omitted from the ProviderStatus
+func emptyTimelineToNil(timeline *kubevirtproviderv1alpha1.ProvisioningTimeline) *kubevirtproviderv1alpha1.ProvisioningTimeline {
+	if *timeline == (kubevirtproviderv1alpha1.ProvisioningTimeline{}) {
+		return nil
+	}
+	return timeline
+}
+
+// vmiReadySince returns the last transition time of the Ready condition of the VirtualMachineInstance,
+// or the current time if the condition doesn't record it
+func vmiReadySince(vmi *kubevirtapiv1.VirtualMachineInstance) *metav1.Time {
+	for _, condition := range vmi.Status.Conditions {
+		if condition.Type == kubevirtapiv1.VirtualMachineInstanceReady && condition.Status == corev1.ConditionTrue &&
+			!condition.LastTransitionTime.IsZero() {
+			return condition.LastTransitionTime.DeepCopy()
+		}
+	}
+	now := metav1.Now()
+	return &now
+}
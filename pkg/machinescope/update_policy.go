@@ -0,0 +1,31 @@
+package machinescope
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/clock"
+)
+
+// UpdatePolicy is the window within which the VirtualMachine of a Machine, which was just created, may not be
+// visible yet in the InfraCluster: the update of the Machine is requeued instead of failing
+type UpdatePolicy struct {
+	// Window is the time since the last update of the Machine during which its VirtualMachine may be missing
+	Window time.Duration
+	// Clock tells the time the window is compared to, the real clock when nil
+	Clock clock.PassiveClock
+}
+
+// allows returns true when the Machine, last updated at lastUpdated, is still within the window. The window is
+// inclusive: a Machine updated exactly Window ago is allowed. A Machine which was never updated is allowed, as is a
+// Machine whose last update is in the future, e.g. from a clock skew between the controllers.
+func (p UpdatePolicy) allows(lastUpdated *metav1.Time) bool {
+	if lastUpdated == nil {
+		return true
+	}
+	passiveClock := p.Clock
+	if passiveClock == nil {
+		passiveClock = clock.RealClock{}
+	}
+	return passiveClock.Since(lastUpdated.Time) <= p.Window
+}
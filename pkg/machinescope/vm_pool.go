@@ -0,0 +1,37 @@
+package machinescope
+
+import (
+	kubevirtapiv1 "kubevirt.io/client-go/api/v1"
+)
+
+const (
+	// PoolVirtualMachineAnnotation is set on a Machine backed by a VirtualMachinePool, to the name of the
+	// VirtualMachine of the pool it adopted
+	PoolVirtualMachineAnnotation = "kubevirt.machine.openshift.io/pool-virtual-machine"
+	// AdoptedByLabel is set on an adopted VirtualMachine, of a VirtualMachinePool or pre-staged by the infra
+	// admins, to the name of the Machine adopting it
+	AdoptedByLabel = "kubevirt.machine.openshift.io/adopted-by"
+
+	virtualMachinePoolKind = "VirtualMachinePool"
+)
+
+func (s *machineScope) GetVirtualMachinePoolName() string {
+	return s.machineProviderSpec.VirtualMachinePoolName
+}
+
+func (s *machineScope) SetPoolVirtualMachine(name string) {
+	if s.machine.Annotations == nil {
+		s.machine.Annotations = map[string]string{}
+	}
+	s.machine.Annotations[PoolVirtualMachineAnnotation] = name
+}
+
+// IsPoolVirtualMachine returns whether the VirtualMachine was created by the VirtualMachinePool
+func IsPoolVirtualMachine(vm *kubevirtapiv1.VirtualMachine, poolName string) bool {
+	for _, ownerReference := range vm.OwnerReferences {
+		if ownerReference.Kind == virtualMachinePoolKind && ownerReference.Name == poolName {
+			return true
+		}
+	}
+	return false
+}
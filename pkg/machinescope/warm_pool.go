@@ -0,0 +1,68 @@
+package machinescope
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	cdiv1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
+
+	kubevirtproviderv1alpha1 "github.com/openshift/cluster-api-provider-kubevirt/pkg/apis/kubevirtprovider/v1alpha1"
+	"github.com/openshift/cluster-api-provider-kubevirt/pkg/utils"
+)
+
+const (
+	// WarmPoolShapeLabel is set on the pre-cloned boot DataVolumes of the warm pool, to the shape of their boot volume.
+	// It is removed from a DataVolume once the DataVolume is claimed by a VirtualMachine.
+	WarmPoolShapeLabel = "kubevirt.machine.openshift.io/warm-pool-shape"
+	// WarmPoolClaimedByLabel is set on a DataVolume of the warm pool to the name of the VirtualMachine claiming it
+	WarmPoolClaimedByLabel = "kubevirt.machine.openshift.io/warm-pool-claimed-by"
+	// WarmBootVolumeAnnotation is set on a VirtualMachine booting from a DataVolume of the warm pool, to its name
+	WarmBootVolumeAnnotation = "kubevirt.machine.openshift.io/warm-boot-volume"
+)
+
+// BootVolumeShape returns the shape of the boot volume of the Machines with the provider spec: the boot volumes
+// of the same shape are clones of the same source, with the same storage, so they can be cloned ahead of time
+func BootVolumeShape(providerSpec *kubevirtproviderv1alpha1.KubevirtMachineProviderSpec) string {
+	storage := providerSpec.RequestedStorage
+	if storage == "" {
+		storage = defaultRequestedStorage
+	}
+	source := providerSpec.SourcePvcName
+	if providerSpec.DataSourceName != "" {
+		source = fmt.Sprintf("datasource:%s/%s", providerSpec.DataSourceNamespace, providerSpec.DataSourceName)
+	}
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s/%s/%s/%s",
+		source, providerSpec.StorageClassName, storage, warmPoolAccessMode(providerSpec))))
+	return hex.EncodeToString(hash[:])[:16]
+}
+
+// BuildWarmBootVolume builds a boot DataVolume of the warm pool, of the shape of the provider spec
+func BuildWarmBootVolume(providerSpec *kubevirtproviderv1alpha1.KubevirtMachineProviderSpec, infraNamespace, infraID string) *cdiv1.DataVolume {
+	storage := providerSpec.RequestedStorage
+	if storage == "" {
+		storage = defaultRequestedStorage
+	}
+	dataVolume := buildBootVolumeDataVolumeTemplate("", providerSpec.SourcePvcName, infraNamespace,
+		providerSpec.StorageClassName, storage, warmPoolAccessMode(providerSpec))
+	dataVolume.Kind = "DataVolume"
+	dataVolume.Name = ""
+	dataVolume.GenerateName = fmt.Sprintf("%s-warm-", infraID)
+	dataVolume.Labels = utils.BuildLabels(infraID)
+	dataVolume.Labels[WarmPoolShapeLabel] = BootVolumeShape(providerSpec)
+	return dataVolume
+}
+
+// warmPoolAccessMode returns the access mode of the boot volumes of the warm pool, which are cloned before the
+// access mode of a Machine could be selected by the capabilities of its storage class
+func warmPoolAccessMode(providerSpec *kubevirtproviderv1alpha1.KubevirtMachineProviderSpec) corev1.PersistentVolumeAccessMode {
+	if providerSpec.PersistentVolumeAccessMode != "" {
+		return corev1.PersistentVolumeAccessMode(providerSpec.PersistentVolumeAccessMode)
+	}
+	return defaultPersistentVolumeAccessMode
+}
+
+func (s *machineScope) GetBootVolumeShape() string {
+	return BootVolumeShape(s.machineProviderSpec)
+}
@@ -0,0 +1,53 @@
+package machinescope
+
+import (
+	"sort"
+
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+)
+
+// InfraZoneLabel is the label of the infra nodes with their zone
+const InfraZoneLabel = "topology.kubernetes.io/zone"
+
+// GetZone returns the infra zone of the Machine: the Zone of the provider spec, or the zone the Machine was
+// assigned among the ZoneNetworkNames. It is empty when the VirtualMachine isn't pinned to a zone.
+func (s *machineScope) GetZone() string {
+	if s.machineProviderSpec.Zone != "" {
+		return s.machineProviderSpec.Zone
+	}
+	zone := s.machine.Labels[machinecontroller.MachineAZLabelName]
+	if _, ok := s.machineProviderSpec.ZoneNetworkNames[zone]; ok {
+		return zone
+	}
+	return ""
+}
+
+// GetZoneChoices returns the zones a Machine without zone is assigned one of, in order, or nil when the
+// Machine already has a zone or isn't pinned to a zone
+func (s *machineScope) GetZoneChoices() []string {
+	if s.GetZone() != "" || len(s.machineProviderSpec.ZoneNetworkNames) == 0 {
+		return nil
+	}
+	zones := make([]string, 0, len(s.machineProviderSpec.ZoneNetworkNames))
+	for zone := range s.machineProviderSpec.ZoneNetworkNames {
+		zones = append(zones, zone)
+	}
+	sort.Strings(zones)
+	return zones
+}
+
+func (s *machineScope) SetZone(zone string) {
+	if s.machine.Labels == nil {
+		s.machine.Labels = map[string]string{}
+	}
+	s.machine.Labels[machinecontroller.MachineAZLabelName] = zone
+}
+
+// networkName returns the NetworkAttachmentDefinition of the zone of the Machine, NetworkName otherwise.
+// Both may be go templates.
+func (s *machineScope) networkName() (string, error) {
+	if networkName := s.machineProviderSpec.ZoneNetworkNames[s.GetZone()]; networkName != "" {
+		return s.renderField("ZoneNetworkNames", networkName)
+	}
+	return s.renderField("NetworkName", s.machineProviderSpec.NetworkName)
+}
@@ -0,0 +1,81 @@
+// metrics package exposes the prometheus metrics of the kubevirt machine controller,
+// registered in the metrics registry of the controller-runtime manager.
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// ProvisioningPhase is a part of the provisioning of a Machine
+type ProvisioningPhase string
+
+const (
+	// ProvisioningPhaseClone is the time from the VirtualMachine creation until its DataVolumes are ready
+	ProvisioningPhaseClone ProvisioningPhase = "clone"
+	// ProvisioningPhaseBoot is the time from the DataVolumes being ready until the VirtualMachineInstance is ready
+	ProvisioningPhaseBoot ProvisioningPhase = "boot"
+	// ProvisioningPhaseJoin is the time from the VirtualMachineInstance being ready until its Node is created
+	ProvisioningPhaseJoin ProvisioningPhase = "join"
+)
+
+var provisioningDuration = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name:    "kubevirt_machine_provisioning_duration_seconds",
+		Help:    "Duration of the provisioning phases of the kubevirt Machines",
+		Buckets: []float64{5, 10, 30, 60, 120, 300, 600, 1200, 1800},
+	},
+	[]string{"phase"},
+)
+
+// InfraRequestOutcome is the outcome of a request to the infra-cluster API
+type InfraRequestOutcome string
+
+const (
+	// InfraRequestSucceeded is a request answered by the infra-cluster API, even with a non transient error
+	InfraRequestSucceeded InfraRequestOutcome = "succeeded"
+	// InfraRequestRetried is an attempt of an idempotent request which failed with a transient error, and is retried
+	InfraRequestRetried InfraRequestOutcome = "retried"
+	// InfraRequestFailed is a request which failed with a transient error, once its retries are exhausted
+	InfraRequestFailed InfraRequestOutcome = "failed"
+	// InfraRequestTimedOut is an attempt which didn't complete within the request timeout
+	InfraRequestTimedOut InfraRequestOutcome = "timed_out"
+	// InfraRequestRejected is a request failed fast, without reaching the infra-cluster API, while the circuit is open
+	InfraRequestRejected InfraRequestOutcome = "circuit_open"
+)
+
+var infraRequests = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "kubevirt_machine_infra_requests_total",
+		Help: "Requests of the kubevirt machine controller to the infra-cluster API, by outcome",
+	},
+	[]string{"outcome"},
+)
+
+var staleSyncs = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "kubevirt_machine_stale_syncs_total",
+		Help: "Syncs of the Machines skipped since their VirtualMachine or VirtualMachineInstance was stale",
+	},
+)
+
+func init() {
+	metrics.Registry.MustRegister(provisioningDuration, infraRequests, staleSyncs)
+}
+
+// ObserveProvisioningPhase records the duration of a provisioning phase, between its start and end
+func ObserveProvisioningPhase(phase ProvisioningPhase, start, end time.Time) {
+	provisioningDuration.WithLabelValues(string(phase)).Observe(end.Sub(start).Seconds())
+}
+
+// ObserveInfraRequest counts a request to the infra-cluster API with its outcome
+func ObserveInfraRequest(outcome InfraRequestOutcome) {
+	infraRequests.WithLabelValues(string(outcome)).Inc()
+}
+
+// ObserveStaleSync counts a sync of a Machine skipped since it was based on stale data
+func ObserveStaleSync() {
+	staleSyncs.Inc()
+}
@@ -0,0 +1,276 @@
+package metrics
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	authenticationv1client "k8s.io/client-go/kubernetes/typed/authentication/v1"
+	authorizationv1client "k8s.io/client-go/kubernetes/typed/authorization/v1"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	// metricsPath is the path the metrics are served on, as by the controller-runtime manager
+	metricsPath = "/metrics"
+	// authDecisionTTL is the time the authentication and authorization of a bearer token are cached for
+	authDecisionTTL = time.Minute
+	// shutdownTimeout bounds the graceful shutdown of the metrics endpoint
+	shutdownTimeout = 10 * time.Second
+)
+
+// ServerOptions configure the metrics endpoint served in place of the one of the controller-runtime manager,
+// for the clusters which require the metrics to be served with TLS and authorization, without a sidecar proxy
+type ServerOptions struct {
+	// BindAddress is the address the metrics endpoint binds to
+	BindAddress string
+	// CertFile and KeyFile are the serving certificate of the metrics endpoint, which is served with TLS when
+	// they are set. The certificate is reloaded once rotated, e.g. by the service CA.
+	CertFile string
+	KeyFile  string
+	// AuthnAuthz requires the scrapers to send a bearer token, authenticated with a TokenReview and allowed to
+	// get the metrics path with a SubjectAccessReview
+	AuthnAuthz bool
+}
+
+// Enabled returns true when the metrics endpoint is served by AddServer, instead of the controller-runtime manager
+func (o ServerOptions) Enabled() bool {
+	return o.CertFile != "" || o.KeyFile != "" || o.AuthnAuthz
+}
+
+// server serves the metrics registry of the controller-runtime manager
+type server struct {
+	bindAddress string
+	handler     http.Handler
+	tlsConfig   *tls.Config
+}
+
+// AddServer adds the metrics endpoint to the manager. It's served by every replica, as the one of the manager.
+func AddServer(mgr manager.Manager, options ServerOptions) error {
+	s := &server{bindAddress: options.BindAddress}
+	s.handler = promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{ErrorHandling: promhttp.HTTPErrorOnError})
+
+	if options.CertFile != "" || options.KeyFile != "" {
+		if options.CertFile == "" || options.KeyFile == "" {
+			return fmt.Errorf("both the certificate and the key of the metrics endpoint are required")
+		}
+		certs := &certificateLoader{certFile: options.CertFile, keyFile: options.KeyFile}
+		if _, err := certs.getCertificate(nil); err != nil {
+			return err
+		}
+		s.tlsConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: certs.getCertificate,
+		}
+	}
+
+	if options.AuthnAuthz {
+		kubernetesClient, err := kubernetes.NewForConfig(mgr.GetConfig())
+		if err != nil {
+			return err
+		}
+		s.handler = newAuthorizer(kubernetesClient.AuthenticationV1().TokenReviews(),
+			kubernetesClient.AuthorizationV1().SubjectAccessReviews()).wrap(s.handler)
+	}
+	return mgr.Add(s)
+}
+
+// Start serves the metrics endpoint until the context is done
+func (s *server) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.bindAddress)
+	if err != nil {
+		return fmt.Errorf("error listening on %s for the metrics: %v", s.bindAddress, err)
+	}
+	if s.tlsConfig != nil {
+		listener = tls.NewListener(listener, s.tlsConfig)
+	}
+	mux := http.NewServeMux()
+	mux.Handle(metricsPath, s.handler)
+	httpServer := &http.Server{Handler: mux}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			klog.Errorf("failed to shut down the metrics endpoint, with error: %v", err)
+		}
+	}()
+
+	klog.Infof("serving the metrics on %s, with TLS: %t", s.bindAddress, s.tlsConfig != nil)
+	if err := httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// NeedLeaderElection returns false, the metrics are served by every replica
+func (s *server) NeedLeaderElection() bool {
+	return false
+}
+
+// certificateLoader loads the serving certificate, again once its files are modified
+type certificateLoader struct {
+	certFile string
+	keyFile  string
+
+	lock        sync.Mutex
+	certificate *tls.Certificate
+	modTime     time.Time
+}
+
+func (l *certificateLoader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	var modTime time.Time
+	for _, file := range []string{l.certFile, l.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, fmt.Errorf("error reading the serving certificate of the metrics: %v", err)
+		}
+		if info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+	}
+	if l.certificate != nil && modTime.Equal(l.modTime) {
+		return l.certificate, nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(l.certFile, l.keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("error loading the serving certificate of the metrics: %v", err)
+	}
+	if l.certificate != nil {
+		klog.Infof("reloaded the serving certificate of the metrics")
+	}
+	l.certificate = &certificate
+	l.modTime = modTime
+	return l.certificate, nil
+}
+
+// authDecision is the cached outcome of the authentication and authorization of a bearer token
+type authDecision struct {
+	status  int
+	expires time.Time
+}
+
+// authorizer authenticates the bearer token of the requests with TokenReviews, and authorizes them to get the
+// requested path with SubjectAccessReviews, as kube-rbac-proxy does
+type authorizer struct {
+	tokenReviews  authenticationv1client.TokenReviewInterface
+	accessReviews authorizationv1client.SubjectAccessReviewInterface
+
+	lock      sync.Mutex
+	decisions map[string]authDecision
+}
+
+func newAuthorizer(tokenReviews authenticationv1client.TokenReviewInterface, accessReviews authorizationv1client.SubjectAccessReviewInterface) *authorizer {
+	return &authorizer{
+		tokenReviews:  tokenReviews,
+		accessReviews: accessReviews,
+		decisions:     map[string]authDecision{},
+	}
+}
+
+// wrap returns a handler serving the requests which are authenticated and authorized
+func (a *authorizer) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization := r.Header.Get("Authorization")
+		token := strings.TrimPrefix(authorization, "Bearer ")
+		if !strings.HasPrefix(authorization, "Bearer ") || token == "" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		status, err := a.authorize(r.Context(), token, r.URL.Path)
+		if err != nil {
+			klog.Errorf("failed to authorize a request of the metrics, with error: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		if status != http.StatusOK {
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authorize returns the HTTP status of the request of the path with the bearer token: OK when allowed,
+// Unauthorized when the token isn't authenticated and Forbidden when the user isn't allowed to get the path
+func (a *authorizer) authorize(ctx context.Context, token string, path string) (int, error) {
+	key := path + "\x00" + token
+	now := time.Now()
+	a.lock.Lock()
+	decision, ok := a.decisions[key]
+	a.lock.Unlock()
+	if ok && now.Before(decision.expires) {
+		return decision.status, nil
+	}
+
+	status, err := a.review(ctx, token, path)
+	if err != nil {
+		return 0, err
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	for cachedKey, cached := range a.decisions {
+		if !now.Before(cached.expires) {
+			delete(a.decisions, cachedKey)
+		}
+	}
+	a.decisions[key] = authDecision{status: status, expires: now.Add(authDecisionTTL)}
+	return status, nil
+}
+
+func (a *authorizer) review(ctx context.Context, token string, path string) (int, error) {
+	tokenReview, err := a.tokenReviews.Create(ctx, &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("error creating TokenReview: %v", err)
+	}
+	if !tokenReview.Status.Authenticated {
+		return http.StatusUnauthorized, nil
+	}
+
+	user := tokenReview.Status.User
+	extra := map[string]authorizationv1.ExtraValue{}
+	for k, v := range user.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+	accessReview, err := a.accessReviews.Create(ctx, &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   user.Username,
+			UID:    user.UID,
+			Groups: user.Groups,
+			Extra:  extra,
+			NonResourceAttributes: &authorizationv1.NonResourceAttributes{
+				Path: path,
+				Verb: "get",
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("error creating SubjectAccessReview: %v", err)
+	}
+	if !accessReview.Status.Allowed {
+		klog.V(3).Infof("user %s isn't allowed to get the metrics: %s", user.Username, accessReview.Status.Reason)
+		return http.StatusForbidden, nil
+	}
+	return http.StatusOK, nil
+}
@@ -0,0 +1,178 @@
+package testutils
+
+import (
+	"context"
+	"fmt"
+
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// FakeClient is an in-memory tenant-cluster client.Client, holding the Nodes and Machines read and
+// written by the controllers. Methods the controllers don't use are not implemented and panic.
+type FakeClient struct {
+	client.Client
+
+	Nodes    map[string]*corev1.Node
+	Machines []machinev1.Machine
+	// DeletedNodes records the names of the deleted Nodes
+	DeletedNodes []string
+	// ListErr is returned by List when set
+	ListErr error
+
+	// machineIndexers are the indexes of the Machines registered by IndexField, by field
+	machineIndexers map[string]client.IndexerFunc
+}
+
+// NewFakeClient returns a FakeClient holding copies of the given Nodes and Machines
+func NewFakeClient(nodes []corev1.Node, machines []machinev1.Machine) *FakeClient {
+	c := &FakeClient{Nodes: map[string]*corev1.Node{}}
+	for i := range nodes {
+		c.Nodes[nodes[i].Name] = nodes[i].DeepCopy()
+	}
+	for i := range machines {
+		c.Machines = append(c.Machines, *machines[i].DeepCopy())
+	}
+	return c
+}
+
+func (c *FakeClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	switch o := obj.(type) {
+	case *corev1.Node:
+		node, ok := c.Nodes[key.Name]
+		if !ok {
+			return apierrors.NewNotFound(schema.GroupResource{Resource: "nodes"}, key.Name)
+		}
+		node.DeepCopyInto(o)
+		return nil
+	case *machinev1.Machine:
+		for i := range c.Machines {
+			if c.Machines[i].Namespace == key.Namespace && c.Machines[i].Name == key.Name {
+				c.Machines[i].DeepCopyInto(o)
+				return nil
+			}
+		}
+		return apierrors.NewNotFound(schema.GroupResource{Resource: "machines"}, key.Name)
+	}
+	return fmt.Errorf("FakeClient: unsupported object type %T", obj)
+}
+
+// IndexField registers an index of the Machines, which List uses for the MatchingFields of the field, as the
+// cache of the manager does
+func (c *FakeClient) IndexField(ctx context.Context, obj client.Object, field string, extractValue client.IndexerFunc) error {
+	if _, ok := obj.(*machinev1.Machine); !ok {
+		return fmt.Errorf("FakeClient: unsupported index of object type %T", obj)
+	}
+	if c.machineIndexers == nil {
+		c.machineIndexers = map[string]client.IndexerFunc{}
+	}
+	c.machineIndexers[field] = extractValue
+	return nil
+}
+
+func (c *FakeClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if c.ListErr != nil {
+		return c.ListErr
+	}
+	listOptions := client.ListOptions{}
+	listOptions.ApplyOptions(opts)
+	switch l := list.(type) {
+	case *machinev1.MachineList:
+		l.Items = nil
+		for i := range c.Machines {
+			matches, err := c.machineMatchesFields(&c.Machines[i], listOptions.FieldSelector)
+			if err != nil {
+				return err
+			}
+			if matches {
+				l.Items = append(l.Items, *c.Machines[i].DeepCopy())
+			}
+		}
+		return nil
+	case *corev1.NodeList:
+		l.Items = nil
+		for _, node := range c.Nodes {
+			l.Items = append(l.Items, *node.DeepCopy())
+		}
+		return nil
+	}
+	return fmt.Errorf("FakeClient: unsupported list type %T", list)
+}
+
+// machineMatchesFields returns true when the indexed values of the Machine match the field selector
+func (c *FakeClient) machineMatchesFields(machine *machinev1.Machine, selector fields.Selector) (bool, error) {
+	if selector == nil {
+		return true, nil
+	}
+	for _, requirement := range selector.Requirements() {
+		indexer, ok := c.machineIndexers[requirement.Field]
+		if !ok {
+			return false, fmt.Errorf("FakeClient: no index of the Machines for the field %s", requirement.Field)
+		}
+		found := false
+		for _, value := range indexer(machine) {
+			if value == requirement.Value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (c *FakeClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	switch o := obj.(type) {
+	case *corev1.Node:
+		if _, ok := c.Nodes[o.Name]; !ok {
+			return apierrors.NewNotFound(schema.GroupResource{Resource: "nodes"}, o.Name)
+		}
+		c.Nodes[o.Name] = o.DeepCopy()
+		return nil
+	case *machinev1.Machine:
+		for i := range c.Machines {
+			if c.Machines[i].Namespace == o.Namespace && c.Machines[i].Name == o.Name {
+				c.Machines[i] = *o.DeepCopy()
+				return nil
+			}
+		}
+		return apierrors.NewNotFound(schema.GroupResource{Resource: "machines"}, o.Name)
+	}
+	return fmt.Errorf("FakeClient: unsupported object type %T", obj)
+}
+
+func (c *FakeClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	node, ok := obj.(*corev1.Node)
+	if !ok {
+		return fmt.Errorf("FakeClient: unsupported object type %T", obj)
+	}
+	if _, ok := c.Nodes[node.Name]; !ok {
+		return apierrors.NewNotFound(schema.GroupResource{Resource: "nodes"}, node.Name)
+	}
+	delete(c.Nodes, node.Name)
+	c.DeletedNodes = append(c.DeletedNodes, node.Name)
+	return nil
+}
+
+// Status returns a writer which updates the objects of the FakeClient as a whole
+func (c *FakeClient) Status() client.StatusWriter {
+	return fakeStatusWriter{c}
+}
+
+type fakeStatusWriter struct {
+	c *FakeClient
+}
+
+func (w fakeStatusWriter) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	return w.c.Update(ctx, obj)
+}
+
+func (w fakeStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	return fmt.Errorf("FakeClient: status patch is not supported")
+}
@@ -0,0 +1,40 @@
+package testutils
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// GaugeValues returns the values of the series of the gauge, by the values of the labels joined with "/" in the
+// order of labelNames. The collector is drained by a goroutine, whatever the number of series.
+func GaugeValues(gauge *prometheus.GaugeVec, labelNames ...string) (map[string]float64, error) {
+	ch := make(chan prometheus.Metric)
+	go func() {
+		gauge.Collect(ch)
+		close(ch)
+	}()
+	values := map[string]float64{}
+	var err error
+	for metric := range ch {
+		// The channel is drained on errors as well, so the collecting goroutine returns
+		if err != nil {
+			continue
+		}
+		m := &dto.Metric{}
+		if err = metric.Write(m); err != nil {
+			continue
+		}
+		labels := map[string]string{}
+		for _, label := range m.Label {
+			labels[label.GetName()] = label.GetValue()
+		}
+		var key []string
+		for _, name := range labelNames {
+			key = append(key, labels[name])
+		}
+		values[strings.Join(key, "/")] = m.Gauge.GetValue()
+	}
+	return values, err
+}
@@ -161,7 +161,11 @@ func StubVirtualMachine(name *string, namespace *string, UID *string) *kubevirta
 			},
 			Template: &kubevirtapiv1.VirtualMachineInstanceTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Labels: map[string]string{"kubevirt.io/vm": MachineName, "name": MachineName},
+					Labels: map[string]string{
+						"kubevirt.io/vm": MachineName,
+						"name":           MachineName,
+						fmt.Sprintf("tenantcluster-%s-machine.openshift.io", InfraID): "owned",
+					},
 				},
 				Spec: kubevirtapiv1.VirtualMachineInstanceSpec{
 					TerminationGracePeriodSeconds: func(src int64) *int64 { return &src }(600),
@@ -258,3 +262,28 @@ func deepCopyMap(src map[string]string) map[string]string {
 func StringPointer(src string) *string {
 	return &src
 }
+
+// MachineAPINamespace is the namespace of the Machines and the MachineSets stubbed for the controllers
+const MachineAPINamespace = "openshift-machine-api"
+
+// StubMachineOfMachineSet returns a Machine of the MachineAPINamespace, controlled by the MachineSet unless its
+// name is empty
+func StubMachineOfMachineSet(name string, machineSet string) machinev1.Machine {
+	machine := machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: MachineAPINamespace}}
+	if machineSet != "" {
+		controller := true
+		machine.OwnerReferences = []metav1.OwnerReference{{Kind: "MachineSet", Name: machineSet, Controller: &controller}}
+	}
+	return machine
+}
+
+// StubMachineSet returns a MachineSet of the MachineAPINamespace with the annotations and the providerSpec
+func StubMachineSet(name string, annotations map[string]string, providerSpec kubevirtproviderv1alpha1.KubevirtMachineProviderSpec) (machinev1.MachineSet, error) {
+	value, err := kubevirtproviderv1alpha1.RawExtensionFromProviderSpec(&providerSpec)
+	if err != nil {
+		return machinev1.MachineSet{}, fmt.Errorf("codec.EncodeProviderSpec failed: %v", err)
+	}
+	machineSet := machinev1.MachineSet{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: MachineAPINamespace, Annotations: annotations}}
+	machineSet.Spec.Template.Spec.ProviderSpec.Value = value
+	return machineSet, nil
+}
@@ -1,9 +1,27 @@
 package utils
 
-import "fmt"
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	kubevirtapiv1 "kubevirt.io/client-go/api/v1"
+)
 
 func BuildLabels(infraID string) map[string]string {
 	return map[string]string{
 		fmt.Sprintf("tenantcluster-%s-machine.openshift.io", infraID): "owned",
 	}
 }
+
+// IsGuestAgentConnected returns whether the qemu-guest-agent of the VirtualMachineInstance is connected
+func IsGuestAgentConnected(vmi *kubevirtapiv1.VirtualMachineInstance) bool {
+	if vmi == nil {
+		return false
+	}
+	for _, condition := range vmi.Status.Conditions {
+		if condition.Type == kubevirtapiv1.VirtualMachineInstanceAgentConnected {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}